@@ -84,6 +84,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and push images into the specified repo, to pull base images")
 	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupRegistryCache(&commonCmdData, cmd)
 	common.SetupInsecureHelmDependencies(&commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
 
@@ -101,11 +102,17 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupSaveBuildReport(&commonCmdData, cmd)
 	common.SetupBuildReportPath(&commonCmdData, cmd)
+	common.SetupBuildTracePath(&commonCmdData, cmd)
+	common.SetupDockerfileLayersCache(&commonCmdData, cmd)
+	common.SetupDistributedBuild(&commonCmdData, cmd)
 	common.SetupDeprecatedReportPath(&commonCmdData, cmd)
 	common.SetupDeprecatedReportFormat(&commonCmdData, cmd)
 
 	common.SetupAddCustomTag(&commonCmdData, cmd)
 	common.SetupVirtualMerge(&commonCmdData, cmd)
+	common.SetupSignKey(&commonCmdData, cmd)
+	common.SetupSBOM(&commonCmdData, cmd)
+	common.SetupVulnerabilityScanner(&commonCmdData, cmd)
 
 	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
 	common.SetupFollow(&commonCmdData, cmd)
@@ -118,6 +125,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	common.SetupDockerServerStoragePath(&commonCmdData, cmd)
 
 	commonCmdData.SetupPlatform(cmd)
+	common.SetupReproducible(&commonCmdData, cmd)
 
 	return cmd
 }
@@ -223,6 +231,9 @@ func run(ctx context.Context, containerBackend container_backend.ContainerBacken
 	if err != nil {
 		return err
 	}
+	if *commonCmdData.DistributedBuild && synchronization.SynchronizationType == common.LocalSynchronization {
+		return fmt.Errorf("--distributed-build requires the synchronization shared between hosts: specify --repo to use the default synchronization server or set --synchronization explicitly")
+	}
 	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
 	if err != nil {
 		return err
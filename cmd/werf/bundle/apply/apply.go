@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	uuid "github.com/satori/go.uuid"
@@ -24,6 +26,7 @@ import (
 	"github.com/werf/werf/pkg/deploy/helm/command_helpers"
 	"github.com/werf/werf/pkg/deploy/lock_manager"
 	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/werf"
 	"github.com/werf/werf/pkg/werf/global_warnings"
 )
@@ -70,6 +73,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and push images into the specified repo, to pull base images")
 	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupRegistryCache(&commonCmdData, cmd)
 	common.SetupInsecureHelmDependencies(&commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
 
@@ -86,6 +90,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
+	common.SetupSecretBackendConfig(&commonCmdData, cmd)
 
 	common.SetupSaveDeployReport(&commonCmdData, cmd)
 	common.SetupDeployReportPath(&commonCmdData, cmd)
@@ -100,6 +105,8 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	common.SetupHooksStatusProgressPeriod(&commonCmdData, cmd)
 	common.SetupReleasesHistoryMax(&commonCmdData, cmd)
 
+	common.SetupVerifyKey(&commonCmdData, cmd)
+
 	defaultTag := os.Getenv("WERF_TAG")
 	if defaultTag == "" {
 		defaultTag = "latest"
@@ -168,13 +175,67 @@ func runApply(ctx context.Context) error {
 		return err
 	}
 
+	bundleRef := fmt.Sprintf("%s:%s", repoAddress, cmdData.Tag)
+
+	verifier, err := common.GetVerifier(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	var dockerRegistry docker_registry.Interface
+	if verifier != nil {
+		dockerRegistry, err = common.CreateDockerRegistry(repoAddress, *commonCmdData.InsecureRegistry, *commonCmdData.SkipTlsVerifyRegistry)
+		if err != nil {
+			return err
+		}
+
+		digests, err := verifier.VerifyImages(ctx, dockerRegistry, []string{bundleRef})
+		if err != nil {
+			return err
+		}
+
+		// pull exactly the verified manifest, the tag might be moved after verification
+		bundleRef = fmt.Sprintf("%s@%s", bundleRef, digests[0])
+	}
+
 	bundleTmpDir := filepath.Join(werf.GetServiceDir(), "tmp", "bundles", uuid.NewV4().String())
 	defer os.RemoveAll(bundleTmpDir)
 
-	if err := bundles.Pull(ctx, fmt.Sprintf("%s:%s", repoAddress, cmdData.Tag), bundleTmpDir, bundlesRegistryClient); err != nil {
+	if err := bundles.Pull(ctx, bundleRef, bundleTmpDir, bundlesRegistryClient); err != nil {
 		return fmt.Errorf("unable to pull bundle: %w", err)
 	}
 
+	// verified images are deployed by digest: the references override .Values.werf.image of the bundle
+	verifiedImages := map[string]interface{}{}
+	if verifier != nil {
+		images, err := bundles.GetChartDirImages(bundleTmpDir)
+		if err != nil {
+			return fmt.Errorf("unable to get bundle images: %w", err)
+		}
+
+		var imageNames, imageRefs []string
+		for imageName := range images {
+			imageNames = append(imageNames, imageName)
+		}
+		sort.Strings(imageNames)
+		for _, imageName := range imageNames {
+			imageRefs = append(imageRefs, images[imageName])
+		}
+
+		digests, err := verifier.VerifyImages(ctx, dockerRegistry, imageRefs)
+		if err != nil {
+			return err
+		}
+
+		for i, imageName := range imageNames {
+			if strings.Contains(imageRefs[i], "@") {
+				verifiedImages[imageName] = imageRefs[i]
+			} else {
+				verifiedImages[imageName] = fmt.Sprintf("%s@%s", imageRefs[i], digests[i])
+			}
+		}
+	}
+
 	namespace := common.GetNamespace(&commonCmdData)
 	releaseName, err := common.GetRequiredRelease(&commonCmdData)
 	if err != nil {
@@ -194,12 +255,19 @@ func runApply(ctx context.Context) error {
 
 	secretsManager := secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{DisableSecretsDecryption: *commonCmdData.IgnoreSecretKey})
 
+	secretBackend, err := common.GetLocalSecretBackendConfig(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
 	bundle, err := chart_extender.NewBundle(ctx, bundleTmpDir, helm_v3.Settings, helmRegistryClient, secretsManager, chart_extender.BundleOptions{
 		SecretValueFiles:                  common.GetSecretValues(&commonCmdData),
 		BuildChartDependenciesOpts:        command_helpers.BuildChartDependenciesOptions{IgnoreInvalidAnnotationsAndLabels: true},
 		IgnoreInvalidAnnotationsAndLabels: true,
 		ExtraAnnotations:                  userExtraAnnotations,
 		ExtraLabels:                       userExtraLabels,
+		Environment:                       *commonCmdData.Environment,
+		SecretBackend:                     secretBackend,
 	})
 	if err != nil {
 		return err
@@ -213,6 +281,9 @@ func runApply(ctx context.Context) error {
 	}); err != nil {
 		return fmt.Errorf("error creating service values: %w", err)
 	} else {
+		if len(verifiedImages) > 0 {
+			vals["werf"].(map[string]interface{})["image"] = verifiedImages
+		}
 		bundle.SetServiceValues(vals)
 	}
 
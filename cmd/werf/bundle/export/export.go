@@ -107,6 +107,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and push images into the specified repo and to pull base images")
 	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupRegistryCache(&commonCmdData, cmd)
 	common.SetupInsecureHelmDependencies(&commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
 
@@ -130,6 +131,8 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupSaveBuildReport(&commonCmdData, cmd)
 	common.SetupBuildReportPath(&commonCmdData, cmd)
+	common.SetupBuildTracePath(&commonCmdData, cmd)
+	common.SetupDockerfileLayersCache(&commonCmdData, cmd)
 	common.SetupDeprecatedReportPath(&commonCmdData, cmd)
 	common.SetupDeprecatedReportFormat(&commonCmdData, cmd)
 
@@ -141,6 +144,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	common.SetupSkipBuild(&commonCmdData, cmd)
 	common.SetupRequireBuiltImages(&commonCmdData, cmd)
 	commonCmdData.SetupPlatform(cmd)
+	common.SetupReproducible(&commonCmdData, cmd)
 
 	common.SetupDisableAutoHostCleanup(&commonCmdData, cmd)
 	common.SetupAllowedDockerStorageVolumeUsage(&commonCmdData, cmd)
@@ -375,6 +379,7 @@ func runExport(ctx context.Context, imagesToProcess build.ImagesToProcess) error
 		SubchartExtenderFactoryFunc: func() chart.ChartExtender {
 			return chart_extender.NewWerfSubchart(ctx, secretsManager, chart_extender.WerfSubchartOptions{
 				DisableDefaultSecretValues: *commonCmdData.DisableDefaultSecretValues,
+				Environment:                *commonCmdData.Environment,
 			})
 		},
 	}
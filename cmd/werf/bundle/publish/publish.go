@@ -92,6 +92,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and push images into the specified repo and to pull base images")
 	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupRegistryCache(&commonCmdData, cmd)
 	common.SetupInsecureHelmDependencies(&commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
 
@@ -113,6 +114,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
+	common.SetupSecretBackendConfig(&commonCmdData, cmd)
 
 	commonCmdData.SetupDisableDefaultValues(cmd)
 	commonCmdData.SetupDisableDefaultSecretValues(cmd)
@@ -120,6 +122,8 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupSaveBuildReport(&commonCmdData, cmd)
 	common.SetupBuildReportPath(&commonCmdData, cmd)
+	common.SetupBuildTracePath(&commonCmdData, cmd)
+	common.SetupDockerfileLayersCache(&commonCmdData, cmd)
 	common.SetupDeprecatedReportPath(&commonCmdData, cmd)
 	common.SetupDeprecatedReportFormat(&commonCmdData, cmd)
 
@@ -139,9 +143,12 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	common.SetupSkipBuild(&commonCmdData, cmd)
 	common.SetupRequireBuiltImages(&commonCmdData, cmd)
 	commonCmdData.SetupPlatform(cmd)
+	common.SetupReproducible(&commonCmdData, cmd)
 
 	commonCmdData.SetupHelmCompatibleChart(cmd, false)
 	commonCmdData.SetupRenameChart(cmd)
+	commonCmdData.SetupOCIArtifact(cmd)
+	common.SetupSignKey(&commonCmdData, cmd)
 
 	defaultTag := os.Getenv("WERF_TAG")
 	if defaultTag == "" {
@@ -350,6 +357,11 @@ func runPublish(ctx context.Context, imagesToProcess build.ImagesToProcess) erro
 		return err
 	}
 
+	secretBackend, err := common.GetLocalSecretBackendConfig(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
 	wc := chart_extender.NewWerfChart(ctx, giterminismManager, secretsManager, chartDir, helm_v3.Settings, helmRegistryClient, chart_extender.WerfChartOptions{
 		BuildChartDependenciesOpts:        command_helpers.BuildChartDependenciesOptions{SkipUpdate: *commonCmdData.SkipDependenciesRepoRefresh},
 		SecretValueFiles:                  common.GetSecretValues(&commonCmdData),
@@ -358,6 +370,7 @@ func runPublish(ctx context.Context, imagesToProcess build.ImagesToProcess) erro
 		IgnoreInvalidAnnotationsAndLabels: true,
 		DisableDefaultValues:              *commonCmdData.DisableDefaultValues,
 		DisableDefaultSecretValues:        *commonCmdData.DisableDefaultSecretValues,
+		SecretBackend:                     secretBackend,
 	})
 
 	if err := wc.SetEnv(*commonCmdData.Environment); err != nil {
@@ -394,6 +407,7 @@ func runPublish(ctx context.Context, imagesToProcess build.ImagesToProcess) erro
 		SubchartExtenderFactoryFunc: func() chart.ChartExtender {
 			return chart_extender.NewWerfSubchart(ctx, secretsManager, chart_extender.WerfSubchartOptions{
 				DisableDefaultSecretValues: *commonCmdData.DisableDefaultSecretValues,
+				Environment:                *commonCmdData.Environment,
 			})
 		},
 	}
@@ -424,8 +438,18 @@ func runPublish(ctx context.Context, imagesToProcess build.ImagesToProcess) erro
 		bundleRepo = stagesStorage.Address()
 	}
 
-	return bundles.Publish(ctx, bundle, fmt.Sprintf("%s:%s", bundleRepo, cmdData.Tag), bundlesRegistryClient, bundles.PublishOptions{
+	publishOptions := bundles.PublishOptions{
 		HelmCompatibleChart: *commonCmdData.HelmCompatibleChart,
 		RenameChart:         *commonCmdData.RenameChart,
-	})
+		Signer:              buildOptions.Signer,
+	}
+
+	if publishOptions.Signer != nil {
+		publishOptions.RegistryClient, err = common.CreateDockerRegistry(bundleRepo, *commonCmdData.InsecureRegistry, *commonCmdData.SkipTlsVerifyRegistry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return bundles.Publish(ctx, bundle, fmt.Sprintf("%s:%s", bundleRepo, cmdData.Tag), bundlesRegistryClient, publishOptions)
 }
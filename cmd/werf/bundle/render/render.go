@@ -46,7 +46,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 		Long:                  common.GetLongCommandDescription(`Take locally extracted bundle or download bundle from the specified container registry using specified version tag or version mask and render it as Kubernetes manifests.`),
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretKeyEnv),
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
@@ -72,6 +72,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and push images into the specified repo, to pull base images")
 	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupRegistryCache(&commonCmdData, cmd)
 	common.SetupInsecureHelmDependencies(&commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
 
@@ -88,6 +89,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
+	common.SetupSecretBackendConfig(&commonCmdData, cmd)
 	commonCmdData.SetupDisableDefaultSecretValues(cmd)
 
 	common.SetupRelease(&commonCmdData, cmd)
@@ -190,12 +192,19 @@ func runRender(ctx context.Context) error {
 
 	secretsManager := secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{DisableSecretsDecryption: *commonCmdData.IgnoreSecretKey})
 
+	secretBackend, err := common.GetLocalSecretBackendConfig(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
 	bundle, err := chart_extender.NewBundle(ctx, bundleDir, helm_v3.Settings, helmRegistryClient, secretsManager, chart_extender.BundleOptions{
 		SecretValueFiles:                  common.GetSecretValues(&commonCmdData),
 		BuildChartDependenciesOpts:        command_helpers.BuildChartDependenciesOptions{IgnoreInvalidAnnotationsAndLabels: false},
 		IgnoreInvalidAnnotationsAndLabels: false,
 		ExtraAnnotations:                  userExtraAnnotations,
 		ExtraLabels:                       userExtraLabels,
+		Environment:                       *commonCmdData.Environment,
+		SecretBackend:                     secretBackend,
 	})
 	if err != nil {
 		return err
@@ -217,6 +226,7 @@ func runRender(ctx context.Context) error {
 		SubchartExtenderFactoryFunc: func() chart.ChartExtender {
 			return chart_extender.NewWerfSubchart(ctx, secretsManager, chart_extender.WerfSubchartOptions{
 				DisableDefaultSecretValues: *commonCmdData.DisableDefaultSecretValues,
+				Environment:                *commonCmdData.Environment,
 			})
 		},
 	}
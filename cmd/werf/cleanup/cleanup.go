@@ -26,6 +26,9 @@ var commonCmdData common.CmdData
 
 var cmdData struct {
 	ScanContextOnly string
+	PlanOutput      string
+	ApplyPlan       string
+	SavingsReport   string
 }
 
 func NewCmd(ctx context.Context) *cobra.Command {
@@ -56,61 +59,139 @@ func NewCmd(ctx context.Context) *cobra.Command {
 		},
 	})
 
-	common.SetupDir(&commonCmdData, cmd)
-	common.SetupGitWorkTree(&commonCmdData, cmd)
-	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
-	common.SetupConfigPath(&commonCmdData, cmd)
-	common.SetupEnvironment(&commonCmdData, cmd)
-
-	common.SetupGiterminismOptions(&commonCmdData, cmd)
-
-	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
-	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
-
-	common.SetupSecondaryStagesStorageOptions(&commonCmdData, cmd)
-	common.SetupCacheStagesStorageOptions(&commonCmdData, cmd)
-	common.SetupRepoOptions(&commonCmdData, cmd, common.RepoDataOptions{})
-	common.SetupFinalRepo(&commonCmdData, cmd)
-	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultCleanupParallelTasksLimit)
-
-	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and delete images from the specified repo")
-	common.SetupInsecureRegistry(&commonCmdData, cmd)
-	common.SetupInsecureHelmDependencies(&commonCmdData, cmd)
-	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
-
-	common.SetupScanContextNamespaceOnly(&commonCmdData, cmd)
+	setupCleanupOptions(&commonCmdData, cmd)
 	common.SetupDryRun(&commonCmdData, cmd)
 
-	common.SetupLogOptions(&commonCmdData, cmd)
-	common.SetupLogProjectDir(&commonCmdData, cmd)
-
-	common.SetupSynchronization(&commonCmdData, cmd)
-	common.SetupKubeConfig(&commonCmdData, cmd)
-	common.SetupKubeConfigBase64(&commonCmdData, cmd)
-	common.SetupWithoutKube(&commonCmdData, cmd)
-	common.SetupKeepStagesBuiltWithinLastNHours(&commonCmdData, cmd)
-
-	common.SetupDisableAutoHostCleanup(&commonCmdData, cmd)
-	common.SetupAllowedDockerStorageVolumeUsage(&commonCmdData, cmd)
-	common.SetupAllowedDockerStorageVolumeUsageMargin(&commonCmdData, cmd)
-	common.SetupAllowedLocalCacheVolumeUsage(&commonCmdData, cmd)
-	common.SetupAllowedLocalCacheVolumeUsageMargin(&commonCmdData, cmd)
-	common.SetupDockerServerStoragePath(&commonCmdData, cmd)
-	commonCmdData.SetupPlatform(cmd)
+	cmd.AddCommand(NewExplainCmd(ctx))
 
 	// aliases, but only WERF_SCAN_ONLY_CONTEXT env var is supported
 	cmd.PersistentFlags().StringVarP(&cmdData.ScanContextOnly, "scan-context-only", "", os.Getenv("WERF_SCAN_CONTEXT_ONLY"), "Scan for used images only in the specified kube context, scan all contexts from kube config otherwise (default false or $WERF_SCAN_CONTEXT_ONLY)")
 	cmd.PersistentFlags().StringVarP(&cmdData.ScanContextOnly, "kube-context", "", os.Getenv("WERF_SCAN_CONTEXT_ONLY"), "Scan for used images only in the specified kube context, scan all contexts from kube config otherwise (default false or $WERF_SCAN_CONTEXT_ONLY)")
 
+	cmd.Flags().StringVarP(&cmdData.PlanOutput, "plan-output", "", os.Getenv("WERF_PLAN_OUTPUT"), "Write the cleanup plan with keep or delete decision and its reason for each stage, final stage, custom tag, image metadata and import metadata record to the specified file. YAML format is used for .yaml/.yml extension, JSON format otherwise. Use with --dry-run to review the plan before applying it with --apply-plan (default $WERF_PLAN_OUTPUT)")
+	cmd.Flags().StringVarP(&cmdData.ApplyPlan, "apply-plan", "", os.Getenv("WERF_APPLY_PLAN"), "Delete exactly the items marked for deletion in the specified cleanup plan file (written with --plan-output) without evaluating cleanup policies again (default $WERF_APPLY_PLAN)")
+	cmd.Flags().StringVarP(&cmdData.SavingsReport, "savings-report", "", os.Getenv("WERF_SAVINGS_REPORT"), "Compute sizes of unique layers referenced by stages before and after the cleanup, print reclaimable bytes per werf image and overall, and write the report to the specified file. Layers shared between stages are counted once. YAML format is used for .yaml/.yml extension, JSON format otherwise (default $WERF_SAVINGS_REPORT)")
+
 	return cmd
 }
 
+// setupCleanupOptions sets up options that are needed to evaluate cleanup policies (shared with the explain subcommand)
+func setupCleanupOptions(commonCmdData *common.CmdData, cmd *cobra.Command) {
+	common.SetupDir(commonCmdData, cmd)
+	common.SetupGitWorkTree(commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(commonCmdData, cmd)
+	common.SetupConfigPath(commonCmdData, cmd)
+	common.SetupEnvironment(commonCmdData, cmd)
+
+	common.SetupGiterminismOptions(commonCmdData, cmd)
+
+	common.SetupTmpDir(commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(commonCmdData, cmd, common.SetupHomeDirOptions{})
+
+	common.SetupSecondaryStagesStorageOptions(commonCmdData, cmd)
+	common.SetupCacheStagesStorageOptions(commonCmdData, cmd)
+	common.SetupRepoOptions(commonCmdData, cmd, common.RepoDataOptions{})
+	common.SetupFinalRepo(commonCmdData, cmd)
+	common.SetupParallelOptions(commonCmdData, cmd, common.DefaultCleanupParallelTasksLimit)
+
+	common.SetupDockerConfig(commonCmdData, cmd, "Command needs granted permissions to read, pull and delete images from the specified repo")
+	common.SetupInsecureRegistry(commonCmdData, cmd)
+	common.SetupRegistryCache(commonCmdData, cmd)
+	common.SetupInsecureHelmDependencies(commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(commonCmdData, cmd)
+
+	common.SetupScanContextNamespaceOnly(commonCmdData, cmd)
+
+	common.SetupLogOptions(commonCmdData, cmd)
+	common.SetupLogProjectDir(commonCmdData, cmd)
+
+	common.SetupSynchronization(commonCmdData, cmd)
+	common.SetupKubeConfig(commonCmdData, cmd)
+	common.SetupKubeConfigBase64(commonCmdData, cmd)
+	common.SetupWithoutKube(commonCmdData, cmd)
+	common.SetupAllowList(commonCmdData, cmd)
+	common.SetupKeepStagesBuiltWithinLastNHours(commonCmdData, cmd)
+	common.SetupKeepDockerfileLayersCacheBuiltWithinLastNHours(commonCmdData, cmd)
+
+	common.SetupDisableAutoHostCleanup(commonCmdData, cmd)
+	common.SetupAllowedDockerStorageVolumeUsage(commonCmdData, cmd)
+	common.SetupAllowedDockerStorageVolumeUsageMargin(commonCmdData, cmd)
+	common.SetupAllowedLocalCacheVolumeUsage(commonCmdData, cmd)
+	common.SetupAllowedLocalCacheVolumeUsageMargin(commonCmdData, cmd)
+	common.SetupDockerServerStoragePath(commonCmdData, cmd)
+	commonCmdData.SetupPlatform(cmd)
+}
+
 func runCleanup(ctx context.Context) error {
+	if cmdData.PlanOutput != "" && cmdData.ApplyPlan != "" {
+		return fmt.Errorf("--plan-output and --apply-plan options cannot be used together")
+	}
+
+	if cmdData.SavingsReport != "" && cmdData.ApplyPlan != "" {
+		return fmt.Errorf("--savings-report and --apply-plan options cannot be used together")
+	}
+
+	// the plan is already computed, cleanup policies are not evaluated to apply it
+	withCleanupPolicies := cmdData.ApplyPlan == ""
+
+	return run(ctx, &commonCmdData, runOptions{WithCleanupPolicies: withCleanupPolicies}, func(ctx context.Context, projectName string, storageManager *manager.StorageManager, cleanupOptions *cleaning.CleanupOptions) error {
+		if !withCleanupPolicies {
+			plan, err := cleaning.ReadCleanupPlan(cmdData.ApplyPlan)
+			if err != nil {
+				return err
+			}
+
+			logboek.LogOptionalLn()
+			return cleaning.ApplyCleanupPlan(ctx, projectName, storageManager, plan, cleaning.ApplyCleanupPlanOptions{DryRun: *commonCmdData.DryRun})
+		}
+
+		if cmdData.PlanOutput != "" {
+			cleanupOptions.Plan = cleaning.NewCleanupPlan(projectName, storageManager)
+		}
+
+		if cmdData.SavingsReport != "" {
+			cleanupOptions.SavingsReport = cleaning.NewSavingsReport(projectName, storageManager)
+		}
+
+		logboek.LogOptionalLn()
+		if err := cleaning.Cleanup(ctx, projectName, storageManager, *cleanupOptions); err != nil {
+			return err
+		}
+
+		if cleanupOptions.Plan != nil {
+			if err := cleaning.WriteCleanupPlan(cleanupOptions.Plan, cmdData.PlanOutput); err != nil {
+				return err
+			}
+
+			logboek.Context(ctx).Default().LogFDetails("Cleanup plan saved to %s\n", cmdData.PlanOutput)
+		}
+
+		if cleanupOptions.SavingsReport != nil {
+			if err := cleaning.WriteSavingsReport(cleanupOptions.SavingsReport, cmdData.SavingsReport); err != nil {
+				return err
+			}
+
+			logboek.Context(ctx).Default().LogFDetails("Savings report saved to %s\n", cmdData.SavingsReport)
+		}
+
+		return nil
+	})
+}
+
+type runOptions struct {
+	// WithCleanupPolicies enables initialization of everything that cleanup policies need (git history, Kubernetes clients)
+	WithCleanupPolicies bool
+	// ReadOnly disables the auto host cleanup and the synchronization of the local git repository with origin
+	ReadOnly bool
+}
+
+// run initializes werf, storages and, if opts.WithCleanupPolicies is set, everything that cleanup policies need (git history, Kubernetes clients) before calling f
+func run(ctx context.Context, commonCmdData *common.CmdData, opts runOptions, f func(ctx context.Context, projectName string, storageManager *manager.StorageManager, cleanupOptions *cleaning.CleanupOptions) error) error {
 	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
 		return fmt.Errorf("initialization error: %w", err)
 	}
 
-	containerBackend, processCtx, err := common.InitProcessContainerBackend(ctx, &commonCmdData)
+	containerBackend, processCtx, err := common.InitProcessContainerBackend(ctx, commonCmdData)
 	if err != nil {
 		return err
 	}
@@ -137,27 +218,29 @@ func runCleanup(ctx context.Context) error {
 		return err
 	}
 
-	if err := common.DockerRegistryInit(ctx, &commonCmdData); err != nil {
+	if err := common.DockerRegistryInit(ctx, commonCmdData); err != nil {
 		return err
 	}
 
-	defer func() {
-		if err := common.RunAutoHostCleanup(ctx, &commonCmdData, containerBackend); err != nil {
-			logboek.Context(ctx).Error().LogF("Auto host cleanup failed: %s\n", err)
-		}
-	}()
+	if !opts.ReadOnly {
+		defer func() {
+			if err := common.RunAutoHostCleanup(ctx, commonCmdData, containerBackend); err != nil {
+				logboek.Context(ctx).Error().LogF("Auto host cleanup failed: %s\n", err)
+			}
+		}()
+	}
 
 	common.SetupOndemandKubeInitializer(cmdData.ScanContextOnly, *commonCmdData.KubeConfig, *commonCmdData.KubeConfigBase64, *commonCmdData.KubeConfigPathMergeList)
 	if err := common.GetOndemandKubeInitializer().Init(ctx); err != nil {
 		return err
 	}
 
-	giterminismManager, err := common.GetGiterminismManager(ctx, &commonCmdData)
+	giterminismManager, err := common.GetGiterminismManager(ctx, commonCmdData)
 	if err != nil {
 		return err
 	}
 
-	common.ProcessLogProjectDir(&commonCmdData, giterminismManager.ProjectDir())
+	common.ProcessLogProjectDir(commonCmdData, giterminismManager.ProjectDir())
 
 	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
 	if err != nil {
@@ -165,12 +248,12 @@ func runCleanup(ctx context.Context) error {
 	}
 	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
 
-	_, werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, true))
+	_, werfConfig, err := common.GetRequiredWerfConfig(ctx, commonCmdData, giterminismManager, common.GetWerfConfigOptions(commonCmdData, true))
 	if err != nil {
 		return fmt.Errorf("unable to load werf config: %w", err)
 	}
 
-	if !werfConfig.Meta.GitWorktree.GetForceShallowClone() && !werfConfig.Meta.GitWorktree.GetAllowFetchingOriginBranchesAndTags() {
+	if opts.WithCleanupPolicies && !werfConfig.Meta.GitWorktree.GetForceShallowClone() && !werfConfig.Meta.GitWorktree.GetAllowFetchingOriginBranchesAndTags() {
 		isShallow, err := giterminismManager.LocalGitRepo().IsShallowClone(ctx)
 		if err != nil {
 			return fmt.Errorf("check shallow clone failed: %w", err)
@@ -185,7 +268,7 @@ func runCleanup(ctx context.Context) error {
 		}
 	}
 
-	if werfConfig.Meta.GitWorktree.GetAllowFetchingOriginBranchesAndTags() {
+	if opts.WithCleanupPolicies && !opts.ReadOnly && werfConfig.Meta.GitWorktree.GetAllowFetchingOriginBranchesAndTags() {
 		if err := giterminismManager.LocalGitRepo().SyncWithOrigin(ctx); err != nil {
 			return fmt.Errorf("synchronization failed: %w", err)
 		}
@@ -200,16 +283,16 @@ It is worth noting that auto-cleaning is enabled by default, and manual use is u
 
 		return err
 	}
-	stagesStorage, err := common.GetStagesStorage(ctx, containerBackend, &commonCmdData)
+	stagesStorage, err := common.GetStagesStorage(ctx, containerBackend, commonCmdData)
 	if err != nil {
 		return err
 	}
-	finalStagesStorage, err := common.GetOptionalFinalStagesStorage(ctx, containerBackend, &commonCmdData)
+	finalStagesStorage, err := common.GetOptionalFinalStagesStorage(ctx, containerBackend, commonCmdData)
 	if err != nil {
 		return err
 	}
 
-	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, stagesStorage)
+	synchronization, err := common.GetSynchronization(ctx, commonCmdData, projectName, stagesStorage)
 	if err != nil {
 		return err
 	}
@@ -217,11 +300,11 @@ It is worth noting that auto-cleaning is enabled by default, and manual use is u
 	if err != nil {
 		return err
 	}
-	secondaryStagesStorageList, err := common.GetSecondaryStagesStorageList(ctx, stagesStorage, containerBackend, &commonCmdData)
+	secondaryStagesStorageList, err := common.GetSecondaryStagesStorageList(ctx, stagesStorage, containerBackend, commonCmdData)
 	if err != nil {
 		return err
 	}
-	cacheStagesStorageList, err := common.GetCacheStagesStorageList(ctx, containerBackend, &commonCmdData)
+	cacheStagesStorageList, err := common.GetCacheStagesStorageList(ctx, containerBackend, commonCmdData)
 	if err != nil {
 		return err
 	}
@@ -232,6 +315,10 @@ It is worth noting that auto-cleaning is enabled by default, and manual use is u
 		storageManager.EnableParallel(int(*commonCmdData.ParallelTasksLimit))
 	}
 
+	if !opts.WithCleanupPolicies {
+		return f(ctx, projectName, storageManager, nil)
+	}
+
 	imagesNames, err := common.GetManagedImagesNames(ctx, projectName, stagesStorage, werfConfig)
 	if err != nil {
 		return err
@@ -246,7 +333,7 @@ It is worth noting that auto-cleaning is enabled by default, and manual use is u
 			return fmt.Errorf("unable to get Kubernetes clusters connections: %w", err)
 		}
 
-		kubernetesNamespaceRestrictionByContext = common.GetKubernetesNamespaceRestrictionByContext(&commonCmdData, kubernetesContextClients)
+		kubernetesNamespaceRestrictionByContext = common.GetKubernetesNamespaceRestrictionByContext(commonCmdData, kubernetesContextClients)
 	}
 
 	cleanupOptions := cleaning.CleanupOptions{
@@ -255,11 +342,12 @@ It is worth noting that auto-cleaning is enabled by default, and manual use is u
 		KubernetesContextClients:                kubernetesContextClients,
 		KubernetesNamespaceRestrictionByContext: kubernetesNamespaceRestrictionByContext,
 		WithoutKube:                             *commonCmdData.WithoutKube,
+		AllowListProviders:                      common.GetAllowListProviders(commonCmdData),
 		ConfigMetaCleanup:                       werfConfig.Meta.Cleanup,
 		KeepStagesBuiltWithinLastNHours:         *commonCmdData.KeepStagesBuiltWithinLastNHours,
-		DryRun:                                  *commonCmdData.DryRun,
+		KeepDockerfileLayersCacheBuiltWithinLastNHours: *commonCmdData.KeepDockerfileLayersCacheBuiltWithinLastNHours,
+		DryRun: *commonCmdData.DryRun,
 	}
 
-	logboek.LogOptionalLn()
-	return cleaning.Cleanup(ctx, projectName, storageManager, cleanupOptions)
+	return f(ctx, projectName, storageManager, &cleanupOptions)
 }
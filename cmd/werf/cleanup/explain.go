@@ -0,0 +1,118 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/cleaning"
+	"github.com/werf/werf/pkg/storage/manager"
+	"github.com/werf/werf/pkg/werf/global_warnings"
+)
+
+var explainCommonCmdData common.CmdData
+
+func NewExplainCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &explainCommonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:                   "explain IMAGE_NAME|STAGE_ID",
+		DisableFlagsInUseLine: true,
+		Short:                 "Explain why cleanup keeps or deletes images",
+		Long: common.GetLongCommandDescription(`Explain why cleanup keeps or deletes stages, final stages, custom tags and metadata of the specified werf image or stage.
+
+The command evaluates cleanup policies the same way as werf cleanup --dry-run does and reports for each related item the keep policy and git references with commits it was found by, the Kubernetes resources that use it or other reason of the decision. Nothing is deleted, the host is not cleaned up and the local git repository is not synchronized with origin.`),
+		Example: `  # Explain decisions for all stages of the image backend
+  $ werf cleanup explain backend --repo registry.mydomain.com/myproject/werf
+
+  # Explain decision for a certain stage
+  $ werf cleanup explain 2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7-1611836746968 --repo registry.mydomain.com/myproject/werf`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			defer global_warnings.PrintGlobalWarnings(ctx)
+
+			if len(args) != 1 {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("accepts 1 position argument, received %d", len(args))
+			}
+
+			if err := common.ProcessLogOptions(&explainCommonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runExplain(ctx, args[0])
+		},
+	})
+
+	setupCleanupOptions(&explainCommonCmdData, cmd)
+
+	// explain never deletes anything
+	dryRun := true
+	explainCommonCmdData.DryRun = &dryRun
+
+	return cmd
+}
+
+func runExplain(ctx context.Context, imageNameOrStageID string) error {
+	return run(ctx, &explainCommonCmdData, runOptions{WithCleanupPolicies: true, ReadOnly: true}, func(ctx context.Context, projectName string, storageManager *manager.StorageManager, cleanupOptions *cleaning.CleanupOptions) error {
+		cleanupOptions.DryRun = true
+		cleanupOptions.Plan = cleaning.NewCleanupPlan(projectName, storageManager)
+
+		cleanupCtx := ctx
+		if !(*explainCommonCmdData.LogVerbose || *explainCommonCmdData.LogDebug) {
+			cleanupCtx = logboek.NewContext(ctx, logboek.Context(ctx).NewSubLogger(io.Discard, io.Discard))
+		}
+
+		if err := logboek.Context(ctx).LogProcess("Evaluating cleanup policies").DoError(func() error {
+			return cleaning.Cleanup(cleanupCtx, projectName, storageManager, *cleanupOptions)
+		}); err != nil {
+			return err
+		}
+
+		items := cleanupOptions.Plan.ItemsRelatedTo(imageNameOrStageID)
+		if len(items) == 0 {
+			return fmt.Errorf("no stages, custom tags or metadata found for %q in the repo", imageNameOrStageID)
+		}
+
+		for _, item := range items {
+			logboek.Context(ctx).Default().LogLn(explainCleanupPlanItemTitle(item))
+			logboek.Context(ctx).Default().LogFDetails("  %s: %s\n", item.Action, item.Reason)
+			for _, detail := range item.Details {
+				logboek.Context(ctx).Default().LogFDetails("    %s\n", detail)
+			}
+			logboek.Context(ctx).LogOptionalLn()
+		}
+
+		return nil
+	})
+}
+
+func explainCleanupPlanItemTitle(item *cleaning.CleanupPlanItem) string {
+	var parts []string
+	switch item.Kind {
+	case cleaning.CleanupPlanItemStage:
+		parts = append(parts, fmt.Sprintf("stage %s", item.Tag))
+	case cleaning.CleanupPlanItemFinalStage:
+		parts = append(parts, fmt.Sprintf("final stage %s", item.Tag))
+	case cleaning.CleanupPlanItemCustomTag:
+		parts = append(parts, fmt.Sprintf("custom tag %s", item.Tag), fmt.Sprintf("stage %s", item.StageID))
+	case cleaning.CleanupPlanItemImageMetadata:
+		parts = append(parts, fmt.Sprintf("image metadata %q", item.ImageName), fmt.Sprintf("stage %s", item.StageID), fmt.Sprintf("commit %s", item.Commit))
+	case cleaning.CleanupPlanItemImportMetadata:
+		parts = append(parts, fmt.Sprintf("import metadata %s", item.ImportMetadataID))
+	case cleaning.CleanupPlanItemVulnerabilityScanMetadata:
+		parts = append(parts, fmt.Sprintf("vulnerability scan metadata of stage %s", item.StageID))
+	case cleaning.CleanupPlanItemDigestInputsMetadata:
+		parts = append(parts, fmt.Sprintf("digest inputs metadata of stage %s", item.StageID))
+	case cleaning.CleanupPlanItemDockerfileLayersCache:
+		parts = append(parts, fmt.Sprintf("Dockerfile layers cache %s", item.Tag))
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,51 @@
+package cleanup
+
+import (
+	"testing"
+
+	"github.com/werf/werf/pkg/cleaning"
+)
+
+func TestExplainCleanupPlanItemTitle(t *testing.T) {
+	for _, tc := range []struct {
+		item     *cleaning.CleanupPlanItem
+		expected string
+	}{
+		{
+			item:     &cleaning.CleanupPlanItem{Kind: cleaning.CleanupPlanItemStage, Tag: "digest-1000"},
+			expected: "stage digest-1000",
+		},
+		{
+			item:     &cleaning.CleanupPlanItem{Kind: cleaning.CleanupPlanItemFinalStage, Tag: "digest-1000"},
+			expected: "final stage digest-1000",
+		},
+		{
+			item:     &cleaning.CleanupPlanItem{Kind: cleaning.CleanupPlanItemCustomTag, Tag: "main", StageID: "digest-1000"},
+			expected: "custom tag main, stage digest-1000",
+		},
+		{
+			item:     &cleaning.CleanupPlanItem{Kind: cleaning.CleanupPlanItemImageMetadata, ImageName: "backend", StageID: "digest-1000", Commit: "c1"},
+			expected: `image metadata "backend", stage digest-1000, commit c1`,
+		},
+		{
+			item:     &cleaning.CleanupPlanItem{Kind: cleaning.CleanupPlanItemImportMetadata, ImportMetadataID: "import-id"},
+			expected: "import metadata import-id",
+		},
+		{
+			item:     &cleaning.CleanupPlanItem{Kind: cleaning.CleanupPlanItemVulnerabilityScanMetadata, StageID: "digest-1000"},
+			expected: "vulnerability scan metadata of stage digest-1000",
+		},
+		{
+			item:     &cleaning.CleanupPlanItem{Kind: cleaning.CleanupPlanItemDigestInputsMetadata, StageID: "digest-1000"},
+			expected: "digest inputs metadata of stage digest-1000",
+		},
+		{
+			item:     &cleaning.CleanupPlanItem{Kind: cleaning.CleanupPlanItemDockerfileLayersCache, Tag: "digest"},
+			expected: "Dockerfile layers cache digest",
+		},
+	} {
+		if title := explainCleanupPlanItemTitle(tc.item); title != tc.expected {
+			t.Errorf("expected title %q for %s, got %q", tc.expected, tc.item.Kind, title)
+		}
+	}
+}
@@ -42,10 +42,13 @@ type CmdData struct {
 	SetFile                    *[]string
 	SecretValues               *[]string
 	IgnoreSecretKey            *bool
+	SecretBackendConfig        *string
+	OldSecretBackendConfig     *string
 	DisableDefaultValues       *bool
 	DisableDefaultSecretValues *bool
 	HelmCompatibleChart        *bool
 	RenameChart                *string
+	OCIArtifact                *bool
 
 	WithoutImages *bool
 	Repo          *RepoData
@@ -65,13 +68,24 @@ type CmdData struct {
 	Parallel           *bool
 	ParallelTasksLimit *int64
 
-	DockerConfig                    *string
-	InsecureRegistry                *bool
-	SkipTlsVerifyRegistry           *bool
-	InsecureHelmDependencies        *bool
-	DryRun                          *bool
-	KeepStagesBuiltWithinLastNHours *uint64
-	WithoutKube                     *bool
+	DockerConfig                                   *string
+	InsecureRegistry                               *bool
+	RegistryCache                                  *bool
+	RegistryCacheTagsTTLSeconds                    *int64
+	SkipTlsVerifyRegistry                          *bool
+	InsecureHelmDependencies                       *bool
+	DryRun                                         *bool
+	KeepStagesBuiltWithinLastNHours                *uint64
+	KeepDockerfileLayersCacheBuiltWithinLastNHours *uint64
+	WithoutKube                                    *bool
+	AllowListImages                                *[]string
+	AllowListManifestsDir                          *[]string
+	SignKey                                        *string
+	VerifyKey                                      *string
+	SBOM                                           *string
+	VulnerabilityScanner                           *string
+	VulnerabilitySeverityThreshold                 *string
+	VulnerabilityScanCacheTTLHours                 *uint64
 
 	LooseGiterminism *bool
 	Dev              *bool
@@ -97,6 +111,11 @@ type CmdData struct {
 
 	SaveBuildReport *bool
 	BuildReportPath *string
+	BuildTracePath  *string
+
+	DockerfileLayersCache *bool
+	DistributedBuild      *bool
+	Reproducible          *bool
 
 	SaveDeployReport *bool
 	UseDeployReport  *bool
@@ -171,6 +190,11 @@ func (cmdData *CmdData) SetupHelmCompatibleChart(cmd *cobra.Command, defaultEnab
 	cmd.Flags().BoolVarP(cmdData.HelmCompatibleChart, "helm-compatible-chart", "C", defaultVal, fmt.Sprintf(`Set chart name in the Chart.yaml of the published chart to the last path component of container registry repo (for REGISTRY/PATH/TO/REPO address chart name will be REPO, more info https://helm.sh/docs/topics/registries/#oci-feature-deprecation-and-behavior-changes-with-v370). In helm compatibility mode chart is fully conforming with the helm OCI registry requirements. Default %v or $WERF_HELM_COMPATIBLE_CHART.`, defaultEnabled))
 }
 
+func (cmdData *CmdData) SetupOCIArtifact(cmd *cobra.Command) {
+	cmdData.OCIArtifact = new(bool)
+	cmd.Flags().BoolVarP(cmdData.OCIArtifact, "oci-artifact", "", util.GetBoolEnvironmentDefaultFalse("WERF_OCI_ARTIFACT"), `Publish bundle as a standard Helm OCI artifact with the application/vnd.cncf.helm.chart.content.v1.tar+gzip chart content media type, which can be pulled by helm (version 3.8+) and other OCI-native tools, instead of the legacy werf bundle layout (default $WERF_OCI_ARTIFACT or false). Bundles of both layouts can be applied by werf.`)
+}
+
 func (cmdData *CmdData) SetupRenameChart(cmd *cobra.Command) {
 	cmdData.RenameChart = new(string)
 	cmd.Flags().StringVarP(cmdData.RenameChart, "rename-chart", "", os.Getenv("WERF_RENAME_CHART"), `Force setting of chart name in the Chart.yaml of the published chart to the specified value (can be set by the $WERF_RENAME_CHART, no rename by default, could not be used together with the '--helm-compatible-chart' option).`)
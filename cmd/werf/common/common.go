@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -17,15 +18,20 @@ import (
 	"github.com/werf/logboek/pkg/types"
 	"github.com/werf/werf/pkg/build"
 	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/cleaning/allow_list"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
 	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/giterminism_manager"
 	"github.com/werf/werf/pkg/logging"
+	"github.com/werf/werf/pkg/sbom"
+	"github.com/werf/werf/pkg/signing"
 	"github.com/werf/werf/pkg/storage"
 	"github.com/werf/werf/pkg/true_git"
 	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/vulnerability_scan"
 	"github.com/werf/werf/pkg/werf"
 	"github.com/werf/werf/pkg/werf/global_warnings"
 )
@@ -217,6 +223,26 @@ func SetupBuildReportPath(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().StringVarP(cmdData.BuildReportPath, "build-report-path", "", os.Getenv("WERF_BUILD_REPORT_PATH"), fmt.Sprintf("Change build report path and format (by default $WERF_BUILD_REPORT_PATH or %q if not set). Extension must be either .json for JSON format or .env for env-file format. If extension not specified, then .json is used", DefaultBuildReportPathJSON))
 }
 
+func SetupBuildTracePath(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.BuildTracePath = new(string)
+	cmd.Flags().StringVarP(cmdData.BuildTracePath, "build-trace-path", "", os.Getenv("WERF_BUILD_TRACE_PATH"), "Save the timeline of the images and stages build in the Chrome trace event format, which can be opened in chrome://tracing or https://ui.perfetto.dev (default $WERF_BUILD_TRACE_PATH)")
+}
+
+func SetupDockerfileLayersCache(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.DockerfileLayersCache = new(bool)
+	cmd.Flags().BoolVarP(cmdData.DockerfileLayersCache, "dockerfile-layers-cache", "", util.GetBoolEnvironmentDefaultFalse("WERF_DOCKERFILE_LAYERS_CACHE"), "Pull and push the intermediate layers cache of the Dockerfile images built by Buildah from/to the --repo, --cache-repo and --secondary-repo (only pull) to reuse unchanged instructions layers on the ephemeral runners (default $WERF_DOCKERFILE_LAYERS_CACHE)")
+}
+
+func SetupDistributedBuild(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.DistributedBuild = new(bool)
+	cmd.Flags().BoolVarP(cmdData.DistributedBuild, "distributed-build", "", util.GetBoolEnvironmentDefaultFalse("WERF_DISTRIBUTED_BUILD"), `Share the images builds between the werf processes running on several hosts with the same --repo and --synchronization. Each process claims and builds the images not claimed by other processes, then waits for the rest images and takes over the builds of failed processes, so that each process gets all images in the result (default $WERF_DISTRIBUTED_BUILD)`)
+}
+
+func SetupReproducible(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.Reproducible = new(bool)
+	cmd.Flags().BoolVarP(cmdData.Reproducible, "reproducible", "", util.GetBoolEnvironmentDefaultFalse("WERF_REPRODUCIBLE"), "Build byte-identical stages from the same inputs: the image configs created time and the layers files timestamps are set to $SOURCE_DATE_EPOCH or to the Unix epoch if it is not set, Buildah backend only. The option affects the stages digests and can also be enabled by the build.reproducible directive in the werf.yaml (default $WERF_REPRODUCIBLE)")
+}
+
 func GetSaveBuildReport(cmdData *CmdData) bool {
 	if cmdData.SaveBuildReport == nil {
 		return false
@@ -295,6 +321,124 @@ func SetupWithoutKube(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(cmdData.WithoutKube, "without-kube", "", util.GetBoolEnvironmentDefaultFalse("WERF_WITHOUT_KUBE"), "Do not skip deployed Kubernetes images (default $WERF_WITHOUT_KUBE)")
 }
 
+func SetupAllowList(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.AllowListImages = new([]string)
+	cmd.Flags().StringArrayVarP(cmdData.AllowListImages, "allow-list-images", "", []string{}, `Keep images referenced in the specified file or http(s) URL with one image reference per line (can specify multiple).
+Empty lines and lines starting with # are ignored.
+Also, can be specified with $WERF_ALLOW_LIST_IMAGES_* (e.g. $WERF_ALLOW_LIST_IMAGES_1=images.txt, $WERF_ALLOW_LIST_IMAGES_2=https://example.com/images.txt)`)
+
+	cmdData.AllowListManifestsDir = new([]string)
+	cmd.Flags().StringArrayVarP(cmdData.AllowListManifestsDir, "allow-list-manifests-dir", "", []string{}, `Keep images referenced in manifests from the specified directory (can specify multiple).
+The values of the image keys in YAML and JSON files (Kubernetes manifests, docker-compose files, ECS task definitions, Nomad JSON jobs) and the image attributes in HCL files (Nomad jobs) are used.
+Also, can be specified with $WERF_ALLOW_LIST_MANIFESTS_DIR_* (e.g. $WERF_ALLOW_LIST_MANIFESTS_DIR_1=deploy/compose, $WERF_ALLOW_LIST_MANIFESTS_DIR_2=deploy/nomad)`)
+}
+
+func GetAllowListProviders(cmdData *CmdData) []allow_list.Provider {
+	var providers []allow_list.Provider
+	for _, source := range append(util.PredefinedValuesByEnvNamePrefix("WERF_ALLOW_LIST_IMAGES_"), *cmdData.AllowListImages...) {
+		providers = append(providers, allow_list.NewImageListProvider(source))
+	}
+
+	for _, dir := range append(util.PredefinedValuesByEnvNamePrefix("WERF_ALLOW_LIST_MANIFESTS_DIR_"), *cmdData.AllowListManifestsDir...) {
+		providers = append(providers, allow_list.NewManifestsDirProvider(dir))
+	}
+
+	return providers
+}
+
+func SetupSignKey(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.SignKey = new(string)
+	cmd.Flags().StringVarP(cmdData.SignKey, "sign-key", "", os.Getenv("WERF_SIGN_KEY"), `Sign published images with the specified private key file and push cosign-compatible signatures into the container registry next to the images (default $WERF_SIGN_KEY).
+Key generated by the "cosign generate-key-pair" command or unencrypted PEM encoded ECDSA, RSA or Ed25519 private key can be used. The passphrase of the encrypted key can be specified with $WERF_SIGN_KEY_PASSPHRASE`)
+}
+
+func GetSigner(cmdData *CmdData) (*signing.Signer, error) {
+	if cmdData.SignKey == nil || *cmdData.SignKey == "" {
+		return nil, nil
+	}
+
+	return signing.NewSigner(*cmdData.SignKey, []byte(os.Getenv("WERF_SIGN_KEY_PASSPHRASE")))
+}
+
+func SetupSBOM(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.SBOM = new(string)
+	cmd.Flags().StringVarP(cmdData.SBOM, "sbom", "", os.Getenv("WERF_SBOM"), `Generate SBOM in the specified format (spdx or cyclonedx) for each final image and push it into the container registry as an OCI referrer artifact of the image (default $WERF_SBOM).
+The packages are listed from the dpkg and apk databases found in the final image filesystem. Pushed SBOMs are listed in the build report`)
+}
+
+func GetSBOMGenerator(cmdData *CmdData) (*sbom.Generator, error) {
+	if cmdData.SBOM == nil || *cmdData.SBOM == "" {
+		return nil, nil
+	}
+
+	format, err := sbom.ParseFormat(*cmdData.SBOM)
+	if err != nil {
+		return nil, fmt.Errorf("bad --sbom value: %w", err)
+	}
+
+	return sbom.NewGenerator(format), nil
+}
+
+func SetupVulnerabilityScanner(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.VulnerabilityScanner = new(string)
+	cmd.Flags().StringVarP(cmdData.VulnerabilityScanner, "vulnerability-scanner", "", os.Getenv("WERF_VULNERABILITY_SCANNER"), `Scan each final image with the specified scanner command before deploying, for example "trivy image --format json --quiet" (default $WERF_VULNERABILITY_SCANNER).
+The image reference is passed to the command as the last argument, the command should print the report in the Trivy JSON format. Scan results are cached in the stages storage for the --vulnerability-scan-cache-ttl-hours and listed in the build report, the images are rescanned when the scanner command changes`)
+
+	cmdData.VulnerabilitySeverityThreshold = new(string)
+	defaultThreshold := os.Getenv("WERF_VULNERABILITY_SEVERITY_THRESHOLD")
+	if defaultThreshold == "" {
+		defaultThreshold = string(vulnerability_scan.SeverityHigh)
+	}
+	cmd.Flags().StringVarP(cmdData.VulnerabilitySeverityThreshold, "vulnerability-severity-threshold", "", defaultThreshold, `Fail if the vulnerabilities of the specified or higher severity (UNKNOWN, LOW, MEDIUM, HIGH or CRITICAL) are found by the --vulnerability-scanner (default $WERF_VULNERABILITY_SEVERITY_THRESHOLD or HIGH)`)
+
+	cmdData.VulnerabilityScanCacheTTLHours = new(uint64)
+	envValue, err := util.GetUint64EnvVar("WERF_VULNERABILITY_SCAN_CACHE_TTL_HOURS")
+	if err != nil {
+		TerminateWithError(err.Error(), 1)
+	}
+
+	var defaultTTL uint64
+	if envValue != nil {
+		defaultTTL = *envValue
+	} else {
+		defaultTTL = 24
+	}
+	cmd.Flags().Uint64VarP(cmdData.VulnerabilityScanCacheTTLHours, "vulnerability-scan-cache-ttl-hours", "", defaultTTL, "Use the cached --vulnerability-scanner result of the image that was scanned within last hours, the image is rescanned with the updated vulnerabilities database otherwise, 0 disables the cache (default $WERF_VULNERABILITY_SCAN_CACHE_TTL_HOURS or 24)")
+}
+
+func GetVulnerabilityScanner(cmdData *CmdData) (*vulnerability_scan.Scanner, error) {
+	if cmdData.VulnerabilityScanner == nil || *cmdData.VulnerabilityScanner == "" {
+		return nil, nil
+	}
+
+	threshold, err := vulnerability_scan.ParseSeverity(*cmdData.VulnerabilitySeverityThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("bad --vulnerability-severity-threshold value: %w", err)
+	}
+
+	scanner, err := vulnerability_scan.NewScanner(*cmdData.VulnerabilityScanner, threshold)
+	if err != nil {
+		return nil, fmt.Errorf("bad --vulnerability-scanner value: %w", err)
+	}
+	scanner.CacheTTL = time.Duration(*cmdData.VulnerabilityScanCacheTTLHours) * time.Hour
+
+	return scanner, nil
+}
+
+func SetupVerifyKey(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.VerifyKey = new(string)
+	cmd.Flags().StringVarP(cmdData.VerifyKey, "verify-key", "", os.Getenv("WERF_VERIFY_KEY"), `Verify signatures of the deployed images with the specified public key file and refuse to deploy unsigned images or images with signatures made with another key (default $WERF_VERIFY_KEY).
+The verified images are deployed by digest. Signatures made by werf with the --sign-key option and by the "cosign sign" command are supported`)
+}
+
+func GetVerifier(cmdData *CmdData) (*signing.Verifier, error) {
+	if cmdData.VerifyKey == nil || *cmdData.VerifyKey == "" {
+		return nil, nil
+	}
+
+	return signing.NewVerifier(*cmdData.VerifyKey)
+}
+
 func SetupKeepStagesBuiltWithinLastNHours(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.KeepStagesBuiltWithinLastNHours = new(uint64)
 
@@ -313,6 +457,24 @@ func SetupKeepStagesBuiltWithinLastNHours(cmdData *CmdData, cmd *cobra.Command)
 	cmd.Flags().Uint64VarP(cmdData.KeepStagesBuiltWithinLastNHours, "keep-stages-built-within-last-n-hours", "", defaultValue, "Keep stages that were built within last hours (default $WERF_KEEP_STAGES_BUILT_WITHIN_LAST_N_HOURS or 2)")
 }
 
+func SetupKeepDockerfileLayersCacheBuiltWithinLastNHours(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.KeepDockerfileLayersCacheBuiltWithinLastNHours = new(uint64)
+
+	envValue, err := util.GetUint64EnvVar("WERF_KEEP_DOCKERFILE_LAYERS_CACHE_BUILT_WITHIN_LAST_N_HOURS")
+	if err != nil {
+		TerminateWithError(err.Error(), 1)
+	}
+
+	var defaultValue uint64
+	if envValue != nil {
+		defaultValue = *envValue
+	} else {
+		defaultValue = 168
+	}
+
+	cmd.Flags().Uint64VarP(cmdData.KeepDockerfileLayersCacheBuiltWithinLastNHours, "keep-dockerfile-layers-cache-built-within-last-n-hours", "", defaultValue, "Keep the Dockerfile layers cache (see --dockerfile-layers-cache) that was built within last hours, the older cache is deleted (default $WERF_KEEP_DOCKERFILE_LAYERS_CACHE_BUILT_WITHIN_LAST_N_HOURS or 168)")
+}
+
 func SetupEnvironment(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.Environment = new(string)
 	cmd.Flags().StringVarP(cmdData.Environment, "env", "", os.Getenv("WERF_ENV"), "Use specified environment (default $WERF_ENV)")
@@ -526,6 +688,19 @@ func SetupInsecureHelmDependencies(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(cmdData.InsecureHelmDependencies, "insecure-helm-dependencies", "", util.GetBoolEnvironmentDefaultFalse("WERF_INSECURE_HELM_DEPENDENCIES"), "Allow insecure oci registries to be used in the .helm/Chart.yaml dependencies configuration (default $WERF_INSECURE_HELM_DEPENDENCIES)")
 }
 
+func SetupRegistryCache(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.RegistryCache = new(bool)
+	cmd.Flags().BoolVarP(cmdData.RegistryCache, "registry-cache", "", util.GetBoolEnvironmentDefaultFalse("WERF_REGISTRY_CACHE"), `Cache registry manifests, image configs and tag listings on the host to reduce the number of registry API requests (default $WERF_REGISTRY_CACHE).
+The cache is shared by werf processes on the host. Manifests and image configs are stored by repository and digest and served only after the registry confirms their existence with a HEAD request, tag listings are stored per registry user for --registry-cache-tags-ttl seconds and are not used to check whether a stage was already built by another process. Records not used for 7 days and the least recently used records above 512 MiB are removed automatically`)
+
+	cmdData.RegistryCacheTagsTTLSeconds = new(int64)
+	defaultTagsTTL := int64(60)
+	if v := GetIntEnvVarStrict("WERF_REGISTRY_CACHE_TAGS_TTL_SECONDS"); v != nil {
+		defaultTagsTTL = *v
+	}
+	cmd.Flags().Int64VarP(cmdData.RegistryCacheTagsTTLSeconds, "registry-cache-tags-ttl", "", defaultTagsTTL, "Time in seconds the cached tag listings are used with --registry-cache. Set 0 to always request tag listings from the registry. Defaults to $WERF_REGISTRY_CACHE_TAGS_TTL_SECONDS or 60 seconds")
+}
+
 func SetupInsecureRegistry(cmdData *CmdData, cmd *cobra.Command) {
 	if cmdData.InsecureRegistry != nil {
 		return
@@ -758,6 +933,83 @@ func SetupIgnoreSecretKey(cmdData *CmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(cmdData.IgnoreSecretKey, "ignore-secret-key", "", util.GetBoolEnvironmentDefaultFalse("WERF_IGNORE_SECRET_KEY"), "Disable secrets decryption (default $WERF_IGNORE_SECRET_KEY)")
 }
 
+func SetupSecretBackendConfig(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.SecretBackendConfig = new(string)
+	cmd.Flags().StringVarP(cmdData.SecretBackendConfig, "secret-backend-config", "", os.Getenv("WERF_SECRET_BACKEND_CONFIG"), `Use the specified secret backend config instead of the secret backend config of the chart. Only such config can run envelope.unwrapCommand (default $WERF_SECRET_BACKEND_CONFIG or secret-backend.yaml of the project chart if exists, otherwise secrets are encrypted with the secret key)`)
+}
+
+func SetupSecretEnvironment(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.Environment = new(string)
+	cmd.Flags().StringVarP(cmdData.Environment, "env", "", os.Getenv("WERF_SECRET_ENV"), `Use the secret key of the specified environment for the environment-scoped secret files from .helm/secret/werfEnvironments/ENV (default $WERF_SECRET_ENV or the common secret key)`)
+}
+
+func SetupOldSecretBackendConfig(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.OldSecretBackendConfig = new(string)
+	cmd.Flags().StringVarP(cmdData.OldSecretBackendConfig, "old-secret-backend-config", "", os.Getenv("WERF_OLD_SECRET_BACKEND_CONFIG"), `Use the previous secret backend config to decrypt secrets when rotating secrets between backends (default $WERF_OLD_SECRET_BACKEND_CONFIG or the current secret backend config of the chart)`)
+}
+
+// GetSecretBackendConfig returns the secret backend config specified explicitly by the user or the secret backend config of the project chart.
+// Returns nil config (the default aes backend) if no secret backend config found
+func GetSecretBackendConfig(ctx context.Context, cmdData *CmdData) (*secrets_manager.SecretBackendConfig, error) {
+	if backend, err := GetLocalSecretBackendConfig(cmdData); err != nil || backend != nil {
+		return backend, err
+	}
+
+	chartDir, err := getSecretBackendChartDir(ctx, cmdData)
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets_manager.LoadSecretBackendConfig(filepath.Join(chartDir, secrets_manager.SecretBackendConfigFileName))
+}
+
+// GetLocalSecretBackendConfig returns nil config if the secret backend config is not specified explicitly by the user
+func GetLocalSecretBackendConfig(cmdData *CmdData) (*secrets_manager.SecretBackendConfig, error) {
+	if *cmdData.SecretBackendConfig == "" {
+		return nil, nil
+	}
+	return secrets_manager.LoadLocalSecretBackendConfig(*cmdData.SecretBackendConfig)
+}
+
+// getSecretBackendChartDir returns the chart dir of the werf config or .helm in the working directory if the project has no git work tree or werf config
+func getSecretBackendChartDir(ctx context.Context, cmdData *CmdData) (string, error) {
+	workingDir := GetWorkingDir(cmdData)
+
+	if _, err := GetGitWorkTree(ctx, cmdData, workingDir); err != nil {
+		var notFoundErr *GitWorktreeNotFoundError
+		if errors.As(err, &notFoundErr) {
+			return filepath.Join(workingDir, ".helm"), nil
+		}
+		return "", err
+	}
+
+	giterminismManager, err := GetGiterminismManager(ctx, cmdData)
+	if err != nil {
+		return "", err
+	}
+
+	var env string
+	if cmdData.Environment != nil {
+		env = *cmdData.Environment
+	}
+
+	werfConfigPath, werfConfig, err := GetOptionalWerfConfig(ctx, cmdData, giterminismManager, config.WerfConfigOptions{Env: env})
+	if err != nil {
+		return "", fmt.Errorf("unable to load werf config: %w", err)
+	}
+
+	if werfConfig == nil {
+		return filepath.Join(giterminismManager.ProjectDir(), ".helm"), nil
+	}
+
+	chartDir, err := GetHelmChartDir(werfConfigPath, werfConfig, giterminismManager)
+	if err != nil {
+		return "", fmt.Errorf("getting helm chart dir failed: %w", err)
+	}
+
+	return filepath.Join(giterminismManager.ProjectDir(), chartDir), nil
+}
+
 func SetupParallelOptions(cmdData *CmdData, cmd *cobra.Command, defaultValue int64) {
 	SetupParallel(cmdData, cmd)
 	SetupParallelTasksLimit(cmdData, cmd, defaultValue)
@@ -1043,6 +1295,30 @@ func GetGiterminismManager(ctx context.Context, cmdData *CmdData) (giterminism_m
 	})
 }
 
+// GetGiterminismManagerForRevision returns the giterminism manager for the specified revision of the project git repository instead of HEAD
+func GetGiterminismManagerForRevision(ctx context.Context, cmdData *CmdData, revision string) (giterminism_manager.Interface, error) {
+	workingDir := GetWorkingDir(cmdData)
+
+	gitWorkTree, err := GetGitWorkTree(ctx, cmdData, workingDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get git work tree: %w", err)
+	}
+
+	commit, err := true_git.ResolveCommit(ctx, gitWorkTree, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	localGitRepo, err := git_repo.OpenLocalRepo(ctx, "own", gitWorkTree, git_repo.OpenLocalRepoOptions{HeadCommit: commit})
+	if err != nil {
+		return nil, err
+	}
+
+	return giterminism_manager.NewManager(ctx, workingDir, localGitRepo, commit, giterminism_manager.NewManagerOptions{
+		LooseGiterminism: *cmdData.LooseGiterminism,
+	})
+}
+
 func GetGitWorkTree(ctx context.Context, cmdData *CmdData, workingDir string) (string, error) {
 	if *cmdData.GitWorkTree != "" {
 		workTree := *cmdData.GitWorkTree
@@ -1318,6 +1594,10 @@ func ProcessLogTerminalWidth(cmdData *CmdData) error {
 }
 
 func DockerRegistryInit(ctx context.Context, cmdData *CmdData) error {
+	if cmdData.RegistryCache != nil && *cmdData.RegistryCache {
+		docker_registry.InitRegistryCache(filepath.Join(werf.GetLocalCacheDir(), "registry"), time.Duration(*cmdData.RegistryCacheTagsTTLSeconds)*time.Second)
+	}
+
 	return docker_registry.Init(ctx, *cmdData.InsecureRegistry, *cmdData.SkipTlsVerifyRegistry)
 }
 
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/werf/werf/pkg/build"
 	"github.com/werf/werf/pkg/build/stage"
@@ -34,9 +37,34 @@ func GetConveyorOptions(commonCmdData *CmdData, imagesToProcess build.ImagesToPr
 		conveyorOptions.TargetPlatforms = platforms
 	}
 
+	if commonCmdData.Reproducible != nil {
+		conveyorOptions.Reproducible = *commonCmdData.Reproducible
+	}
+
+	sourceDateEpoch, err := GetSourceDateEpoch()
+	if err != nil {
+		return build.ConveyorOptions{}, err
+	}
+	conveyorOptions.SourceDateEpoch = sourceDateEpoch
+
 	return conveyorOptions, nil
 }
 
+// GetSourceDateEpoch returns the time from the SOURCE_DATE_EPOCH environment variable or the Unix epoch if it is not set
+func GetSourceDateEpoch() (time.Time, error) {
+	value := os.Getenv("SOURCE_DATE_EPOCH")
+	if value == "" {
+		return time.Unix(0, 0), nil
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: the number of seconds since the Unix epoch expected", value)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
+
 func GetConveyorOptionsWithParallel(commonCmdData *CmdData, imagesToProcess build.ImagesToProcess, buildStagesOptions build.BuildOptions) (build.ConveyorOptions, error) {
 	conveyorOptions, err := GetConveyorOptions(commonCmdData, imagesToProcess)
 	if err != nil {
@@ -51,6 +79,10 @@ func GetConveyorOptionsWithParallel(commonCmdData *CmdData, imagesToProcess buil
 	}
 	conveyorOptions.ParallelTasksLimit = parallelTasksLimit
 
+	if commonCmdData.DistributedBuild != nil {
+		conveyorOptions.DistributedBuild = *commonCmdData.DistributedBuild
+	}
+
 	return conveyorOptions, nil
 }
 
@@ -60,7 +92,15 @@ func GetShouldBeBuiltOptions(commonCmdData *CmdData, giterminismManager gitermin
 		return options, err
 	}
 
-	options = build.ShouldBeBuiltOptions{CustomTagFuncList: customTagFuncList}
+	vulnerabilityScanner, err := GetVulnerabilityScanner(commonCmdData)
+	if err != nil {
+		return options, err
+	}
+
+	options = build.ShouldBeBuiltOptions{
+		CustomTagFuncList:    customTagFuncList,
+		VulnerabilityScanner: vulnerabilityScanner,
+	}
 	return options, nil
 }
 
@@ -75,7 +115,25 @@ func GetBuildOptions(ctx context.Context, commonCmdData *CmdData, giterminismMan
 		return buildOptions, err
 	}
 
+	signer, err := GetSigner(commonCmdData)
+	if err != nil {
+		return buildOptions, err
+	}
+
+	sbomGenerator, err := GetSBOMGenerator(commonCmdData)
+	if err != nil {
+		return buildOptions, err
+	}
+
+	vulnerabilityScanner, err := GetVulnerabilityScanner(commonCmdData)
+	if err != nil {
+		return buildOptions, err
+	}
+
 	buildOptions = build.BuildOptions{
+		Signer:                       signer,
+		SBOMGenerator:                sbomGenerator,
+		VulnerabilityScanner:         vulnerabilityScanner,
 		SkipImageMetadataPublication: *commonCmdData.Dev,
 		CustomTagFuncList:            customTagFuncList,
 		ImageBuildOptions: container_backend.BuildOptions{
@@ -85,6 +143,14 @@ func GetBuildOptions(ctx context.Context, commonCmdData *CmdData, giterminismMan
 		IntrospectOptions: introspectOptions,
 	}
 
+	if commonCmdData.BuildTracePath != nil {
+		buildOptions.TracePath = *commonCmdData.BuildTracePath
+	}
+
+	if commonCmdData.DockerfileLayersCache != nil {
+		buildOptions.DockerfileLayersCache = *commonCmdData.DockerfileLayersCache
+	}
+
 	usedNewBuildReportOption := (commonCmdData.SaveBuildReport != nil && *commonCmdData.SaveBuildReport == true) || (commonCmdData.BuildReportPath != nil && *commonCmdData.BuildReportPath != "")
 
 	usedOldBuildReportOption := (commonCmdData.DeprecatedReportPath != nil && *commonCmdData.DeprecatedReportPath != "") || (commonCmdData.DeprecatedReportFormat != nil && *commonCmdData.DeprecatedReportFormat != "")
@@ -19,6 +19,7 @@ const (
 	WerfDebugAnsibleArgs Env = "WERF_DEBUG_ANSIBLE_ARGS"
 	WerfSecretKey        Env = "WERF_SECRET_KEY"
 	WerfOldSecretKey     Env = "WERF_OLD_SECRET_KEY"
+	WerfSecretKeyEnv     Env = "WERF_SECRET_KEY_<ENV>"
 )
 
 var envDescription = map[Env]string{
@@ -29,6 +30,11 @@ Secret key also can be defined in files:
 * ~/.werf/global_secret_key (globally),
 * .werf_secret_key (per project)`,
 	WerfOldSecretKey: "Use specified old secret key to rotate secrets",
+	WerfSecretKeyEnv: `Use specified secret key to extract the environment-scoped secrets (werfEnvironments) of the environment ENV (upper case, "-" replaced with _).
+
+Secret key of the environment also can be defined in files:
+* ~/.werf/global_secret_key.<env> (globally),
+* .werf_secret_key.<env> (per project)`,
 }
 
 func EnvsDescription(envs ...Env) string {
@@ -51,6 +51,7 @@ func NewBundlesRegistryClient(ctx context.Context, commonCmdData *CmdData) (*bun
 		bundles_registry.ClientOptInsecure(insecure),
 		bundles_registry.ClientOptSkipTlsVerify(skipTlsVerify),
 		bundles_registry.ClientOptWriter(out),
+		bundles_registry.ClientOptOCIArtifact(commonCmdData.OCIArtifact != nil && *commonCmdData.OCIArtifact),
 	)
 }
 
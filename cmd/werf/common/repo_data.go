@@ -56,6 +56,8 @@ func (repoData *RepoData) CreateStagesStorage(ctx context.Context, containerBack
 
 	if addr == storage.LocalStorageAddress {
 		return storage.NewLocalStagesStorage(containerBackend), nil
+	} else if storage.IsOCILayoutStorageAddress(addr) {
+		return storage.NewOCILayoutStagesStorage(strings.TrimPrefix(addr, storage.OCILayoutStorageAddressPrefix), containerBackend), nil
 	} else {
 		dockerRegistry, err := repoData.CreateDockerRegistry(ctx, insecureRegistry, skipTlsVerifyRegistry)
 		if err != nil {
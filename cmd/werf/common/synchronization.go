@@ -150,7 +150,7 @@ func GetSynchronization(ctx context.Context, cmdData *CmdData, projectName strin
 
 	switch {
 	case *cmdData.Synchronization == "":
-		if stagesStorage.Address() == storage.LocalStorageAddress {
+		if stagesStorage.Address() == storage.LocalStorageAddress || storage.IsOCILayoutStorageAddress(stagesStorage.Address()) {
 			return &SynchronizationParams{SynchronizationType: LocalSynchronization, Address: storage.LocalStorageAddress}, nil
 		}
 
@@ -1,7 +1,9 @@
 package converge
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -16,6 +18,8 @@ import (
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
 
 	"github.com/werf/kubedog/pkg/kube"
 	"github.com/werf/logboek"
@@ -29,12 +33,15 @@ import (
 	"github.com/werf/werf/pkg/deploy/helm/command_helpers"
 	"github.com/werf/werf/pkg/deploy/helm/maintenance_helper"
 	"github.com/werf/werf/pkg/deploy/lock_manager"
+	"github.com/werf/werf/pkg/deploy/plan"
 	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/git_repo/gitdata"
 	"github.com/werf/werf/pkg/giterminism_manager"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/ssh_agent"
+	"github.com/werf/werf/pkg/storage"
 	"github.com/werf/werf/pkg/storage/lrumeta"
 	"github.com/werf/werf/pkg/storage/manager"
 	"github.com/werf/werf/pkg/tmp_manager"
@@ -45,8 +52,10 @@ import (
 )
 
 var cmdData struct {
-	Timeout      int
-	AutoRollback bool
+	Timeout            int
+	AutoRollback       bool
+	AutoRollbackPolicy string
+	Plan               bool
 }
 
 var commonCmdData common.CmdData
@@ -70,7 +79,10 @@ func NewCmd(ctx context.Context) *cobra.Command {
 		Short: "Build and push images, then deploy application into Kubernetes",
 		Long:  common.GetLongCommandDescription(GetConvergeDocs().Long),
 		Example: `# Build and deploy current application state into production environment
-werf converge --repo registry.mydomain.com/web --env production`,
+werf converge --repo registry.mydomain.com/web --env production
+
+# Show the changes of the release resources in production environment without deploying
+werf converge --repo registry.mydomain.com/web --env production --plan`,
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
 			common.CmdEnvAnno: common.EnvsDescription(common.WerfDebugAnsibleArgs, common.WerfSecretKey),
@@ -126,6 +138,7 @@ werf converge --repo registry.mydomain.com/web --env production`,
 
 	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, pull and push images into the specified repo, to pull base images")
 	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupRegistryCache(&commonCmdData, cmd)
 	common.SetupInsecureHelmDependencies(&commonCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
 
@@ -154,6 +167,7 @@ werf converge --repo registry.mydomain.com/web --env production`,
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
+	common.SetupSecretBackendConfig(&commonCmdData, cmd)
 
 	commonCmdData.SetupDisableDefaultValues(cmd)
 	commonCmdData.SetupDisableDefaultSecretValues(cmd)
@@ -161,6 +175,8 @@ werf converge --repo registry.mydomain.com/web --env production`,
 
 	common.SetupSaveBuildReport(&commonCmdData, cmd)
 	common.SetupBuildReportPath(&commonCmdData, cmd)
+	common.SetupBuildTracePath(&commonCmdData, cmd)
+	common.SetupDockerfileLayersCache(&commonCmdData, cmd)
 	common.SetupDeprecatedReportPath(&commonCmdData, cmd)
 	common.SetupDeprecatedReportFormat(&commonCmdData, cmd)
 
@@ -174,7 +190,10 @@ werf converge --repo registry.mydomain.com/web --env production`,
 	common.SetupParallelOptions(&commonCmdData, cmd, common.DefaultBuildParallelTasksLimit)
 	common.SetupSkipBuild(&commonCmdData, cmd)
 	common.SetupRequireBuiltImages(&commonCmdData, cmd)
+	common.SetupVerifyKey(&commonCmdData, cmd)
+	common.SetupVulnerabilityScanner(&commonCmdData, cmd)
 	commonCmdData.SetupPlatform(cmd)
+	common.SetupReproducible(&commonCmdData, cmd)
 	common.SetupFollow(&commonCmdData, cmd)
 
 	common.SetupDisableAutoHostCleanup(&commonCmdData, cmd)
@@ -191,6 +210,8 @@ werf converge --repo registry.mydomain.com/web --env production`,
 	cmd.Flags().IntVarP(&cmdData.Timeout, "timeout", "t", int(*defaultTimeout), "Resources tracking timeout in seconds ($WERF_TIMEOUT by default)")
 	cmd.Flags().BoolVarP(&cmdData.AutoRollback, "auto-rollback", "R", util.GetBoolEnvironmentDefaultFalse("WERF_AUTO_ROLLBACK"), "Enable auto rollback of the failed release to the previous deployed release version when current deploy process have failed ($WERF_AUTO_ROLLBACK by default)")
 	cmd.Flags().BoolVarP(&cmdData.AutoRollback, "atomic", "", util.GetBoolEnvironmentDefaultFalse("WERF_ATOMIC"), "Enable auto rollback of the failed release to the previous deployed release version when current deploy process have failed ($WERF_ATOMIC by default)")
+	cmd.Flags().StringVarP(&cmdData.AutoRollbackPolicy, "auto-rollback-policy", "", util.GetFirstExistingEnvVarAsString("WERF_AUTO_ROLLBACK_POLICY"), fmt.Sprintf("Failures which trigger the auto rollback to the last successful release revision: %q (any failure of the deploy process), %q (failure or timeout of the resources tracking) or %q (timeout of the resources tracking). Failures of the resources annotated with %s: \"true\" do not trigger the auto rollback ($WERF_AUTO_ROLLBACK_POLICY or %q by default)", helm.AutoRollbackOnFailure, helm.AutoRollbackOnTrackingFailure, helm.AutoRollbackOnTrackingTimeout, helm.SkipAutoRollbackAnnoName, helm.AutoRollbackOnFailure))
+	cmd.Flags().BoolVarP(&cmdData.Plan, "plan", "", util.GetBoolEnvironmentDefaultFalse("WERF_PLAN"), "Do not deploy, only show the resources of the release which would be created, updated or deleted with the diffs against the live cluster objects verified with the server-side dry-run. Secret values are masked. The images are still built and pushed into the repo before planning unless --require-built-images is specified ($WERF_PLAN by default)")
 
 	return cmd
 }
@@ -272,6 +293,15 @@ func runMain(ctx context.Context, imagesToProcess build.ImagesToProcess) error {
 }
 
 func run(ctx context.Context, containerBackend container_backend.ContainerBackend, giterminismManager giterminism_manager.Interface, imagesToProcess build.ImagesToProcess) error {
+	autoRollbackPolicy := helm.AutoRollbackOnFailure
+	if cmdData.AutoRollbackPolicy != "" {
+		policy, err := helm.ParseAutoRollbackPolicy(cmdData.AutoRollbackPolicy)
+		if err != nil {
+			return fmt.Errorf("bad --auto-rollback-policy: %w", err)
+		}
+		autoRollbackPolicy = policy
+	}
+
 	werfConfigPath, werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, true))
 	if err != nil {
 		return fmt.Errorf("unable to load werf config: %w", err)
@@ -298,6 +328,11 @@ func run(ctx context.Context, containerBackend container_backend.ContainerBacken
 		return err
 	}
 
+	verifier, err := common.GetVerifier(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
 	var imagesInfoGetters []*image.InfoGetter
 	var imagesRepo string
 
@@ -327,6 +362,21 @@ func run(ctx context.Context, containerBackend container_backend.ContainerBacken
 		if err != nil {
 			return err
 		}
+
+		var verificationRegistry docker_registry.Interface
+		if verifier != nil {
+			var imagesStagesStorage storage.StagesStorage = stagesStorage
+			if finalStagesStorage != nil {
+				imagesStagesStorage = finalStagesStorage
+			}
+
+			repoStagesStorage, isRepo := imagesStagesStorage.(*storage.RepoStagesStorage)
+			if !isRepo {
+				return fmt.Errorf("images signature verification is not supported for the %s stages storage: signatures are stored in the container registry, --repo=ADDRESS param with the container registry address required", imagesStagesStorage.String())
+			}
+			verificationRegistry = repoStagesStorage.DockerRegistry
+		}
+
 		useCustomTagFunc, err := common.GetUseCustomTagFunc(&commonCmdData, giterminismManager, werfConfig)
 		if err != nil {
 			return err
@@ -370,6 +420,20 @@ func run(ctx context.Context, containerBackend container_backend.ContainerBacken
 			return err
 		}
 
+		if verifier != nil {
+			digests, err := verifier.VerifyImages(ctx, verificationRegistry, util.MapFuncToSlice(imagesInfoGetters, func(getter *image.InfoGetter) string {
+				return getter.GetName()
+			}))
+			if err != nil {
+				return err
+			}
+
+			// deploy exactly the verified manifests, the tags might be moved after verification
+			for i, getter := range imagesInfoGetters {
+				getter.Digest = digests[i]
+			}
+		}
+
 		logboek.LogOptionalLn()
 	}
 
@@ -413,6 +477,11 @@ func run(ctx context.Context, containerBackend container_backend.ContainerBacken
 		return fmt.Errorf("unable to create helm registry client: %w", err)
 	}
 
+	secretBackend, err := common.GetLocalSecretBackendConfig(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
 	wc := chart_extender.NewWerfChart(ctx, giterminismManager, secretsManager, chartDir, helm_v3.Settings, helmRegistryClient, chart_extender.WerfChartOptions{
 		BuildChartDependenciesOpts:        command_helpers.BuildChartDependenciesOptions{SkipUpdate: *commonCmdData.SkipDependenciesRepoRefresh},
 		SecretValueFiles:                  common.GetSecretValues(&commonCmdData),
@@ -421,6 +490,7 @@ func run(ctx context.Context, containerBackend container_backend.ContainerBacken
 		IgnoreInvalidAnnotationsAndLabels: true,
 		DisableDefaultValues:              *commonCmdData.DisableDefaultValues,
 		DisableDefaultSecretValues:        *commonCmdData.DisableDefaultSecretValues,
+		SecretBackend:                     secretBackend,
 	})
 
 	if err := wc.SetEnv(*commonCmdData.Environment); err != nil {
@@ -458,6 +528,7 @@ func run(ctx context.Context, containerBackend container_backend.ContainerBacken
 		SubchartExtenderFactoryFunc: func() chart.ChartExtender {
 			return chart_extender.NewWerfSubchart(ctx, secretsManager, chart_extender.WerfSubchartOptions{
 				DisableDefaultSecretValues: *commonCmdData.DisableDefaultSecretValues,
+				Environment:                *commonCmdData.Environment,
 			})
 		},
 	}
@@ -473,6 +544,11 @@ func run(ctx context.Context, containerBackend container_backend.ContainerBacken
 	if err != nil {
 		return err
 	}
+
+	if cmdData.Plan {
+		return planRelease(ctx, actionConfig, wc, releaseName, namespace, valueOpts, filepath.Join(giterminismManager.ProjectDir(), chartDir))
+	}
+
 	maintenanceHelper := createMaintenanceHelper(ctx, actionConfig, kubeConfigOptions)
 
 	if err := migrateHelm2ToHelm3(ctx, releaseName, namespace, maintenanceHelper, wc.ChainPostRenderer, valueOpts, filepath.Join(giterminismManager.ProjectDir(), chartDir), helmRegistryClient); err != nil {
@@ -501,7 +577,6 @@ func run(ctx context.Context, containerBackend container_backend.ContainerBacken
 		CreateNamespace:             common.NewBool(true),
 		Install:                     common.NewBool(true),
 		Wait:                        common.NewBool(true),
-		Atomic:                      common.NewBool(cmdData.AutoRollback),
 		Timeout:                     common.NewDuration(time.Duration(cmdData.Timeout) * time.Second),
 		IgnorePending:               common.NewBool(true),
 		CleanupOnFail:               common.NewBool(true),
@@ -510,12 +585,101 @@ func run(ctx context.Context, containerBackend container_backend.ContainerBacken
 
 	return command_helpers.LockReleaseWrapper(ctx, releaseName, lockManager, func() error {
 		if err := helmUpgradeCmd.RunE(helmUpgradeCmd, []string{releaseName, filepath.Join(giterminismManager.ProjectDir(), chartDir)}); err != nil {
+			if cmdData.AutoRollback {
+				return autoRollback(ctx, actionConfig, releaseName, namespace, autoRollbackPolicy, deployReportPath, err)
+			}
 			return fmt.Errorf("helm upgrade have failed: %w", err)
 		}
 		return nil
 	})
 }
 
+// autoRollback rolls back the failed release according to the policy and saves the rollback result into the deploy report
+func autoRollback(ctx context.Context, actionConfig *action.Configuration, releaseName, namespace string, policy helm.AutoRollbackPolicy, deployReportPath *string, upgradeErr error) error {
+	report := helm.AutoRollback(ctx, actionConfig, releaseName, helm.AutoRollbackOptions{
+		Policy:                      policy,
+		Timeout:                     time.Duration(cmdData.Timeout) * time.Second,
+		StagesExternalDepsGenerator: helm.NewStagesExternalDepsGenerator(&actionConfig.RESTClientGetter, &namespace),
+	})
+
+	if deployReportPath != nil {
+		if err := helm.WriteRollbackReport(*deployReportPath, report); err != nil {
+			global_warnings.GlobalWarningLn(ctx, fmt.Sprintf("unable to save auto rollback result into deploy report: %s", err))
+		}
+	}
+
+	switch report.Status {
+	case helm.RollbackSucceeded:
+		return fmt.Errorf("helm upgrade have failed, release %q has been rolled back to revision %d: %w", releaseName, report.Revision, upgradeErr)
+	case helm.RollbackUninstalled:
+		return fmt.Errorf("helm upgrade have failed, release %q without successful revisions has been uninstalled: %w", releaseName, upgradeErr)
+	case helm.RollbackFailed:
+		return fmt.Errorf("helm upgrade have failed: %w\nauto rollback of release %q have failed: %s", upgradeErr, releaseName, report.Error)
+	default:
+		return fmt.Errorf("helm upgrade have failed: %w", upgradeErr)
+	}
+}
+
+// planRelease renders the chart as the upgrade does and prints the changes of the release resources without deploying
+func planRelease(ctx context.Context, actionConfig *action.Configuration, wc *chart_extender.WerfChart, releaseName, namespace string, valueOpts *values.Options, fullChartDir string) error {
+	currentRelease, err := getCurrentRelease(actionConfig, releaseName)
+	if err != nil {
+		return fmt.Errorf("unable to get current release %q: %w", releaseName, err)
+	}
+
+	// the client-only rendering replaces the kube client of the action config with the fake one
+	kubeClient := actionConfig.KubeClient
+
+	var manifest bytes.Buffer
+	if err := logboek.Context(ctx).LogProcess("Rendering release %q", releaseName).DoError(func() error {
+		helmTemplateCmd, _ := helm_v3.NewTemplateCmd(actionConfig, &manifest, helm_v3.TemplateCmdOptions{
+			StagesSplitter:              helm.NewStagesSplitter(),
+			StagesExternalDepsGenerator: helm.NewStagesExternalDepsGenerator(&actionConfig.RESTClientGetter, &namespace),
+			ChainPostRenderer:           wc.ChainPostRenderer,
+			ValueOpts:                   valueOpts,
+			Validate:                    common.NewBool(true),
+			IncludeCrds:                 common.NewBool(true),
+			IsUpgrade:                   common.NewBool(currentRelease != nil),
+		})
+		if err := helmTemplateCmd.RunE(helmTemplateCmd, []string{releaseName, fullChartDir}); err != nil {
+			return fmt.Errorf("helm templates rendering failed: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	planOpts := plan.ReleasePlanOptions{}
+	if currentRelease != nil {
+		planOpts.PreviousManifest = currentRelease.Manifest
+	}
+	if wc.SecretsRuntimeData != nil {
+		planOpts.SecretValues = wc.SecretsRuntimeData.SecretValuesToMask
+	}
+
+	var releasePlan *plan.ReleasePlan
+	if err := logboek.Context(ctx).LogProcess("Planning release %q", releaseName).DoError(func() error {
+		releasePlan, err = plan.BuildReleasePlan(ctx, kubeClient, releaseName, manifest.String(), planOpts)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	return releasePlan.Print(logboek.Context(ctx).OutStream())
+}
+
+// getCurrentRelease returns the release helm upgrades from: the last deployed release or the last release if there is no deployed one, nil if the release does not exist
+func getCurrentRelease(actionConfig *action.Configuration, releaseName string) (*release.Release, error) {
+	rel, err := actionConfig.Releases.Deployed(releaseName)
+	if errors.Is(err, driver.ErrNoDeployedReleases) {
+		rel, err = actionConfig.Releases.Last(releaseName)
+	}
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, nil
+	}
+	return rel, err
+}
+
 func createMaintenanceHelper(ctx context.Context, actionConfig *action.Configuration, kubeConfigOptions kube.KubeConfigOptions) *maintenance_helper.MaintenanceHelper {
 	maintenanceOpts := maintenance_helper.MaintenanceHelperOptions{
 		KubeConfigOptions: kubeConfigOptions,
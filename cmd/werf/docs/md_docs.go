@@ -124,11 +124,7 @@ func fullCommandFilesystemPath(cmd string) string {
 
 func GenCliPages(cmdGroups templates.CommandGroups, pagesDir string) error {
 	for _, group := range cmdGroups {
-		for _, cmd := range group.Commands {
-			if cmd.Hidden {
-				continue
-			}
-
+		for _, cmd := range documentedCommands(group.Commands) {
 			if err := genCliPages(cmd, pagesDir); err != nil {
 				return err
 			}
@@ -188,11 +184,7 @@ cli: &cli
 		}
 
 		indent += 2
-		for _, cmd := range group.Commands {
-			if cmd.Hidden {
-				continue
-			}
-
+		for _, cmd := range documentedCommands(group.Commands) {
 			if err := genCliSidebar(cmd, indent, buf); err != nil {
 				return err
 			}
@@ -208,17 +200,7 @@ cli: &cli
 
 func genCliSidebar(cmd *cobra.Command, indent int, buf *bytes.Buffer) error {
 	if len(cmd.Commands()) == 0 {
-		fullCommandName := fullCommandFilesystemPath(cmd.CommandPath())
-
-		commandRecord := fmt.Sprintf(`
-%[1]s- title: %[2]s
-%[1]s  url: /reference/cli/%[3]s.html
-`, strings.Repeat("  ", indent), cmd.CommandPath(), fullCommandName)
-
-		_, err := buf.WriteString(commandRecord)
-		if err != nil {
-			return err
-		}
+		return genCliSidebarCommandRecord(cmd, indent, buf)
 	} else {
 		groupRecord := fmt.Sprintf(`
 %[1]s- title: %[2]s
@@ -230,6 +212,14 @@ func genCliSidebar(cmd *cobra.Command, indent int, buf *bytes.Buffer) error {
 		}
 
 		indent += 2
+
+		// the command with subcommands could be runnable itself
+		if cmd.Runnable() {
+			if err := genCliSidebarCommandRecord(cmd, indent, buf); err != nil {
+				return err
+			}
+		}
+
 		for _, command := range cmd.Commands() {
 			if cmd.Hidden {
 				continue
@@ -244,6 +234,18 @@ func genCliSidebar(cmd *cobra.Command, indent int, buf *bytes.Buffer) error {
 	return nil
 }
 
+func genCliSidebarCommandRecord(cmd *cobra.Command, indent int, buf *bytes.Buffer) error {
+	fullCommandName := fullCommandFilesystemPath(cmd.CommandPath())
+
+	commandRecord := fmt.Sprintf(`
+%[1]s- title: %[2]s
+%[1]s  url: /reference/cli/%[3]s.html
+`, strings.Repeat("  ", indent), cmd.CommandPath(), fullCommandName)
+
+	_, err := buf.WriteString(commandRecord)
+	return err
+}
+
 func GenCliOverview(cmdGroups templates.CommandGroups, pagesDir string) error {
 	indexPage := `---
 title: Overview of command groups
@@ -261,19 +263,15 @@ toc: false
 
 		indexPage += fmt.Sprintf("%s:\n", group.Message)
 
-		for _, cmd := range group.Commands {
-			if cmd.Hidden {
-				continue
-			}
-
+		for _, cmd := range documentedCommands(group.Commands) {
 			var fullCommandName string
-			if len(cmd.Commands()) == 0 {
+			if len(cmd.Commands()) == 0 || cmd.Runnable() {
 				fullCommandName = fullCommandFilesystemPath(cmd.CommandPath())
 			} else {
 				fullCommandName = fullCommandFilesystemPath(cmd.Commands()[0].CommandPath())
 			}
 
-			indexPage += fmt.Sprintf(" - [werf %s]({{ \"/reference/cli/%s.html\" | true_relative_url }}) — {%% include /reference/cli/%s.short.md %%}.\n", cmd.Name(), fullCommandName, fullCommandName)
+			indexPage += fmt.Sprintf(" - [%s]({{ \"/reference/cli/%s.html\" | true_relative_url }}) — {%% include /reference/cli/%s.short.md %%}.\n", cmd.CommandPath(), fullCommandName, fullCommandName)
 		}
 
 		doNewline = true
@@ -289,7 +287,7 @@ toc: false
 
 func GenCliPartials(cmd *cobra.Command, dir string) error {
 	for _, c := range cmd.Commands() {
-		if cmd.Hidden {
+		if cmd.Hidden && c.Hidden {
 			continue
 		}
 
@@ -355,3 +353,22 @@ func getLongFromCommand(cmd *cobra.Command) string {
 		}
 	}
 }
+
+// documentedCommands returns the visible commands of the group, the hidden command is replaced with its visible subcommands
+func documentedCommands(commands []*cobra.Command) []*cobra.Command {
+	var res []*cobra.Command
+	for _, cmd := range commands {
+		if !cmd.Hidden {
+			res = append(res, cmd)
+			continue
+		}
+
+		for _, subCmd := range cmd.Commands() {
+			if !subCmd.Hidden {
+				res = append(res, subCmd)
+			}
+		}
+	}
+
+	return res
+}
@@ -534,7 +534,13 @@ New key should reside either in the $WERF_SECRET_KEY or .werf_secret_key file.
 Command will extract data with the old key, generate new secret data and rewrite files:
 * standard raw Secret files in the .helm/secret folder;
 * standard secret Values YAML file .helm/secret-values.yaml;
-* additional secret Values YAML files specified with EXTRA_SECRET_VALUES_FILE_PATH params`
+* additional secret Values YAML files specified with EXTRA_SECRET_VALUES_FILE_PATH params.
+
+To re-encrypt secrets with another secret backend, change the secret-backend.yaml file of the chart
+and specify the previous secret backend config with the --old-secret-backend-config option.
+The envelope backend does not use the secret keys.
+
+The environment-scoped secrets (werfEnvironments) are encrypted with the secret keys of the environments and are not regenerated`
 
 	docs.LongMD = "Regenerate Secret files with new Secret key.\n\n" +
 		"Old key should be specified in the `$WERF_OLD_SECRET_KEY`.\n\n" +
@@ -542,7 +548,46 @@ Command will extract data with the old key, generate new secret data and rewrite
 		"Command will extract data with the old key, generate new Secret data and rewrite files:\n" +
 		"* standard raw Secret files in the `.helm/secret folder`;\n" +
 		"* standard Secret Values YAML file `.helm/secret-values.yaml`;\n" +
-		"* additional Secret Values YAML files specified with `EXTRA_SECRET_VALUES_FILE_PATH` params."
+		"* additional Secret Values YAML files specified with `EXTRA_SECRET_VALUES_FILE_PATH` params.\n\n" +
+		"To re-encrypt secrets with another secret backend, change the `secret-backend.yaml` file of the chart " +
+		"and specify the previous secret backend config with the `--old-secret-backend-config` option. " +
+		"The envelope backend does not use the secret keys.\n\n" +
+		"The environment-scoped secrets (`werfEnvironments`) are encrypted with the secret keys of the environments and are not regenerated."
+
+	return docs
+}
+
+func GetHelmSecretCheckDocs() structs.DocsStruct {
+	var docs structs.DocsStruct
+
+	docs.Long = `Check that all secrets of the chart can be decrypted with the current secret key.
+
+Command will try to decrypt:
+* standard raw Secret files in the .helm/secret folder;
+* standard secret Values YAML file .helm/secret-values.yaml;
+* additional secret Values YAML files specified with EXTRA_SECRET_VALUES_FILE_PATH params;
+* secret files and secret Values of the unpacked subcharts in the .helm/charts folder.
+
+Command reports the secret files and the secret values which cannot be decrypted, which are still encrypted
+with the old key from the $WERF_OLD_SECRET_KEY (if specified) and which are duplicated in the plain values.yaml of the chart.
+The environment-scoped secrets (werfEnvironments) are checked with the secret keys of the environments, the secrets
+of the environments without the secret key are skipped with a warning. The packed subcharts (.tgz) in the .helm/charts
+folder are reported as skipped.
+
+Command exits with non-zero code if any problem found`
+
+	docs.LongMD = "Check that all secrets of the chart can be decrypted with the current secret key.\n\n" +
+		"Command will try to decrypt:\n" +
+		"* standard raw Secret files in the `.helm/secret` folder;\n" +
+		"* standard Secret Values YAML file `.helm/secret-values.yaml`;\n" +
+		"* additional Secret Values YAML files specified with `EXTRA_SECRET_VALUES_FILE_PATH` params;\n" +
+		"* Secret files and Secret Values of the unpacked subcharts in the `.helm/charts` folder.\n\n" +
+		"Command reports the Secret files and the Secret values which cannot be decrypted, which are still encrypted " +
+		"with the old key from the `$WERF_OLD_SECRET_KEY` (if specified) and which are duplicated in the plain `values.yaml` of the chart. " +
+		"The environment-scoped secrets (`werfEnvironments`) are checked with the secret keys of the environments, the secrets " +
+		"of the environments without the secret key are skipped with a warning. The packed subcharts (`.tgz`) in the `.helm/charts` " +
+		"folder are reported as skipped.\n\n" +
+		"Command exits with non-zero code if any problem found."
 
 	return docs
 }
@@ -599,10 +644,17 @@ func GetHelmSecretValuesEditDocs() structs.DocsStruct {
 	var docs structs.DocsStruct
 
 	docs.Long = `Edit or create new secret values file.
-Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file.`
+Encryption key should be in $WERF_SECRET_KEY or .werf_secret_key file.
+
+The environment-scoped sections (werfEnvironments.ENV) are encrypted with the secret key of the environment
+from $WERF_SECRET_KEY_<ENV> or .werf_secret_key.<env> file. The sections of the environments without the secret key
+are not shown in the editor and are kept as is.`
 
 	docs.LongMD = "Edit or create new secret values file.\n\n" +
-		"Encryption key should be in `$WERF_SECRET_KEY` or `.werf_secret_key` file."
+		"Encryption key should be in `$WERF_SECRET_KEY` or `.werf_secret_key` file.\n\n" +
+		"The environment-scoped sections (`werfEnvironments.ENV`) are encrypted with the secret key of the environment " +
+		"from `$WERF_SECRET_KEY_<ENV>` or `.werf_secret_key.<env>` file. The sections of the environments without the secret key " +
+		"are not shown in the editor and are kept as is."
 
 	return docs
 }
@@ -80,6 +80,7 @@ func NewGetAutogeneratedValuesCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupDockerConfig(&getAutogeneratedValuedCmdData, cmd, "Command needs granted permissions to read and pull images from the specified repo")
 	common.SetupInsecureRegistry(&getAutogeneratedValuedCmdData, cmd)
+	common.SetupRegistryCache(&getAutogeneratedValuedCmdData, cmd)
 	common.SetupSkipTlsVerifyRegistry(&getAutogeneratedValuedCmdData, cmd)
 
 	common.SetupStubTags(&getAutogeneratedValuedCmdData, cmd)
@@ -87,6 +88,7 @@ func NewGetAutogeneratedValuesCmd(ctx context.Context) *cobra.Command {
 	common.SetupLogOptions(&getAutogeneratedValuedCmdData, cmd)
 
 	getAutogeneratedValuedCmdData.SetupPlatform(cmd)
+	common.SetupReproducible(&getAutogeneratedValuedCmdData, cmd)
 
 	return cmd
 }
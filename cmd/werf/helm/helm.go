@@ -16,6 +16,7 @@ import (
 	"github.com/werf/kubedog/pkg/kube"
 	"github.com/werf/werf/cmd/werf/common"
 	helm2 "github.com/werf/werf/cmd/werf/docs/replacers/helm"
+	helm_secret_check "github.com/werf/werf/cmd/werf/helm/secret/check"
 	helm_secret_decrypt "github.com/werf/werf/cmd/werf/helm/secret/decrypt"
 	helm_secret_encrypt "github.com/werf/werf/cmd/werf/helm/secret/encrypt"
 	helm_secret_file_decrypt "github.com/werf/werf/cmd/werf/helm/secret/file/decrypt"
@@ -267,6 +268,7 @@ func secretCmd(ctx context.Context) *cobra.Command {
 		helm_secret_encrypt.NewCmd(ctx),
 		helm_secret_decrypt.NewCmd(ctx),
 		helm_secret_rotate_secret_key.NewCmd(ctx),
+		helm_secret_check.NewCmd(ctx),
 	)
 
 	return cmd
@@ -0,0 +1,503 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/cmd/werf/docs/replacers/helm"
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/git_repo/gitdata"
+	"github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:                   "check [EXTRA_SECRET_VALUES_FILE_PATH...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Check that all secrets of the chart can be decrypted with the current secret key",
+		Long:                  common.GetLongCommandDescription(helm.GetHelmSecretCheckDocs().Long),
+		Example: `  # Check secrets of the chart and the subcharts
+  $ werf helm secret check
+
+  # Check secrets and find the secrets which are still encrypted with the old key after the rotation
+  $ WERF_OLD_SECRET_KEY=$(cat old_secret_key) werf helm secret check .helm/secret-values-production.yaml`,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretKeyEnv, common.WerfOldSecretKey),
+			common.DocsLongMD: helm.GetHelmSecretCheckDocs().LongMD,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return runCheck(ctx, args...)
+		},
+	})
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismOptions(&commonCmdData, cmd)
+	common.SetupSecretBackendConfig(&commonCmdData, cmd)
+	common.SetupOldSecretBackendConfig(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runCheck(ctx context.Context, extraSecretValuesPaths ...string) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %w", err)
+	}
+
+	gitDataManager, err := gitdata.GetHostGitDataManager(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting host git data manager: %w", err)
+	}
+
+	if err := git_repo.Init(gitDataManager); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(ctx, true_git.Options{LiveGitOutput: *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	giterminismManager, err := common.GetGiterminismManager(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	werfConfigPath, werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, true))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %w", err)
+	}
+
+	helmChartDir, err := common.GetHelmChartDir(werfConfigPath, werfConfig, giterminismManager)
+	if err != nil {
+		return fmt.Errorf("getting helm chart dir failed: %w", err)
+	}
+
+	secretBackend, err := common.GetLocalSecretBackendConfig(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	checker := &secretsChecker{
+		ctx:            ctx,
+		secretsManager: secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{}),
+		projectDir:     giterminismManager.ProjectDir(),
+	}
+
+	if *commonCmdData.OldSecretBackendConfig != "" {
+		if checker.oldSecretBackend, err = secrets_manager.LoadLocalSecretBackendConfig(*commonCmdData.OldSecretBackendConfig); err != nil {
+			return err
+		}
+		checker.checkOldKey = true
+	} else {
+		checker.checkOldKey = os.Getenv(string(common.WerfOldSecretKey)) != ""
+	}
+
+	var extraSecretValuesAbsPaths []string
+	for _, path := range extraSecretValuesPaths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		extraSecretValuesAbsPaths = append(extraSecretValuesAbsPaths, absPath)
+	}
+
+	if err := checker.CheckChart(filepath.Join(giterminismManager.ProjectDir(), helmChartDir), secretBackend, extraSecretValuesAbsPaths); err != nil {
+		return err
+	}
+
+	for _, skipped := range checker.skipped {
+		logboek.Context(ctx).Warn().LogLn(skipped)
+	}
+
+	for _, problem := range checker.problems {
+		logboek.Context(ctx).Default().LogLn(problem)
+	}
+
+	if len(checker.problems) > 0 {
+		return fmt.Errorf("found %d problem(s) in %d secret file(s) and %d secret value(s)", len(checker.problems), checker.checkedFiles, checker.checkedValues)
+	}
+
+	logboek.Context(ctx).Default().LogF("No problems found in %d secret file(s) and %d secret value(s)\n", checker.checkedFiles, checker.checkedValues)
+
+	return nil
+}
+
+type secretsChecker struct {
+	ctx              context.Context
+	secretsManager   *secrets_manager.SecretsManager
+	projectDir       string
+	checkOldKey      bool
+	oldSecretBackend *secrets_manager.SecretBackendConfig
+
+	problems      []string
+	skipped       []string
+	checkedFiles  int
+	checkedValues int
+}
+
+// CheckChart checks the secret files, the secret values and the extra secret values files of the chart and then the unpacked subcharts from the charts directory.
+// The secret backend config of the chart is used if the backend is not specified, the packed subcharts are reported as skipped
+func (c *secretsChecker) CheckChart(chartDir string, backend *secrets_manager.SecretBackendConfig, extraSecretValuesPaths []string) error {
+	if backend == nil {
+		var err error
+		if backend, err = secrets_manager.LoadSecretBackendConfig(filepath.Join(chartDir, secrets_manager.SecretBackendConfigFileName)); err != nil {
+			return err
+		}
+	}
+
+	encoders := &chartEncoders{checker: c, chartDir: chartDir, backend: backend}
+
+	plainValues, err := readPlainValues(filepath.Join(chartDir, "values.yaml"))
+	if err != nil {
+		return err
+	}
+
+	secretDir := filepath.Join(chartDir, "secret")
+	if exist, err := util.DirExists(secretDir); err != nil {
+		return err
+	} else if exist {
+		if err := filepath.Walk(secretDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+
+			// The environment-scoped secret files are in the secret/werfEnvironments/<env> directory
+			var env string
+			if relPath, err := filepath.Rel(filepath.Join(secretDir, secret.EnvironmentsYamlKey), path); err == nil && !strings.HasPrefix(relPath, "..") {
+				env = strings.Split(filepath.ToSlash(relPath), "/")[0]
+			}
+
+			return c.checkSecretFile(encoders, env, path)
+		}); err != nil {
+			return err
+		}
+	}
+
+	secretValuesPaths := extraSecretValuesPaths
+	defaultSecretValuesPath := filepath.Join(chartDir, "secret-values.yaml")
+	if exist, err := util.FileExists(defaultSecretValuesPath); err != nil {
+		return err
+	} else if exist {
+		secretValuesPaths = append([]string{defaultSecretValuesPath}, secretValuesPaths...)
+	}
+
+	for _, path := range secretValuesPaths {
+		if err := c.checkSecretValuesFile(encoders, path, plainValues); err != nil {
+			return err
+		}
+	}
+
+	subchartsDir := filepath.Join(chartDir, "charts")
+	if exist, err := util.DirExists(subchartsDir); err != nil {
+		return err
+	} else if !exist {
+		return nil
+	}
+
+	entries, err := ioutil.ReadDir(subchartsDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		subchartDir := filepath.Join(subchartsDir, entry.Name())
+		if !entry.IsDir() {
+			if strings.HasSuffix(entry.Name(), ".tgz") {
+				c.addSkipped(subchartDir, "packed subchart is not checked, unpack it to check its secrets")
+			}
+			continue
+		}
+
+		if exist, err := util.FileExists(filepath.Join(subchartDir, "Chart.yaml")); err != nil {
+			return err
+		} else if !exist {
+			continue
+		}
+
+		if err := c.CheckChart(subchartDir, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *secretsChecker) checkSecretFile(encoders *chartEncoders, env, path string) error {
+	encoder, err := encoders.Get(env)
+	if err != nil || encoder == nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	c.checkedFiles++
+	if _, problem := c.decrypt(encoders, encoder, env, bytes.TrimSpace(data)); problem != "" {
+		c.addProblem(path, "", problem)
+	}
+
+	return nil
+}
+
+func (c *secretsChecker) checkSecretValuesFile(encoders *chartEncoders, path string, plainValues map[string]string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	c.checkedFiles++
+
+	commonData, environments, err := secret.SplitEnvironmentsYamlData(data)
+	if err != nil {
+		c.addProblem(path, "", fmt.Sprintf("invalid secret values: %s", err))
+		return nil
+	}
+
+	if err := c.checkSecretValues(encoders, "", path, commonData, plainValues); err != nil {
+		return err
+	}
+
+	for _, env := range environments {
+		if err := c.checkSecretValues(encoders, env.Environment, path, env.Data, plainValues); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *secretsChecker) checkSecretValues(encoders *chartEncoders, env, path string, data []byte, plainValues map[string]string) error {
+	values, err := secret.GetYamlValues(data)
+	if err != nil {
+		c.addProblem(path, "", fmt.Sprintf("invalid secret values: %s", err))
+		return nil
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	encoder, err := encoders.Get(env)
+	if err != nil || encoder == nil {
+		return err
+	}
+
+	for _, value := range values {
+		valuePath := value.Path
+		if env != "" {
+			valuePath = fmt.Sprintf("%s.%s.%s", secret.EnvironmentsYamlKey, env, value.Path)
+		}
+
+		c.checkedValues++
+
+		if !value.IsString {
+			c.addProblem(path, valuePath, "not encrypted")
+			continue
+		}
+
+		decryptedValue, problem := c.decrypt(encoders, encoder, env, []byte(value.Value))
+		if problem != "" {
+			c.addProblem(path, valuePath, problem)
+			continue
+		}
+
+		if plainValue, ok := plainValues[value.Path]; ok {
+			if plainValue == string(decryptedValue) {
+				c.addProblem(path, valuePath, "duplicated in plain values.yaml with the same value")
+			} else {
+				c.addProblem(path, valuePath, "duplicated in plain values.yaml")
+			}
+		}
+	}
+
+	return nil
+}
+
+// decrypt returns the decrypted data or the problem description if the data cannot be decrypted with the current secret key
+func (c *secretsChecker) decrypt(encoders *chartEncoders, encoder *secret.YamlEncoder, env string, data []byte) ([]byte, string) {
+	decryptedData, err := encoder.Decrypt(data)
+	if err == nil {
+		return decryptedData, ""
+	}
+
+	if oldEncoder := encoders.GetOld(env); oldEncoder != nil {
+		if _, oldErr := oldEncoder.Decrypt(data); oldErr == nil {
+			return nil, "encrypted with the old secret key"
+		}
+	}
+
+	return nil, fmt.Sprintf("cannot be decrypted with the current secret key: %s", err)
+}
+
+func (c *secretsChecker) addSkipped(path, reason string) {
+	c.skipped = append(c.skipped, fmt.Sprintf("%s: skipped: %s", c.relPath(path), reason))
+}
+
+func (c *secretsChecker) addProblem(path, valuePath, problem string) {
+	path = c.relPath(path)
+
+	if valuePath != "" {
+		c.problems = append(c.problems, fmt.Sprintf("%s: %s: %s", path, valuePath, problem))
+	} else {
+		c.problems = append(c.problems, fmt.Sprintf("%s: %s", path, problem))
+	}
+}
+
+// relPath returns the path relative to the project dir if the path is in the project dir
+func (c *secretsChecker) relPath(path string) string {
+	if relPath, err := filepath.Rel(c.projectDir, path); err == nil && !strings.HasPrefix(relPath, "..") {
+		return relPath
+	}
+	return path
+}
+
+// chartEncoders returns the encoders of the chart secret backend for the common secrets and the environment-scoped secrets
+type chartEncoders struct {
+	checker  *secretsChecker
+	chartDir string
+	backend  *secrets_manager.SecretBackendConfig
+
+	encoders    map[string]*secret.YamlEncoder
+	oldEncoder  *secret.YamlEncoder
+	oldLoaded   bool
+	skippedEnvs map[string]bool
+}
+
+// Get returns nil encoder if the secret key of the environment is not found, the secrets of such environment are not checked
+func (e *chartEncoders) Get(env string) (*secret.YamlEncoder, error) {
+	if enc, ok := e.encoders[env]; ok {
+		return enc, nil
+	}
+	if e.skippedEnvs[env] {
+		return nil, nil
+	}
+
+	backend := e.backend.ForEnvironment(env)
+	if backend.UsesSecretKey() {
+		var err error
+		if env == "" {
+			_, err = secrets_manager.GetRequiredSecretKey(e.checker.projectDir)
+		} else {
+			_, err = secrets_manager.GetRequiredEnvironmentSecretKey(e.checker.projectDir, env)
+		}
+
+		if err != nil {
+			if env == "" || !secrets_manager.IsEncryptionKeyRequiredError(err) {
+				return nil, fmt.Errorf("unable to check secrets of chart %q: unable to load secret key: %w", e.chartDir, err)
+			}
+
+			logboek.Context(e.checker.ctx).Warn().LogF("Secrets of environment %q of chart %q are not checked: %s\n", env, e.chartDir, err)
+
+			if e.skippedEnvs == nil {
+				e.skippedEnvs = make(map[string]bool)
+			}
+			e.skippedEnvs[env] = true
+
+			return nil, nil
+		}
+	}
+
+	var enc *secret.YamlEncoder
+	var err error
+	if env == "" {
+		enc, err = e.checker.secretsManager.GetYamlEncoder(e.checker.ctx, e.checker.projectDir, e.backend)
+	} else {
+		enc, err = e.checker.secretsManager.GetYamlEncoderForEnvironment(e.checker.ctx, e.checker.projectDir, e.backend, env)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to check secrets of chart %q: %w", e.chartDir, err)
+	}
+
+	if e.encoders == nil {
+		e.encoders = make(map[string]*secret.YamlEncoder)
+	}
+	e.encoders[env] = enc
+
+	return enc, nil
+}
+
+// GetOld returns the encoder of the old secret key ($WERF_OLD_SECRET_KEY) to detect the secrets not regenerated after the rotation.
+// The environment-scoped secrets are not checked with the old secret key
+func (e *chartEncoders) GetOld(env string) *secret.YamlEncoder {
+	if env != "" || !e.checker.checkOldKey {
+		return nil
+	}
+
+	if !e.oldLoaded {
+		e.oldLoaded = true
+
+		oldBackend := e.checker.oldSecretBackend
+		if oldBackend == nil {
+			oldBackend = e.backend
+		}
+
+		if enc, err := e.checker.secretsManager.GetYamlEncoderForOldKey(e.checker.ctx, oldBackend); err != nil {
+			logboek.Context(e.checker.ctx).Warn().LogF("Secrets of chart %q are not checked with the old secret key: %s\n", e.chartDir, err)
+		} else {
+			e.oldEncoder = enc
+		}
+	}
+
+	return e.oldEncoder
+}
+
+// readPlainValues returns the scalar values of the values file by the dot-separated paths
+func readPlainValues(path string) (map[string]string, error) {
+	res := make(map[string]string)
+
+	if exist, err := util.FileExists(path); err != nil {
+		return nil, err
+	} else if !exist {
+		return res, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := secret.GetYamlValues(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read values file %q: %w", path, err)
+	}
+
+	for _, value := range values {
+		res[value.Path] = value.Value
+	}
+
+	return res, nil
+}
@@ -0,0 +1,109 @@
+package secret
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func TestSecretsChecker_CheckChart(t *testing.T) {
+	if err := werf.Init(t.TempDir(), t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("WERF_SECRET_KEY", "bfd966688bbe64c1986a356be2d6ba0a")
+	t.Setenv("WERF_OLD_SECRET_KEY", "8a3cf3d4d3b3b77b1a31f9b5cf6e2e8c")
+
+	checker := &secretsChecker{
+		ctx:            context.Background(),
+		secretsManager: secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{}),
+		projectDir:     "testdata",
+		checkOldKey:    true,
+	}
+
+	if err := checker.CheckChart(filepath.Join("testdata", "chart"), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedProblems := []string{
+		"chart/charts/broken-subchart/secret/key: cannot be decrypted with the current secret key",
+		"chart/secret-values.yaml: app.broken: cannot be decrypted with the current secret key",
+		"chart/secret-values.yaml: app.password: duplicated in plain values.yaml with the same value",
+		"chart/secret-values.yaml: app.plain: not encrypted",
+		"chart/secret-values.yaml: app.stale: encrypted with the old secret key",
+		"chart/secret-values.yaml: app.token: duplicated in plain values.yaml",
+		"chart/secret/stale.crt: encrypted with the old secret key",
+	}
+
+	problems := append([]string{}, checker.problems...)
+	sort.Strings(problems)
+
+	if len(problems) != len(expectedProblems) {
+		t.Fatalf("\n[EXPECTED]: %d problems:\n%s\n[GOT]: %d problems:\n%s", len(expectedProblems), strings.Join(expectedProblems, "\n"), len(problems), strings.Join(problems, "\n"))
+	}
+
+	for ind, expected := range expectedProblems {
+		if !strings.HasPrefix(problems[ind], expected) {
+			t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", expected, problems[ind])
+		}
+	}
+
+	expectedSkipped := []string{"chart/charts/packed-1.0.0.tgz: skipped: packed subchart is not checked, unpack it to check its secrets"}
+	if strings.Join(checker.skipped, "\n") != strings.Join(expectedSkipped, "\n") {
+		t.Errorf("\n[EXPECTED]: %q\n[GOT]: %q", expectedSkipped, checker.skipped)
+	}
+
+	// secret-values.yaml of the chart and the subchart, 2 secret files of the chart and 1 secret file of the broken subchart
+	if checker.checkedFiles != 5 {
+		t.Errorf("\n[EXPECTED]: 5 checked files\n[GOT]: %d", checker.checkedFiles)
+	}
+
+	if checker.checkedValues != 6 {
+		t.Errorf("\n[EXPECTED]: 6 checked values\n[GOT]: %d", checker.checkedValues)
+	}
+}
+
+func TestSecretsChecker_CheckChart_secretBackend(t *testing.T) {
+	if err := werf.Init(t.TempDir(), t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("WERF_SECRET_KEY", "bfd966688bbe64c1986a356be2d6ba0a")
+
+	// The secret backend config of the chart is overridden by the specified secret backend config
+	chartDir := t.TempDir()
+	for name, data := range map[string]string{
+		"Chart.yaml": "apiVersion: v2\nname: chart\nversion: 1.0.0\n",
+		secrets_manager.SecretBackendConfigFileName: "backend: age\nage:\n  recipients:\n  - age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj\n",
+		"secret-values.yaml":                        "key: 100015fd2ab84c4194bc86b123331bdd5ddfbfc400112cd5d6c3a51d370794e1e1f1\n",
+	} {
+		if err := os.WriteFile(filepath.Join(chartDir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backend, err := secrets_manager.ParseSecretBackendConfig([]byte("backend: aes\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checker := &secretsChecker{
+		ctx:            context.Background(),
+		secretsManager: secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{}),
+		projectDir:     chartDir,
+	}
+
+	if err := checker.CheckChart(chartDir, backend, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(checker.problems) != 0 || checker.checkedValues != 1 {
+		t.Errorf("\n[EXPECTED]: 1 checked value without problems\n[GOT]: %d checked values with problems %q", checker.checkedValues, checker.problems)
+	}
+}
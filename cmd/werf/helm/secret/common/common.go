@@ -17,6 +17,7 @@ import (
 type GenerateOptions struct {
 	FilePath       string
 	OutputFilePath string
+	Environment    string
 	Values         bool
 }
 
@@ -10,40 +10,35 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/werf/werf/pkg/deploy/secrets_manager"
-	"github.com/werf/werf/pkg/secret"
 )
 
-func SecretFileDecrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir, filePath, outputFilePath string) error {
+func SecretFileDecrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, backend *secrets_manager.SecretBackendConfig, env, filePath, outputFilePath string) error {
 	options := &GenerateOptions{
 		FilePath:       filePath,
 		OutputFilePath: outputFilePath,
+		Environment:    env,
 		Values:         false,
 	}
 
-	return secretDecrypt(ctx, m, workingDir, options)
+	return secretDecrypt(ctx, m, workingDir, backend, options)
 }
 
-func SecretValuesDecrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir, filePath, outputFilePath string) error {
+func SecretValuesDecrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, backend *secrets_manager.SecretBackendConfig, filePath, outputFilePath string) error {
 	options := &GenerateOptions{
 		FilePath:       filePath,
 		OutputFilePath: outputFilePath,
 		Values:         true,
 	}
 
-	return secretDecrypt(ctx, m, workingDir, options)
+	return secretDecrypt(ctx, m, workingDir, backend, options)
 }
 
-func secretDecrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, options *GenerateOptions) error {
+func secretDecrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, backend *secrets_manager.SecretBackendConfig, options *GenerateOptions) error {
 	var encodedData []byte
 	var data []byte
 	var err error
 
-	var encoder *secret.YamlEncoder
-	if enc, err := m.GetYamlEncoder(ctx, workingDir); err != nil {
-		return err
-	} else {
-		encoder = enc
-	}
+	encoders := newSecretEncoders(ctx, m, workingDir, backend)
 
 	if options.FilePath != "" {
 		encodedData, err = ReadFileData(options.FilePath)
@@ -68,11 +63,16 @@ func secretDecrypt(ctx context.Context, m *secrets_manager.SecretsManager, worki
 	encodedData = bytes.TrimSpace(encodedData)
 
 	if options.Values {
-		data, err = encoder.DecryptYamlData(encodedData)
+		data, err = decryptValues(ctx, encoders, encodedData, true)
 		if err != nil {
 			return err
 		}
 	} else {
+		encoder, err := encoders.Get(options.Environment)
+		if err != nil {
+			return err
+		}
+
 		data, err = encoder.Decrypt(encodedData)
 		if err != nil {
 			return err
@@ -18,20 +18,16 @@ import (
 	"github.com/werf/logboek"
 	"github.com/werf/logboek/pkg/style"
 	"github.com/werf/werf/pkg/deploy/secrets_manager"
-	"github.com/werf/werf/pkg/secret"
 	"github.com/werf/werf/pkg/util"
 	"github.com/werf/werf/pkg/werf"
 )
 
-func SecretEdit(ctx context.Context, m *secrets_manager.SecretsManager, workingDir, filePath string, values bool) error {
-	var encoder *secret.YamlEncoder
-	if enc, err := m.GetYamlEncoder(ctx, workingDir); err != nil {
-		return err
-	} else {
-		encoder = enc
-	}
+// SecretEdit edits the secret file encrypted with the secret key of the environment (the common secret key if env is empty)
+// or the secret values file with the common values and the environment-scoped sections (werfEnvironments) decrypted with the available secret keys
+func SecretEdit(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, backend *secrets_manager.SecretBackendConfig, env, filePath string, values bool) error {
+	encoders := newSecretEncoders(ctx, m, workingDir, backend)
 
-	data, encodedData, err := readEditedFile(filePath, values, encoder)
+	data, encodedData, err := readEditedFile(ctx, filePath, values, env, encoders)
 	if err != nil {
 		return err
 	}
@@ -67,11 +63,16 @@ func SecretEdit(ctx context.Context, m *secrets_manager.SecretsManager, workingD
 
 		var newEncodedData []byte
 		if values {
-			newEncodedData, err = encoder.EncryptYamlData(newData)
+			newEncodedData, err = encryptEditedValues(encoders, data, newData, encodedData)
 			if err != nil {
 				return err
 			}
 		} else {
+			encoder, err := encoders.Get(env)
+			if err != nil {
+				return err
+			}
+
 			newEncodedData, err = encoder.Encrypt(newData)
 			if err != nil {
 				return err
@@ -81,13 +82,6 @@ func SecretEdit(ctx context.Context, m *secrets_manager.SecretsManager, workingD
 		}
 
 		if !bytes.Equal(data, newData) {
-			if values {
-				newEncodedData, err = secret.MergeEncodedYaml(data, newData, encodedData, newEncodedData)
-				if err != nil {
-					return fmt.Errorf("unable to merge changed values of encoded yaml: %w", err)
-				}
-			}
-
 			if err := SaveGeneratedData(filePath, newEncodedData); err != nil {
 				return err
 			}
@@ -120,7 +114,7 @@ func SecretEdit(ctx context.Context, m *secrets_manager.SecretsManager, workingD
 	return nil
 }
 
-func readEditedFile(filePath string, values bool, encoder *secret.YamlEncoder) ([]byte, []byte, error) {
+func readEditedFile(ctx context.Context, filePath string, values bool, env string, encoders *secretEncoders) ([]byte, []byte, error) {
 	var data, encodedData []byte
 
 	exist, err := util.FileExists(filePath)
@@ -137,11 +131,16 @@ func readEditedFile(filePath string, values bool, encoder *secret.YamlEncoder) (
 		encodedData = bytes.TrimSpace(encodedData)
 
 		if values {
-			data, err = encoder.DecryptYamlData(encodedData)
+			data, err = decryptValues(ctx, encoders, encodedData, false)
 			if err != nil {
 				return nil, nil, err
 			}
 		} else {
+			encoder, err := encoders.Get(env)
+			if err != nil {
+				return nil, nil, err
+			}
+
 			data, err = encoder.Decrypt(encodedData)
 			if err != nil {
 				return nil, nil, err
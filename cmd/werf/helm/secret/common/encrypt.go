@@ -9,40 +9,35 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/werf/werf/pkg/deploy/secrets_manager"
-	"github.com/werf/werf/pkg/secret"
 )
 
-func SecretFileEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir, filePath, outputFilePath string) error {
+func SecretFileEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, backend *secrets_manager.SecretBackendConfig, env, filePath, outputFilePath string) error {
 	options := &GenerateOptions{
 		FilePath:       filePath,
 		OutputFilePath: outputFilePath,
+		Environment:    env,
 		Values:         false,
 	}
 
-	return secretEncrypt(ctx, m, workingDir, options)
+	return secretEncrypt(ctx, m, workingDir, backend, options)
 }
 
-func SecretValuesEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir, filePath, outputFilePath string) error {
+func SecretValuesEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, backend *secrets_manager.SecretBackendConfig, filePath, outputFilePath string) error {
 	options := &GenerateOptions{
 		FilePath:       filePath,
 		OutputFilePath: outputFilePath,
 		Values:         true,
 	}
 
-	return secretEncrypt(ctx, m, workingDir, options)
+	return secretEncrypt(ctx, m, workingDir, backend, options)
 }
 
-func secretEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, options *GenerateOptions) error {
+func secretEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, backend *secrets_manager.SecretBackendConfig, options *GenerateOptions) error {
 	var data []byte
 	var encodedData []byte
 	var err error
 
-	var encoder *secret.YamlEncoder
-	if enc, err := m.GetYamlEncoder(ctx, workingDir); err != nil {
-		return err
-	} else {
-		encoder = enc
-	}
+	encoders := newSecretEncoders(ctx, m, workingDir, backend)
 
 	switch {
 	case options.FilePath != "":
@@ -64,11 +59,16 @@ func secretEncrypt(ctx context.Context, m *secrets_manager.SecretsManager, worki
 	}
 
 	if options.Values {
-		encodedData, err = encoder.EncryptYamlData(data)
+		encodedData, err = encryptValues(encoders, data)
 		if err != nil {
 			return err
 		}
 	} else {
+		encoder, err := encoders.Get(options.Environment)
+		if err != nil {
+			return err
+		}
+
 		encodedData, err = encoder.Encrypt(data)
 		if err != nil {
 			return err
@@ -0,0 +1,201 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/secret"
+)
+
+// secretEncoders returns the encoders of the common secrets and the environment-scoped secrets (werfEnvironments)
+type secretEncoders struct {
+	ctx        context.Context
+	m          *secrets_manager.SecretsManager
+	workingDir string
+	backend    *secrets_manager.SecretBackendConfig
+
+	encoders map[string]*secret.YamlEncoder
+}
+
+func newSecretEncoders(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, backend *secrets_manager.SecretBackendConfig) *secretEncoders {
+	return &secretEncoders{ctx: ctx, m: m, workingDir: workingDir, backend: backend, encoders: make(map[string]*secret.YamlEncoder)}
+}
+
+// Get returns the encoder of the environment, empty env is for the common secrets
+func (e *secretEncoders) Get(env string) (*secret.YamlEncoder, error) {
+	if enc, ok := e.encoders[env]; ok {
+		return enc, nil
+	}
+
+	var enc *secret.YamlEncoder
+	var err error
+	if env == "" {
+		enc, err = e.m.GetYamlEncoder(e.ctx, e.workingDir, e.backend)
+	} else {
+		enc, err = e.m.GetYamlEncoderForEnvironment(e.ctx, e.workingDir, e.backend, env)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e.encoders[env] = enc
+	return enc, nil
+}
+
+// encryptValues encrypts the common secret values and each environment-scoped section with the encoder of the environment.
+// The common values are not encrypted if there are none, so that the secret key is not required for the environment-scoped values only
+func encryptValues(encoders *secretEncoders, data []byte) ([]byte, error) {
+	return encryptEditedValues(encoders, nil, data, nil)
+}
+
+// encryptEditedValues encrypts the edited secret values preserving the encrypted values which are not changed.
+// The environment-scoped sections of the encoded data which are not in the data (not decrypted due to the missed secret key) are preserved as is
+func encryptEditedValues(encoders *secretEncoders, data, newData, encodedData []byte) ([]byte, error) {
+	oldCommonData, oldEnvironments, err := secret.SplitEnvironmentsYamlData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	newCommonData, newEnvironments, err := secret.SplitEnvironmentsYamlData(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedCommonData, encodedEnvironments, err := secret.SplitEnvironmentsYamlData(encodedData)
+	if err != nil {
+		return nil, err
+	}
+
+	var hiddenEnvironments []*secret.EnvironmentYamlData
+	for _, env := range encodedEnvironments {
+		if findEnvironmentYamlData(oldEnvironments, env.Environment) == nil {
+			hiddenEnvironments = append(hiddenEnvironments, env)
+		}
+	}
+
+	resultCommonData, err := encryptValuesSection(encoders, "", oldCommonData, newCommonData, encodedCommonData)
+	if err != nil {
+		return nil, err
+	}
+
+	var resultEnvironments []*secret.EnvironmentYamlData
+	for _, env := range newEnvironments {
+		if findEnvironmentYamlData(hiddenEnvironments, env.Environment) != nil {
+			return nil, fmt.Errorf("unable to change secret values of environment %q: the secret key of the environment is required", env.Environment)
+		}
+
+		var oldEnvData, encodedEnvData []byte
+		if oldEnv := findEnvironmentYamlData(oldEnvironments, env.Environment); oldEnv != nil {
+			oldEnvData = oldEnv.Data
+		}
+		if encodedEnv := findEnvironmentYamlData(encodedEnvironments, env.Environment); encodedEnv != nil {
+			encodedEnvData = encodedEnv.Data
+		}
+
+		resultEnvData, err := encryptValuesSection(encoders, env.Environment, oldEnvData, env.Data, encodedEnvData)
+		if err != nil {
+			return nil, err
+		}
+
+		resultEnvironments = append(resultEnvironments, &secret.EnvironmentYamlData{Environment: env.Environment, Data: resultEnvData})
+	}
+
+	return secret.JoinEnvironmentsYamlData(resultCommonData, append(resultEnvironments, hiddenEnvironments...))
+}
+
+func encryptValuesSection(encoders *secretEncoders, env string, data, newData, encodedData []byte) ([]byte, error) {
+	if env == "" && isEmptyValues(newData) {
+		return newData, nil
+	}
+
+	encoder, err := encoders.Get(env)
+	if err != nil {
+		return nil, err
+	}
+
+	newEncodedData, err := encoder.EncryptYamlData(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	if data == nil {
+		return newEncodedData, nil
+	}
+
+	newEncodedData, err = secret.MergeEncodedYaml(data, newData, encodedData, newEncodedData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to merge changed values of encoded yaml: %w", err)
+	}
+
+	return newEncodedData, nil
+}
+
+// decryptValues decrypts the common secret values and the environment-scoped sections with the available secret keys.
+// The sections of the environments without the secret key are returned encrypted if keepHidden is true and omitted otherwise
+func decryptValues(ctx context.Context, encoders *secretEncoders, encodedData []byte, keepHidden bool) ([]byte, error) {
+	encodedCommonData, encodedEnvironments, err := secret.SplitEnvironmentsYamlData(encodedData)
+	if err != nil {
+		return nil, err
+	}
+
+	commonData := encodedCommonData
+	if !isEmptyValues(encodedCommonData) {
+		encoder, err := encoders.Get("")
+		if err != nil {
+			return nil, err
+		}
+
+		if commonData, err = encoder.DecryptYamlData(encodedCommonData); err != nil {
+			return nil, err
+		}
+	}
+
+	var environments []*secret.EnvironmentYamlData
+	for _, env := range encodedEnvironments {
+		envData, err := decryptValuesSection(encoders, env)
+		if secrets_manager.IsEncryptionKeyRequiredError(err) {
+			logboek.Context(ctx).Warn().LogF("Secret values of environment %q are not decrypted: %s\n", env.Environment, err)
+
+			if keepHidden {
+				environments = append(environments, env)
+			}
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to decrypt secret values of environment %q: %w", env.Environment, err)
+		}
+
+		environments = append(environments, &secret.EnvironmentYamlData{Environment: env.Environment, Data: envData})
+	}
+
+	return secret.JoinEnvironmentsYamlData(commonData, environments)
+}
+
+func decryptValuesSection(encoders *secretEncoders, env *secret.EnvironmentYamlData) ([]byte, error) {
+	encoder, err := encoders.Get(env.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	return encoder.DecryptYamlData(env.Data)
+}
+
+func findEnvironmentYamlData(environments []*secret.EnvironmentYamlData, env string) *secret.EnvironmentYamlData {
+	for _, environment := range environments {
+		if environment.Environment == env {
+			return environment
+		}
+	}
+	return nil
+}
+
+func isEmptyValues(data []byte) bool {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return false
+	}
+	return len(values) == 0
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/git_repo/gitdata"
 	"github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/true_git"
 	"github.com/werf/werf/pkg/werf"
 )
 
@@ -57,10 +58,14 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	})
 
 	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
 	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
 	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
 
 	common.SetupGiterminismOptions(&commonCmdData, cmd)
+	common.SetupSecretBackendConfig(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
@@ -83,18 +88,27 @@ func runSecretDecrypt(ctx context.Context) error {
 		return err
 	}
 
+	if err := true_git.Init(ctx, true_git.Options{LiveGitOutput: *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
 	workingDir := common.GetWorkingDir(&commonCmdData)
 
-	return secretDecrypt(ctx, secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{}), workingDir)
+	secretBackend, err := common.GetSecretBackendConfig(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	return secretDecrypt(ctx, secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{}), workingDir, secretBackend)
 }
 
-func secretDecrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string) error {
+func secretDecrypt(ctx context.Context, m *secrets_manager.SecretsManager, workingDir string, backend *secrets_manager.SecretBackendConfig) error {
 	var encodedData []byte
 	var data []byte
 	var err error
 
 	var encoder *secret.YamlEncoder
-	if enc, err := m.GetYamlEncoder(ctx, workingDir); err != nil {
+	if enc, err := m.GetYamlEncoder(ctx, workingDir, backend); err != nil {
 		return err
 	} else {
 		encoder = enc
@@ -13,6 +13,7 @@ import (
 	"github.com/werf/werf/pkg/deploy/secrets_manager"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/git_repo/gitdata"
+	"github.com/werf/werf/pkg/true_git"
 	"github.com/werf/werf/pkg/werf"
 )
 
@@ -36,7 +37,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
   $ cat .helm/secret/date | werf helm secret decrypt
   Tue Jun 26 09:58:10 PDT 1990`,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretKeyEnv),
 			common.DocsLongMD: helm.GetHelmSecretFileDecryptDocs().LongMD,
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -66,10 +67,15 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	})
 
 	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
 	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
 	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
 
 	common.SetupGiterminismOptions(&commonCmdData, cmd)
+	common.SetupSecretBackendConfig(&commonCmdData, cmd)
+	common.SetupSecretEnvironment(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
@@ -92,7 +98,16 @@ func runSecretDecrypt(ctx context.Context, filePath string) error {
 		return err
 	}
 
+	if err := true_git.Init(ctx, true_git.Options{LiveGitOutput: *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
 	workingDir := common.GetWorkingDir(&commonCmdData)
 
-	return secret_common.SecretFileDecrypt(ctx, secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{}), workingDir, filePath, CmdData.OutputFilePath)
+	secretBackend, err := common.GetSecretBackendConfig(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	return secret_common.SecretFileDecrypt(ctx, secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{}), workingDir, secretBackend, *commonCmdData.Environment, filePath, CmdData.OutputFilePath)
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/werf/werf/pkg/deploy/secrets_manager"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/git_repo/gitdata"
+	"github.com/werf/werf/pkg/true_git"
 	"github.com/werf/werf/pkg/werf"
 )
 
@@ -27,7 +28,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 		Example: `  # Create/edit existing secret file
   $ werf helm secret file edit .helm/secret/privacy`,
 		Annotations: map[string]string{
-			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
+			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfSecretKeyEnv),
 			common.DocsLongMD: helm.GetHelmSecretFileEditDocs().LongMD,
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -47,10 +48,15 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	})
 
 	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
 	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
 	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
 
 	common.SetupGiterminismOptions(&commonCmdData, cmd)
+	common.SetupSecretBackendConfig(&commonCmdData, cmd)
+	common.SetupSecretEnvironment(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
@@ -71,7 +77,16 @@ func runSecretEdit(ctx context.Context, filePath string) error {
 		return err
 	}
 
+	if err := true_git.Init(ctx, true_git.Options{LiveGitOutput: *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
 	workingDir := common.GetWorkingDir(&commonCmdData)
 
-	return secret_common.SecretEdit(ctx, secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{}), workingDir, filePath, false)
+	secretBackend, err := common.GetSecretBackendConfig(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	return secret_common.SecretEdit(ctx, secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{}), workingDir, secretBackend, *commonCmdData.Environment, filePath, false)
 }
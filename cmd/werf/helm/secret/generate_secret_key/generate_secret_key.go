@@ -11,6 +11,10 @@ import (
 	"github.com/werf/werf/pkg/deploy/secrets_manager"
 )
 
+var cmdData struct {
+	Age bool
+}
+
 var commonCmdData common.CmdData
 
 func NewCmd(ctx context.Context) *cobra.Command {
@@ -27,7 +31,10 @@ func NewCmd(ctx context.Context) *cobra.Command {
   $ export WERF_SECRET_KEY=$(werf helm secret generate-secret-key)
 
   # Save encryption key in .werf_secret_key file
-  $ werf helm secret generate-secret-key > .werf_secret_key`,
+  $ werf helm secret generate-secret-key > .werf_secret_key
+
+  # Save personal age identity for the age secret backend
+  $ werf helm secret generate-secret-key --age > ~/.werf/global_secret_key`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
 				common.PrintHelp(cmd)
@@ -40,10 +47,22 @@ func NewCmd(ctx context.Context) *cobra.Command {
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
+	cmd.Flags().BoolVarP(&cmdData.Age, "age", "", false, "Generate age identity for the age secret backend instead of hex encryption key, the public key of the identity is printed in the comment")
+
 	return cmd
 }
 
 func runGenerateSecretKey() error {
+	if cmdData.Age {
+		data, err := secrets_manager.GenerateAgeSecretKey()
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(string(data))
+		return nil
+	}
+
 	key, err := secrets_manager.GenerateSecretKey()
 	if err != nil {
 		return err
@@ -29,7 +29,7 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	cmd := common.SetCommandContext(ctx, &cobra.Command{
 		Use:                   "rotate-secret-key [EXTRA_SECRET_VALUES_FILE_PATH...]",
 		DisableFlagsInUseLine: true,
-		Short:                 "Regenerate secret files with new secret key",
+		Short:                 "Regenerate secret files with new secret key or secret backend",
 		Long:                  common.GetLongCommandDescription(helm.GetHelmSecretRotateSecretKeyDocs().Long),
 		Annotations: map[string]string{
 			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey, common.WerfOldSecretKey),
@@ -57,6 +57,8 @@ func NewCmd(ctx context.Context) *cobra.Command {
 	common.SetupEnvironment(&commonCmdData, cmd)
 
 	common.SetupGiterminismOptions(&commonCmdData, cmd)
+	common.SetupSecretBackendConfig(&commonCmdData, cmd)
+	common.SetupOldSecretBackendConfig(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
@@ -96,15 +98,33 @@ func runRotateSecretKey(ctx context.Context, cmd *cobra.Command, secretValuesPat
 		return fmt.Errorf("getting helm chart dir failed: %w", err)
 	}
 
+	secretBackend, err := common.GetLocalSecretBackendConfig(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	if secretBackend == nil {
+		if secretBackend, err = secrets_manager.LoadSecretBackendConfig(filepath.Join(giterminismManager.ProjectDir(), helmChartDir, secrets_manager.SecretBackendConfigFileName)); err != nil {
+			return err
+		}
+	}
+
+	oldSecretBackend := secretBackend
+	if *commonCmdData.OldSecretBackendConfig != "" {
+		if oldSecretBackend, err = secrets_manager.LoadLocalSecretBackendConfig(*commonCmdData.OldSecretBackendConfig); err != nil {
+			return err
+		}
+	}
+
 	secretsManager := secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{})
 
-	newEncoder, err := secretsManager.GetYamlEncoder(ctx, giterminismManager.ProjectDir())
+	newEncoder, err := secretsManager.GetYamlEncoder(ctx, giterminismManager.ProjectDir(), secretBackend)
 	if err != nil {
 		common.PrintHelp(cmd)
 		return err
 	}
 
-	oldEncoder, err := secretsManager.GetYamlEncoderForOldKey(ctx)
+	oldEncoder, err := secretsManager.GetYamlEncoderForOldKey(ctx, oldSecretBackend)
 	if err != nil {
 		common.PrintHelp(cmd)
 		return err
@@ -153,6 +173,11 @@ func secretsRegenerate(newEncoder, oldEncoder *secret.YamlEncoder, helmChartDir
 						return err
 					}
 
+					// The environment-scoped secret files are encrypted with the secret keys of the environments and are not rotated
+					if fileInfo.IsDir() && path == filepath.Join(secretDirectory, secret.EnvironmentsYamlKey) {
+						return filepath.SkipDir
+					}
+
 					if !fileInfo.IsDir() {
 						secretFilesPaths = append(secretFilesPaths, path)
 					}
@@ -184,10 +209,31 @@ func secretsRegenerate(newEncoder, oldEncoder *secret.YamlEncoder, helmChartDir
 		return err
 	}
 
+	// The environment-scoped secret values (werfEnvironments) are encrypted with the secret keys of the environments and are not rotated
+	secretValuesEnvironments := map[string][]*secret.EnvironmentYamlData{}
+	for filePath, fileData := range secretValuesFilesData {
+		commonData, environments, err := secret.SplitEnvironmentsYamlData(fileData)
+		if err != nil {
+			return fmt.Errorf("unable to parse secret values file %q: %w", filePath, err)
+		}
+
+		secretValuesFilesData[filePath] = commonData
+		secretValuesEnvironments[filePath] = environments
+	}
+
 	if err := regenerateSecrets(secretValuesFilesData, regeneratedFilesData, oldEncoder.DecryptYamlData, newEncoder.EncryptYamlData); err != nil {
 		return err
 	}
 
+	for filePath, environments := range secretValuesEnvironments {
+		data, err := secret.JoinEnvironmentsYamlData(regeneratedFilesData[filePath], environments)
+		if err != nil {
+			return fmt.Errorf("unable to regenerate secret values file %q: %w", filePath, err)
+		}
+
+		regeneratedFilesData[filePath] = data
+	}
+
 	for filePath, fileData := range regeneratedFilesData {
 		err := logboek.LogProcess(fmt.Sprintf("Saving file %q", filePath)).DoError(func() error {
 			fileData = append(bytes.TrimSpace(fileData), []byte("\n")...)
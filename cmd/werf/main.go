@@ -47,6 +47,8 @@ import (
 	"github.com/werf/werf/cmd/werf/render"
 	"github.com/werf/werf/cmd/werf/run"
 	"github.com/werf/werf/cmd/werf/slugify"
+	stage_explain "github.com/werf/werf/cmd/werf/stage/explain"
+	stage_graph "github.com/werf/werf/cmd/werf/stage/graph"
 	stage_image "github.com/werf/werf/cmd/werf/stage/image"
 	"github.com/werf/werf/cmd/werf/synchronization"
 	"github.com/werf/werf/cmd/werf/version"
@@ -252,6 +254,8 @@ func stageCmd(ctx context.Context) *cobra.Command {
 		Hidden: true,
 	})
 	cmd.AddCommand(
+		stage_explain.NewCmd(ctx),
+		stage_graph.NewCmd(ctx),
 		stage_image.NewCmd(ctx),
 	)
 
@@ -0,0 +1,301 @@
+package explain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/werf/werf/pkg/build"
+	"github.com/werf/werf/pkg/build/digest_inputs"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+)
+
+// comparedStagesGetter returns the image stages digests inputs to compare with and the description of the compared state
+type comparedStagesGetter interface {
+	GetStages(ctx context.Context, imageName, platform string) ([]digest_inputs.Stage, string, error)
+}
+
+type commitComparedStagesGetter struct {
+	commit string
+	stages map[string][]digest_inputs.Stage
+}
+
+func newCommitComparedStagesGetter(commit string, explanations []*build.StageExplanation) *commitComparedStagesGetter {
+	getter := &commitComparedStagesGetter{commit: commit, stages: map[string][]digest_inputs.Stage{}}
+	for _, explanation := range explanations {
+		key := imagePlatformKey(explanation.ImageName, explanation.Platform)
+		getter.stages[key] = append(getter.stages[key], digest_inputs.Stage{
+			Name:   explanation.StageName,
+			Digest: explanation.Digest,
+			Inputs: explanation.Inputs,
+		})
+	}
+
+	return getter
+}
+
+func (getter *commitComparedStagesGetter) GetStages(_ context.Context, imageName, platform string) ([]digest_inputs.Stage, string, error) {
+	return getter.stages[imagePlatformKey(imageName, platform)], fmt.Sprintf("commit %s", getter.commit), nil
+}
+
+type storedComparedStagesGetter struct {
+	stagesStorage storage.PrimaryStagesStorage
+	projectName   string
+	gitWorkTree   string
+	headCommit    string
+}
+
+func newStoredComparedStagesGetter(stagesStorage storage.PrimaryStagesStorage, projectName, gitWorkTree, headCommit string) *storedComparedStagesGetter {
+	return &storedComparedStagesGetter{
+		stagesStorage: stagesStorage,
+		projectName:   projectName,
+		gitWorkTree:   gitWorkTree,
+		headCommit:    headCommit,
+	}
+}
+
+func (getter *storedComparedStagesGetter) GetStages(ctx context.Context, imageName, _ string) ([]digest_inputs.Stage, string, error) {
+	imageMetadataByImageName, _, err := getter.stagesStorage.GetAllAndGroupImageMetadataByImageName(ctx, getter.projectName, []string{imageName})
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to get image %q metadata: %w", imageName, err)
+	}
+
+	stageIDByCommit := map[string]string{}
+	for stageID, commitList := range imageMetadataByImageName[imageName] {
+		for _, commit := range commitList {
+			stageIDByCommit[commit] = stageID
+		}
+	}
+
+	if len(stageIDByCommit) == 0 {
+		return nil, "", nil
+	}
+
+	commit, err := getter.findNearestCommit(stageIDByCommit)
+	if err != nil {
+		return nil, "", err
+	}
+	if commit == "" {
+		return nil, "", nil
+	}
+
+	stageID, err := image.ParseStageID(stageIDByCommit[commit])
+	if err != nil {
+		return nil, "", err
+	}
+
+	if stageID.IsMultiplatform {
+		return nil, "", fmt.Errorf("comparing with the stored multi-platform image built for commit %s is not supported: use --compare-commit=%s instead", commit, commit)
+	}
+
+	desc, err := getter.stagesStorage.GetStageDescription(ctx, getter.projectName, *stageID)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to get stage %s description: %w", stageID.String(), err)
+	}
+	if desc == nil {
+		return nil, "", fmt.Errorf("stage %s of the image built for commit %s not found", stageID.String(), commit)
+	}
+
+	comparedState := fmt.Sprintf("stored image %s built for commit %s", desc.Info.Name, commit)
+
+	metadata, err := getter.stagesStorage.GetDigestInputsMetadata(ctx, getter.projectName, stageID.String())
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to get stage %s digest inputs metadata: %w", stageID.String(), err)
+	}
+	if metadata == nil {
+		return nil, comparedState, fmt.Errorf("%s has no stages digests inputs: the image has been built by the werf version without digests inputs support", comparedState)
+	}
+
+	return metadata.Stages, comparedState, nil
+}
+
+// findNearestCommit walks the history from the head commit and returns the first commit with the image built
+func (getter *storedComparedStagesGetter) findNearestCommit(stageIDByCommit map[string]string) (string, error) {
+	repository, err := git.PlainOpenWithOptions(getter.gitWorkTree, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return "", fmt.Errorf("unable to open git repository %s: %w", getter.gitWorkTree, err)
+	}
+
+	commitIter, err := repository.Log(&git.LogOptions{From: plumbing.NewHash(getter.headCommit)})
+	if err != nil {
+		return "", fmt.Errorf("unable to get git log from %s: %w", getter.headCommit, err)
+	}
+	defer commitIter.Close()
+
+	var nearestCommit string
+	if err := commitIter.ForEach(func(c *object.Commit) error {
+		if _, ok := stageIDByCommit[c.Hash.String()]; ok {
+			nearestCommit = c.Hash.String()
+			return io.EOF
+		}
+		return nil
+	}); err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("unable to walk git log: %w", err)
+	}
+
+	return nearestCommit, nil
+}
+
+func imagePlatformKey(imageName, platform string) string {
+	return fmt.Sprintf("%s/%s", imageName, platform)
+}
+
+func printExplanations(ctx context.Context, explanations []*build.StageExplanation, comparedStages comparedStagesGetter) error {
+	var prevImageKey string
+	var stagesToCompare []digest_inputs.Stage
+	var stageOccurrences map[string]int
+
+	for _, explanation := range explanations {
+		imageKey := imagePlatformKey(explanation.ImageName, explanation.Platform)
+		if imageKey != prevImageKey {
+			prevImageKey = imageKey
+			stageOccurrences = map[string]int{}
+
+			imageName := explanation.ImageName
+			if imageName == "" {
+				imageName = "~"
+			}
+			if explanation.Platform != "" {
+				fmt.Printf("Image %s [%s]\n", imageName, explanation.Platform)
+			} else {
+				fmt.Printf("Image %s\n", imageName)
+			}
+
+			if comparedStages != nil {
+				var comparedState string
+				var err error
+				stagesToCompare, comparedState, err = comparedStages.GetStages(ctx, explanation.ImageName, explanation.Platform)
+				if err != nil {
+					return err
+				}
+
+				if comparedState == "" {
+					fmt.Println("  Nothing to compare with: the image has not been built for the ancestor commits")
+				} else {
+					fmt.Printf("  Compared with %s\n", comparedState)
+				}
+			}
+		}
+
+		stageOccurrences[explanation.StageName]++
+		if cmdData.Stage != "" && cmdData.Stage != explanation.StageName {
+			continue
+		}
+
+		if explanation.Skipped {
+			fmt.Printf("  Stage %s\n", explanation.StageName)
+			fmt.Println("    Skipped: the digest cannot be calculated until the previous stage or the image dependency is built")
+			continue
+		}
+
+		fmt.Printf("  Stage %s (digest %s)\n", explanation.StageName, explanation.Digest)
+		if explanation.Found {
+			fmt.Printf("    Found %s\n", explanation.StageImageName)
+		} else {
+			fmt.Println("    Not found: the stage will be built")
+		}
+
+		if comparedStages == nil || stagesToCompare == nil {
+			for _, input := range explanation.Inputs {
+				printValue("    ", input.Name, input.Value)
+			}
+			continue
+		}
+
+		comparedStage := findStage(stagesToCompare, explanation.StageName, stageOccurrences[explanation.StageName])
+		if comparedStage == nil {
+			fmt.Println("    The stage does not exist in the compared state")
+			continue
+		}
+
+		if comparedStage.Digest == explanation.Digest {
+			fmt.Println("    Digest has not been changed")
+			continue
+		}
+
+		changes := digest_inputs.Diff(comparedStage.Inputs, explanation.Inputs)
+		fmt.Printf("    Digest has been changed (was %s)\n", comparedStage.Digest)
+		for _, change := range changes {
+			printChange("    ", change)
+		}
+	}
+
+	return nil
+}
+
+func findStage(stages []digest_inputs.Stage, name string, occurrence int) *digest_inputs.Stage {
+	var n int
+	for i := range stages {
+		if stages[i].Name == name {
+			n++
+			if n == occurrence {
+				return &stages[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+func printValue(indent, name, value string) {
+	lines := strings.Split(value, "\n")
+	if len(lines) == 1 {
+		fmt.Printf("%s%s: %s\n", indent, name, value)
+		return
+	}
+
+	fmt.Printf("%s%s:\n", indent, name)
+	for _, line := range lines {
+		fmt.Printf("%s    %s\n", indent, line)
+	}
+}
+
+func printChange(indent string, change digest_inputs.Change) {
+	switch change.Type {
+	case digest_inputs.ChangeAdded:
+		printValue(indent+string(change.Type)+" ", change.Name, change.NewValue)
+	case digest_inputs.ChangeRemoved:
+		printValue(indent+string(change.Type)+" ", change.Name, change.OldValue)
+	case digest_inputs.ChangeModified:
+		oldLines := strings.Split(change.OldValue, "\n")
+		newLines := strings.Split(change.NewValue, "\n")
+		if len(oldLines) == 1 && len(newLines) == 1 {
+			fmt.Printf("%s%s %s: %s -> %s\n", indent, change.Type, change.Name, change.OldValue, change.NewValue)
+			return
+		}
+
+		fmt.Printf("%s%s %s:\n", indent, change.Type, change.Name)
+		for _, line := range linesDifference(oldLines, newLines) {
+			fmt.Printf("%s    - %s\n", indent, line)
+		}
+		for _, line := range linesDifference(newLines, oldLines) {
+			fmt.Printf("%s    + %s\n", indent, line)
+		}
+	}
+}
+
+// linesDifference returns the lines of a which are not in b
+func linesDifference(a, b []string) []string {
+	bLines := map[string]int{}
+	for _, line := range b {
+		bLines[line]++
+	}
+
+	var res []string
+	for _, line := range a {
+		if bLines[line] > 0 {
+			bLines[line]--
+			continue
+		}
+		res = append(res, line)
+	}
+
+	return res
+}
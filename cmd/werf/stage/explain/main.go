@@ -0,0 +1,271 @@
+package explain
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/logboek/pkg/level"
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/build"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/git_repo/gitdata"
+	"github.com/werf/werf/pkg/giterminism_manager"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/logging"
+	"github.com/werf/werf/pkg/ssh_agent"
+	"github.com/werf/werf/pkg/storage/lrumeta"
+	"github.com/werf/werf/pkg/storage/manager"
+	"github.com/werf/werf/pkg/tmp_manager"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var cmdData struct {
+	Stage         string
+	CompareCommit string
+	CompareStored bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "explain [options] [IMAGE_NAME]",
+		Short: "Explain stages digests",
+		Long: common.GetLongCommandDescription(`Print the inputs of every stage digest of the image to find out why the stage is rebuilt.
+
+The stages are calculated for the current project state until the first stage which is not found in the repo (this stage will be built). The following stages of the image and the stages of the images based on it are printed as skipped, other images are processed as usual.
+
+With --compare-commit the inputs are compared with the inputs calculated for the specified commit. With --compare-stored the inputs are compared with the inputs stored in the stages storage metadata of the image built for the nearest ancestor commit. Only changed inputs are printed in the compare mode: "+" — added, "-" — removed, "~" — changed.`),
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			logboek.SetAcceptedLevel(level.Error)
+
+			var imageName string
+			if len(args) > 1 {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("%d position argument can be specified, received %d", 1, len(args))
+			} else if len(args) == 1 {
+				imageName = args[0]
+			}
+
+			if cmdData.CompareCommit != "" && cmdData.CompareStored {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("--compare-commit and --compare-stored cannot be used together")
+			}
+
+			return run(ctx, imageName)
+		},
+	})
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	common.SetupSecondaryStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupCacheStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupRepoOptions(&commonCmdData, cmd, common.RepoDataOptions{OptionalRepo: true})
+	common.SetupFinalRepo(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read and pull images from the specified repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupRegistryCache(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeConfigBase64(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	common.SetupVirtualMerge(&commonCmdData, cmd)
+
+	commonCmdData.SetupPlatform(cmd)
+	common.SetupReproducible(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.Stage, "stage", "", os.Getenv("WERF_STAGE"), "Explain only the specified stage (default $WERF_STAGE)")
+	cmd.Flags().StringVarP(&cmdData.CompareCommit, "compare-commit", "", os.Getenv("WERF_COMPARE_COMMIT"), "Compare stages digests inputs with the inputs calculated for the specified git commit, branch or tag (default $WERF_COMPARE_COMMIT)")
+	cmd.Flags().BoolVarP(&cmdData.CompareStored, "compare-stored", "", util.GetBoolEnvironmentDefaultFalse("WERF_COMPARE_STORED"), "Compare stages digests inputs with the inputs stored in the repo for the image built for the nearest ancestor commit (default $WERF_COMPARE_STORED)")
+
+	return cmd
+}
+
+func run(ctx context.Context, imageName string) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %w", err)
+	}
+
+	containerBackend, processCtx, err := common.InitProcessContainerBackend(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+	ctx = processCtx
+
+	gitDataManager, err := gitdata.GetHostGitDataManager(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting host git data manager: %w", err)
+	}
+
+	if err := git_repo.Init(gitDataManager); err != nil {
+		return err
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := lrumeta.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(ctx, true_git.Options{LiveGitOutput: *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(ctx, &commonCmdData); err != nil {
+		return err
+	}
+
+	giterminismManager, err := common.GetGiterminismManager(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, giterminismManager.ProjectDir())
+
+	_, werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, false))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %w", err)
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	if imageName != "" && !werfConfig.HasImage(imageName) {
+		return fmt.Errorf("image %q is not defined in werf.yaml", logging.ImageLogName(imageName, false))
+	}
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %w", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	if err := ssh_agent.Init(ctx, common.GetSSHKey(&commonCmdData)); err != nil {
+		return fmt.Errorf("cannot initialize ssh agent: %w", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logboek.Warn().LogF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	stagesStorage, err := common.GetStagesStorage(ctx, containerBackend, &commonCmdData)
+	if err != nil {
+		return err
+	}
+	finalStagesStorage, err := common.GetOptionalFinalStagesStorage(ctx, containerBackend, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, stagesStorage)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
+	if err != nil {
+		return err
+	}
+	secondaryStagesStorageList, err := common.GetSecondaryStagesStorageList(ctx, stagesStorage, containerBackend, &commonCmdData)
+	if err != nil {
+		return err
+	}
+	cacheStagesStorageList, err := common.GetCacheStagesStorageList(ctx, containerBackend, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	storageManager := manager.NewStorageManager(projectName, stagesStorage, finalStagesStorage, secondaryStagesStorageList, cacheStagesStorageList, storageLockManager)
+
+	explainStages := func(giterminismManager giterminism_manager.Interface) ([]*build.StageExplanation, error) {
+		_, werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, false))
+		if err != nil {
+			return nil, fmt.Errorf("unable to load werf config: %w", err)
+		}
+
+		var imageNameList []string
+		if imageName != "" {
+			imageNameList = append(imageNameList, imageName)
+		}
+
+		conveyorOptions, err := common.GetConveyorOptions(&commonCmdData, build.NewImagesToProcess(imageNameList, false))
+		if err != nil {
+			return nil, err
+		}
+
+		var explanations []*build.StageExplanation
+		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, giterminismManager, giterminismManager.ProjectDir(), projectTmpDir, ssh_agent.SSHAuthSock, containerBackend, storageManager, storageLockManager, conveyorOptions)
+		defer conveyorWithRetry.Terminate()
+
+		if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
+			explanations, err = c.ExplainStages(ctx)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+
+		return explanations, nil
+	}
+
+	explanations, err := explainStages(giterminismManager)
+	if err != nil {
+		return err
+	}
+
+	var comparedStages comparedStagesGetter
+	switch {
+	case cmdData.CompareCommit != "":
+		compareGiterminismManager, err := common.GetGiterminismManagerForRevision(ctx, &commonCmdData, cmdData.CompareCommit)
+		if err != nil {
+			return fmt.Errorf("unable to get giterminism manager for %q: %w", cmdData.CompareCommit, err)
+		}
+
+		compareExplanations, err := explainStages(compareGiterminismManager)
+		if err != nil {
+			return fmt.Errorf("unable to explain stages for %q: %w", cmdData.CompareCommit, err)
+		}
+
+		comparedStages = newCommitComparedStagesGetter(compareGiterminismManager.HeadCommit(), compareExplanations)
+	case cmdData.CompareStored:
+		gitWorkTree, err := common.GetGitWorkTree(ctx, &commonCmdData, common.GetWorkingDir(&commonCmdData))
+		if err != nil {
+			return fmt.Errorf("unable to get git work tree: %w", err)
+		}
+
+		comparedStages = newStoredComparedStagesGetter(stagesStorage, projectName, gitWorkTree, giterminismManager.HeadCommit())
+	}
+
+	return printExplanations(ctx, explanations, comparedStages)
+}
@@ -0,0 +1,233 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/logboek/pkg/level"
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/build"
+	"github.com/werf/werf/pkg/build/stages_graph"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/git_repo/gitdata"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/logging"
+	"github.com/werf/werf/pkg/ssh_agent"
+	"github.com/werf/werf/pkg/storage/lrumeta"
+	"github.com/werf/werf/pkg/storage/manager"
+	"github.com/werf/werf/pkg/tmp_manager"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var cmdData struct {
+	GraphFormat     string
+	WithCacheStatus bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd(ctx context.Context) *cobra.Command {
+	ctx = common.NewContextWithCmdData(ctx, &commonCmdData)
+	cmd := common.SetCommandContext(ctx, &cobra.Command{
+		Use:   "graph [options] [IMAGE_NAME...]",
+		Short: "Print stages graph",
+		Long: common.GetLongCommandDescription(`Print the graph of the images stages in the Graphviz DOT or Mermaid format.
+
+The graph includes the stages of the Stapel and Dockerfile images built for every target platform, the base images, the imports (including Dockerfile COPY --from) and the dependencies of the images.
+
+With --with-cache-status the stages are checked in the repo to show which stages will be built: the stages are checked until the first stage which is not found, the following stages of the image and the images based on it are marked as uncached, the status of other stages is not shown.`),
+		Example: `  # Render the stages graph of all images to SVG
+  $ werf stage graph | dot -Tsvg > stages.svg
+
+  # Print the Mermaid graph of the image stages with the cache status
+  $ werf stage graph --graph-format=mermaid --with-cache-status --repo ghcr.io/example/project backend`,
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			logboek.SetAcceptedLevel(level.Error)
+
+			format, err := stages_graph.ParseFormat(cmdData.GraphFormat)
+			if err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return run(ctx, args, format)
+		},
+	})
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupGitWorkTree(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupEnvironment(&commonCmdData, cmd)
+
+	common.SetupGiterminismOptions(&commonCmdData, cmd)
+
+	common.SetupTmpDir(&commonCmdData, cmd, common.SetupTmpDirOptions{})
+	common.SetupHomeDir(&commonCmdData, cmd, common.SetupHomeDirOptions{})
+	common.SetupSSHKey(&commonCmdData, cmd)
+
+	common.SetupSecondaryStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupCacheStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupRepoOptions(&commonCmdData, cmd, common.RepoDataOptions{OptionalRepo: true})
+	common.SetupFinalRepo(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read and pull images from the specified repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupRegistryCache(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeConfigBase64(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	common.SetupVirtualMerge(&commonCmdData, cmd)
+
+	commonCmdData.SetupPlatform(cmd)
+	common.SetupReproducible(&commonCmdData, cmd)
+
+	defaultGraphFormat := os.Getenv("WERF_GRAPH_FORMAT")
+	if defaultGraphFormat == "" {
+		defaultGraphFormat = string(stages_graph.FormatDOT)
+	}
+	cmd.Flags().StringVarP(&cmdData.GraphFormat, "graph-format", "", defaultGraphFormat, "Graph format: dot or mermaid (default $WERF_GRAPH_FORMAT or dot)")
+	cmd.Flags().BoolVarP(&cmdData.WithCacheStatus, "with-cache-status", "", util.GetBoolEnvironmentDefaultFalse("WERF_WITH_CACHE_STATUS"), "Check the stages in the repo and mark the cached and uncached stages (default $WERF_WITH_CACHE_STATUS)")
+
+	return cmd
+}
+
+func run(ctx context.Context, imageNameList []string, format stages_graph.Format) error {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %w", err)
+	}
+
+	containerBackend, processCtx, err := common.InitProcessContainerBackend(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+	ctx = processCtx
+
+	gitDataManager, err := gitdata.GetHostGitDataManager(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting host git data manager: %w", err)
+	}
+
+	if err := git_repo.Init(gitDataManager); err != nil {
+		return err
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := lrumeta.Init(); err != nil {
+		return err
+	}
+
+	if err := true_git.Init(ctx, true_git.Options{LiveGitOutput: *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(ctx, &commonCmdData); err != nil {
+		return err
+	}
+
+	giterminismManager, err := common.GetGiterminismManager(ctx, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, giterminismManager.ProjectDir())
+
+	_, werfConfig, err := common.GetRequiredWerfConfig(ctx, &commonCmdData, giterminismManager, common.GetWerfConfigOptions(&commonCmdData, false))
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %w", err)
+	}
+
+	projectName := werfConfig.Meta.Project
+
+	for _, imageName := range imageNameList {
+		if !werfConfig.HasImage(imageName) {
+			return fmt.Errorf("image %q is not defined in werf.yaml", logging.ImageLogName(imageName, false))
+		}
+	}
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return fmt.Errorf("getting project tmp dir failed: %w", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	if err := ssh_agent.Init(ctx, common.GetSSHKey(&commonCmdData)); err != nil {
+		return fmt.Errorf("cannot initialize ssh agent: %w", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logboek.Warn().LogF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	stagesStorage, err := common.GetStagesStorage(ctx, containerBackend, &commonCmdData)
+	if err != nil {
+		return err
+	}
+	finalStagesStorage, err := common.GetOptionalFinalStagesStorage(ctx, containerBackend, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, stagesStorage)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
+	if err != nil {
+		return err
+	}
+	secondaryStagesStorageList, err := common.GetSecondaryStagesStorageList(ctx, stagesStorage, containerBackend, &commonCmdData)
+	if err != nil {
+		return err
+	}
+	cacheStagesStorageList, err := common.GetCacheStagesStorageList(ctx, containerBackend, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	storageManager := manager.NewStorageManager(projectName, stagesStorage, finalStagesStorage, secondaryStagesStorageList, cacheStagesStorageList, storageLockManager)
+
+	conveyorOptions, err := common.GetConveyorOptions(&commonCmdData, common.GetImagesToProcess(imageNameList, false))
+	if err != nil {
+		return err
+	}
+
+	var graph *stages_graph.Graph
+	conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, giterminismManager, giterminismManager.ProjectDir(), projectTmpDir, ssh_agent.SSHAuthSock, containerBackend, storageManager, storageLockManager, conveyorOptions)
+	defer conveyorWithRetry.Terminate()
+
+	if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
+		graph, err = c.GetStagesGraph(ctx, build.StagesGraphOptions{WithCacheStatus: cmdData.WithCacheStatus})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	fmt.Print(string(graph.Render(format)))
+
+	return nil
+}
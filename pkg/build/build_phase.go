@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -19,6 +20,7 @@ import (
 	"github.com/werf/logboek"
 	"github.com/werf/logboek/pkg/style"
 	"github.com/werf/logboek/pkg/types"
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/build/image"
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/container_backend"
@@ -27,10 +29,13 @@ import (
 	"github.com/werf/werf/pkg/git_repo"
 	imagePkg "github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/logging"
+	"github.com/werf/werf/pkg/sbom"
+	"github.com/werf/werf/pkg/signing"
 	"github.com/werf/werf/pkg/stapel"
 	"github.com/werf/werf/pkg/storage"
 	"github.com/werf/werf/pkg/storage/manager"
 	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/vulnerability_scan"
 	"github.com/werf/werf/pkg/werf"
 )
 
@@ -45,9 +50,20 @@ type BuildOptions struct {
 
 	ReportPath   string
 	ReportFormat ReportFormat
+	// TracePath is the path to save the images and stages build timeline in the Chrome trace event format
+	TracePath string
 
 	SkipImageMetadataPublication bool
 	CustomTagFuncList            []imagePkg.CustomTagFunc
+
+	// Signer signs the final images pushed into the container registry if specified
+	Signer *signing.Signer
+	// SBOMGenerator generates SBOMs for the final images pushed into the container registry if specified
+	SBOMGenerator *sbom.Generator
+	// VulnerabilityScanner scans the final images and fails the build if vulnerabilities of the threshold severity or higher are found
+	VulnerabilityScanner *vulnerability_scan.Scanner
+	// DockerfileLayersCache enables pulling and pushing the intermediate layers cache of the Dockerfile images built by Buildah
+	DockerfileLayersCache bool
 }
 
 type IntrospectOptions struct {
@@ -84,6 +100,20 @@ type BuildPhase struct {
 	StagesIterator *StagesIterator
 	ImagesReport   *ImagesReport
 
+	sbomArtifacts map[string][]*sbom.Artifact
+	// vulnerabilityScanResults are the scan results of the final images by werf image name and target platform
+	vulnerabilityScanResults map[string]map[string]*vulnerability_scan.Result
+
+	imageBuild  *imageBuildRecord
+	stageRecord *ReportStageRecord
+
+	// imageStagesDigestInputs are the digest inputs of the current image stages, stored in the digest inputs metadata of the image last stage
+	imageStagesDigestInputs []digest_inputs.Stage
+	// imageLastStageBuilt is set if the last processed stage of the current image has been built or fetched from the secondary stages storage
+	imageLastStageBuilt bool
+	// explanations are collected instead of building the missing stages in the explain mode
+	explanations *stagesExplanations
+
 	buildContextArchive container_backend.BuildContextArchiver
 }
 
@@ -98,6 +128,17 @@ type ImagesReport struct {
 	mux              sync.Mutex
 	Images           map[string]ReportImageRecord
 	ImagesByPlatform map[string]map[string]ReportImageRecord
+
+	imageBuilds []*imageBuildRecord
+}
+
+// imageBuildRecord is the timeline of the image build for the target platform
+type imageBuildRecord struct {
+	Name       string
+	Platform   string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Stages     []ReportStageRecord
 }
 
 func NewImagesReport() *ImagesReport {
@@ -123,6 +164,27 @@ func (report *ImagesReport) SetImageByPlatformRecord(targetPlatform, name string
 	report.ImagesByPlatform[name][targetPlatform] = imageRecord
 }
 
+func (report *ImagesReport) addImageBuild(record *imageBuildRecord) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+	report.imageBuilds = append(report.imageBuilds, record)
+}
+
+// getImageStages returns stages of the image built for the target platform or for all target platforms if the platform is not specified
+func (report *ImagesReport) getImageStages(name, targetPlatform string) []ReportStageRecord {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+
+	var stages []ReportStageRecord
+	for _, record := range report.imageBuilds {
+		if record.Name == name && (targetPlatform == "" || record.Platform == targetPlatform) {
+			stages = append(stages, record.Stages...)
+		}
+	}
+
+	return stages
+}
+
 func (report *ImagesReport) ToJsonData() ([]byte, error) {
 	report.mux.Lock()
 	defer report.mux.Unlock()
@@ -173,6 +235,67 @@ type ReportImageRecord struct {
 	DockerImageDigest string
 	DockerImageName   string
 	Rebuilt           bool
+	SBOM              []ReportSBOMRecord              `json:",omitempty"`
+	Vulnerabilities   []ReportVulnerabilityScanRecord `json:",omitempty"`
+	Stages            []ReportStageRecord             `json:",omitempty"`
+}
+
+type ReportStageStatus string
+
+const (
+	// ReportStageCached stage has been found in the stages storage
+	ReportStageCached ReportStageStatus = "cached"
+	// ReportStageFetched stage has been copied from the secondary stages storage
+	ReportStageFetched ReportStageStatus = "fetched"
+	// ReportStageBuilt stage has been built
+	ReportStageBuilt ReportStageStatus = "built"
+)
+
+type ReportStageRecord struct {
+	Name                 string
+	Platform             string
+	Digest               string
+	DockerImageName      string
+	Status               ReportStageStatus
+	StartedAt            time.Time
+	DurationSeconds      float64
+	BuildDurationSeconds float64
+	// FetchDurationSeconds includes fetching of the base image (or the previous stage) for the stage build and copying of the stage from the secondary stages storage
+	FetchDurationSeconds float64
+	// PushDurationSeconds includes storing of the built stage into the stages storage and copying of the stage into the cache stages storages
+	PushDurationSeconds float64
+	Size                int64
+	LayerSize           int64
+
+	prevStageSize                              int64
+	buildDuration, fetchDuration, pushDuration time.Duration
+}
+
+type ReportSBOMRecord struct {
+	Platform  string `json:",omitempty"`
+	Format    string
+	MediaType string
+	Reference string
+	Digest    string
+}
+
+func newReportSBOMRecords(artifacts []*sbom.Artifact, platform string) []ReportSBOMRecord {
+	var records []ReportSBOMRecord
+	for _, artifact := range artifacts {
+		if platform != "" && artifact.Platform != "" && artifact.Platform != platform {
+			continue
+		}
+
+		records = append(records, ReportSBOMRecord{
+			Platform:  artifact.Platform,
+			Format:    string(artifact.Format),
+			MediaType: string(artifact.MediaType),
+			Reference: artifact.Reference,
+			Digest:    artifact.Digest,
+		})
+	}
+
+	return records
 }
 
 func (phase *BuildPhase) Name() string {
@@ -183,10 +306,47 @@ func (phase *BuildPhase) BeforeImages(ctx context.Context) error {
 	if err := phase.Conveyor.StorageManager.InitCache(ctx); err != nil {
 		return fmt.Errorf("unable to init storage manager cache: %w", err)
 	}
+
+	// signatures are pushed into the container registry, so the unsupported stages storage is rejected before building
+	if phase.Signer != nil {
+		if _, err := phase.getFinalImagesRepoStagesStorage(); err != nil {
+			return fmt.Errorf("images signing requires the container registry: %w", err)
+		}
+	}
+
+	if phase.DockerfileLayersCache {
+		if _, isBuildah := phase.Conveyor.ContainerBackend.(*container_backend.BuildahBackend); !isBuildah {
+			logboek.Context(ctx).Warn().LogLn("WARNING: Dockerfile layers cache is supported only for the Buildah container backend and will not be used")
+		} else if phase.Conveyor.IsReproducible() {
+			logboek.Context(ctx).Warn().LogLn("WARNING: Dockerfile layers cache is only pulled and not pushed in the reproducible mode")
+		} else if err := phase.putDockerfileLayersCacheRecords(ctx); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (phase *BuildPhase) AfterImages(ctx context.Context) error {
+	if phase.explanations != nil {
+		return nil
+	}
+
+	// the images are scanned before publishing, so the blocked images are not published into the final repo and not tagged
+	if phase.VulnerabilityScanner != nil {
+		if err := phase.scanFinalImages(ctx); err != nil {
+			return err
+		}
+
+		// the report is written anyway to show the found vulnerabilities
+		if err := phase.checkVulnerabilityScanResults(); err != nil {
+			if reportErr := phase.createReport(ctx); reportErr != nil {
+				return reportErr
+			}
+			return err
+		}
+	}
+
 	forcedTargetPlatforms := phase.Conveyor.GetForcedTargetPlatforms()
 	commonTargetPlatforms, err := phase.Conveyor.GetTargetPlatforms()
 	if err != nil {
@@ -290,9 +450,162 @@ func (phase *BuildPhase) AfterImages(ctx context.Context) error {
 		}
 	}
 
+	if phase.SBOMGenerator != nil {
+		if err := phase.generateFinalImagesSBOMs(ctx); err != nil {
+			return err
+		}
+	}
+
+	if phase.Signer != nil {
+		if err := phase.signFinalImages(ctx); err != nil {
+			return err
+		}
+	}
+
 	return phase.createReport(ctx)
 }
 
+func (phase *BuildPhase) signFinalImages(ctx context.Context) error {
+	repoStagesStorage, err := phase.getFinalImagesRepoStagesStorage()
+	if err != nil {
+		return fmt.Errorf("images signing requires the container registry: %w", err)
+	}
+
+	var references []string
+	for _, pair := range phase.getFinalImagesDescriptions() {
+		_, desc := pair.Unpair()
+		references = append(references, desc.Info.Name)
+	}
+
+	if len(references) == 0 {
+		return nil
+	}
+
+	logboek.Context(ctx).Default().LogOptionalLn()
+	if err := phase.Signer.SignImages(ctx, repoStagesStorage.DockerRegistry, references); err != nil {
+		return fmt.Errorf("unable to sign images: %w", err)
+	}
+
+	return nil
+}
+
+func (phase *BuildPhase) generateFinalImagesSBOMs(ctx context.Context) error {
+	repoStagesStorage, err := phase.getFinalImagesRepoStagesStorage()
+	if err != nil {
+		return fmt.Errorf("SBOM generation requires the container registry: %w", err)
+	}
+
+	phase.sbomArtifacts = make(map[string][]*sbom.Artifact)
+	for _, pair := range phase.getFinalImagesDescriptions() {
+		name, desc := pair.Unpair()
+
+		logboek.Context(ctx).Default().LogOptionalLn()
+		if err := logboek.Context(ctx).Default().LogProcess("Generating %s SBOM for image %s", phase.SBOMGenerator.Format, logging.ImageLogName(name, false)).DoError(func() error {
+			artifacts, err := phase.SBOMGenerator.Generate(ctx, repoStagesStorage.DockerRegistry, desc.Info.Name)
+			if err != nil {
+				return err
+			}
+			phase.sbomArtifacts[name] = artifacts
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to generate SBOM for image %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (phase *BuildPhase) getFinalImagesRepoStagesStorage() (*storage.RepoStagesStorage, error) {
+	var stagesStorage storage.StagesStorage = phase.Conveyor.StorageManager.GetStagesStorage()
+	if finalStagesStorage := phase.Conveyor.StorageManager.GetFinalStagesStorage(); finalStagesStorage != nil {
+		stagesStorage = finalStagesStorage
+	}
+
+	repoStagesStorage, isRepo := stagesStorage.(*storage.RepoStagesStorage)
+	if !isRepo {
+		return nil, fmt.Errorf("%s stages storage is not supported, --repo=ADDRESS param with the container registry address required", stagesStorage.String())
+	}
+
+	return repoStagesStorage, nil
+}
+
+// putDockerfileLayersCacheRecords records the layers cache in the stages storages the cache is pushed into before building:
+// the cleanup does not touch the layers cache tags of the repo without the record
+func (phase *BuildPhase) putDockerfileLayersCacheRecords(ctx context.Context) error {
+	stagesStorageList := append([]storage.StagesStorage{phase.Conveyor.StorageManager.GetStagesStorage()}, phase.Conveyor.StorageManager.GetCacheStagesStorageList()...)
+	for _, stagesStorage := range stagesStorageList {
+		repoStagesStorage, isRepo := stagesStorage.(*storage.RepoStagesStorage)
+		if !isRepo {
+			continue
+		}
+
+		if err := repoStagesStorage.PutDockerfileLayersCacheRecord(ctx, phase.Conveyor.ProjectName(), storage.WithCache()); err != nil {
+			return fmt.Errorf("unable to put Dockerfile layers cache record into %s: %w", repoStagesStorage.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// getDockerfileLayersCacheRepos returns the repos of the stages storages to pull and push the Dockerfile layers cache:
+// the cache is pushed into the primary and cache stages storages and also pulled from the secondary ones (local storages are skipped)
+func (phase *BuildPhase) getDockerfileLayersCacheRepos() ([]string, []string) {
+	var cacheFrom, cacheTo []string
+
+	repoAddress := func(stagesStorage storage.StagesStorage) (string, bool) {
+		repoStagesStorage, isRepo := stagesStorage.(*storage.RepoStagesStorage)
+		if !isRepo {
+			return "", false
+		}
+		return repoStagesStorage.RepoAddress, true
+	}
+
+	if repo, ok := repoAddress(phase.Conveyor.StorageManager.GetStagesStorage()); ok {
+		cacheFrom = append(cacheFrom, repo)
+		cacheTo = append(cacheTo, repo)
+	}
+
+	for _, stagesStorage := range phase.Conveyor.StorageManager.GetCacheStagesStorageList() {
+		if repo, ok := repoAddress(stagesStorage); ok {
+			cacheFrom = append(cacheFrom, repo)
+			cacheTo = append(cacheTo, repo)
+		}
+	}
+
+	for _, stagesStorage := range phase.Conveyor.StorageManager.GetSecondaryStagesStorageList() {
+		if repo, ok := repoAddress(stagesStorage); ok {
+			cacheFrom = append(cacheFrom, repo)
+		}
+	}
+
+	return cacheFrom, cacheTo
+}
+
+// getFinalImagesDescriptions returns descriptions of the final images (multiplatform manifest lists for the images built for several platforms) by werf image name
+func (phase *BuildPhase) getFinalImagesDescriptions() []util.Pair[string, *imagePkg.StageDescription] {
+	var res []util.Pair[string, *imagePkg.StageDescription]
+	for _, desc := range phase.Conveyor.imagesTree.GetImagesByName(true) {
+		name, images := desc.Unpair()
+
+		var stageDesc *imagePkg.StageDescription
+		if len(images) == 1 {
+			stageImage := images[0].GetLastNonEmptyStage().GetStageImage().Image
+			if stageDesc = stageImage.GetFinalStageDescription(); stageDesc == nil {
+				stageDesc = stageImage.GetStageDescription()
+			}
+		} else {
+			img := phase.Conveyor.imagesTree.GetMultiplatformImage(name)
+			if stageDesc = img.GetFinalStageDescription(); stageDesc == nil {
+				stageDesc = img.GetStageDescription()
+			}
+		}
+
+		res = append(res, util.NewPair(name, stageDesc))
+	}
+
+	return res
+}
+
 func (phase *BuildPhase) publishFinalImage(ctx context.Context, name string, img *image.Image, finalStagesStorage storage.StagesStorage) error {
 	stg := img.GetLastNonEmptyStage()
 
@@ -469,6 +782,13 @@ func (phase *BuildPhase) createReport(ctx context.Context) error {
 				DockerImageName:   desc.Info.Name,
 				Rebuilt:           img.GetRebuilt(),
 			}
+			if len(targetPlatforms) == 1 {
+				record.SBOM = newReportSBOMRecords(phase.sbomArtifacts[name], "")
+			} else {
+				record.SBOM = newReportSBOMRecords(phase.sbomArtifacts[name], img.TargetPlatform)
+			}
+			record.Vulnerabilities = newReportVulnerabilityScanRecords(phase.vulnerabilityScanResults[name], img.TargetPlatform)
+			record.Stages = phase.ImagesReport.getImageStages(img.GetName(), img.TargetPlatform)
 
 			if os.Getenv("WERF_ENABLE_REPORT_BY_PLATFORM") == "1" {
 				phase.ImagesReport.SetImageByPlatformRecord(img.TargetPlatform, img.GetName(), record)
@@ -479,9 +799,8 @@ func (phase *BuildPhase) createReport(ctx context.Context) error {
 		}
 
 		if _, isLocal := phase.Conveyor.StorageManager.GetStagesStorage().(*storage.LocalStagesStorage); !isLocal {
-			if len(targetPlatforms) > 1 {
-				img := phase.Conveyor.imagesTree.GetMultiplatformImage(name)
-
+			// the multiplatform image is not created if the images are blocked by the vulnerability scan before publishing
+			if img := phase.Conveyor.imagesTree.GetMultiplatformImage(name); len(targetPlatforms) > 1 && img != nil {
 				isRebuilt := false
 				for _, pImg := range img.Images {
 					isRebuilt = (isRebuilt || pImg.GetRebuilt())
@@ -500,6 +819,9 @@ func (phase *BuildPhase) createReport(ctx context.Context) error {
 					DockerImageDigest: desc.Info.RepoDigest,
 					DockerImageName:   desc.Info.Name,
 					Rebuilt:           isRebuilt,
+					SBOM:              newReportSBOMRecords(phase.sbomArtifacts[name], ""),
+					Vulnerabilities:   newReportVulnerabilityScanRecords(phase.vulnerabilityScanResults[name], ""),
+					Stages:            phase.ImagesReport.getImageStages(img.Name, ""),
 				}
 				phase.ImagesReport.SetImageRecord(img.Name, record)
 			}
@@ -530,6 +852,18 @@ func (phase *BuildPhase) createReport(ctx context.Context) error {
 		}
 	}
 
+	if phase.TracePath != "" {
+		data, err := phase.ImagesReport.ToChromeTraceData()
+		if err != nil {
+			return fmt.Errorf("unable to prepare build trace: %w", err)
+		}
+
+		logboek.Context(ctx).Debug().LogF("Writing build trace to the %q\n", phase.TracePath)
+		if err := ioutil.WriteFile(phase.TracePath, data, 0o644); err != nil {
+			return fmt.Errorf("unable to write build trace to %s: %w", phase.TracePath, err)
+		}
+	}
+
 	return nil
 }
 
@@ -539,6 +873,21 @@ func (phase *BuildPhase) ImageProcessingShouldBeStopped(_ context.Context, _ *im
 
 func (phase *BuildPhase) BeforeImageStages(ctx context.Context, img *image.Image) (deferFn func(), err error) {
 	phase.StagesIterator = NewStagesIterator(phase.Conveyor)
+	phase.imageBuild = &imageBuildRecord{Name: img.GetName(), Platform: img.TargetPlatform, StartedAt: time.Now()}
+	phase.imageStagesDigestInputs = nil
+	phase.imageLastStageBuilt = false
+
+	// the base image and the dependencies cannot be resolved without the missing stages of the images the image depends on
+	if phase.explanations != nil && phase.explanations.isStopped(img) {
+		return nil, nil
+	}
+
+	// the secrets files are written by the first stage using them and should not outlive the image build
+	deferFn = func() {
+		if err := img.Secrets.Cleanup(); err != nil {
+			logboek.Context(ctx).Warn().LogF("WARNING: unable to cleanup secrets of image %s: %s\n", img.LogDetailedName(), err)
+		}
+	}
 
 	if err := img.SetupBaseImage(ctx, phase.Conveyor.StorageManager, manager.StorageOptions{
 		ContainerBackend: phase.Conveyor.ContainerBackend,
@@ -557,8 +906,10 @@ func (phase *BuildPhase) BeforeImageStages(ctx context.Context, img *image.Image
 			return nil, fmt.Errorf("unable to create build context archive: %w", err)
 		}
 
+		cleanupSecrets := deferFn
 		deferFn = func() {
 			phase.buildContextArchive.CleanupExtractedDir(ctx)
+			cleanupSecrets()
 		}
 	}
 
@@ -566,8 +917,35 @@ func (phase *BuildPhase) BeforeImageStages(ctx context.Context, img *image.Image
 }
 
 func (phase *BuildPhase) AfterImageStages(ctx context.Context, img *image.Image) error {
+	if phase.explanations != nil && phase.explanations.isStopped(img) {
+		return nil
+	}
+
 	img.SetLastNonEmptyStage(phase.StagesIterator.PrevNonEmptyStage)
 	img.SetContentDigest(phase.StagesIterator.PrevNonEmptyStage.GetContentDigest())
+
+	if phase.imageLastStageBuilt {
+		if err := phase.putImageDigestInputsMetadata(ctx, img); err != nil {
+			return err
+		}
+	}
+
+	phase.imageBuild.FinishedAt = time.Now()
+	phase.ImagesReport.addImageBuild(phase.imageBuild)
+
+	return nil
+}
+
+// putImageDigestInputsMetadata stores the digest inputs of the image stages keyed by the image last stage for werf stage explain --compare-stored.
+// The stage found in the stages storage already has the metadata, so it is only stored for the newly built last stage
+func (phase *BuildPhase) putImageDigestInputsMetadata(ctx context.Context, img *image.Image) error {
+	stageID := img.GetLastNonEmptyStage().GetStageImage().Image.GetStageDescription().StageID.String()
+	metadata := &storage.DigestInputsMetadata{StageID: stageID, Stages: phase.imageStagesDigestInputs}
+
+	if err := phase.Conveyor.StorageManager.GetStagesStorage().PutDigestInputsMetadata(ctx, phase.Conveyor.ProjectName(), metadata); err != nil {
+		return fmt.Errorf("unable to put digest inputs metadata of image %s stage %s: %w", img.LogDetailedName(), stageID, err)
+	}
+
 	return nil
 }
 
@@ -692,16 +1070,40 @@ func (phase *BuildPhase) getPrevNonEmptyStageImageSize() int64 {
 }
 
 func (phase *BuildPhase) OnImageStage(ctx context.Context, img *image.Image, stg stage.Interface) error {
-	return phase.StagesIterator.OnImageStage(ctx, img, stg, func(img *image.Image, stg stage.Interface, isEmpty bool) error {
+	if phase.explanations != nil && phase.explanations.isStopped(img) {
+		phase.explanations.addSkipped(img, stg)
+		return nil
+	}
+
+	err := phase.StagesIterator.OnImageStage(ctx, img, stg, func(img *image.Image, stg stage.Interface, isEmpty bool) error {
 		return phase.onImageStage(ctx, img, stg, isEmpty)
 	})
+	if phase.explanations != nil && errors.Is(err, errStageNotFound) {
+		phase.explanations.stop(img)
+		return nil
+	}
+
+	return err
 }
 
-func (phase *BuildPhase) onImageStage(ctx context.Context, img *image.Image, stg stage.Interface, isEmpty bool) error {
+func (phase *BuildPhase) onImageStage(ctx context.Context, img *image.Image, stg stage.Interface, isEmpty bool) (err error) {
 	if isEmpty {
 		return nil
 	}
 
+	phase.stageRecord = &ReportStageRecord{
+		Name:          string(stg.Name()),
+		Platform:      img.TargetPlatform,
+		Status:        ReportStageCached,
+		StartedAt:     time.Now(),
+		prevStageSize: phase.getPrevNonEmptyStageImageSize(),
+	}
+	defer func() {
+		if err == nil {
+			phase.finishStageRecord(stg)
+		}
+	}()
+
 	if err := stg.FetchDependencies(ctx, phase.Conveyor, phase.Conveyor.ContainerBackend, docker_registry.API()); err != nil {
 		return fmt.Errorf("unable to fetch dependencies for stage %s: %w", stg.LogDetailedName(), err)
 	}
@@ -726,6 +1128,16 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *image.Image, stg
 		return err
 	}
 
+	if phase.explanations != nil {
+		phase.explanations.add(img, stg, foundSuitableStage, phase.imageStagesDigestInputs[len(phase.imageStagesDigestInputs)-1].Inputs)
+		if !foundSuitableStage {
+			return errStageNotFound
+		}
+		return nil
+	}
+
+	phase.imageLastStageBuilt = !foundSuitableStage
+
 	if foundSuitableStage {
 		logboek.Context(ctx).Default().LogFHighlight("Use previously built image for %s\n", stg.LogDetailedName())
 		container_backend.LogImageInfo(ctx, stg.GetStageImage().Image, phase.getPrevNonEmptyStageImageSize(), img.ShouldLogPlatform())
@@ -745,6 +1157,9 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *image.Image, stg
 	if err != nil {
 		return err
 	}
+	if foundSuitableSecondaryStage {
+		phase.stageRecord.Status = ReportStageFetched
+	}
 
 	if !foundSuitableSecondaryStage {
 		if phase.ShouldBeBuiltMode {
@@ -753,12 +1168,17 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *image.Image, stg
 		}
 
 		// Will build a new stage
-		i := phase.Conveyor.GetOrCreateStageImage(uuid.New().String(), phase.StagesIterator.GetPrevImage(img, stg), stg, img)
+		i := phase.Conveyor.GetOrCreateStageImage(phase.newStageImageName(stg), phase.StagesIterator.GetPrevImage(img, stg), stg, img)
 		stg.SetStageImage(i)
 
+		phase.stageRecord.Status = ReportStageBuilt
+
+		fetchStartTime := time.Now()
 		if err := phase.fetchBaseImageForStage(ctx, img, stg); err != nil {
 			return err
 		}
+		phase.stageRecord.fetchDuration += time.Since(fetchStartTime)
+
 		if err := phase.prepareStageInstructions(ctx, img, stg); err != nil {
 			return err
 		}
@@ -798,10 +1218,11 @@ func (phase *BuildPhase) findAndFetchStageFromSecondaryStagesStorage(ctx context
 			defer unlockStage()
 		}
 
+		copyStartTime := time.Now()
 		err := logboek.Context(ctx).Default().LogProcess("Copy suitable stage from secondary %s", secondaryStagesStorage.String()).DoError(func() error {
 			// Copy suitable stage from a secondary stages storage to the primary stages storage
 			// while primary stages storage lock for this digest is held
-			if copiedStageDesc, err := storageManager.CopySuitableByDigestStage(ctx, secondaryStageDesc, secondaryStagesStorage, storageManager.GetStagesStorage(), phase.Conveyor.ContainerBackend, img.TargetPlatform); err != nil {
+			if copiedStageDesc, err := storageManager.CopySuitableByDigestStage(ctx, secondaryStageDesc, secondaryStagesStorage, storageManager.GetStagesStorage(), phase.Conveyor.ContainerBackend, img.GetBuildPlatform()); err != nil {
 				return fmt.Errorf("unable to copy suitable stage %s from %s to %s: %w", secondaryStageDesc.StageID.String(), secondaryStagesStorage.String(), storageManager.GetStagesStorage().String(), err)
 			} else {
 				i := phase.Conveyor.GetOrCreateStageImage(copiedStageDesc.Info.Name, phase.StagesIterator.GetPrevImage(img, stg), stg, img)
@@ -817,9 +1238,15 @@ func (phase *BuildPhase) findAndFetchStageFromSecondaryStagesStorage(ctx context
 		if err != nil {
 			return err
 		}
+		phase.stageRecord.fetchDuration += time.Since(copyStartTime)
 
 		unlockStage()
 
+		pushStartTime := time.Now()
+		defer func() {
+			phase.stageRecord.pushDuration += time.Since(pushStartTime)
+		}()
+
 		if err := storageManager.CopyStageIntoCacheStorages(
 			ctx, *stg.GetStageImage().Image.GetStageDescription().StageID,
 			storageManager.GetCacheStagesStorageList(),
@@ -868,8 +1295,10 @@ func (phase *BuildPhase) fetchBaseImageForStage(ctx context.Context, img *image.
 }
 
 func (phase *BuildPhase) calculateStage(ctx context.Context, img *image.Image, stg stage.Interface) (bool, func(), error) {
+	digestCtx, digestInputsCollector := digest_inputs.NewContext(ctx)
+
 	// FIXME(stapel-to-buildah): store StageImage-s everywhere in stage and build pkgs
-	stageDependencies, err := stg.GetDependencies(ctx, phase.Conveyor, phase.Conveyor.ContainerBackend, phase.StagesIterator.GetPrevImage(img, stg), phase.StagesIterator.GetPrevBuiltImage(img, stg), phase.buildContextArchive)
+	stageDependencies, err := stg.GetDependencies(digestCtx, phase.Conveyor, phase.Conveyor.ContainerBackend, phase.StagesIterator.GetPrevImage(img, stg), phase.StagesIterator.GetPrevBuiltImage(img, stg), phase.buildContextArchive)
 	if err != nil {
 		return false, nil, err
 	}
@@ -881,13 +1310,20 @@ func (phase *BuildPhase) calculateStage(ctx context.Context, img *image.Image, s
 		}
 	}
 	opts.TargetPlatform = img.TargetPlatform
+	opts.BuildPlatform = stg.GetBuildPlatform()
 
-	stageDigest, err := calculateDigest(ctx, stage.GetLegacyCompatibleStageName(stg.Name()), stageDependencies, phase.StagesIterator.PrevNonEmptyStage, phase.Conveyor, opts)
+	stageDigest, err := calculateDigest(digestCtx, stage.GetLegacyCompatibleStageName(stg.Name()), stageDependencies, phase.StagesIterator.PrevNonEmptyStage, phase.Conveyor, opts)
 	if err != nil {
 		return false, nil, err
 	}
 	stg.SetDigest(stageDigest)
 
+	phase.imageStagesDigestInputs = append(phase.imageStagesDigestInputs, digest_inputs.Stage{
+		Name:   string(stg.Name()),
+		Digest: stageDigest,
+		Inputs: digestInputsCollector.Inputs(),
+	})
+
 	logboek.Context(ctx).Info().LogProcessInline("Lock parallel conveyor tasks by stage digest %s", stg.LogDetailedName()).
 		Options(func(options types.LogProcessInlineOptionsInterface) {
 			if !phase.Conveyor.Parallel {
@@ -911,7 +1347,7 @@ func (phase *BuildPhase) calculateStage(ctx context.Context, img *image.Image, s
 		}
 	}
 
-	stageContentSig, err := calculateDigest(ctx, fmt.Sprintf("%s-content", stg.Name()), "", stg, phase.Conveyor, calculateDigestOption{TargetPlatform: img.TargetPlatform})
+	stageContentSig, err := calculateDigest(ctx, fmt.Sprintf("%s-content", stg.Name()), "", stg, phase.Conveyor, calculateDigestOption{TargetPlatform: img.TargetPlatform, BuildPlatform: stg.GetBuildPlatform()})
 	if err != nil {
 		return false, phase.Conveyor.GetStageDigestMutex(stg.GetDigest()).Unlock, fmt.Errorf("unable to calculate stage %s content digest: %w", stg.Name(), err)
 	}
@@ -922,6 +1358,16 @@ func (phase *BuildPhase) calculateStage(ctx context.Context, img *image.Image, s
 	return foundSuitableStage, phase.Conveyor.GetStageDigestMutex(stg.GetDigest()).Unlock, nil
 }
 
+// newStageImageName returns the name of the local image of the stage being built.
+// The name is stored in the stage labels, so it is derived from the stage digest in the reproducible mode.
+func (phase *BuildPhase) newStageImageName(stg stage.Interface) string {
+	if phase.Conveyor.IsReproducible() {
+		return fmt.Sprintf("werf-stage-build-%s", stg.GetDigest())
+	}
+
+	return uuid.New().String()
+}
+
 func (phase *BuildPhase) prepareStageInstructions(ctx context.Context, img *image.Image, stg stage.Interface) error {
 	logboek.Context(ctx).Debug().LogF("-- BuildPhase.prepareStage %s %s\n", img.LogDetailedName(), stg.LogDetailedName())
 
@@ -990,6 +1436,17 @@ func (phase *BuildPhase) prepareStageInstructions(ctx context.Context, img *imag
 		}
 		stageImage.Builder.DockerfileBuilder().AppendLabels(labels...)
 
+		if phase.DockerfileLayersCache {
+			cacheFrom, cacheTo := phase.getDockerfileLayersCacheRepos()
+			stageImage.Builder.DockerfileBuilder().AppendCacheFrom(cacheFrom...)
+
+			// the reproducible layers cache images are created at SOURCE_DATE_EPOCH and do not have the build time,
+			// so the cleanup would not be able to keep the recently built ones
+			if !phase.Conveyor.IsReproducible() {
+				stageImage.Builder.DockerfileBuilder().AppendCacheTo(cacheTo...)
+			}
+		}
+
 		phase.Conveyor.AppendOnTerminateFunc(func() error {
 			return stageImage.Builder.DockerfileBuilder().Cleanup(ctx)
 		})
@@ -1060,13 +1517,16 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *image.I
 		time.Sleep(time.Duration(seconds) * time.Second)
 	}
 
+	buildStartTime := time.Now()
 	if err := logboek.Context(ctx).Streams().DoErrorWithTag(fmt.Sprintf("%s/%s", img.LogName(), stg.Name()), img.LogTagStyle(), func() error {
 		opts := phase.ImageBuildOptions
-		opts.TargetPlatform = img.TargetPlatform
+		opts.TargetPlatform = img.GetBuildPlatform()
+		opts.Timestamp = phase.Conveyor.GetReproducibleTimestamp()
 		return stageImage.Builder.Build(ctx, opts)
 	}); err != nil {
 		return fmt.Errorf("failed to build image for stage %s with digest %s: %w", stg.Name(), stg.GetDigest(), err)
 	}
+	phase.stageRecord.buildDuration = time.Since(buildStartTime)
 
 	if v := os.Getenv("WERF_TEST_ATOMIC_STAGE_BUILD__SLEEP_SECONDS_BEFORE_STAGE_SAVE"); v != "" {
 		seconds := 0
@@ -1111,6 +1571,11 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *image.I
 			return nil
 		}
 
+		pushStartTime := time.Now()
+		defer func() {
+			phase.stageRecord.pushDuration += time.Since(pushStartTime)
+		}()
+
 		// use newly built image
 		newStageImageName, uniqueID := phase.Conveyor.StorageManager.GenerateStageUniqueID(stg.GetDigest(), stages)
 		phase.Conveyor.UnsetStageImage(stageImage.Image.Name())
@@ -1152,6 +1617,23 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *image.I
 	}
 }
 
+func (phase *BuildPhase) finishStageRecord(stg stage.Interface) {
+	record := phase.stageRecord
+	record.Digest = stg.GetDigest()
+	record.DurationSeconds = time.Since(record.StartedAt).Seconds()
+	record.BuildDurationSeconds = record.buildDuration.Seconds()
+	record.FetchDurationSeconds = record.fetchDuration.Seconds()
+	record.PushDurationSeconds = record.pushDuration.Seconds()
+
+	if desc := stg.GetStageImage().Image.GetStageDescription(); desc != nil {
+		record.DockerImageName = desc.Info.Name
+		record.Size = desc.Info.Size
+		record.LayerSize = desc.Info.Size - record.prevStageSize
+	}
+
+	phase.imageBuild.Stages = append(phase.imageBuild.Stages, *record)
+}
+
 func introspectStage(ctx context.Context, s stage.Interface) error {
 	return logboek.Context(ctx).Info().LogProcess("Introspecting stage %s", s.Name()).
 		Options(func(options types.LogProcessOptionsInterface) {
@@ -1171,6 +1653,8 @@ func introspectStage(ctx context.Context, s stage.Interface) error {
 type calculateDigestOption struct {
 	BaseImage      string
 	TargetPlatform string
+	// BuildPlatform is set if the stage is run on the build host platform instead of the target platform
+	BuildPlatform string
 }
 
 func calculateDigest(ctx context.Context, stageName, stageDependencies string, prevNonEmptyStage stage.Interface, conveyor *Conveyor, opts calculateDigestOption) (string, error) {
@@ -1183,6 +1667,17 @@ func calculateDigest(ctx context.Context, stageName, stageDependencies string, p
 		checksumArgsNames = append(checksumArgsNames, "TargetPlatform")
 	}
 
+	if opts.BuildPlatform != "" {
+		checksumArgs = append(checksumArgs, opts.BuildPlatform)
+		checksumArgsNames = append(checksumArgsNames, "BuildPlatform")
+	}
+
+	// reproducible stages are not mixed up with the regular ones and the ones with another timestamp
+	if timestamp := conveyor.GetReproducibleTimestamp(); timestamp != nil {
+		checksumArgs = append(checksumArgs, fmt.Sprintf("reproducible:%d", timestamp.Unix()))
+		checksumArgsNames = append(checksumArgsNames, "SourceDateEpoch")
+	}
+
 	checksumArgs = append(checksumArgs, imagePkg.BuildCacheVersion, stageName, stageDependencies)
 	checksumArgsNames = append(checksumArgsNames,
 		"BuildCacheVersion",
@@ -1210,6 +1705,14 @@ func calculateDigest(ctx context.Context, stageName, stageDependencies string, p
 
 	digest := util.Sha3_224Hash(checksumArgs...)
 
+	for ind, checksumArg := range checksumArgs {
+		// stage dependencies are explained by the inputs recorded by the stage itself
+		if checksumArgsNames[ind] == "StageDependencies" {
+			continue
+		}
+		digest_inputs.Add(ctx, checksumArgsNames[ind], checksumArg)
+	}
+
 	blockMsg := fmt.Sprintf("Stage %s digest %s", stageName, digest)
 	logboek.Context(ctx).Debug().LogBlock(blockMsg).Do(func() {
 		for ind, checksumArg := range checksumArgs {
@@ -0,0 +1,102 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// chromeTrace is the Chrome trace event format (https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU),
+// which can be opened in chrome://tracing or https://ui.perfetto.dev
+type chromeTrace struct {
+	TraceEvents     []chromeTraceEvent `json:"traceEvents"`
+	DisplayTimeUnit string             `json:"displayTimeUnit"`
+}
+
+type chromeTraceEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat,omitempty"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur,omitempty"`
+	PID       int                    `json:"pid"`
+	TID       int                    `json:"tid"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+// ToChromeTraceData returns the timeline of the built images: each image for the target platform is a separate thread
+// with the image build span and spans of its stages, so the critical path of the concurrent build is visible
+func (report *ImagesReport) ToChromeTraceData() ([]byte, error) {
+	report.mux.Lock()
+	defer report.mux.Unlock()
+
+	imageBuilds := make([]*imageBuildRecord, len(report.imageBuilds))
+	copy(imageBuilds, report.imageBuilds)
+	sort.SliceStable(imageBuilds, func(i, j int) bool {
+		return imageBuilds[i].StartedAt.Before(imageBuilds[j].StartedAt)
+	})
+
+	var origin time.Time
+	if len(imageBuilds) > 0 {
+		origin = imageBuilds[0].StartedAt
+	}
+	timestamp := func(t time.Time) int64 {
+		return t.Sub(origin).Microseconds()
+	}
+
+	trace := chromeTrace{TraceEvents: []chromeTraceEvent{}, DisplayTimeUnit: "ms"}
+	for ind, record := range imageBuilds {
+		tid := ind + 1
+		threadName := record.Name
+		if record.Name == "" {
+			threadName = "~"
+		}
+		if record.Platform != "" {
+			threadName = fmt.Sprintf("%s [%s]", threadName, record.Platform)
+		}
+
+		trace.TraceEvents = append(trace.TraceEvents,
+			chromeTraceEvent{Name: "thread_name", Phase: "M", PID: 1, TID: tid, Args: map[string]interface{}{"name": threadName}},
+			chromeTraceEvent{Name: "thread_sort_index", Phase: "M", PID: 1, TID: tid, Args: map[string]interface{}{"sort_index": tid}},
+			chromeTraceEvent{
+				Name:      threadName,
+				Category:  "image",
+				Phase:     "X",
+				Timestamp: timestamp(record.StartedAt),
+				Duration:  record.FinishedAt.Sub(record.StartedAt).Microseconds(),
+				PID:       1,
+				TID:       tid,
+			},
+		)
+
+		for _, stg := range record.Stages {
+			trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+				Name:      stg.Name,
+				Category:  string(stg.Status),
+				Phase:     "X",
+				Timestamp: timestamp(stg.StartedAt),
+				Duration:  time.Duration(stg.DurationSeconds * float64(time.Second)).Microseconds(),
+				PID:       1,
+				TID:       tid,
+				Args: map[string]interface{}{
+					"digest":         stg.Digest,
+					"image":          stg.DockerImageName,
+					"status":         stg.Status,
+					"buildSeconds":   stg.BuildDurationSeconds,
+					"fetchSeconds":   stg.FetchDurationSeconds,
+					"pushSeconds":    stg.PushDurationSeconds,
+					"sizeBytes":      stg.Size,
+					"layerSizeBytes": stg.LayerSize,
+				},
+			})
+		}
+	}
+
+	data, err := json.MarshalIndent(trace, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, []byte("\n")...), nil
+}
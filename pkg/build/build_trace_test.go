@@ -0,0 +1,91 @@
+package build
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+func TestImagesReport_ToChromeTraceData(t *testing.T) {
+	origin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	report := NewImagesReport()
+	report.addImageBuild(&imageBuildRecord{
+		Name:       "frontend",
+		Platform:   "linux/arm64",
+		StartedAt:  origin.Add(500 * time.Millisecond),
+		FinishedAt: origin.Add(3 * time.Second),
+		Stages: []ReportStageRecord{
+			{
+				Name:                 "from",
+				Digest:               "c9a4c0d5b2e6f2b3a0b3a1e5d4c3b2a1f0e9d8c7b6a5f4e3d2c1b0a9",
+				DockerImageName:      "registry.example.com/project:c9a4c0d5b2e6f2b3a0b3a1e5d4c3b2a1f0e9d8c7b6a5f4e3d2c1b0a9-1704067200500",
+				Status:               ReportStageFetched,
+				StartedAt:            origin.Add(500 * time.Millisecond),
+				DurationSeconds:      1.5,
+				FetchDurationSeconds: 1.5,
+				Size:                 1024,
+				LayerSize:            1024,
+			},
+		},
+	})
+	report.addImageBuild(&imageBuildRecord{
+		Name:       "backend",
+		StartedAt:  origin,
+		FinishedAt: origin.Add(2 * time.Second),
+		Stages: []ReportStageRecord{
+			{
+				Name:            "from",
+				Digest:          "2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7",
+				DockerImageName: "registry.example.com/project:2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7-1611836746968",
+				Status:          ReportStageCached,
+				StartedAt:       origin,
+				DurationSeconds: 0.25,
+				Size:            2048,
+				LayerSize:       2048,
+			},
+			{
+				Name:                 "install",
+				Digest:               "a0b12ee0b3b4d1b5d4b0e3ccdb6e7a87a5dbb9b6e3f1c0ad4a5f7c53",
+				DockerImageName:      "registry.example.com/project:a0b12ee0b3b4d1b5d4b0e3ccdb6e7a87a5dbb9b6e3f1c0ad4a5f7c53-1704067200250",
+				Status:               ReportStageBuilt,
+				StartedAt:            origin.Add(250 * time.Millisecond),
+				DurationSeconds:      1.75,
+				BuildDurationSeconds: 1.25,
+				PushDurationSeconds:  0.5,
+				Size:                 4096,
+				LayerSize:            2048,
+			},
+		},
+	})
+	report.addImageBuild(&imageBuildRecord{
+		StartedAt:  origin.Add(2 * time.Second),
+		FinishedAt: origin.Add(2 * time.Second),
+	})
+
+	data, err := report.ToChromeTraceData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goldenPath := filepath.Join("testdata", "chrome_trace.golden.json")
+	if *updateGolden {
+		if err := os.WriteFile(goldenPath, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, expected) {
+		t.Errorf("chrome trace data does not match %s (run with -update to regenerate):\n%s", goldenPath, data)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
 	"github.com/werf/werf/pkg/container_backend/stage_builder"
@@ -154,12 +155,13 @@ func (b *Ansible) stageChecksum(ctx context.Context, userStageName string) strin
 		}
 		checksumArgs = append(checksumArgs, string(jsonOutput))
 	}
+	digest_inputs.Add(ctx, userStageName, checksumArgs...)
 
 	if debugUserStageChecksum() {
 		logboek.Context(ctx).Debug().LogFHighlight("DEBUG: %s stage tasks checksum dependencies %v\n", userStageName, checksumArgs)
 	}
 
-	if stageVersionChecksum := b.stageVersionChecksum(userStageName); stageVersionChecksum != "" {
+	if stageVersionChecksum := b.stageVersionChecksum(ctx, userStageName); stageVersionChecksum != "" {
 		if debugUserStageChecksum() {
 			logboek.Context(ctx).Debug().LogFHighlight("DEBUG: %s stage version checksum %v\n", userStageName, stageVersionChecksum)
 		}
@@ -174,7 +176,7 @@ func (b *Ansible) stageChecksum(ctx context.Context, userStageName string) strin
 	}
 }
 
-func (b *Ansible) stageVersionChecksum(userStageName string) string {
+func (b *Ansible) stageVersionChecksum(ctx context.Context, userStageName string) string {
 	var stageVersionChecksumArgs []string
 
 	cacheVersionFieldName := "CacheVersion"
@@ -186,6 +188,7 @@ func (b *Ansible) stageVersionChecksum(userStageName string) string {
 	}
 
 	if stageChecksum != "" {
+		digest_inputs.Add(ctx, stageCacheVersionFieldName, stageChecksum)
 		stageVersionChecksumArgs = append(stageVersionChecksumArgs, stageChecksum)
 	}
 
@@ -195,6 +198,7 @@ func (b *Ansible) stageVersionChecksum(userStageName string) string {
 	}
 
 	if checksum != "" {
+		digest_inputs.Add(ctx, cacheVersionFieldName, checksum)
 		stageVersionChecksumArgs = append(stageVersionChecksumArgs, checksum)
 	}
 
@@ -10,6 +10,7 @@ import (
 	"gopkg.in/oleiade/reflections.v1"
 
 	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
 	"github.com/werf/werf/pkg/container_backend/stage_builder"
@@ -98,12 +99,13 @@ func (b *Shell) stageChecksum(ctx context.Context, userStageName string) string
 	var checksumArgs []string
 
 	checksumArgs = append(checksumArgs, b.stageCommands(userStageName)...)
+	digest_inputs.Add(ctx, userStageName, checksumArgs...)
 
 	if debugUserStageChecksum() {
 		logboek.Context(ctx).Debug().LogFHighlight("DEBUG: %s stage tasks checksum dependencies %v\n", userStageName, checksumArgs)
 	}
 
-	if stageVersionChecksum := b.stageVersionChecksum(userStageName); stageVersionChecksum != "" {
+	if stageVersionChecksum := b.stageVersionChecksum(ctx, userStageName); stageVersionChecksum != "" {
 		if debugUserStageChecksum() {
 			logboek.Context(ctx).Debug().LogFHighlight("DEBUG: %s stage version checksum %v\n", userStageName, stageVersionChecksum)
 		}
@@ -117,7 +119,7 @@ func (b *Shell) stageChecksum(ctx context.Context, userStageName string) string
 	}
 }
 
-func (b *Shell) stageVersionChecksum(userStageName string) string {
+func (b *Shell) stageVersionChecksum(ctx context.Context, userStageName string) string {
 	var stageVersionChecksumArgs []string
 
 	cacheVersionFieldName := "CacheVersion"
@@ -129,6 +131,7 @@ func (b *Shell) stageVersionChecksum(userStageName string) string {
 	}
 
 	if stageChecksum != "" {
+		digest_inputs.Add(ctx, stageCacheVersionFieldName, stageChecksum)
 		stageVersionChecksumArgs = append(stageVersionChecksumArgs, stageChecksum)
 	}
 
@@ -138,6 +141,7 @@ func (b *Shell) stageVersionChecksum(userStageName string) string {
 	}
 
 	if checksum != "" {
+		digest_inputs.Add(ctx, cacheVersionFieldName, checksum)
 		stageVersionChecksumArgs = append(stageVersionChecksumArgs, checksum)
 	}
 
@@ -26,6 +26,7 @@ import (
 	"github.com/werf/werf/pkg/storage/manager"
 	"github.com/werf/werf/pkg/util"
 	"github.com/werf/werf/pkg/util/parallel"
+	"github.com/werf/werf/pkg/vulnerability_scan"
 )
 
 type Conveyor struct {
@@ -70,6 +71,12 @@ type ConveyorOptions struct {
 	ParallelTasksLimit              int64
 	LocalGitRepoVirtualMergeOptions stage.VirtualMergeOptions
 	TargetPlatforms                 []string
+	// DistributedBuild enables sharing the images builds between the werf processes working with the same repo
+	DistributedBuild bool
+	// Reproducible enables building byte-identical stages from the same inputs (also enabled by the build.reproducible werf.yaml directive)
+	Reproducible bool
+	// SourceDateEpoch is the timestamp of the reproducible stages files and configs
+	SourceDateEpoch time.Time
 
 	ImagesToProcess
 }
@@ -161,6 +168,20 @@ func (c *Conveyor) GetTargetPlatforms() ([]string, error) {
 	return prepareConfigurationPlatforms(c.werfConfig.Meta.Build.Platform)
 }
 
+func (c *Conveyor) IsReproducible() bool {
+	return c.Reproducible || c.werfConfig.Meta.Build.Reproducible
+}
+
+// GetReproducibleTimestamp returns the timestamp to normalize the stages files and configs if the reproducible build is enabled, nil otherwise
+func (c *Conveyor) GetReproducibleTimestamp() *time.Time {
+	if !c.IsReproducible() {
+		return nil
+	}
+
+	timestamp := c.SourceDateEpoch.UTC()
+	return &timestamp
+}
+
 func (c *Conveyor) GetServiceRWMutex(service string) *sync.RWMutex {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -374,7 +395,8 @@ func (c *Conveyor) ShouldAddManagedImagesRecords() bool {
 }
 
 type ShouldBeBuiltOptions struct {
-	CustomTagFuncList []imagePkg.CustomTagFunc
+	CustomTagFuncList    []imagePkg.CustomTagFunc
+	VulnerabilityScanner *vulnerability_scan.Scanner
 }
 
 func (c *Conveyor) ShouldBeBuilt(ctx context.Context, opts ShouldBeBuiltOptions) error {
@@ -386,7 +408,8 @@ func (c *Conveyor) ShouldBeBuilt(ctx context.Context, opts ShouldBeBuiltOptions)
 		NewBuildPhase(c, BuildPhaseOptions{
 			ShouldBeBuiltMode: true,
 			BuildOptions: BuildOptions{
-				CustomTagFuncList: opts.CustomTagFuncList,
+				CustomTagFuncList:    opts.CustomTagFuncList,
+				VulnerabilityScanner: opts.VulnerabilityScanner,
 			},
 		}),
 	}
@@ -470,6 +493,10 @@ func (c *Conveyor) checkContainerBackendSupported(ctx context.Context) error {
 	c.ContainerBackend.ClaimTargetPlatforms(ctx, targetPlatforms)
 
 	if _, isBuildah := c.ContainerBackend.(*container_backend.BuildahBackend); !isBuildah {
+		if c.IsReproducible() {
+			return fmt.Errorf("reproducible build is supported only for the buildah container backend: the docker server backend does not allow to normalize the image layers timestamps (enable buildah backend with WERF_BUILDAH_MODE environment variable)")
+		}
+
 		return nil
 	}
 
@@ -583,6 +610,10 @@ func (c *Conveyor) runPhases(ctx context.Context, phases []Phase, logImages bool
 }
 
 func (c *Conveyor) doImages(ctx context.Context, phases []Phase, logImages bool) error {
+	if c.DistributedBuild {
+		return c.doImagesDistributed(ctx, phases, logImages)
+	}
+
 	if c.Parallel && len(c.imagesTree.GetImages()) > 1 {
 		return c.doImagesInParallel(ctx, phases, logImages)
 	} else {
@@ -598,24 +629,7 @@ func (c *Conveyor) doImages(ctx context.Context, phases []Phase, logImages bool)
 
 func (c *Conveyor) doImagesInParallel(ctx context.Context, phases []Phase, logImages bool) error {
 	if logImages {
-		blockMsg := "Concurrent build plan"
-		if c.ParallelTasksLimit > 0 {
-			blockMsg = fmt.Sprintf("%s (no more than %d images at the same time)", blockMsg, c.ParallelTasksLimit)
-		}
-
-		logboek.Context(ctx).LogBlock(blockMsg).
-			Options(func(options types.LogBlockOptionsInterface) {
-				options.Style(stylePkg.Highlight())
-			}).
-			Do(func() {
-				for setId := range c.imagesTree.GetImagesSets() {
-					logboek.Context(ctx).LogFHighlight("Set #%d:\n", setId)
-					for _, img := range c.imagesTree.GetImagesSets()[setId] {
-						logboek.Context(ctx).LogLnHighlight("-", img.LogDetailedName())
-					}
-					logboek.Context(ctx).LogOptionalLn()
-				}
-			})
+		c.logBuildPlan(ctx, "Concurrent build plan", c.imagesTree.GetImagesSets())
 	}
 
 	var setImageExecutionTimesArray [][]string
@@ -660,25 +674,48 @@ func (c *Conveyor) doImagesInParallel(ctx context.Context, phases []Phase, logIm
 	}
 
 	if logImages {
-		blockMsg := "Build summary"
-		logboek.Context(ctx).LogBlock(blockMsg).
-			Options(func(options types.LogBlockOptionsInterface) {
-				options.Style(stylePkg.Highlight())
-			}).
-			Do(func() {
-				for setId, setImageExecutionTImes := range setImageExecutionTimesArray {
-					logboek.Context(ctx).LogFHighlight("Set #%d:\n", setId)
-					for _, msg := range setImageExecutionTImes {
-						logboek.Context(ctx).LogLnHighlight("-", msg)
-					}
-					logboek.Context(ctx).LogOptionalLn()
-				}
-			})
+		logBuildSummary(ctx, setImageExecutionTimesArray)
 	}
 
 	return nil
 }
 
+func (c *Conveyor) logBuildPlan(ctx context.Context, blockMsg string, sets image.ImagesSets) {
+	if c.Parallel && c.ParallelTasksLimit > 0 {
+		blockMsg = fmt.Sprintf("%s (no more than %d images at the same time)", blockMsg, c.ParallelTasksLimit)
+	}
+
+	logboek.Context(ctx).LogBlock(blockMsg).
+		Options(func(options types.LogBlockOptionsInterface) {
+			options.Style(stylePkg.Highlight())
+		}).
+		Do(func() {
+			for setId := range sets {
+				logboek.Context(ctx).LogFHighlight("Set #%d:\n", setId)
+				for _, img := range sets[setId] {
+					logboek.Context(ctx).LogLnHighlight("-", img.LogDetailedName())
+				}
+				logboek.Context(ctx).LogOptionalLn()
+			}
+		})
+}
+
+func logBuildSummary(ctx context.Context, setImageExecutionTimesArray [][]string) {
+	logboek.Context(ctx).LogBlock("Build summary").
+		Options(func(options types.LogBlockOptionsInterface) {
+			options.Style(stylePkg.Highlight())
+		}).
+		Do(func() {
+			for setId, setImageExecutionTImes := range setImageExecutionTimesArray {
+				logboek.Context(ctx).LogFHighlight("Set #%d:\n", setId)
+				for _, msg := range setImageExecutionTImes {
+					logboek.Context(ctx).LogLnHighlight("-", msg)
+				}
+				logboek.Context(ctx).LogOptionalLn()
+			}
+		})
+}
+
 func (c *Conveyor) doImage(ctx context.Context, img *image.Image, phases []Phase, logImages bool) error {
 	var imagesLogger types.ManagerInterface
 	if logImages {
@@ -774,7 +811,7 @@ func (c *Conveyor) GetOrCreateStageImage(name string, prevStageImage *stage.Stag
 		return stageImage
 	}
 
-	i := container_backend.NewLegacyStageImage(extractLegacyStageImage(prevStageImage), name, c.ContainerBackend, img.TargetPlatform)
+	i := container_backend.NewLegacyStageImage(extractLegacyStageImage(prevStageImage), name, c.ContainerBackend, img.GetBuildPlatform())
 
 	var baseImage string
 	if stg != nil {
@@ -806,6 +843,10 @@ func (c *Conveyor) GetImage(targetPlatform, name string) *image.Image {
 	panic(fmt.Sprintf("Image %q not found!", name))
 }
 
+func (c *Conveyor) GetImageBuildPlatform(targetPlatform, imageName string) string {
+	return c.GetImage(targetPlatform, imageName).GetBuildPlatform()
+}
+
 func (c *Conveyor) GetImageStageContentDigest(targetPlatform, imageName, stageName string) string {
 	return c.getImageStage(targetPlatform, imageName, stageName).GetContentDigest()
 }
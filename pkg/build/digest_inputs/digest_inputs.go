@@ -0,0 +1,171 @@
+package digest_inputs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Input is the named value which affects the stage digest
+type Input struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Collector collects the inputs of the stage digest calculation to explain why the digest has been changed
+type Collector struct {
+	mux    sync.Mutex
+	inputs []Input
+}
+
+type collectorCtxKey struct{}
+
+func NewContext(ctx context.Context) (context.Context, *Collector) {
+	collector := &Collector{}
+	return context.WithValue(ctx, collectorCtxKey{}, collector), collector
+}
+
+// Add records the input into the collector of the context, the values are joined by new line.
+// Nothing is recorded if there is no collector in the context
+func Add(ctx context.Context, name string, values ...string) {
+	collector, ok := ctx.Value(collectorCtxKey{}).(*Collector)
+	if !ok {
+		return
+	}
+
+	collector.mux.Lock()
+	defer collector.mux.Unlock()
+	collector.inputs = append(collector.inputs, Input{Name: name, Value: strings.Join(values, "\n")})
+}
+
+func (collector *Collector) Inputs() []Input {
+	collector.mux.Lock()
+	defer collector.mux.Unlock()
+
+	res := make([]Input, len(collector.inputs))
+	copy(res, collector.inputs)
+	return res
+}
+
+// Stage is the stage digest with its inputs, the list of image stages is stored in the stage image label
+type Stage struct {
+	Name   string  `json:"name"`
+	Digest string  `json:"digest"`
+	Inputs []Input `json:"inputs"`
+}
+
+// EncodeStages encodes the stages into the compact metadata label value (gzipped and base64 encoded json)
+func EncodeStages(stages []Stage) (string, error) {
+	data, err := json.Marshal(stages)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func DecodeStages(value string) ([]Stage, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode base64: %w", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create gzip reader: %w", err)
+	}
+	defer zr.Close()
+
+	data, err = io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress: %w", err)
+	}
+
+	var stages []Stage
+	if err := json.Unmarshal(data, &stages); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal json: %w", err)
+	}
+
+	return stages, nil
+}
+
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "+"
+	ChangeRemoved  ChangeType = "-"
+	ChangeModified ChangeType = "~"
+)
+
+type Change struct {
+	Type     ChangeType
+	Name     string
+	OldValue string
+	NewValue string
+}
+
+// Diff returns the changes of the inputs, the inputs with the same name are matched in order of occurrence
+func Diff(oldInputs, newInputs []Input) []Change {
+	oldKeyed := keyInputs(oldInputs)
+	newKeyed := keyInputs(newInputs)
+
+	oldByKey := map[string]Input{}
+	for _, elm := range oldKeyed {
+		oldByKey[elm.key] = elm.Input
+	}
+	newByKey := map[string]Input{}
+	for _, elm := range newKeyed {
+		newByKey[elm.key] = elm.Input
+	}
+
+	var changes []Change
+	for _, elm := range newKeyed {
+		oldInput, ok := oldByKey[elm.key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Type: ChangeAdded, Name: elm.Name, NewValue: elm.Value})
+		case oldInput.Value != elm.Value:
+			changes = append(changes, Change{Type: ChangeModified, Name: elm.Name, OldValue: oldInput.Value, NewValue: elm.Value})
+		}
+	}
+
+	for _, elm := range oldKeyed {
+		if _, ok := newByKey[elm.key]; !ok {
+			changes = append(changes, Change{Type: ChangeRemoved, Name: elm.Name, OldValue: elm.Value})
+		}
+	}
+
+	return changes
+}
+
+type keyedInput struct {
+	Input
+	key string
+}
+
+func keyInputs(inputs []Input) []keyedInput {
+	occurrences := map[string]int{}
+
+	var res []keyedInput
+	for _, input := range inputs {
+		occurrences[input.Name]++
+		res = append(res, keyedInput{Input: input, key: fmt.Sprintf("%s#%d", input.Name, occurrences[input.Name])})
+	}
+
+	return res
+}
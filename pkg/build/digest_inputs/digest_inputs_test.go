@@ -0,0 +1,65 @@
+package digest_inputs
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("digest inputs", func() {
+	It("should record inputs only when the collector is in the context", func() {
+		Add(context.Background(), "ignored", "value")
+
+		ctx, collector := NewContext(context.Background())
+		Add(ctx, "Commands", "apt-get update", "apt-get install -y curl")
+		Add(ctx, "CacheVersion", "1")
+
+		Expect(collector.Inputs()).To(Equal([]Input{
+			{Name: "Commands", Value: "apt-get update\napt-get install -y curl"},
+			{Name: "CacheVersion", Value: "1"},
+		}))
+	})
+
+	It("should encode and decode stages", func() {
+		stages := []Stage{
+			{Name: "from", Digest: "d1", Inputs: []Input{{Name: "Base image", Value: "alpine:3.17"}}},
+			{Name: "install", Digest: "d2", Inputs: []Input{{Name: "install", Value: "apk add curl"}}},
+		}
+
+		value, err := EncodeStages(stages)
+		Expect(err).To(Succeed())
+
+		decodedStages, err := DecodeStages(value)
+		Expect(err).To(Succeed())
+		Expect(decodedStages).To(Equal(stages))
+	})
+
+	DescribeTable("diff",
+		func(oldInputs, newInputs []Input, expectedChanges []Change) {
+			Expect(Diff(oldInputs, newInputs)).To(Equal(expectedChanges))
+		},
+		Entry("no changes",
+			[]Input{{Name: "a", Value: "1"}},
+			[]Input{{Name: "a", Value: "1"}},
+			nil,
+		),
+		Entry("modified, added and removed inputs",
+			[]Input{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}},
+			[]Input{{Name: "a", Value: "3"}, {Name: "c", Value: "4"}},
+			[]Change{
+				{Type: ChangeModified, Name: "a", OldValue: "1", NewValue: "3"},
+				{Type: ChangeAdded, Name: "c", NewValue: "4"},
+				{Type: ChangeRemoved, Name: "b", OldValue: "2"},
+			},
+		),
+		Entry("inputs with the same name are matched in order",
+			[]Input{{Name: "RUN instruction", Value: "a"}, {Name: "RUN instruction", Value: "b"}},
+			[]Input{{Name: "RUN instruction", Value: "a"}, {Name: "RUN instruction", Value: "c"}, {Name: "RUN instruction", Value: "d"}},
+			[]Change{
+				{Type: ChangeModified, Name: "RUN instruction", OldValue: "b", NewValue: "c"},
+				{Type: ChangeAdded, Name: "RUN instruction", NewValue: "d"},
+			},
+		),
+	)
+})
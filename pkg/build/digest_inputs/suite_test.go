@@ -0,0 +1,13 @@
+package digest_inputs
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDigestInputs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Digest Inputs Suite")
+}
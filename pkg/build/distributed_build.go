@@ -0,0 +1,142 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/build/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/util/parallel"
+)
+
+// doImagesDistributed shares the images builds between the werf processes working with the same repo and synchronization.
+// Each images set is processed in two passes:
+//   - the images which are not claimed by other processes are claimed and built;
+//   - the images claimed by other processes are awaited and then processed again to get their stages from the repo,
+//     the image is built by the current process if another one has failed or the image build has not been started yet.
+//
+// Thus, the following set is processed when all images of the current set are built
+// and each process gets all images in the result (build report, etc.) like in the regular build
+func (c *Conveyor) doImagesDistributed(ctx context.Context, phases []Phase, logImages bool) error {
+	return c.doImagesSetsDistributed(ctx, c.imagesTree.GetImagesSets(), phases, logImages)
+}
+
+func (c *Conveyor) doImagesSetsDistributed(ctx context.Context, sets image.ImagesSets, phases []Phase, logImages bool) error {
+	if logImages {
+		c.logBuildPlan(ctx, "Distributed build plan", sets)
+	}
+
+	var setImageExecutionTimesArray [][]string
+	for setId, set := range sets {
+		var pendingImages []*image.Image
+		var setImageExecutionTimes []string
+		var mutex sync.Mutex
+
+		if err := c.doDistributedBuildTasks(ctx, set, func(ctx context.Context, img *image.Image) error {
+			taskStartTime := time.Now()
+
+			built, err := c.doImageWithDistributedBuildLock(ctx, img, phases, logImages, true)
+			if err != nil {
+				return err
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if !built {
+				logboek.Context(ctx).Default().LogF("Image %s is being built by another werf process, postponed\n", img.LogDetailedName())
+				pendingImages = append(pendingImages, img)
+				return nil
+			}
+
+			setImageExecutionTimes = append(setImageExecutionTimes, fmt.Sprintf("%s (%.2f seconds)", img.LogDetailedName(), time.Since(taskStartTime).Seconds()))
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if len(pendingImages) != 0 {
+			logboek.Context(ctx).Default().LogF("Waiting for %d images of the set #%d built by other werf processes\n", len(pendingImages), setId)
+
+			if err := c.doDistributedBuildTasks(ctx, pendingImages, func(ctx context.Context, img *image.Image) error {
+				taskStartTime := time.Now()
+
+				if _, err := c.doImageWithDistributedBuildLock(ctx, img, phases, logImages, false); err != nil {
+					return err
+				}
+
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				setImageExecutionTimes = append(setImageExecutionTimes, fmt.Sprintf("%s (%.2f seconds, waited for another werf process)", img.LogDetailedName(), time.Since(taskStartTime).Seconds()))
+
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+
+		setImageExecutionTimesArray = append(setImageExecutionTimesArray, setImageExecutionTimes)
+	}
+
+	if logImages {
+		logBuildSummary(ctx, setImageExecutionTimesArray)
+	}
+
+	return nil
+}
+
+func (c *Conveyor) doDistributedBuildTasks(ctx context.Context, images []*image.Image, taskFunc func(ctx context.Context, img *image.Image) error) error {
+	if !c.Parallel || len(images) == 1 {
+		for _, img := range images {
+			if err := taskFunc(ctx, img); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return parallel.DoTasks(ctx, len(images), parallel.DoTasksOptions{
+		InitDockerCLIForEachWorker: true,
+		MaxNumberOfWorkers:         int(c.ParallelTasksLimit),
+		LiveOutput:                 true,
+	}, func(ctx context.Context, taskId int) error {
+		return taskFunc(ctx, images[taskId])
+	})
+}
+
+// doImageWithDistributedBuildLock processes the image holding the image build lock.
+// Returns false without processing if nonBlocking is set and the lock is held by another werf process
+func (c *Conveyor) doImageWithDistributedBuildLock(ctx context.Context, img *image.Image, phases []Phase, logImages, nonBlocking bool) (bool, error) {
+	acquired, lock, err := c.StorageLockManager.LockImageBuild(ctx, c.ProjectName(), c.distributedImageBuildKey(img), storage.LockImageBuildOptions{NonBlocking: nonBlocking})
+	if err != nil {
+		return false, fmt.Errorf("unable to lock image %s build: %w", img.LogDetailedName(), err)
+	}
+
+	if !acquired {
+		return false, nil
+	}
+	defer c.StorageLockManager.Unlock(ctx, lock)
+
+	var imagePhases []Phase
+	for _, phase := range phases {
+		imagePhases = append(imagePhases, phase.Clone())
+	}
+
+	if err := c.doImage(ctx, img, imagePhases, logImages); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// distributedImageBuildKey identifies the image build of the current commit across the werf processes
+func (c *Conveyor) distributedImageBuildKey(img *image.Image) string {
+	return util.Sha256Hash(c.giterminismManager.HeadCommit(), img.GetName(), img.TargetPlatform)
+}
@@ -0,0 +1,127 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/werf/lockgate/pkg/file_locker"
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/build/image"
+	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/giterminism_manager"
+	"github.com/werf/werf/pkg/storage"
+)
+
+type distributedBuildTestGiterminismManager struct {
+	giterminism_manager.Interface
+}
+
+func (manager *distributedBuildTestGiterminismManager) HeadCommit() string {
+	return "9d8059842b6fde712c58315ca0ab4713d90761c0"
+}
+
+// distributedBuildTestPhase records the images processed by the conveyor
+type distributedBuildTestPhase struct {
+	mutex           sync.Mutex
+	processedImages []string
+}
+
+func (phase *distributedBuildTestPhase) Name() string {
+	return "test"
+}
+
+func (phase *distributedBuildTestPhase) BeforeImages(_ context.Context) error {
+	return nil
+}
+
+func (phase *distributedBuildTestPhase) AfterImages(_ context.Context) error {
+	return nil
+}
+
+func (phase *distributedBuildTestPhase) BeforeImageStages(_ context.Context, _ *image.Image) (func(), error) {
+	return nil, nil
+}
+
+func (phase *distributedBuildTestPhase) OnImageStage(_ context.Context, _ *image.Image, _ stage.Interface) error {
+	return nil
+}
+
+func (phase *distributedBuildTestPhase) AfterImageStages(_ context.Context, img *image.Image) error {
+	phase.mutex.Lock()
+	defer phase.mutex.Unlock()
+
+	phase.processedImages = append(phase.processedImages, img.GetName())
+
+	return nil
+}
+
+func (phase *distributedBuildTestPhase) ImageProcessingShouldBeStopped(_ context.Context, _ *image.Image) bool {
+	return false
+}
+
+func (phase *distributedBuildTestPhase) Clone() Phase {
+	return phase
+}
+
+func TestConveyor_DoImagesSetsDistributed(t *testing.T) {
+	locker, err := file_locker.NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Conveyor{
+		werfConfig:         &config.WerfConfig{Meta: &config.Meta{Project: "project"}},
+		giterminismManager: &distributedBuildTestGiterminismManager{},
+		StorageLockManager: storage.NewGenericLockManager(locker),
+	}
+
+	newImage := func(name string) *image.Image {
+		return &image.Image{Name: name, CommonImageOptions: image.CommonImageOptions{ForceTargetPlatformLogging: true}}
+	}
+	backend, frontend, app := newImage("backend"), newImage("frontend"), newImage("app")
+	sets := image.ImagesSets{{backend, frontend}, {app}}
+
+	var out bytes.Buffer
+	ctx := logboek.NewContext(context.Background(), logboek.NewLogger(&out, &out))
+
+	// the backend image build is claimed by another werf process and finished a bit later
+	acquired, lock, err := c.StorageLockManager.LockImageBuild(ctx, c.ProjectName(), c.distributedImageBuildKey(backend), storage.LockImageBuildOptions{NonBlocking: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Fatal("expected image build lock to be acquired")
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = c.StorageLockManager.Unlock(ctx, lock)
+	}()
+
+	phase := &distributedBuildTestPhase{}
+	if err := c.doImagesSetsDistributed(ctx, sets, []Phase{phase}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if processed := strings.Join(phase.processedImages, ","); processed != "frontend,backend,app" {
+		t.Errorf("expected images to be processed in order frontend,backend,app, got %s", processed)
+	}
+
+	output := out.String()
+	for _, expected := range []string{
+		"Distributed build plan",
+		"image backend is being built by another werf process, postponed",
+		"Waiting for 1 images of the set #0 built by other werf processes",
+		"Build summary",
+		"waited for another werf process",
+		"Set #1:",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
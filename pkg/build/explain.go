@@ -0,0 +1,154 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/werf/werf/pkg/build/digest_inputs"
+	"github.com/werf/werf/pkg/build/image"
+	"github.com/werf/werf/pkg/build/stage"
+)
+
+// errStageNotFound stops the stages processing of the image in the explain mode: the following stages and dependent images cannot be calculated without the missing stage
+var errStageNotFound = errors.New("stage not found")
+
+// StageExplanation describes the inputs of the stage digest calculated for the current project state
+type StageExplanation struct {
+	ImageName string
+	Platform  string
+	StageName string
+	Digest    string
+	// Found is false when there is no suitable stage in the stages storage, thus the stage will be built
+	Found bool
+	// Skipped is true when the stage digest cannot be calculated because the previous stage or the stage of the image dependency is not found
+	Skipped bool
+	// StageImageName is the name of the found stage image
+	StageImageName string
+	Inputs         []digest_inputs.Input
+}
+
+type stagesExplanations struct {
+	mux          sync.Mutex
+	explanations []*StageExplanation
+
+	// imagesDependencies are the names of the images used by the image (from, imports and dependencies)
+	imagesDependencies map[string][]string
+	// stoppedImages are the images (by name and platform) with the stage which is not found
+	stoppedImages map[string]bool
+}
+
+func newStagesExplanations(imagesDependencies map[string][]string) *stagesExplanations {
+	return &stagesExplanations{
+		imagesDependencies: imagesDependencies,
+		stoppedImages:      map[string]bool{},
+	}
+}
+
+func (e *stagesExplanations) add(img *image.Image, stg stage.Interface, found bool, inputs []digest_inputs.Input) {
+	explanation := &StageExplanation{
+		ImageName: img.GetName(),
+		Platform:  img.TargetPlatform,
+		StageName: string(stg.Name()),
+		Digest:    stg.GetDigest(),
+		Found:     found,
+		Inputs:    inputs,
+	}
+	if found {
+		explanation.StageImageName = stg.GetStageImage().Image.Name()
+	}
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.explanations = append(e.explanations, explanation)
+}
+
+func (e *stagesExplanations) addSkipped(img *image.Image, stg stage.Interface) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.explanations = append(e.explanations, &StageExplanation{
+		ImageName: img.GetName(),
+		Platform:  img.TargetPlatform,
+		StageName: string(stg.Name()),
+		Skipped:   true,
+	})
+}
+
+func (e *stagesExplanations) stop(img *image.Image) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.stoppedImages[explainedImageKey(img.GetName(), img.TargetPlatform)] = true
+}
+
+// isStopped reports whether the image or one of the images it depends on has the stage which is not found
+func (e *stagesExplanations) isStopped(img *image.Image) bool {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if e.stoppedImages[explainedImageKey(img.GetName(), img.TargetPlatform)] {
+		return true
+	}
+
+	for _, name := range e.imagesDependencies[img.GetName()] {
+		if e.stoppedImages[explainedImageKey(name, img.TargetPlatform)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func explainedImageKey(imageName, platform string) string {
+	return fmt.Sprintf("%s/%s", imageName, platform)
+}
+
+// ExplainStages calculates the stages digests and collects their inputs without building anything.
+// Processing of the image stops on the first stage which is not found in the stages storage,
+// the following stages and the stages of the dependent images are marked as skipped
+func (c *Conveyor) ExplainStages(ctx context.Context) ([]*StageExplanation, error) {
+	if err := c.determineStages(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.explainDeterminedStages(ctx)
+}
+
+func (c *Conveyor) explainDeterminedStages(ctx context.Context) ([]*StageExplanation, error) {
+	graphList, err := c.werfConfig.GetImageGraphList(nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get images graph: %w", err)
+	}
+
+	imagesDependencies := map[string][]string{}
+	for _, graph := range graphList {
+		var dependencies []string
+		if graph.DependsOn.From != "" {
+			dependencies = append(dependencies, graph.DependsOn.From)
+		}
+		dependencies = append(dependencies, graph.DependsOn.Imports...)
+		dependencies = append(dependencies, graph.DependsOn.Dependencies...)
+		imagesDependencies[graph.ImageName] = dependencies
+	}
+
+	explanations := newStagesExplanations(imagesDependencies)
+	phase := NewBuildPhase(c, BuildPhaseOptions{ShouldBeBuiltMode: true})
+	phase.explanations = explanations
+
+	if err := c.runPhases(ctx, []Phase{phase}, false); err != nil {
+		return nil, err
+	}
+
+	// the images are processed concurrently, so the explanations are grouped by images in the images tree order
+	imagesOrder := map[string]int{}
+	for ind, img := range c.imagesTree.GetImages() {
+		imagesOrder[explainedImageKey(img.GetName(), img.TargetPlatform)] = ind
+	}
+	sort.SliceStable(explanations.explanations, func(i, j int) bool {
+		return imagesOrder[explainedImageKey(explanations.explanations[i].ImageName, explanations.explanations[i].Platform)] <
+			imagesOrder[explainedImageKey(explanations.explanations[j].ImageName, explanations.explanations[j].Platform)]
+	})
+
+	return explanations.explanations, nil
+}
@@ -0,0 +1,37 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/werf/werf/pkg/build/image"
+)
+
+func TestStagesExplanations_IsStopped(t *testing.T) {
+	explanations := newStagesExplanations(map[string][]string{
+		"backend":  {"base"},
+		"frontend": {"assets"},
+	})
+
+	base := &image.Image{Name: "base", TargetPlatform: "linux/amd64"}
+	baseArm := &image.Image{Name: "base", TargetPlatform: "linux/arm64"}
+	backend := &image.Image{Name: "backend", TargetPlatform: "linux/amd64"}
+	backendArm := &image.Image{Name: "backend", TargetPlatform: "linux/arm64"}
+	frontend := &image.Image{Name: "frontend", TargetPlatform: "linux/amd64"}
+
+	explanations.stop(base)
+
+	for _, tt := range []struct {
+		img      *image.Image
+		expected bool
+	}{
+		{img: base, expected: true},
+		{img: backend, expected: true},
+		{img: baseArm, expected: false},
+		{img: backendArm, expected: false},
+		{img: frontend, expected: false},
+	} {
+		if res := explanations.isStopped(tt.img); res != tt.expected {
+			t.Errorf("isStopped(%s [%s]) = %v, expected %v", tt.img.Name, tt.img.TargetPlatform, res, tt.expected)
+		}
+	}
+}
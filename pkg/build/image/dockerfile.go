@@ -152,6 +152,8 @@ func mapDockerfileToImagesSets(ctx context.Context, cfg *dockerfile.Dockerfile,
 			}
 		}
 
+		img.Secrets = stage.NewImageSecrets(dockerfileImageConfig.Secrets, img.TmpDir)
+
 		for ind, instr := range stg.Instructions {
 			stageLogName := fmt.Sprintf("%s%d", strings.ToUpper(instr.GetInstructionData().Name()), ind+1)
 			isFirstStage := (len(img.stages) == 0)
@@ -159,6 +161,7 @@ func mapDockerfileToImagesSets(ctx context.Context, cfg *dockerfile.Dockerfile,
 				TargetPlatform:   img.TargetPlatform,
 				ImageName:        img.Name,
 				LogName:          stageLogName,
+				Secrets:          img.Secrets,
 				ImageTmpDir:      img.TmpDir,
 				ContainerWerfDir: img.ContainerWerfDir,
 				ProjectName:      opts.ProjectName,
@@ -276,9 +279,14 @@ func mapLegacyDockerfileToImage(ctx context.Context, dockerfileImageConfig *conf
 		dockerTargetIndex,
 	)
 
+	imageTmpDir := filepath.Join(opts.TmpDir, "image", dockerfileImageConfig.Name)
+	img.Secrets = stage.NewImageSecrets(dockerfileImageConfig.Secrets, imageTmpDir)
+
 	baseStageOptions := &stage.BaseStageOptions{
 		TargetPlatform: targetPlatform,
 		ImageName:      dockerfileImageConfig.Name,
+		Secrets:        img.Secrets,
+		ImageTmpDir:    imageTmpDir,
 		ProjectName:    opts.ProjectName,
 	}
 
@@ -51,6 +51,8 @@ type ImageOptions struct {
 	BaseImageName             string
 	FetchLatestBaseImage      bool
 	DockerfileExpanderFactory dockerfile.ExpanderFactory
+	// BuildPlatform is set if the image stages are run on the build host platform instead of the target platform
+	BuildPlatform string
 }
 
 func NewImage(ctx context.Context, targetPlatform, name string, baseImageType BaseImageType, opts ImageOptions) (*Image, error) {
@@ -72,6 +74,7 @@ func NewImage(ctx context.Context, targetPlatform, name string, baseImageType Ba
 		IsDockerfileTargetStage: opts.IsDockerfileTargetStage,
 		DockerfileImageConfig:   opts.DockerfileImageConfig,
 		TargetPlatform:          targetPlatform,
+		BuildPlatform:           opts.BuildPlatform,
 
 		baseImageType:             baseImageType,
 		baseImageReference:        opts.BaseImageReference,
@@ -97,6 +100,10 @@ type Image struct {
 	Name                    string
 	DockerfileImageConfig   *config.ImageFromDockerfile
 	TargetPlatform          string
+	// BuildPlatform is the platform of the build host if the image stages are run natively for the target platform, empty otherwise
+	BuildPlatform string
+	// Secrets are prepared by the image stages and should be cleaned up after the image stages are processed
+	Secrets *stage.ImageSecrets
 
 	stages            []stage.Interface
 	lastNonEmptyStage stage.Interface
@@ -121,6 +128,14 @@ func (i *Image) ShouldLogPlatform() bool {
 	return i.ForceTargetPlatformLogging || i.TargetPlatform != i.ContainerBackend.GetRuntimePlatform()
 }
 
+// GetBuildPlatform returns the platform the image stages are run on
+func (i *Image) GetBuildPlatform() string {
+	if i.BuildPlatform != "" {
+		return i.BuildPlatform
+	}
+	return i.TargetPlatform
+}
+
 func (i *Image) LogDetailedName() string {
 	var targetPlatformForLog string
 	if i.ShouldLogPlatform() {
@@ -314,6 +329,15 @@ func (i *Image) GetBaseStageImage() *stage.StageImage {
 	return i.baseStageImage
 }
 
+func (i *Image) GetBaseImageType() BaseImageType {
+	return i.baseImageType
+}
+
+// GetBaseImageName returns the name of the werf image used as the base image (StageAsBaseImage)
+func (i *Image) GetBaseImageName() string {
+	return i.baseImageName
+}
+
 func (i *Image) GetBaseImageReference() string {
 	return i.baseImageReference
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/util"
 )
 
 func MapStapelConfigToImagesSets(ctx context.Context, metaConfig *config.Meta, stapelImageConfig config.StapelImageInterface, targetPlatform string, opts CommonImageOptions) (ImagesSets, error) {
@@ -36,6 +37,10 @@ func mapStapelConfigToImage(ctx context.Context, metaConfig *config.Meta, stapel
 		IsArtifact:         imageArtifact,
 	}
 
+	if imageBaseConfig.RunOnBuildPlatform {
+		imageOpts.BuildPlatform = opts.ContainerBackend.GetRuntimePlatform()
+	}
+
 	var baseImageType BaseImageType
 	if from != "" {
 		baseImageType = ImageFromRegistryAsBaseImage
@@ -64,12 +69,16 @@ func initStages(ctx context.Context, image *Image, metaConfig *config.Meta, stap
 	imageBaseConfig := stapelImageConfig.ImageBaseConfig()
 	imageName := imageBaseConfig.Name
 	imageArtifact := stapelImageConfig.IsArtifact()
+	imageTmpDir := filepath.Join(opts.TmpDir, "image", imageBaseConfig.Name)
+	image.Secrets = stage.NewImageSecrets(imageBaseConfig.Secrets, imageTmpDir)
 
 	baseStageOptions := &stage.BaseStageOptions{
 		TargetPlatform:   image.TargetPlatform,
+		BuildPlatform:    image.BuildPlatform,
 		ImageName:        imageName,
 		ConfigMounts:     imageBaseConfig.Mount,
-		ImageTmpDir:      filepath.Join(opts.TmpDir, "image", imageBaseConfig.Name),
+		Secrets:          image.Secrets,
+		ImageTmpDir:      imageTmpDir,
 		ContainerWerfDir: opts.ContainerWerfDir,
 		ProjectName:      opts.ProjectName,
 	}
@@ -94,19 +103,30 @@ func initStages(ctx context.Context, image *Image, metaConfig *config.Meta, stap
 
 	gitMappingsExist := len(gitMappings) != 0
 
+	userStageOptions := func(name stage.StageName) *stage.BaseStageOptions {
+		if !util.IsStringsContainValue(imageBaseConfig.RunOnBuildPlatformStages, string(name)) {
+			return baseStageOptions
+		}
+
+		options := *baseStageOptions
+		options.BuildPlatform = opts.ContainerBackend.GetRuntimePlatform()
+		options.BuildPlatformBaseImage = imageBaseConfig.From
+		return &options
+	}
+
 	stages = appendIfExist(ctx, stages, stage.GenerateFromStage(imageBaseConfig, image.baseImageRepoId, baseStageOptions))
-	stages = appendIfExist(ctx, stages, stage.GenerateBeforeInstallStage(ctx, imageBaseConfig, baseStageOptions))
+	stages = appendIfExist(ctx, stages, stage.GenerateBeforeInstallStage(ctx, imageBaseConfig, userStageOptions(stage.BeforeInstall)))
 	stages = appendIfExist(ctx, stages, stage.GenerateDependenciesBeforeInstallStage(imageBaseConfig, baseStageOptions))
 
 	if gitMappingsExist {
 		stages = append(stages, stage.NewGitArchiveStage(gitArchiveStageOptions, baseStageOptions))
 	}
 
-	stages = appendIfExist(ctx, stages, stage.GenerateInstallStage(ctx, imageBaseConfig, gitPatchStageOptions, baseStageOptions))
+	stages = appendIfExist(ctx, stages, stage.GenerateInstallStage(ctx, imageBaseConfig, gitPatchStageOptions, userStageOptions(stage.Install)))
 	stages = appendIfExist(ctx, stages, stage.GenerateDependenciesAfterInstallStage(imageBaseConfig, baseStageOptions))
-	stages = appendIfExist(ctx, stages, stage.GenerateBeforeSetupStage(ctx, imageBaseConfig, gitPatchStageOptions, baseStageOptions))
+	stages = appendIfExist(ctx, stages, stage.GenerateBeforeSetupStage(ctx, imageBaseConfig, gitPatchStageOptions, userStageOptions(stage.BeforeSetup)))
 	stages = appendIfExist(ctx, stages, stage.GenerateDependenciesBeforeSetupStage(imageBaseConfig, baseStageOptions))
-	stages = appendIfExist(ctx, stages, stage.GenerateSetupStage(ctx, imageBaseConfig, gitPatchStageOptions, baseStageOptions))
+	stages = appendIfExist(ctx, stages, stage.GenerateSetupStage(ctx, imageBaseConfig, gitPatchStageOptions, userStageOptions(stage.Setup)))
 	stages = appendIfExist(ctx, stages, stage.GenerateDependenciesAfterSetupStage(imageBaseConfig, baseStageOptions))
 
 	if !imageArtifact {
@@ -116,6 +136,7 @@ func initStages(ctx context.Context, image *Image, metaConfig *config.Meta, stap
 		}
 
 		stages = appendIfExist(ctx, stages, stage.GenerateStapelDockerInstructionsStage(stapelImageConfig.(*config.StapelImage), baseStageOptions))
+		stages = appendIfExist(ctx, stages, stage.GenerateSquashStage(stapelImageConfig.(*config.StapelImage), baseStageOptions))
 	}
 
 	if len(gitMappings) != 0 {
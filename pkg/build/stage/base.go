@@ -35,6 +35,7 @@ const (
 	GitCache                  StageName = "gitCache"
 	GitLatestPatch            StageName = "gitLatestPatch"
 	DockerInstructions        StageName = "dockerInstructions"
+	Squash                    StageName = "squash"
 
 	Dockerfile StageName = "dockerfile"
 )
@@ -69,6 +70,7 @@ var AllStages = []StageName{
 	GitCache,
 	GitLatestPatch,
 	DockerInstructions,
+	Squash,
 
 	Dockerfile,
 }
@@ -76,11 +78,15 @@ var AllStages = []StageName{
 type BaseStageOptions struct {
 	LogName          string
 	TargetPlatform   string
+	BuildPlatform    string
 	ImageName        string
 	ConfigMounts     []*config.Mount
+	Secrets          *ImageSecrets
 	ImageTmpDir      string
 	ContainerWerfDir string
 	ProjectName      string
+	// BuildPlatformBaseImage is set if only the selected stages are run on the build platform in the container of this image
+	BuildPlatformBaseImage string
 }
 
 func NewBaseStage(name StageName, options *BaseStageOptions) *BaseStage {
@@ -88,8 +94,11 @@ func NewBaseStage(name StageName, options *BaseStageOptions) *BaseStage {
 	s.name = name
 	s.logName = options.LogName
 	s.targetPlatform = options.TargetPlatform
+	s.buildPlatform = options.BuildPlatform
+	s.buildPlatformBaseImage = options.BuildPlatformBaseImage
 	s.imageName = options.ImageName
 	s.configMounts = options.ConfigMounts
+	s.secrets = options.Secrets
 	s.imageTmpDir = options.ImageTmpDir
 	s.containerWerfDir = options.ContainerWerfDir
 	s.projectName = options.ProjectName
@@ -100,6 +109,7 @@ type BaseStage struct {
 	name             StageName
 	logName          string
 	targetPlatform   string
+	buildPlatform    string
 	imageName        string
 	digest           string
 	contentDigest    string
@@ -108,7 +118,10 @@ type BaseStage struct {
 	imageTmpDir      string
 	containerWerfDir string
 	configMounts     []*config.Mount
+	secrets          *ImageSecrets
 	projectName      string
+	// buildPlatformBaseImage is set if only this stage is run on the build platform
+	buildPlatformBaseImage string
 }
 
 func (s *BaseStage) HasPrevStage() bool {
@@ -277,11 +290,14 @@ func (s *BaseStage) PrepareImage(ctx context.Context, c Conveyor, cb container_b
 	 * NOTE: Take into account when adding new base PrepareImage steps.
 	 */
 
-	addLabels := map[string]string{imagePkg.WerfProjectRepoCommitLabel: c.GiterminismManager().HeadCommit()}
-	if c.UseLegacyStapelBuilder(cb) {
-		stageImage.Builder.LegacyStapelStageBuilder().Container().ServiceCommitChangeOptions().AddLabel(addLabels)
-	} else {
-		stageImage.Builder.StapelStageBuilder().AddLabels(addLabels)
+	// the commit differs between the builds of the same stage, so it is not stored in the reproducible stages
+	if !c.IsReproducible() {
+		addLabels := map[string]string{imagePkg.WerfProjectRepoCommitLabel: c.GiterminismManager().HeadCommit()}
+		if c.UseLegacyStapelBuilder(cb) {
+			stageImage.Builder.LegacyStapelStageBuilder().Container().ServiceCommitChangeOptions().AddLabel(addLabels)
+		} else {
+			stageImage.Builder.StapelStageBuilder().AddLabels(addLabels)
+		}
 	}
 
 	serviceMounts := s.getServiceMounts(prevBuiltImage)
@@ -296,6 +312,10 @@ func (s *BaseStage) PrepareImage(ctx context.Context, c Conveyor, cb container_b
 		return fmt.Errorf("error adding mounts volumes: %w", err)
 	}
 
+	if err := s.addPlatformEnvs(c, cb, stageImage); err != nil {
+		return fmt.Errorf("error adding platform envs: %w", err)
+	}
+
 	return nil
 }
 
@@ -36,6 +36,10 @@ func (s *BeforeInstallStage) PrepareImage(ctx context.Context, c Conveyor, cb co
 		return err
 	}
 
+	if err := s.BaseStage.addSecretsVolume(ctx, c, cb, stageImage); err != nil {
+		return err
+	}
+
 	if err := s.builder.BeforeInstall(ctx, cb, stageImage.Builder, c.UseLegacyStapelBuilder(cb)); err != nil {
 		return err
 	}
@@ -14,6 +14,8 @@ type Conveyor interface {
 	PutImportMetadata(ctx context.Context, projectName string, metadata *storage.ImportMetadata) error
 	RmImportMetadata(ctx context.Context, projectName, id string) error
 
+	// GetImageBuildPlatform returns the platform the image for the target platform is built on
+	GetImageBuildPlatform(targetPlatform, imageName string) string
 	GetImageStageContentDigest(targetPlatform, imageName, stageName string) string
 	GetImageContentDigest(targetPlatform, imageName string) string
 
@@ -32,6 +34,8 @@ type Conveyor interface {
 	GiterminismManager() giterminism_manager.Interface
 
 	UseLegacyStapelBuilder(cb container_backend.ContainerBackend) bool
+	// IsReproducible returns true if the stages should be byte-identical when built from the same inputs
+	IsReproducible() bool
 }
 
 type VirtualMergeOptions struct {
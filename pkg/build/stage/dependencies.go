@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
 	"github.com/werf/werf/pkg/docker"
@@ -67,6 +68,14 @@ type DependenciesStage struct {
 	dependencies []*config.Dependency
 }
 
+func (s *DependenciesStage) GetImports() []*config.Import {
+	return s.imports
+}
+
+func (s *DependenciesStage) GetDependenciesConfigs() []*config.Dependency {
+	return s.dependencies
+}
+
 func (s *DependenciesStage) GetDependencies(ctx context.Context, c Conveyor, cb container_backend.ContainerBackend, prevImage, prevBuiltImage *StageImage, buildContextArchive container_backend.BuildContextArchiver) (string, error) {
 	var args []string
 
@@ -83,13 +92,17 @@ func (s *DependenciesStage) GetDependencies(ctx context.Context, c Conveyor, cb
 		args = append(args, sourceChecksum)
 		args = append(args, elm.To)
 		args = append(args, elm.Group, elm.Owner)
+		digest_inputs.Add(ctx, fmt.Sprintf("Import %s:%s to %s", getSourceImageName(elm), elm.Add, elm.To), sourceChecksum, elm.Group, elm.Owner)
 	}
 
 	for _, dep := range s.dependencies {
 		args = append(args, "Dependency", c.GetImageNameForLastImageStage(s.targetPlatform, dep.ImageName))
+		var importIDs []string
 		for _, imp := range dep.Imports {
 			args = append(args, "DependencyImport", getDependencyImportID(imp))
+			importIDs = append(importIDs, getDependencyImportID(imp))
 		}
+		digest_inputs.Add(ctx, fmt.Sprintf("Dependency %s", dep.ImageName), append([]string{c.GetImageNameForLastImageStage(s.targetPlatform, dep.ImageName)}, importIDs...)...)
 	}
 
 	return util.Sha256Hash(args...), nil
@@ -181,7 +194,7 @@ func (s *DependenciesStage) prepareImage(ctx context.Context, c Conveyor, cr con
 		labelValue := importMetadata.Checksum
 
 		stageImage.Builder.StapelStageBuilder().AddLabels(map[string]string{labelKey: labelValue})
-		stageImage.Builder.StapelStageBuilder().AddDependencyImport(sourceImageName, elm.Add, elm.To, elm.IncludePaths, elm.ExcludePaths, elm.Owner, elm.Group)
+		stageImage.Builder.StapelStageBuilder().AddDependencyImport(sourceImageName, getSourceImagePlatform(c, s.targetPlatform, elm), elm.Add, elm.To, elm.IncludePaths, elm.ExcludePaths, elm.Owner, elm.Group)
 	}
 
 	for _, dep := range s.dependencies {
@@ -324,7 +337,7 @@ func (s *DependenciesStage) generateImportChecksum(ctx context.Context, c Convey
 				ExcludePaths: importElm.ExcludePaths,
 				Owner:        importElm.Owner,
 				Group:        importElm.Group,
-			}, container_backend.CalculateDependencyImportChecksum{TargetPlatform: s.targetPlatform})
+			}, container_backend.CalculateDependencyImportChecksum{TargetPlatform: c.GetImageBuildPlatform(s.targetPlatform, getSourceImageName(importElm))})
 		})
 
 		if err != nil {
@@ -442,6 +455,14 @@ func getSourceImageDockerImageName(c Conveyor, targetPlatform string, importElm
 	return sourceImageDockerImageName
 }
 
+// getSourceImagePlatform returns the platform of the source image if it is built on the build platform, empty otherwise
+func getSourceImagePlatform(c Conveyor, targetPlatform string, importElm *config.Import) string {
+	if platform := c.GetImageBuildPlatform(targetPlatform, getSourceImageName(importElm)); platform != targetPlatform {
+		return platform
+	}
+	return ""
+}
+
 func getSourceImageID(c Conveyor, targetPlatform string, importElm *config.Import) string {
 	sourceImageName := getSourceImageName(importElm)
 
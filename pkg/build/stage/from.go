@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
 	imagePkg "github.com/werf/werf/pkg/image"
@@ -56,31 +57,39 @@ func (s *FromStage) GetDependencies(ctx context.Context, c Conveyor, cb containe
 
 	if s.cacheVersion != "" {
 		args = append(args, s.cacheVersion)
+		digest_inputs.Add(ctx, "fromCacheVersion", s.cacheVersion)
 	}
 
 	if s.baseImageRepoIdOrNone != "" {
 		args = append(args, s.baseImageRepoIdOrNone)
+		digest_inputs.Add(ctx, "Base image ID", s.baseImageRepoIdOrNone)
 	}
 
 	for _, mount := range s.configMounts {
 		args = append(args, filepath.ToSlash(filepath.Clean(mount.From)), path.Clean(mount.To), mount.Type)
+		digest_inputs.Add(ctx, fmt.Sprintf("Mount %s", path.Clean(mount.To)), filepath.ToSlash(filepath.Clean(mount.From)), mount.Type)
 	}
 
 	if s.fromImageOrArtifactImageName != "" {
 		args = append(args, c.GetImageContentDigest(s.targetPlatform, s.fromImageOrArtifactImageName))
+		digest_inputs.Add(ctx, fmt.Sprintf("From image %s content digest", s.fromImageOrArtifactImageName), c.GetImageContentDigest(s.targetPlatform, s.fromImageOrArtifactImageName))
 	} else {
 		args = append(args, prevImage.Image.Name())
+		digest_inputs.Add(ctx, "Base image", prevImage.Image.Name())
 	}
 
 	return util.Sha256Hash(args...), nil
 }
 
 func (s *FromStage) PrepareImage(ctx context.Context, c Conveyor, cb container_backend.ContainerBackend, prevBuiltImage, stageImage *StageImage, buildContextArchive container_backend.BuildContextArchiver) error {
-	addLabels := map[string]string{imagePkg.WerfProjectRepoCommitLabel: c.GiterminismManager().HeadCommit()}
-	if c.UseLegacyStapelBuilder(cb) {
-		stageImage.Builder.LegacyStapelStageBuilder().Container().ServiceCommitChangeOptions().AddLabel(addLabels)
-	} else {
-		stageImage.Builder.StapelStageBuilder().AddLabels(addLabels)
+	// the commit differs between the builds of the same stage, so it is not stored in the reproducible stages
+	if !c.IsReproducible() {
+		addLabels := map[string]string{imagePkg.WerfProjectRepoCommitLabel: c.GiterminismManager().HeadCommit()}
+		if c.UseLegacyStapelBuilder(cb) {
+			stageImage.Builder.LegacyStapelStageBuilder().Container().ServiceCommitChangeOptions().AddLabel(addLabels)
+		} else {
+			stageImage.Builder.StapelStageBuilder().AddLabels(addLabels)
+		}
 	}
 
 	serviceMounts := s.getServiceMounts(prevBuiltImage)
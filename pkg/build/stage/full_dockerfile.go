@@ -15,6 +15,7 @@ import (
 	"github.com/moby/buildkit/frontend/dockerfile/shell"
 
 	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
 	"github.com/werf/werf/pkg/container_backend/stage_builder"
@@ -473,6 +474,10 @@ func (s *FullDockerfileStage) GetDependencies(ctx context.Context, c Conveyor, c
 		logboek.Context(ctx).LogLn(dockerfileStageDependencies)
 	}
 
+	for ind, dependency := range dockerfileStageDependencies {
+		digest_inputs.Add(ctx, fmt.Sprintf("Dockerfile dependency #%d", ind+1), dependency)
+	}
+
 	return util.Sha256Hash(dockerfileStageDependencies...), nil
 }
 
@@ -684,7 +689,16 @@ func (s *FullDockerfileStage) PrepareImage(ctx context.Context, c Conveyor, cb c
 
 	stageImage.Builder.DockerfileBuilder().SetBuildContextArchive(buildContextArchive)
 
-	stageImage.Builder.DockerfileBuilder().AppendLabels(fmt.Sprintf("%s=%s", image.WerfProjectRepoCommitLabel, c.GiterminismManager().HeadCommit()))
+	// the commit differs between the builds of the same stage, so it is not stored in the reproducible stages
+	if !c.IsReproducible() {
+		stageImage.Builder.DockerfileBuilder().AppendLabels(fmt.Sprintf("%s=%s", image.WerfProjectRepoCommitLabel, c.GiterminismManager().HeadCommit()))
+	}
+
+	_, secrets, err := s.PrepareSecrets(ctx, c)
+	if err != nil {
+		return fmt.Errorf("unable to prepare secrets: %w", err)
+	}
+	stageImage.Builder.DockerfileBuilder().AppendSecrets(secrets...)
 
 	if c.GiterminismManager().Dev() {
 		stageImage.Builder.DockerfileBuilder().AppendLabels(fmt.Sprintf("%s=true", image.WerfDevLabel))
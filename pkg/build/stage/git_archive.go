@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/container_backend"
 	"github.com/werf/werf/pkg/git_repo"
 	"github.com/werf/werf/pkg/image"
@@ -57,6 +58,7 @@ func (s *GitArchiveStage) GetDependencies(ctx context.Context, c Conveyor, cb co
 		}
 
 		args = append(args, gitMapping.GetParamshash())
+		digest_inputs.Add(ctx, fmt.Sprintf("Git mapping %s params", gitMapping.GetFullName()), gitMapping.GetParamshash())
 	}
 
 	sort.Strings(args)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/container_backend"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/util"
@@ -52,6 +53,8 @@ func (s *GitCacheStage) GetDependencies(ctx context.Context, c Conveyor, cb cont
 		return "", err
 	}
 
+	digest_inputs.Add(ctx, "Git patch size step", fmt.Sprintf("%d", patchSize/patchSizeStep))
+
 	return util.Sha256Hash(fmt.Sprintf("%d", patchSize/patchSizeStep)), nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/container_backend"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/util"
@@ -60,6 +61,7 @@ func (s *GitLatestPatchStage) GetDependencies(ctx context.Context, c Conveyor, c
 		}
 
 		args = append(args, patchContent)
+		digest_inputs.Add(ctx, fmt.Sprintf("Git mapping %s patch checksum", gitMapping.GetFullName()), util.Sha256Hash(patchContent))
 	}
 
 	return util.Sha256Hash(args...), nil
@@ -3,6 +3,7 @@ package instruction
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
@@ -36,6 +37,17 @@ func (stg *Base[T, BT]) HasPrevStage() bool {
 	return stg.hasPrevStage
 }
 
+// GetStageRefsImagesNames returns the werf images of the Dockerfile stages the instruction refers to (COPY --from, RUN --mount=from)
+func (stg *Base[T, BT]) GetStageRefsImagesNames() []string {
+	var res []string
+	for _, dep := range stg.instruction.GetDependenciesByStageRef() {
+		res = append(res, dep.GetWerfImageName())
+	}
+	sort.Strings(res)
+
+	return res
+}
+
 func (stg *Base[T, BT]) IsStapelStage() bool {
 	return false
 }
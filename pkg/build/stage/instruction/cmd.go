@@ -6,6 +6,7 @@ import (
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
@@ -28,5 +29,7 @@ func (stg *Cmd) GetDependencies(ctx context.Context, c stage.Conveyor, cb contai
 	args = append(args, append([]string{"Cmd"}, stg.instruction.Data.CmdLine...)...)
 	args = append(args, "PrependShell", fmt.Sprintf("%v", stg.instruction.Data.PrependShell))
 
+	digest_inputs.Add(ctx, "CMD instruction", args...)
+
 	return util.Sha256Hash(args...), nil
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
@@ -63,5 +64,7 @@ func (stg *Copy) GetDependencies(ctx context.Context, c stage.Conveyor, cb conta
 
 	// TODO(staged-dockerfile): support --link option: https://docs.docker.com/engine/reference/builder/#copy---link
 
+	digest_inputs.Add(ctx, "COPY instruction", args...)
+
 	return util.Sha256Hash(args...), nil
 }
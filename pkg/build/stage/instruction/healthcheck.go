@@ -6,6 +6,7 @@ import (
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
@@ -31,5 +32,7 @@ func (stg *Healthcheck) GetDependencies(ctx context.Context, c stage.Conveyor, c
 	args = append(args, "StartPeriod", stg.instruction.Data.Health.StartPeriod.String())
 	args = append(args, "Retries", fmt.Sprintf("%d", stg.instruction.Data.Health.Retries))
 
+	digest_inputs.Add(ctx, "HEALTHCHECK instruction", args...)
+
 	return util.Sha256Hash(args...), nil
 }
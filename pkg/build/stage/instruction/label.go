@@ -5,6 +5,7 @@ import (
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
@@ -31,5 +32,7 @@ func (stg *Label) GetDependencies(ctx context.Context, c stage.Conveyor, cb cont
 		}
 	}
 
+	digest_inputs.Add(ctx, "LABEL instruction", args...)
+
 	return util.Sha256Hash(args...), nil
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
@@ -36,6 +37,24 @@ func (stg *Run) ExpandInstruction(c stage.Conveyor, env map[string]string) error
 	return nil
 }
 
+func (stg *Run) PrepareImage(ctx context.Context, c stage.Conveyor, cb container_backend.ContainerBackend, prevBuiltImage, stageImage *stage.StageImage, buildContextArchive container_backend.BuildContextArchiver) error {
+	for _, mnt := range stg.backendInstruction.GetMounts() {
+		if mnt.Type != instructions.MountTypeSecret {
+			continue
+		}
+
+		_, secrets, err := stg.PrepareSecrets(ctx, c)
+		if err != nil {
+			return fmt.Errorf("unable to prepare secrets: %w", err)
+		}
+		stg.backendInstruction.Secrets = secrets
+
+		break
+	}
+
+	return stg.Base.PrepareImage(ctx, c, cb, prevBuiltImage, stageImage, buildContextArchive)
+}
+
 func (stg *Run) GetDependencies(ctx context.Context, c stage.Conveyor, cb container_backend.ContainerBackend, prevImage, prevBuiltImage *stage.StageImage, buildContextArchive container_backend.BuildContextArchiver) (string, error) {
 	var args []string
 
@@ -90,6 +109,8 @@ func (stg *Run) GetDependencies(ctx context.Context, c stage.Conveyor, cb contai
 		}
 	}
 
+	digest_inputs.Add(ctx, "RUN instruction", args...)
+
 	return util.Sha256Hash(args...), nil
 }
 
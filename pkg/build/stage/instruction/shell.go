@@ -5,6 +5,7 @@ import (
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
@@ -26,5 +27,7 @@ func (stg *Shell) GetDependencies(ctx context.Context, c stage.Conveyor, cb cont
 
 	args = append(args, append([]string{"Shell"}, stg.instruction.Data.Shell...)...)
 
+	digest_inputs.Add(ctx, "SHELL instruction", args...)
+
 	return util.Sha256Hash(args...), nil
 }
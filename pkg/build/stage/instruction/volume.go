@@ -5,6 +5,7 @@ import (
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
@@ -26,5 +27,7 @@ func (stg *Volume) GetDependencies(ctx context.Context, c stage.Conveyor, cb con
 
 	args = append(args, append([]string{"Volumes"}, stg.instruction.Data.Volumes...)...)
 
+	digest_inputs.Add(ctx, "VOLUME instruction", args...)
+
 	return util.Sha256Hash(args...), nil
 }
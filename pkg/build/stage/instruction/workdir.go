@@ -5,6 +5,7 @@ import (
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
@@ -26,5 +27,7 @@ func (stg *Workdir) GetDependencies(ctx context.Context, c stage.Conveyor, cb co
 
 	args = append(args, "Path", stg.instruction.Data.Path)
 
+	digest_inputs.Add(ctx, "WORKDIR instruction", args...)
+
 	return util.Sha256Hash(args...), nil
 }
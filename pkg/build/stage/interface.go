@@ -37,6 +37,9 @@ type Interface interface {
 
 	SelectSuitableStage(_ context.Context, c Conveyor, stages []*image.StageDescription) (*image.StageDescription, error)
 
+	// GetBuildPlatform returns the platform the stage commands are run on if it differs from the target platform
+	GetBuildPlatform() string
+
 	HasPrevStage() bool
 	IsStapelStage() bool
 
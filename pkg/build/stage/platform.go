@@ -0,0 +1,65 @@
+package stage
+
+import (
+	"fmt"
+
+	"github.com/werf/werf/pkg/container_backend"
+	"github.com/werf/werf/pkg/container_backend/thirdparty/platformutil"
+)
+
+func (s *BaseStage) GetBuildPlatform() string {
+	return s.buildPlatform
+}
+
+// addPlatformEnvs passes the target and build platforms to the stage commands in the BuildKit-compatible environment variables
+// when the stages are run on the build platform, so that the commands could cross-compile the artifacts for the target platform
+func (s *BaseStage) addPlatformEnvs(c Conveyor, cb container_backend.ContainerBackend, stageImage *StageImage) error {
+	if s.buildPlatform == "" {
+		return nil
+	}
+
+	envs, err := platformEnvs(s.targetPlatform, s.buildPlatform)
+	if err != nil {
+		return err
+	}
+
+	// only this stage is run on the build platform, the image itself is built for the target platform
+	if s.buildPlatformBaseImage != "" {
+		if c.UseLegacyStapelBuilder(cb) {
+			return fmt.Errorf("running the selected stages on the build platform is supported only with the Buildah backend")
+		}
+
+		envs["TARGETROOT"] = container_backend.BuildPlatformTargetRootDir
+		stageImage.Builder.StapelStageBuilder().SetBuildPlatform(s.buildPlatform, s.buildPlatformBaseImage, envs)
+
+		return nil
+	}
+
+	if c.UseLegacyStapelBuilder(cb) {
+		stageImage.Builder.LegacyStapelStageBuilder().Container().RunOptions().AddEnv(envs)
+	} else {
+		stageImage.Builder.StapelStageBuilder().AddEnvs(envs)
+	}
+
+	return nil
+}
+
+func platformEnvs(targetPlatform, buildPlatform string) (map[string]string, error) {
+	envs := map[string]string{}
+	for prefix, platform := range map[string]string{
+		"TARGET": targetPlatform,
+		"BUILD":  buildPlatform,
+	} {
+		spec, err := platformutil.ParsePlatform(platform)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse platform %q: %w", platform, err)
+		}
+
+		envs[prefix+"PLATFORM"] = platform
+		envs[prefix+"OS"] = spec.OS
+		envs[prefix+"ARCH"] = spec.Architecture
+		envs[prefix+"VARIANT"] = spec.Variant
+	}
+
+	return envs, nil
+}
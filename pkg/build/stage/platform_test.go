@@ -0,0 +1,87 @@
+package stage
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/container_backend"
+	"github.com/werf/werf/pkg/container_backend/stage_builder"
+)
+
+var _ = Describe("platformEnvs", func() {
+	It("should pass the target and build platforms components", func() {
+		envs, err := platformEnvs("linux/arm/v7", "linux/amd64")
+		Expect(err).To(Succeed())
+		Expect(envs).To(Equal(map[string]string{
+			"TARGETPLATFORM": "linux/arm/v7",
+			"TARGETOS":       "linux",
+			"TARGETARCH":     "arm",
+			"TARGETVARIANT":  "v7",
+			"BUILDPLATFORM":  "linux/amd64",
+			"BUILDOS":        "linux",
+			"BUILDARCH":      "amd64",
+			"BUILDVARIANT":   "",
+		}))
+	})
+
+	It("should fail on invalid platform", func() {
+		_, err := platformEnvs("linux/unknown/arch/v1", "linux/amd64")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("BaseStage platform envs", func() {
+	newStageImage := func() (*StageImage, *stage_builder.StageBuilder) {
+		img := NewLegacyImageStub()
+		stageBuilder := stage_builder.NewStageBuilder(NewContainerBackendStub(), "", img)
+		return &StageImage{Image: img, Builder: stageBuilder}, stageBuilder
+	}
+
+	newConveyor := func(useLegacyStapelBuilder bool) Conveyor {
+		return &squashConveyorStub{
+			ConveyorStub:           NewConveyorStub(NewGiterminismManagerStub(NewLocalGitRepoStub("9d8059842b6fde712c58315ca0ab4713d90761c0"), NewGiterminismInspectorStub()), nil, nil, nil),
+			useLegacyStapelBuilder: useLegacyStapelBuilder,
+		}
+	}
+
+	It("should add the envs to the image if all stages are run on the build platform", func() {
+		stage := NewBaseStage(Install, &BaseStageOptions{TargetPlatform: "linux/arm64", BuildPlatform: "linux/amd64"})
+		stageImage, stageBuilder := newStageImage()
+
+		Expect(stage.addPlatformEnvs(newConveyor(false), nil, stageImage)).To(Succeed())
+
+		options := stageBuilder.GetStapelStageBuilderImplementation()
+		Expect(options.Envs).To(HaveKeyWithValue("TARGETPLATFORM", "linux/arm64"))
+		Expect(options.BuildPlatform).To(BeEmpty())
+	})
+
+	It("should run the selected stage commands in the build platform base image", func() {
+		stage := NewBaseStage(Install, &BaseStageOptions{TargetPlatform: "linux/arm64", BuildPlatform: "linux/amd64", BuildPlatformBaseImage: "golang:1.20"})
+		stageImage, stageBuilder := newStageImage()
+
+		Expect(stage.addPlatformEnvs(newConveyor(false), nil, stageImage)).To(Succeed())
+
+		options := stageBuilder.GetStapelStageBuilderImplementation()
+		Expect(options.Envs).To(BeEmpty())
+		Expect(options.BuildPlatform).To(Equal("linux/amd64"))
+		Expect(options.BuildPlatformBaseImage).To(Equal("golang:1.20"))
+		Expect(options.BuildPlatformEnvs).To(HaveKeyWithValue("TARGETPLATFORM", "linux/arm64"))
+		Expect(options.BuildPlatformEnvs).To(HaveKeyWithValue("TARGETROOT", container_backend.BuildPlatformTargetRootDir))
+	})
+
+	It("should fail to run the selected stage on the build platform with the legacy stapel builder", func() {
+		stage := NewBaseStage(Install, &BaseStageOptions{TargetPlatform: "linux/arm64", BuildPlatform: "linux/amd64", BuildPlatformBaseImage: "golang:1.20"})
+
+		stageImage, _ := newStageImage()
+
+		Expect(stage.addPlatformEnvs(newConveyor(true), nil, stageImage)).NotTo(Succeed())
+	})
+
+	It("should not add anything if the stage is run on the target platform", func() {
+		stage := NewBaseStage(Install, &BaseStageOptions{TargetPlatform: "linux/arm64"})
+		stageImage, stageBuilder := newStageImage()
+
+		Expect(stage.addPlatformEnvs(newConveyor(false), nil, stageImage)).To(Succeed())
+		Expect(stageBuilder.GetStapelStageBuilderImplementation()).To(BeNil())
+	})
+})
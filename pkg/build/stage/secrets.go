@@ -0,0 +1,204 @@
+package stage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/container_backend"
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/util"
+)
+
+// secretsMountDir is the directory with the secrets files available during the user stages of the stapel image
+const secretsMountDir = "/run/secrets"
+
+// secretsTmpfsDir is the tmpfs-backed directory for the secrets files, so the secrets values do not touch the host disk
+var secretsTmpfsDir = "/dev/shm"
+
+// ImageSecrets are the secrets of the image shared by all image stages.
+// The secrets files are written on the first use and removed by Cleanup after the image stages are processed
+type ImageSecrets struct {
+	configSecrets []*config.Secret
+	imageTmpDir   string
+
+	mux   sync.Mutex
+	dir   string
+	specs []string
+}
+
+func NewImageSecrets(configSecrets []*config.Secret, imageTmpDir string) *ImageSecrets {
+	return &ImageSecrets{configSecrets: configSecrets, imageTmpDir: imageTmpDir}
+}
+
+// Prepare writes the values of the image secrets to the files named by the secrets ids in the new directory once for the image.
+// The directory is created with 0700 permissions in the tmpfs (see secretsRootDir) and removed if any secret cannot be written.
+// Returns the directory and the secrets in the id=ID,type=file,src=PATH format or empty values if there are no secrets
+func (s *ImageSecrets) Prepare(ctx context.Context, c Conveyor) (_ string, _ []string, err error) {
+	if s == nil || len(s.configSecrets) == 0 {
+		return "", nil, nil
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.dir != "" {
+		return s.dir, s.specs, nil
+	}
+
+	secretsRootDir := s.secretsRootDir(ctx)
+	if err := os.MkdirAll(secretsRootDir, 0o700); err != nil {
+		return "", nil, fmt.Errorf("unable to create dir %s: %w", secretsRootDir, err)
+	}
+
+	dir, err := os.MkdirTemp(secretsRootDir, "werf-secrets-")
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to create secrets dir: %w", err)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		if removeErr := os.RemoveAll(dir); removeErr != nil {
+			err = fmt.Errorf("%w (unable to remove secrets dir %s: %s)", err, dir, removeErr)
+		}
+	}()
+
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return "", nil, fmt.Errorf("unable to set secrets dir %s permissions: %w", dir, err)
+	}
+
+	specs, err := writeSecretsFiles(ctx, c, s.configSecrets, dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.dir = dir
+	s.specs = specs
+
+	return s.dir, s.specs, nil
+}
+
+// secretsRootDir returns the tmpfs-backed directory for the secrets files if available (Linux),
+// otherwise the secrets files are written into the image temporary directory
+func (s *ImageSecrets) secretsRootDir(ctx context.Context) string {
+	if runtime.GOOS == "linux" {
+		if info, err := os.Stat(secretsTmpfsDir); err == nil && info.IsDir() {
+			return secretsTmpfsDir
+		}
+	}
+
+	logboek.Context(ctx).Warn().LogF("WARNING: tmpfs directory %s is not available, the secrets files are written into the werf temporary directory\n", secretsTmpfsDir)
+
+	return filepath.Join(s.imageTmpDir, "secrets")
+}
+
+// Cleanup removes the secrets files written by Prepare
+func (s *ImageSecrets) Cleanup() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.dir == "" {
+		return nil
+	}
+
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("unable to remove secrets dir %s: %w", s.dir, err)
+	}
+
+	s.dir = ""
+	s.specs = nil
+
+	return nil
+}
+
+func writeSecretsFiles(ctx context.Context, c Conveyor, configSecrets []*config.Secret, dir string) ([]string, error) {
+	projectDir := c.GiterminismManager().ProjectDir()
+
+	var encoder *secret.YamlEncoder
+	var specs []string
+	for _, secretCfg := range configSecrets {
+		var value []byte
+		var err error
+		switch {
+		case secretCfg.Env != "":
+			envValue, ok := os.LookupEnv(secretCfg.Env)
+			if !ok {
+				return nil, fmt.Errorf("environment variable %s for secret %q is not set", secretCfg.Env, secretCfg.ID)
+			}
+			value = []byte(envValue)
+		case secretCfg.Src != "":
+			src := secretCfg.Src
+			switch {
+			case strings.HasPrefix(src, "~"):
+				src = util.ExpandPath(src)
+			case !filepath.IsAbs(src):
+				src = filepath.Join(projectDir, src)
+			}
+
+			value, err = os.ReadFile(src)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read secret %q file: %w", secretCfg.ID, err)
+			}
+		case secretCfg.EncryptedValue != "":
+			if encoder == nil {
+				encoder, err = secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{}).GetYamlEncoder(ctx, projectDir, nil)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			value, err = encoder.Decrypt([]byte(secretCfg.EncryptedValue))
+			if err != nil {
+				return nil, fmt.Errorf("unable to decrypt secret %q: %w", secretCfg.ID, err)
+			}
+		}
+
+		path := filepath.Join(dir, secretCfg.ID)
+		if err := os.WriteFile(path, value, 0o600); err != nil {
+			return nil, fmt.Errorf("unable to write secret %q file: %w", secretCfg.ID, err)
+		}
+
+		specs = append(specs, fmt.Sprintf("id=%s,type=file,src=%s", secretCfg.ID, path))
+	}
+
+	return specs, nil
+}
+
+// PrepareSecrets returns the secrets files of the image prepared once for all image stages (see ImageSecrets.Prepare)
+func (s *BaseStage) PrepareSecrets(ctx context.Context, c Conveyor) (string, []string, error) {
+	return s.secrets.Prepare(ctx, c)
+}
+
+// addSecretsVolume mounts the tmpfs-backed directory with the image secrets files into the secretsMountDir
+func (s *BaseStage) addSecretsVolume(ctx context.Context, c Conveyor, cr container_backend.ContainerBackend, stageImage *StageImage) error {
+	dir, _, err := s.PrepareSecrets(ctx, c)
+	if err != nil {
+		return fmt.Errorf("unable to prepare secrets: %w", err)
+	}
+
+	if dir == "" {
+		return nil
+	}
+
+	volume := fmt.Sprintf("%s:%s", dir, secretsMountDir)
+	if c.UseLegacyStapelBuilder(cr) {
+		stageImage.Builder.LegacyStapelStageBuilder().Container().RunOptions().AddVolume(volume)
+	} else {
+		stageImage.Builder.StapelStageBuilder().AddBuildVolumes(volume)
+	}
+
+	return nil
+}
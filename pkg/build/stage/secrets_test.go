@@ -0,0 +1,139 @@
+package stage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/secret"
+)
+
+var _ = Describe("BaseStage secrets", func() {
+	var conveyor *ConveyorStub
+	var projectDir, imageTmpDir, tmpfsDir string
+
+	BeforeEach(func() {
+		projectDir = GinkgoT().TempDir()
+		imageTmpDir = GinkgoT().TempDir()
+
+		tmpfsDir = GinkgoT().TempDir()
+		defaultSecretsTmpfsDir := secretsTmpfsDir
+		secretsTmpfsDir = tmpfsDir
+		DeferCleanup(func() { secretsTmpfsDir = defaultSecretsTmpfsDir })
+
+		giterminismManager := NewGiterminismManagerStub(NewLocalGitRepoStub("9d8059842b6fde712c58315ca0ab4713d90761c0"), NewGiterminismInspectorStub())
+		giterminismManager.projectDir = projectDir
+		conveyor = NewConveyorStub(giterminismManager, nil, nil, nil)
+	})
+
+	It("should write secrets values from all sources to the files named by ids", func() {
+		Expect(os.Setenv("WERF_TEST_SECRET_TOKEN", "token-value")).To(Succeed())
+		DeferCleanup(os.Unsetenv, "WERF_TEST_SECRET_TOKEN")
+
+		Expect(os.WriteFile(filepath.Join(projectDir, "key"), []byte("key-value"), 0o600)).To(Succeed())
+
+		key, err := secrets_manager.GenerateSecretKey()
+		Expect(err).To(Succeed())
+		Expect(os.Setenv("WERF_SECRET_KEY", string(key))).To(Succeed())
+		DeferCleanup(os.Unsetenv, "WERF_SECRET_KEY")
+
+		encoder, err := secret.NewAesEncoder(key)
+		Expect(err).To(Succeed())
+		encryptedValue, err := encoder.Encrypt([]byte("encrypted-value"))
+		Expect(err).To(Succeed())
+
+		stg := NewBaseStage("install", &BaseStageOptions{
+			ImageName:   "example-image",
+			ImageTmpDir: imageTmpDir,
+			Secrets: NewImageSecrets([]*config.Secret{
+				{ID: "token", Env: "WERF_TEST_SECRET_TOKEN"},
+				{ID: "key", Src: "key"},
+				{ID: "conf", EncryptedValue: string(encryptedValue)},
+			}, imageTmpDir),
+		})
+
+		dir, specs, err := stg.PrepareSecrets(context.Background(), conveyor)
+		Expect(err).To(Succeed())
+		Expect(dir).To(HavePrefix(tmpfsDir))
+
+		dirInfo, err := os.Stat(dir)
+		Expect(err).To(Succeed())
+		Expect(dirInfo.Mode().Perm()).To(Equal(os.FileMode(0o700)))
+		Expect(specs).To(Equal([]string{
+			fmt.Sprintf("id=token,type=file,src=%s", filepath.Join(dir, "token")),
+			fmt.Sprintf("id=key,type=file,src=%s", filepath.Join(dir, "key")),
+			fmt.Sprintf("id=conf,type=file,src=%s", filepath.Join(dir, "conf")),
+		}))
+
+		for id, expectedValue := range map[string]string{
+			"token": "token-value",
+			"key":   "key-value",
+			"conf":  "encrypted-value",
+		} {
+			data, err := os.ReadFile(filepath.Join(dir, id))
+			Expect(err).To(Succeed())
+			Expect(string(data)).To(Equal(expectedValue))
+
+			info, err := os.Stat(filepath.Join(dir, id))
+			Expect(err).To(Succeed())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+		}
+	})
+
+	It("should do nothing if there are no secrets", func() {
+		stg := NewBaseStage("install", &BaseStageOptions{ImageName: "example-image", ImageTmpDir: imageTmpDir})
+
+		dir, specs, err := stg.PrepareSecrets(context.Background(), conveyor)
+		Expect(err).To(Succeed())
+		Expect(dir).To(BeEmpty())
+		Expect(specs).To(BeEmpty())
+	})
+
+	It("should fail if the secret environment variable is not set", func() {
+		stg := NewBaseStage("install", &BaseStageOptions{
+			ImageName:   "example-image",
+			ImageTmpDir: imageTmpDir,
+			Secrets:     NewImageSecrets([]*config.Secret{{ID: "token", Env: "WERF_TEST_SECRET_NO_SUCH_ENV"}}, imageTmpDir),
+		})
+
+		_, _, err := stg.PrepareSecrets(context.Background(), conveyor)
+		Expect(err).To(MatchError(ContainSubstring("WERF_TEST_SECRET_NO_SUCH_ENV")))
+
+		entries, err := os.ReadDir(tmpfsDir)
+		Expect(err).To(Succeed())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should prepare secrets once for all image stages and remove them on cleanup", func() {
+		Expect(os.Setenv("WERF_TEST_SECRET_TOKEN", "token-value")).To(Succeed())
+		DeferCleanup(os.Unsetenv, "WERF_TEST_SECRET_TOKEN")
+
+		secrets := NewImageSecrets([]*config.Secret{{ID: "token", Env: "WERF_TEST_SECRET_TOKEN"}}, imageTmpDir)
+		installStage := NewBaseStage("install", &BaseStageOptions{ImageName: "example-image", ImageTmpDir: imageTmpDir, Secrets: secrets})
+		setupStage := NewBaseStage("setup", &BaseStageOptions{ImageName: "example-image", ImageTmpDir: imageTmpDir, Secrets: secrets})
+
+		installDir, installSpecs, err := installStage.PrepareSecrets(context.Background(), conveyor)
+		Expect(err).To(Succeed())
+		setupDir, setupSpecs, err := setupStage.PrepareSecrets(context.Background(), conveyor)
+		Expect(err).To(Succeed())
+		Expect(setupDir).To(Equal(installDir))
+		Expect(setupSpecs).To(Equal(installSpecs))
+
+		entries, err := os.ReadDir(tmpfsDir)
+		Expect(err).To(Succeed())
+		Expect(entries).To(HaveLen(1))
+
+		Expect(secrets.Cleanup()).To(Succeed())
+		Expect(installDir).NotTo(BeADirectory())
+
+		entries, err = os.ReadDir(tmpfsDir)
+		Expect(err).To(Succeed())
+		Expect(entries).To(BeEmpty())
+	})
+})
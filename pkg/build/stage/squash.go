@@ -0,0 +1,48 @@
+package stage
+
+import (
+	"context"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/container_backend"
+)
+
+func GenerateSquashStage(imageConfig *config.StapelImage, baseStageOptions *BaseStageOptions) *SquashStage {
+	if imageConfig.Squash {
+		return newSquashStage(baseStageOptions)
+	}
+
+	return nil
+}
+
+func newSquashStage(baseStageOptions *BaseStageOptions) *SquashStage {
+	s := &SquashStage{}
+	s.BaseStage = NewBaseStage(Squash, baseStageOptions)
+	return s
+}
+
+// SquashStage flattens the layers of the previous stages into a single layer.
+// The previous stages are stored and reused as usual, only the final image is squashed
+type SquashStage struct {
+	*BaseStage
+}
+
+func (s *SquashStage) GetDependencies(ctx context.Context, c Conveyor, cb container_backend.ContainerBackend, prevImage, prevBuiltImage *StageImage, buildContextArchive container_backend.BuildContextArchiver) (string, error) {
+	return "", nil
+}
+
+func (s *SquashStage) PrepareImage(ctx context.Context, c Conveyor, cb container_backend.ContainerBackend, prevBuiltImage, stageImage *StageImage, buildContextArchive container_backend.BuildContextArchiver) error {
+	if c.UseLegacyStapelBuilder(cb) {
+		stageImage.Image.SetCommitChangeOptions(container_backend.LegacyCommitChangeOptions{Squash: true})
+	}
+
+	if err := s.BaseStage.PrepareImage(ctx, c, cb, prevBuiltImage, stageImage, nil); err != nil {
+		return err
+	}
+
+	if !c.UseLegacyStapelBuilder(cb) {
+		stageImage.Builder.StapelStageBuilder().SetSquash(true)
+	}
+
+	return nil
+}
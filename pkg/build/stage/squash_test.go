@@ -0,0 +1,77 @@
+package stage
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/container_backend"
+	"github.com/werf/werf/pkg/container_backend/stage_builder"
+	imagePkg "github.com/werf/werf/pkg/image"
+)
+
+type squashConveyorStub struct {
+	*ConveyorStub
+
+	useLegacyStapelBuilder bool
+}
+
+func (c *squashConveyorStub) UseLegacyStapelBuilder(_ container_backend.ContainerBackend) bool {
+	return c.useLegacyStapelBuilder
+}
+
+var _ = Describe("SquashStage", func() {
+	It("should be generated only for the image with squash directive", func() {
+		Expect(GenerateSquashStage(&config.StapelImage{}, &BaseStageOptions{})).To(BeNil())
+
+		stage := GenerateSquashStage(&config.StapelImage{Squash: true}, &BaseStageOptions{})
+		Expect(stage).NotTo(BeNil())
+		Expect(stage.Name()).To(Equal(Squash))
+	})
+
+	It("should not depend on anything but the previous stages", func() {
+		stage := newSquashStage(&BaseStageOptions{})
+
+		dependencies, err := stage.GetDependencies(context.Background(), nil, nil, nil, nil, nil)
+		Expect(err).To(Succeed())
+		Expect(dependencies).To(BeEmpty())
+	})
+
+	DescribeTable("preparing image",
+		func(useLegacyStapelBuilder bool) {
+			ctx := context.Background()
+
+			conveyor := &squashConveyorStub{
+				ConveyorStub:           NewConveyorStub(NewGiterminismManagerStub(NewLocalGitRepoStub("9d8059842b6fde712c58315ca0ab4713d90761c0"), NewGiterminismInspectorStub()), nil, nil, nil),
+				useLegacyStapelBuilder: useLegacyStapelBuilder,
+			}
+			containerBackend := NewContainerBackendStub()
+			stage := newSquashStage(&BaseStageOptions{
+				ImageName:   "example-image",
+				ProjectName: "example-project",
+			})
+
+			img := NewLegacyImageStub()
+			stageBuilder := stage_builder.NewStageBuilder(containerBackend, "", img)
+			stageImage := &StageImage{
+				Image:   img,
+				Builder: stageBuilder,
+			}
+
+			Expect(stage.PrepareImage(ctx, conveyor, containerBackend, nil, stageImage, nil)).To(Succeed())
+
+			if useLegacyStapelBuilder {
+				Expect(img._CommitChangeOptions.Squash).To(BeTrue())
+				Expect(img._Container._ServiceCommitChangeOptions.Labels).To(HaveKeyWithValue(imagePkg.WerfProjectRepoCommitLabel, "9d8059842b6fde712c58315ca0ab4713d90761c0"))
+			} else {
+				Expect(img._CommitChangeOptions.Squash).To(BeFalse())
+				Expect(stageBuilder.GetStapelStageBuilderImplementation().Squash).To(BeTrue())
+				Expect(stageBuilder.GetStapelStageBuilderImplementation().Labels).To(ContainElement(imagePkg.WerfProjectRepoCommitLabel + "=9d8059842b6fde712c58315ca0ab4713d90761c0"))
+			}
+		},
+		Entry("should squash the committed container with the legacy stapel builder", true),
+		Entry("should squash the built stage with the stapel builder", false),
+	)
+})
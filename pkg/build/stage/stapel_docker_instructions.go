@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_backend"
 	"github.com/werf/werf/pkg/util"
@@ -49,6 +50,16 @@ func (s *StapelDockerInstructionsStage) GetDependencies(ctx context.Context, c C
 	args = append(args, s.instructions.User)
 	args = append(args, s.instructions.HealthCheck)
 
+	digest_inputs.Add(ctx, "Volume", s.instructions.Volume...)
+	digest_inputs.Add(ctx, "Expose", s.instructions.Expose...)
+	digest_inputs.Add(ctx, "Env", mapToSortedArgs(s.instructions.Env)...)
+	digest_inputs.Add(ctx, "Label", mapToSortedArgs(s.instructions.Label)...)
+	digest_inputs.Add(ctx, "Cmd", s.instructions.Cmd)
+	digest_inputs.Add(ctx, "Entrypoint", s.instructions.Entrypoint)
+	digest_inputs.Add(ctx, "Workdir", s.instructions.Workdir)
+	digest_inputs.Add(ctx, "User", s.instructions.User)
+	digest_inputs.Add(ctx, "HealthCheck", s.instructions.HealthCheck)
+
 	return util.Sha256Hash(args...), nil
 }
 
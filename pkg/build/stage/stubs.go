@@ -17,7 +17,8 @@ import (
 type LegacyImageStub struct {
 	container_backend.LegacyImageInterface
 
-	_Container *LegacyContainerStub
+	_Container           *LegacyContainerStub
+	_CommitChangeOptions container_backend.LegacyCommitChangeOptions
 }
 
 func NewLegacyImageStub() *LegacyImageStub {
@@ -30,6 +31,10 @@ func (img *LegacyImageStub) Container() container_backend.LegacyContainer {
 	return img._Container
 }
 
+func (img *LegacyImageStub) SetCommitChangeOptions(opts container_backend.LegacyCommitChangeOptions) {
+	img._CommitChangeOptions = opts
+}
+
 type LegacyContainerStub struct {
 	container_backend.LegacyContainer
 
@@ -49,11 +54,18 @@ func (c *LegacyContainerStub) ServiceCommitChangeOptions() container_backend.Leg
 type LegacyContainerOptionsStub struct {
 	container_backend.LegacyContainerOptions
 
-	Env map[string]string
+	Env    map[string]string
+	Labels map[string]string
 }
 
 func NewLegacyContainerOptionsStub() *LegacyContainerOptionsStub {
-	return &LegacyContainerOptionsStub{Env: make(map[string]string)}
+	return &LegacyContainerOptionsStub{Env: make(map[string]string), Labels: make(map[string]string)}
+}
+
+func (opts *LegacyContainerOptionsStub) AddLabel(labels map[string]string) {
+	for k, v := range labels {
+		opts.Labels[k] = v
+	}
 }
 
 func (opts *LegacyContainerOptionsStub) AddEnv(envs map[string]string) {
@@ -84,6 +96,10 @@ func (c *ConveyorStub) UseLegacyStapelBuilder(cr container_backend.ContainerBack
 	return true
 }
 
+func (c *ConveyorStub) IsReproducible() bool {
+	return false
+}
+
 func (c *ConveyorStub) GetImageNameForLastImageStage(targetPlatform, imageName string) string {
 	return c.lastStageImageNameByImageName[imageName]
 }
@@ -117,6 +133,7 @@ type GiterminismManagerStub struct {
 
 	inspector    giterminism_manager.Inspector
 	localGitRepo git_repo.GitRepo
+	projectDir   string
 }
 
 func NewGiterminismManagerStub(localGitRepo git_repo.GitRepo, inspector giterminism_manager.Inspector) *GiterminismManagerStub {
@@ -148,6 +165,10 @@ func (manager *GiterminismManagerStub) Inspector() giterminism_manager.Inspector
 	return manager.inspector
 }
 
+func (manager *GiterminismManagerStub) ProjectDir() string {
+	return manager.projectDir
+}
+
 type LocalGitRepoStub struct {
 	git_repo.GitRepo
 
@@ -2,10 +2,12 @@ package stage
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/werf/logboek"
 	"github.com/werf/werf/pkg/build/builder"
+	"github.com/werf/werf/pkg/build/digest_inputs"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/util"
 )
@@ -51,6 +53,7 @@ func (s *UserStage) getStageDependenciesChecksum(ctx context.Context, c Conveyor
 		}
 
 		args = append(args, checksum)
+		digest_inputs.Add(ctx, fmt.Sprintf("Git mapping %s %s dependencies checksum", gitMapping.GetFullName(), name), checksum)
 	}
 
 	return util.Sha256Hash(args...), nil
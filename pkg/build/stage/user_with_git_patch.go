@@ -40,6 +40,10 @@ func (s *UserWithGitPatchStage) PrepareImage(ctx context.Context, c Conveyor, cb
 		return err
 	}
 
+	if err := s.BaseStage.addSecretsVolume(ctx, c, cb, stageImage); err != nil {
+		return err
+	}
+
 	if isPatchEmpty, err := s.GitPatchStage.IsEmpty(ctx, c, prevBuiltImage); err != nil {
 		return err
 	} else if !isPatchEmpty {
@@ -0,0 +1,189 @@
+package build
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/werf/pkg/build/image"
+	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/build/stages_graph"
+	"github.com/werf/werf/pkg/config"
+)
+
+type StagesGraphOptions struct {
+	// WithCacheStatus enables checking of the stages in the stages storage.
+	// The stages are checked until the first stage which is not found, the following stages are marked as uncached
+	WithCacheStatus bool
+}
+
+// GetStagesGraph returns the graph of the images stages for all target platforms with the base images, imports and dependencies
+func (c *Conveyor) GetStagesGraph(ctx context.Context, opts StagesGraphOptions) (*stages_graph.Graph, error) {
+	if err := c.determineStages(ctx); err != nil {
+		return nil, err
+	}
+
+	foundByStageKey := map[string]bool{}
+	if opts.WithCacheStatus {
+		explanations, err := c.explainDeterminedStages(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, explanation := range explanations {
+			foundByStageKey[stagesGraphStageKey(explanation.ImageName, explanation.Platform, explanation.Digest)] = explanation.Found
+		}
+	}
+
+	g := stages_graph.NewGraph()
+	images := c.imagesTree.GetImages()
+
+	stagesNodes := map[*image.Image][]*stages_graph.Node{}
+	for _, img := range images {
+		var prevNode *stages_graph.Node
+		for _, stg := range img.GetStages() {
+			node := g.AddNode(&stages_graph.Node{
+				Type:      stages_graph.NodeStage,
+				ImageName: img.GetName(),
+				Platform:  img.TargetPlatform,
+				Name:      stageLogName(stg),
+				Digest:    stg.GetDigest(),
+			})
+
+			if found, ok := foundByStageKey[stagesGraphStageKey(node.ImageName, node.Platform, node.Digest)]; ok && node.Digest != "" {
+				if found {
+					node.Status = stages_graph.StatusCached
+				} else {
+					node.Status = stages_graph.StatusUncached
+				}
+			}
+
+			if prevNode != nil {
+				g.AddEdge(prevNode, node, stages_graph.EdgeStage)
+			}
+			prevNode = node
+
+			stagesNodes[img] = append(stagesNodes[img], node)
+		}
+	}
+
+	findImage := func(name, platform string) *image.Image {
+		for _, img := range images {
+			if img.GetName() == name && img.TargetPlatform == platform {
+				return img
+			}
+		}
+		return nil
+	}
+
+	lastStageNode := func(name, platform string) *stages_graph.Node {
+		img := findImage(name, platform)
+		if img == nil || len(stagesNodes[img]) == 0 {
+			return nil
+		}
+		return stagesNodes[img][len(stagesNodes[img])-1]
+	}
+
+	stageNode := func(name, platform, stageName string) *stages_graph.Node {
+		img := findImage(name, platform)
+		if img == nil {
+			return nil
+		}
+
+		for ind, stg := range img.GetStages() {
+			if string(stg.Name()) == stageName {
+				return stagesNodes[img][ind]
+			}
+		}
+
+		return lastStageNode(name, platform)
+	}
+
+	addEdge := func(from, to *stages_graph.Node, edgeType stages_graph.EdgeType) {
+		if from != nil && to != nil {
+			g.AddEdge(from, to, edgeType)
+		}
+	}
+
+	baseImagesNodes := map[string]*stages_graph.Node{}
+	for _, img := range images {
+		nodes := stagesNodes[img]
+		if len(nodes) == 0 {
+			continue
+		}
+		firstNode := nodes[0]
+
+		switch img.GetBaseImageType() {
+		case image.StageAsBaseImage:
+			addEdge(lastStageNode(img.GetBaseImageName(), img.TargetPlatform), firstNode, stages_graph.EdgeFrom)
+		case image.ImageFromRegistryAsBaseImage:
+			reference := img.GetBaseImageReference()
+			baseNode, ok := baseImagesNodes[reference]
+			if !ok {
+				baseNode = g.AddNode(&stages_graph.Node{Type: stages_graph.NodeBaseImage, Name: reference})
+				baseImagesNodes[reference] = baseNode
+			}
+			addEdge(baseNode, firstNode, stages_graph.EdgeFrom)
+		}
+
+		for ind, stg := range img.GetStages() {
+			switch typedStage := stg.(type) {
+			case interface {
+				GetImports() []*config.Import
+				GetDependenciesConfigs() []*config.Dependency
+			}:
+				for _, elm := range typedStage.GetImports() {
+					sourceImageName := elm.ImageName
+					if sourceImageName == "" {
+						sourceImageName = elm.ArtifactName
+					}
+
+					if elm.Stage != "" {
+						addEdge(stageNode(sourceImageName, img.TargetPlatform, elm.Stage), nodes[ind], stages_graph.EdgeImport)
+					} else {
+						addEdge(lastStageNode(sourceImageName, img.TargetPlatform), nodes[ind], stages_graph.EdgeImport)
+					}
+				}
+
+				for _, dep := range typedStage.GetDependenciesConfigs() {
+					addEdge(lastStageNode(dep.ImageName, img.TargetPlatform), nodes[ind], stages_graph.EdgeDependency)
+				}
+			case interface{ GetStageRefsImagesNames() []string }:
+				for _, name := range typedStage.GetStageRefsImagesNames() {
+					addEdge(lastStageNode(name, img.TargetPlatform), nodes[ind], stages_graph.EdgeImport)
+				}
+			}
+		}
+
+		if img.IsDockerfileImage && img.IsDockerfileTargetStage && img.DockerfileImageConfig != nil {
+			for _, dep := range img.DockerfileImageConfig.Dependencies {
+				addEdge(lastStageNode(dep.ImageName, img.TargetPlatform), firstNode, stages_graph.EdgeDependency)
+			}
+		}
+	}
+
+	for _, desc := range c.imagesTree.GetImagesByName(true) {
+		name, platformImages := desc.Unpair()
+
+		imageNode := g.AddNode(&stages_graph.Node{Type: stages_graph.NodeImage, ImageName: name, Name: name})
+		for _, img := range platformImages {
+			addEdge(lastStageNode(img.GetName(), img.TargetPlatform), imageNode, stages_graph.EdgePlatform)
+		}
+	}
+
+	if opts.WithCacheStatus {
+		g.PropagateUncachedStatus()
+	}
+
+	return g, nil
+}
+
+func stagesGraphStageKey(imageName, platform, digest string) string {
+	return fmt.Sprintf("%s/%s/%s", imageName, platform, digest)
+}
+
+func stageLogName(stg stage.Interface) string {
+	if named, ok := stg.(interface{ LogName() string }); ok {
+		return named.LogName()
+	}
+	return string(stg.Name())
+}
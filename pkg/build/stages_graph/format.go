@@ -0,0 +1,171 @@
+package stages_graph
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatDOT     Format = "dot"
+	FormatMermaid Format = "mermaid"
+)
+
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case FormatDOT, FormatMermaid:
+		return Format(value), nil
+	default:
+		return "", fmt.Errorf("unsupported graph format %q: %s or %s expected", value, FormatDOT, FormatMermaid)
+	}
+}
+
+func (g *Graph) Render(format Format) []byte {
+	switch format {
+	case FormatMermaid:
+		return g.Mermaid()
+	default:
+		return g.DOT()
+	}
+}
+
+var dotStatusFillColor = map[Status]string{
+	StatusCached:   "#c8e6c9",
+	StatusUncached: "#ffcdd2",
+}
+
+// DOT renders the graph in the Graphviz DOT language
+func (g *Graph) DOT() []byte {
+	var buf bytes.Buffer
+
+	writeNode := func(indent string, node *Node) {
+		attrs := []string{fmt.Sprintf("label=%s", dotQuote(strings.Join(nodeLabelLines(node), "\n")))}
+		switch node.Type {
+		case NodeBaseImage:
+			attrs = append(attrs, "shape=component")
+		case NodeImage:
+			attrs = append(attrs, "shape=box3d")
+		}
+		if color, ok := dotStatusFillColor[node.Status]; ok {
+			attrs = append(attrs, "style=filled", fmt.Sprintf("fillcolor=%s", dotQuote(color)))
+		}
+
+		fmt.Fprintf(&buf, "%s%s [%s];\n", indent, node.ID, strings.Join(attrs, ", "))
+	}
+
+	buf.WriteString("digraph stages {\n")
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=box];\n")
+
+	clusters, other := g.clusters()
+	for ind, cluster := range clusters {
+		fmt.Fprintf(&buf, "  subgraph cluster_%d {\n", ind)
+		fmt.Fprintf(&buf, "    label=%s;\n", dotQuote(clusterLabel(cluster[0])))
+		for _, node := range cluster {
+			writeNode("    ", node)
+		}
+		buf.WriteString("  }\n")
+	}
+
+	for _, node := range other {
+		writeNode("  ", node)
+	}
+
+	for _, edge := range g.Edges {
+		if edge.Type == EdgeStage {
+			fmt.Fprintf(&buf, "  %s -> %s;\n", edge.From, edge.To)
+			continue
+		}
+
+		style := "solid"
+		if edge.Type == EdgeImport || edge.Type == EdgeDependency {
+			style = "dashed"
+		}
+		fmt.Fprintf(&buf, "  %s -> %s [label=%s, style=%s];\n", edge.From, edge.To, dotQuote(string(edge.Type)), style)
+	}
+
+	buf.WriteString("}\n")
+
+	return buf.Bytes()
+}
+
+func dotQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return `"` + value + `"`
+}
+
+// Mermaid renders the graph as the Mermaid flowchart
+func (g *Graph) Mermaid() []byte {
+	var buf bytes.Buffer
+
+	writeNode := func(indent string, node *Node) {
+		label := mermaidQuote(strings.Join(nodeLabelLines(node), "\n"))
+		switch node.Type {
+		case NodeBaseImage:
+			fmt.Fprintf(&buf, "%s%s[(%s)]\n", indent, node.ID, label)
+		case NodeImage:
+			fmt.Fprintf(&buf, "%s%s[[%s]]\n", indent, node.ID, label)
+		default:
+			fmt.Fprintf(&buf, "%s%s[%s]\n", indent, node.ID, label)
+		}
+	}
+
+	buf.WriteString("flowchart LR\n")
+
+	clusters, other := g.clusters()
+	for ind, cluster := range clusters {
+		fmt.Fprintf(&buf, "  subgraph cluster_%d[%s]\n", ind, mermaidQuote(clusterLabel(cluster[0])))
+		for _, node := range cluster {
+			writeNode("    ", node)
+		}
+		buf.WriteString("  end\n")
+	}
+
+	for _, node := range other {
+		writeNode("  ", node)
+	}
+
+	for _, edge := range g.Edges {
+		switch edge.Type {
+		case EdgeStage:
+			fmt.Fprintf(&buf, "  %s --> %s\n", edge.From, edge.To)
+		case EdgeImport, EdgeDependency:
+			fmt.Fprintf(&buf, "  %s -. %s .-> %s\n", edge.From, edge.Type, edge.To)
+		default:
+			fmt.Fprintf(&buf, "  %s -- %s --> %s\n", edge.From, edge.Type, edge.To)
+		}
+	}
+
+	var cachedIDs, uncachedIDs []string
+	for _, node := range g.Nodes {
+		switch node.Status {
+		case StatusCached:
+			cachedIDs = append(cachedIDs, node.ID)
+		case StatusUncached:
+			uncachedIDs = append(uncachedIDs, node.ID)
+		}
+	}
+
+	if len(cachedIDs) > 0 || len(uncachedIDs) > 0 {
+		fmt.Fprintf(&buf, "  classDef %s fill:%s\n", StatusCached, dotStatusFillColor[StatusCached])
+		fmt.Fprintf(&buf, "  classDef %s fill:%s\n", StatusUncached, dotStatusFillColor[StatusUncached])
+	}
+	if len(cachedIDs) > 0 {
+		fmt.Fprintf(&buf, "  class %s %s\n", strings.Join(cachedIDs, ","), StatusCached)
+	}
+	if len(uncachedIDs) > 0 {
+		fmt.Fprintf(&buf, "  class %s %s\n", strings.Join(uncachedIDs, ","), StatusUncached)
+	}
+
+	return buf.Bytes()
+}
+
+func mermaidQuote(value string) string {
+	value = strings.ReplaceAll(value, `"`, "#quot;")
+	value = strings.ReplaceAll(value, "\n", "<br/>")
+	return `"` + value + `"`
+}
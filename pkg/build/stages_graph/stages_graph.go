@@ -0,0 +1,180 @@
+package stages_graph
+
+import (
+	"fmt"
+)
+
+type NodeType string
+
+const (
+	NodeStage NodeType = "stage"
+	// NodeBaseImage is the base image pulled from the container registry
+	NodeBaseImage NodeType = "baseImage"
+	// NodeImage is the final werf image assembled from the images built for the target platforms
+	NodeImage NodeType = "image"
+)
+
+type Status string
+
+const (
+	StatusCached   Status = "cached"
+	StatusUncached Status = "uncached"
+)
+
+type EdgeType string
+
+const (
+	// EdgeStage connects the sequential stages of the image
+	EdgeStage EdgeType = "stage"
+	// EdgeFrom connects the last stage of the base werf image with the first stage of the image
+	EdgeFrom EdgeType = "from"
+	// EdgeImport connects the stage of the source image with the stage importing files from it (including Dockerfile COPY --from)
+	EdgeImport EdgeType = "import"
+	// EdgeDependency connects the last stage of the image with the stage using its info (werf.yaml dependencies)
+	EdgeDependency EdgeType = "dependency"
+	// EdgePlatform connects the last stage of the image built for the target platform with the final image
+	EdgePlatform EdgeType = "platform"
+)
+
+type Node struct {
+	ID        string
+	Type      NodeType
+	ImageName string
+	Platform  string
+	// Name is the stage name, the base image reference or the image name depending on the node type
+	Name   string
+	Digest string
+	// Status is empty when the stage has not been checked in the stages storage
+	Status Status
+}
+
+type Edge struct {
+	From string
+	To   string
+	Type EdgeType
+}
+
+type Graph struct {
+	Nodes []*Node
+	Edges []*Edge
+}
+
+func NewGraph() *Graph {
+	return &Graph{}
+}
+
+// AddNode adds the node and assigns the unique identifier to it
+func (g *Graph) AddNode(node *Node) *Node {
+	node.ID = fmt.Sprintf("n%d", len(g.Nodes)+1)
+	g.Nodes = append(g.Nodes, node)
+	return node
+}
+
+func (g *Graph) AddEdge(from, to *Node, edgeType EdgeType) {
+	for _, edge := range g.Edges {
+		if edge.From == from.ID && edge.To == to.ID && edge.Type == edgeType {
+			return
+		}
+	}
+
+	g.Edges = append(g.Edges, &Edge{From: from.ID, To: to.ID, Type: edgeType})
+}
+
+// PropagateUncachedStatus marks the stages without status as uncached if they follow the uncached stage:
+// the digest of the stage depends on the digest of the previous stage, the base image stage and the stages of the imported and dependency images
+func (g *Graph) PropagateUncachedStatus() {
+	nodeByID := map[string]*Node{}
+	for _, node := range g.Nodes {
+		nodeByID[node.ID] = node
+	}
+
+	var queue []*Node
+	for _, node := range g.Nodes {
+		if node.Status == StatusUncached {
+			queue = append(queue, node)
+		}
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		for _, edge := range g.Edges {
+			if edge.From != node.ID || edge.Type == EdgePlatform {
+				continue
+			}
+
+			next := nodeByID[edge.To]
+			if next.Type != NodeStage || next.Status != "" {
+				continue
+			}
+
+			next.Status = StatusUncached
+			queue = append(queue, next)
+		}
+	}
+}
+
+// clusters returns the stages nodes grouped by image and target platform in order of appearance, other nodes are returned separately
+func (g *Graph) clusters() ([][]*Node, []*Node) {
+	var clusters [][]*Node
+	var other []*Node
+	clusterIndex := map[string]int{}
+
+	for _, node := range g.Nodes {
+		if node.Type != NodeStage {
+			other = append(other, node)
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s", node.ImageName, node.Platform)
+		ind, ok := clusterIndex[key]
+		if !ok {
+			ind = len(clusters)
+			clusterIndex[key] = ind
+			clusters = append(clusters, nil)
+		}
+		clusters[ind] = append(clusters[ind], node)
+	}
+
+	return clusters, other
+}
+
+func clusterLabel(node *Node) string {
+	imageName := node.ImageName
+	if imageName == "" {
+		imageName = "~"
+	}
+
+	if node.Platform == "" {
+		return imageName
+	}
+
+	return fmt.Sprintf("%s [%s]", imageName, node.Platform)
+}
+
+// nodeLabelLines returns the node name with the short digest and status
+func nodeLabelLines(node *Node) []string {
+	lines := []string{node.Name}
+	if node.Type == NodeImage {
+		imageName := node.Name
+		if imageName == "" {
+			imageName = "~"
+		}
+		lines = []string{fmt.Sprintf("image %s", imageName)}
+	}
+
+	if node.Digest != "" {
+		digest := node.Digest
+		if len(digest) > 12 {
+			digest = digest[:12]
+		}
+		lines = append(lines, digest)
+	}
+
+	if node.Status != "" {
+		lines = append(lines, string(node.Status))
+	}
+
+	return lines
+}
@@ -0,0 +1,148 @@
+package stages_graph
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func newTestGraph() *Graph {
+	g := NewGraph()
+
+	base := g.AddNode(&Node{Type: NodeBaseImage, Name: "alpine:3.17"})
+	backendFrom := g.AddNode(&Node{Type: NodeStage, ImageName: "backend", Platform: "linux/amd64", Name: "from", Digest: "0123456789abcdef0123", Status: StatusCached})
+	backendInstall := g.AddNode(&Node{Type: NodeStage, ImageName: "backend", Platform: "linux/amd64", Name: "install", Status: StatusUncached})
+	backendImports := g.AddNode(&Node{Type: NodeStage, ImageName: "backend", Platform: "linux/amd64", Name: "dependenciesAfterInstall"})
+	frontendFrom := g.AddNode(&Node{Type: NodeStage, ImageName: "frontend", Platform: "linux/amd64", Name: "from"})
+	frontendSetup := g.AddNode(&Node{Type: NodeStage, ImageName: "frontend", Platform: "linux/amd64", Name: "setup"})
+	final := g.AddNode(&Node{Type: NodeImage, ImageName: "frontend", Name: "frontend"})
+
+	g.AddEdge(base, backendFrom, EdgeFrom)
+	g.AddEdge(backendFrom, backendInstall, EdgeStage)
+	g.AddEdge(backendInstall, backendImports, EdgeStage)
+	g.AddEdge(base, frontendFrom, EdgeFrom)
+	g.AddEdge(frontendFrom, frontendSetup, EdgeStage)
+	g.AddEdge(frontendSetup, backendImports, EdgeImport)
+	g.AddEdge(frontendSetup, backendImports, EdgeImport)
+	g.AddEdge(frontendSetup, final, EdgePlatform)
+
+	return g
+}
+
+var _ = Describe("stages graph", func() {
+	It("should not add duplicate edges", func() {
+		Expect(newTestGraph().Edges).To(HaveLen(7))
+	})
+
+	It("should propagate uncached status to the following stages only", func() {
+		g := newTestGraph()
+		g.PropagateUncachedStatus()
+
+		var statuses []Status
+		for _, node := range g.Nodes {
+			statuses = append(statuses, node.Status)
+		}
+
+		Expect(statuses).To(Equal([]Status{"", StatusCached, StatusUncached, StatusUncached, "", "", ""}))
+	})
+
+	It("should propagate uncached status through imports and dependencies", func() {
+		g := NewGraph()
+
+		assetsBuild := g.AddNode(&Node{Type: NodeStage, ImageName: "assets", Platform: "linux/amd64", Name: "build", Status: StatusUncached})
+		configRender := g.AddNode(&Node{Type: NodeStage, ImageName: "config", Platform: "linux/amd64", Name: "render", Status: StatusUncached})
+		backendFrom := g.AddNode(&Node{Type: NodeStage, ImageName: "backend", Platform: "linux/amd64", Name: "from", Status: StatusCached})
+		backendImports := g.AddNode(&Node{Type: NodeStage, ImageName: "backend", Platform: "linux/amd64", Name: "dependenciesBeforeInstall"})
+		backendInstall := g.AddNode(&Node{Type: NodeStage, ImageName: "backend", Platform: "linux/amd64", Name: "install"})
+		frontendFrom := g.AddNode(&Node{Type: NodeStage, ImageName: "frontend", Platform: "linux/amd64", Name: "from", Status: StatusCached})
+		frontendSetup := g.AddNode(&Node{Type: NodeStage, ImageName: "frontend", Platform: "linux/amd64", Name: "setup"})
+		final := g.AddNode(&Node{Type: NodeImage, ImageName: "backend", Name: "backend"})
+
+		g.AddEdge(backendFrom, backendImports, EdgeStage)
+		g.AddEdge(backendImports, backendInstall, EdgeStage)
+		g.AddEdge(assetsBuild, backendImports, EdgeImport)
+		g.AddEdge(frontendFrom, frontendSetup, EdgeStage)
+		g.AddEdge(configRender, frontendSetup, EdgeDependency)
+		g.AddEdge(backendInstall, final, EdgePlatform)
+
+		g.PropagateUncachedStatus()
+
+		var statuses []Status
+		for _, node := range g.Nodes {
+			statuses = append(statuses, node.Status)
+		}
+
+		Expect(statuses).To(Equal([]Status{StatusUncached, StatusUncached, StatusCached, StatusUncached, StatusUncached, StatusCached, StatusUncached, ""}))
+	})
+
+	It("should render DOT", func() {
+		Expect(string(newTestGraph().DOT())).To(Equal(`digraph stages {
+  rankdir=LR;
+  node [shape=box];
+  subgraph cluster_0 {
+    label="backend [linux/amd64]";
+    n2 [label="from\n0123456789ab\ncached", style=filled, fillcolor="#c8e6c9"];
+    n3 [label="install\nuncached", style=filled, fillcolor="#ffcdd2"];
+    n4 [label="dependenciesAfterInstall"];
+  }
+  subgraph cluster_1 {
+    label="frontend [linux/amd64]";
+    n5 [label="from"];
+    n6 [label="setup"];
+  }
+  n1 [label="alpine:3.17", shape=component];
+  n7 [label="image frontend", shape=box3d];
+  n1 -> n2 [label="from", style=solid];
+  n2 -> n3;
+  n3 -> n4;
+  n1 -> n5 [label="from", style=solid];
+  n5 -> n6;
+  n6 -> n4 [label="import", style=dashed];
+  n6 -> n7 [label="platform", style=solid];
+}
+`))
+	})
+
+	It("should render Mermaid", func() {
+		Expect(string(newTestGraph().Mermaid())).To(Equal(`flowchart LR
+  subgraph cluster_0["backend [linux/amd64]"]
+    n2["from<br/>0123456789ab<br/>cached"]
+    n3["install<br/>uncached"]
+    n4["dependenciesAfterInstall"]
+  end
+  subgraph cluster_1["frontend [linux/amd64]"]
+    n5["from"]
+    n6["setup"]
+  end
+  n1[("alpine:3.17")]
+  n7[["image frontend"]]
+  n1 -- from --> n2
+  n2 --> n3
+  n3 --> n4
+  n1 -- from --> n5
+  n5 --> n6
+  n6 -. import .-> n4
+  n6 -- platform --> n7
+  classDef cached fill:#c8e6c9
+  classDef uncached fill:#ffcdd2
+  class n2 cached
+  class n3 uncached
+`))
+	})
+
+	It("should escape labels", func() {
+		g := NewGraph()
+		g.AddNode(&Node{Type: NodeBaseImage, Name: `say "hi"`})
+
+		Expect(string(g.DOT())).To(ContainSubstring(`n1 [label="say \"hi\"", shape=component];`))
+		Expect(string(g.Mermaid())).To(ContainSubstring(`n1[("say #quot;hi#quot;")]`))
+	})
+
+	It("should parse format", func() {
+		format, err := ParseFormat("mermaid")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(format).To(Equal(FormatMermaid))
+
+		_, err = ParseFormat("svg")
+		Expect(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,13 @@
+package stages_graph
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestStagesGraph(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Stages Graph Suite")
+}
@@ -0,0 +1,174 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/build/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/vulnerability_scan"
+)
+
+type ReportVulnerabilityScanRecord struct {
+	Platform string `json:",omitempty"`
+	Summary  map[vulnerability_scan.Severity]int
+	Findings []*vulnerability_scan.Finding
+}
+
+func newReportVulnerabilityScanRecords(results map[string]*vulnerability_scan.Result, platform string) []ReportVulnerabilityScanRecord {
+	platforms := util.MapKeys(results)
+	sort.Strings(platforms)
+
+	var records []ReportVulnerabilityScanRecord
+	for _, resultPlatform := range platforms {
+		if platform != "" && resultPlatform != platform {
+			continue
+		}
+
+		record := ReportVulnerabilityScanRecord{
+			Summary:  results[resultPlatform].Summary(),
+			Findings: results[resultPlatform].Findings,
+		}
+		if platform == "" && len(results) > 1 {
+			record.Platform = resultPlatform
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// scanFinalImages scans the final images of all target platforms before they are published.
+// The scan results are cached by the stage id in the stages storage, so the images not changed since the previous scan
+// are not rescanned until the cache TTL expires or the scanner command changes
+func (phase *BuildPhase) scanFinalImages(ctx context.Context) error {
+	stagesStorage := phase.Conveyor.StorageManager.GetStagesStorage()
+
+	phase.vulnerabilityScanResults = make(map[string]map[string]*vulnerability_scan.Result)
+	for _, desc := range phase.Conveyor.imagesTree.GetImagesByName(true) {
+		name, images := desc.Unpair()
+
+		for _, img := range images {
+			if img.IsArtifact {
+				continue
+			}
+
+			result, err := phase.scanImage(ctx, stagesStorage, img)
+			if err != nil {
+				return fmt.Errorf("unable to scan image %q for vulnerabilities: %w", name, err)
+			}
+
+			if phase.vulnerabilityScanResults[name] == nil {
+				phase.vulnerabilityScanResults[name] = make(map[string]*vulnerability_scan.Result)
+			}
+			phase.vulnerabilityScanResults[name][img.TargetPlatform] = result
+		}
+	}
+
+	return nil
+}
+
+func (phase *BuildPhase) scanImage(ctx context.Context, stagesStorage storage.StagesStorage, img *image.Image) (*vulnerability_scan.Result, error) {
+	// the image is scanned in the stages storage, since it is not published into the final repo yet
+	desc := img.GetLastNonEmptyStage().GetStageImage().Image.GetStageDescription()
+	stageID := desc.StageID.String()
+
+	var result *vulnerability_scan.Result
+	logboek.Context(ctx).Default().LogOptionalLn()
+	err := logboek.Context(ctx).Default().LogProcess("Scanning image %s for vulnerabilities", img.LogDetailedName()).DoError(func() error {
+		metadata, err := stagesStorage.GetVulnerabilityScanMetadata(ctx, phase.Conveyor.ProjectName(), stageID)
+		if err != nil {
+			return fmt.Errorf("unable to get vulnerability scan metadata: %w", err)
+		}
+
+		if reason := vulnerabilityScanCacheMissReason(metadata, phase.VulnerabilityScanner, time.Now()); reason == "" {
+			logboek.Context(ctx).Default().LogF("Using the cached scan result of stage %s\n", stageID)
+			result = metadata.Result
+		} else {
+			logboek.Context(ctx).Info().LogF("Scanning stage %s: %s\n", stageID, reason)
+
+			if result, err = phase.VulnerabilityScanner.Scan(ctx, desc.Info.Name); err != nil {
+				return err
+			}
+
+			if phase.VulnerabilityScanner.CacheTTL > 0 {
+				metadata := &storage.VulnerabilityScanMetadata{
+					StageID:   stageID,
+					Result:    result,
+					Scanner:   phase.VulnerabilityScanner.CommandString(),
+					ScannedAt: time.Now(),
+				}
+				if err := stagesStorage.PutVulnerabilityScanMetadata(ctx, phase.Conveyor.ProjectName(), metadata); err != nil {
+					return fmt.Errorf("unable to put vulnerability scan metadata: %w", err)
+				}
+			}
+		}
+
+		logboek.Context(ctx).Default().LogF("Found %s\n", result.SummaryString())
+
+		return nil
+	})
+
+	return result, err
+}
+
+// vulnerabilityScanCacheMissReason returns why the cached scan result cannot be used or an empty string if it can
+func vulnerabilityScanCacheMissReason(metadata *storage.VulnerabilityScanMetadata, scanner *vulnerability_scan.Scanner, now time.Time) string {
+	switch {
+	case scanner.CacheTTL == 0:
+		return "scan results cache is disabled"
+	case metadata == nil:
+		return "no cached scan result"
+	case metadata.Scanner != scanner.CommandString():
+		return fmt.Sprintf("cached scan result was made by another scanner command %q", metadata.Scanner)
+	case now.Sub(metadata.ScannedAt) >= scanner.CacheTTL:
+		return fmt.Sprintf("cached scan result is older than %s", scanner.CacheTTL)
+	default:
+		return ""
+	}
+}
+
+// checkVulnerabilityScanResults returns the error describing the findings of the threshold severity or higher
+func (phase *BuildPhase) checkVulnerabilityScanResults() error {
+	names := util.MapKeys(phase.vulnerabilityScanResults)
+	sort.Strings(names)
+
+	var blockedImages []string
+	for _, name := range names {
+		results := phase.vulnerabilityScanResults[name]
+
+		platforms := util.MapKeys(results)
+		sort.Strings(platforms)
+
+		for _, platform := range platforms {
+			findings := phase.VulnerabilityScanner.BlockingFindings(results[platform])
+			if len(findings) == 0 {
+				continue
+			}
+
+			var ids []string
+			for _, finding := range findings {
+				ids = append(ids, fmt.Sprintf("%s (%s %s)", finding.ID, finding.Package, finding.Severity))
+			}
+
+			imageName := name
+			if len(results) > 1 {
+				imageName = fmt.Sprintf("%s (%s)", name, platform)
+			}
+
+			blockedImages = append(blockedImages, fmt.Sprintf("image %s: %s", imageName, strings.Join(ids, ", ")))
+		}
+	}
+
+	if len(blockedImages) > 0 {
+		return fmt.Errorf("vulnerabilities of %s or higher severity found:\n%s", phase.VulnerabilityScanner.Threshold, strings.Join(blockedImages, "\n"))
+	}
+
+	return nil
+}
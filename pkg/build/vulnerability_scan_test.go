@@ -0,0 +1,50 @@
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/vulnerability_scan"
+)
+
+func TestVulnerabilityScanCacheMissReason(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	scanner, err := vulnerability_scan.NewScanner("trivy image --format json --quiet", vulnerability_scan.SeverityHigh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner.CacheTTL = 24 * time.Hour
+
+	disabledCacheScanner := *scanner
+	disabledCacheScanner.CacheTTL = 0
+
+	newMetadata := func(command string, scannedAt time.Time) *storage.VulnerabilityScanMetadata {
+		return &storage.VulnerabilityScanMetadata{StageID: "stage", Result: &vulnerability_scan.Result{}, Scanner: command, ScannedAt: scannedAt}
+	}
+
+	for _, tt := range []struct {
+		name      string
+		metadata  *storage.VulnerabilityScanMetadata
+		scanner   *vulnerability_scan.Scanner
+		wantCache bool
+	}{
+		{name: "fresh result of the same scanner", metadata: newMetadata("trivy image --format json --quiet", now.Add(-time.Hour)), scanner: scanner, wantCache: true},
+		{name: "no cached result", scanner: scanner},
+		{name: "result of another scanner", metadata: newMetadata("grype -o json", now.Add(-time.Hour)), scanner: scanner},
+		{name: "result cached before the scanner was recorded", metadata: newMetadata("", time.Time{}), scanner: scanner},
+		{name: "expired result", metadata: newMetadata("trivy image --format json --quiet", now.Add(-25*time.Hour)), scanner: scanner},
+		{name: "disabled cache", metadata: newMetadata("trivy image --format json --quiet", now.Add(-time.Hour)), scanner: &disabledCacheScanner},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := vulnerabilityScanCacheMissReason(tt.metadata, tt.scanner, now)
+			if tt.wantCache && reason != "" {
+				t.Errorf("expected the cached result to be used, got %q", reason)
+			}
+			if !tt.wantCache && reason == "" {
+				t.Errorf("expected the image to be rescanned")
+			}
+		})
+	}
+}
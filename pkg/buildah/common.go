@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -48,6 +49,14 @@ type BuildFromDockerfileOpts struct {
 	BuildArgs  map[string]string
 	Target     string
 	Labels     []string
+	// CacheFrom is the list of repositories to pull the intermediate layers cache from
+	CacheFrom []string
+	// CacheTo is the list of repositories to push the intermediate layers cache to
+	CacheTo []string
+	// Secrets is the list of secrets available in the RUN --mount=type=secret instructions in the id=ID,src=PATH format
+	Secrets []string
+	// Timestamp is set as the created time of the image and the modification time of the layer files if specified
+	Timestamp *time.Time
 }
 
 type RunMount struct {
@@ -73,6 +82,8 @@ type RunCommandOpts struct {
 	GlobalMounts []*specs.Mount
 	// Mounts as allowed in Dockerfile RUN --mount option. Have more restrictions than GlobalMounts (e.g. Source of bind-mount can't be outside of ContextDir or container root).
 	RunMounts []*instructions.Mount
+	// Secrets for the RUN --mount=type=secret mounts in the id=ID,src=PATH format.
+	Secrets []string
 }
 
 type RmiOpts struct {
@@ -85,6 +96,10 @@ type CommitOpts struct {
 	CommonOpts
 
 	Image string
+	// Timestamp is set as the created time of the image and the modification time of the layer files if specified
+	Timestamp *time.Time
+	// Squash flattens the image layers into a single layer
+	Squash bool
 }
 
 type ConfigOpts struct {
@@ -139,6 +154,7 @@ type (
 	BuildFromCommandsOpts CommonOpts
 	PushOpts              CommonOpts
 	PullOpts              CommonOpts
+	SaveOpts              CommonOpts
 	TagOpts               CommonOpts
 	MountOpts             CommonOpts
 	UmountOpts            CommonOpts
@@ -150,6 +166,7 @@ type Buildah interface {
 	GetRuntimePlatform() string
 	Tag(ctx context.Context, ref, newRef string, opts TagOpts) error
 	Push(ctx context.Context, ref string, opts PushOpts) error
+	Save(ctx context.Context, ref, archivePath string, opts SaveOpts) error
 	BuildFromDockerfile(ctx context.Context, dockerfile string, opts BuildFromDockerfileOpts) (string, error)
 	RunCommand(ctx context.Context, container string, command []string, opts RunCommandOpts) error
 	FromCommand(ctx context.Context, container, image string, opts FromCommandOpts) (string, error)
@@ -24,6 +24,7 @@ import (
 	"github.com/containers/buildah/imagebuildah"
 	"github.com/containers/buildah/pkg/parse"
 	"github.com/containers/common/libimage"
+	"github.com/containers/image/v5/docker/reference"
 	"github.com/containers/image/v5/manifest"
 	imgstor "github.com/containers/image/v5/storage"
 	storageTransport "github.com/containers/image/v5/storage"
@@ -272,6 +273,32 @@ func (b *NativeBuildah) Push(ctx context.Context, ref string, opts PushOpts) err
 	return nil
 }
 
+func (b *NativeBuildah) Save(ctx context.Context, ref, archivePath string, opts SaveOpts) error {
+	sysCtx, err := b.getSystemContext("")
+	if err != nil {
+		return err
+	}
+
+	pushOpts := buildah.PushOptions{
+		SignaturePolicyPath: b.SignaturePolicyPath,
+		ReportWriter:        opts.LogWriter,
+		Store:               b.Store,
+		SystemContext:       sysCtx,
+		ManifestType:        manifest.DockerV2Schema2MediaType,
+	}
+
+	archiveRef, err := alltransports.ParseImageName(fmt.Sprintf("docker-archive:%s:%s", archivePath, ref))
+	if err != nil {
+		return fmt.Errorf("error parsing archive ref from %q: %w", archivePath, err)
+	}
+
+	if _, _, err = buildah.Push(ctx, ref, archiveRef, pushOpts); err != nil {
+		return fmt.Errorf("error saving image %q into archive %q: %w", ref, archivePath, err)
+	}
+
+	return nil
+}
+
 func (b *NativeBuildah) BuildFromDockerfile(ctx context.Context, dockerfile string, opts BuildFromDockerfileOpts) (string, error) {
 	var targetPlatform string
 	var targetPlatforms []struct{ OS, Arch, Variant string }
@@ -316,6 +343,7 @@ func (b *NativeBuildah) BuildFromDockerfile(ctx context.Context, dockerfile stri
 		ForceRmIntermediateCtrs: false,
 		NoCache:                 false,
 		Labels:                  opts.Labels,
+		Timestamp:               opts.Timestamp,
 	}
 
 	if targetPlatform != b.GetRuntimePlatform() {
@@ -324,6 +352,28 @@ func (b *NativeBuildah) BuildFromDockerfile(ctx context.Context, dockerfile stri
 		buildOpts.NoCache = true
 	}
 
+	for _, repo := range opts.CacheFrom {
+		ref, err := reference.ParseNormalizedNamed(repo)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse cache-from repo %q: %w", repo, err)
+		}
+		buildOpts.CacheFrom = append(buildOpts.CacheFrom, ref)
+	}
+
+	for _, repo := range opts.CacheTo {
+		ref, err := reference.ParseNormalizedNamed(repo)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse cache-to repo %q: %w", repo, err)
+		}
+		buildOpts.CacheTo = append(buildOpts.CacheTo, ref)
+	}
+
+	if len(opts.Secrets) > 0 {
+		commonBuildOpts := b.defaultCommonBuildOptions
+		commonBuildOpts.Secrets = opts.Secrets
+		buildOpts.CommonBuildOpts = &commonBuildOpts
+	}
+
 	errLog := &bytes.Buffer{}
 	if opts.LogWriter != nil {
 		buildOpts.Out = opts.LogWriter
@@ -378,6 +428,11 @@ func (b *NativeBuildah) RunCommand(ctx context.Context, container string, comman
 		return err
 	}
 
+	secrets, err := parse.Secrets(opts.Secrets)
+	if err != nil {
+		return fmt.Errorf("unable to parse secrets: %w", err)
+	}
+
 	runOpts := buildah.RunOptions{
 		Env:              opts.Envs,
 		ContextDir:       contextDir,
@@ -395,8 +450,7 @@ func (b *NativeBuildah) RunCommand(ctx context.Context, container string, comman
 		Cmd:              []string{},
 		Mounts:           globalMounts,
 		RunMounts:        runMounts,
-		// TODO(ilya-lesikov):
-		Secrets: nil,
+		Secrets:          secrets,
 		// TODO(ilya-lesikov):
 		SSHSources: nil,
 	}
@@ -545,6 +599,8 @@ func (b *NativeBuildah) Commit(ctx context.Context, container string, opts Commi
 		SystemContext:         sysCtx,
 		MaxRetries:            MaxPullPushRetries,
 		RetryDelay:            PullPushRetryDelay,
+		HistoryTimestamp:      opts.Timestamp,
+		Squash:                opts.Squash,
 	})
 	if err != nil {
 		return "", fmt.Errorf("error doing commit: %w", err)
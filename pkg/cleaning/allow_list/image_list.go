@@ -0,0 +1,95 @@
+package allow_list
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// imageListHTTPClient fails the request instead of hanging the cleanup if the image list URL is unreachable
+var imageListHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// ImageListProvider reads image references from a file or an http(s) URL, one reference per line.
+// Empty lines and lines starting with # are ignored.
+type ImageListProvider struct {
+	Source string
+}
+
+func NewImageListProvider(source string) *ImageListProvider {
+	return &ImageListProvider{Source: source}
+}
+
+func (p *ImageListProvider) UsedImages(ctx context.Context) ([]*DeployedImage, error) {
+	data, err := p.read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read image list %q: %w", p.Source, err)
+	}
+
+	return parseImageList(p.Source, data)
+}
+
+func (p *ImageListProvider) read(ctx context.Context) ([]byte, error) {
+	if !isURL(p.Source) {
+		return os.ReadFile(p.Source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := imageListHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func (p *ImageListProvider) String() string {
+	return "image list " + p.Source
+}
+
+func parseImageList(source string, data []byte) ([]*DeployedImage, error) {
+	var images []*DeployedImage
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if _, err := name.ParseReference(line); err != nil {
+			return nil, fmt.Errorf("invalid image reference at %s:%d: %w", source, lineNumber, err)
+		}
+
+		images = AppendDeployedImages(images, &DeployedImage{
+			Name:           line,
+			ResourcesNames: []string{fmt.Sprintf("%s:%d", source, lineNumber)},
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
@@ -0,0 +1,95 @@
+package allow_list
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImageList(t *testing.T) {
+	data := []byte(`# images in use
+registry.example.com/project:backend
+
+  registry.example.com/project:frontend
+registry.example.com/project@sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b
+registry.example.com/project:backend
+`)
+
+	images, err := parseImageList("images.txt", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*DeployedImage{
+		{Name: "registry.example.com/project:backend", ResourcesNames: []string{"images.txt:2", "images.txt:6"}},
+		{Name: "registry.example.com/project:frontend", ResourcesNames: []string{"images.txt:4"}},
+		{Name: "registry.example.com/project@sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b", ResourcesNames: []string{"images.txt:5"}},
+	}
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images %s, got %s", deployedImagesString(expected), deployedImagesString(images))
+	}
+}
+
+func TestParseImageList_Malformed(t *testing.T) {
+	for _, data := range []string{
+		"registry.example.com/project:backend\nregistry.example.com/project:back end\n",
+		"registry.example.com/Project:backend\n",
+		"registry.example.com/project@sha256:short\n",
+		"- registry.example.com/project:backend\n",
+	} {
+		if _, err := parseImageList("images.txt", []byte(data)); err == nil {
+			t.Errorf("expected error for image list %q", data)
+		}
+	}
+
+	_, err := parseImageList("images.txt", []byte("registry.example.com/project:backend\nregistry.example.com/project:back end\n"))
+	if err == nil || !strings.Contains(err.Error(), "images.txt:2") {
+		t.Errorf("expected error with the line of the invalid reference, got %v", err)
+	}
+}
+
+func TestImageListProvider_URL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images.txt":
+			_, _ = w.Write([]byte("registry.example.com/project:backend\n"))
+		case "/slow.txt":
+			time.Sleep(time.Second)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	images, err := NewImageListProvider(server.URL + "/images.txt").UsedImages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 || images[0].Name != "registry.example.com/project:backend" {
+		t.Errorf("unexpected images %s", deployedImagesString(images))
+	}
+
+	if _, err := NewImageListProvider(server.URL + "/missing.txt").UsedImages(context.Background()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected unexpected response status error, got %v", err)
+	}
+
+	defaultClient := imageListHTTPClient
+	defer func() { imageListHTTPClient = defaultClient }()
+	imageListHTTPClient = &http.Client{Timeout: 100 * time.Millisecond}
+
+	if _, err := NewImageListProvider(server.URL + "/slow.txt").UsedImages(context.Background()); err == nil {
+		t.Errorf("expected timeout error")
+	}
+}
+
+func deployedImagesString(images []*DeployedImage) []string {
+	var res []string
+	for _, img := range images {
+		res = append(res, img.Name+" "+strings.Join(img.ResourcesNames, ","))
+	}
+	return res
+}
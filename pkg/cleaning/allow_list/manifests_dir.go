@@ -0,0 +1,138 @@
+package allow_list
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var hclImageRegexp = regexp.MustCompile(`^\s*image\s*=\s*"([^"]+)"`)
+
+// ManifestsDirProvider scans a directory of manifests for images in use:
+// string values of "image" keys in YAML and JSON files (Kubernetes manifests, docker-compose files, ECS task definitions, Nomad JSON jobs)
+// and image attributes in HCL files (Nomad jobs).
+type ManifestsDirProvider struct {
+	Dir string
+}
+
+func NewManifestsDirProvider(dir string) *ManifestsDirProvider {
+	return &ManifestsDirProvider{Dir: dir}
+}
+
+func (p *ManifestsDirProvider) UsedImages(_ context.Context) ([]*DeployedImage, error) {
+	var images []*DeployedImage
+
+	if err := filepath.WalkDir(p.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		var parseFunc func(path string, data []byte) ([]*DeployedImage, error)
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			parseFunc = parseManifestImages
+		case ".hcl", ".nomad":
+			parseFunc = parseHCLManifestImages
+		default:
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		fileImages, err := parseFunc(filepath.ToSlash(path), data)
+		if err != nil {
+			return fmt.Errorf("unable to parse %q: %w", path, err)
+		}
+		images = AppendDeployedImages(images, fileImages...)
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("unable to scan manifests dir %q: %w", p.Dir, err)
+	}
+
+	return images, nil
+}
+
+func (p *ManifestsDirProvider) String() string {
+	return "manifests dir " + p.Dir
+}
+
+func parseManifestImages(path string, data []byte) ([]*DeployedImage, error) {
+	var images []*DeployedImage
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		walkManifestNode(&doc, nil, func(keyPath []string, value *yaml.Node) {
+			images = AppendDeployedImages(images, &DeployedImage{
+				Name:           value.Value,
+				ResourcesNames: []string{fmt.Sprintf("%s:%d %s", path, value.Line, strings.Join(keyPath, "."))},
+			})
+		})
+	}
+
+	return images, nil
+}
+
+// walkManifestNode calls f for each non-empty scalar value of the "image" key
+func walkManifestNode(node *yaml.Node, keyPath []string, f func(keyPath []string, value *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			walkManifestNode(n, keyPath, f)
+		}
+	case yaml.SequenceNode:
+		for ind, n := range node.Content {
+			walkManifestNode(n, append(append([]string{}, keyPath...), fmt.Sprintf("%d", ind)), f)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			valueKeyPath := append(append([]string{}, keyPath...), key.Value)
+
+			if key.Value == "image" && value.Kind == yaml.ScalarNode && value.Value != "" {
+				f(valueKeyPath, value)
+				continue
+			}
+
+			walkManifestNode(value, valueKeyPath, f)
+		}
+	}
+}
+
+func parseHCLManifestImages(path string, data []byte) ([]*DeployedImage, error) {
+	var images []*DeployedImage
+	for ind, line := range strings.Split(string(data), "\n") {
+		if match := hclImageRegexp.FindStringSubmatch(line); match != nil {
+			images = AppendDeployedImages(images, &DeployedImage{
+				Name:           match[1],
+				ResourcesNames: []string{fmt.Sprintf("%s:%d", path, ind+1)},
+			})
+		}
+	}
+
+	return images, nil
+}
@@ -0,0 +1,156 @@
+package allow_list
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseManifestImages(t *testing.T) {
+	data := []byte(`apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: registry.example.com/project:backend
+      - name: sidecar
+        image: ""
+      - name: nested
+        image:
+          name: not-a-scalar
+---
+services:
+  web:
+    image: registry.example.com/project:frontend
+`)
+
+	images, err := parseManifestImages("manifests/app.yaml", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*DeployedImage{
+		{Name: "registry.example.com/project:backend", ResourcesNames: []string{"manifests/app.yaml:8 spec.template.spec.containers.0.image"}},
+		{Name: "registry.example.com/project:frontend", ResourcesNames: []string{"manifests/app.yaml:17 services.web.image"}},
+	}
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images %s, got %s", deployedImagesString(expected), deployedImagesString(images))
+	}
+}
+
+func TestParseManifestImages_JSON(t *testing.T) {
+	data := []byte(`{"containerDefinitions": [{"name": "app", "image": "registry.example.com/project:backend"}]}`)
+
+	images, err := parseManifestImages("task.json", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*DeployedImage{
+		{Name: "registry.example.com/project:backend", ResourcesNames: []string{"task.json:1 containerDefinitions.0.image"}},
+	}
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images %s, got %s", deployedImagesString(expected), deployedImagesString(images))
+	}
+}
+
+func TestParseManifestImages_Malformed(t *testing.T) {
+	for _, data := range []string{
+		"spec:\n  image: [registry.example.com/project:backend\n",
+		"spec:\n\timage: registry.example.com/project:backend\n",
+		`{"image": "registry.example.com/project:backend"`,
+	} {
+		if _, err := parseManifestImages("app.yaml", []byte(data)); err == nil {
+			t.Errorf("expected error for manifest %q", data)
+		}
+	}
+}
+
+func TestParseHCLManifestImages(t *testing.T) {
+	data := []byte(`job "app" {
+  group "app" {
+    task "backend" {
+      config {
+        image = "registry.example.com/project:backend"
+      }
+    }
+    task "frontend" {
+      config {
+        image   =   "registry.example.com/project:frontend"
+        # image = "registry.example.com/project:commented"
+        image_pull_timeout = "5m"
+        image = registry.example.com/project:unquoted
+      }
+    }
+  }
+}
+`)
+
+	images, err := parseHCLManifestImages("app.nomad", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*DeployedImage{
+		{Name: "registry.example.com/project:backend", ResourcesNames: []string{"app.nomad:5"}},
+		{Name: "registry.example.com/project:frontend", ResourcesNames: []string{"app.nomad:10"}},
+	}
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images %s, got %s", deployedImagesString(expected), deployedImagesString(images))
+	}
+}
+
+func TestManifestsDirProvider(t *testing.T) {
+	dir := t.TempDir()
+	for path, data := range map[string]string{
+		"k8s/app.yml":        "image: registry.example.com/project:backend\n",
+		"compose/web.yaml":   "image: registry.example.com/project:backend\n",
+		"nomad/app.hcl":      `image = "registry.example.com/project:frontend"` + "\n",
+		"README.md":          "image: registry.example.com/project:ignored\n",
+		"ecs/task.JSON":      `{"image": "registry.example.com/project:worker"}`,
+		"nomad/job.nomad":    "",
+		"k8s/values.txt.bak": "image: registry.example.com/project:ignored\n",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	images, err := NewManifestsDirProvider(dir).UsedImages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]int{}
+	for _, img := range images {
+		names[img.Name] = len(img.ResourcesNames)
+	}
+
+	expected := map[string]int{
+		"registry.example.com/project:backend":  2,
+		"registry.example.com/project:frontend": 1,
+		"registry.example.com/project:worker":   1,
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected images with resources count %v, got %v", expected, names)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "k8s", "broken.yaml"), []byte("image: [\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewManifestsDirProvider(dir).UsedImages(context.Background()); err == nil {
+		t.Errorf("expected error for the malformed manifest")
+	}
+
+	if _, err := NewManifestsDirProvider(filepath.Join(dir, "missing")).UsedImages(context.Background()); err == nil {
+		t.Errorf("expected error for the missing dir")
+	}
+}
@@ -0,0 +1,32 @@
+package allow_list
+
+import (
+	"context"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// Provider is a source of images that are in use and must be kept by cleanup
+type Provider interface {
+	// UsedImages returns image references with resources that use them
+	UsedImages(ctx context.Context) ([]*DeployedImage, error)
+	String() string
+}
+
+type KubernetesProvider struct {
+	ContextName string
+	Client      kubernetes.Interface
+	Namespace   string
+}
+
+func NewKubernetesProvider(contextName string, client kubernetes.Interface, namespace string) *KubernetesProvider {
+	return &KubernetesProvider{ContextName: contextName, Client: client, Namespace: namespace}
+}
+
+func (p *KubernetesProvider) UsedImages(ctx context.Context) ([]*DeployedImage, error) {
+	return DeployedDockerImages(ctx, p.Client, p.Namespace)
+}
+
+func (p *KubernetesProvider) String() string {
+	return "kubernetes context " + p.ContextName
+}
@@ -25,15 +25,29 @@ import (
 	"github.com/werf/werf/pkg/util"
 )
 
+const (
+	protectionReasonImagesKeepPolicy   = "kept by images keep policy"
+	protectionReasonKubernetes         = "used in the Kubernetes"
+	protectionReasonAllowList          = "referenced by allow-list provider"
+	protectionReasonGitHistory         = "found in the git history"
+	deletionReasonImagesPerImageLimit  = "exceeds images per image limit"
+	deletionReasonNotProtectedByPolicy = "not protected by any cleanup policy"
+)
+
 type CleanupOptions struct {
 	ImageNameList                           []string
 	LocalGit                                GitRepo
 	KubernetesContextClients                []*kube.ContextClient
 	KubernetesNamespaceRestrictionByContext map[string]string
 	WithoutKube                             bool // legacy
+	AllowListProviders                      []allow_list.Provider
 	ConfigMetaCleanup                       config.MetaCleanup
 	KeepStagesBuiltWithinLastNHours         uint64
-	DryRun                                  bool
+	// KeepDockerfileLayersCacheBuiltWithinLastNHours is the age of the Dockerfile layers cache tags to keep, older tags are deleted (all tags are deleted if 0)
+	KeepDockerfileLayersCacheBuiltWithinLastNHours uint64
+	DryRun                                         bool
+	Plan                                           *CleanupPlan   // records keep or delete decision with a reason for each item, if set
+	SavingsReport                                  *SavingsReport // filled with sizes of unique layers before and after the cleanup, if set
 }
 
 func Cleanup(ctx context.Context, projectName string, storageManager *manager.StorageManager, options CleanupOptions) error {
@@ -51,26 +65,36 @@ func newCleanupManager(projectName string, storageManager *manager.StorageManage
 		KubernetesContextClients:                options.KubernetesContextClients,
 		KubernetesNamespaceRestrictionByContext: options.KubernetesNamespaceRestrictionByContext,
 		WithoutKube:                             options.WithoutKube,
+		AllowListProviders:                      options.AllowListProviders,
 		ConfigMetaCleanup:                       options.ConfigMetaCleanup,
 		KeepStagesBuiltWithinLastNHours:         options.KeepStagesBuiltWithinLastNHours,
+		KeepDockerfileLayersCacheBuiltWithinLastNHours: options.KeepDockerfileLayersCacheBuiltWithinLastNHours,
+		Plan:          options.Plan,
+		SavingsReport: options.SavingsReport,
 	}
 }
 
 type cleanupManager struct {
 	stageManager stage_manager.Manager
 
-	nonexistentImportMetadataIDs []string
-
-	ProjectName                             string
-	StorageManager                          manager.StorageManagerInterface
-	ImageNameList                           []string
-	LocalGit                                GitRepo
-	KubernetesContextClients                []*kube.ContextClient
-	KubernetesNamespaceRestrictionByContext map[string]string
-	WithoutKube                             bool
-	ConfigMetaCleanup                       config.MetaCleanup
-	KeepStagesBuiltWithinLastNHours         uint64
-	DryRun                                  bool
+	nonexistentImportMetadataIDs    []string
+	stageIDsOverImagesPerImageLimit map[string]bool
+	imagesKeepPoliciesStageIDs      []*imagesKeepPolicyStageIDs
+
+	ProjectName                                    string
+	StorageManager                                 manager.StorageManagerInterface
+	ImageNameList                                  []string
+	LocalGit                                       GitRepo
+	KubernetesContextClients                       []*kube.ContextClient
+	KubernetesNamespaceRestrictionByContext        map[string]string
+	WithoutKube                                    bool
+	AllowListProviders                             []allow_list.Provider
+	ConfigMetaCleanup                              config.MetaCleanup
+	KeepStagesBuiltWithinLastNHours                uint64
+	KeepDockerfileLayersCacheBuiltWithinLastNHours uint64
+	DryRun                                         bool
+	Plan                                           *CleanupPlan
+	SavingsReport                                  *SavingsReport
 }
 
 type GitRepo interface {
@@ -117,6 +141,18 @@ func (m *cleanupManager) run(ctx context.Context) error {
 		return err
 	}
 
+	// digests of the stages before any deletion to find the SBOMs and signatures of the deleted stages when the cleanup is done
+	stagesDigestsBefore := stageDescriptionListDigests(m.stageManager.GetStageDescriptionList(stage_manager.StageDescriptionListOptions{}))
+	finalStagesDigestsBefore := stageDescriptionListDigests(m.stageManager.GetFinalStageDescriptionList(stage_manager.StageDescriptionListOptions{}))
+
+	// snapshot stages before any deletion to compare sizes of unique layers when the cleanup is done
+	var imageStagesBefore map[string][]*image.StageDescription
+	var stagesBefore []*image.StageDescription
+	if m.SavingsReport != nil {
+		imageStagesBefore = m.imageStageDescriptionList()
+		stagesBefore = m.allStageDescriptionList()
+	}
+
 	if !(m.WithoutKube || m.ConfigMetaCleanup.DisableKubernetesBasedPolicy) {
 		if len(m.KubernetesContextClients) == 0 {
 			return fmt.Errorf("no kubernetes configs found to skip images being used in the Kubernetes, pass --without-kube option (or WERF_WITHOUT_KUBE env var) to suppress this error")
@@ -127,19 +163,45 @@ func (m *cleanupManager) run(ctx context.Context) error {
 			return fmt.Errorf("error getting deployed docker images names from Kubernetes: %w", err)
 		}
 
+		usedImageResources := deployedDockerImagesResources(deployedDockerImages)
+
 		if err := logboek.Context(ctx).LogProcess("Skipping repo tags that are being used in Kubernetes").DoError(func() error {
-			return m.skipStageIDsThatAreUsedInKubernetes(ctx, deployedDockerImages)
+			return m.skipStageIDsThatAreUsed(ctx, usedImageResources, protectionReasonKubernetes)
 		}); err != nil {
 			return err
 		}
 
 		if err := logboek.Context(ctx).LogProcess("Skipping final repo tags that are being used in Kubernetes").DoError(func() error {
-			return m.skipFinalStageIDsThatAreUsedInKubernetes(ctx, deployedDockerImages)
+			return m.skipFinalStageIDsThatAreUsed(ctx, usedImageResources, protectionReasonKubernetes)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if len(m.AllowListProviders) != 0 {
+		usedImageResources, err := m.allowListImagesResources(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting images from allow-list providers: %w", err)
+		}
+
+		if err := logboek.Context(ctx).LogProcess("Skipping repo tags that are referenced by allow-list providers").DoError(func() error {
+			return m.skipStageIDsThatAreUsed(ctx, usedImageResources, protectionReasonAllowList)
+		}); err != nil {
+			return err
+		}
+
+		if err := logboek.Context(ctx).LogProcess("Skipping final repo tags that are referenced by allow-list providers").DoError(func() error {
+			return m.skipFinalStageIDsThatAreUsed(ctx, usedImageResources, protectionReasonAllowList)
 		}); err != nil {
 			return err
 		}
 	}
 
+	// images keep policies select stages by images metadata, so they are evaluated before any metadata deletion
+	if policies := m.ConfigMetaCleanup.GetImagesKeepPolicies(); len(policies) != 0 {
+		m.initImagesKeepPolicies(policies)
+	}
+
 	if !m.ConfigMetaCleanup.DisableGitHistoryBasedPolicy {
 		if err := logboek.Context(ctx).LogProcess("Git history-based cleanup").DoError(func() error {
 			return m.gitHistoryBasedCleanup(ctx)
@@ -148,6 +210,22 @@ func (m *cleanupManager) run(ctx context.Context) error {
 		}
 	}
 
+	if len(m.imagesKeepPoliciesStageIDs) != 0 {
+		if err := logboek.Context(ctx).LogProcess("Images keep policies").DoError(func() error {
+			return m.imagesKeepPoliciesBasedCleanup(ctx)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if m.ConfigMetaCleanup.ImagesPerImageLimit != nil {
+		if err := logboek.Context(ctx).LogProcess("Applying images per image limit (%d)", *m.ConfigMetaCleanup.ImagesPerImageLimit).DoError(func() error {
+			return m.applyImagesPerImageLimit(ctx, *m.ConfigMetaCleanup.ImagesPerImageLimit)
+		}); err != nil {
+			return err
+		}
+	}
+
 	if err := logboek.Context(ctx).LogProcess("Cleanup unused stages").DoError(func() error {
 		return m.cleanupUnusedStages(ctx)
 	}); err != nil {
@@ -162,50 +240,81 @@ func (m *cleanupManager) run(ctx context.Context) error {
 		}
 	}
 
+	if err := m.cleanupImageArtifacts(ctx, m.StorageManager.GetStagesStorage(), stagesDigestsBefore, m.stageManager.GetStageDescriptionList(stage_manager.StageDescriptionListOptions{})); err != nil {
+		return err
+	}
+
+	if m.StorageManager.GetFinalStagesStorage() != nil {
+		if err := m.cleanupImageArtifacts(ctx, m.StorageManager.GetFinalStagesStorage(), finalStagesDigestsBefore, m.stageManager.GetFinalStageDescriptionList(stage_manager.StageDescriptionListOptions{})); err != nil {
+			return err
+		}
+	}
+
+	for _, stagesStorage := range []storage.StagesStorage{m.StorageManager.GetStagesStorage(), m.StorageManager.GetFinalStagesStorage()} {
+		if repoStagesStorage, isRepo := stagesStorage.(*storage.RepoStagesStorage); isRepo {
+			if err := logboek.Context(ctx).LogProcess("Cleanup Dockerfile layers cache in %s", repoStagesStorage.String()).DoError(func() error {
+				return m.cleanupDockerfileLayersCache(ctx, repoStagesStorage)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := removeOCILayoutUnusedBlobs(ctx, m.StorageManager, m.DryRun); err != nil {
+		return err
+	}
+
+	if m.SavingsReport != nil {
+		m.SavingsReport.fill(m.DryRun, imageStagesBefore, stagesBefore, m.allStageDescriptionList())
+		m.SavingsReport.log(ctx)
+	}
+
 	return nil
 }
 
-func (m *cleanupManager) skipStageIDsThatAreUsedInKubernetes(ctx context.Context, deployedDockerImages []*DeployedDockerImage) error {
-	handledDeployedStages := map[string]bool{}
-	handleTagFunc := func(tag, stageID string, f func()) {
+func (m *cleanupManager) allStageDescriptionList() []*image.StageDescription {
+	stages := m.stageManager.GetStageDescriptionList(stage_manager.StageDescriptionListOptions{})
+	return append(stages, m.stageManager.GetFinalStageDescriptionList(stage_manager.StageDescriptionListOptions{})...)
+}
+
+// skipStageIDsThatAreUsed protects stages that are referenced by the content-based tag or custom tags in usedImageResources (docker image name -> resources that use it)
+func (m *cleanupManager) skipStageIDsThatAreUsed(ctx context.Context, usedImageResources map[string][]string, reason string) error {
+	handledUsedStages := map[string]bool{}
+	handleTagFunc := func(tag, stageID string, f func(resources []string)) {
 		dockerImageName := fmt.Sprintf("%s:%s", m.StorageManager.GetStagesStorage().Address(), tag)
-		for _, deployedDockerImage := range deployedDockerImages {
-			if deployedDockerImage.Name == dockerImageName {
-				if !handledDeployedStages[stageID] {
-					f()
-
-					logboek.Context(ctx).Default().LogFDetails("tag: %s\n", tag)
-					logboek.Context(ctx).Default().LogBlock("used by resources").Do(func() {
-						for _, cr := range deployedDockerImage.ContextResources {
-							for _, r := range cr.ResourcesNames {
-								logboek.Context(ctx).Default().LogF("ctx/%s %s\n", cr.ContextName, r)
-							}
-						}
-					})
+		resources, ok := usedImageResources[dockerImageName]
+		if !ok || handledUsedStages[stageID] {
+			return
+		}
 
-					logboek.Context(ctx).LogOptionalLn()
-					handledDeployedStages[stageID] = true
-				}
+		f(resources)
 
-				break
+		logboek.Context(ctx).Default().LogFDetails("tag: %s\n", tag)
+		logboek.Context(ctx).Default().LogBlock("used by resources").Do(func() {
+			for _, r := range resources {
+				logboek.Context(ctx).Default().LogF("%s\n", r)
 			}
-		}
+		})
+
+		logboek.Context(ctx).LogOptionalLn()
+		handledUsedStages[stageID] = true
 	}
 
 	for _, stageID := range m.stageManager.GetStageIDList() {
-		handleTagFunc(stageID, stageID, func() {
-			m.stageManager.MarkStageAsProtected(stageID, "used in the Kubernetes")
+		handleTagFunc(stageID, stageID, func(resources []string) {
+			m.stageManager.MarkStageAsProtected(stageID, reason, resources...)
 		})
 	}
 
 	for stageID, customTagList := range m.stageManager.GetCustomTagsMetadata() {
 		for _, customTag := range customTagList {
-			handleTagFunc(customTag, stageID, func() {
+			handleTagFunc(customTag, stageID, func(resources []string) {
 				if m.stageManager.IsStageExist(stageID) {
 					// keep existent stage and associated custom tags
-					m.stageManager.MarkStageAsProtected(stageID, "used in the Kubernetes")
+					m.stageManager.MarkStageAsProtected(stageID, reason, resources...)
 				} else {
 					// keep custom tags that do not have associated existent stage
+					m.Plan.addCustomTags(CleanupPlanActionKeep, reason, stageID, customTagList...)
 					m.stageManager.ForgetCustomTagsByStageID(stageID)
 				}
 			})
@@ -215,24 +324,15 @@ func (m *cleanupManager) skipStageIDsThatAreUsedInKubernetes(ctx context.Context
 	return nil
 }
 
-func (m *cleanupManager) skipFinalStageIDsThatAreUsedInKubernetes(ctx context.Context, deployedDockerImages []*DeployedDockerImage) error {
-	handledDeployedFinalStages := map[string]bool{}
-Loop:
+func (m *cleanupManager) skipFinalStageIDsThatAreUsed(ctx context.Context, usedImageResources map[string][]string, reason string) error {
 	for _, stageID := range m.stageManager.GetFinalStageIDList() {
 		dockerImageName := fmt.Sprintf("%s:%s", m.StorageManager.GetFinalStagesStorage().Address(), stageID)
 
-		for _, deployedDockerImage := range deployedDockerImages {
-			if deployedDockerImage.Name == dockerImageName {
-				if !handledDeployedFinalStages[stageID] {
-					m.stageManager.MarkFinalStageAsProtected(stageID, "used in the Kubernetes")
+		if resources, ok := usedImageResources[dockerImageName]; ok {
+			m.stageManager.MarkFinalStageAsProtected(stageID, reason, resources...)
 
-					logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageID)
-					logboek.Context(ctx).LogOptionalLn()
-					handledDeployedFinalStages[stageID] = true
-				}
-
-				continue Loop
-			}
+			logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageID)
+			logboek.Context(ctx).LogOptionalLn()
 		}
 	}
 
@@ -249,6 +349,28 @@ type ContextResources struct {
 	ResourcesNames []string
 }
 
+// ResourcesList returns resources that use the image in the "ctx/CONTEXT RESOURCE" form
+func (i *DeployedDockerImage) ResourcesList() []string {
+	var res []string
+	for _, cr := range i.ContextResources {
+		for _, r := range cr.ResourcesNames {
+			res = append(res, fmt.Sprintf("ctx/%s %s", cr.ContextName, r))
+		}
+	}
+
+	return res
+}
+
+// deployedDockerImagesResources maps docker image name to the resources that use the image in the "ctx/CONTEXT RESOURCE" form
+func deployedDockerImagesResources(deployedDockerImages []*DeployedDockerImage) map[string][]string {
+	res := map[string][]string{}
+	for _, deployedDockerImage := range deployedDockerImages {
+		res[deployedDockerImage.Name] = append(res[deployedDockerImage.Name], deployedDockerImage.ResourcesList()...)
+	}
+
+	return res
+}
+
 func AppendContextDeployedDockerImages(list []*DeployedDockerImage, contextName string, images []*allow_list.DeployedImage) (res []*DeployedDockerImage) {
 	for _, desc := range list {
 		res = append(res, &DeployedDockerImage{
@@ -295,7 +417,8 @@ func (m *cleanupManager) deployedDockerImages(ctx context.Context) ([]*DeployedD
 	for _, contextClient := range m.KubernetesContextClients {
 		if err := logboek.Context(ctx).LogProcessInline("Getting deployed docker images (context %s)", contextClient.ContextName).
 			DoError(func() error {
-				contextDeployedImages, err := allow_list.DeployedDockerImages(ctx, contextClient.Client, m.KubernetesNamespaceRestrictionByContext[contextClient.ContextName])
+				provider := allow_list.NewKubernetesProvider(contextClient.ContextName, contextClient.Client, m.KubernetesNamespaceRestrictionByContext[contextClient.ContextName])
+				contextDeployedImages, err := provider.UsedImages(ctx)
 				if err != nil {
 					return fmt.Errorf("cannot get deployed imagesStageList: %w", err)
 				}
@@ -311,6 +434,29 @@ func (m *cleanupManager) deployedDockerImages(ctx context.Context) ([]*DeployedD
 	return deployedDockerImages, nil
 }
 
+// allowListImagesResources maps docker image name to the resources of allow-list providers that reference the image
+func (m *cleanupManager) allowListImagesResources(ctx context.Context) (map[string][]string, error) {
+	res := map[string][]string{}
+	for _, provider := range m.AllowListProviders {
+		if err := logboek.Context(ctx).LogProcessInline("Getting used images (%s)", provider).DoError(func() error {
+			images, err := provider.UsedImages(ctx)
+			if err != nil {
+				return err
+			}
+
+			for _, img := range images {
+				res[img.Name] = append(res[img.Name], img.ResourcesNames...)
+			}
+
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
 func (m *cleanupManager) gitHistoryBasedCleanup(ctx context.Context) error {
 	gitRepository, err := m.LocalGit.PlainOpen()
 	if err != nil {
@@ -319,7 +465,7 @@ func (m *cleanupManager) gitHistoryBasedCleanup(ctx context.Context) error {
 
 	var referencesToScan []*git_history_based_cleanup.ReferenceToScan
 	if err := logboek.Context(ctx).Default().LogProcess("Preparing references to scan").DoError(func() error {
-		referencesToScan, err = git_history_based_cleanup.ReferencesToScan(ctx, gitRepository, m.ConfigMetaCleanup.KeepPolicies)
+		referencesToScan, err = git_history_based_cleanup.ReferencesToScan(ctx, gitRepository, m.ConfigMetaCleanup.GetReferencesKeepPolicies())
 		return err
 	}); err != nil {
 		return err
@@ -328,6 +474,7 @@ func (m *cleanupManager) gitHistoryBasedCleanup(ctx context.Context) error {
 	for imageName, stageIDCommitList := range m.stageManager.GetImageStageIDCommitListToCleanup() {
 		var reachedStageIDs []string
 		var hitStageIDCommitList map[string][]string
+		var stageIDReferenceHits map[string][]*git_history_based_cleanup.ReferenceHit
 		// TODO(multiarch): iterate target platforms
 		if err := logboek.Context(ctx).LogProcess(logging.ImageLogProcessName(imageName, false, "")).DoError(func() error {
 			if logboek.Context(ctx).Streams().Width() > 120 {
@@ -337,7 +484,7 @@ func (m *cleanupManager) gitHistoryBasedCleanup(ctx context.Context) error {
 
 			if err := logboek.Context(ctx).LogProcess("Scanning git references history").DoError(func() error {
 				if countStageIDCommitList(stageIDCommitList) != 0 {
-					reachedStageIDs, hitStageIDCommitList, err = git_history_based_cleanup.ScanReferencesHistory(ctx, gitRepository, referencesToScan, stageIDCommitList)
+					reachedStageIDs, hitStageIDCommitList, stageIDReferenceHits, err = git_history_based_cleanup.ScanReferencesHistory(ctx, gitRepository, referencesToScan, stageIDCommitList)
 				} else {
 					logboek.Context(ctx).LogLn("Scanning stopped due to nothing to seek")
 				}
@@ -360,7 +507,7 @@ func (m *cleanupManager) gitHistoryBasedCleanup(ctx context.Context) error {
 			}
 
 			if len(reachedStageIDs) != 0 {
-				m.handleSavedStageIDs(ctx, reachedStageIDs)
+				m.handleSavedStageIDs(ctx, reachedStageIDs, stageIDReferenceHits)
 			}
 
 			if err := logboek.Context(ctx).LogProcess("Cleaning image metadata").DoError(func() error {
@@ -452,10 +599,15 @@ func (m *cleanupManager) prepareStageIDTableRows(ctx context.Context, stageIDCus
 	return rows
 }
 
-func (m *cleanupManager) handleSavedStageIDs(ctx context.Context, savedStageIDs []string) {
+func (m *cleanupManager) handleSavedStageIDs(ctx context.Context, savedStageIDs []string, stageIDReferenceHits map[string][]*git_history_based_cleanup.ReferenceHit) {
 	logboek.Context(ctx).Default().LogBlock("Saved tags").Do(func() {
 		for _, stageID := range savedStageIDs {
-			m.stageManager.MarkStageAsProtected(stageID, "found in the git history")
+			var details []string
+			for _, hit := range stageIDReferenceHits[stageID] {
+				details = append(details, hit.String())
+			}
+
+			m.stageManager.MarkStageAsProtected(stageID, protectionReasonGitHistory, details...)
 			logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", stageID)
 			logboek.Context(ctx).LogOptionalLn()
 		}
@@ -515,10 +667,16 @@ func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName str
 		stageIDCommitListToCleanup := m.stageManager.GetStageIDCommitListToCleanup(imageName)
 	stageIDCommitListLoop:
 		for stageID, commitList := range stageIDCommitListToCleanup {
+			if m.isStageKeptByImagesKeepPolicies(stageID) {
+				m.Plan.addImageMetadata(CleanupPlanActionKeep, protectionReasonImagesKeepPolicy, imageName, map[string][]string{stageID: commitList})
+				continue
+			}
+
 			commitListToCheck := commitList
 			for _, stageIDToUnlink := range stageIDsToUnlink {
 				if stageIDToUnlink == stageID {
 					stageIDCommitListToDelete[stageID] = commitListToCheck
+					m.Plan.addImageMetadata(CleanupPlanActionDelete, "stage not found in the git history", imageName, map[string][]string{stageID: commitListToCheck})
 					continue stageIDCommitListLoop
 				}
 			}
@@ -529,6 +687,8 @@ func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName str
 			}
 
 			stageIDCommitListToDelete[stageID] = commitListToCheck
+			m.Plan.addImageMetadata(CleanupPlanActionDelete, "commit not found in the git history", imageName, map[string][]string{stageID: commitListToCheck})
+			m.Plan.addImageMetadata(CleanupPlanActionKeep, "commit found in the git history", imageName, map[string][]string{stageID: util.ExcludeFromStringArray(commitList, commitListToCheck...)})
 		}
 
 		if countStageIDCommitList(stageIDCommitListToDelete) != 0 {
@@ -548,6 +708,7 @@ func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName str
 	}
 
 	nonexistentStageIDCommitList := m.stageManager.GetNonexistentStageIDCommitList(imageName)
+	m.Plan.addImageMetadata(CleanupPlanActionDelete, "stage does not exist", imageName, nonexistentStageIDCommitList)
 	if countStageIDCommitList(nonexistentStageIDCommitList) != 0 {
 		header := fmt.Sprintf("Deleting metadata for nonexistent stageIDs (%d)", countStageIDCommitList(nonexistentStageIDCommitList))
 
@@ -563,7 +724,16 @@ func (m *cleanupManager) cleanupImageMetadata(ctx context.Context, imageName str
 		}
 	}
 
-	stageIDNonexistentCommitList := m.stageManager.GetStageIDNonexistentCommitList(imageName)
+	stageIDNonexistentCommitList := map[string][]string{}
+	for stageID, commitList := range m.stageManager.GetStageIDNonexistentCommitList(imageName) {
+		if m.isStageKeptByImagesKeepPolicies(stageID) {
+			m.Plan.addImageMetadata(CleanupPlanActionKeep, protectionReasonImagesKeepPolicy, imageName, map[string][]string{stageID: commitList})
+			continue
+		}
+
+		stageIDNonexistentCommitList[stageID] = commitList
+	}
+	m.Plan.addImageMetadata(CleanupPlanActionDelete, "commit does not exist in the local git repository", imageName, stageIDNonexistentCommitList)
 	if countStageIDCommitList(stageIDNonexistentCommitList) != 0 {
 		header := fmt.Sprintf("Deleting metadata for nonexistent commits (%d)", countStageIDCommitList(stageIDNonexistentCommitList))
 
@@ -596,8 +766,9 @@ func countStageIDCommitList(stageIDCommitList map[string][]string) int {
 func (m *cleanupManager) cleanupNonexistentImageMetadata(ctx context.Context) error {
 	var counter int
 	stageIDCommitListByNonexistentImage := m.stageManager.GetStageIDCommitListByNonexistentImage()
-	for _, stageIDCommitList := range stageIDCommitListByNonexistentImage {
+	for imageName, stageIDCommitList := range stageIDCommitListByNonexistentImage {
 		counter += countStageIDCommitList(stageIDCommitList)
+		m.Plan.addImageMetadata(CleanupPlanActionDelete, "image is not defined in werf.yaml", imageName, stageIDCommitList)
 	}
 
 	if counter == 0 {
@@ -682,9 +853,11 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 				for _, exclSD := range excludedSDListBySD {
 					if sd.Info.Name == exclSD.Info.Name {
 						excludedSDListByReason[reason] = append(excludedSDListByReason[reason], exclSD)
+						m.Plan.addStages(CleanupPlanItemStage, CleanupPlanActionKeep, reason, m.stageManager.GetStageProtectionDetails(sd.Info.Tag), exclSD)
 					} else {
 						ancestorReason := fmt.Sprintf("ancestors of images %s", reason)
 						excludedSDListByReason[ancestorReason] = append(excludedSDListByReason[ancestorReason], exclSD)
+						m.Plan.addStages(CleanupPlanItemStage, CleanupPlanActionKeep, ancestorReason, []string{sd.Info.Tag}, exclSD)
 					}
 				}
 			}
@@ -714,13 +887,15 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 	if !(m.ConfigMetaCleanup.DisableBuiltWithinLastNHoursPolicy || keepImagesBuiltWithinLastNHours == 0) {
 		var excludedSDList []*image.StageDescription
 		for _, sd := range stageDescriptionListToDelete {
-			if (time.Since(sd.Info.GetCreatedAt()).Hours()) <= float64(keepImagesBuiltWithinLastNHours) {
+			if (time.Since(sd.GetBuiltAt()).Hours()) <= float64(keepImagesBuiltWithinLastNHours) {
 				var excludedRelativesSDList []*image.StageDescription
 				stageDescriptionListToDelete, excludedRelativesSDList = m.excludeStageAndRelativesByImage(stageDescriptionListToDelete, sd.Info)
 				excludedSDList = append(excludedSDList, excludedRelativesSDList...)
 			}
 		}
 
+		m.Plan.addStages(CleanupPlanItemStage, CleanupPlanActionKeep, fmt.Sprintf("built within last %d hours", keepImagesBuiltWithinLastNHours), nil, excludedSDList...)
+
 		if len(excludedSDList) != 0 {
 			logboek.Context(ctx).Default().LogBlock("Saved stages that were built within last %d hours (%d/%d)", keepImagesBuiltWithinLastNHours, len(excludedSDList), len(stageDescriptionList)).Do(func() {
 				for _, stage := range excludedSDList {
@@ -731,6 +906,10 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 		}
 	}
 
+	for _, sd := range stageDescriptionListToDelete {
+		m.Plan.addStages(CleanupPlanItemStage, CleanupPlanActionDelete, m.stageDeletionReason(sd.Info.Tag), nil, sd)
+	}
+
 	if len(stageDescriptionListToDelete) != 0 {
 		if err := logboek.Context(ctx).Default().LogProcess("Deleting stages tags (%d/%d)", len(stageDescriptionListToDelete), stageDescriptionListCount).DoError(func() error {
 			return m.deleteStages(ctx, stageDescriptionListToDelete, false)
@@ -753,6 +932,14 @@ func (m *cleanupManager) cleanupUnusedStages(ctx context.Context) error {
 		}
 	}
 
+	if err := m.deleteUnusedVulnerabilityScanMetadata(ctx); err != nil {
+		return fmt.Errorf("unable to cleanup vulnerability scan metadata: %w", err)
+	}
+
+	if err := m.deleteUnusedDigestInputsMetadata(ctx); err != nil {
+		return fmt.Errorf("unable to cleanup digest inputs metadata: %w", err)
+	}
+
 	return nil
 }
 
@@ -765,10 +952,15 @@ func (m *cleanupManager) cleanupFinalStages(ctx context.Context) error {
 
 	var finalStagesDescriptionListToDelete []*image.StageDescription
 
+	for _, finalStg := range m.stageManager.GetFinalStageDescriptionList(stage_manager.StageDescriptionListOptions{OnlyProtected: true}) {
+		m.Plan.addStages(CleanupPlanItemFinalStage, CleanupPlanActionKeep, m.stageManager.GetFinalStageProtectionReason(finalStg.Info.Tag), m.stageManager.GetFinalStageProtectionDetails(finalStg.Info.Tag), finalStg)
+	}
+
 FilterOutFinalStages:
 	for _, finalStg := range finalStagesDescriptionList {
 		for _, stg := range stagesDescriptionList {
 			if stg.StageID.IsEqual(*finalStg.StageID) {
+				m.Plan.addStages(CleanupPlanItemFinalStage, CleanupPlanActionKeep, "stage exists in the repo", nil, finalStg)
 				continue FilterOutFinalStages
 			}
 		}
@@ -776,6 +968,8 @@ FilterOutFinalStages:
 		finalStagesDescriptionListToDelete = append(finalStagesDescriptionListToDelete, finalStg)
 	}
 
+	m.Plan.addStages(CleanupPlanItemFinalStage, CleanupPlanActionDelete, "stage does not exist in the repo", nil, finalStagesDescriptionListToDelete...)
+
 	if len(finalStagesDescriptionListToDelete) != 0 {
 		if err := logboek.Context(ctx).Default().LogProcess("Deleting final stages tags (%d/%d)", len(finalStagesDescriptionListToDelete), finalStageDescriptionListFullCount).DoError(func() error {
 			return m.deleteStages(ctx, finalStagesDescriptionListToDelete, true)
@@ -802,6 +996,8 @@ func (m *cleanupManager) initImportsMetadata(ctx context.Context, stageDescripti
 		}
 
 		if metadata == nil {
+			m.Plan.addImportMetadata(CleanupPlanActionDelete, "invalid import metadata", metadataID)
+
 			if err := logboek.Context(ctx).Warn().LogProcess("Deleting invalid import metadata %s", metadataID).
 				DoError(func() error {
 					return m.deleteImportsMetadata(ctx, []string{metadataID})
@@ -820,6 +1016,9 @@ func (m *cleanupManager) initImportsMetadata(ctx context.Context, stageDescripti
 		stage := findStageByImageID(stageDescriptionList, sourceImageID)
 		if stage == nil {
 			m.nonexistentImportMetadataIDs = append(m.nonexistentImportMetadataIDs, importSourceID)
+			m.Plan.addImportMetadata(CleanupPlanActionDelete, "source image does not exist", importSourceID)
+		} else {
+			m.Plan.addImportMetadata(CleanupPlanActionKeep, "source image exists", importSourceID)
 		}
 
 		return nil
@@ -963,8 +1162,10 @@ func (m *cleanupManager) deleteUnusedCustomTags(ctx context.Context) error {
 		numberOfCustomTags += len(customTagList)
 		if !m.stageManager.IsStageExist(stageID) {
 			customTagListToDelete = append(customTagListToDelete, customTagList...)
+			m.Plan.addCustomTags(CleanupPlanActionDelete, "associated stage does not exist", stageID, customTagList...)
 		} else {
 			customTagListToKeep = append(customTagListToKeep, customTagList...)
+			m.Plan.addCustomTags(CleanupPlanActionKeep, "associated stage exists", stageID, customTagList...)
 		}
 	}
 
@@ -994,6 +1195,246 @@ func (m *cleanupManager) deleteUnusedCustomTags(ctx context.Context) error {
 	return nil
 }
 
+func (m *cleanupManager) deleteUnusedVulnerabilityScanMetadata(ctx context.Context) error {
+	stageIDs, err := m.StorageManager.GetStagesStorage().GetVulnerabilityScanMetadataIDs(ctx, m.ProjectName, storage.WithCache())
+	if err != nil {
+		return err
+	}
+
+	var stageIDsToDelete []string
+	for _, stageID := range stageIDs {
+		if !m.stageManager.IsStageExist(stageID) {
+			stageIDsToDelete = append(stageIDsToDelete, stageID)
+			m.Plan.addVulnerabilityScanMetadata(CleanupPlanActionDelete, "associated stage does not exist", stageID)
+		} else {
+			m.Plan.addVulnerabilityScanMetadata(CleanupPlanActionKeep, "associated stage exists", stageID)
+		}
+	}
+
+	if len(stageIDsToDelete) != 0 {
+		if err := logboek.Context(ctx).Default().LogProcess("Cleaning vulnerability scan metadata (%d/%d)", len(stageIDsToDelete), len(stageIDs)).DoError(func() error {
+			return deleteVulnerabilityScanMetadata(ctx, m.ProjectName, m.StorageManager, stageIDsToDelete, m.DryRun)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteVulnerabilityScanMetadata(ctx context.Context, projectName string, storageManager manager.StorageManagerInterface, stageIDs []string, dryRun bool) error {
+	if dryRun {
+		for _, stageID := range stageIDs {
+			logboek.Context(ctx).Info().LogFDetails("  stageID: %s\n", stageID)
+			logboek.Context(ctx).Info().LogOptionalLn()
+		}
+		return nil
+	}
+
+	return storageManager.ForEachRmVulnerabilityScanMetadata(ctx, projectName, stageIDs, func(ctx context.Context, stageID string, err error) error {
+		if err != nil {
+			if err := handleDeletionError(err); err != nil {
+				return err
+			}
+
+			logboek.Context(ctx).Warn().LogF("WARNING: Vulnerability scan metadata of stage %s deletion failed: %s\n", stageID, err)
+
+			return nil
+		}
+
+		logboek.Context(ctx).Info().LogFDetails("  stageID: %s\n", stageID)
+
+		return nil
+	})
+}
+
+func (m *cleanupManager) deleteUnusedDigestInputsMetadata(ctx context.Context) error {
+	stageIDs, err := m.StorageManager.GetStagesStorage().GetDigestInputsMetadataIDs(ctx, m.ProjectName, storage.WithCache())
+	if err != nil {
+		return err
+	}
+
+	var stageIDsToDelete []string
+	for _, stageID := range stageIDs {
+		if !m.stageManager.IsStageExist(stageID) {
+			stageIDsToDelete = append(stageIDsToDelete, stageID)
+			m.Plan.addDigestInputsMetadata(CleanupPlanActionDelete, "associated stage does not exist", stageID)
+		} else {
+			m.Plan.addDigestInputsMetadata(CleanupPlanActionKeep, "associated stage exists", stageID)
+		}
+	}
+
+	if len(stageIDsToDelete) != 0 {
+		if err := logboek.Context(ctx).Default().LogProcess("Cleaning digest inputs metadata (%d/%d)", len(stageIDsToDelete), len(stageIDs)).DoError(func() error {
+			return deleteDigestInputsMetadata(ctx, m.ProjectName, m.StorageManager, stageIDsToDelete, m.DryRun)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteDigestInputsMetadata(ctx context.Context, projectName string, storageManager manager.StorageManagerInterface, stageIDs []string, dryRun bool) error {
+	if dryRun {
+		for _, stageID := range stageIDs {
+			logboek.Context(ctx).Info().LogFDetails("  stageID: %s\n", stageID)
+			logboek.Context(ctx).Info().LogOptionalLn()
+		}
+		return nil
+	}
+
+	return storageManager.ForEachRmDigestInputsMetadata(ctx, projectName, stageIDs, func(ctx context.Context, stageID string, err error) error {
+		if err != nil {
+			if err := handleDeletionError(err); err != nil {
+				return err
+			}
+
+			logboek.Context(ctx).Warn().LogF("WARNING: Digest inputs metadata of stage %s deletion failed: %s\n", stageID, err)
+
+			return nil
+		}
+
+		logboek.Context(ctx).Info().LogFDetails("  stageID: %s\n", stageID)
+
+		return nil
+	})
+}
+
+// cleanupImageArtifacts deletes the SBOMs, the signatures and the referrers fallback indexes of the deleted manifests:
+// the artifacts of the stages deleted by this cleanup are deleted without requests, the artifacts of the unknown manifests are deleted if there is no manifest in the repo
+func (m *cleanupManager) cleanupImageArtifacts(ctx context.Context, stagesStorage storage.StagesStorage, digestsBefore map[string]bool, stagesAfter []*image.StageDescription) error {
+	repoStagesStorage, isRepo := stagesStorage.(*storage.RepoStagesStorage)
+	if !isRepo {
+		return nil
+	}
+
+	digestsAfter := stageDescriptionListDigests(stagesAfter)
+
+	return logboek.Context(ctx).LogProcess("Cleanup SBOMs and signatures of deleted images in %s", repoStagesStorage.String()).DoError(func() error {
+		tags, err := repoStagesStorage.GetImageArtifactTags(ctx, storage.WithCache())
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range tags {
+			subjectDigest := storage.GetImageArtifactTagSubjectDigest(tag)
+			if digestsAfter[subjectDigest] {
+				continue
+			}
+
+			if !digestsBefore[subjectDigest] {
+				exist, err := repoStagesStorage.IsManifestExist(ctx, subjectDigest)
+				if err != nil {
+					return fmt.Errorf("unable to check manifest %s existence: %w", subjectDigest, err)
+				}
+
+				if exist {
+					continue
+				}
+			}
+
+			if !m.DryRun {
+				if err := repoStagesStorage.DeleteImageArtifactTag(ctx, tag); err != nil {
+					if err := handleDeletionError(err); err != nil {
+						return err
+					}
+
+					logboek.Context(ctx).Warn().LogF("WARNING: SBOM or signature tag %s deletion failed: %s\n", tag, err)
+
+					continue
+				}
+			}
+
+			logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", tag)
+		}
+
+		return nil
+	})
+}
+
+// cleanupDockerfileLayersCache deletes the Dockerfile layers cache tags which were built earlier than the specified number of hours ago.
+// The cache tags are not related to the stages, so the age is the only criteria to get rid of the cache of the removed or changed instructions.
+// The repo without the layers cache record is skipped, the tags of other images committed not by Buildah are kept
+func (m *cleanupManager) cleanupDockerfileLayersCache(ctx context.Context, repoStagesStorage *storage.RepoStagesStorage) error {
+	tags, err := repoStagesStorage.GetDockerfileLayersCacheTags(ctx, storage.WithCache())
+	if err != nil {
+		return err
+	}
+
+	keepReason := fmt.Sprintf("built within last %d hours", m.KeepDockerfileLayersCacheBuiltWithinLastNHours)
+	deleteReason := fmt.Sprintf("built earlier than %d hours ago", m.KeepDockerfileLayersCacheBuiltWithinLastNHours)
+
+	for _, tag := range tags {
+		info, err := repoStagesStorage.GetDockerfileLayersCacheTagInfo(ctx, tag)
+		if err != nil {
+			return err
+		}
+
+		if info == nil {
+			continue
+		}
+
+		if time.Since(info.GetCreatedAt()).Hours() <= float64(m.KeepDockerfileLayersCacheBuiltWithinLastNHours) {
+			m.Plan.addDockerfileLayersCacheTags(CleanupPlanActionKeep, keepReason, tag)
+			continue
+		}
+
+		m.Plan.addDockerfileLayersCacheTags(CleanupPlanActionDelete, deleteReason, tag)
+
+		if !m.DryRun {
+			if err := repoStagesStorage.DeleteDockerfileLayersCacheTag(ctx, tag); err != nil {
+				if err := handleDeletionError(err); err != nil {
+					return err
+				}
+
+				logboek.Context(ctx).Warn().LogF("WARNING: Dockerfile layers cache tag %s deletion failed: %s\n", tag, err)
+
+				continue
+			}
+		}
+
+		logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", tag)
+	}
+
+	return nil
+}
+
+// stageDescriptionListDigests returns the digests of the stages manifests and of the platform manifests of the multiplatform stages
+func stageDescriptionListDigests(stages []*image.StageDescription) map[string]bool {
+	digests := map[string]bool{}
+	for _, stageDesc := range stages {
+		digests[stageDesc.Info.RepoDigest] = true
+		for _, platformInfo := range stageDesc.Info.Index {
+			digests[platformInfo.RepoDigest] = true
+		}
+	}
+
+	return digests
+}
+
+// removeOCILayoutUnusedBlobs removes the blobs of the deleted OCI layout tags once for the whole cleanup or purge
+func removeOCILayoutUnusedBlobs(ctx context.Context, storageManager manager.StorageManagerInterface, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+
+	for _, stagesStorage := range []storage.StagesStorage{storageManager.GetStagesStorage(), storageManager.GetFinalStagesStorage()} {
+		ociLayoutStagesStorage, isOCILayout := stagesStorage.(*storage.OCILayoutStagesStorage)
+		if !isOCILayout {
+			continue
+		}
+
+		if err := logboek.Context(ctx).Default().LogProcess("Removing unused blobs of %s", ociLayoutStagesStorage.String()).DoError(func() error {
+			return ociLayoutStagesStorage.RemoveUnusedBlobs(ctx)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func excludeStages(stages []*image.StageDescription, stagesToExclude ...*image.StageDescription) []*image.StageDescription {
 	var updatedStageList []*image.StageDescription
 
@@ -0,0 +1,425 @@
+package cleaning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/storage/manager"
+)
+
+type CleanupPlanItemKind string
+
+const (
+	CleanupPlanItemStage          CleanupPlanItemKind = "stage"
+	CleanupPlanItemFinalStage     CleanupPlanItemKind = "finalStage"
+	CleanupPlanItemCustomTag      CleanupPlanItemKind = "customTag"
+	CleanupPlanItemImageMetadata  CleanupPlanItemKind = "imageMetadata"
+	CleanupPlanItemImportMetadata CleanupPlanItemKind = "importMetadata"
+
+	CleanupPlanItemVulnerabilityScanMetadata CleanupPlanItemKind = "vulnerabilityScanMetadata"
+	CleanupPlanItemDigestInputsMetadata      CleanupPlanItemKind = "digestInputsMetadata"
+	CleanupPlanItemDockerfileLayersCache     CleanupPlanItemKind = "dockerfileLayersCache"
+)
+
+var cleanupPlanItemKindOrder = []CleanupPlanItemKind{
+	CleanupPlanItemStage,
+	CleanupPlanItemFinalStage,
+	CleanupPlanItemCustomTag,
+	CleanupPlanItemImageMetadata,
+	CleanupPlanItemImportMetadata,
+	CleanupPlanItemVulnerabilityScanMetadata,
+	CleanupPlanItemDigestInputsMetadata,
+	CleanupPlanItemDockerfileLayersCache,
+}
+
+type CleanupPlanAction string
+
+const (
+	CleanupPlanActionKeep   CleanupPlanAction = "keep"
+	CleanupPlanActionDelete CleanupPlanAction = "delete"
+)
+
+type CleanupPlan struct {
+	ProjectName string             `json:"projectName"`
+	Repo        string             `json:"repo"`
+	FinalRepo   string             `json:"finalRepo,omitempty"`
+	Items       []*CleanupPlanItem `json:"items"`
+
+	mutex sync.Mutex
+}
+
+type CleanupPlanItem struct {
+	Kind   CleanupPlanItemKind `json:"kind"`
+	Action CleanupPlanAction   `json:"action"`
+	Reason string              `json:"reason"`
+	// Details contain git references, Kubernetes resources or stages that caused the decision
+	Details []string `json:"details,omitempty"`
+
+	Tag              string `json:"tag,omitempty"`
+	StageID          string `json:"stageID,omitempty"`
+	ImageName        string `json:"imageName,omitempty"`
+	Commit           string `json:"commit,omitempty"`
+	ImportMetadataID string `json:"importMetadataID,omitempty"`
+}
+
+func NewCleanupPlan(projectName string, storageManager manager.StorageManagerInterface) *CleanupPlan {
+	plan := &CleanupPlan{
+		ProjectName: projectName,
+		Repo:        storageManager.GetStagesStorage().Address(),
+	}
+
+	if finalStagesStorage := storageManager.GetFinalStagesStorage(); finalStagesStorage != nil {
+		plan.FinalRepo = finalStagesStorage.Address()
+	}
+
+	return plan
+}
+
+func (p *CleanupPlan) add(item *CleanupPlanItem) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.Items = append(p.Items, item)
+}
+
+func (p *CleanupPlan) addStages(kind CleanupPlanItemKind, action CleanupPlanAction, reason string, details []string, stages ...*image.StageDescription) {
+	for _, stg := range stages {
+		p.add(&CleanupPlanItem{Kind: kind, Action: action, Reason: reason, Details: details, Tag: stg.Info.Tag})
+	}
+}
+
+func (p *CleanupPlan) addCustomTags(action CleanupPlanAction, reason, stageID string, customTags ...string) {
+	for _, customTag := range customTags {
+		p.add(&CleanupPlanItem{Kind: CleanupPlanItemCustomTag, Action: action, Reason: reason, StageID: stageID, Tag: customTag})
+	}
+}
+
+func (p *CleanupPlan) addImageMetadata(action CleanupPlanAction, reason, imageName string, stageIDCommitList map[string][]string) {
+	for stageID, commitList := range stageIDCommitList {
+		for _, commit := range commitList {
+			p.add(&CleanupPlanItem{Kind: CleanupPlanItemImageMetadata, Action: action, Reason: reason, ImageName: imageName, StageID: stageID, Commit: commit})
+		}
+	}
+}
+
+func (p *CleanupPlan) addImportMetadata(action CleanupPlanAction, reason, importMetadataID string) {
+	p.add(&CleanupPlanItem{Kind: CleanupPlanItemImportMetadata, Action: action, Reason: reason, ImportMetadataID: importMetadataID})
+}
+
+func (p *CleanupPlan) addVulnerabilityScanMetadata(action CleanupPlanAction, reason string, stageIDs ...string) {
+	for _, stageID := range stageIDs {
+		p.add(&CleanupPlanItem{Kind: CleanupPlanItemVulnerabilityScanMetadata, Action: action, Reason: reason, StageID: stageID})
+	}
+}
+
+func (p *CleanupPlan) addDigestInputsMetadata(action CleanupPlanAction, reason string, stageIDs ...string) {
+	for _, stageID := range stageIDs {
+		p.add(&CleanupPlanItem{Kind: CleanupPlanItemDigestInputsMetadata, Action: action, Reason: reason, StageID: stageID})
+	}
+}
+
+func (p *CleanupPlan) addDockerfileLayersCacheTags(action CleanupPlanAction, reason string, tags ...string) {
+	for _, tag := range tags {
+		p.add(&CleanupPlanItem{Kind: CleanupPlanItemDockerfileLayersCache, Action: action, Reason: reason, Tag: tag})
+	}
+}
+
+// ItemsToDelete returns plan items of the specified kind marked for deletion
+func (p *CleanupPlan) ItemsToDelete(kind CleanupPlanItemKind) []*CleanupPlanItem {
+	var res []*CleanupPlanItem
+	for _, item := range p.Items {
+		if item.Kind == kind && item.Action == CleanupPlanActionDelete {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
+
+func (p *CleanupPlan) sortItems() {
+	kindIndex := func(kind CleanupPlanItemKind) int {
+		for ind, k := range cleanupPlanItemKindOrder {
+			if k == kind {
+				return ind
+			}
+		}
+		return len(cleanupPlanItemKindOrder)
+	}
+
+	sort.SliceStable(p.Items, func(i, j int) bool {
+		a, b := p.Items[i], p.Items[j]
+		if a.Kind != b.Kind {
+			return kindIndex(a.Kind) < kindIndex(b.Kind)
+		}
+
+		for _, pair := range [][2]string{
+			{a.ImageName, b.ImageName},
+			{a.StageID, b.StageID},
+			{a.Tag, b.Tag},
+			{a.Commit, b.Commit},
+			{a.ImportMetadataID, b.ImportMetadataID},
+		} {
+			if pair[0] != pair[1] {
+				return pair[0] < pair[1]
+			}
+		}
+
+		return false
+	})
+}
+
+// WriteCleanupPlan writes plan in YAML format if path has .yaml or .yml extension, in JSON format otherwise
+func WriteCleanupPlan(plan *CleanupPlan, path string) error {
+	plan.sortItems()
+
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(plan)
+	default:
+		data, err = json.MarshalIndent(plan, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("unable to marshal cleanup plan: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write cleanup plan %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// ReadCleanupPlan reads plan written by WriteCleanupPlan (YAML parser handles both formats)
+func ReadCleanupPlan(path string) (*CleanupPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cleanup plan %q: %w", path, err)
+	}
+
+	plan := &CleanupPlan{}
+	if err := yaml.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal cleanup plan %q: %w", path, err)
+	}
+
+	return plan, nil
+}
+
+type ApplyCleanupPlanOptions struct {
+	DryRun bool
+}
+
+// ApplyCleanupPlan deletes exactly the items marked for deletion in the plan, the cleanup policies are not evaluated again
+func ApplyCleanupPlan(ctx context.Context, projectName string, storageManager manager.StorageManagerInterface, plan *CleanupPlan, options ApplyCleanupPlanOptions) error {
+	if plan.ProjectName != projectName {
+		return fmt.Errorf("cleanup plan is for project %q, but current project is %q", plan.ProjectName, projectName)
+	}
+
+	if repo := storageManager.GetStagesStorage().Address(); plan.Repo != repo {
+		return fmt.Errorf("cleanup plan is for repo %q, but current repo is %q", plan.Repo, repo)
+	}
+
+	var finalRepo string
+	if finalStagesStorage := storageManager.GetFinalStagesStorage(); finalStagesStorage != nil {
+		finalRepo = finalStagesStorage.Address()
+	}
+	if plan.FinalRepo != finalRepo {
+		return fmt.Errorf("cleanup plan is for final repo %q, but current final repo is %q", plan.FinalRepo, finalRepo)
+	}
+
+	deleteStageOptions := manager.ForEachDeleteStageOptions{
+		FilterStagesAndProcessRelatedDataOptions: storage.FilterStagesAndProcessRelatedDataOptions{
+			SkipUsedImage: true,
+		},
+	}
+
+	if items := plan.ItemsToDelete(CleanupPlanItemImageMetadata); len(items) != 0 {
+		imageStageIDCommitList := map[string]map[string][]string{}
+		for _, item := range items {
+			if _, ok := imageStageIDCommitList[item.ImageName]; !ok {
+				imageStageIDCommitList[item.ImageName] = map[string][]string{}
+			}
+			imageStageIDCommitList[item.ImageName][item.StageID] = append(imageStageIDCommitList[item.ImageName][item.StageID], item.Commit)
+		}
+
+		if err := logboek.Context(ctx).Default().LogProcess("Deleting image metadata (%d)", len(items)).DoError(func() error {
+			for imageName, stageIDCommitList := range imageStageIDCommitList {
+				if err := deleteImageMetadata(ctx, projectName, storageManager, imageName, stageIDCommitList, options.DryRun); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	if items := plan.ItemsToDelete(CleanupPlanItemStage); len(items) != 0 {
+		stageDescriptionList, err := storageManager.GetStageDescriptionList(ctx)
+		if err != nil {
+			return err
+		}
+
+		stagesToDelete := selectCleanupPlanStages(ctx, stageDescriptionList, items)
+		if len(stagesToDelete) != 0 {
+			if err := logboek.Context(ctx).Default().LogProcess("Deleting stages tags (%d/%d)", len(stagesToDelete), len(items)).DoError(func() error {
+				return deleteStages(ctx, storageManager, options.DryRun, deleteStageOptions, stagesToDelete, false)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if items := plan.ItemsToDelete(CleanupPlanItemFinalStage); len(items) != 0 {
+		finalStageDescriptionList, err := storageManager.GetFinalStageDescriptionList(ctx)
+		if err != nil {
+			return err
+		}
+
+		stagesToDelete := selectCleanupPlanStages(ctx, finalStageDescriptionList, items)
+		if len(stagesToDelete) != 0 {
+			if err := logboek.Context(ctx).Default().LogProcess("Deleting final stages tags (%d/%d)", len(stagesToDelete), len(items)).DoError(func() error {
+				return deleteStages(ctx, storageManager, options.DryRun, deleteStageOptions, stagesToDelete, true)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if items := plan.ItemsToDelete(CleanupPlanItemCustomTag); len(items) != 0 {
+		var customTagList []string
+		for _, item := range items {
+			customTagList = append(customTagList, item.Tag)
+		}
+
+		if err := logboek.Context(ctx).LogProcess("Deleting custom tags (%d)", len(customTagList)).DoError(func() error {
+			return deleteCustomTags(ctx, storageManager, customTagList, options.DryRun)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if items := plan.ItemsToDelete(CleanupPlanItemImportMetadata); len(items) != 0 {
+		var importMetadataIDs []string
+		for _, item := range items {
+			importMetadataIDs = append(importMetadataIDs, item.ImportMetadataID)
+		}
+
+		if err := logboek.Context(ctx).Default().LogProcess("Cleaning imports metadata (%d)", len(importMetadataIDs)).DoError(func() error {
+			return deleteImportsMetadata(ctx, projectName, storageManager, importMetadataIDs, options.DryRun)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if items := plan.ItemsToDelete(CleanupPlanItemVulnerabilityScanMetadata); len(items) != 0 {
+		var stageIDs []string
+		for _, item := range items {
+			stageIDs = append(stageIDs, item.StageID)
+		}
+
+		if err := logboek.Context(ctx).Default().LogProcess("Cleaning vulnerability scan metadata (%d)", len(stageIDs)).DoError(func() error {
+			return deleteVulnerabilityScanMetadata(ctx, projectName, storageManager, stageIDs, options.DryRun)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if items := plan.ItemsToDelete(CleanupPlanItemDigestInputsMetadata); len(items) != 0 {
+		var stageIDs []string
+		for _, item := range items {
+			stageIDs = append(stageIDs, item.StageID)
+		}
+
+		if err := logboek.Context(ctx).Default().LogProcess("Cleaning digest inputs metadata (%d)", len(stageIDs)).DoError(func() error {
+			return deleteDigestInputsMetadata(ctx, projectName, storageManager, stageIDs, options.DryRun)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func selectCleanupPlanStages(ctx context.Context, stageDescriptionList []*image.StageDescription, items []*CleanupPlanItem) []*image.StageDescription {
+	var res []*image.StageDescription
+
+itemsLoop:
+	for _, item := range items {
+		for _, stg := range stageDescriptionList {
+			if stg.Info.Tag == item.Tag {
+				res = append(res, stg)
+				continue itemsLoop
+			}
+		}
+
+		logboek.Context(ctx).Warn().LogF("WARNING: Stage %s from the cleanup plan not found, skipping\n", item.Tag)
+	}
+
+	return res
+}
+
+// ItemsRelatedTo returns plan items related to the werf image name or the stage ID (full or digest only): stages with their ancestors, final stages, custom tags and metadata
+func (p *CleanupPlan) ItemsRelatedTo(imageNameOrStageID string) []*CleanupPlanItem {
+	stageIDs := []string{imageNameOrStageID}
+	for _, item := range p.Items {
+		if item.Kind == CleanupPlanItemImageMetadata && item.ImageName == imageNameOrStageID {
+			stageIDs = append(stageIDs, item.StageID)
+		}
+	}
+
+	isRelatedStageID := func(stageID string) bool {
+		if stageID == "" {
+			return false
+		}
+
+		for _, id := range stageIDs {
+			if stageID == id || strings.HasPrefix(stageID, id+"-") {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	var res []*CleanupPlanItem
+	for _, item := range p.Items {
+		isRelated := item.ImageName == imageNameOrStageID || isRelatedStageID(item.StageID)
+
+		switch item.Kind {
+		case CleanupPlanItemStage:
+			isRelated = isRelated || isRelatedStageID(item.Tag)
+
+			// stages kept as ancestors of related stages
+			for _, detail := range item.Details {
+				isRelated = isRelated || isRelatedStageID(detail)
+			}
+		case CleanupPlanItemFinalStage:
+			isRelated = isRelated || isRelatedStageID(item.Tag)
+		}
+
+		if isRelated {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,76 @@
+package cleaning
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCleanupPlan_ItemsRelatedTo(t *testing.T) {
+	backendStage := &CleanupPlanItem{Kind: CleanupPlanItemStage, Action: CleanupPlanActionKeep, Reason: "used by image", Tag: "backend-digest-1000"}
+	backendAncestor := &CleanupPlanItem{Kind: CleanupPlanItemStage, Action: CleanupPlanActionKeep, Reason: "ancestor of kept stage", Details: []string{"backend-digest-1000"}, Tag: "base-digest-900"}
+	backendFinalStage := &CleanupPlanItem{Kind: CleanupPlanItemFinalStage, Action: CleanupPlanActionKeep, Reason: "used by image", Tag: "backend-digest-1000"}
+	backendCustomTag := &CleanupPlanItem{Kind: CleanupPlanItemCustomTag, Action: CleanupPlanActionKeep, Reason: "stage is kept", StageID: "backend-digest-1000", Tag: "backend-main"}
+	backendMetadata := &CleanupPlanItem{Kind: CleanupPlanItemImageMetadata, Action: CleanupPlanActionKeep, Reason: "git history", ImageName: "backend", StageID: "backend-digest-1000", Commit: "c1"}
+	backendScanMetadata := &CleanupPlanItem{Kind: CleanupPlanItemVulnerabilityScanMetadata, Action: CleanupPlanActionKeep, Reason: "stage is kept", StageID: "backend-digest-1000"}
+
+	frontendStage := &CleanupPlanItem{Kind: CleanupPlanItemStage, Action: CleanupPlanActionDelete, Reason: "not used", Tag: "frontend-digest-2000"}
+	frontendMetadata := &CleanupPlanItem{Kind: CleanupPlanItemImageMetadata, Action: CleanupPlanActionDelete, Reason: "not found in git history", ImageName: "frontend", StageID: "frontend-digest-2000", Commit: "c2"}
+	importMetadata := &CleanupPlanItem{Kind: CleanupPlanItemImportMetadata, Action: CleanupPlanActionDelete, Reason: "source stage is deleted", ImportMetadataID: "import-id"}
+
+	plan := &CleanupPlan{
+		Items: []*CleanupPlanItem{
+			backendStage, backendAncestor, backendFinalStage, backendCustomTag, backendMetadata, backendScanMetadata,
+			frontendStage, frontendMetadata, importMetadata,
+		},
+	}
+
+	for _, tc := range []struct {
+		name               string
+		imageNameOrStageID string
+		expected           []*CleanupPlanItem
+	}{
+		{
+			name:               "image name",
+			imageNameOrStageID: "backend",
+			expected:           []*CleanupPlanItem{backendStage, backendAncestor, backendFinalStage, backendCustomTag, backendMetadata, backendScanMetadata},
+		},
+		{
+			name:               "full stage ID",
+			imageNameOrStageID: "backend-digest-1000",
+			expected:           []*CleanupPlanItem{backendStage, backendAncestor, backendFinalStage, backendCustomTag, backendMetadata, backendScanMetadata},
+		},
+		{
+			name:               "stage digest",
+			imageNameOrStageID: "frontend-digest",
+			expected:           []*CleanupPlanItem{frontendStage, frontendMetadata},
+		},
+		{
+			name:               "ancestor stage ID does not include descendants",
+			imageNameOrStageID: "base-digest-900",
+			expected:           []*CleanupPlanItem{backendAncestor},
+		},
+		{
+			name:               "digest prefix is not a stage digest",
+			imageNameOrStageID: "backend-dig",
+		},
+		{
+			name:               "unknown image",
+			imageNameOrStageID: "unknown",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			items := plan.ItemsRelatedTo(tc.imageNameOrStageID)
+			if !reflect.DeepEqual(items, tc.expected) {
+				t.Errorf("expected items %s, got %s", cleanupPlanItemsString(tc.expected), cleanupPlanItemsString(items))
+			}
+		})
+	}
+}
+
+func cleanupPlanItemsString(items []*CleanupPlanItem) []string {
+	var res []string
+	for _, item := range items {
+		res = append(res, string(item.Kind)+":"+item.Tag+item.StageID+item.ImportMetadataID)
+	}
+	return res
+}
@@ -0,0 +1,169 @@
+package cleaning
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+)
+
+type dockerfileLayersCacheTestRepo struct {
+	repo                     string
+	repoStagesStorage        *storage.RepoStagesStorage
+	deletedManifestsRecorder *[]string
+
+	oldCacheTag    string
+	oldCacheDigest v1.Hash
+}
+
+// newDockerfileLayersCacheTestRepo pushes the old and the recent layers cache images committed by Buildah,
+// the old image with the cache-like tag which is not committed by Buildah and the old stage
+func newDockerfileLayersCacheTestRepo(t *testing.T) *dockerfileLayersCacheTestRepo {
+	// the test registry does not untag the manifest deleted by digest, so the deletion requests are recorded
+	var deletedManifests []string
+	registryHandler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deletedManifests = append(deletedManifests, path.Base(r.URL.Path))
+		}
+		registryHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	repo := strings.TrimPrefix(server.URL, "http://") + "/project"
+	dockerRegistry, err := docker_registry.NewDockerRegistry(repo, docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{InsecureRegistry: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &dockerfileLayersCacheTestRepo{
+		repo:                     repo,
+		repoStagesStorage:        storage.NewRepoStagesStorage(repo, nil, dockerRegistry),
+		deletedManifestsRecorder: &deletedManifests,
+		oldCacheTag:              strings.Repeat("a", 64),
+	}
+
+	buildahLabels := map[string]string{image.BuildahVersionLabel: "1.30.0"}
+	r.oldCacheDigest = pushTestImageWithLabels(t, repo+":"+r.oldCacheTag, time.Now().Add(-10*24*time.Hour), buildahLabels)
+	pushTestImageWithLabels(t, repo+":"+strings.Repeat("b", 64), time.Now().Add(-time.Hour), buildahLabels)
+	pushTestImage(t, repo+":"+strings.Repeat("c", 64), time.Now().Add(-10*24*time.Hour))
+	pushTestImage(t, repo+":2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7-1611836746968", time.Now().Add(-10*24*time.Hour))
+
+	return r
+}
+
+func (r *dockerfileLayersCacheTestRepo) deletedManifests() []string {
+	return *r.deletedManifestsRecorder
+}
+
+func TestCleanup_DockerfileLayersCacheKeepsRecentlyBuiltTags(t *testing.T) {
+	ctx := context.Background()
+	r := newDockerfileLayersCacheTestRepo(t)
+
+	if err := r.repoStagesStorage.PutDockerfileLayersCacheRecord(ctx, "project"); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &CleanupPlan{}
+	m := &cleanupManager{KeepDockerfileLayersCacheBuiltWithinLastNHours: 168, Plan: plan}
+	if err := m.cleanupDockerfileLayersCache(ctx, r.repoStagesStorage); err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted := r.deletedManifests(); len(deleted) != 1 || deleted[0] != r.oldCacheDigest.String() {
+		t.Errorf("expected only manifest %s to be deleted, got %v", r.oldCacheDigest, deleted)
+	}
+
+	if items := plan.ItemsToDelete(CleanupPlanItemDockerfileLayersCache); len(items) != 1 || items[0].Tag != r.oldCacheTag {
+		t.Errorf("expected only %s to be planned for deletion, got %+v", r.oldCacheTag, items)
+	}
+}
+
+func TestCleanup_DockerfileLayersCacheWithoutRecord(t *testing.T) {
+	ctx := context.Background()
+	r := newDockerfileLayersCacheTestRepo(t)
+
+	plan := &CleanupPlan{}
+	m := &cleanupManager{KeepDockerfileLayersCacheBuiltWithinLastNHours: 168, Plan: plan}
+	if err := m.cleanupDockerfileLayersCache(ctx, r.repoStagesStorage); err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted := r.deletedManifests(); len(deleted) != 0 {
+		t.Errorf("expected no manifests to be deleted without the layers cache record, got %v", deleted)
+	}
+
+	if len(plan.Items) != 0 {
+		t.Errorf("expected no plan items without the layers cache record, got %+v", plan.Items)
+	}
+}
+
+func TestPurge_DockerfileLayersCache(t *testing.T) {
+	ctx := context.Background()
+	r := newDockerfileLayersCacheTestRepo(t)
+
+	if err := r.repoStagesStorage.PutDockerfileLayersCacheRecord(ctx, "project"); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &purgeManager{}
+	if err := m.deleteDockerfileLayersCache(ctx, r.repoStagesStorage); err != nil {
+		t.Fatal(err)
+	}
+
+	// the old and the recent cache images and the record are deleted
+	if deleted := r.deletedManifests(); len(deleted) != 3 {
+		t.Errorf("expected 3 manifests to be deleted, got %v", deleted)
+	}
+}
+
+func pushTestImage(t *testing.T, reference string, createdAt time.Time) v1.Hash {
+	return pushTestImageWithLabels(t, reference, createdAt, nil)
+}
+
+func pushTestImageWithLabels(t *testing.T, reference string, createdAt time.Time, labels map[string]string) v1.Hash {
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err = mutate.Config(img, v1.Config{Labels: labels})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err = mutate.CreatedAt(img, v1.Time{Time: createdAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := name.ParseReference(reference, name.Insecure)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return digest
+}
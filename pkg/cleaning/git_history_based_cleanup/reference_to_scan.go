@@ -26,6 +26,12 @@ type ReferenceToScan struct {
 type referenceScanOptions struct {
 	scanDepthLimit          int
 	imagesCleanupKeepPolicy config.MetaCleanupKeepPolicyImagesPerReference
+	keepPolicy              *config.MetaCleanupKeepPolicy
+}
+
+// KeepPolicy returns the keep policy the reference was selected by (the last one if several policies match)
+func (r *ReferenceToScan) KeepPolicy() *config.MetaCleanupKeepPolicy {
+	return r.keepPolicy
 }
 
 func (r *ReferenceToScan) String() string {
@@ -266,6 +272,10 @@ func applyCleanupKeepPolicy(refs []*ReferenceToScan, policy *config.MetaCleanupK
 	refs = applyReferencesLimit(refs, policy.References.Limit)
 	applyImagesPerReference(refs, policy.ImagesPerReference)
 
+	for _, ref := range refs {
+		ref.keepPolicy = policy
+	}
+
 	return refs
 }
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5"
@@ -16,8 +17,30 @@ import (
 	"github.com/werf/werf/pkg/util"
 )
 
-func ScanReferencesHistory(ctx context.Context, gitRepository *git.Repository, refs []*ReferenceToScan, expectedStageIDCommitList map[string][]string) ([]string, map[string][]string, error) {
+// ReferenceHit describes a reference that reached a stage ID and commits of the reference history the stage ID was found by
+type ReferenceHit struct {
+	Reference *ReferenceToScan
+	Commits   []string
+}
+
+func (h *ReferenceHit) String() string {
+	res := h.Reference.String()
+
+	if policy := h.Reference.KeepPolicy(); policy != nil {
+		res += fmt.Sprintf(" by policy %s", policy.String())
+	}
+
+	if len(h.Commits) != 0 {
+		res += fmt.Sprintf(" commits %s", strings.Join(h.Commits, ","))
+	}
+
+	return res
+}
+
+// ScanReferencesHistory returns reached stage IDs, hit commits and references that reached each stage ID
+func ScanReferencesHistory(ctx context.Context, gitRepository *git.Repository, refs []*ReferenceToScan, expectedStageIDCommitList map[string][]string) ([]string, map[string][]string, map[string][]*ReferenceHit, error) {
 	var reachedStageIDs []string
+	stageIDReferenceHits := map[string][]*ReferenceHit{}
 	var stopCommitList []string
 	stageIDHitCommitList := map[string][]string{}
 
@@ -44,6 +67,9 @@ func ScanReferencesHistory(ctx context.Context, gitRepository *git.Repository, r
 
 			stopCommitList = util.AddNewStringsToStringArray(stopCommitList, refStopCommitList...)
 			reachedStageIDs = util.AddNewStringsToStringArray(reachedStageIDs, refReachedStageIDs...)
+			for _, stageID := range refReachedStageIDs {
+				stageIDReferenceHits[stageID] = append(stageIDReferenceHits[stageID], &ReferenceHit{Reference: ref, Commits: refStageIDHitCommitList[stageID]})
+			}
 
 			for refStageID, refCommitList := range refStageIDHitCommitList {
 				hitCommitList, ok := stageIDHitCommitList[refStageID]
@@ -57,11 +83,11 @@ func ScanReferencesHistory(ctx context.Context, gitRepository *git.Repository, r
 
 			return nil
 		}); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	}
 
-	return reachedStageIDs, stageIDHitCommitList, nil
+	return reachedStageIDs, stageIDHitCommitList, stageIDReferenceHits, nil
 }
 
 func applyImagesCleanupInPolicy(gitRepository *git.Repository, stageIDCommitList map[string][]string, in *time.Duration) map[string][]string {
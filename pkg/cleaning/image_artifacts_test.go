@@ -0,0 +1,117 @@
+package cleaning
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+)
+
+type imageArtifactsTestRepo struct {
+	repo              string
+	repoStagesStorage *storage.RepoStagesStorage
+
+	keptDigest               v1.Hash
+	keptSignatureDigest      v1.Hash
+	deletedDigest            string
+	deletedSignatureDigest   v1.Hash
+	deletedSBOMDigest        v1.Hash
+	unknownSignatureDigest   v1.Hash
+	deletedManifestsRecorder *[]string
+}
+
+// newImageArtifactsTestRepo pushes the kept stage with the signature, the signature and SBOM of the stage deleted by the cleanup
+// and the signature of the manifest that does not exist in the repo
+func newImageArtifactsTestRepo(t *testing.T) *imageArtifactsTestRepo {
+	// the test registry does not untag the manifest deleted by digest, so the deletion requests are recorded
+	var deletedManifests []string
+	registryHandler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deletedManifests = append(deletedManifests, path.Base(r.URL.Path))
+		}
+		registryHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	repo := strings.TrimPrefix(server.URL, "http://") + "/project"
+	dockerRegistry, err := docker_registry.NewDockerRegistry(repo, docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{InsecureRegistry: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &imageArtifactsTestRepo{
+		repo:                     repo,
+		repoStagesStorage:        storage.NewRepoStagesStorage(repo, nil, dockerRegistry),
+		deletedDigest:            "sha256:" + strings.Repeat("d", 64),
+		deletedManifestsRecorder: &deletedManifests,
+	}
+
+	r.keptDigest = pushTestImage(t, repo+":2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7-1611836746968", time.Now())
+	r.keptSignatureDigest = pushTestImage(t, repo+":"+imageArtifactTag(r.keptDigest.String(), ".sig"), time.Now())
+	r.deletedSignatureDigest = pushTestImage(t, repo+":"+imageArtifactTag(r.deletedDigest, ".sig"), time.Now())
+	r.deletedSBOMDigest = pushTestImage(t, repo+":"+imageArtifactTag(r.deletedDigest, ".spdx"), time.Now())
+	r.unknownSignatureDigest = pushTestImage(t, repo+":"+imageArtifactTag("sha256:"+strings.Repeat("e", 64), ".sig"), time.Now())
+
+	return r
+}
+
+func (r *imageArtifactsTestRepo) deletedManifests() []string {
+	res := append([]string{}, *r.deletedManifestsRecorder...)
+	sort.Strings(res)
+	return res
+}
+
+func imageArtifactTag(digest, suffix string) string {
+	return strings.Replace(digest, ":", "-", 1) + suffix
+}
+
+func sortedDigests(digests ...v1.Hash) []string {
+	var res []string
+	for _, digest := range digests {
+		res = append(res, digest.String())
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestCleanup_ImageArtifactsOfDeletedImages(t *testing.T) {
+	r := newImageArtifactsTestRepo(t)
+
+	m := &cleanupManager{}
+	digestsBefore := map[string]bool{r.keptDigest.String(): true, r.deletedDigest: true}
+	stagesAfter := []*image.StageDescription{{Info: &image.Info{RepoDigest: r.keptDigest.String()}}}
+	if err := m.cleanupImageArtifacts(context.Background(), r.repoStagesStorage, digestsBefore, stagesAfter); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := sortedDigests(r.deletedSignatureDigest, r.deletedSBOMDigest, r.unknownSignatureDigest)
+	if deleted := r.deletedManifests(); strings.Join(deleted, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected manifests %v to be deleted, got %v", expected, deleted)
+	}
+}
+
+func TestPurge_ImageArtifacts(t *testing.T) {
+	r := newImageArtifactsTestRepo(t)
+
+	m := &purgeManager{}
+	if err := m.deleteImageArtifacts(context.Background(), r.repoStagesStorage); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := sortedDigests(r.keptSignatureDigest, r.deletedSignatureDigest, r.deletedSBOMDigest, r.unknownSignatureDigest)
+	if deleted := r.deletedManifests(); strings.Join(deleted, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected manifests %v to be deleted, got %v", expected, deleted)
+	}
+}
@@ -0,0 +1,194 @@
+package cleaning
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/image"
+)
+
+// imageStageDescriptionList returns existing stages of each managed image (stages that image metadata records point to) sorted by creation time, the newest first
+func (m *cleanupManager) imageStageDescriptionList() map[string][]*image.StageDescription {
+	res := map[string][]*image.StageDescription{}
+	for imageName, stageIDCommitList := range m.stageManager.GetImageStageIDCommitListToCleanup() {
+		var stages []*image.StageDescription
+		for stageID := range stageIDCommitList {
+			if stg := m.stageManager.GetStageDescription(stageID); stg != nil {
+				stages = append(stages, stg)
+			}
+		}
+
+		sort.Slice(stages, func(i, j int) bool {
+			return stages[i].GetBuiltAt().After(stages[j].GetBuiltAt())
+		})
+
+		res[imageName] = stages
+	}
+
+	return res
+}
+
+type imagesKeepPolicyStageIDs struct {
+	Policy *config.MetaCleanupKeepPolicy
+	// StageIDs are the stages kept by the policy by image name
+	StageIDs map[string][]string
+}
+
+// initImagesKeepPolicies selects the stages kept by the images keep policies.
+// The stages are linked to the images only by the images metadata, so the policies must be evaluated before any metadata deletion,
+// otherwise the kept stages lose the metadata and are deleted by the next cleanup
+func (m *cleanupManager) initImagesKeepPolicies(policies []*config.MetaCleanupKeepPolicy) {
+	imageStages := m.imageStageDescriptionList()
+
+	for _, policy := range policies {
+		policyStageIDs := &imagesKeepPolicyStageIDs{Policy: policy, StageIDs: map[string][]string{}}
+		for imageName, stages := range imageStages {
+			var matchedStages []*image.StageDescription
+			for _, stg := range stages {
+				if policy.Images.IsImageMatched(imageName, stg.Info.Labels) {
+					matchedStages = append(matchedStages, stg)
+				}
+			}
+
+			for _, stg := range applyImagesLimit(matchedStages, policy.Images.Limit) {
+				policyStageIDs.StageIDs[imageName] = append(policyStageIDs.StageIDs[imageName], stg.Info.Tag)
+			}
+		}
+
+		m.imagesKeepPoliciesStageIDs = append(m.imagesKeepPoliciesStageIDs, policyStageIDs)
+	}
+}
+
+func (m *cleanupManager) isStageKeptByImagesKeepPolicies(stageID string) bool {
+	for _, policyStageIDs := range m.imagesKeepPoliciesStageIDs {
+		for _, stageIDs := range policyStageIDs.StageIDs {
+			for _, keptStageID := range stageIDs {
+				if keptStageID == stageID {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// imagesKeepPoliciesBasedCleanup protects the stages selected by initImagesKeepPolicies, which are not protected by the other policies
+func (m *cleanupManager) imagesKeepPoliciesBasedCleanup(ctx context.Context) error {
+	for _, policyStageIDs := range m.imagesKeepPoliciesStageIDs {
+		logboek.Context(ctx).Default().LogBlock(policyStageIDs.Policy.String()).Do(func() {
+			for imageName, stageIDs := range policyStageIDs.StageIDs {
+				for _, stageID := range stageIDs {
+					if m.stageManager.GetStageProtectionReason(stageID) != "" {
+						continue
+					}
+
+					m.stageManager.MarkStageAsProtected(stageID, protectionReasonImagesKeepPolicy, policyStageIDs.Policy.String())
+					logboek.Context(ctx).Default().LogFDetails("  tag: %s (image %q)\n", stageID, imageName)
+				}
+			}
+		})
+	}
+
+	return nil
+}
+
+// applyImagesPerImageLimit keeps no more than limit protected stages of each image, the oldest ones above the limit lose the protection (except the ones that are used in the Kubernetes or referenced by allow-list providers)
+func (m *cleanupManager) applyImagesPerImageLimit(ctx context.Context, limit int) error {
+	if m.stageIDsOverImagesPerImageLimit == nil {
+		m.stageIDsOverImagesPerImageLimit = map[string]bool{}
+	}
+
+	for imageName, stages := range m.imageStageDescriptionList() {
+		var counter int
+		for _, stg := range stages {
+			stageID := stg.Info.Tag
+
+			reasons := m.stageManager.GetStageProtectionReasons(stageID)
+			if len(reasons) == 0 {
+				continue
+			}
+
+			counter++
+			if counter <= limit || isStageUsedByProtectionReasons(reasons) {
+				continue
+			}
+
+			m.stageManager.UnmarkStageAsProtected(stageID)
+			m.stageIDsOverImagesPerImageLimit[stageID] = true
+			logboek.Context(ctx).Default().LogFDetails("  tag: %s (image %q)\n", stageID, imageName)
+		}
+	}
+
+	return nil
+}
+
+// isStageUsedByProtectionReasons returns true if the stage is used in the Kubernetes or referenced by allow-list providers, such stage is never deleted by limits
+func isStageUsedByProtectionReasons(reasons []string) bool {
+	for _, reason := range reasons {
+		if reason == protectionReasonKubernetes || reason == protectionReasonAllowList {
+			return true
+		}
+	}
+
+	return false
+}
+
+func applyImagesLimit(stages []*image.StageDescription, limit *config.MetaCleanupKeepPolicyLimit) []*image.StageDescription {
+	if limit == nil {
+		return stages
+	}
+
+	var inStages []*image.StageDescription
+	if limit.In != nil {
+		for _, stg := range stages {
+			if stg.GetBuiltAt().After(time.Now().Add(-*limit.In)) {
+				inStages = append(inStages, stg)
+			}
+		}
+	}
+
+	var lastStages []*image.StageDescription
+	if limit.Last != nil {
+		if *limit.Last == -1 || len(stages) < *limit.Last {
+			lastStages = append(lastStages, stages...)
+		} else {
+			lastStages = append(lastStages, stages[:*limit.Last]...)
+		}
+	}
+
+	switch {
+	case limit.In == nil && limit.Last == nil:
+		return stages
+	case limit.In == nil:
+		return lastStages
+	case limit.Last == nil:
+		return inStages
+	case limit.Operator != nil && *limit.Operator == config.OrOperator:
+		return appendUniqueStageDescriptions(lastStages, inStages...)
+	default:
+		var res []*image.StageDescription
+		for _, stg := range lastStages {
+			for _, inStg := range inStages {
+				if stg == inStg {
+					res = append(res, stg)
+					break
+				}
+			}
+		}
+
+		return res
+	}
+}
+
+func (m *cleanupManager) stageDeletionReason(stageID string) string {
+	if m.stageIDsOverImagesPerImageLimit[stageID] {
+		return fmt.Sprintf("%s (%d)", deletionReasonImagesPerImageLimit, *m.ConfigMetaCleanup.ImagesPerImageLimit)
+	}
+
+	return deletionReasonNotProtectedByPolicy
+}
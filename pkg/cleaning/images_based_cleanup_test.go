@@ -0,0 +1,175 @@
+package cleaning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+
+	"github.com/werf/lockgate/pkg/file_locker"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/storage/manager"
+	"github.com/werf/werf/pkg/werf"
+)
+
+type testGitRepo struct {
+	dir string
+}
+
+func (repo *testGitRepo) PlainOpen() (*git.Repository, error) {
+	return git.PlainOpen(repo.dir)
+}
+
+func (repo *testGitRepo) IsCommitExists(_ context.Context, _ string) (bool, error) {
+	return true, nil
+}
+
+func TestCleanup_ImagesKeepPolicyKeepsStageAcrossRuns(t *testing.T) {
+	ctx := context.Background()
+
+	if err := werf.Init(t.TempDir(), t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := image.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	gitDir := t.TempDir()
+	if _, err := git.PlainInit(gitDir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	stagesStorage := storage.NewOCILayoutStagesStorage(t.TempDir(), nil)
+
+	keptStageBuiltAt, deletedStageBuiltAt := time.Now().Add(-24*time.Hour), time.Now().Add(-48*time.Hour)
+	keptStageID := putTestStage(ctx, t, stagesStorage, "a0b12ee0b3b4d1b5d4b0e3ccdb6e7a87a5dbb9b6e3f1c0ad4a5f7c53", keptStageBuiltAt.UnixMilli(), keptStageBuiltAt)
+	deletedStageID := putTestStage(ctx, t, stagesStorage, "b0b12ee0b3b4d1b5d4b0e3ccdb6e7a87a5dbb9b6e3f1c0ad4a5f7c53", deletedStageBuiltAt.UnixMilli(), deletedStageBuiltAt)
+
+	for _, stageID := range []string{keptStageID, deletedStageID} {
+		if err := stagesStorage.PutImageMetadata(ctx, "project", "backend", "commit-"+stageID, stageID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	locker, err := file_locker.NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := 1
+	options := CleanupOptions{
+		ImageNameList: []string{"backend"},
+		LocalGit:      &testGitRepo{dir: gitDir},
+		WithoutKube:   true,
+		ConfigMetaCleanup: config.MetaCleanup{
+			KeepPolicies: []*config.MetaCleanupKeepPolicy{
+				{Images: &config.MetaCleanupKeepPolicyImages{Limit: &config.MetaCleanupKeepPolicyLimit{Last: &last}}},
+			},
+		},
+	}
+
+	for run := 1; run <= 2; run++ {
+		storageManager := manager.NewStorageManager("project", stagesStorage, nil, nil, nil, storage.NewGenericLockManager(locker))
+		if err := Cleanup(ctx, "project", storageManager, options); err != nil {
+			t.Fatalf("cleanup run %d failed: %s", run, err)
+		}
+
+		stageIDs, err := stagesStorage.GetStagesIDs(ctx, "project")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stageIDs) != 1 || stageIDs[0].String() != keptStageID {
+			t.Fatalf("cleanup run %d: expected only stage %s to be kept, got %v", run, keptStageID, stageIDs)
+		}
+
+		if exist, err := stagesStorage.IsImageMetadataExist(ctx, "project", "backend", "commit-"+keptStageID, keptStageID); err != nil {
+			t.Fatal(err)
+		} else if !exist {
+			t.Fatalf("cleanup run %d: expected image metadata of the kept stage %s to be kept", run, keptStageID)
+		}
+	}
+}
+
+func TestCleanup_KeepsReproducibleStagesBuiltWithinLastNHours(t *testing.T) {
+	ctx := context.Background()
+
+	if err := werf.Init(t.TempDir(), t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := image.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	gitDir := t.TempDir()
+	if _, err := git.PlainInit(gitDir, false); err != nil {
+		t.Fatal(err)
+	}
+
+	stagesStorage := storage.NewOCILayoutStagesStorage(t.TempDir(), nil)
+
+	// the reproducible stages are created at SOURCE_DATE_EPOCH, which is the Unix epoch by default
+	sourceDateEpoch := time.Unix(0, 0)
+	keptStageID := putTestStage(ctx, t, stagesStorage, "a0b12ee0b3b4d1b5d4b0e3ccdb6e7a87a5dbb9b6e3f1c0ad4a5f7c53", time.Now().Add(-time.Hour).UnixMilli(), sourceDateEpoch)
+	putTestStage(ctx, t, stagesStorage, "b0b12ee0b3b4d1b5d4b0e3ccdb6e7a87a5dbb9b6e3f1c0ad4a5f7c53", time.Now().Add(-48*time.Hour).UnixMilli(), sourceDateEpoch)
+
+	locker, err := file_locker.NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := CleanupOptions{
+		ImageNameList:     []string{"backend"},
+		LocalGit:          &testGitRepo{dir: gitDir},
+		WithoutKube:       true,
+		ConfigMetaCleanup: config.MetaCleanup{KeepImagesBuiltWithinLastNHours: 2},
+	}
+
+	storageManager := manager.NewStorageManager("project", stagesStorage, nil, nil, nil, storage.NewGenericLockManager(locker))
+	if err := Cleanup(ctx, "project", storageManager, options); err != nil {
+		t.Fatal(err)
+	}
+
+	stageIDs, err := stagesStorage.GetStagesIDs(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stageIDs) != 1 || stageIDs[0].String() != keptStageID {
+		t.Fatalf("expected only the stage %s built within last 2 hours to be kept, got %v", keptStageID, stageIDs)
+	}
+}
+
+func putTestStage(ctx context.Context, t *testing.T, stagesStorage *storage.OCILayoutStagesStorage, digest string, uniqueID int64, createdAt time.Time) string {
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err = mutate.CreatedAt(img, v1.Time{Time: createdAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the stages storage initializes the layout
+	if _, err := stagesStorage.GetStagesIDs(ctx, "project"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := layout.FromPath(stagesStorage.LayoutDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stageID := image.NewStageID(digest, uniqueID).String()
+	if err := p.AppendImage(img, layout.WithAnnotations(map[string]string{"org.opencontainers.image.ref.name": stageID})); err != nil {
+		t.Fatal(err)
+	}
+
+	return stageID
+}
@@ -59,6 +59,28 @@ func (m *purgeManager) run(ctx context.Context) error {
 		return err
 	}
 
+	if err := logboek.Context(ctx).Default().LogProcess("Deleting vulnerability scan metadata").DoError(func() error {
+		stageIDs, err := m.StorageManager.GetStagesStorage().GetVulnerabilityScanMetadataIDs(ctx, m.ProjectName, storage.WithCache())
+		if err != nil {
+			return err
+		}
+
+		return deleteVulnerabilityScanMetadata(ctx, m.ProjectName, m.StorageManager, stageIDs, m.DryRun)
+	}); err != nil {
+		return err
+	}
+
+	if err := logboek.Context(ctx).Default().LogProcess("Deleting digest inputs metadata").DoError(func() error {
+		stageIDs, err := m.StorageManager.GetStagesStorage().GetDigestInputsMetadataIDs(ctx, m.ProjectName, storage.WithCache())
+		if err != nil {
+			return err
+		}
+
+		return deleteDigestInputsMetadata(ctx, m.ProjectName, m.StorageManager, stageIDs, m.DryRun)
+	}); err != nil {
+		return err
+	}
+
 	if err := logboek.Context(ctx).Default().LogProcess("Deleting managed images").DoError(func() error {
 		managedImages, err := m.StorageManager.GetStagesStorage().GetManagedImages(ctx, m.ProjectName, storage.WithCache())
 		if err != nil {
@@ -95,6 +117,14 @@ func (m *purgeManager) run(ctx context.Context) error {
 		return err
 	}
 
+	if repoStagesStorage, isRepo := m.StorageManager.GetStagesStorage().(*storage.RepoStagesStorage); isRepo {
+		if err := logboek.Context(ctx).Default().LogProcess("Deleting Dockerfile layers cache").DoError(func() error {
+			return m.deleteDockerfileLayersCache(ctx, repoStagesStorage)
+		}); err != nil {
+			return err
+		}
+	}
+
 	if m.StorageManager.GetFinalStagesStorage() != nil {
 		if err := logboek.Context(ctx).Default().LogProcess("Deleting final stages").DoError(func() error {
 			finalStages, err := m.StorageManager.GetFinalStageDescriptionList(ctx)
@@ -108,6 +138,41 @@ func (m *purgeManager) run(ctx context.Context) error {
 		}
 	}
 
+	for _, stagesStorage := range []storage.StagesStorage{m.StorageManager.GetStagesStorage(), m.StorageManager.GetFinalStagesStorage()} {
+		if repoStagesStorage, isRepo := stagesStorage.(*storage.RepoStagesStorage); isRepo {
+			if err := logboek.Context(ctx).Default().LogProcess("Deleting SBOMs and signatures in %s", repoStagesStorage.String()).DoError(func() error {
+				return m.deleteImageArtifacts(ctx, repoStagesStorage)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return removeOCILayoutUnusedBlobs(ctx, m.StorageManager, m.DryRun)
+}
+
+func (m *purgeManager) deleteImageArtifacts(ctx context.Context, repoStagesStorage *storage.RepoStagesStorage) error {
+	tags, err := repoStagesStorage.GetImageArtifactTags(ctx, storage.WithCache())
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if !m.DryRun {
+			if err := repoStagesStorage.DeleteImageArtifactTag(ctx, tag); err != nil {
+				if err := handleDeletionError(err); err != nil {
+					return err
+				}
+
+				logboek.Context(ctx).Warn().LogF("WARNING: SBOM or signature tag %s deletion failed: %s\n", tag, err)
+
+				continue
+			}
+		}
+
+		logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", tag)
+	}
+
 	return nil
 }
 
@@ -160,6 +225,46 @@ func (m *purgeManager) deleteImageMetadata(ctx context.Context, imageNameOrID st
 	return deleteImageMetadata(ctx, m.ProjectName, m.StorageManager, imageNameOrID, stageIDCommitList, m.DryRun)
 }
 
+func (m *purgeManager) deleteDockerfileLayersCache(ctx context.Context, repoStagesStorage *storage.RepoStagesStorage) error {
+	tags, err := repoStagesStorage.GetDockerfileLayersCacheTags(ctx, storage.WithCache())
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		info, err := repoStagesStorage.GetDockerfileLayersCacheTagInfo(ctx, tag)
+		if err != nil {
+			return err
+		}
+
+		if info == nil {
+			continue
+		}
+
+		if !m.DryRun {
+			if err := repoStagesStorage.DeleteDockerfileLayersCacheTag(ctx, tag); err != nil {
+				if err := handleDeletionError(err); err != nil {
+					return err
+				}
+
+				logboek.Context(ctx).Warn().LogF("WARNING: Dockerfile layers cache tag %s deletion failed: %s\n", tag, err)
+
+				continue
+			}
+		}
+
+		logboek.Context(ctx).Default().LogFDetails("  tag: %s\n", tag)
+	}
+
+	if !m.DryRun {
+		if err := repoStagesStorage.DeleteDockerfileLayersCacheRecord(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (m *purgeManager) deleteCustomTags(ctx context.Context) error {
 	stageIDCustomTagList, err := stage_manager.GetCustomTagsMetadata(ctx, m.StorageManager)
 	if err != nil {
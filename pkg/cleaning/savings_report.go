@@ -0,0 +1,258 @@
+package cleaning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/logging"
+	"github.com/werf/werf/pkg/storage/manager"
+)
+
+// SavingsReport contains sizes of unique layers (blobs) referenced by stages before and after the cleanup.
+// Layers shared between stages are counted once, so reclaimable bytes are the bytes that the registry garbage collector is able to free.
+type SavingsReport struct {
+	ProjectName string                `json:"projectName"`
+	Repo        string                `json:"repo"`
+	FinalRepo   string                `json:"finalRepo,omitempty"`
+	DryRun      bool                  `json:"dryRun"`
+	Images      []*SavingsReportImage `json:"images"`
+	// Unattributed are the stages that cannot be attributed to any werf image by the images metadata (e.g. intermediate stages or stages of deleted images metadata)
+	Unattributed SavingsReportUnattributed `json:"unattributed"`
+	Total        SavingsReportTotal        `json:"total"`
+}
+
+type SavingsReportImage struct {
+	ImageName     string `json:"imageName"`
+	Stages        int    `json:"stages"`
+	DeletedStages int    `json:"deletedStages"`
+	// SizeBytes is the size of unique layers of the image stages before the cleanup
+	SizeBytes int64 `json:"sizeBytes"`
+	// ReclaimableBytes is the size of layers of the deleted image stages that are not used by any remaining stage
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+}
+
+type SavingsReportUnattributed struct {
+	Stages           int   `json:"stages"`
+	DeletedStages    int   `json:"deletedStages"`
+	SizeBytes        int64 `json:"sizeBytes"`
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+}
+
+type SavingsReportTotal struct {
+	Stages           int   `json:"stages"`
+	DeletedStages    int   `json:"deletedStages"`
+	SizeBytesBefore  int64 `json:"sizeBytesBefore"`
+	SizeBytesAfter   int64 `json:"sizeBytesAfter"`
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+}
+
+func NewSavingsReport(projectName string, storageManager manager.StorageManagerInterface) *SavingsReport {
+	report := &SavingsReport{
+		ProjectName: projectName,
+		Repo:        storageManager.GetStagesStorage().Address(),
+	}
+
+	if finalStagesStorage := storageManager.GetFinalStagesStorage(); finalStagesStorage != nil {
+		report.FinalRepo = finalStagesStorage.Address()
+	}
+
+	return report
+}
+
+// stageLayers returns layer sizes by the repository and digest, the image size is used as a single layer if the stage was fetched without layers info.
+// The same layer in the stages storage and final stages storage repositories is stored and deleted separately, so the repository is the part of the key
+func stageLayers(info *image.Info) map[string]int64 {
+	return stageRepositoryLayers(stageRepository(info), info)
+}
+
+func stageRepositoryLayers(repository string, info *image.Info) map[string]int64 {
+	res := map[string]int64{}
+
+	if info.IsIndex {
+		for _, subInfo := range info.Index {
+			for key, size := range stageRepositoryLayers(repository, subInfo) {
+				res[key] = size
+			}
+		}
+		return res
+	}
+
+	if len(info.Layers) == 0 {
+		if info.Size != 0 {
+			res[fmt.Sprintf("%s@%s", info.Name, info.RepoDigest)] = info.Size
+		}
+		return res
+	}
+
+	for _, l := range info.Layers {
+		res[fmt.Sprintf("%s@%s", repository, l.Digest)] = l.Size
+	}
+
+	return res
+}
+
+func stageRepository(info *image.Info) string {
+	if info.Repository != "" {
+		return info.Repository
+	}
+
+	return strings.TrimSuffix(info.Name, ":"+info.Tag)
+}
+
+func stagesLayers(stages []*image.StageDescription) map[string]int64 {
+	res := map[string]int64{}
+	for _, stg := range stages {
+		for key, size := range stageLayers(stg.Info) {
+			res[key] = size
+		}
+	}
+
+	return res
+}
+
+func layersSize(layers map[string]int64) (res int64) {
+	for _, size := range layers {
+		res += size
+	}
+
+	return res
+}
+
+func reclaimableLayersSize(deletedLayers, remainingLayers map[string]int64) (res int64) {
+	for key, size := range deletedLayers {
+		if _, ok := remainingLayers[key]; !ok {
+			res += size
+		}
+	}
+
+	return res
+}
+
+// fill attributes stagesBefore (stages and final stages) to the images by the stage IDs of imageStages (the stages linked to the images by the images metadata),
+// the rest of stages are reported as unattributed
+func (r *SavingsReport) fill(dryRun bool, imageStages map[string][]*image.StageDescription, stagesBefore, stagesAfter []*image.StageDescription) {
+	r.DryRun = dryRun
+	r.Images = nil
+
+	layersBefore := stagesLayers(stagesBefore)
+	layersAfter := stagesLayers(stagesAfter)
+
+	// stages and final stages have the same IDs, so they are distinguished by the image name with the repo address
+	remainingStageNames := map[string]bool{}
+	for _, stg := range stagesAfter {
+		remainingStageNames[stg.Info.Name] = true
+	}
+
+	deletedStagesFunc := func(stages []*image.StageDescription) []*image.StageDescription {
+		var res []*image.StageDescription
+		for _, stg := range stages {
+			if !remainingStageNames[stg.Info.Name] {
+				res = append(res, stg)
+			}
+		}
+		return res
+	}
+
+	attributedStageIDs := map[string]bool{}
+	for imageName, stages := range imageStages {
+		imageStageIDs := map[string]bool{}
+		for _, stg := range stages {
+			imageStageIDs[stg.StageID.String()] = true
+			attributedStageIDs[stg.StageID.String()] = true
+		}
+
+		var attributedStages []*image.StageDescription
+		for _, stg := range stagesBefore {
+			if imageStageIDs[stg.StageID.String()] {
+				attributedStages = append(attributedStages, stg)
+			}
+		}
+
+		deletedStages := deletedStagesFunc(attributedStages)
+		r.Images = append(r.Images, &SavingsReportImage{
+			ImageName:        imageName,
+			Stages:           len(attributedStages),
+			DeletedStages:    len(deletedStages),
+			SizeBytes:        layersSize(stagesLayers(attributedStages)),
+			ReclaimableBytes: reclaimableLayersSize(stagesLayers(deletedStages), layersAfter),
+		})
+	}
+
+	var unattributedStages []*image.StageDescription
+	for _, stg := range stagesBefore {
+		if !attributedStageIDs[stg.StageID.String()] {
+			unattributedStages = append(unattributedStages, stg)
+		}
+	}
+
+	deletedUnattributedStages := deletedStagesFunc(unattributedStages)
+	r.Unattributed = SavingsReportUnattributed{
+		Stages:           len(unattributedStages),
+		DeletedStages:    len(deletedUnattributedStages),
+		SizeBytes:        layersSize(stagesLayers(unattributedStages)),
+		ReclaimableBytes: reclaimableLayersSize(stagesLayers(deletedUnattributedStages), layersAfter),
+	}
+
+	sort.Slice(r.Images, func(i, j int) bool {
+		return r.Images[i].ImageName < r.Images[j].ImageName
+	})
+
+	r.Total = SavingsReportTotal{
+		Stages:           len(stagesBefore),
+		DeletedStages:    len(stagesBefore) - len(stagesAfter),
+		SizeBytesBefore:  layersSize(layersBefore),
+		SizeBytesAfter:   layersSize(layersAfter),
+		ReclaimableBytes: reclaimableLayersSize(layersBefore, layersAfter),
+	}
+}
+
+func (r *SavingsReport) log(ctx context.Context) {
+	title := "Reclaimable storage"
+	if r.DryRun {
+		title = "Reclaimable storage (dry run)"
+	}
+
+	logboek.Context(ctx).Default().LogBlock(title).Do(func() {
+		for _, reportImage := range r.Images {
+			logboek.Context(ctx).Default().LogFDetails("%s: %s of %s (%d/%d stages)\n", logging.ImageLogName(reportImage.ImageName, false), humanize.Bytes(uint64(reportImage.ReclaimableBytes)), humanize.Bytes(uint64(reportImage.SizeBytes)), reportImage.DeletedStages, reportImage.Stages)
+		}
+
+		if r.Unattributed.Stages != 0 {
+			logboek.Context(ctx).Default().LogFDetails("Not attributed to any image: %s of %s (%d/%d stages)\n", humanize.Bytes(uint64(r.Unattributed.ReclaimableBytes)), humanize.Bytes(uint64(r.Unattributed.SizeBytes)), r.Unattributed.DeletedStages, r.Unattributed.Stages)
+		}
+
+		logboek.Context(ctx).Default().LogF("Total: %s of %s (%d/%d stages)\n", humanize.Bytes(uint64(r.Total.ReclaimableBytes)), humanize.Bytes(uint64(r.Total.SizeBytesBefore)), r.Total.DeletedStages, r.Total.Stages)
+	})
+}
+
+// WriteSavingsReport writes report to the path, YAML format is used for .yaml/.yml extension, JSON format otherwise
+func WriteSavingsReport(report *SavingsReport, path string) error {
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(report)
+	default:
+		data, err = json.MarshalIndent(report, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("unable to marshal savings report: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write savings report %q: %w", path, err)
+	}
+
+	return nil
+}
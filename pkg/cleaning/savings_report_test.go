@@ -0,0 +1,97 @@
+package cleaning
+
+import (
+	"testing"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+func newTestStageDescription(repo, digest string, uniqueID int64, layers ...image.LayerInfo) *image.StageDescription {
+	stageID := image.NewStageID(digest, uniqueID)
+	return &image.StageDescription{
+		StageID: stageID,
+		Info: &image.Info{
+			Name:   repo + ":" + stageID.String(),
+			Tag:    stageID.String(),
+			Layers: layers,
+		},
+	}
+}
+
+func TestSavingsReport_Fill(t *testing.T) {
+	base := image.LayerInfo{Digest: "sha256:base", Size: 100}
+
+	backend := newTestStageDescription("repo", "backend", 1, base, image.LayerInfo{Digest: "sha256:backend", Size: 10})
+	oldBackend := newTestStageDescription("repo", "old-backend", 1, base, image.LayerInfo{Digest: "sha256:old-backend", Size: 20})
+	oldBackendFinal := newTestStageDescription("final-repo", "old-backend", 1, base, image.LayerInfo{Digest: "sha256:old-backend", Size: 20})
+	intermediate := newTestStageDescription("repo", "intermediate", 1, base, image.LayerInfo{Digest: "sha256:intermediate", Size: 40})
+
+	report := &SavingsReport{}
+	report.fill(
+		false,
+		map[string][]*image.StageDescription{"backend": {backend, oldBackend}},
+		[]*image.StageDescription{backend, oldBackend, oldBackendFinal, intermediate},
+		[]*image.StageDescription{backend},
+	)
+
+	if len(report.Images) != 1 {
+		t.Fatalf("expected 1 image, got %d", len(report.Images))
+	}
+
+	// the base layer of the deleted final stage is not used by other stages of the final repo
+	expectedImage := SavingsReportImage{ImageName: "backend", Stages: 3, DeletedStages: 2, SizeBytes: 250, ReclaimableBytes: 140}
+	if *report.Images[0] != expectedImage {
+		t.Errorf("expected image %+v, got %+v", expectedImage, *report.Images[0])
+	}
+
+	expectedUnattributed := SavingsReportUnattributed{Stages: 1, DeletedStages: 1, SizeBytes: 140, ReclaimableBytes: 40}
+	if report.Unattributed != expectedUnattributed {
+		t.Errorf("expected unattributed %+v, got %+v", expectedUnattributed, report.Unattributed)
+	}
+
+	expectedTotal := SavingsReportTotal{Stages: 4, DeletedStages: 3, SizeBytesBefore: 290, SizeBytesAfter: 110, ReclaimableBytes: 180}
+	if report.Total != expectedTotal {
+		t.Errorf("expected total %+v, got %+v", expectedTotal, report.Total)
+	}
+}
+
+func TestSavingsReport_FillSharedLayerInTwoRepos(t *testing.T) {
+	shared := image.LayerInfo{Digest: "sha256:shared", Size: 100}
+
+	deletedStage := newTestStageDescription("repo", "deleted", 1, shared)
+	keptFinalStage := newTestStageDescription("final-repo", "kept", 1, shared)
+	keptStage := newTestStageDescription("repo", "kept", 1, shared)
+	deletedFinalStage := newTestStageDescription("final-repo", "deleted", 1, shared)
+
+	report := &SavingsReport{}
+	report.fill(
+		false,
+		map[string][]*image.StageDescription{"backend": {deletedStage}, "frontend": {keptStage}},
+		[]*image.StageDescription{keptStage, deletedFinalStage},
+		[]*image.StageDescription{keptStage},
+	)
+
+	// the shared layer is kept in the repo, but deleted from the final repo
+	expectedTotal := SavingsReportTotal{Stages: 2, DeletedStages: 1, SizeBytesBefore: 200, SizeBytesAfter: 100, ReclaimableBytes: 100}
+	if report.Total != expectedTotal {
+		t.Errorf("expected total %+v, got %+v", expectedTotal, report.Total)
+	}
+
+	report.fill(
+		false,
+		map[string][]*image.StageDescription{"backend": {deletedStage}, "frontend": {keptStage}},
+		[]*image.StageDescription{deletedStage, keptFinalStage, deletedFinalStage},
+		[]*image.StageDescription{keptFinalStage},
+	)
+
+	// the shared layer is deleted from the repo, but kept in the final repo
+	expectedImage := SavingsReportImage{ImageName: "backend", Stages: 2, DeletedStages: 2, SizeBytes: 200, ReclaimableBytes: 100}
+	if len(report.Images) != 2 || *report.Images[0] != expectedImage {
+		t.Fatalf("expected image %+v, got %+v", expectedImage, report.Images)
+	}
+
+	expectedTotal = SavingsReportTotal{Stages: 3, DeletedStages: 2, SizeBytesBefore: 200, SizeBytesAfter: 100, ReclaimableBytes: 100}
+	if report.Total != expectedTotal {
+		t.Errorf("expected total %+v, got %+v", expectedTotal, report.Total)
+	}
+}
@@ -26,11 +26,13 @@ func NewManager() Manager {
 }
 
 type stage struct {
-	stageID          string
-	isMultiplatform  bool
-	description      *image.StageDescription
-	isProtected      bool
-	protectionReason string
+	stageID           string
+	isMultiplatform   bool
+	description       *image.StageDescription
+	isProtected       bool
+	protectionReason  string
+	protectionReasons []string
+	protectionDetails []string
 }
 
 func newStage(stageID string, description *image.StageDescription) *stage {
@@ -186,14 +188,44 @@ func (m *Manager) GetFinalStageIDList() []string {
 	return result
 }
 
-func (m *Manager) MarkStageAsProtected(stageID, reason string) {
-	m.stages[stageID].isProtected = true
-	m.stages[stageID].protectionReason = reason
+// MarkStageAsProtected method marks the stage as protected, the reason of the last protection is kept as the protection reason if the stage is protected several times, all reasons are available with GetStageProtectionReasons
+func (m *Manager) MarkStageAsProtected(stageID, reason string, details ...string) {
+	m.stages[stageID].markAsProtected(reason, details...)
 }
 
-func (m *Manager) MarkFinalStageAsProtected(stageID, reason string) {
-	m.finalStages[stageID].isProtected = true
-	m.finalStages[stageID].protectionReason = reason
+func (m *Manager) MarkFinalStageAsProtected(stageID, reason string, details ...string) {
+	m.finalStages[stageID].markAsProtected(reason, details...)
+}
+
+func (s *stage) markAsProtected(reason string, details ...string) {
+	s.isProtected = true
+	s.protectionReason = reason
+	s.protectionDetails = append(s.protectionDetails, details...)
+
+	for _, r := range s.protectionReasons {
+		if r == reason {
+			return
+		}
+	}
+	s.protectionReasons = append(s.protectionReasons, reason)
+}
+
+// GetStageProtectionDetails method returns details that were passed when the stage was marked as protected (git references, Kubernetes resources, etc.)
+func (m *Manager) GetStageProtectionDetails(stageID string) []string {
+	if stage, ok := m.stages[stageID]; ok {
+		return stage.protectionDetails
+	}
+
+	return nil
+}
+
+// GetFinalStageProtectionDetails method is the same as GetStageProtectionDetails but for final stages
+func (m *Manager) GetFinalStageProtectionDetails(stageID string) []string {
+	if stage, ok := m.finalStages[stageID]; ok {
+		return stage.protectionDetails
+	}
+
+	return nil
 }
 
 // GetImageStageIDCommitListToCleanup method returns existing stage IDs and related existing commits (for each managed image)
@@ -357,3 +389,45 @@ func (m *Manager) GetCustomTagsMetadata() map[string][]string {
 func (m *Manager) ForgetCustomTagsByStageID(stageID string) {
 	delete(m.stageIDCustomTagList, stageID)
 }
+
+func (m *Manager) GetStageDescription(stageID string) *image.StageDescription {
+	if stage, ok := m.stages[stageID]; ok {
+		return stage.description
+	}
+
+	return nil
+}
+
+// GetStageProtectionReason method returns the reason passed to MarkStageAsProtected or an empty string if the stage is not protected
+func (m *Manager) GetStageProtectionReason(stageID string) string {
+	if stage, ok := m.stages[stageID]; ok && stage.isProtected {
+		return stage.protectionReason
+	}
+
+	return ""
+}
+
+// GetStageProtectionReasons method returns all reasons passed to MarkStageAsProtected in order or nil if the stage is not protected
+func (m *Manager) GetStageProtectionReasons(stageID string) []string {
+	if stage, ok := m.stages[stageID]; ok && stage.isProtected {
+		return stage.protectionReasons
+	}
+
+	return nil
+}
+
+// GetFinalStageProtectionReason method is the same as GetStageProtectionReason but for final stages
+func (m *Manager) GetFinalStageProtectionReason(stageID string) string {
+	if stage, ok := m.finalStages[stageID]; ok && stage.isProtected {
+		return stage.protectionReason
+	}
+
+	return ""
+}
+
+func (m *Manager) UnmarkStageAsProtected(stageID string) {
+	m.stages[stageID].isProtected = false
+	m.stages[stageID].protectionReason = ""
+	m.stages[stageID].protectionReasons = nil
+	m.stages[stageID].protectionDetails = nil
+}
@@ -0,0 +1,34 @@
+package stage_manager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestManager_MarkStageAsProtected(t *testing.T) {
+	m := NewManager()
+	m.stages["stage"] = newStage("stage", nil)
+
+	m.MarkStageAsProtected("stage", "used in the Kubernetes", "deploy/app")
+	m.MarkStageAsProtected("stage", "found in the git history", "branch main")
+	m.MarkStageAsProtected("stage", "used in the Kubernetes", "deploy/worker")
+
+	if reason := m.GetStageProtectionReason("stage"); reason != "used in the Kubernetes" {
+		t.Errorf("expected the last protection reason, got %q", reason)
+	}
+
+	expectedReasons := []string{"used in the Kubernetes", "found in the git history"}
+	if reasons := m.GetStageProtectionReasons("stage"); !reflect.DeepEqual(reasons, expectedReasons) {
+		t.Errorf("expected protection reasons %v, got %v", expectedReasons, reasons)
+	}
+
+	expectedDetails := []string{"deploy/app", "branch main", "deploy/worker"}
+	if details := m.GetStageProtectionDetails("stage"); !reflect.DeepEqual(details, expectedDetails) {
+		t.Errorf("expected protection details %v, got %v", expectedDetails, details)
+	}
+
+	m.UnmarkStageAsProtected("stage")
+	if reasons := m.GetStageProtectionReasons("stage"); reasons != nil {
+		t.Errorf("expected no protection reasons after unmarking, got %v", reasons)
+	}
+}
@@ -18,6 +18,7 @@ type ImageFromDockerfile struct {
 	Network         string
 	SSH             string
 	Dependencies    []*Dependency
+	Secrets         []*Secret
 	Staged          bool
 	Platform        []string
 
@@ -50,6 +51,10 @@ func (c *ImageFromDockerfile) validate(giterminismManager giterminism_manager.In
 		}
 	}
 
+	if err := validateSecretsIDs(c.Secrets, c.raw.doc); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -2,4 +2,6 @@ package config
 
 type MetaBuild struct {
 	Platform []string
+	// Reproducible enables normalization of the stages timestamps to build byte-identical stages from the same inputs
+	Reproducible bool
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -13,14 +14,44 @@ type MetaCleanup struct {
 	DisableBuiltWithinLastNHoursPolicy bool
 	KeepImagesBuiltWithinLastNHours    uint64
 	KeepPolicies                       []*MetaCleanupKeepPolicy
+	ImagesPerImageLimit                *int
+}
+
+// GetReferencesKeepPolicies returns git history-based keep policies
+func (c MetaCleanup) GetReferencesKeepPolicies() []*MetaCleanupKeepPolicy {
+	var res []*MetaCleanupKeepPolicy
+	for _, policy := range c.KeepPolicies {
+		if policy.Images == nil {
+			res = append(res, policy)
+		}
+	}
+
+	return res
+}
+
+// GetImagesKeepPolicies returns keep policies that select images regardless of the git history
+func (c MetaCleanup) GetImagesKeepPolicies() []*MetaCleanupKeepPolicy {
+	var res []*MetaCleanupKeepPolicy
+	for _, policy := range c.KeepPolicies {
+		if policy.Images != nil {
+			res = append(res, policy)
+		}
+	}
+
+	return res
 }
 
 type MetaCleanupKeepPolicy struct {
 	References         MetaCleanupKeepPolicyReferences
 	ImagesPerReference MetaCleanupKeepPolicyImagesPerReference
+	Images             *MetaCleanupKeepPolicyImages
 }
 
 func (p *MetaCleanupKeepPolicy) String() string {
+	if p.Images != nil {
+		return fmt.Sprintf("images={%s}", p.Images.String())
+	}
+
 	var parts []string
 	parts = append(parts, fmt.Sprintf("references={%s}", p.References.String()))
 
@@ -54,6 +85,52 @@ func (c *MetaCleanupKeepPolicyReferences) String() string {
 	return strings.Join(parts, " ")
 }
 
+type MetaCleanupKeepPolicyImages struct {
+	ImageNameRegexp *regexp.Regexp
+	LabelsRegexps   map[string]*regexp.Regexp
+	Limit           *MetaCleanupKeepPolicyLimit
+}
+
+func (c *MetaCleanupKeepPolicyImages) String() string {
+	var parts []string
+
+	if c.ImageNameRegexp != nil {
+		parts = append(parts, fmt.Sprintf("imageName=%s", c.ImageNameRegexp.String()))
+	}
+
+	if len(c.LabelsRegexps) != 0 {
+		var labelsParts []string
+		for name, regex := range c.LabelsRegexps {
+			labelsParts = append(labelsParts, fmt.Sprintf("%s=%s", name, regex.String()))
+		}
+		sort.Strings(labelsParts)
+
+		parts = append(parts, fmt.Sprintf("labels={%s}", strings.Join(labelsParts, " ")))
+	}
+
+	if c.Limit != nil {
+		parts = append(parts, fmt.Sprintf("limit={%s}", c.Limit.String()))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// IsImageMatched checks the werf image name and labels of the image against the policy selectors
+func (c *MetaCleanupKeepPolicyImages) IsImageMatched(imageName string, labels map[string]string) bool {
+	if c.ImageNameRegexp != nil && !c.ImageNameRegexp.MatchString(imageName) {
+		return false
+	}
+
+	for name, regex := range c.LabelsRegexps {
+		value, ok := labels[name]
+		if !ok || !regex.MatchString(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
 type MetaCleanupKeepPolicyImagesPerReference struct {
 	MetaCleanupKeepPolicyLimit
 }
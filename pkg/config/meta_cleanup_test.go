@@ -0,0 +1,69 @@
+package config
+
+import (
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type imagesKeepPolicyEntry struct {
+	imageNameRegexp string
+	labelsRegexps   map[string]string
+	imageName       string
+	labels          map[string]string
+	expectedMatch   bool
+}
+
+var _ = DescribeTable("matching images keep policy", func(e imagesKeepPolicyEntry) {
+	policy := &MetaCleanupKeepPolicyImages{}
+	if e.imageNameRegexp != "" {
+		policy.ImageNameRegexp = regexp.MustCompile(e.imageNameRegexp)
+	}
+	if len(e.labelsRegexps) != 0 {
+		policy.LabelsRegexps = map[string]*regexp.Regexp{}
+		for name, value := range e.labelsRegexps {
+			policy.LabelsRegexps[name] = regexp.MustCompile(value)
+		}
+	}
+
+	Ω(policy.IsImageMatched(e.imageName, e.labels)).Should(Equal(e.expectedMatch))
+},
+	Entry("empty policy", imagesKeepPolicyEntry{
+		imageName:     "backend",
+		expectedMatch: true,
+	}),
+	Entry("image name matched", imagesKeepPolicyEntry{
+		imageNameRegexp: "^backend$",
+		imageName:       "backend",
+		expectedMatch:   true,
+	}),
+	Entry("image name not matched", imagesKeepPolicyEntry{
+		imageNameRegexp: "^backend$",
+		imageName:       "frontend",
+		expectedMatch:   false,
+	}),
+	Entry("labels matched", imagesKeepPolicyEntry{
+		labelsRegexps: map[string]string{"release": "^v[0-9]+"},
+		imageName:     "backend",
+		labels:        map[string]string{"release": "v1.2.0", "other": "value"},
+		expectedMatch: true,
+	}),
+	Entry("label value not matched", imagesKeepPolicyEntry{
+		labelsRegexps: map[string]string{"release": "^v[0-9]+"},
+		imageName:     "backend",
+		labels:        map[string]string{"release": "dev"},
+		expectedMatch: false,
+	}),
+	Entry("label missing", imagesKeepPolicyEntry{
+		labelsRegexps: map[string]string{"release": ".*"},
+		imageName:     "backend",
+		expectedMatch: false,
+	}),
+	Entry("image name and labels matched", imagesKeepPolicyEntry{
+		imageNameRegexp: "^back",
+		labelsRegexps:   map[string]string{"release": "true"},
+		imageName:       "backend",
+		labels:          map[string]string{"release": "true"},
+		expectedMatch:   true,
+	}))
@@ -19,6 +19,7 @@ type rawImageFromDockerfile struct {
 	Network         string                 `yaml:"network,omitempty"`
 	SSH             string                 `yaml:"ssh,omitempty"`
 	RawDependencies []*rawDependency       `yaml:"dependencies,omitempty"`
+	RawSecrets      []*rawSecret           `yaml:"secrets,omitempty"`
 	Staged          bool                   `yaml:"staged,omitempty"`
 	Platform        []string               `yaml:"platform,omitempty"`
 
@@ -128,6 +129,15 @@ func (c *rawImageFromDockerfile) toImageFromDockerfileDirective(giterminismManag
 		image.Dependencies = append(image.Dependencies, dependencyDirective)
 	}
 
+	for _, rawSecret := range c.RawSecrets {
+		secretDirective, err := rawSecret.toDirective(giterminismManager)
+		if err != nil {
+			return nil, err
+		}
+
+		image.Secrets = append(image.Secrets, secretDirective)
+	}
+
 	image.Staged = c.Staged || util.GetBoolEnvironmentDefaultFalse("WERF_FORCE_STAGED_DOCKERFILE")
 	image.Platform = append([]string{}, c.Platform...)
 	image.raw = c
@@ -1,7 +1,8 @@
 package config
 
 type rawMetaBuild struct {
-	Platform []string `yaml:"platform,omitempty"`
+	Platform     []string `yaml:"platform,omitempty"`
+	Reproducible bool     `yaml:"reproducible,omitempty"`
 
 	rawMeta *rawMeta
 
@@ -31,5 +32,6 @@ func (c *rawMetaBuild) UnmarshalYAML(unmarshal func(interface{}) error) error {
 func (c *rawMetaBuild) toMetaBuild() MetaBuild {
 	metaBuild := MetaBuild{}
 	metaBuild.Platform = c.Platform
+	metaBuild.Reproducible = c.Reproducible
 	return metaBuild
 }
@@ -13,6 +13,7 @@ type rawMetaCleanup struct {
 	DisableBuiltWithinLastNHoursPolicy bool                        `yaml:"disableBuiltWithinLastNHoursPolicy,omitempty"`
 	KeepPolicies                       []*rawMetaCleanupKeepPolicy `yaml:"keepPolicies,omitempty"`
 	KeepImagesBuiltWithinLastNHours    *uint64                     `yaml:"keepImagesBuiltWithinLastNHours,omitempty"`
+	ImagesPerImageLimit                *int                        `yaml:"imagesPerImageLimit,omitempty"`
 
 	rawMeta               *rawMeta
 	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
@@ -21,6 +22,7 @@ type rawMetaCleanup struct {
 type rawMetaCleanupKeepPolicy struct {
 	References         *rawMetaCleanupKeepPolicyReferences         `yaml:"references,omitempty"`
 	ImagesPerReference *rawMetaCleanupKeepPolicyImagesPerReference `yaml:"imagesPerReference,omitempty"`
+	Images             *rawMetaCleanupKeepPolicyImages             `yaml:"images,omitempty"`
 
 	rawMetaCleanup        *rawMetaCleanup
 	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
@@ -38,6 +40,18 @@ type rawMetaCleanupKeepPolicyReferences struct {
 	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
 }
 
+type rawMetaCleanupKeepPolicyImages struct {
+	ImageName string                                   `yaml:"imageName,omitempty"`
+	Labels    map[string]string                        `yaml:"labels,omitempty"`
+	Limit     *rawMetaCleanupKeepPolicyReferencesLimit `yaml:"limit,omitempty"`
+
+	ImageNameRegexp *regexp.Regexp            `yaml:"-"`
+	LabelsRegexps   map[string]*regexp.Regexp `yaml:"-"`
+
+	rawMetaCleanup        *rawMetaCleanup
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
 type rawMetaCleanupKeepPolicyImagesPerReference rawMetaCleanupKeepPolicyReferencesLimit
 
 type rawMetaCleanupKeepPolicyReferencesLimit struct {
@@ -66,6 +80,10 @@ func (c *rawMetaCleanup) UnmarshalYAML(unmarshal func(interface{}) error) error
 		return err
 	}
 
+	if c.ImagesPerImageLimit != nil && *c.ImagesPerImageLimit < 1 {
+		return newDetailedConfigError(fmt.Sprintf("invalid value %d for `imagesPerImageLimit: int`, it must be positive!", *c.ImagesPerImageLimit), c, c.rawMeta.doc)
+	}
+
 	if c.KeepImagesBuiltWithinLastNHours == nil {
 		defaultKeepImagesBuiltWithinLastNHours := uint64(2)
 		c.KeepImagesBuiltWithinLastNHours = &defaultKeepImagesBuiltWithinLastNHours
@@ -91,8 +109,12 @@ func (c *rawMetaCleanupKeepPolicy) UnmarshalYAML(unmarshal func(interface{}) err
 		return err
 	}
 
-	if c.References == nil {
-		return newDetailedConfigError("cleanup keep policy must have references section!", c, c.rawMetaCleanup.rawMeta.doc)
+	if c.References == nil && c.Images == nil {
+		return newDetailedConfigError("cleanup keep policy must have references or images section!", c, c.rawMetaCleanup.rawMeta.doc)
+	} else if c.References != nil && c.Images != nil {
+		return newDetailedConfigError("specify only references or images section for cleanup keep policy!", c, c.rawMetaCleanup.rawMeta.doc)
+	} else if c.Images != nil && c.ImagesPerReference != nil {
+		return newDetailedConfigError("imagesPerReference section cannot be used with images section in cleanup keep policy!", c, c.rawMetaCleanup.rawMeta.doc)
 	}
 
 	return nil
@@ -140,8 +162,52 @@ func (c *rawMetaCleanupKeepPolicyReferences) UnmarshalYAML(unmarshal func(interf
 	return nil
 }
 
+func (c *rawMetaCleanupKeepPolicyImages) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMetaCleanupKeepPolicy); ok {
+		c.rawMetaCleanup = parent.rawMetaCleanup
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaCleanupKeepPolicyImages
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawMetaCleanup.rawMeta.doc); err != nil {
+		return err
+	}
+
+	if c.ImageName != "" {
+		regex, err := processCleanupRegexpString(c.ImageName)
+		if err != nil {
+			return newDetailedConfigError(fmt.Sprintf("invalid value %q for `imageName: string|REGEX`!", c.ImageName), c, c.rawMetaCleanup.rawMeta.doc)
+		}
+
+		c.ImageNameRegexp = regex
+	}
+
+	for name, value := range c.Labels {
+		regex, err := processCleanupRegexpString(value)
+		if err != nil {
+			return newDetailedConfigError(fmt.Sprintf("invalid value %q for label %q `labels: {name: string|REGEX}`!", value, name), c, c.rawMetaCleanup.rawMeta.doc)
+		}
+
+		if c.LabelsRegexps == nil {
+			c.LabelsRegexps = map[string]*regexp.Regexp{}
+		}
+		c.LabelsRegexps[name] = regex
+	}
+
+	return nil
+}
+
 func (c *rawMetaCleanupKeepPolicyReferencesLimit) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	if parent, ok := parentStack.Peek().(*rawMetaCleanupKeepPolicyReferences); ok {
+	switch parent := parentStack.Peek().(type) {
+	case *rawMetaCleanupKeepPolicyReferences:
+		c.rawMetaCleanup = parent.rawMetaCleanup
+	case *rawMetaCleanupKeepPolicyImages:
 		c.rawMetaCleanup = parent.rawMetaCleanup
 	}
 
@@ -170,6 +236,16 @@ func (c *rawMetaCleanupKeepPolicyReferencesLimit) UnmarshalYAML(unmarshal func(i
 }
 
 func (c *rawMetaCleanupKeepPolicyReferences) processRegexpString(name, configValue string) (*regexp.Regexp, error) {
+	regex, err := processCleanupRegexpString(configValue)
+	if err != nil {
+		return nil, newDetailedConfigError(fmt.Sprintf("invalid value %q for `%s: string|REGEX`!", configValue, name), c, c.rawMetaCleanup.rawMeta.doc)
+	}
+
+	return regex, nil
+}
+
+// processCleanupRegexpString compiles the value as /REGEXP/ or as an exact string match otherwise
+func processCleanupRegexpString(configValue string) (*regexp.Regexp, error) {
 	var value string
 	if strings.HasPrefix(configValue, "/") && strings.HasSuffix(configValue, "/") {
 		value = strings.TrimPrefix(configValue, "/")
@@ -178,13 +254,7 @@ func (c *rawMetaCleanupKeepPolicyReferences) processRegexpString(name, configVal
 		value = regexp.QuoteMeta(configValue)
 	}
 
-	expr := fmt.Sprintf("^%s$", value)
-	regex, err := regexp.Compile(expr)
-	if err != nil {
-		return nil, newDetailedConfigError(fmt.Sprintf("invalid value %q for `%s: string|REGEX`!", configValue, name), c, c.rawMetaCleanup.rawMeta.doc)
-	}
-
-	return regex, nil
+	return regexp.Compile(fmt.Sprintf("^%s$", value))
 }
 
 func (c *rawMetaCleanup) toMetaCleanup() MetaCleanup {
@@ -199,6 +269,7 @@ func (c *rawMetaCleanup) toMetaCleanup() MetaCleanup {
 	}
 
 	metaCleanup.KeepImagesBuiltWithinLastNHours = *c.KeepImagesBuiltWithinLastNHours
+	metaCleanup.ImagesPerImageLimit = c.ImagesPerImageLimit
 
 	return metaCleanup
 }
@@ -214,9 +285,25 @@ func (c *rawMetaCleanupKeepPolicy) toMetaCleanupKeepPolicy() *MetaCleanupKeepPol
 		policy.ImagesPerReference = c.ImagesPerReference.toMetaCleanupKeepPolicyImagesPerReference()
 	}
 
+	if c.Images != nil {
+		policy.Images = c.Images.toMetaCleanupKeepPolicyImages()
+	}
+
 	return policy
 }
 
+func (c *rawMetaCleanupKeepPolicyImages) toMetaCleanupKeepPolicyImages() *MetaCleanupKeepPolicyImages {
+	images := &MetaCleanupKeepPolicyImages{}
+	images.ImageNameRegexp = c.ImageNameRegexp
+	images.LabelsRegexps = c.LabelsRegexps
+
+	if c.Limit != nil {
+		images.Limit = c.Limit.toMetaCleanupKeepPolicyLimit()
+	}
+
+	return images
+}
+
 func (c *rawMetaCleanupKeepPolicyReferences) toMetaCleanupKeepPolicyReferences() MetaCleanupKeepPolicyReferences {
 	references := MetaCleanupKeepPolicyReferences{}
 	references.BranchRegexp = c.BranchRegexp
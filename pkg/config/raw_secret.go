@@ -0,0 +1,62 @@
+package config
+
+import "github.com/werf/werf/pkg/giterminism_manager"
+
+type rawSecret struct {
+	ID             string `yaml:"id,omitempty"`
+	Env            string `yaml:"env,omitempty"`
+	Src            string `yaml:"src,omitempty"`
+	EncryptedValue string `yaml:"encryptedValue,omitempty"`
+
+	rawStapelImage         *rawStapelImage         `yaml:"-"` // possible parent
+	rawImageFromDockerfile *rawImageFromDockerfile `yaml:"-"` // possible parent
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (s *rawSecret) doc() *doc {
+	switch {
+	case s.rawStapelImage != nil:
+		return s.rawStapelImage.doc
+	case s.rawImageFromDockerfile != nil:
+		return s.rawImageFromDockerfile.doc
+	}
+
+	return nil
+}
+
+func (s *rawSecret) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	switch parent := parentStack.Peek().(type) {
+	case *rawStapelImage:
+		s.rawStapelImage = parent
+	case *rawImageFromDockerfile:
+		s.rawImageFromDockerfile = parent
+	}
+
+	type plain rawSecret
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(s.UnsupportedAttributes, s, s.doc()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *rawSecret) toDirective(giterminismManager giterminism_manager.Interface) (*Secret, error) {
+	secret := &Secret{
+		ID:             s.ID,
+		Env:            s.Env,
+		Src:            s.Src,
+		EncryptedValue: s.EncryptedValue,
+		raw:            s,
+	}
+
+	if err := secret.validate(giterminismManager); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
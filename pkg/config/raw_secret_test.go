@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/werf/werf/pkg/util"
+)
+
+var _ = Describe("rawSecret", func() {
+	var giterminismManager *GiterminismManagerStub
+
+	BeforeEach(func() {
+		parentStack = util.NewStack()
+		giterminismManager = NewGiterminismManagerStub(NewLocalGitRepoStub("9d8059842b6fde712c58315ca0ab4713d90761c0"))
+	})
+
+	toSecrets := func(yamlMap map[string]interface{}) ([]*Secret, error) {
+		rawYaml, err := yaml.Marshal(yamlMap)
+		Expect(err).To(Succeed())
+
+		doc := &doc{Content: rawYaml}
+		if _, ok := yamlMap["dockerfile"]; ok {
+			rawDockerfileImage := &rawImageFromDockerfile{doc: doc}
+			Expect(yaml.UnmarshalStrict(doc.Content, rawDockerfileImage)).To(Succeed())
+
+			dockerfileImage, err := rawDockerfileImage.toImageFromDockerfileDirective(giterminismManager, "image1")
+			if err != nil {
+				return nil, err
+			}
+			return dockerfileImage.Secrets, nil
+		}
+
+		rawStapelImage := &rawStapelImage{doc: doc}
+		Expect(yaml.UnmarshalStrict(doc.Content, rawStapelImage)).To(Succeed())
+
+		stapelImage, err := rawStapelImage.toStapelImageDirective(giterminismManager, "image1")
+		if err != nil {
+			return nil, err
+		}
+		return stapelImage.Secrets, nil
+	}
+
+	DescribeTable("unmarshal and convert to directive succeed and produce expected Secrets",
+		func(yamlMap map[string]interface{}, expected []*Secret) {
+			secrets, err := toSecrets(yamlMap)
+			Expect(err).To(Succeed())
+			Expect(secrets).To(HaveLen(len(expected)))
+
+			for i, expectedSecret := range expected {
+				Expect(secrets[i].ID).To(Equal(expectedSecret.ID))
+				Expect(secrets[i].Env).To(Equal(expectedSecret.Env))
+				Expect(secrets[i].Src).To(Equal(expectedSecret.Src))
+				Expect(secrets[i].EncryptedValue).To(Equal(expectedSecret.EncryptedValue))
+			}
+		},
+		Entry(
+			"stapel image with all secrets sources",
+			map[string]interface{}{
+				"image": "image1",
+				"from":  "alpine",
+				"secrets": []map[string]interface{}{
+					{"id": "npm_token", "env": "NPM_TOKEN"},
+					{"id": "deploy-key", "src": "~/.ssh/id_rsa"},
+					{"id": "pip.conf", "encryptedValue": "1000a1b2c3"},
+				},
+			},
+			[]*Secret{
+				{ID: "npm_token", Env: "NPM_TOKEN"},
+				{ID: "deploy-key", Src: "~/.ssh/id_rsa"},
+				{ID: "pip.conf", EncryptedValue: "1000a1b2c3"},
+			},
+		),
+		Entry(
+			"dockerfile image with secret",
+			map[string]interface{}{
+				"image":      "image1",
+				"dockerfile": "Dockerfile",
+				"secrets": []map[string]interface{}{
+					{"id": "npm_token", "env": "NPM_TOKEN"},
+				},
+			},
+			[]*Secret{
+				{ID: "npm_token", Env: "NPM_TOKEN"},
+			},
+		),
+	)
+
+	DescribeTable("unmarshal and convert to directive fail with configError",
+		func(yamlMap map[string]interface{}) {
+			var errConf *configError
+			_, err := toSecrets(yamlMap)
+			Expect(errors.As(err, &errConf)).To(BeTrue())
+		},
+		Entry(
+			"with missing id",
+			map[string]interface{}{
+				"image":   "image1",
+				"from":    "alpine",
+				"secrets": []map[string]interface{}{{"env": "NPM_TOKEN"}},
+			},
+		),
+		Entry(
+			"with invalid id",
+			map[string]interface{}{
+				"image":   "image1",
+				"from":    "alpine",
+				"secrets": []map[string]interface{}{{"id": "npm,token", "env": "NPM_TOKEN"}},
+			},
+		),
+		Entry(
+			"with missing source",
+			map[string]interface{}{
+				"image":      "image1",
+				"dockerfile": "Dockerfile",
+				"secrets":    []map[string]interface{}{{"id": "npm_token"}},
+			},
+		),
+		Entry(
+			"with several sources",
+			map[string]interface{}{
+				"image":      "image1",
+				"dockerfile": "Dockerfile",
+				"secrets":    []map[string]interface{}{{"id": "npm_token", "env": "NPM_TOKEN", "src": "npm_token"}},
+			},
+		),
+		Entry(
+			"with duplicated ids",
+			map[string]interface{}{
+				"image": "image1",
+				"from":  "alpine",
+				"secrets": []map[string]interface{}{
+					{"id": "npm_token", "env": "NPM_TOKEN"},
+					{"id": "npm_token", "src": "npm_token"},
+				},
+			},
+		),
+	)
+})
@@ -7,21 +7,24 @@ import (
 )
 
 type rawStapelImage struct {
-	Images           []string         `yaml:"-"`
-	Artifact         string           `yaml:"artifact,omitempty"`
-	From             string           `yaml:"from,omitempty"`
-	FromLatest       bool             `yaml:"fromLatest,omitempty"`
-	FromCacheVersion string           `yaml:"fromCacheVersion,omitempty"`
-	FromImage        string           `yaml:"fromImage,omitempty"`
-	FromArtifact     string           `yaml:"fromArtifact,omitempty"`
-	RawGit           []*rawGit        `yaml:"git,omitempty"`
-	RawShell         *rawShell        `yaml:"shell,omitempty"`
-	RawAnsible       *rawAnsible      `yaml:"ansible,omitempty"`
-	RawMount         []*rawMount      `yaml:"mount,omitempty"`
-	RawDocker        *rawDocker       `yaml:"docker,omitempty"`
-	RawImport        []*rawImport     `yaml:"import,omitempty"`
-	RawDependencies  []*rawDependency `yaml:"dependencies,omitempty"`
-	Platform         []string         `yaml:"platform,omitempty"`
+	Images             []string         `yaml:"-"`
+	Artifact           string           `yaml:"artifact,omitempty"`
+	From               string           `yaml:"from,omitempty"`
+	FromLatest         bool             `yaml:"fromLatest,omitempty"`
+	FromCacheVersion   string           `yaml:"fromCacheVersion,omitempty"`
+	FromImage          string           `yaml:"fromImage,omitempty"`
+	FromArtifact       string           `yaml:"fromArtifact,omitempty"`
+	RawGit             []*rawGit        `yaml:"git,omitempty"`
+	RawShell           *rawShell        `yaml:"shell,omitempty"`
+	RawAnsible         *rawAnsible      `yaml:"ansible,omitempty"`
+	RawMount           []*rawMount      `yaml:"mount,omitempty"`
+	RawDocker          *rawDocker       `yaml:"docker,omitempty"`
+	RawImport          []*rawImport     `yaml:"import,omitempty"`
+	RawDependencies    []*rawDependency `yaml:"dependencies,omitempty"`
+	RawSecrets         []*rawSecret     `yaml:"secrets,omitempty"`
+	Platform           []string         `yaml:"platform,omitempty"`
+	RunOnBuildPlatform interface{}      `yaml:"runOnBuildPlatform,omitempty"`
+	Squash             bool             `yaml:"squash,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -143,6 +146,8 @@ func (c *rawStapelImage) toStapelImageDirective(giterminismManager giterminism_m
 		}
 	}
 
+	image.Squash = c.Squash
+
 	if err := c.validateStapelImageDirective(image); err != nil {
 		return nil, err
 	}
@@ -151,6 +156,10 @@ func (c *rawStapelImage) toStapelImageDirective(giterminismManager giterminism_m
 }
 
 func (c *rawStapelImage) validateStapelImageDirective(image *StapelImage) (err error) {
+	if image.RunOnBuildPlatform {
+		return newDetailedConfigError("`runOnBuildPlatform: true` is supported only for artifact, select the stages to run on the build platform instead!", nil, c.doc)
+	}
+
 	if err := image.validate(); err != nil {
 		return err
 	}
@@ -197,6 +206,10 @@ func (c *rawStapelImage) validateStapelImageArtifactDirective(imageArtifact *Sta
 		return newDetailedConfigError("`docker` section is not supported for artifact!", nil, c.doc)
 	}
 
+	if c.Squash {
+		return newDetailedConfigError("`squash: true` is not supported for artifact!", nil, c.doc)
+	}
+
 	if err := imageArtifact.validate(); err != nil {
 		return err
 	}
@@ -216,6 +229,10 @@ func (c *rawStapelImage) toStapelImageBaseDirective(giterminismManager gitermini
 	imageBase.FromCacheVersion = c.FromCacheVersion
 	imageBase.Platform = append([]string{}, c.Platform...)
 
+	if imageBase.RunOnBuildPlatform, imageBase.RunOnBuildPlatformStages, err = c.toRunOnBuildPlatformDirective(); err != nil {
+		return nil, err
+	}
+
 	for _, git := range c.RawGit {
 		if git.gitType() == "local" {
 			if gitLocal, err := git.toGitLocalDirective(); err != nil {
@@ -269,6 +286,15 @@ func (c *rawStapelImage) toStapelImageBaseDirective(giterminismManager gitermini
 		imageBase.Dependencies = append(imageBase.Dependencies, dependencyDirective)
 	}
 
+	for _, rawSecret := range c.RawSecrets {
+		secretDirective, err := rawSecret.toDirective(giterminismManager)
+		if err != nil {
+			return nil, err
+		}
+
+		imageBase.Secrets = append(imageBase.Secrets, secretDirective)
+	}
+
 	if err := c.validateStapelImageBaseDirective(giterminismManager, imageBase); err != nil {
 		return nil, err
 	}
@@ -302,3 +328,29 @@ func (c *rawStapelImage) toBaseStapelImageBaseDirective(giterminismManager giter
 
 	return imageBase, nil
 }
+
+// toRunOnBuildPlatformDirective returns true if all stages should be run on the build platform, or the selected user stages
+func (c *rawStapelImage) toRunOnBuildPlatformDirective() (bool, []string, error) {
+	if c.RunOnBuildPlatform == nil {
+		return false, nil, nil
+	}
+
+	if val, ok := c.RunOnBuildPlatform.(bool); ok {
+		return val, nil, nil
+	}
+
+	stages, err := InterfaceToStringArray(c.RunOnBuildPlatform, nil, c.doc)
+	if err != nil {
+		return false, nil, newDetailedConfigError(fmt.Sprintf("`runOnBuildPlatform: true` or array of stages expected, got `%v`!", c.RunOnBuildPlatform), nil, c.doc)
+	}
+
+	for _, stage := range stages {
+		switch stage {
+		case "beforeInstall", "install", "beforeSetup", "setup":
+		default:
+			return false, nil, newDetailedConfigError(fmt.Sprintf("invalid stage `%s` in `runOnBuildPlatform`: expected beforeInstall, install, beforeSetup or setup!", stage), nil, c.doc)
+		}
+	}
+
+	return false, stages, nil
+}
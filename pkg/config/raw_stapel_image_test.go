@@ -282,5 +282,64 @@ var _ = Describe("rawStapelImage", func() {
 				}},
 			},
 		),
+		Entry(
+			"with runOnBuildPlatform for image",
+			map[string]interface{}{
+				"image":              "image1",
+				"from":               "alpine",
+				"runOnBuildPlatform": true,
+			},
+		),
+		Entry(
+			"with invalid stage in runOnBuildPlatform",
+			map[string]interface{}{
+				"image":              "image1",
+				"from":               "alpine",
+				"runOnBuildPlatform": []string{"install", "dockerInstructions"},
+			},
+		),
+		Entry(
+			"with runOnBuildPlatform stages and fromImage",
+			map[string]interface{}{
+				"image":              "image1",
+				"fromImage":          "image2",
+				"runOnBuildPlatform": []string{"install"},
+			},
+		),
+	)
+
+	DescribeTable("unmarshal and convert to directive succeed and produce expected runOnBuildPlatform",
+		func(yamlMap map[string]interface{}, expected []string) {
+			rawYaml, err := yaml.Marshal(yamlMap)
+			Expect(err).To(Succeed())
+
+			doc := &doc{Content: rawYaml}
+			rawStapelImage := &rawStapelImage{doc: doc}
+			Expect(yaml.UnmarshalStrict(doc.Content, rawStapelImage)).To(Succeed())
+
+			stapelImage, err := rawStapelImage.toStapelImageDirective(giterminismManager, "image1")
+			Expect(err).To(Succeed())
+
+			Expect(stapelImage.RunOnBuildPlatform).To(BeFalse())
+			Expect(stapelImage.RunOnBuildPlatformStages).To(Equal(expected))
+		},
+		Entry(
+			"with single stage",
+			map[string]interface{}{
+				"image":              "image1",
+				"from":               "alpine",
+				"runOnBuildPlatform": "install",
+			},
+			[]string{"install"},
+		),
+		Entry(
+			"with several stages",
+			map[string]interface{}{
+				"image":              "image1",
+				"from":               "alpine",
+				"runOnBuildPlatform": []string{"beforeInstall", "setup"},
+			},
+			[]string{"beforeInstall", "setup"},
+		),
 	)
 })
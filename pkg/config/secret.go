@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/werf/werf/pkg/giterminism_manager"
+	"github.com/werf/werf/pkg/util"
+)
+
+var secretIDRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Secret is the build-time secret available in the RUN instructions and the user stages, but not stored in the image layers.
+// The value is taken from one of the sources: the environment variable, the file or the value encrypted with the werf secret key
+type Secret struct {
+	ID             string
+	Env            string
+	Src            string
+	EncryptedValue string
+
+	raw *rawSecret
+}
+
+func (s *Secret) validate(giterminismManager giterminism_manager.Interface) error {
+	if s.ID == "" {
+		return newDetailedConfigError("`id: ID` required for secret!", s.raw, s.raw.doc())
+	}
+
+	if !secretIDRegexp.MatchString(s.ID) {
+		return newDetailedConfigError(fmt.Sprintf("invalid secret `id: %s`: only alphanumeric characters, dots, dashes and underscores are allowed!", s.ID), s.raw, s.raw.doc())
+	}
+
+	var sources []string
+	for _, source := range []struct{ Name, Value string }{
+		{"env", s.Env},
+		{"src", s.Src},
+		{"encryptedValue", s.EncryptedValue},
+	} {
+		if source.Value != "" {
+			sources = append(sources, fmt.Sprintf("`%s`", source.Name))
+		}
+	}
+
+	switch len(sources) {
+	case 0:
+		return newDetailedConfigError(fmt.Sprintf("secret %q source is not specified: expected `env: NAME`, `src: PATH` or `encryptedValue: VALUE`!", s.ID), s.raw, s.raw.doc())
+	case 1:
+	default:
+		return newDetailedConfigError(fmt.Sprintf("only one source can be specified for secret %q, but found: %s!", s.ID, strings.Join(sources, ", ")), s.raw, s.raw.doc())
+	}
+
+	var err error
+	switch {
+	case s.Env != "":
+		err = giterminismManager.Inspector().InspectConfigSecretEnv(s.Env)
+	case s.Src != "":
+		err = giterminismManager.Inspector().InspectConfigSecretSrc(s.Src)
+	}
+
+	if err != nil {
+		return newDetailedConfigError(err.Error(), s.raw, s.raw.doc())
+	}
+
+	return nil
+}
+
+func validateSecretsIDs(secrets []*Secret, configDoc *doc) error {
+	var ids []string
+	for _, secret := range secrets {
+		ids = append(ids, secret.ID)
+	}
+
+	if duplicatedIDs := util.FindDuplicatedStrings(ids); len(duplicatedIDs) > 0 {
+		return newDetailedConfigError(fmt.Sprintf("each secret id should be unique, but found duplicates for: %s", strings.Join(duplicatedIDs, ", ")), nil, configDoc)
+	}
+
+	return nil
+}
@@ -9,6 +9,8 @@ import (
 type StapelImage struct {
 	*StapelImageBase
 	Docker *Docker
+	// Squash enables flattening the layers of the image stages into a single layer of the final image
+	Squash bool
 }
 
 func (c *StapelImage) validate() error {
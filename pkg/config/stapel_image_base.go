@@ -21,7 +21,14 @@ type StapelImageBase struct {
 	Mount            []*Mount
 	Import           []*Import
 	Dependencies     []*Dependency
+	Secrets          []*Secret
 	Platform         []string
+	// RunOnBuildPlatform enables running all stages of the artifact on the platform of the build host without emulation for each target platform.
+	// The target platform is available in the TARGETPLATFORM, TARGETOS, TARGETARCH and TARGETVARIANT environment variables
+	RunOnBuildPlatform bool
+	// RunOnBuildPlatformStages are the user stages, which commands are run in the base image for the platform of the build host,
+	// while the root filesystem of the stage being built for the target platform is available by the TARGETROOT path
+	RunOnBuildPlatformStages []string
 
 	raw *rawStapelImage
 }
@@ -107,10 +114,18 @@ func (c *StapelImageBase) validate(giterminismManager giterminism_manager.Interf
 		mountByTo[mount.To] = true
 	}
 
+	if err := validateSecretsIDs(c.Secrets, c.raw.doc); err != nil {
+		return err
+	}
+
 	if !oneOrNone([]bool{c.From != "", c.raw.FromImage != "", c.raw.FromArtifact != ""}) {
 		return newDetailedConfigError("conflict between `from`, `fromImage` and `fromArtifact` directives!", nil, c.raw.doc)
 	}
 
+	if len(c.RunOnBuildPlatformStages) > 0 && c.From == "" {
+		return newDetailedConfigError("`runOnBuildPlatform` with the selected stages requires `from: DOCKER_IMAGE` directive to run the stages in the base image for the build platform!", nil, c.raw.doc)
+	}
+
 	if c.raw.FromArtifact != "" {
 		logboek.Context(context.Background()).Warn().LogLn("WARNING: Do not use artifacts as a base for other images and artifacts. The feature is deprecated, and the directive 'fromArtifact' will be completely removed in version v1.3.\n\nCareless use of artifacts may lead to difficult to trace issues that may arise long after the configuration has been written. The artifact image is cached after the first build and ignores any changes in the project git repository unless the user has explicitly specified stage dependencies. As found, this behavior is completely unexpected for users despite the fact that it is absolutely correct in the werf logic.")
 	}
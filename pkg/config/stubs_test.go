@@ -70,3 +70,19 @@ func NewGitRepoArchiveStub() *GitRepoArchiveStub {
 func (archive *GitRepoArchiveStub) GetFilePath() string {
 	return "no-such-file"
 }
+
+func (manager *GiterminismManagerStub) Inspector() giterminism_manager.Inspector {
+	return GiterminismInspectorStub{}
+}
+
+type GiterminismInspectorStub struct {
+	giterminism_manager.Inspector
+}
+
+func (inspector GiterminismInspectorStub) InspectConfigSecretEnv(_ string) error {
+	return nil
+}
+
+func (inspector GiterminismInspectorStub) InspectConfigSecretSrc(_ string) error {
+	return nil
+}
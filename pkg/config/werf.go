@@ -319,6 +319,10 @@ func (c *WerfConfig) validateImageFrom(i *StapelImageBase) error {
 		if interf := c.GetImage(fromImageName); interf == nil {
 			return newDetailedConfigError(fmt.Sprintf("no such image `%s`!", fromImageName), i.raw, i.raw.doc)
 		}
+
+		if i.RunOnBuildPlatform {
+			return newDetailedConfigError(fmt.Sprintf("cannot use image `%s` built for the target platform as `fromImage` directive value with `runOnBuildPlatform: true`!", fromImageName), nil, i.raw.doc)
+		}
 	} else if i.raw.FromArtifact != "" {
 		fromArtifactName := i.raw.FromArtifact
 
@@ -326,9 +330,14 @@ func (c *WerfConfig) validateImageFrom(i *StapelImageBase) error {
 			return newDetailedConfigError(fmt.Sprintf("cannot use own image name as `fromArtifact` directive value!"), nil, i.raw.doc)
 		}
 
-		if imageArtifact := c.GetArtifact(fromArtifactName); imageArtifact == nil {
+		imageArtifact := c.GetArtifact(fromArtifactName)
+		if imageArtifact == nil {
 			return newDetailedConfigError(fmt.Sprintf("no such image artifact `%s`!", fromArtifactName), i.raw, i.raw.doc)
 		}
+
+		if imageArtifact.RunOnBuildPlatform != i.RunOnBuildPlatform {
+			return newDetailedConfigError(fmt.Sprintf("cannot use image artifact `%s` as `fromArtifact` directive value: `runOnBuildPlatform` should be the same for the image and its base artifact!", fromArtifactName), nil, i.raw.doc)
+		}
 	}
 
 	return nil
@@ -3,6 +3,7 @@ package container_backend
 import (
 	"fmt"
 	"io"
+	"time"
 )
 
 type AddDataArchiveOptions struct {
@@ -19,17 +20,21 @@ type BuildStapelStageOptionsInterface interface {
 	SetUser(user string) BuildStapelStageOptionsInterface
 	SetWorkdir(workdir string) BuildStapelStageOptionsInterface
 	SetHealthcheck(healthcheck string) BuildStapelStageOptionsInterface
+	SetSquash(squash bool) BuildStapelStageOptionsInterface
+	SetBuildPlatform(platform, baseImage string, envs map[string]string) BuildStapelStageOptionsInterface
 
 	AddBuildVolumes(volumes ...string) BuildStapelStageOptionsInterface
 	AddCommands(commands ...string) BuildStapelStageOptionsInterface
 
 	AddDataArchive(archive io.ReadCloser, archiveType ArchiveType, to string, o AddDataArchiveOptions) BuildStapelStageOptionsInterface
 	RemoveData(removeType RemoveType, paths, keepParentDirs []string) BuildStapelStageOptionsInterface
-	AddDependencyImport(imageName, fromPath, toPath string, includePaths, excludePaths []string, owner, group string) BuildStapelStageOptionsInterface
+	AddDependencyImport(imageName, imagePlatform, fromPath, toPath string, includePaths, excludePaths []string, owner, group string) BuildStapelStageOptionsInterface
 }
 
 type BuildStapelStageOptions struct {
 	TargetPlatform string
+	Timestamp      *time.Time
+	Squash         bool
 
 	Labels      []string
 	Volumes     []string
@@ -44,11 +49,20 @@ type BuildStapelStageOptions struct {
 	BuildVolumes []string
 	Commands     []string
 
+	// BuildPlatform is set if the commands are run in the container of the BuildPlatformBaseImage for the build platform
+	// with the root filesystem of the stage mounted into the BuildPlatformTargetRootDir
+	BuildPlatform          string
+	BuildPlatformBaseImage string
+	BuildPlatformEnvs      map[string]string
+
 	DataArchiveSpecs      []DataArchiveSpec
 	RemoveDataSpecs       []RemoveDataSpec
 	DependencyImportSpecs []DependencyImportSpec
 }
 
+// BuildPlatformTargetRootDir is the path of the root filesystem of the stage for the target platform in the container of the build platform
+const BuildPlatformTargetRootDir = "/.werf/target"
+
 type ArchiveType int
 
 //go:generate stringer -type=ArchiveType
@@ -80,13 +94,15 @@ type RemoveDataSpec struct {
 }
 
 type DependencyImportSpec struct {
-	ImageName    string
-	FromPath     string
-	ToPath       string
-	IncludePaths []string
-	ExcludePaths []string
-	Owner        string
-	Group        string
+	ImageName string
+	// ImagePlatform is the platform of the source image if it differs from the target platform of the stage
+	ImagePlatform string
+	FromPath      string
+	ToPath        string
+	IncludePaths  []string
+	ExcludePaths  []string
+	Owner         string
+	Group         string
 }
 
 func (opts *BuildStapelStageOptions) AddLabels(labels map[string]string) BuildStapelStageOptionsInterface {
@@ -143,6 +159,18 @@ func (opts *BuildStapelStageOptions) SetHealthcheck(healthcheck string) BuildSta
 	return opts
 }
 
+func (opts *BuildStapelStageOptions) SetSquash(squash bool) BuildStapelStageOptionsInterface {
+	opts.Squash = squash
+	return opts
+}
+
+func (opts *BuildStapelStageOptions) SetBuildPlatform(platform, baseImage string, envs map[string]string) BuildStapelStageOptionsInterface {
+	opts.BuildPlatform = platform
+	opts.BuildPlatformBaseImage = baseImage
+	opts.BuildPlatformEnvs = envs
+	return opts
+}
+
 func (opts *BuildStapelStageOptions) AddBuildVolumes(volumes ...string) BuildStapelStageOptionsInterface {
 	opts.BuildVolumes = append(opts.BuildVolumes, volumes...)
 	return opts
@@ -173,15 +201,16 @@ func (opts *BuildStapelStageOptions) RemoveData(removeType RemoveType, paths, ke
 	return opts
 }
 
-func (opts *BuildStapelStageOptions) AddDependencyImport(imageName, fromPath, toPath string, includePaths, excludePaths []string, owner, group string) BuildStapelStageOptionsInterface {
+func (opts *BuildStapelStageOptions) AddDependencyImport(imageName, imagePlatform, fromPath, toPath string, includePaths, excludePaths []string, owner, group string) BuildStapelStageOptionsInterface {
 	opts.DependencyImportSpecs = append(opts.DependencyImportSpecs, DependencyImportSpec{
-		ImageName:    imageName,
-		FromPath:     fromPath,
-		ToPath:       toPath,
-		IncludePaths: includePaths,
-		ExcludePaths: excludePaths,
-		Owner:        owner,
-		Group:        group,
+		ImageName:     imageName,
+		ImagePlatform: imagePlatform,
+		FromPath:      fromPath,
+		ToPath:        toPath,
+		IncludePaths:  includePaths,
+		ExcludePaths:  excludePaths,
+		Owner:         owner,
+		Group:         group,
 	})
 	return opts
 }
@@ -154,7 +154,7 @@ fi
 `, strings.Join(scriptCommands, "\n")))
 }
 
-func (backend *BuildahBackend) applyCommands(ctx context.Context, container *containerDesc, buildVolumes, commands []string, opts CommonOpts) error {
+func (backend *BuildahBackend) applyCommands(ctx context.Context, container *containerDesc, buildVolumes, commands []string, envs map[string]string, opts CommonOpts) error {
 	hostScriptPath := filepath.Join(backend.TmpDir, fmt.Sprintf("script-%s.sh", uuid.New().String()))
 	if err := os.WriteFile(hostScriptPath, makeScript(commands), os.FileMode(0o555)); err != nil {
 		return fmt.Errorf("unable to write script file %q: %w", hostScriptPath, err)
@@ -178,10 +178,17 @@ func (backend *BuildahBackend) applyCommands(ctx context.Context, container *con
 		mounts = append(mounts, m...)
 	}
 
+	var runEnvs []string
+	for k, v := range envs {
+		runEnvs = append(runEnvs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(runEnvs)
+
 	if err := backend.buildah.RunCommand(ctx, container.Name, []string{"sh", destScriptPath}, buildah.RunCommandOpts{
 		CommonOpts:   backend.getBuildahCommonOpts(ctx, false, nil, opts.TargetPlatform),
 		User:         "0:0",
 		WorkingDir:   "/",
+		Envs:         runEnvs,
 		GlobalMounts: mounts,
 	}); err != nil {
 		return fmt.Errorf("unable to run commands script: %w", err)
@@ -190,6 +197,26 @@ func (backend *BuildahBackend) applyCommands(ctx context.Context, container *con
 	return nil
 }
 
+// applyCommandsOnBuildPlatform runs the commands in the container of the base image for the build platform without emulation,
+// the root filesystem of the stage container for the target platform is mounted into the BuildPlatformTargetRootDir
+func (backend *BuildahBackend) applyCommandsOnBuildPlatform(ctx context.Context, container *containerDesc, opts BuildStapelStageOptions) error {
+	buildPlatformOpts := CommonOpts{TargetPlatform: opts.BuildPlatform}
+
+	buildPlatformContainers, err := backend.createContainers(ctx, []string{opts.BuildPlatformBaseImage}, buildPlatformOpts)
+	if err != nil {
+		return fmt.Errorf("unable to create build platform container: %w", err)
+	}
+	defer func() {
+		if err := backend.removeContainers(ctx, buildPlatformContainers, buildPlatformOpts); err != nil {
+			logboek.Context(ctx).Error().LogF("ERROR: unable to remove temporal build platform container: %s\n", err)
+		}
+	}()
+
+	buildVolumes := append([]string{fmt.Sprintf("%s:%s", container.RootMount, BuildPlatformTargetRootDir)}, opts.BuildVolumes...)
+
+	return backend.applyCommands(ctx, buildPlatformContainers[0], buildVolumes, opts.Commands, opts.BuildPlatformEnvs, buildPlatformOpts)
+}
+
 func (backend *BuildahBackend) CalculateDependencyImportChecksum(ctx context.Context, dependencyImport DependencyImportSpec, opts CalculateDependencyImportChecksum) (string, error) {
 	// TODO(2.0): Take into account empty dirs
 
@@ -275,7 +302,7 @@ func (backend *BuildahBackend) CalculateDependencyImportChecksum(ctx context.Con
 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
 }
 
-func (backend *BuildahBackend) applyDataArchives(ctx context.Context, container *containerDesc, dataArchives []DataArchiveSpec) error {
+func (backend *BuildahBackend) applyDataArchives(ctx context.Context, container *containerDesc, dataArchives []DataArchiveSpec, reproducible bool) error {
 	for _, archive := range dataArchives {
 		destPath := filepath.Join(container.RootMount, archive.To)
 
@@ -307,6 +334,9 @@ func (backend *BuildahBackend) applyDataArchives(ctx context.Context, container
 		if err != nil {
 			return fmt.Errorf("error getting UID/GID: %w", err)
 		}
+		if reproducible {
+			uid, gid = getReproducibleUIDAndGID(uid, gid)
+		}
 
 		logboek.Context(ctx).Debug().LogF("Extracting archive into container path %s\n", archive.To)
 
@@ -354,27 +384,40 @@ func (backend *BuildahBackend) applyRemoveData(ctx context.Context, container *c
 	return nil
 }
 
-func (backend *BuildahBackend) applyDependenciesImports(ctx context.Context, container *containerDesc, depImports []DependencyImportSpec, opts CommonOpts) error {
+func (backend *BuildahBackend) applyDependenciesImports(ctx context.Context, container *containerDesc, depImports []DependencyImportSpec, reproducible bool, opts CommonOpts) error {
 	var depImages []string
+	depImagesPlatforms := map[string]string{}
 	for _, imp := range depImports {
 		if util.IsStringsContainValue(depImages, imp.ImageName) {
 			continue
 		}
 
 		depImages = append(depImages, imp.ImageName)
+		depImagesPlatforms[imp.ImageName] = imp.ImagePlatform
 	}
 
-	logboek.Context(ctx).Debug().LogF("Creating containers for depContainers images %v\n", depImages)
-	createdDepContainers, err := backend.createContainers(ctx, depImages, opts)
-	if err != nil {
-		return fmt.Errorf("unable to create depContainers containers: %w", err)
-	}
+	var createdDepContainers []*containerDesc
 	defer func() {
 		if err := backend.removeContainers(ctx, createdDepContainers, opts); err != nil {
 			logboek.Context(ctx).Error().LogF("ERROR: unable to remove temporal depContainers containers: %s\n", err)
 		}
 	}()
 
+	logboek.Context(ctx).Debug().LogF("Creating containers for depContainers images %v\n", depImages)
+	for _, depImage := range depImages {
+		// the source image may be built for another platform, e.g. on the build platform for the target platform
+		depOpts := opts
+		if platform := depImagesPlatforms[depImage]; platform != "" {
+			depOpts.TargetPlatform = platform
+		}
+
+		containers, err := backend.createContainers(ctx, []string{depImage}, depOpts)
+		if err != nil {
+			return fmt.Errorf("unable to create depContainers containers: %w", err)
+		}
+		createdDepContainers = append(createdDepContainers, containers...)
+	}
+
 	// NOTE: maybe it is more optimal not to mount all dependencies at once, but mount one-by-one
 	logboek.Context(ctx).Debug().LogF("Mounting depContainers containers %v\n", createdDepContainers)
 	if err := backend.mountContainers(ctx, createdDepContainers, opts); err != nil {
@@ -396,10 +439,13 @@ func (backend *BuildahBackend) applyDependenciesImports(ctx context.Context, con
 			absFrom := filepath.Join(dep.RootMount, imp.FromPath)
 			absTo := filepath.Join(container.RootMount, imp.ToPath)
 
-			var uid, gid *uint32
-			if uid, gid, err = getUIDAndGID(imp.Owner, imp.Group, container.RootMount); err != nil {
+			uid, gid, err := getUIDAndGID(imp.Owner, imp.Group, container.RootMount)
+			if err != nil {
 				return fmt.Errorf("error getting UID/GID: %w", err)
 			}
+			if reproducible {
+				uid, gid = getReproducibleUIDAndGID(uid, gid)
+			}
 
 			pathMatcher := path_matcher.NewPathMatcher(path_matcher.PathMatcherOptions{
 				IncludeGlobs: imp.IncludePaths,
@@ -476,6 +522,7 @@ func (backend *BuildahBackend) BuildDockerfileStage(ctx context.Context, baseIma
 	logboek.Context(ctx).Debug().LogF("Committing build container %s\n", container.Name)
 	imageID, err := backend.buildah.Commit(ctx, container.Name, buildah.CommitOpts{
 		CommonOpts: backend.getBuildahCommonOpts(ctx, true, nil, opts.TargetPlatform),
+		Timestamp:  opts.Timestamp,
 	})
 	if err != nil {
 		return "", fmt.Errorf("error committing container %s: %w", container.Name, err)
@@ -500,7 +547,10 @@ func (backend *BuildahBackend) BuildStapelStage(ctx context.Context, baseImage s
 	}()
 	// TODO(stapel-to-buildah): cleanup orphan build containers in werf-host-cleanup procedure
 
-	if len(opts.DependencyImportSpecs)+len(opts.DataArchiveSpecs)+len(opts.RemoveDataSpecs) > 0 {
+	// the commands run on the build platform change the root filesystem of the build container mounted into another container
+	commandsOnBuildPlatform := len(opts.Commands) > 0 && opts.BuildPlatform != ""
+
+	if len(opts.DependencyImportSpecs)+len(opts.DataArchiveSpecs)+len(opts.RemoveDataSpecs) > 0 || commandsOnBuildPlatform {
 		logboek.Context(ctx).Debug().LogF("Mounting build container %s\n", container.Name)
 		if err := backend.mountContainers(ctx, []*containerDesc{container}, commonOpts); err != nil {
 			return "", fmt.Errorf("unable to mount build container %s: %w", container.Name, err)
@@ -514,12 +564,12 @@ func (backend *BuildahBackend) BuildStapelStage(ctx context.Context, baseImage s
 	}
 
 	if len(opts.DependencyImportSpecs) > 0 {
-		if err := backend.applyDependenciesImports(ctx, container, opts.DependencyImportSpecs, commonOpts); err != nil {
+		if err := backend.applyDependenciesImports(ctx, container, opts.DependencyImportSpecs, opts.Timestamp != nil, commonOpts); err != nil {
 			return "", err
 		}
 	}
 	if len(opts.DataArchiveSpecs) > 0 {
-		if err := backend.applyDataArchives(ctx, container, opts.DataArchiveSpecs); err != nil {
+		if err := backend.applyDataArchives(ctx, container, opts.DataArchiveSpecs, opts.Timestamp != nil); err != nil {
 			return "", err
 		}
 	}
@@ -528,8 +578,12 @@ func (backend *BuildahBackend) BuildStapelStage(ctx context.Context, baseImage s
 			return "", err
 		}
 	}
-	if len(opts.Commands) > 0 {
-		if err := backend.applyCommands(ctx, container, opts.BuildVolumes, opts.Commands, commonOpts); err != nil {
+	if commandsOnBuildPlatform {
+		if err := backend.applyCommandsOnBuildPlatform(ctx, container, opts); err != nil {
+			return "", err
+		}
+	} else if len(opts.Commands) > 0 {
+		if err := backend.applyCommands(ctx, container, opts.BuildVolumes, opts.Commands, nil, commonOpts); err != nil {
 			return "", err
 		}
 	}
@@ -558,7 +612,11 @@ func (backend *BuildahBackend) BuildStapelStage(ctx context.Context, baseImage s
 	// TODO(stapel-to-buildah): Save container name as builtID. There is no need to commit an image here,
 	//                            because buildah allows to commit and push directly container, which would happen later.
 	logboek.Context(ctx).Debug().LogF("committing container %q\n", container.Name)
-	imgID, err := backend.buildah.Commit(ctx, container.Name, buildah.CommitOpts{CommonOpts: backend.getBuildahCommonOpts(ctx, true, nil, opts.TargetPlatform)})
+	imgID, err := backend.buildah.Commit(ctx, container.Name, buildah.CommitOpts{
+		CommonOpts: backend.getBuildahCommonOpts(ctx, true, nil, opts.TargetPlatform),
+		Timestamp:  opts.Timestamp,
+		Squash:     opts.Squash,
+	})
 	if err != nil {
 		return "", fmt.Errorf("unable to commit container %q: %w", container.Name, err)
 	}
@@ -696,6 +754,10 @@ func (backend *BuildahBackend) BuildDockerfile(ctx context.Context, dockerfileCo
 		BuildArgs:  buildArgs,
 		Target:     opts.Target,
 		Labels:     opts.Labels,
+		CacheFrom:  opts.CacheFrom,
+		CacheTo:    opts.CacheTo,
+		Secrets:    opts.Secrets,
+		Timestamp:  opts.Timestamp,
 	})
 }
 
@@ -857,6 +919,20 @@ func getUIDAndGID(userNameOrUID, groupNameOrGID, fsRoot string) (*uint32, *uint3
 	return uid, gid, nil
 }
 
+// getReproducibleUIDAndGID returns root UID/GID instead of the unset ones,
+// so that the added files have the same owner regardless of the user running the build and of the source files owner.
+func getReproducibleUIDAndGID(uid, gid *uint32) (*uint32, *uint32) {
+	var root uint32
+	if uid == nil {
+		uid = &root
+	}
+	if gid == nil {
+		gid = &root
+	}
+
+	return uid, gid
+}
+
 // Returns nil pointer if username/UID is empty string.
 func getUID(userNameOrUID, fsRoot string) (*uint32, error) {
 	var uid *uint32
@@ -1020,4 +1096,53 @@ func (backend *BuildahBackend) PostManifest(ctx context.Context, ref string, opt
 	return nil
 }
 
+func (backend *BuildahBackend) SaveImageToStream(ctx context.Context, ref string) (io.ReadCloser, error) {
+	archivePath := filepath.Join(backend.TmpDir, fmt.Sprintf("image-%s.tar", uuid.New().String()))
+
+	if err := backend.buildah.Save(ctx, ref, archivePath, buildah.SaveOpts(backend.getBuildahCommonOpts(ctx, true, nil, ""))); err != nil {
+		return nil, fmt.Errorf("unable to save image %q: %w", ref, err)
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open image archive %q: %w", archivePath, err)
+	}
+
+	return &removeOnCloseFile{File: f}, nil
+}
+
+func (backend *BuildahBackend) LoadImageFromStream(ctx context.Context, input io.Reader) error {
+	f, err := ioutil.TempFile(backend.TmpDir, "image-*.tar")
+	if err != nil {
+		return fmt.Errorf("unable to create image archive: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, input); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write image archive %q: %w", f.Name(), err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close image archive %q: %w", f.Name(), err)
+	}
+
+	if err := backend.Pull(ctx, fmt.Sprintf("docker-archive:%s", f.Name()), PullOpts{}); err != nil {
+		return fmt.Errorf("unable to load image archive %q: %w", f.Name(), err)
+	}
+	return nil
+}
+
+// removeOnCloseFile removes the underlying temporary file once the stream is consumed.
+type removeOnCloseFile struct {
+	*os.File
+}
+
+func (f *removeOnCloseFile) Close() error {
+	closeErr := f.File.Close()
+	if err := os.Remove(f.File.Name()); err != nil {
+		return fmt.Errorf("unable to remove %q: %w", f.File.Name(), err)
+	}
+	return closeErr
+}
+
 func (backend *BuildahBackend) ClaimTargetPlatforms(ctx context.Context, targetPlatforms []string) {}
@@ -3,6 +3,7 @@ package container_backend
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -80,6 +81,9 @@ func (backend *DockerServerBackend) BuildDockerfile(ctx context.Context, _ []byt
 	for _, label := range opts.Labels {
 		cliArgs = append(cliArgs, "--label", label)
 	}
+	for _, secret := range opts.Secrets {
+		cliArgs = append(cliArgs, "--secret", secret)
+	}
 
 	tempID := uuid.New().String()
 	opts.Tags = append(opts.Tags, tempID)
@@ -99,7 +103,7 @@ func (backend *DockerServerBackend) BuildDockerfile(ctx context.Context, _ []byt
 	}
 	defer contextReader.Close()
 
-	return tempID, docker.CliBuild_LiveOutputWithCustomIn(ctx, contextReader, cliArgs...)
+	return tempID, docker.CliBuild_LiveOutputWithCustomIn(ctx, contextReader, len(opts.Secrets) > 0, cliArgs...)
 }
 
 func (backend *DockerServerBackend) BuildDockerfileStage(ctx context.Context, baseImage string, opts BuildDockerfileStageOptions, instructions ...InstructionInterface) (string, error) {
@@ -342,3 +346,11 @@ func (backend *DockerServerBackend) Containers(ctx context.Context, opts Contain
 func (backend *DockerServerBackend) PostManifest(ctx context.Context, ref string, opts PostManifestOpts) error {
 	return docker.CreateImage(ctx, ref, docker.CreateImageOptions{Labels: opts.Labels})
 }
+
+func (backend *DockerServerBackend) SaveImageToStream(ctx context.Context, ref string) (io.ReadCloser, error) {
+	return docker.ImageSave(ctx, ref)
+}
+
+func (backend *DockerServerBackend) LoadImageFromStream(ctx context.Context, input io.Reader) error {
+	return docker.ImageLoad(ctx, input)
+}
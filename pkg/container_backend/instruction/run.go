@@ -15,6 +15,8 @@ import (
 type Run struct {
 	instructions.RunCommand
 	Envs []string
+	// Secrets for the RUN --mount=type=secret mounts in the id=ID,type=file,src=PATH format
+	Secrets []string
 }
 
 func NewRun(i instructions.RunCommand, envs []string) *Run {
@@ -68,6 +70,7 @@ func (i *Run) Apply(ctx context.Context, containerName string, drv buildah.Build
 		NetworkType:     i.GetNetwork(),
 		RunMounts:       i.GetMounts(),
 		Envs:            i.Envs,
+		Secrets:         i.Secrets,
 	}); err != nil {
 		return fmt.Errorf("error running command %v for container %s: %w", i.CmdLine, containerName, err)
 	}
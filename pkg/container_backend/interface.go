@@ -2,6 +2,8 @@ package container_backend
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/util"
@@ -41,17 +43,24 @@ type BuildDockerfileOpts struct {
 	SSH                  string
 	Labels               []string
 	Tags                 []string
+	CacheFrom            []string   // repos to pull the intermediate layers cache from (Buildah only)
+	CacheTo              []string   // repos to push the intermediate layers cache to (Buildah only)
+	Secrets              []string   // {"id=id1,type=file,src=path1", ...}, Docker Server backend uses BuildKit if specified
+	Timestamp            *time.Time // the created time of the image and the modification time of the layer files (Buildah only)
 }
 
 type BuildDockerfileStageOptions struct {
 	CommonOpts
 	BuildContextArchive BuildContextArchiver
+	Timestamp           *time.Time
 }
 
 type BuildOptions struct {
 	TargetPlatform        string
 	IntrospectBeforeError bool
 	IntrospectAfterError  bool
+	// Timestamp is set as the created time of the image and the modification time of the layer files to build reproducible images
+	Timestamp *time.Time
 }
 
 type ImagesOptions struct {
@@ -77,6 +86,8 @@ type ContainerBackend interface {
 	Rmi(ctx context.Context, ref string, opts RmiOpts) error
 	Rm(ctx context.Context, name string, opts RmOpts) error
 	PostManifest(ctx context.Context, ref string, opts PostManifestOpts) error
+	SaveImageToStream(ctx context.Context, ref string) (io.ReadCloser, error)
+	LoadImageFromStream(ctx context.Context, input io.Reader) error
 
 	GetImageInfo(ctx context.Context, ref string, opts GetImageInfoOpts) (*image.Info, error)
 	BuildDockerfile(ctx context.Context, dockerfile []byte, opts BuildDockerfileOpts) (string, error)
@@ -161,6 +161,14 @@ func (i *LegacyStageImage) Commit(ctx context.Context) error {
 		return err
 	}
 
+	if i.commitChangeOptions.Squash {
+		squashedId, err := squashLegacyContainerImage(ctx, i.container.Name(), builtId)
+		if err != nil {
+			return fmt.Errorf("unable to squash image: %w", err)
+		}
+		builtId = squashedId
+	}
+
 	i.buildImage = newLegacyBaseImage(builtId, i.ContainerBackend)
 	i.builtID = builtId
 
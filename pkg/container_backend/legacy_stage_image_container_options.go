@@ -12,6 +12,7 @@ import (
 
 type LegacyCommitChangeOptions struct {
 	ExactValues bool
+	Squash      bool
 }
 
 type LegacyStageImageContainerOptions struct {
@@ -2,6 +2,7 @@ package container_backend
 
 import (
 	"context"
+	"io"
 
 	"github.com/werf/logboek"
 	"github.com/werf/werf/pkg/image"
@@ -179,6 +180,22 @@ func (runtime *PerfCheckContainerBackend) PostManifest(ctx context.Context, ref
 	return
 }
 
+func (runtime *PerfCheckContainerBackend) SaveImageToStream(ctx context.Context, ref string) (resStream io.ReadCloser, resErr error) {
+	logboek.Context(ctx).Default().LogProcess("ContainerBackend.SaveImageToStream %q", ref).
+		Do(func() {
+			resStream, resErr = runtime.ContainerBackend.SaveImageToStream(ctx, ref)
+		})
+	return
+}
+
+func (runtime *PerfCheckContainerBackend) LoadImageFromStream(ctx context.Context, input io.Reader) (resErr error) {
+	logboek.Context(ctx).Default().LogProcess("ContainerBackend.LoadImageFromStream").
+		Do(func() {
+			resErr = runtime.ContainerBackend.LoadImageFromStream(ctx, input)
+		})
+	return
+}
+
 func (runtime *PerfCheckContainerBackend) TagImageByName(ctx context.Context, img LegacyImageInterface) (resErr error) {
 	logboek.Context(ctx).Default().LogProcess("ContainerBackend.TagImageByName %q", img.Name()).
 		Do(func() {
@@ -0,0 +1,172 @@
+package container_backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/werf"
+)
+
+// squashLegacyContainerImage creates the image with the single layer from the container filesystem and the config of the committed image.
+// The committed image is removed, the squashed image id is returned
+func squashLegacyContainerImage(ctx context.Context, containerName, imageId string) (string, error) {
+	inspect, err := docker.ImageInspect(ctx, imageId)
+	if err != nil {
+		return "", fmt.Errorf("unable to inspect image %s: %w", imageId, err)
+	}
+
+	configFile, err := newSquashedImageConfigFile(inspect)
+	if err != nil {
+		return "", err
+	}
+
+	layerPath, err := exportContainerFilesystem(ctx, containerName)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(layerPath)
+
+	layer, err := tarball.LayerFromFile(layerPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to open container %s filesystem archive: %w", containerName, err)
+	}
+
+	img, err := newSquashedImage(configFile, layer, imageId)
+	if err != nil {
+		return "", err
+	}
+
+	squashedId, err := loadImage(ctx, img)
+	if err != nil {
+		return "", fmt.Errorf("unable to load squashed image: %w", err)
+	}
+
+	if err := docker.ImageRemove(ctx, imageId); err != nil {
+		logboek.Context(ctx).Warn().LogF("WARNING: unable to remove image %s: %s\n", imageId, err)
+	}
+
+	return squashedId, nil
+}
+
+// newSquashedImage creates the image with the single layer and the config of the squashed image
+func newSquashedImage(configFile *v1.ConfigFile, layer v1.Layer, imageId string) (v1.Image, error) {
+	img, err := mutate.ConfigFile(empty.Image, configFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set image config: %w", err)
+	}
+
+	img, err = mutate.Append(img, mutate.Addendum{
+		Layer: layer,
+		History: v1.History{
+			Created:   configFile.Created,
+			CreatedBy: "werf squash",
+			Comment:   fmt.Sprintf("squashed %s", imageId),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to append squashed layer: %w", err)
+	}
+
+	return img, nil
+}
+
+// newSquashedImageConfigFile converts the config of the docker image, the fields of both configs have the same json representation
+func newSquashedImageConfigFile(inspect *types.ImageInspect) (*v1.ConfigFile, error) {
+	configFile := &v1.ConfigFile{
+		OS:           inspect.Os,
+		Architecture: inspect.Architecture,
+		Variant:      inspect.Variant,
+		Author:       inspect.Author,
+	}
+
+	if inspect.Config != nil {
+		data, err := json.Marshal(inspect.Config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal image config: %w", err)
+		}
+
+		if err := json.Unmarshal(data, &configFile.Config); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal image config: %w", err)
+		}
+	}
+
+	if inspect.Created != "" {
+		createdTime, err := time.Parse(time.RFC3339Nano, inspect.Created)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse image creation time %q: %w", inspect.Created, err)
+		}
+		configFile.Created = v1.Time{Time: createdTime}
+	}
+
+	return configFile, nil
+}
+
+func exportContainerFilesystem(ctx context.Context, containerName string) (string, error) {
+	stream, err := docker.ContainerExport(ctx, containerName)
+	if err != nil {
+		return "", fmt.Errorf("unable to export container %s: %w", containerName, err)
+	}
+	defer stream.Close()
+
+	f, err := os.CreateTemp(werf.GetTmpDir(), "squash-*.tar")
+	if err != nil {
+		return "", fmt.Errorf("unable to create container filesystem archive: %w", err)
+	}
+
+	if _, err := io.Copy(f, stream); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("unable to write container %s filesystem archive: %w", containerName, err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("unable to close container filesystem archive: %w", err)
+	}
+
+	return f.Name(), nil
+}
+
+// loadImage loads the untagged image into the docker server and returns the image id
+func loadImage(ctx context.Context, img v1.Image) (string, error) {
+	configName, err := img.ConfigName()
+	if err != nil {
+		return "", fmt.Errorf("unable to get image config digest: %w", err)
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return "", fmt.Errorf("unable to get image digest: %w", err)
+	}
+
+	// the digest reference is used to load the image without tags
+	ref, err := name.NewDigest(fmt.Sprintf("werf-squash@%s", digest))
+	if err != nil {
+		return "", fmt.Errorf("unable to create image reference: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(tarball.Write(ref, img, pw))
+	}()
+
+	if err := docker.ImageLoad(ctx, pr); err != nil {
+		pr.CloseWithError(err)
+		return "", err
+	}
+
+	return configName.String(), nil
+}
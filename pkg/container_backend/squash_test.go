@@ -0,0 +1,99 @@
+package container_backend
+
+import (
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Squash", func() {
+	It("should convert the docker image config", func() {
+		configFile, err := newSquashedImageConfigFile(&types.ImageInspect{
+			Os:           "linux",
+			Architecture: "arm",
+			Variant:      "v7",
+			Author:       "werf",
+			Created:      "2023-03-01T10:20:30.123456789Z",
+			Config: &container.Config{
+				User:         "app",
+				Env:          []string{"PATH=/usr/local/bin:/usr/bin", "MODE=production"},
+				Entrypoint:   []string{"/entrypoint.sh"},
+				Cmd:          []string{"serve", "--port", "8080"},
+				WorkingDir:   "/app",
+				Labels:       map[string]string{"werf": "project"},
+				ExposedPorts: nat.PortSet{"8080/tcp": {}},
+				Volumes:      map[string]struct{}{"/data": {}},
+				StopSignal:   "SIGTERM",
+			},
+		})
+		Expect(err).To(Succeed())
+
+		Expect(configFile.OS).To(Equal("linux"))
+		Expect(configFile.Architecture).To(Equal("arm"))
+		Expect(configFile.Variant).To(Equal("v7"))
+		Expect(configFile.Author).To(Equal("werf"))
+		Expect(configFile.Created.Time.Equal(time.Date(2023, 3, 1, 10, 20, 30, 123456789, time.UTC))).To(BeTrue())
+		Expect(configFile.Config).To(Equal(v1.Config{
+			User:         "app",
+			Env:          []string{"PATH=/usr/local/bin:/usr/bin", "MODE=production"},
+			Entrypoint:   []string{"/entrypoint.sh"},
+			Cmd:          []string{"serve", "--port", "8080"},
+			WorkingDir:   "/app",
+			Labels:       map[string]string{"werf": "project"},
+			ExposedPorts: map[string]struct{}{"8080/tcp": {}},
+			Volumes:      map[string]struct{}{"/data": {}},
+			StopSignal:   "SIGTERM",
+		}))
+	})
+
+	It("should convert the docker image without config and creation time", func() {
+		configFile, err := newSquashedImageConfigFile(&types.ImageInspect{Os: "linux", Architecture: "amd64"})
+		Expect(err).To(Succeed())
+		Expect(configFile.Created.IsZero()).To(BeTrue())
+		Expect(configFile.Config).To(Equal(v1.Config{}))
+	})
+
+	It("should fail on invalid creation time", func() {
+		_, err := newSquashedImageConfigFile(&types.ImageInspect{Created: "yesterday"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should create the image with the single layer and the squashed image config", func() {
+		layer, err := random.Layer(1024, "application/vnd.docker.image.rootfs.diff.tar.gzip")
+		Expect(err).To(Succeed())
+
+		created := v1.Time{Time: time.Date(2023, 3, 1, 10, 20, 30, 0, time.UTC)}
+		img, err := newSquashedImage(&v1.ConfigFile{
+			OS:           "linux",
+			Architecture: "amd64",
+			Created:      created,
+			Config:       v1.Config{Cmd: []string{"serve"}, Labels: map[string]string{"werf": "project"}},
+		}, layer, "sha256:0123456789abcdef")
+		Expect(err).To(Succeed())
+
+		layers, err := img.Layers()
+		Expect(err).To(Succeed())
+		Expect(layers).To(HaveLen(1))
+
+		layerDiffID, err := layer.DiffID()
+		Expect(err).To(Succeed())
+
+		configFile, err := img.ConfigFile()
+		Expect(err).To(Succeed())
+		Expect(configFile.OS).To(Equal("linux"))
+		Expect(configFile.Architecture).To(Equal("amd64"))
+		Expect(configFile.Config.Cmd).To(Equal([]string{"serve"}))
+		Expect(configFile.Config.Labels).To(Equal(map[string]string{"werf": "project"}))
+		Expect(configFile.RootFS.DiffIDs).To(Equal([]v1.Hash{layerDiffID}))
+		Expect(configFile.History).To(HaveLen(1))
+		Expect(configFile.History[0].CreatedBy).To(Equal("werf squash"))
+		Expect(configFile.History[0].Comment).To(Equal("squashed sha256:0123456789abcdef"))
+		Expect(configFile.History[0].Created.Time.Equal(created.Time)).To(BeTrue())
+	})
+})
@@ -19,6 +19,9 @@ type DockerfileBuilderInterface interface {
 	SetNetwork(network string)
 	SetSSH(ssh string)
 	AppendLabels(labels ...string)
+	AppendCacheFrom(repos ...string)
+	AppendCacheTo(repos ...string)
+	AppendSecrets(secrets ...string)
 	SetBuildContextArchive(buildContextArchive container_backend.BuildContextArchiver)
 }
 
@@ -45,6 +48,7 @@ func (b *DockerfileBuilder) Build(ctx context.Context, opts container_backend.Bu
 	finalOpts := b.BuildDockerfileOptions
 	finalOpts.BuildContextArchive = b.BuildContextArchive
 	finalOpts.TargetPlatform = opts.TargetPlatform
+	finalOpts.Timestamp = opts.Timestamp
 
 	if container_backend.Debug() {
 		fmt.Printf("BuildContextArchive=%q\n", b.BuildContextArchive)
@@ -104,6 +108,18 @@ func (b *DockerfileBuilder) AppendLabels(labels ...string) {
 	b.BuildDockerfileOptions.Labels = append(b.BuildDockerfileOptions.Labels, labels...)
 }
 
+func (b *DockerfileBuilder) AppendCacheFrom(repos ...string) {
+	b.BuildDockerfileOptions.CacheFrom = append(b.BuildDockerfileOptions.CacheFrom, repos...)
+}
+
+func (b *DockerfileBuilder) AppendCacheTo(repos ...string) {
+	b.BuildDockerfileOptions.CacheTo = append(b.BuildDockerfileOptions.CacheTo, repos...)
+}
+
+func (b *DockerfileBuilder) AppendSecrets(secrets ...string) {
+	b.BuildDockerfileOptions.Secrets = append(b.BuildDockerfileOptions.Secrets, secrets...)
+}
+
 func (b *DockerfileBuilder) SetBuildContextArchive(buildContextArchive container_backend.BuildContextArchiver) {
 	b.BuildContextArchive = buildContextArchive
 }
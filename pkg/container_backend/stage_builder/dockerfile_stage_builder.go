@@ -63,6 +63,7 @@ func (b *DockerfileStageBuilder) Build(ctx context.Context, opts container_backe
 	backendOpts := container_backend.BuildDockerfileStageOptions{
 		CommonOpts:          container_backend.CommonOpts{TargetPlatform: opts.TargetPlatform},
 		BuildContextArchive: b.buildContextArchive,
+		Timestamp:           opts.Timestamp,
 	}
 
 	if builtID, err := b.containerBackend.BuildDockerfileStage(ctx, b.baseImage, backendOpts, instructions...); err != nil {
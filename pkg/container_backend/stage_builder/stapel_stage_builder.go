@@ -31,6 +31,7 @@ func NewStapelStageBuilder(containerBackend container_backend.ContainerBackend,
 func (builder *StapelStageBuilder) Build(ctx context.Context, opts container_backend.BuildOptions) error {
 	finalOpts := builder.BuildStapelStageOptions
 	finalOpts.TargetPlatform = opts.TargetPlatform
+	finalOpts.Timestamp = opts.Timestamp
 	// TODO: support introspect options
 
 	builtID, err := builder.ContainerBackend.BuildStapelStage(ctx, builder.BaseImage, finalOpts)
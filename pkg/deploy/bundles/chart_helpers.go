@@ -6,6 +6,7 @@ import (
 	"compress/gzip"
 	"context"
 	"fmt"
+	"path/filepath"
 
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
@@ -57,3 +58,26 @@ func SaveChartValues(ctx context.Context, ch *chart.Chart) error {
 
 	return nil
 }
+
+// GetChartDirImages returns image references by image names from .Values.werf.image of the chart saved into the dir
+func GetChartDirImages(dir string) (map[string]string, error) {
+	vals, err := chartutil.ReadValuesFile(filepath.Join(dir, chartutil.ValuesfileName))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read chart values: %w", err)
+	}
+
+	images := map[string]string{}
+	if werfVals, ok := vals["werf"].(map[string]interface{}); ok {
+		if imageVals, ok := werfVals["image"].(map[string]interface{}); ok {
+			for imageName, v := range imageVals {
+				image, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("unexpected value .Values.werf.image.%s=%v", imageName, v)
+				}
+				images[imageName] = image
+			}
+		}
+	}
+
+	return images, nil
+}
@@ -10,11 +10,17 @@ import (
 	"github.com/werf/logboek"
 	"github.com/werf/werf/pkg/deploy/bundles/registry"
 	"github.com/werf/werf/pkg/deploy/helm/chart_extender"
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/signing"
 )
 
 type PublishOptions struct {
 	HelmCompatibleChart bool
 	RenameChart         string
+
+	// Signer signs the published bundle if specified, RegistryClient is used to push the signature
+	Signer         *signing.Signer
+	RegistryClient docker_registry.Interface
 }
 
 func Publish(ctx context.Context, bundle *chart_extender.Bundle, bundleRef string, bundlesRegistryClient *registry.Client, opts PublishOptions) error {
@@ -54,5 +60,11 @@ func Publish(ctx context.Context, bundle *chart_extender.Bundle, bundleRef strin
 		return err
 	}
 
+	if opts.Signer != nil {
+		if err := opts.Signer.SignImages(ctx, opts.RegistryClient, []string{r.FullName()}); err != nil {
+			return fmt.Errorf("unable to sign bundle %q: %w", bundleRef, err)
+		}
+	}
+
 	return nil
 }
@@ -119,17 +119,17 @@ func (cache *Cache) FetchReference(ref *Reference) (*CacheRefSummary, error) {
 			var contentLayer *ocispec.Descriptor
 			for _, layer := range manifest.Layers {
 				layer := layer
-				if layer.MediaType == HelmChartContentLayerMediaType {
+				if IsHelmChartContentLayerMediaType(layer.MediaType) {
 					contentLayer = &layer
 				}
 			}
 			if contentLayer == nil {
 				return &r, errors.New(
-					fmt.Sprintf("manifest does not contain a layer with mediatype %s", HelmChartContentLayerMediaType))
+					fmt.Sprintf("manifest does not contain a layer with mediatype %s or %s", HelmChartContentLayerMediaType, HelmChartOCIContentLayerMediaType))
 			}
 			if contentLayer.Size == 0 {
 				return &r, errors.New(
-					fmt.Sprintf("manifest layer with mediatype %s is of size 0", HelmChartContentLayerMediaType))
+					fmt.Sprintf("manifest layer with mediatype %s is of size 0", contentLayer.MediaType))
 			}
 			r.ContentLayer = contentLayer
 			info, err := cache.ociStore.Info(ctx(cache.out, cache.debug), contentLayer.Digest)
@@ -153,8 +153,8 @@ func (cache *Cache) FetchReference(ref *Reference) (*CacheRefSummary, error) {
 	return &r, nil
 }
 
-// StoreReference stores a chart ref in cache
-func (cache *Cache) StoreReference(ref *Reference, ch *chart.Chart) (*CacheRefSummary, error) {
+// StoreReference stores a chart ref in cache, the chart content layer gets the specified media type
+func (cache *Cache) StoreReference(ref *Reference, ch *chart.Chart, contentLayerMediaType string) (*CacheRefSummary, error) {
 	if err := cache.init(); err != nil {
 		return nil, err
 	}
@@ -171,7 +171,7 @@ func (cache *Cache) StoreReference(ref *Reference, ch *chart.Chart) (*CacheRefSu
 		return &r, err
 	}
 	r.Config = config
-	contentLayer, _, err := cache.saveChartContentLayer(ch)
+	contentLayer, _, err := cache.saveChartContentLayer(ch, contentLayerMediaType)
 	if err != nil {
 		return &r, err
 	}
@@ -286,7 +286,7 @@ func (cache *Cache) saveChartConfig(ch *chart.Chart) (*ocispec.Descriptor, bool,
 }
 
 // saveChartContentLayer stores the chart as tarball blob and returns a descriptor
-func (cache *Cache) saveChartContentLayer(ch *chart.Chart) (*ocispec.Descriptor, bool, error) {
+func (cache *Cache) saveChartContentLayer(ch *chart.Chart, mediaType string) (*ocispec.Descriptor, bool, error) {
 	destDir := filepath.Join(cache.rootDir, ".build")
 	os.MkdirAll(destDir, 0o755)
 	tmpFile, err := chartutil.Save(ch, destDir)
@@ -302,7 +302,7 @@ func (cache *Cache) saveChartContentLayer(ch *chart.Chart) (*ocispec.Descriptor,
 	if err != nil {
 		return nil, contentExists, err
 	}
-	descriptor := cache.memoryStore.Add("", HelmChartContentLayerMediaType, contentBytes)
+	descriptor := cache.memoryStore.Add("", mediaType, contentBytes)
 	return &descriptor, contentExists, nil
 }
 
@@ -47,6 +47,8 @@ type (
 		debug         bool
 		insecure      bool
 		skipTlsVerify bool
+		// save charts as standard Helm OCI artifacts instead of the legacy layout
+		ociArtifact bool
 		// path to repository config file e.g. ~/.docker/config.json
 		credentialsFile string
 		out             io.Writer
@@ -185,15 +187,15 @@ func (c *Client) PullChart(ref *Reference) (*bytes.Buffer, error) {
 	var contentLayer *ocispec.Descriptor
 	for _, layer := range layerDescriptors {
 		layer := layer
-		if layer.MediaType == HelmChartContentLayerMediaType {
+		if IsHelmChartContentLayerMediaType(layer.MediaType) {
 			contentLayer = &layer
 		}
 	}
 
 	if contentLayer == nil {
 		return buf, errors.New(
-			fmt.Sprintf("manifest does not contain a layer with mediatype %s",
-				HelmChartContentLayerMediaType))
+			fmt.Sprintf("manifest does not contain a layer with mediatype %s or %s",
+				HelmChartContentLayerMediaType, HelmChartOCIContentLayerMediaType))
 	}
 
 	_, b, ok := store.Get(*contentLayer)
@@ -246,7 +248,12 @@ func (c *Client) PullChartToCache(ref *Reference) error {
 
 // SaveChart stores a copy of chart in local cache
 func (c *Client) SaveChart(ch *chart.Chart, ref *Reference) error {
-	r, err := c.cache.StoreReference(ref, ch)
+	contentLayerMediaType := HelmChartContentLayerMediaType
+	if c.ociArtifact {
+		contentLayerMediaType = HelmChartOCIContentLayerMediaType
+	}
+
+	r, err := c.cache.StoreReference(ref, ch, contentLayerMediaType)
 	if err != nil {
 		return err
 	}
@@ -79,3 +79,11 @@ func ClientOptSkipTlsVerify(skipTlsVerify bool) ClientOption {
 		client.skipTlsVerify = skipTlsVerify
 	}
 }
+
+// ClientOptOCIArtifact returns a function that sets the chart content layer media type of saved charts
+// to the standard Helm OCI artifact media type instead of the legacy one
+func ClientOptOCIArtifact(ociArtifact bool) ClientOption {
+	return func(client *Client) {
+		client.ociArtifact = ociArtifact
+	}
+}
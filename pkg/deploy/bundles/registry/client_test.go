@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+var _ = Describe("Client", func() {
+	DescribeTable("should push the chart content layer with the media type",
+		func(ociArtifact bool, expectedMediaType string) {
+			server := httptest.NewServer(registry.New())
+			DeferCleanup(server.Close)
+
+			tmpDir := GinkgoT().TempDir()
+			cache, err := NewCache(CacheOptRoot(filepath.Join(tmpDir, "cache")))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			client, err := NewClient(
+				ClientOptCredentialsFile(filepath.Join(tmpDir, "config.json")),
+				ClientOptCache(cache),
+				ClientOptInsecure(true),
+				ClientOptOCIArtifact(ociArtifact),
+			)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			ref, err := ParseReference(strings.TrimPrefix(server.URL, "http://") + "/bundles/test-bundle:0.1.0")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			ch := &chart.Chart{
+				Metadata: &chart.Metadata{
+					APIVersion: "v2",
+					Name:       "test-bundle",
+					Version:    "0.1.0",
+				},
+			}
+			Ω(client.SaveChart(ch, ref)).Should(Succeed())
+			Ω(client.PushChart(ref)).Should(Succeed())
+
+			remoteRef, err := name.ParseReference(ref.FullName(), name.Insecure)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			img, err := remote.Image(remoteRef)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			manifest, err := img.Manifest()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(manifest.Config.MediaType)).Should(Equal(HelmChartConfigMediaType))
+			Ω(manifest.Layers).Should(HaveLen(1))
+			Ω(string(manifest.Layers[0].MediaType)).Should(Equal(expectedMediaType))
+
+			pulledChart, err := client.PullChart(ref)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(pulledChart.Len()).ShouldNot(BeZero())
+
+			digest, err := img.Digest()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			digestRef, err := ParseReference(ref.FullName() + "@" + digest.String())
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(digestRef.Tag).Should(Equal(ref.Tag))
+			Ω(digestRef.Digest).Should(Equal(digest.String()))
+			Ω(client.PullChartToCache(digestRef)).Should(Succeed())
+		},
+		Entry("legacy werf bundle layout", false, HelmChartContentLayerMediaType),
+		Entry("standard Helm OCI artifact", true, HelmChartOCIContentLayerMediaType),
+	)
+})
@@ -22,6 +22,9 @@ const (
 
 	// HelmChartContentLayerMediaType is the reserved media type for Helm chart package content
 	HelmChartContentLayerMediaType = "application/tar+gzip"
+
+	// HelmChartOCIContentLayerMediaType is the media type for Helm chart package content of the standard OCI artifact (Helm 3.8+)
+	HelmChartOCIContentLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
 )
 
 // KnownMediaTypes returns a list of layer mediaTypes that the Helm client knows about
@@ -29,5 +32,11 @@ func KnownMediaTypes() []string {
 	return []string{
 		HelmChartConfigMediaType,
 		HelmChartContentLayerMediaType,
+		HelmChartOCIContentLayerMediaType,
 	}
 }
+
+// IsHelmChartContentLayerMediaType returns true for both the legacy and the OCI artifact chart content media types
+func IsHelmChartContentLayerMediaType(mediaType string) bool {
+	return mediaType == HelmChartContentLayerMediaType || mediaType == HelmChartOCIContentLayerMediaType
+}
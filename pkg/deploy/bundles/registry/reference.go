@@ -27,10 +27,7 @@ import (
 
 var (
 	validPortRegEx = regexp.MustCompile(`^([1-9]\d{0,3}|0|[1-5][0-9]{4}|6[0-4][0-9]{3}|65[0-4][0-9]{2}|655[0-2][0-9]|6553[0-5])$`) // adapted from https://stackoverflow.com/a/12968117
-	// TODO: Currently we don't support digests, so we are only splitting on the
-	// colon. However, when we add support for digests, we'll need to use the
-	// regexp anyway to split on both colons and @, so leaving it like this for
-	// now
+	// The digest is split off on the @ before splitting on the colon
 	referenceDelimiter = regexp.MustCompile(`[:]`)
 	errEmptyRepo       = errors.New("parsed repo was empty")
 	errTooManyColons   = errors.New("ref may only contain a single colon character (:) unless specifying a port number")
@@ -41,6 +38,8 @@ type (
 	Reference struct {
 		Tag  string
 		Repo string
+		// Digest pins the manifest the tag points to (REPO:TAG@DIGEST)
+		Digest string
 	}
 )
 
@@ -49,7 +48,12 @@ func ParseReference(s string) (*Reference, error) {
 	if s == "" {
 		return nil, errEmptyRepo
 	}
-	// Split the components of the string on the colon or @, if it is more than 3,
+
+	var digest string
+	if i := strings.LastIndex(s, "@"); i != -1 {
+		s, digest = s[:i], s[i+1:]
+	}
+	// Split the components of the string on the colon, if it is more than 3,
 	// immediately return an error. Other validation will be performed later in
 	// the function
 	splitComponents := fixSplitComponents(referenceDelimiter.Split(s, -1))
@@ -67,6 +71,8 @@ func ParseReference(s string) (*Reference, error) {
 		ref = &Reference{Repo: strings.Join(splitComponents[:2], ":"), Tag: splitComponents[2]}
 	}
 
+	ref.Digest = digest
+
 	// ensure the reference is valid
 	err := ref.validate()
 	if err != nil {
@@ -76,12 +82,16 @@ func ParseReference(s string) (*Reference, error) {
 	return ref, nil
 }
 
-// FullName the full name of a reference (repo:tag)
+// FullName the full name of a reference (repo:tag or repo:tag@digest)
 func (ref *Reference) FullName() string {
-	if ref.Tag == "" {
-		return ref.Repo
+	name := ref.Repo
+	if ref.Tag != "" {
+		name = fmt.Sprintf("%s:%s", name, ref.Tag)
+	}
+	if ref.Digest != "" {
+		name = fmt.Sprintf("%s@%s", name, ref.Digest)
 	}
-	return fmt.Sprintf("%s:%s", ref.Repo, ref.Tag)
+	return name
 }
 
 // validate makes sure the ref meets our criteria
@@ -24,6 +24,8 @@ const (
 
 	StageWeightAnnoName = "werf.io/weight"
 
+	SkipAutoRollbackAnnoName = "werf.io/skip-auto-rollback"
+
 	ExternalDependencyResourceAnnoName  = "external-dependency.werf.io/resource"
 	ExternalDependencyNamespaceAnnoName = "external-dependency.werf.io/namespace"
 )
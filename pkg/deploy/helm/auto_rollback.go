@@ -0,0 +1,234 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	helm_kube "helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/phases"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"github.com/werf/logboek"
+)
+
+type AutoRollbackPolicy string
+
+const (
+	// AutoRollbackOnFailure rolls back the release on any failure of the deploy process
+	AutoRollbackOnFailure AutoRollbackPolicy = "on-failure"
+	// AutoRollbackOnTrackingFailure rolls back the release only if the resources tracking fails or times out
+	AutoRollbackOnTrackingFailure AutoRollbackPolicy = "on-tracking-failure"
+	// AutoRollbackOnTrackingTimeout rolls back the release only if the resources tracking times out
+	AutoRollbackOnTrackingTimeout AutoRollbackPolicy = "on-tracking-timeout"
+)
+
+func ParseAutoRollbackPolicy(value string) (AutoRollbackPolicy, error) {
+	switch policy := AutoRollbackPolicy(value); policy {
+	case AutoRollbackOnFailure, AutoRollbackOnTrackingFailure, AutoRollbackOnTrackingTimeout:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unsupported auto rollback policy %q: expected %q, %q or %q", value, AutoRollbackOnFailure, AutoRollbackOnTrackingFailure, AutoRollbackOnTrackingTimeout)
+	}
+}
+
+// TrackingFailure is the failure of the resources tracking
+type TrackingFailure struct {
+	Err     error
+	Timeout bool
+	// AutoRollbackSkipped is true if only the resources with the werf.io/skip-auto-rollback annotation failed
+	AutoRollbackSkipped bool
+}
+
+func newTrackingFailure(err error, autoRollbackSkipped bool) *TrackingFailure {
+	return &TrackingFailure{
+		Err:                 err,
+		Timeout:             isTrackingTimeout(err),
+		AutoRollbackSkipped: autoRollbackSkipped,
+	}
+}
+
+// kubedogNoActivityTimeoutReason is the failure reason kubedog reports for the resources without activity for the werf.io/no-activity-timeout
+const kubedogNoActivityTimeoutReason = "marking resource as failed because no activity for"
+
+// isTrackingTimeout returns true if the tracking has failed on the --timeout, on the timeout of the kubedog watches or on the no activity timeout.
+// The no activity timeout is reported by kubedog as the failure reason of the resource, so it is only detected by the message
+func isTrackingTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, wait.ErrWaitTimeout) || strings.Contains(err.Error(), kubedogNoActivityTimeoutReason)
+}
+
+// ShouldRollback returns whether the failed release should be rolled back and the reason of the decision.
+// The tracking failure is nil if the deploy process has failed not during the resources tracking
+func (policy AutoRollbackPolicy) ShouldRollback(failure *TrackingFailure) (bool, string) {
+	if failure != nil && failure.AutoRollbackSkipped {
+		return false, fmt.Sprintf("only resources with %s annotation have failed", SkipAutoRollbackAnnoName)
+	}
+
+	switch {
+	case failure != nil && failure.Timeout:
+		return true, "resources tracking has timed out"
+	case failure != nil && policy != AutoRollbackOnTrackingTimeout:
+		return true, "resources tracking has failed"
+	case failure == nil && policy == AutoRollbackOnFailure:
+		return true, "deploy process has failed"
+	case failure != nil:
+		return false, fmt.Sprintf("resources tracking has failed without timeout, rollback is not allowed by %q policy", policy)
+	default:
+		return false, fmt.Sprintf("deploy process has failed not during resources tracking, rollback is not allowed by %q policy", policy)
+	}
+}
+
+type RollbackStatus string
+
+const (
+	RollbackSucceeded RollbackStatus = "succeeded"
+	RollbackFailed    RollbackStatus = "failed"
+	RollbackSkipped   RollbackStatus = "skipped"
+	// RollbackUninstalled is the status of the failed release without successful revisions, such release is uninstalled as helm does with --atomic
+	RollbackUninstalled RollbackStatus = "uninstalled"
+)
+
+// RollbackReport is the result of the auto rollback saved into the deploy report
+type RollbackReport struct {
+	Status RollbackStatus `json:"status"`
+	Reason string         `json:"reason,omitempty"`
+	// Revision is the last successful revision the release is rolled back to
+	Revision int    `json:"revision,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type AutoRollbackOptions struct {
+	Policy                      AutoRollbackPolicy
+	Timeout                     time.Duration
+	StagesExternalDepsGenerator phases.ExternalDepsGenerator
+}
+
+// AutoRollback rolls back the failed release to the last successful revision if the failure matches the policy.
+// The failed release without successful revisions (e.g. the failed first install) is uninstalled
+func AutoRollback(ctx context.Context, actionConfig *action.Configuration, releaseName string, opts AutoRollbackOptions) *RollbackReport {
+	var trackingFailure *TrackingFailure
+	if waiter := getResourcesWaiter(actionConfig); waiter != nil {
+		trackingFailure = waiter.TrackingFailure
+	}
+
+	shouldRollback, reason := opts.Policy.ShouldRollback(trackingFailure)
+	if !shouldRollback {
+		logboek.Context(ctx).Warn().LogF("Release %q is not rolled back: %s\n", releaseName, reason)
+		return &RollbackReport{Status: RollbackSkipped, Reason: reason}
+	}
+
+	revision, err := getLastSuccessfulRevision(actionConfig, releaseName)
+	if err != nil {
+		return &RollbackReport{Status: RollbackFailed, Reason: reason, Error: err.Error()}
+	} else if revision == 0 {
+		return uninstallFailedRelease(ctx, actionConfig, releaseName, reason, opts)
+	}
+
+	rollback := action.NewRollback(actionConfig, NewStagesSplitter(), opts.StagesExternalDepsGenerator)
+	rollback.Version = revision
+	rollback.Wait = true
+	rollback.Timeout = opts.Timeout
+	rollback.CleanupOnFail = true
+
+	if err := logboek.Context(ctx).Default().LogProcess("Rolling back release %q to revision %d: %s", releaseName, revision, reason).DoError(func() error {
+		return rollback.Run(releaseName)
+	}); err != nil {
+		return &RollbackReport{Status: RollbackFailed, Reason: reason, Revision: revision, Error: err.Error()}
+	}
+
+	return &RollbackReport{Status: RollbackSucceeded, Reason: reason, Revision: revision}
+}
+
+func uninstallFailedRelease(ctx context.Context, actionConfig *action.Configuration, releaseName, reason string, opts AutoRollbackOptions) *RollbackReport {
+	reason = fmt.Sprintf("%s, there is no successful revision", reason)
+
+	uninstall := action.NewUninstall(actionConfig, NewStagesSplitter())
+	uninstall.KeepHistory = false
+	uninstall.Timeout = opts.Timeout
+
+	if err := logboek.Context(ctx).Default().LogProcess("Uninstalling release %q: %s", releaseName, reason).DoError(func() error {
+		_, err := uninstall.Run(releaseName)
+		return err
+	}); err != nil {
+		return &RollbackReport{Status: RollbackFailed, Reason: reason, Error: err.Error()}
+	}
+
+	return &RollbackReport{Status: RollbackUninstalled, Reason: reason}
+}
+
+// WriteRollbackReport adds the rollback result to the deploy report saved by the failed deploy process
+func WriteRollbackReport(deployReportPath string, report *RollbackReport) error {
+	deployReport := struct {
+		*release.DeployReport
+		Rollback *RollbackReport `json:"rollback"`
+	}{DeployReport: release.NewDeployReport(), Rollback: report}
+
+	data, err := os.ReadFile(deployReportPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read deploy report %q: %w", deployReportPath, err)
+	} else if err == nil {
+		if err := json.Unmarshal(data, deployReport.DeployReport); err != nil {
+			return fmt.Errorf("unable to unmarshal deploy report %q: %w", deployReportPath, err)
+		}
+	}
+
+	data, err = json.MarshalIndent(deployReport, "", "\t")
+	if err != nil {
+		return fmt.Errorf("error marshalling deploy report: %w", err)
+	}
+
+	if err := os.WriteFile(deployReportPath, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write deploy report %q: %w", deployReportPath, err)
+	}
+
+	return nil
+}
+
+// getLastSuccessfulRevision returns 0 if there is no successful revision of the release
+func getLastSuccessfulRevision(actionConfig *action.Configuration, releaseName string) (int, error) {
+	history, err := actionConfig.Releases.History(releaseName)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get release %q history: %w", releaseName, err)
+	}
+
+	// failed releases are not superseded unless the next release is successful
+	successfulHistory := releaseutil.FilterFunc(func(rel *release.Release) bool {
+		return rel.Info.Status == release.StatusSuperseded || rel.Info.Status == release.StatusDeployed
+	}).Filter(history)
+	if len(successfulHistory) == 0 {
+		return 0, nil
+	}
+
+	releaseutil.Reverse(successfulHistory, releaseutil.SortByRevision)
+	return successfulHistory[0].Version, nil
+}
+
+func getResourcesWaiter(actionConfig *action.Configuration) *ResourcesWaiter {
+	kubeClient, ok := actionConfig.KubeClient.(*helm_kube.Client)
+	if !ok {
+		return nil
+	}
+
+	waiter, _ := kubeClient.ResourcesWaiter.(*ResourcesWaiter)
+	return waiter
+}
+
+func isAutoRollbackSkipped(info *resource.Info) bool {
+	accessor, err := meta.Accessor(info.Object)
+	if err != nil {
+		return false
+	}
+
+	skip, _ := strconv.ParseBool(accessor.GetAnnotations()[SkipAutoRollbackAnnoName])
+	return skip
+}
@@ -0,0 +1,143 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var _ = Describe("AutoRollbackPolicy", func() {
+	trackingFailure := newTrackingFailure(fmt.Errorf("deploy/app failed: pods are not ready"), false)
+	trackingTimeout := newTrackingFailure(fmt.Errorf("deploy/app track failed: %w", context.DeadlineExceeded), false)
+	skippedTrackingTimeout := newTrackingFailure(fmt.Errorf("deploy/app track failed: %w", context.DeadlineExceeded), true)
+	watchTimeout := newTrackingFailure(fmt.Errorf("job/migrate track failed: %w", wait.ErrWaitTimeout), false)
+	noActivityTimeout := newTrackingFailure(fmt.Errorf("cronjob/backup failed: marking resource as failed because no activity for 4m0s"), false)
+
+	DescribeTable("deciding whether to roll back the failed release",
+		func(policy AutoRollbackPolicy, failure *TrackingFailure, expectedRollback bool) {
+			shouldRollback, reason := policy.ShouldRollback(failure)
+			Expect(shouldRollback).To(Equal(expectedRollback))
+			Expect(reason).NotTo(BeEmpty())
+		},
+		Entry("on-failure: deploy process failure", AutoRollbackOnFailure, nil, true),
+		Entry("on-failure: tracking failure", AutoRollbackOnFailure, trackingFailure, true),
+		Entry("on-failure: tracking failure of the skipped resources", AutoRollbackOnFailure, skippedTrackingTimeout, false),
+		Entry("on-tracking-failure: deploy process failure", AutoRollbackOnTrackingFailure, nil, false),
+		Entry("on-tracking-failure: tracking failure", AutoRollbackOnTrackingFailure, trackingFailure, true),
+		Entry("on-tracking-failure: tracking timeout", AutoRollbackOnTrackingFailure, trackingTimeout, true),
+		Entry("on-tracking-timeout: tracking failure", AutoRollbackOnTrackingTimeout, trackingFailure, false),
+		Entry("on-tracking-timeout: tracking timeout", AutoRollbackOnTrackingTimeout, trackingTimeout, true),
+		Entry("on-tracking-timeout: tracking timeout of the skipped resources", AutoRollbackOnTrackingTimeout, skippedTrackingTimeout, false),
+		Entry("on-tracking-timeout: kubedog watch timeout", AutoRollbackOnTrackingTimeout, watchTimeout, true),
+		Entry("on-tracking-timeout: kubedog no activity timeout", AutoRollbackOnTrackingTimeout, noActivityTimeout, true),
+	)
+
+	It("should fail to parse unsupported policy", func() {
+		_, err := ParseAutoRollbackPolicy("always")
+		Expect(err).To(MatchError(ContainSubstring(`unsupported auto rollback policy "always"`)))
+
+		policy, err := ParseAutoRollbackPolicy("on-tracking-timeout")
+		Expect(err).To(Succeed())
+		Expect(policy).To(Equal(AutoRollbackOnTrackingTimeout))
+	})
+})
+
+var _ = Describe("getLastSuccessfulRevision", func() {
+	It("should return the last deployed or superseded revision", func() {
+		actionConfig := &action.Configuration{Releases: storage.Init(driver.NewMemory())}
+		for version, status := range []release.Status{release.StatusSuperseded, release.StatusSuperseded, release.StatusFailed, release.StatusFailed} {
+			Expect(actionConfig.Releases.Create(&release.Release{Name: "app", Version: version + 1, Info: &release.Info{Status: status}})).To(Succeed())
+		}
+
+		revision, err := getLastSuccessfulRevision(actionConfig, "app")
+		Expect(err).To(Succeed())
+		Expect(revision).To(Equal(2))
+	})
+
+	It("should return 0 if there is no successful revision", func() {
+		actionConfig := &action.Configuration{Releases: storage.Init(driver.NewMemory())}
+		Expect(actionConfig.Releases.Create(&release.Release{Name: "app", Version: 1, Info: &release.Info{Status: release.StatusFailed}})).To(Succeed())
+
+		revision, err := getLastSuccessfulRevision(actionConfig, "app")
+		Expect(err).To(Succeed())
+		Expect(revision).To(Equal(0))
+	})
+})
+
+// newFailedReleaseActionConfig returns the action config with the failed first revision of the release and the fake kube client
+func newFailedReleaseActionConfig(releaseName string) *action.Configuration {
+	actionConfig := &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(string, ...interface{}) {},
+	}
+
+	Expect(actionConfig.Releases.Create(&release.Release{
+		Name:      releaseName,
+		Namespace: "app-production",
+		Version:   1,
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: releaseName, Version: "1.0.0"}},
+		Info:      &release.Info{Status: release.StatusFailed},
+	})).To(Succeed())
+
+	return actionConfig
+}
+
+var _ = Describe("AutoRollback", func() {
+	It("should uninstall the failed release without successful revisions", func() {
+		actionConfig := newFailedReleaseActionConfig("app")
+
+		report := AutoRollback(context.Background(), actionConfig, "app", AutoRollbackOptions{Policy: AutoRollbackOnFailure})
+		Expect(report.Error).To(BeEmpty())
+		Expect(report.Status).To(Equal(RollbackUninstalled))
+		Expect(report.Reason).To(Equal("deploy process has failed, there is no successful revision"))
+
+		_, err := actionConfig.Releases.History("app")
+		Expect(err).To(MatchError(driver.ErrReleaseNotFound))
+	})
+
+	It("should not uninstall the failed release if the failure does not match the policy", func() {
+		actionConfig := newFailedReleaseActionConfig("app")
+
+		report := AutoRollback(context.Background(), actionConfig, "app", AutoRollbackOptions{Policy: AutoRollbackOnTrackingFailure})
+		Expect(report.Status).To(Equal(RollbackSkipped))
+
+		history, err := actionConfig.Releases.History("app")
+		Expect(err).To(Succeed())
+		Expect(history).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("WriteRollbackReport", func() {
+	It("should add the rollback result to the deploy report", func() {
+		deployReportPath := filepath.Join(GinkgoT().TempDir(), "deploy-report.json")
+		Expect(os.WriteFile(deployReportPath, []byte(`{"release": "app", "namespace": "app-production", "revision": 3, "status": "failed"}`), 0o644)).To(Succeed())
+
+		Expect(WriteRollbackReport(deployReportPath, &RollbackReport{Status: RollbackSucceeded, Reason: "resources tracking has timed out", Revision: 2})).To(Succeed())
+
+		data, err := os.ReadFile(deployReportPath)
+		Expect(err).To(Succeed())
+		Expect(string(data)).To(ContainSubstring(`"release": "app"`))
+		Expect(string(data)).To(ContainSubstring(`"revision": 3`))
+		Expect(string(data)).To(ContainSubstring(`"status": "failed"`))
+		Expect(string(data)).To(ContainSubstring(`"rollback": {
+		"status": "succeeded",
+		"reason": "resources tracking has timed out",
+		"revision": 2
+	}`))
+	})
+})
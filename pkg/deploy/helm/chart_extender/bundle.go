@@ -30,6 +30,8 @@ type BundleOptions struct {
 	ExtraLabels                       map[string]string
 	IgnoreInvalidAnnotationsAndLabels bool
 	DisableDefaultValues              bool
+	Environment                       string
+	SecretBackend                     *secrets_manager.SecretBackendConfig
 }
 
 func NewBundle(ctx context.Context, dir string, helmEnvSettings *cli.EnvSettings, registryClient *registry.Client, secretsManager *secrets_manager.SecretsManager, opts BundleOptions) (*Bundle, error) {
@@ -43,6 +45,8 @@ func NewBundle(ctx context.Context, dir string, helmEnvSettings *cli.EnvSettings
 		ChartExtenderContextData:       helpers.NewChartExtenderContextData(ctx),
 		secretsManager:                 secretsManager,
 		DisableDefaultValues:           opts.DisableDefaultValues,
+		Environment:                    opts.Environment,
+		SecretBackend:                  opts.SecretBackend,
 	}
 
 	extraAnnotationsAndLabelsPostRenderer := helm.NewExtraAnnotationsAndLabelsPostRenderer(nil, nil, opts.IgnoreInvalidAnnotationsAndLabels)
@@ -78,6 +82,8 @@ type Bundle struct {
 	RegistryClient             *registry.Client
 	BuildChartDependenciesOpts command_helpers.BuildChartDependenciesOptions
 	DisableDefaultValues       bool
+	Environment                string
+	SecretBackend              *secrets_manager.SecretBackendConfig
 
 	extraAnnotationsAndLabelsPostRenderer *helm.ExtraAnnotationsAndLabelsPostRenderer
 	secretsManager                        *secrets_manager.SecretsManager
@@ -119,6 +125,8 @@ func (bundle *Bundle) ChartLoaded(files []*chart.ChartExtenderBufferedFile) erro
 			LoadFromLocalFilesystem:    true,
 			CustomSecretValueFiles:     bundle.SecretValueFiles,
 			WithoutDefaultSecretValues: false,
+			Environment:                bundle.Environment,
+			SecretBackend:              bundle.SecretBackend,
 		}); err != nil {
 			return fmt.Errorf("error decoding secrets: %w", err)
 		}
@@ -2,6 +2,7 @@ package secrets
 
 import (
 	"fmt"
+	"path"
 	"path/filepath"
 	"strings"
 	"unicode"
@@ -10,6 +11,7 @@ import (
 	"helm.sh/helm/v3/pkg/chartutil"
 	"sigs.k8s.io/yaml"
 
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
 	"github.com/werf/werf/pkg/secret"
 	"github.com/werf/werf/pkg/util"
 )
@@ -29,11 +31,37 @@ func GetDefaultSecretValuesFile(loadedChartFiles []*chart.ChartExtenderBufferedF
 	return nil
 }
 
+// GetSecretBackendConfig returns nil config (the default aes backend) if the chart has no secret backend config file.
+// The chart can be pulled from the untrusted source, so the commands of the config are not executed
+func GetSecretBackendConfig(chartDir string, loadedChartFiles []*chart.ChartExtenderBufferedFile) (*secrets_manager.SecretBackendConfig, error) {
+	for _, file := range loadedChartFiles {
+		if file.Name != secrets_manager.SecretBackendConfigFileName {
+			continue
+		}
+
+		config, err := secrets_manager.ParseSecretBackendConfig(file.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret backend config %q: %w", filepath.Join(chartDir, file.Name), err)
+		}
+
+		return config, nil
+	}
+
+	return nil, nil
+}
+
+// EnvironmentSecretDirName returns the dir of the environment-scoped secret files (secret/werfEnvironments/<env>),
+// these files override the secret files with the same relative path for the environment
+func EnvironmentSecretDirName(env string) string {
+	return path.Join(SecretDirName, secret.EnvironmentsYamlKey, env)
+}
+
+// GetSecretDirFiles returns the secret files excluding the environment-scoped secret files
 func GetSecretDirFiles(loadedChartFiles []*chart.ChartExtenderBufferedFile) []*chart.ChartExtenderBufferedFile {
 	var res []*chart.ChartExtenderBufferedFile
 
 	for _, file := range loadedChartFiles {
-		if !util.IsSubpathOfBasePath(SecretDirName, file.Name) {
+		if !util.IsSubpathOfBasePath(SecretDirName, file.Name) || util.IsSubpathOfBasePath(path.Join(SecretDirName, secret.EnvironmentsYamlKey), file.Name) {
 			continue
 		}
 		res = append(res, file)
@@ -42,6 +70,50 @@ func GetSecretDirFiles(loadedChartFiles []*chart.ChartExtenderBufferedFile) []*c
 	return res
 }
 
+func GetEnvironmentSecretDirFiles(loadedChartFiles []*chart.ChartExtenderBufferedFile, env string) []*chart.ChartExtenderBufferedFile {
+	var res []*chart.ChartExtenderBufferedFile
+
+	for _, file := range loadedChartFiles {
+		if !util.IsSubpathOfBasePath(EnvironmentSecretDirName(env), file.Name) {
+			continue
+		}
+		res = append(res, file)
+	}
+
+	return res
+}
+
+// SplitSecretValueFiles returns the secret values files without the environment-scoped sections and
+// the files with the section of the specified environment (werfEnvironments.<env>), the sections of other environments are skipped.
+// The files without the common secret values are skipped, so that the secret key is not required for the environment-scoped secrets only
+func SplitSecretValueFiles(chartDir string, secretValuesFiles []*chart.ChartExtenderBufferedFile, env string) ([]*chart.ChartExtenderBufferedFile, []*chart.ChartExtenderBufferedFile, error) {
+	var commonFiles, envFiles []*chart.ChartExtenderBufferedFile
+
+	for _, file := range secretValuesFiles {
+		commonData, environments, err := secret.SplitEnvironmentsYamlData(file.Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot parse secret values file %s: %w", filepath.Join(chartDir, file.Name), err)
+		}
+
+		var commonValues map[string]interface{}
+		if err := yaml.Unmarshal(commonData, &commonValues); err != nil {
+			return nil, nil, fmt.Errorf("cannot unmarshal secret values file %s: %w", filepath.Join(chartDir, file.Name), err)
+		}
+
+		if len(commonValues) > 0 {
+			commonFiles = append(commonFiles, &chart.ChartExtenderBufferedFile{Name: file.Name, Data: commonData})
+		}
+
+		for _, environment := range environments {
+			if env != "" && environment.Environment == env {
+				envFiles = append(envFiles, &chart.ChartExtenderBufferedFile{Name: file.Name, Data: environment.Data})
+			}
+		}
+	}
+
+	return commonFiles, envFiles, nil
+}
+
 func LoadChartSecretValueFiles(chartDir string, secretDirFiles []*chart.ChartExtenderBufferedFile, encoder *secret.YamlEncoder) (map[string]interface{}, error) {
 	var res map[string]interface{}
 
@@ -62,11 +134,12 @@ func LoadChartSecretValueFiles(chartDir string, secretDirFiles []*chart.ChartExt
 	return res, nil
 }
 
-func LoadChartSecretDirFilesData(chartDir string, secretFiles []*chart.ChartExtenderBufferedFile, encoder *secret.YamlEncoder) (map[string]string, error) {
+// LoadChartSecretDirFilesData returns the decrypted data of the secret files by the path relative to the secretDirName
+func LoadChartSecretDirFilesData(chartDir, secretDirName string, secretFiles []*chart.ChartExtenderBufferedFile, encoder *secret.YamlEncoder) (map[string]string, error) {
 	res := make(map[string]string)
 
 	for _, file := range secretFiles {
-		if !util.IsSubpathOfBasePath(SecretDirName, file.Name) {
+		if !util.IsSubpathOfBasePath(secretDirName, file.Name) {
 			continue
 		}
 
@@ -75,7 +148,7 @@ func LoadChartSecretDirFilesData(chartDir string, secretFiles []*chart.ChartExte
 			return nil, fmt.Errorf("error decoding %s: %w", filepath.Join(chartDir, file.Name), err)
 		}
 
-		relPath := util.GetRelativeToBaseFilepath(SecretDirName, file.Name)
+		relPath := util.GetRelativeToBaseFilepath(secretDirName, file.Name)
 		res[filepath.ToSlash(relPath)] = string(decodedData)
 	}
 
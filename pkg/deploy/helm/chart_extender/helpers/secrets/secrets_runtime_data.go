@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io/ioutil"
 
+	"github.com/mitchellh/copystructure"
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"sigs.k8s.io/yaml"
 
 	"github.com/werf/werf/pkg/deploy/secrets_manager"
@@ -18,6 +20,12 @@ type SecretsRuntimeData struct {
 	DecryptedSecretValues    map[string]interface{}
 	DecryptedSecretFilesData map[string]string
 	SecretValuesToMask       []string
+	SecretBackend            *secrets_manager.SecretBackendConfig
+
+	// environment of the environment-scoped secrets (werfEnvironments) merged into DecryptedSecretValues and DecryptedSecretFilesData
+	environment                      string
+	decryptedCommonSecretValues      map[string]interface{}
+	decryptedEnvironmentSecretValues map[string]interface{}
 }
 
 func NewSecretsRuntimeData() *SecretsRuntimeData {
@@ -31,11 +39,28 @@ type DecodeAndLoadSecretsOptions struct {
 	CustomSecretValueFiles     []string
 	LoadFromLocalFilesystem    bool
 	WithoutDefaultSecretValues bool
+	Environment                string
+	// SecretBackend is the secret backend config specified explicitly by the user, it is used instead of the secret backend config of the chart
+	SecretBackend *secrets_manager.SecretBackendConfig
 }
 
 func (secretsRuntimeData *SecretsRuntimeData) DecodeAndLoadSecrets(ctx context.Context, loadedChartFiles []*chart.ChartExtenderBufferedFile, chartDir, secretsWorkingDir string, secretsManager *secrets_manager.SecretsManager, opts DecodeAndLoadSecretsOptions) error {
 	secretDirFiles := GetSecretDirFiles(loadedChartFiles)
 
+	var envSecretDirFiles []*chart.ChartExtenderBufferedFile
+	if opts.Environment != "" {
+		envSecretDirFiles = GetEnvironmentSecretDirFiles(loadedChartFiles, opts.Environment)
+	}
+	secretsRuntimeData.environment = opts.Environment
+
+	if opts.SecretBackend != nil {
+		secretsRuntimeData.SecretBackend = opts.SecretBackend
+	} else if backend, err := GetSecretBackendConfig(chartDir, loadedChartFiles); err != nil {
+		return err
+	} else {
+		secretsRuntimeData.SecretBackend = backend
+	}
+
 	var loadedSecretValuesFiles []*chart.ChartExtenderBufferedFile
 
 	if !opts.WithoutDefaultSecretValues {
@@ -64,9 +89,14 @@ func (secretsRuntimeData *SecretsRuntimeData) DecodeAndLoadSecrets(ctx context.C
 		loadedSecretValuesFiles = append(loadedSecretValuesFiles, file)
 	}
 
+	secretValuesFiles, envSecretValuesFiles, err := SplitSecretValueFiles(chartDir, loadedSecretValuesFiles, opts.Environment)
+	if err != nil {
+		return fmt.Errorf("error loading secret value files: %w", err)
+	}
+
 	var encoder *secret.YamlEncoder
-	if len(secretDirFiles)+len(loadedSecretValuesFiles) > 0 {
-		if enc, err := secretsManager.GetYamlEncoder(ctx, secretsWorkingDir); err != nil {
+	if len(secretDirFiles)+len(secretValuesFiles) > 0 {
+		if enc, err := secretsManager.GetYamlEncoder(ctx, secretsWorkingDir, secretsRuntimeData.SecretBackend); err != nil {
 			return fmt.Errorf("error getting secrets yaml encoder: %w", err)
 		} else {
 			encoder = enc
@@ -74,43 +104,138 @@ func (secretsRuntimeData *SecretsRuntimeData) DecodeAndLoadSecrets(ctx context.C
 	}
 
 	if len(secretDirFiles) > 0 {
-		if data, err := LoadChartSecretDirFilesData(chartDir, secretDirFiles, encoder); err != nil {
+		if data, err := LoadChartSecretDirFilesData(chartDir, SecretDirName, secretDirFiles, encoder); err != nil {
 			return fmt.Errorf("error loading secret files data: %w", err)
 		} else {
 			secretsRuntimeData.DecryptedSecretFilesData = data
-			for _, fileData := range secretsRuntimeData.DecryptedSecretFilesData {
-				secretsRuntimeData.SecretValuesToMask = append(secretsRuntimeData.SecretValuesToMask, fileData)
-			}
 		}
 	}
 
-	if len(loadedSecretValuesFiles) > 0 {
-		if values, err := LoadChartSecretValueFiles(chartDir, loadedSecretValuesFiles, encoder); err != nil {
+	if len(secretValuesFiles) > 0 {
+		if values, err := LoadChartSecretValueFiles(chartDir, secretValuesFiles, encoder); err != nil {
 			return fmt.Errorf("error loading secret value files: %w", err)
 		} else {
-			secretsRuntimeData.DecryptedSecretValues = values
-			secretsRuntimeData.SecretValuesToMask = append(secretsRuntimeData.SecretValuesToMask, secretvalues.ExtractSecretValuesFromMap(values)...)
+			secretsRuntimeData.decryptedCommonSecretValues = values
 		}
 	}
 
+	// The environment is specified explicitly for the deploy, so the secrets of the environment are required
+	// and the missing secret key of the environment is an error (only the edit and check commands skip such secrets)
+	if len(envSecretDirFiles)+len(envSecretValuesFiles) > 0 {
+		if err := secretsRuntimeData.decodeAndLoadEnvironmentSecrets(ctx, chartDir, secretsWorkingDir, secretsManager, envSecretDirFiles, envSecretValuesFiles); err != nil {
+			return err
+		}
+	}
+
+	if values, err := mergeSecretValues(secretsRuntimeData.decryptedCommonSecretValues, secretsRuntimeData.decryptedEnvironmentSecretValues); err != nil {
+		return err
+	} else {
+		secretsRuntimeData.DecryptedSecretValues = values
+	}
+
+	for _, fileData := range secretsRuntimeData.DecryptedSecretFilesData {
+		secretsRuntimeData.SecretValuesToMask = append(secretsRuntimeData.SecretValuesToMask, fileData)
+	}
+	secretsRuntimeData.SecretValuesToMask = append(secretsRuntimeData.SecretValuesToMask, secretvalues.ExtractSecretValuesFromMap(secretsRuntimeData.DecryptedSecretValues)...)
+
+	return nil
+}
+
+func (secretsRuntimeData *SecretsRuntimeData) decodeAndLoadEnvironmentSecrets(ctx context.Context, chartDir, secretsWorkingDir string, secretsManager *secrets_manager.SecretsManager, secretDirFiles, secretValuesFiles []*chart.ChartExtenderBufferedFile) error {
+	env := secretsRuntimeData.environment
+
+	encoder, err := secretsManager.GetYamlEncoderForEnvironment(ctx, secretsWorkingDir, secretsRuntimeData.SecretBackend, env)
+	if err != nil {
+		return fmt.Errorf("error getting environment %q secrets yaml encoder: %w", env, err)
+	}
+
+	filesData, err := LoadChartSecretDirFilesData(chartDir, EnvironmentSecretDirName(env), secretDirFiles, encoder)
+	if err != nil {
+		return fmt.Errorf("error loading environment %q secret files data: %w", env, err)
+	}
+
+	values, err := LoadChartSecretValueFiles(chartDir, secretValuesFiles, encoder)
+	if err != nil {
+		return fmt.Errorf("error loading environment %q secret values: %w", env, err)
+	}
+
+	if secretsRuntimeData.DecryptedSecretFilesData == nil {
+		secretsRuntimeData.DecryptedSecretFilesData = make(map[string]string)
+	}
+	for relPath, data := range filesData {
+		secretsRuntimeData.DecryptedSecretFilesData[relPath] = data
+	}
+	secretsRuntimeData.decryptedEnvironmentSecretValues = values
+
 	return nil
 }
 
+// mergeSecretValues returns the common secret values overridden by the environment-scoped secret values
+func mergeSecretValues(commonValues, envValues map[string]interface{}) (map[string]interface{}, error) {
+	if envValues == nil {
+		return commonValues, nil
+	}
+	if commonValues == nil {
+		return envValues, nil
+	}
+
+	var res []map[string]interface{}
+	for _, values := range []map[string]interface{}{envValues, commonValues} {
+		v, err := copystructure.Copy(values)
+		if err != nil {
+			return nil, fmt.Errorf("unable to copy secret values: %w", err)
+		}
+		res = append(res, v.(map[string]interface{}))
+	}
+
+	return chartutil.CoalesceTables(res[0], res[1]), nil
+}
+
 func (secretsRuntimeData *SecretsRuntimeData) GetEncodedSecretValues(ctx context.Context, secretsManager *secrets_manager.SecretsManager, secretsWorkingDir string) (map[string]interface{}, error) {
 	if len(secretsRuntimeData.DecryptedSecretValues) == 0 {
 		return nil, nil
 	}
 
+	encryptedData, err := encodeSecretValues(secretsRuntimeData.decryptedCommonSecretValues, func() (*secret.YamlEncoder, error) {
+		return secretsManager.GetYamlEncoder(ctx, secretsWorkingDir, secretsRuntimeData.SecretBackend)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The environment-scoped secret values are kept encrypted with the secret key of the environment
+	if len(secretsRuntimeData.decryptedEnvironmentSecretValues) > 0 {
+		encryptedEnvData, err := encodeSecretValues(secretsRuntimeData.decryptedEnvironmentSecretValues, func() (*secret.YamlEncoder, error) {
+			return secretsManager.GetYamlEncoderForEnvironment(ctx, secretsWorkingDir, secretsRuntimeData.SecretBackend, secretsRuntimeData.environment)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if encryptedData == nil {
+			encryptedData = make(map[string]interface{})
+		}
+		encryptedData[secret.EnvironmentsYamlKey] = map[string]interface{}{secretsRuntimeData.environment: encryptedEnvData}
+	}
+
+	return encryptedData, nil
+}
+
+func encodeSecretValues(values map[string]interface{}, getEncoderFunc func() (*secret.YamlEncoder, error)) (map[string]interface{}, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
 	// FIXME: secrets encoder should receive interface{} raw data instead of []byte yaml data
 
 	var encoder *secret.YamlEncoder
-	if enc, err := secretsManager.GetYamlEncoder(ctx, secretsWorkingDir); err != nil {
+	if enc, err := getEncoderFunc(); err != nil {
 		return nil, fmt.Errorf("error getting secrets yaml encoder: %w", err)
 	} else {
 		encoder = enc
 	}
 
-	decryptedSecretsData, err := yaml.Marshal(secretsRuntimeData.DecryptedSecretValues)
+	decryptedSecretsData, err := yaml.Marshal(values)
 	if err != nil {
 		return nil, fmt.Errorf("unable to marshal decrypted secrets yaml: %w", err)
 	}
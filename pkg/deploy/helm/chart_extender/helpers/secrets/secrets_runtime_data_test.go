@@ -0,0 +1,172 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+
+	"github.com/werf/werf/pkg/deploy/secrets_manager"
+	"github.com/werf/werf/pkg/secret"
+	"github.com/werf/werf/pkg/werf"
+)
+
+const (
+	testCommonSecretKey     = "bfd966688bbe64c1986a356be2d6ba0a"
+	testProductionSecretKey = "8a3cf3d4d3b3b77b1a31f9b5cf6e2e8c"
+)
+
+var _ = Describe("DecodeAndLoadSecrets", func() {
+	var ctx context.Context
+	var secretsManager *secrets_manager.SecretsManager
+	var workingDir string
+
+	setEnv := func(name, value string) {
+		prev, exists := os.LookupEnv(name)
+		Expect(os.Setenv(name, value)).To(Succeed())
+		DeferCleanup(func() {
+			if exists {
+				os.Setenv(name, prev)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+
+	// encrypt encrypts the data with the common secret key or the secret key of the environment
+	encrypt := func(env, data string) string {
+		var encoder *secret.YamlEncoder
+		var err error
+		if env == "" {
+			encoder, err = secretsManager.GetYamlEncoder(ctx, workingDir, nil)
+		} else {
+			encoder, err = secretsManager.GetYamlEncoderForEnvironment(ctx, workingDir, nil, env)
+		}
+		Expect(err).To(Succeed())
+
+		encrypted, err := encoder.Encrypt([]byte(data))
+		Expect(err).To(Succeed())
+
+		return string(encrypted)
+	}
+
+	// chartFiles returns the secret values with the common values and the values of the production and staging environments
+	// and the secret files with the file overridden for the production environment
+	chartFiles := func() []*chart.ChartExtenderBufferedFile {
+		secretValues := fmt.Sprintf(`
+db:
+  user: %s
+  password: %s
+werfEnvironments:
+  production:
+    db:
+      password: %s
+    token: %s
+  staging:
+    db:
+      password: 1000deadbeef
+`, encrypt("", "common-user"), encrypt("", "common-password"), encrypt("production", "production-password"), encrypt("production", "production-token"))
+
+		return []*chart.ChartExtenderBufferedFile{
+			{Name: DefaultSecretValuesFileName, Data: []byte(secretValues)},
+			{Name: "secret/tls.crt", Data: []byte(encrypt("", "common-crt"))},
+			{Name: "secret/tls.key", Data: []byte(encrypt("", "common-key"))},
+			{Name: "secret/werfEnvironments/production/tls.crt", Data: []byte(encrypt("production", "production-crt"))},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		secretsManager = secrets_manager.NewSecretsManager(secrets_manager.SecretsManagerOptions{})
+		workingDir = GinkgoT().TempDir()
+		Expect(werf.Init(GinkgoT().TempDir(), GinkgoT().TempDir())).To(Succeed())
+
+		setEnv("WERF_SECRET_KEY", testCommonSecretKey)
+		setEnv("WERF_SECRET_KEY_PRODUCTION", testProductionSecretKey)
+	})
+
+	It("should merge the secrets of the environment into the common secrets", func() {
+		files := chartFiles()
+
+		secretsRuntimeData := NewSecretsRuntimeData()
+		Expect(secretsRuntimeData.DecodeAndLoadSecrets(ctx, files, "", workingDir, secretsManager, DecodeAndLoadSecretsOptions{
+			Environment: "production",
+		})).To(Succeed())
+
+		Expect(secretsRuntimeData.DecryptedSecretValues).To(Equal(map[string]interface{}{
+			"db": map[string]interface{}{
+				"user":     "common-user",
+				"password": "production-password",
+			},
+			"token": "production-token",
+		}))
+		Expect(secretsRuntimeData.DecryptedSecretFilesData).To(Equal(map[string]string{
+			"tls.crt": "production-crt",
+			"tls.key": "common-key",
+		}))
+		Expect(secretsRuntimeData.SecretValuesToMask).To(ContainElements("common-user", "production-password", "production-token", "production-crt", "common-key"))
+	})
+
+	It("should load only the common secrets without the environment", func() {
+		files := chartFiles()
+		Expect(os.Unsetenv("WERF_SECRET_KEY_PRODUCTION")).To(Succeed())
+
+		secretsRuntimeData := NewSecretsRuntimeData()
+		Expect(secretsRuntimeData.DecodeAndLoadSecrets(ctx, files, "", workingDir, secretsManager, DecodeAndLoadSecretsOptions{})).To(Succeed())
+
+		Expect(secretsRuntimeData.DecryptedSecretValues).To(Equal(map[string]interface{}{
+			"db": map[string]interface{}{
+				"user":     "common-user",
+				"password": "common-password",
+			},
+		}))
+		Expect(secretsRuntimeData.DecryptedSecretFilesData).To(Equal(map[string]string{
+			"tls.crt": "common-crt",
+			"tls.key": "common-key",
+		}))
+	})
+
+	It("should load the secrets of the environment without the common secret key if there are no common secrets", func() {
+		files := []*chart.ChartExtenderBufferedFile{
+			{Name: DefaultSecretValuesFileName, Data: []byte(fmt.Sprintf("werfEnvironments:\n  production:\n    token: %s\n", encrypt("production", "production-token")))},
+		}
+		Expect(os.Unsetenv("WERF_SECRET_KEY")).To(Succeed())
+
+		secretsRuntimeData := NewSecretsRuntimeData()
+		Expect(secretsRuntimeData.DecodeAndLoadSecrets(ctx, files, "", workingDir, secretsManager, DecodeAndLoadSecretsOptions{
+			Environment: "production",
+		})).To(Succeed())
+
+		Expect(secretsRuntimeData.DecryptedSecretValues).To(Equal(map[string]interface{}{"token": "production-token"}))
+	})
+
+	It("should fail if the secret key of the specified environment is not found", func() {
+		files := chartFiles()
+		Expect(os.Unsetenv("WERF_SECRET_KEY_PRODUCTION")).To(Succeed())
+
+		secretsRuntimeData := NewSecretsRuntimeData()
+		err := secretsRuntimeData.DecodeAndLoadSecrets(ctx, files, "", workingDir, secretsManager, DecodeAndLoadSecretsOptions{
+			Environment: "production",
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(secrets_manager.IsEncryptionKeyRequiredError(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("WERF_SECRET_KEY_PRODUCTION"))
+	})
+
+	It("should fail on the invalid environment name", func() {
+		files := []*chart.ChartExtenderBufferedFile{
+			{Name: "secret/werfEnvironments/../../tls.crt", Data: []byte("1000deadbeef")},
+			{Name: DefaultSecretValuesFileName, Data: []byte("werfEnvironments:\n  ../../production:\n    token: 1000deadbeef\n")},
+		}
+
+		secretsRuntimeData := NewSecretsRuntimeData()
+		err := secretsRuntimeData.DecodeAndLoadSecrets(ctx, files, "", workingDir, secretsManager, DecodeAndLoadSecretsOptions{
+			Environment: "../../production",
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(`invalid environment name "../../production"`))
+	})
+})
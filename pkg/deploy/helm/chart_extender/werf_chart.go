@@ -41,6 +41,7 @@ type WerfChartOptions struct {
 	IgnoreInvalidAnnotationsAndLabels bool
 	DisableDefaultValues              bool
 	DisableDefaultSecretValues        bool
+	SecretBackend                     *secrets_manager.SecretBackendConfig
 }
 
 func NewWerfChart(ctx context.Context, giterminismManager giterminism_manager.Interface, secretsManager *secrets_manager.SecretsManager, chartDir string, helmEnvSettings *cli.EnvSettings, registryClient *registry.Client, opts WerfChartOptions) *WerfChart {
@@ -61,6 +62,7 @@ func NewWerfChart(ctx context.Context, giterminismManager giterminism_manager.In
 		DisableDefaultValues:       opts.DisableDefaultValues,
 		DisableDefaultSecretValues: opts.DisableDefaultSecretValues,
 		BuildChartDependenciesOpts: opts.BuildChartDependenciesOpts,
+		SecretBackend:              opts.SecretBackend,
 	}
 
 	wc.extraAnnotationsAndLabelsPostRenderer.Add(opts.ExtraAnnotations, opts.ExtraLabels)
@@ -84,6 +86,8 @@ type WerfChart struct {
 	BuildChartDependenciesOpts command_helpers.BuildChartDependenciesOptions
 	DisableDefaultValues       bool
 	DisableDefaultSecretValues bool
+	Environment                string
+	SecretBackend              *secrets_manager.SecretBackendConfig
 
 	GiterminismManager giterminism_manager.Interface
 	SecretsManager     *secrets_manager.SecretsManager
@@ -115,6 +119,8 @@ func (wc *WerfChart) ChartLoaded(files []*chart.ChartExtenderBufferedFile) error
 			GiterminismManager:         wc.GiterminismManager,
 			CustomSecretValueFiles:     wc.SecretValueFiles,
 			WithoutDefaultSecretValues: wc.DisableDefaultSecretValues,
+			Environment:                wc.Environment,
+			SecretBackend:              wc.SecretBackend,
 		}); err != nil {
 			return fmt.Errorf("error decoding secrets: %w", err)
 		}
@@ -240,6 +246,7 @@ func (wc *WerfChart) SetWerfConfig(werfConfig *config.WerfConfig) error {
 }
 
 func (wc *WerfChart) SetEnv(env string) error {
+	wc.Environment = env
 	wc.extraAnnotationsAndLabelsPostRenderer.Add(map[string]string{
 		"project.werf.io/env": env,
 	}, nil)
@@ -278,7 +285,7 @@ func (wc *WerfChart) CreateNewBundle(ctx context.Context, destDir, chartVersion
 	}
 
 	var secretValsData []byte
-	if wc.SecretsRuntimeData != nil && !wc.SecretsManager.IsMissedSecretKeyModeEnabled() {
+	if wc.SecretsRuntimeData != nil && (!wc.SecretsManager.IsMissedSecretKeyModeEnabled() || !wc.SecretsRuntimeData.SecretBackend.UsesSecretKey()) {
 		vals, err := wc.MakeBundleSecretValues(ctx, wc.SecretsRuntimeData)
 		if err != nil {
 			return nil, fmt.Errorf("unable to construct bundle secret values: %w", err)
@@ -425,6 +432,8 @@ WritingFiles:
 		BuildChartDependenciesOpts:        wc.BuildChartDependenciesOpts,
 		IgnoreInvalidAnnotationsAndLabels: wc.extraAnnotationsAndLabelsPostRenderer.IgnoreInvalidAnnotationsAndLabels,
 		DisableDefaultValues:              wc.DisableDefaultValues,
+		Environment:                       wc.Environment,
+		SecretBackend:                     wc.SecretBackend,
 	})
 }
 
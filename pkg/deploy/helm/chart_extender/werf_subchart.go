@@ -19,6 +19,7 @@ import (
 
 type WerfSubchartOptions struct {
 	DisableDefaultSecretValues bool
+	Environment                string
 }
 
 func NewWerfSubchart(ctx context.Context, secretsManager *secrets_manager.SecretsManager, opts WerfSubchartOptions) *WerfSubchart {
@@ -26,6 +27,7 @@ func NewWerfSubchart(ctx context.Context, secretsManager *secrets_manager.Secret
 		SecretsManager:             secretsManager,
 		ChartExtenderContextData:   helpers.NewChartExtenderContextData(ctx),
 		DisableDefaultSecretValues: opts.DisableDefaultSecretValues,
+		Environment:                opts.Environment,
 	}
 }
 
@@ -34,6 +36,7 @@ type WerfSubchart struct {
 	SecretsManager *secrets_manager.SecretsManager
 
 	DisableDefaultSecretValues bool
+	Environment                string
 
 	*secrets.SecretsRuntimeData
 	*helpers.ChartExtenderContextData
@@ -56,6 +59,7 @@ func (wc *WerfSubchart) ChartLoaded(files []*chart.ChartExtenderBufferedFile) er
 
 		if err := wc.SecretsRuntimeData.DecodeAndLoadSecrets(wc.ChartExtenderContext, files, "", "", wc.SecretsManager, secrets.DecodeAndLoadSecretsOptions{
 			WithoutDefaultSecretValues: wc.DisableDefaultSecretValues,
+			Environment:                wc.Environment,
 		}); err != nil {
 			return fmt.Errorf("error decoding secrets: %w", err)
 		}
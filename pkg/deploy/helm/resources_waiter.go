@@ -44,6 +44,9 @@ type ResourcesWaiter struct {
 	LogsFromTime              time.Time
 	StatusProgressPeriod      time.Duration
 	HooksStatusProgressPeriod time.Duration
+
+	// TrackingFailure is the failure of the last resources tracking, nil if the tracking succeeded
+	TrackingFailure *TrackingFailure
 }
 
 func NewResourcesWaiter(kubeInitializer KubeInitializer, client *helm_kube.Client, logsFromTime time.Time, statusProgressPeriod, hooksStatusProgressPeriod time.Duration) *ResourcesWaiter {
@@ -63,7 +66,11 @@ func extractSpecReplicas(specReplicas *int32) int {
 	return 1
 }
 
+// Wait tracks the resources until they become ready.
+// The resources with the werf.io/skip-auto-rollback annotation are tracked after the others, so that their failure is distinguishable
 func (waiter *ResourcesWaiter) Wait(ctx context.Context, resources helm_kube.ResourceList, timeout time.Duration) error {
+	waiter.TrackingFailure = nil
+
 	if os.Getenv("WERF_DISABLE_RESOURCES_WAITER") == "1" {
 		return nil
 	}
@@ -74,6 +81,37 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, resources helm_kube.Res
 		}
 	}
 
+	skippedResources := resources.Filter(isAutoRollbackSkipped)
+	if len(skippedResources) == 0 {
+		if err := waiter.track(ctx, resources, timeout); err != nil {
+			waiter.TrackingFailure = newTrackingFailure(err, false)
+			return err
+		}
+		return nil
+	}
+
+	startTime := time.Now()
+	if err := waiter.track(ctx, resources.Difference(skippedResources), timeout); err != nil {
+		waiter.TrackingFailure = newTrackingFailure(err, false)
+		return err
+	}
+
+	if timeout > 0 {
+		timeout -= time.Since(startTime)
+		if timeout < time.Second {
+			timeout = time.Second
+		}
+	}
+
+	if err := waiter.track(ctx, skippedResources, timeout); err != nil {
+		waiter.TrackingFailure = newTrackingFailure(err, true)
+		return err
+	}
+
+	return nil
+}
+
+func (waiter *ResourcesWaiter) track(ctx context.Context, resources helm_kube.ResourceList, timeout time.Duration) error {
 	specs, err := makeMultitrackSpecsFromResList(ctx, resources, timeout, waiter.StatusProgressPeriod)
 	if err != nil {
 		return fmt.Errorf("error making multitrack specs: %w", err)
@@ -0,0 +1,134 @@
+package plan
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// diffObjects returns the unified diff of the live and the planned objects, nil object is the absent one.
+// The fields managed by the cluster are ignored and the data of the secrets is masked
+func diffObjects(live, planned *unstructured.Unstructured) (string, error) {
+	if live != nil {
+		live = live.DeepCopy()
+		cleanObject(live)
+	}
+	if planned != nil {
+		planned = planned.DeepCopy()
+		cleanObject(planned)
+	}
+
+	if err := maskSecretData(live, planned); err != nil {
+		return "", err
+	}
+
+	liveData, err := marshalObject(live)
+	if err != nil {
+		return "", err
+	}
+
+	plannedData, err := marshalObject(planned)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(liveData),
+		B:        difflib.SplitLines(plannedData),
+		FromFile: "live",
+		ToFile:   "planned",
+		Context:  3,
+	})
+}
+
+func cleanObject(obj *unstructured.Unstructured) {
+	for _, field := range []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp", "selfLink"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+}
+
+// maskSecretData replaces the values of the secret data with *** keeping the changed values distinguishable:
+// the same values are masked as ***, the changed ones as *** (before) and *** (after)
+func maskSecretData(live, planned *unstructured.Unstructured) error {
+	if !isSecret(live) && !isSecret(planned) {
+		return nil
+	}
+
+	for _, field := range []string{"data", "stringData"} {
+		liveData, err := getSecretData(live, field)
+		if err != nil {
+			return err
+		}
+
+		plannedData, err := getSecretData(planned, field)
+		if err != nil {
+			return err
+		}
+
+		maskedLiveData := map[string]interface{}{}
+		for key, value := range liveData {
+			if plannedValue, ok := plannedData[key]; ok && reflect.DeepEqual(value, plannedValue) {
+				maskedLiveData[key] = "***"
+			} else {
+				maskedLiveData[key] = "*** (before)"
+			}
+		}
+
+		maskedPlannedData := map[string]interface{}{}
+		for key, value := range plannedData {
+			if liveValue, ok := liveData[key]; ok && reflect.DeepEqual(value, liveValue) {
+				maskedPlannedData[key] = "***"
+			} else {
+				maskedPlannedData[key] = "*** (after)"
+			}
+		}
+
+		if liveData != nil {
+			live.Object[field] = maskedLiveData
+		}
+		if plannedData != nil {
+			planned.Object[field] = maskedPlannedData
+		}
+	}
+
+	return nil
+}
+
+func isSecret(obj *unstructured.Unstructured) bool {
+	return obj != nil && obj.GroupVersionKind().Group == "" && obj.GetKind() == "Secret"
+}
+
+func getSecretData(obj *unstructured.Unstructured, field string) (map[string]interface{}, error) {
+	if obj == nil {
+		return nil, nil
+	}
+
+	data, _, err := unstructured.NestedFieldNoCopy(obj.Object, field)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	dataMap, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected type of secret %s: %T", field, data)
+	}
+
+	return dataMap, nil
+}
+
+func marshalObject(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal object: %w", err)
+	}
+
+	return string(data), nil
+}
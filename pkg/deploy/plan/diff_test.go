@@ -0,0 +1,134 @@
+package plan
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/werf/pkg/util/secretvalues"
+)
+
+var _ = Describe("diffObjects", func() {
+	It("should ignore the fields managed by the cluster", func() {
+		live := parseObject(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  resourceVersion: "100"
+  uid: 5c7e0b1a
+  managedFields:
+  - manager: helm
+data:
+  key: old
+`)
+		planned := parseObject(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  resourceVersion: "101"
+  uid: 5c7e0b1a
+data:
+  key: new
+`)
+
+		diff, err := diffObjects(live, planned)
+		Expect(err).To(Succeed())
+		Expect(diff).To(Equal(`--- live
++++ planned
+@@ -1,6 +1,6 @@
+ apiVersion: v1
+ data:
+-  key: old
++  key: new
+ kind: ConfigMap
+ metadata:
+   name: config
+`))
+
+		diff, err = diffObjects(live, live)
+		Expect(err).To(Succeed())
+		Expect(diff).To(BeEmpty())
+	})
+
+	It("should mask the data of the secrets", func() {
+		live := parseObject(`apiVersion: v1
+kind: Secret
+metadata:
+  name: secret
+data:
+  changed: b2xk
+  same: c2FtZQ==
+  removed: cmVtb3ZlZA==
+`)
+		planned := parseObject(`apiVersion: v1
+kind: Secret
+metadata:
+  name: secret
+data:
+  added: YWRkZWQ=
+  changed: bmV3
+  same: c2FtZQ==
+`)
+
+		diff, err := diffObjects(live, planned)
+		Expect(err).To(Succeed())
+		Expect(diff).To(Equal(`--- live
++++ planned
+@@ -1,7 +1,7 @@
+ apiVersion: v1
+ data:
+-  changed: '*** (before)'
+-  removed: '*** (before)'
++  added: '*** (after)'
++  changed: '*** (after)'
+   same: '***'
+ kind: Secret
+ metadata:
+`))
+		Expect(diff).NotTo(ContainSubstring("c2FtZQ=="))
+
+		diff, err = diffObjects(nil, planned)
+		Expect(err).To(Succeed())
+		Expect(diff).NotTo(ContainSubstring("bmV3"))
+		Expect(diff).To(ContainSubstring("+  changed: '*** (after)'"))
+	})
+})
+
+var _ = Describe("parseManifest", func() {
+	It("should skip the hooks and the empty documents", func() {
+		objs, err := parseManifest(`---
+# Source: app/templates/cm.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+# Source: app/templates/empty.yaml
+---
+# Source: app/templates/job.yaml
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-upgrade
+`)
+		Expect(err).To(Succeed())
+		Expect(objs).To(HaveLen(1))
+		Expect(objs[0].GetName()).To(Equal("config"))
+	})
+})
+
+var _ = Describe("MaskSecretValues", func() {
+	It("should mask the secret values and the lines of the multiline secret values", func() {
+		text := "+  password: p4ssw0rd\n+  key: |\n+    line-one\n+    line-two\n+  short: abc\n"
+		Expect(secretvalues.MaskSecretValues(text, []string{"p4ssw0rd", "line-one\nline-two\n", "abc"})).To(Equal("+  password: ***\n+  key: |\n+    ***\n+    ***\n+  short: abc\n"))
+	})
+})
+
+func parseObject(data string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	Expect(yaml.Unmarshal([]byte(data), &obj.Object)).To(Succeed())
+	return obj
+}
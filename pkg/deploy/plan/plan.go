@@ -0,0 +1,252 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"github.com/werf/werf/pkg/util/secretvalues"
+)
+
+type ChangeType string
+
+const (
+	CreateChange ChangeType = "create"
+	UpdateChange ChangeType = "update"
+	DeleteChange ChangeType = "delete"
+)
+
+// ResourceChange is the change of the release resource with the diff of the live and the planned object
+type ResourceChange struct {
+	Type     ChangeType
+	Resource string
+	Diff     string
+	// Warning is set if the planned object is not verified with the server-side dry-run
+	Warning string
+}
+
+// ReleasePlan is the list of the changes which the release upgrade would apply to the cluster, hooks are not planned
+type ReleasePlan struct {
+	ReleaseName string
+	Changes     []*ResourceChange
+	Unchanged   int
+}
+
+type ReleasePlanOptions struct {
+	// PreviousManifest is the manifest of the current release, the resources which are not in the new manifest will be deleted
+	PreviousManifest string
+	// SecretValues are masked in the diffs and in the warnings
+	SecretValues []string
+}
+
+// BuildReleasePlan compares the rendered manifest of the release with the live cluster objects.
+// The planned objects are the result of the server-side dry-run of the same patches helm applies on upgrade
+func BuildReleasePlan(ctx context.Context, kubeClient kube.Interface, releaseName, manifest string, opts ReleasePlanOptions) (*ReleasePlan, error) {
+	previousResources, err := buildResources(ctx, kubeClient, opts.PreviousManifest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse previous release manifest: %w", err)
+	}
+
+	resources, err := buildResources(ctx, kubeClient, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse release manifest: %w", err)
+	}
+
+	plan := &ReleasePlan{ReleaseName: releaseName}
+
+	for _, res := range resources {
+		change, err := planResourceUpgrade(res, findResource(previousResources, res.ID))
+		if err != nil {
+			return nil, fmt.Errorf("unable to plan %s: %w", res, err)
+		}
+
+		if change == nil {
+			plan.Unchanged++
+			continue
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	for _, res := range previousResources {
+		if findResource(resources, res.ID) != nil || res.IsKept() {
+			continue
+		}
+
+		change, err := planResourceDeletion(res)
+		if err != nil {
+			return nil, fmt.Errorf("unable to plan %s: %w", res, err)
+		}
+
+		if change != nil {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	plan.maskSecretValues(opts.SecretValues)
+
+	return plan, nil
+}
+
+// maskSecretValues masks the secret values in the diffs and in the warnings, since the dry-run errors might quote the planned objects
+func (plan *ReleasePlan) maskSecretValues(secretValues []string) {
+	for _, change := range plan.Changes {
+		change.Diff = secretvalues.MaskSecretValues(change.Diff, secretValues)
+		change.Warning = secretvalues.MaskSecretValues(change.Warning, secretValues)
+	}
+}
+
+func (plan *ReleasePlan) Count(changeType ChangeType) int {
+	var count int
+	for _, change := range plan.Changes {
+		if change.Type == changeType {
+			count++
+		}
+	}
+	return count
+}
+
+// Print writes the summary and the diffs of the changes
+func (plan *ReleasePlan) Print(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Release %q plan: %d to create, %d to update, %d to delete, %d unchanged\n", plan.ReleaseName, plan.Count(CreateChange), plan.Count(UpdateChange), plan.Count(DeleteChange), plan.Unchanged)
+
+	for _, change := range plan.Changes {
+		var sign string
+		switch change.Type {
+		case CreateChange:
+			sign = "+"
+		case UpdateChange:
+			sign = "~"
+		case DeleteChange:
+			sign = "-"
+		}
+
+		fmt.Fprintf(&b, "\n%s %s will be %sd\n", sign, change.Resource, change.Type)
+		if change.Warning != "" {
+			for _, line := range strings.Split(strings.TrimSpace(change.Warning), "\n") {
+				fmt.Fprintf(&b, "# %s\n", line)
+			}
+		}
+		b.WriteString(change.Diff)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func planResourceUpgrade(res, previousRes *releaseResource) (*ResourceChange, error) {
+	if res.Info == nil {
+		return newResourceChange(CreateChange, res, nil, res.Object, fmt.Sprintf("Not verified with the server-side dry-run: %s", res.BuildErr))
+	}
+
+	liveObj, err := getLiveObject(res.Info)
+	if err != nil {
+		return nil, err
+	}
+
+	helper := resource.NewHelper(res.Info.Client, res.Info.Mapping).DryRun(true)
+
+	if liveObj == nil {
+		plannedObj, err := helper.Create(res.Info.Namespace, true, res.Info.Object)
+		if err != nil {
+			return newResourceChange(CreateChange, res, nil, res.Object, fmt.Sprintf("Not verified with the server-side dry-run: %s", err))
+		}
+
+		planned, err := toUnstructured(plannedObj)
+		if err != nil {
+			return nil, err
+		}
+
+		return newResourceChange(CreateChange, res, nil, planned, "")
+	}
+
+	var originalObj runtime.Object = liveObj
+	if previousRes != nil && previousRes.Info != nil {
+		originalObj = previousRes.Info.Object
+	}
+
+	patch, patchType, err := createPatch(res.Info, originalObj, liveObj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create patch: %w", err)
+	}
+
+	plannedObj, err := helper.Patch(res.Info.Namespace, res.Info.Name, patchType, patch, nil)
+	if err != nil {
+		return newResourceChange(UpdateChange, res, liveObj, res.Object, fmt.Sprintf("Not verified with the server-side dry-run: %s", err))
+	}
+
+	planned, err := toUnstructured(plannedObj)
+	if err != nil {
+		return nil, err
+	}
+
+	return newResourceChange(UpdateChange, res, liveObj, planned, "")
+}
+
+func planResourceDeletion(res *releaseResource) (*ResourceChange, error) {
+	if res.Info == nil {
+		return newResourceChange(DeleteChange, res, res.Object, nil, fmt.Sprintf("Not verified with the live object: %s", res.BuildErr))
+	}
+
+	liveObj, err := getLiveObject(res.Info)
+	if err != nil {
+		return nil, err
+	}
+
+	if liveObj == nil {
+		return nil, nil
+	}
+
+	return newResourceChange(DeleteChange, res, liveObj, nil, "")
+}
+
+// newResourceChange returns nil if the live and the planned objects are the same
+func newResourceChange(changeType ChangeType, res *releaseResource, live, planned *unstructured.Unstructured, warning string) (*ResourceChange, error) {
+	diff, err := diffObjects(live, planned)
+	if err != nil {
+		return nil, fmt.Errorf("unable to diff %s: %w", res, err)
+	}
+
+	if diff == "" {
+		return nil, nil
+	}
+
+	return &ResourceChange{
+		Type:     changeType,
+		Resource: res.String(),
+		Diff:     diff,
+		Warning:  warning,
+	}, nil
+}
+
+func getLiveObject(info *resource.Info) (*unstructured.Unstructured, error) {
+	obj, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get live object: %w", err)
+	}
+
+	return toUnstructured(obj)
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert object to unstructured: %w", err)
+	}
+
+	return &unstructured.Unstructured{Object: data}, nil
+}
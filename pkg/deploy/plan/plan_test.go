@@ -0,0 +1,32 @@
+package plan
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReleasePlan", func() {
+	It("should mask the secret values in the diffs and in the warnings", func() {
+		plan := &ReleasePlan{
+			ReleaseName: "myapp",
+			Changes: []*ResourceChange{
+				{
+					Type:     CreateChange,
+					Resource: "Secret/db",
+					Diff:     "+  password: p4ssw0rd\n",
+					Warning:  `Not verified with the server-side dry-run: Secret "db" is invalid: data.password: Invalid value: "p4ssw0rd"`,
+				},
+			},
+		}
+
+		plan.maskSecretValues([]string{"p4ssw0rd"})
+
+		var out bytes.Buffer
+		Expect(plan.Print(&out)).To(Succeed())
+		Expect(out.String()).NotTo(ContainSubstring("p4ssw0rd"))
+		Expect(out.String()).To(ContainSubstring(`# Not verified with the server-side dry-run: Secret "db" is invalid: data.password: Invalid value: "***"`))
+		Expect(out.String()).To(ContainSubstring("+  password: ***\n"))
+	})
+})
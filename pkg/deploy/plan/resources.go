@@ -0,0 +1,166 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/logboek"
+)
+
+// releaseResource is the resource of the release manifest.
+// Info is nil if the resource cannot be mapped to the cluster api (e.g. the custom resource of the not yet created CRD)
+type releaseResource struct {
+	ID       string
+	Object   *unstructured.Unstructured
+	Info     *resource.Info
+	BuildErr error
+}
+
+func (res *releaseResource) String() string {
+	name := fmt.Sprintf("%s/%s", res.Object.GetKind(), res.Object.GetName())
+	if namespace := res.namespace(); namespace != "" {
+		return fmt.Sprintf("%s (namespace %s)", name, namespace)
+	}
+	return name
+}
+
+// IsKept returns true if the resource is not deleted by helm when it is removed from the release
+func (res *releaseResource) IsKept() bool {
+	return res.Object.GetAnnotations()[kube.ResourcePolicyAnno] == kube.KeepPolicy
+}
+
+func (res *releaseResource) namespace() string {
+	if res.Info != nil {
+		return res.Info.Namespace
+	}
+	return res.Object.GetNamespace()
+}
+
+// parseManifest returns the objects of the release manifest in the manifest order, hooks are skipped
+func parseManifest(manifest string) ([]*unstructured.Unstructured, error) {
+	manifests := releaseutil.SplitManifests(manifest)
+
+	var keys []string
+	for key := range manifests {
+		keys = append(keys, key)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var objs []*unstructured.Unstructured
+	for _, key := range keys {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(manifests[key]), &obj.Object); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal manifest:\n%s\n---\n%w", manifests[key], err)
+		}
+
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		if _, isHook := obj.GetAnnotations()[release.HookAnnotation]; isHook {
+			continue
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+func buildResources(ctx context.Context, kubeClient kube.Interface, manifest string) ([]*releaseResource, error) {
+	objs, err := parseManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []*releaseResource
+	for _, obj := range objs {
+		res := &releaseResource{Object: obj}
+
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, err
+		}
+
+		infos, err := kubeClient.Build(strings.NewReader(string(data)), false)
+		switch {
+		case err != nil:
+			logboek.Context(ctx).Debug().LogF("Unable to build %s/%s: %s\n", obj.GetKind(), obj.GetName(), err)
+			res.BuildErr = err
+		case len(infos) != 1:
+			res.BuildErr = fmt.Errorf("expected 1 resource, got %d", len(infos))
+		default:
+			res.Info = infos[0]
+		}
+
+		res.ID = resourceID(obj, res.namespace())
+		resources = append(resources, res)
+	}
+
+	return resources, nil
+}
+
+func resourceID(obj *unstructured.Unstructured, namespace string) string {
+	return strings.Join([]string{obj.GroupVersionKind().GroupKind().String(), namespace, obj.GetName()}, "/")
+}
+
+func findResource(resources []*releaseResource, id string) *releaseResource {
+	for _, res := range resources {
+		if res.ID == id {
+			return res
+		}
+	}
+	return nil
+}
+
+// createPatch creates the patch the same way helm does on upgrade:
+// the three-way strategic merge patch of the original, target and current objects for the built-in kinds
+// and the json merge patch of the original and target objects for the custom resources and CRDs
+func createPatch(target *resource.Info, original, current runtime.Object) ([]byte, types.PatchType, error) {
+	originalData, err := json.Marshal(original)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("unable to serialize original object: %w", err)
+	}
+
+	targetData, err := json.Marshal(target.Object)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("unable to serialize target object: %w", err)
+	}
+
+	currentData, err := json.Marshal(current)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("unable to serialize live object: %w", err)
+	}
+
+	versionedObject := kube.AsVersioned(target)
+
+	_, isUnstructured := versionedObject.(runtime.Unstructured)
+	_, isCRD := versionedObject.(*apiextv1beta1.CustomResourceDefinition)
+	if isUnstructured || isCRD {
+		patch, err := jsonpatch.CreateMergePatch(originalData, targetData)
+		return patch, types.MergePatchType, err
+	}
+
+	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(versionedObject)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, fmt.Errorf("unable to create patch metadata: %w", err)
+	}
+
+	patch, err := strategicpatch.CreateThreeWayMergePatch(originalData, targetData, currentData, patchMeta, true)
+	return patch, types.StrategicMergePatchType, err
+}
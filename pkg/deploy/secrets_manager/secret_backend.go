@@ -0,0 +1,199 @@
+package secrets_manager
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/mattn/go-shellwords"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/werf/pkg/secret"
+)
+
+// SecretBackendConfigFileName is the chart file selecting the secret backend of the chart
+const SecretBackendConfigFileName = "secret-backend.yaml"
+
+type SecretBackendType string
+
+const (
+	// AesSecretBackend encrypts secrets with the secret key, it is the default backend
+	AesSecretBackend SecretBackendType = "aes"
+	// AgeSecretBackend encrypts secrets for the age recipients, the secret key is the age identity
+	AgeSecretBackend SecretBackendType = "age"
+	// EnvelopeSecretBackend encrypts secrets with the wrapped data key unwrapped by the command hook
+	EnvelopeSecretBackend SecretBackendType = "envelope"
+)
+
+// SecretBackendConfig is the content of the secret-backend.yaml chart file, nil config is the default aes backend:
+//
+//	backend: age
+//	age:
+//	  recipients:
+//	  - age1...
+//
+//	backend: envelope
+//	envelope:
+//	  wrappedKey: BASE64_WRAPPED_DATA_KEY
+//	  unwrapCommand: vault write -field=plaintext transit/decrypt/werf ciphertext=-
+//
+// The unwrapCommand is executed only if the config is specified explicitly by the user (LoadLocalSecretBackendConfig),
+// the config from the chart files, including the charts of the pulled bundles, cannot run commands.
+//
+// The environment-scoped secrets (werfEnvironments) use the same backend unless it is overridden for the environment:
+//
+//	environments:
+//	  production:
+//	    backend: age
+//	    age:
+//	      recipients:
+//	      - age1...
+type SecretBackendConfig struct {
+	Backend      SecretBackendType               `json:"backend"`
+	Age          *AgeSecretBackendConfig         `json:"age,omitempty"`
+	Envelope     *EnvelopeSecretBackendConfig    `json:"envelope,omitempty"`
+	Environments map[string]*SecretBackendConfig `json:"environments,omitempty"`
+
+	// local is true for the config specified explicitly by the user, only such config can run commands
+	local bool
+}
+
+type AgeSecretBackendConfig struct {
+	Recipients []string `json:"recipients"`
+}
+
+type EnvelopeSecretBackendConfig struct {
+	WrappedKey    string `json:"wrappedKey"`
+	UnwrapCommand string `json:"unwrapCommand"`
+}
+
+func ParseSecretBackendConfig(data []byte) (*SecretBackendConfig, error) {
+	config := &SecretBackendConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal secret backend config: %w", err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	for env, envConfig := range config.Environments {
+		if envConfig == nil {
+			return nil, fmt.Errorf("environments.%s: backend config required", env)
+		}
+		if len(envConfig.Environments) > 0 {
+			return nil, fmt.Errorf("environments.%s: nested environments are not supported", env)
+		}
+		if err := envConfig.validate(); err != nil {
+			return nil, fmt.Errorf("environments.%s: %w", env, err)
+		}
+	}
+
+	return config, nil
+}
+
+func (config *SecretBackendConfig) validate() error {
+	switch config.Backend {
+	case "":
+		config.Backend = AesSecretBackend
+	case AesSecretBackend:
+	case AgeSecretBackend:
+		if config.Age == nil || len(config.Age.Recipients) == 0 {
+			return fmt.Errorf("age.recipients required for the %q secret backend", config.Backend)
+		}
+	case EnvelopeSecretBackend:
+		if config.Envelope == nil || config.Envelope.WrappedKey == "" || config.Envelope.UnwrapCommand == "" {
+			return fmt.Errorf("envelope.wrappedKey and envelope.unwrapCommand required for the %q secret backend", config.Backend)
+		}
+	default:
+		return fmt.Errorf("unsupported secret backend %q: expected %q, %q or %q", config.Backend, AesSecretBackend, AgeSecretBackend, EnvelopeSecretBackend)
+	}
+
+	return nil
+}
+
+// LoadLocalSecretBackendConfig loads the config specified explicitly by the user (--secret-backend-config), the commands of such config are executed
+func LoadLocalSecretBackendConfig(path string) (*SecretBackendConfig, error) {
+	config, err := LoadSecretBackendConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, fmt.Errorf("secret backend config %q not found", path)
+	}
+
+	config.local = true
+	for _, envConfig := range config.Environments {
+		envConfig.local = true
+	}
+
+	return config, nil
+}
+
+// LoadSecretBackendConfig loads the config of the chart and returns nil config (the default aes backend) if the file does not exist.
+// The commands of such config are not executed
+func LoadSecretBackendConfig(path string) (*SecretBackendConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to read secret backend config %q: %w", path, err)
+	}
+
+	config, err := ParseSecretBackendConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret backend config %q: %w", path, err)
+	}
+
+	return config, nil
+}
+
+func (config *SecretBackendConfig) GetBackend() SecretBackendType {
+	if config == nil {
+		return AesSecretBackend
+	}
+	return config.Backend
+}
+
+// ForEnvironment returns the backend config of the environment-scoped secrets
+func (config *SecretBackendConfig) ForEnvironment(env string) *SecretBackendConfig {
+	if config == nil {
+		return nil
+	}
+	if envConfig, ok := config.Environments[env]; ok {
+		return envConfig
+	}
+	return config
+}
+
+// UsesSecretKey returns true if the backend requires the secret key from $WERF_SECRET_KEY, .werf_secret_key or ~/.werf/global_secret_key
+func (config *SecretBackendConfig) UsesSecretKey() bool {
+	return config.GetBackend() != EnvelopeSecretBackend
+}
+
+// newEncoder creates the backend encoder with the secret key, the secret key is not used by the envelope backend
+func (config *SecretBackendConfig) newEncoder(key []byte) (secret.Encoder, error) {
+	switch config.GetBackend() {
+	case AgeSecretBackend:
+		return secret.NewAgeEncoder(config.Age.Recipients, key)
+	case EnvelopeSecretBackend:
+		if !config.local {
+			return nil, fmt.Errorf("envelope.unwrapCommand of the chart secret backend config is not executed: specify the secret backend config with the command explicitly with --secret-backend-config option or $WERF_SECRET_BACKEND_CONFIG")
+		}
+
+		wrappedKey, err := base64.StdEncoding.DecodeString(config.Envelope.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode envelope.wrappedKey: %w", err)
+		}
+
+		unwrapCommand, err := shellwords.Parse(config.Envelope.UnwrapCommand)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse envelope.unwrapCommand %q: %w", config.Envelope.UnwrapCommand, err)
+		}
+
+		return secret.NewEnvelopeEncoder(wrappedKey, unwrapCommand)
+	default:
+		return secret.NewAesEncoder(key)
+	}
+}
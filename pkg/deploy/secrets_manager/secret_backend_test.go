@@ -0,0 +1,53 @@
+package secrets_manager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const envelopeSecretBackendConfig = `backend: envelope
+envelope:
+  wrappedKey: d3JhcHBlZA==
+  unwrapCommand: cat
+environments:
+  production:
+    backend: envelope
+    envelope:
+      wrappedKey: d3JhcHBlZA==
+      unwrapCommand: cat
+`
+
+func TestSecretBackendConfig_EnvelopeUnwrapCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SecretBackendConfigFileName)
+	if err := os.WriteFile(path, []byte(envelopeSecretBackendConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	chartConfig, err := LoadSecretBackendConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, config := range []*SecretBackendConfig{chartConfig, chartConfig.ForEnvironment("production")} {
+		if _, err := config.newEncoder(nil); err == nil || !strings.Contains(err.Error(), "--secret-backend-config") {
+			t.Fatalf("expected the unwrap command of the chart config not to be executed, got error: %v", err)
+		}
+	}
+
+	localConfig, err := LoadLocalSecretBackendConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, config := range []*SecretBackendConfig{localConfig, localConfig.ForEnvironment("production")} {
+		if _, err := config.newEncoder(nil); err != nil {
+			t.Fatalf("expected the unwrap command of the local config to be allowed, got error: %v", err)
+		}
+	}
+}
+
+func TestLoadLocalSecretBackendConfig_NotFound(t *testing.T) {
+	if _, err := LoadLocalSecretBackendConfig(filepath.Join(t.TempDir(), SecretBackendConfigFileName)); err == nil {
+		t.Fatal("expected the error for the missing config specified explicitly")
+	}
+}
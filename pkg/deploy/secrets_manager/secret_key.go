@@ -1,10 +1,12 @@
 package secrets_manager
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/werf/werf/pkg/secret"
@@ -12,10 +14,20 @@ import (
 	"github.com/werf/werf/pkg/werf"
 )
 
+// environmentNameRegexp restricts the environment name used in the secret key file names and the environment variable name.
+// Neither upper case letters nor "_" are allowed, so different environments cannot be mapped to the same environment variable (prod-eu and prod_eu)
+var environmentNameRegexp = regexp.MustCompile(`^[a-z0-9-]+$`)
+
 func GenerateSecretKey() ([]byte, error) {
 	return secret.GenerateAesSecretKey()
 }
 
+// GenerateAgeSecretKey returns the age identity file data with the public key of the identity in the comment
+func GenerateAgeSecretKey() ([]byte, error) {
+	data, _, err := secret.GenerateAgeIdentity()
+	return data, err
+}
+
 func GetRequiredOldSecretKey() ([]byte, error) {
 	secretKey := []byte(os.Getenv("WERF_OLD_SECRET_KEY"))
 	if len(secretKey) == 0 {
@@ -25,25 +37,44 @@ func GetRequiredOldSecretKey() ([]byte, error) {
 }
 
 func GetRequiredSecretKey(workingDir string) ([]byte, error) {
+	return getRequiredSecretKey(workingDir, "WERF_SECRET_KEY", ".werf_secret_key", "global_secret_key")
+}
+
+// GetRequiredEnvironmentSecretKey returns the secret key of the environment-scoped secrets from
+// $WERF_SECRET_KEY_<ENV>, .werf_secret_key.<env> or ~/.werf/global_secret_key.<env>
+func GetRequiredEnvironmentSecretKey(workingDir, env string) ([]byte, error) {
+	if !environmentNameRegexp.MatchString(env) {
+		return nil, fmt.Errorf("invalid environment name %q: only lower case letters, digits and %q are allowed", env, "-")
+	}
+
+	return getRequiredSecretKey(workingDir, EnvironmentSecretKeyEnvName(env), fmt.Sprintf(".werf_secret_key.%s", env), fmt.Sprintf("global_secret_key.%s", env))
+}
+
+// EnvironmentSecretKeyEnvName returns the name of the environment variable with the secret key of the environment (WERF_SECRET_KEY_PROD_EU for prod-eu)
+func EnvironmentSecretKeyEnvName(env string) string {
+	return "WERF_SECRET_KEY_" + strings.ToUpper(strings.ReplaceAll(env, "-", "_"))
+}
+
+func getRequiredSecretKey(workingDir, envName, fileName, globalFileName string) ([]byte, error) {
 	var secretKey []byte
 	var werfSecretKeyPaths []string
 	var notFoundIn []string
 
-	secretKey = []byte(os.Getenv("WERF_SECRET_KEY"))
+	secretKey = []byte(os.Getenv(envName))
 	if len(secretKey) == 0 {
-		notFoundIn = append(notFoundIn, "$WERF_SECRET_KEY")
+		notFoundIn = append(notFoundIn, "$"+envName)
 
 		var werfSecretKeyPath string
 
 		if workingDir != "" {
-			if defaultWerfSecretKeyPath, err := filepath.Abs(filepath.Join(workingDir, ".werf_secret_key")); err != nil {
+			if defaultWerfSecretKeyPath, err := filepath.Abs(filepath.Join(workingDir, fileName)); err != nil {
 				return nil, err
 			} else {
 				werfSecretKeyPaths = append(werfSecretKeyPaths, defaultWerfSecretKeyPath)
 			}
 		}
 
-		werfSecretKeyPaths = append(werfSecretKeyPaths, filepath.Join(werf.GetHomeDir(), "global_secret_key"))
+		werfSecretKeyPaths = append(werfSecretKeyPaths, filepath.Join(werf.GetHomeDir(), globalFileName))
 
 		for _, path := range werfSecretKeyPaths {
 			exist, err := util.FileExists(path)
@@ -93,3 +124,9 @@ func NewEncryptionKeyRequiredError(notFoundIn []string) *EncryptionKeyRequiredEr
 		Msg: fmt.Errorf("required encryption key not found in: %s", strings.Join(notFoundInFormatted, ", ")),
 	}
 }
+
+// IsEncryptionKeyRequiredError returns true if the secrets cannot be decrypted because the secret key is not found
+func IsEncryptionKeyRequiredError(err error) bool {
+	var keyErr *EncryptionKeyRequiredError
+	return errors.As(err, &keyErr)
+}
@@ -0,0 +1,32 @@
+package secrets_manager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvironmentSecretKeyEnvName(t *testing.T) {
+	if name := EnvironmentSecretKeyEnvName("prod-eu"); name != "WERF_SECRET_KEY_PROD_EU" {
+		t.Errorf("expected WERF_SECRET_KEY_PROD_EU, got %s", name)
+	}
+}
+
+func TestGetRequiredEnvironmentSecretKey_RejectsCollidingEnvironmentNames(t *testing.T) {
+	t.Setenv("WERF_SECRET_KEY_PROD_EU", "1000deadbeef")
+
+	key, err := GetRequiredEnvironmentSecretKey(t.TempDir(), "prod-eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(key) != "1000deadbeef" {
+		t.Errorf("expected the secret key of prod-eu, got %q", key)
+	}
+
+	// prod_eu and Prod-EU would share the WERF_SECRET_KEY_PROD_EU variable with prod-eu
+	for _, env := range []string{"prod_eu", "Prod-EU", "../prod"} {
+		_, err := GetRequiredEnvironmentSecretKey(t.TempDir(), env)
+		if err == nil || !strings.Contains(err.Error(), "invalid environment name") {
+			t.Errorf("expected invalid environment name error for %q, got %v", env, err)
+		}
+	}
+}
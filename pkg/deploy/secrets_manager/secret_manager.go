@@ -40,31 +40,77 @@ func (manager *SecretsManager) AllowMissedSecretKeyMode(workingDir string) error
 	return nil
 }
 
-func (manager *SecretsManager) GetYamlEncoder(ctx context.Context, workingDir string) (*secret.YamlEncoder, error) {
+// GetYamlEncoder returns the encoder of the secret backend, nil backend config is the default aes backend
+func (manager *SecretsManager) GetYamlEncoder(ctx context.Context, workingDir string, backend *SecretBackendConfig) (*secret.YamlEncoder, error) {
+	return manager.getYamlEncoder(ctx, backend, func() ([]byte, error) {
+		return GetRequiredSecretKey(workingDir)
+	})
+}
+
+// GetYamlEncoderForEnvironment returns the encoder of the environment-scoped secrets (werfEnvironments) with the secret key of the environment
+func (manager *SecretsManager) GetYamlEncoderForEnvironment(ctx context.Context, workingDir string, backend *SecretBackendConfig, env string) (*secret.YamlEncoder, error) {
+	return manager.getYamlEncoder(ctx, backend.ForEnvironment(env), func() ([]byte, error) {
+		return GetRequiredEnvironmentSecretKey(workingDir, env)
+	})
+}
+
+func (manager *SecretsManager) getYamlEncoder(ctx context.Context, backend *SecretBackendConfig, getKeyFunc func() ([]byte, error)) (*secret.YamlEncoder, error) {
 	if manager.DisableSecretsDecryption {
 		logboek.Context(ctx).Default().LogLnDetails("Secrets decryption disabled")
 		return secret.NewYamlEncoder(nil), nil
 	}
-	if manager.missedSecretKeyModeEnabled {
+	if manager.missedSecretKeyModeEnabled && backend.UsesSecretKey() {
 		logboek.Context(ctx).Error().LogLn("Secrets decryption disabled due to missed key (no WERF_SECRET_KEY is set)")
 		return secret.NewYamlEncoder(nil), nil
 	}
 
-	if key, err := GetRequiredSecretKey(workingDir); err != nil {
-		return nil, fmt.Errorf("unable to load secret key: %w", err)
-	} else if enc, err := secret.NewAesEncoder(key); err != nil {
+	var key []byte
+	if backend.UsesSecretKey() {
+		var err error
+		if key, err = getKeyFunc(); err != nil {
+			_, missedKey := err.(*EncryptionKeyRequiredError)
+			if !missedKey || backend.GetBackend() != AgeSecretBackend {
+				return nil, fmt.Errorf("unable to load secret key: %w", err)
+			}
+
+			// The age recipients are public, so the secrets can be encrypted by anyone without the age identity
+			if enc, encErr := backend.newEncoder(nil); encErr != nil {
+				return nil, fmt.Errorf("check encryption key: %w", encErr)
+			} else {
+				return secret.NewYamlEncoder(&encryptOnlyEncoder{Encoder: enc, decryptErr: fmt.Errorf("unable to load secret key: %w", err)}), nil
+			}
+		}
+	}
+
+	if enc, err := backend.newEncoder(key); err != nil {
 		return nil, fmt.Errorf("check encryption key: %w", err)
 	} else {
 		return secret.NewYamlEncoder(enc), nil
 	}
 }
 
-func (manager *SecretsManager) GetYamlEncoderForOldKey(ctx context.Context) (*secret.YamlEncoder, error) {
-	if key, err := GetRequiredOldSecretKey(); err != nil {
-		return nil, fmt.Errorf("unable to load old secret key: %w", err)
-	} else if enc, err := secret.NewAesEncoder(key); err != nil {
+// GetYamlEncoderForOldKey returns the encoder of the previous secret backend to rotate secrets, nil backend config is the default aes backend
+func (manager *SecretsManager) GetYamlEncoderForOldKey(ctx context.Context, backend *SecretBackendConfig) (*secret.YamlEncoder, error) {
+	var key []byte
+	if backend.UsesSecretKey() {
+		var err error
+		if key, err = GetRequiredOldSecretKey(); err != nil {
+			return nil, fmt.Errorf("unable to load old secret key: %w", err)
+		}
+	}
+
+	if enc, err := backend.newEncoder(key); err != nil {
 		return nil, fmt.Errorf("check old encryption key: %w", err)
 	} else {
 		return secret.NewYamlEncoder(enc), nil
 	}
 }
+
+type encryptOnlyEncoder struct {
+	secret.Encoder
+	decryptErr error
+}
+
+func (enc *encryptOnlyEncoder) Decrypt(_ []byte) ([]byte, error) {
+	return nil, enc.decryptErr
+}
@@ -41,6 +41,10 @@ func ContainerCommit(ctx context.Context, ref string, commitOptions types.Contai
 	return response.ID, nil
 }
 
+func ContainerExport(ctx context.Context, ref string) (io.ReadCloser, error) {
+	return apiCli(ctx).ContainerExport(ctx, ref)
+}
+
 func ContainerRemove(ctx context.Context, ref string, options types.ContainerRemoveOptions) error {
 	return apiCli(ctx).ContainerRemove(ctx, ref, options)
 }
@@ -65,6 +65,29 @@ func ImageInspect(ctx context.Context, ref string) (*types.ImageInspect, error)
 	return &inspect, nil
 }
 
+func ImageRemove(ctx context.Context, ref string) error {
+	_, err := apiCli(ctx).ImageRemove(ctx, ref, types.ImageRemoveOptions{})
+	return err
+}
+
+func ImageSave(ctx context.Context, ref string) (io.ReadCloser, error) {
+	return apiCli(ctx).ImageSave(ctx, []string{ref})
+}
+
+func ImageLoad(ctx context.Context, input io.Reader) error {
+	resp, err := apiCli(ctx).ImageLoad(ctx, input, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return fmt.Errorf("unable to read image load response: %w", err)
+	}
+
+	return nil
+}
+
 func doCliPull(c command.Cli, args ...string) error {
 	return prepareCliCmd(image.NewPullCommand(c), args...).Execute()
 }
@@ -207,10 +230,12 @@ func doCliBuild(c command.Cli, opts BuildOptions, args ...string) error {
 	return prepareCliCmd(cmd, finalArgs...).Execute()
 }
 
-func CliBuild_LiveOutputWithCustomIn(ctx context.Context, rc io.ReadCloser, args ...string) error {
+// CliBuild_LiveOutputWithCustomIn runs docker build reading the build context from rc.
+// BuildKit is used if enabled for the docker client or forced by forceBuildx (e.g. for the RUN --mount=type=secret instructions)
+func CliBuild_LiveOutputWithCustomIn(ctx context.Context, rc io.ReadCloser, forceBuildx bool, args ...string) error {
 	buildOpts := BuildOptions{}
 
-	if useBuildx {
+	if useBuildx || forceBuildx {
 		buildOpts.EnableBuildx = true
 
 		// disable buildkit output in background tasks due to https://github.com/docker/cli/issues/2889
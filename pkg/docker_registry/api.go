@@ -205,10 +205,16 @@ func (api *api) getRepoImageByDesc(ctx context.Context, originalTag string, desc
 			return nil, err
 		} else {
 			for _, l := range layers {
+				lDigest, err := l.Digest()
+				if err != nil {
+					return nil, err
+				}
+
 				if lSize, err := l.Size(); err != nil {
 					return nil, err
 				} else {
 					totalSize += lSize
+					repoImage.Layers = append(repoImage.Layers, image.LayerInfo{Digest: lDigest.String(), Size: lSize})
 				}
 			}
 		}
@@ -533,6 +539,10 @@ func (api *api) getHttpTransport() (transport http.RoundTripper) {
 		transport = newTransport
 	}
 
+	if registryCache != nil {
+		transport = registryCache.transport(transport)
+	}
+
 	return
 }
 
@@ -771,6 +781,77 @@ func (api *api) PushManifestList(ctx context.Context, reference string, opts Man
 	return nil
 }
 
+// GetManifestDigest returns the digest of the manifest or the manifest list (index) by reference without fetching it
+func (api *api) GetManifestDigest(ctx context.Context, reference string) (string, error) {
+	desc, err := api.GetManifestDescriptor(ctx, reference)
+	if err != nil {
+		return "", err
+	}
+
+	return desc.Digest.String(), nil
+}
+
+// GetManifestDescriptor returns the descriptor (media type, digest and size) of the manifest or the manifest list (index) by reference without fetching it
+func (api *api) GetManifestDescriptor(ctx context.Context, reference string) (*v1.Descriptor, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse reference %q: %w", reference, err)
+	}
+
+	desc, err := remote.Head(ref, api.defaultRemoteOptions(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get manifest descriptor of %q: %w", reference, err)
+	}
+
+	return desc, nil
+}
+
+func (api *api) GetRemoteIndex(ctx context.Context, reference string) (v1.ImageIndex, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse reference %q: %w", reference, err)
+	}
+
+	idx, err := remote.Index(ref, api.defaultRemoteOptions(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image index %q: %w", reference, err)
+	}
+
+	return idx, nil
+}
+
+// TryGetRemoteImage returns nil if there is no image by reference
+func (api *api) TryGetRemoteImage(ctx context.Context, reference string) (v1.Image, error) {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse reference %q: %w", reference, err)
+	}
+
+	img, err := remote.Image(ref, api.defaultRemoteOptions(ctx)...)
+	if err != nil {
+		if IsImageNotFoundError(err) || IsStatusNotFoundErr(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to get image %q: %w", reference, err)
+	}
+
+	return img, nil
+}
+
+func (api *api) PushRemoteImage(ctx context.Context, reference string, img v1.Image) error {
+	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("unable to parse reference %q: %w", reference, err)
+	}
+
+	return api.pushWithRetry(ctx, func() error {
+		if err := api.writeToRemote(ctx, ref, img); err != nil {
+			return fmt.Errorf("write to the remote %s have failed: %w", ref.String(), err)
+		}
+		return nil
+	})
+}
+
 func ValidateRepositoryReference(reference string) error {
 	reg := regexp.MustCompile(`^` + dockerReference.NameRegexp.String() + `$`)
 	if !reg.MatchString(reference) {
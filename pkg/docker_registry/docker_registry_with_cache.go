@@ -33,6 +33,11 @@ func (r *DockerRegistryWithCache) Tags(ctx context.Context, reference string, op
 			return cachedTags, nil
 		}
 
+		// the tags might be changed by another werf process, so the persistent registry cache is not used either
+		if !o.cachedTags {
+			ctx = contextWithActualTags(ctx)
+		}
+
 		return r.Interface.Tags(ctx, reference, opts...)
 	})
 }
@@ -29,6 +29,10 @@ func (api *genericApi) MutateAndPushImage(ctx context.Context, sourceReference,
 	return api.commonApi.MutateAndPushImage(ctx, sourceReference, destinationReference, mutateConfigFunc)
 }
 
+func (api *genericApi) PushImageArchive(ctx context.Context, archiveOpener ArchiveOpener, reference string) error {
+	return api.commonApi.PushImageArchive(ctx, archiveOpener, reference)
+}
+
 func (api *genericApi) GetRepoImageConfigFile(ctx context.Context, reference string) (*v1.ConfigFile, error) {
 	mirrorReferenceList, err := api.mirrorReferenceList(ctx, reference)
 	if err != nil {
@@ -26,6 +26,12 @@ type Interface interface {
 	PullImageArchive(ctx context.Context, archiveWriter io.Writer, reference string) error
 	PushManifestList(ctx context.Context, reference string, opts ManifestListOptions) error
 
+	GetManifestDigest(ctx context.Context, reference string) (string, error)
+	GetManifestDescriptor(ctx context.Context, reference string) (*v1.Descriptor, error)
+	GetRemoteIndex(ctx context.Context, reference string) (v1.ImageIndex, error)
+	TryGetRemoteImage(ctx context.Context, reference string) (v1.Image, error)
+	PushRemoteImage(ctx context.Context, reference string, img v1.Image) error
+
 	String() string
 
 	parseReferenceParts(reference string) (referenceParts, error)
@@ -0,0 +1,476 @@
+package docker_registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/werf/werf/pkg/util"
+)
+
+const (
+	RegistryCacheVersion = "1"
+
+	// registryCacheMaxBlobSize limits cached blobs to image configs and other small blobs, layers are never read into memory
+	registryCacheMaxBlobSize  = 1024 * 1024
+	registryCacheMaxRedirects = 10
+
+	registryCacheMaxSize  = 512 * 1024 * 1024
+	registryCacheMaxAge   = 7 * 24 * time.Hour
+	registryCacheGCPeriod = time.Hour
+)
+
+var (
+	registryCache *RegistryCache
+
+	registryCacheManifestPathRegexp = regexp.MustCompile(`^/v2/(.+)/manifests/([^/]+)$`)
+	registryCacheBlobPathRegexp     = regexp.MustCompile(`^/v2/(.+)/blobs/(sha256:[a-f0-9]{64})$`)
+	registryCacheTagsPathRegexp     = regexp.MustCompile(`^/v2/(.+)/tags/list$`)
+	registryCacheRepoPathRegexp     = regexp.MustCompile(`^/v2/(.+)/(manifests|blobs|tags)/`)
+)
+
+// RegistryCache is a persistent cache of registry API responses on the host shared by werf processes:
+// manifests and small blobs are immutable and stored by registry host, repository and digest, tag listings are stored for TagsTTL.
+// The cached manifests and blobs are served only if the registry confirms with the HEAD request that they still exist
+// and are accessible with the credentials of the request, tag listings are stored per credentials identity (the registry and the username from the Keychain)
+// and are served only for the requests that allow cached tags (see WithCachedTags), other requests always get and store the actual tag listings.
+// Records are written to a temporary file and renamed, so concurrent processes never read partially written records.
+// Records not used for MaxAge and the least recently used records above MaxSize are removed by GC, which runs at most once per GCPeriod.
+type RegistryCache struct {
+	Dir      string
+	TagsTTL  time.Duration
+	MaxSize  int64
+	MaxAge   time.Duration
+	GCPeriod time.Duration
+	Keychain authn.Keychain
+}
+
+type registryCacheRecord struct {
+	CreatedAt time.Time   `json:"createdAt"`
+	Header    http.Header `json:"header"`
+	Body      []byte      `json:"body"`
+}
+
+// InitRegistryCache enables the cache for all registry API requests made by werf, tag listings are not cached if tagsTTL is zero
+func InitRegistryCache(dir string, tagsTTL time.Duration) {
+	registryCache = &RegistryCache{
+		Dir:      filepath.Join(dir, RegistryCacheVersion),
+		TagsTTL:  tagsTTL,
+		MaxSize:  registryCacheMaxSize,
+		MaxAge:   registryCacheMaxAge,
+		GCPeriod: registryCacheGCPeriod,
+		Keychain: authn.DefaultKeychain,
+	}
+}
+
+type registryCacheActualTagsKey struct{}
+
+// contextWithActualTags makes the registry cache request the registry for the tag listings instead of serving the cached ones
+func contextWithActualTags(ctx context.Context) context.Context {
+	return context.WithValue(ctx, registryCacheActualTagsKey{}, true)
+}
+
+func isActualTagsContext(ctx context.Context) bool {
+	isActual, _ := ctx.Value(registryCacheActualTagsKey{}).(bool)
+	return isActual
+}
+
+func (c *RegistryCache) transport(underlying http.RoundTripper) http.RoundTripper {
+	return &registryCacheTransport{cache: c, underlying: underlying}
+}
+
+type registryCacheTransport struct {
+	cache      *RegistryCache
+	underlying http.RoundTripper
+}
+
+func (t *registryCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodHead:
+		return t.underlying.RoundTrip(req)
+	default:
+		// tags of the repo might be changed
+		if match := registryCacheRepoPathRegexp.FindStringSubmatch(req.URL.Path); match != nil {
+			if err := t.cache.invalidateTags(req.URL.Host, match[1]); err != nil {
+				return nil, err
+			}
+		}
+
+		return t.underlying.RoundTrip(req)
+	}
+
+	if match := registryCacheManifestPathRegexp.FindStringSubmatch(req.URL.Path); match != nil {
+		return t.getManifest(req, match[1], match[2])
+	}
+
+	if match := registryCacheBlobPathRegexp.FindStringSubmatch(req.URL.Path); match != nil {
+		return t.getBlob(req, match[1], match[2])
+	}
+
+	if match := registryCacheTagsPathRegexp.FindStringSubmatch(req.URL.Path); match != nil && t.cache.TagsTTL > 0 {
+		return t.getTags(req, match[1])
+	}
+
+	return t.underlying.RoundTrip(req)
+}
+
+func (t *registryCacheTransport) getManifest(req *http.Request, repository, reference string) (*http.Response, error) {
+	digest := reference
+	isRevalidated := false
+	if !isDigest(reference) {
+		// the manifest by tag is mutable, ask the registry for the current digest (HEAD requests are cheaper and usually not rate limited)
+		headResp, err := t.head(req)
+		if err != nil {
+			return nil, err
+		}
+
+		digest = headResp.Header.Get("Docker-Content-Digest")
+		if headResp.StatusCode != http.StatusOK || !isDigest(digest) {
+			return t.underlying.RoundTrip(req)
+		}
+		isRevalidated = true
+	}
+
+	recordPath := t.cache.recordPath("manifests", req.URL.Host, repository, digest)
+	if record := t.cache.readRecord(recordPath); record != nil {
+		if isRevalidated {
+			return record.response(req), nil
+		}
+		return t.revalidatedResponse(req, record)
+	}
+
+	resp, err := t.underlying.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	return t.storeResponse(req, resp, recordPath, digest)
+}
+
+func (t *registryCacheTransport) getBlob(req *http.Request, repository, digest string) (*http.Response, error) {
+	recordPath := t.cache.recordPath("blobs", req.URL.Host, repository, digest)
+	if record := t.cache.readRecord(recordPath); record != nil {
+		return t.revalidatedResponse(req, record)
+	}
+
+	resp, err := t.underlying.RoundTrip(req)
+	for redirects := 0; err == nil && isRedirect(resp) && redirects < registryCacheMaxRedirects; redirects++ {
+		resp, err = t.followRedirect(req, resp)
+	}
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	if resp.ContentLength < 0 || resp.ContentLength > registryCacheMaxBlobSize {
+		return resp, nil
+	}
+
+	return t.storeResponse(req, resp, recordPath, digest)
+}
+
+// revalidatedResponse returns the cached response if the registry confirms that the manifest or blob exists and is accessible with the request credentials,
+// otherwise the request is passed to the registry to get the actual response
+func (t *registryCacheTransport) revalidatedResponse(req *http.Request, record *registryCacheRecord) (*http.Response, error) {
+	headResp, err := t.head(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if headResp.StatusCode != http.StatusOK && !isRedirect(headResp) {
+		return t.underlying.RoundTrip(req)
+	}
+
+	return record.response(req), nil
+}
+
+func (t *registryCacheTransport) head(req *http.Request) (*http.Response, error) {
+	headReq := req.Clone(req.Context())
+	headReq.Method = http.MethodHead
+
+	headResp, err := t.underlying.RoundTrip(headReq)
+	if err != nil {
+		return nil, err
+	}
+	headResp.Body.Close()
+
+	return headResp, nil
+}
+
+// followRedirect requests the storage backend the registry redirects to without the registry credentials
+func (t *registryCacheTransport) followRedirect(req *http.Request, resp *http.Response) (*http.Response, error) {
+	resp.Body.Close()
+
+	location, err := resp.Location()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get redirect location for %s: %w", req.URL, err)
+	}
+
+	redirectReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, location.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.underlying.RoundTrip(redirectReq)
+}
+
+func (t *registryCacheTransport) getTags(req *http.Request, repository string) (*http.Response, error) {
+	// tag listings cannot be revalidated, so they are stored per credentials identity
+	identity, err := t.cache.credentialsIdentity(req.URL.Host)
+	if err != nil {
+		return t.underlying.RoundTrip(req)
+	}
+
+	recordPath := filepath.Join(t.cache.tagsDir(req.URL.Host, repository), util.Sha256Hash(identity, req.URL.RawQuery))
+	if !isActualTagsContext(req.Context()) {
+		if record := t.cache.readRecord(recordPath); record != nil {
+			if time.Since(record.CreatedAt) < t.cache.TagsTTL {
+				return record.response(req), nil
+			}
+		}
+	}
+
+	resp, err := t.underlying.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	return t.storeResponse(req, resp, recordPath, "")
+}
+
+// credentialsIdentity returns the username (or the hash of the long-lived token) the Keychain resolves for the registry,
+// short-lived bearer tokens of the requests are not used, so the same identity is shared by all werf processes
+func (c *RegistryCache) credentialsIdentity(host string) (string, error) {
+	registry, err := name.NewRegistry(host, name.WeakValidation)
+	if err != nil {
+		return "", err
+	}
+
+	authenticator, err := c.Keychain.Resolve(registry)
+	if err != nil {
+		return "", err
+	}
+
+	authConfig, err := authenticator.Authorization()
+	if err != nil {
+		return "", err
+	}
+
+	if authConfig.Username != "" {
+		return "username:" + authConfig.Username, nil
+	}
+
+	if authConfig.IdentityToken != "" || authConfig.RegistryToken != "" || authConfig.Auth != "" {
+		return "token:" + util.Sha256Hash(authConfig.IdentityToken, authConfig.RegistryToken, authConfig.Auth), nil
+	}
+
+	return "anonymous", nil
+}
+
+// storeResponse reads the response body, stores it in the cache and returns the response with the same body,
+// the body is stored only if its digest matches the expected one (if specified)
+func (t *registryCacheTransport) storeResponse(req *http.Request, resp *http.Response, recordPath, expectedDigest string) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &registryCacheRecord{
+		CreatedAt: time.Now(),
+		Header:    http.Header{},
+		Body:      body,
+	}
+	for _, key := range []string{"Content-Type", "Docker-Content-Digest", "Link"} {
+		if value := resp.Header.Get(key); value != "" {
+			record.Header.Set(key, value)
+		}
+	}
+
+	// the cache is an optimization, the response is returned even if it cannot be stored
+	if expectedDigest == "" || expectedDigest == fmt.Sprintf("sha256:%x", sha256.Sum256(body)) {
+		_ = t.cache.writeRecord(recordPath, record)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+func (r *registryCacheRecord) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+func (c *RegistryCache) recordPath(kind, host, repository, digest string) string {
+	hash := strings.TrimPrefix(digest, "sha256:")
+	return filepath.Join(c.Dir, kind, util.Sha256Hash(host, repository), hash[:2], hash)
+}
+
+func (c *RegistryCache) tagsDir(host, repository string) string {
+	return filepath.Join(c.Dir, "tags", util.Sha256Hash(host, repository))
+}
+
+func (c *RegistryCache) invalidateTags(host, repository string) error {
+	if err := os.RemoveAll(c.tagsDir(host, repository)); err != nil {
+		return fmt.Errorf("unable to invalidate registry cache tags of %s/%s: %w", host, repository, err)
+	}
+
+	return nil
+}
+
+// readRecord returns nil if there is no record or the record is broken, the registry is requested in this case.
+// The modification time of the record is updated to keep the recently used records on GC
+func (c *RegistryCache) readRecord(path string) *registryCacheRecord {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	record := &registryCacheRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	return record
+}
+
+func (c *RegistryCache) writeRecord(path string, record *registryCacheRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("unable to marshal registry cache record: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create dir %s: %w", dir, err)
+	}
+
+	tmpFile, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary file in %s: %w", dir, err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("unable to write %s: %w", tmpFile.Name(), err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("unable to close %s: %w", tmpFile.Name(), err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("unable to rename %s to %s: %w", tmpFile.Name(), path, err)
+	}
+
+	return c.gcIfNeeded()
+}
+
+// gcIfNeeded runs GC if it has not been run by any werf process for GCPeriod
+func (c *RegistryCache) gcIfNeeded() error {
+	stampPath := filepath.Join(c.Dir, ".last-gc")
+	if info, err := os.Stat(stampPath); err == nil && time.Since(info.ModTime()) < c.GCPeriod {
+		return nil
+	}
+
+	if err := os.WriteFile(stampPath, nil, 0o644); err != nil {
+		return fmt.Errorf("unable to write %s: %w", stampPath, err)
+	}
+
+	return c.GC()
+}
+
+// GC removes the records not used for MaxAge and the least recently used records while the cache size exceeds MaxSize
+func (c *RegistryCache) GC() error {
+	type recordFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []recordFile
+	var totalSize int64
+	if err := filepath.Walk(c.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		// skip temporary files and the GC stamp
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+
+		files = append(files, recordFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		totalSize += info.Size()
+		return nil
+	}); err != nil {
+		return fmt.Errorf("unable to walk registry cache dir %s: %w", c.Dir, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	for _, f := range files {
+		if time.Since(f.modTime) <= c.MaxAge && totalSize <= c.MaxSize {
+			break
+		}
+
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to remove registry cache record %s: %w", f.path, err)
+		}
+		totalSize -= f.size
+	}
+
+	return nil
+}
+
+func isDigest(reference string) bool {
+	hash := strings.TrimPrefix(reference, "sha256:")
+	if hash == reference || len(hash) != 64 {
+		return false
+	}
+
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+func isRedirect(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,266 @@
+package docker_registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type requestCounter struct {
+	mutex    sync.Mutex
+	requests []string
+}
+
+func (c *requestCounter) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mutex.Lock()
+		c.requests = append(c.requests, r.Method+" "+r.URL.Path)
+		c.mutex.Unlock()
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (c *requestCounter) count(method, pathPart string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var res int
+	for _, r := range c.requests {
+		if strings.HasPrefix(r, method+" ") && strings.Contains(r, pathPart) {
+			res++
+		}
+	}
+
+	return res
+}
+
+var _ = Describe("RegistryCache", func() {
+	var counter *requestCounter
+	var repository string
+	var cachedApi *api
+	var digest string
+
+	BeforeEach(func() {
+		counter = &requestCounter{}
+		server := httptest.NewServer(counter.wrap(registry.New()))
+		DeferCleanup(server.Close)
+
+		repository = strings.TrimPrefix(server.URL, "http://") + "/project"
+
+		img, err := random.Image(1024, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		ref, err := name.ParseReference(repository+":tag", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(ref, img)).Should(Succeed())
+
+		imgDigest, err := img.Digest()
+		Ω(err).ShouldNot(HaveOccurred())
+		digest = imgDigest.String()
+
+		InitRegistryCache(GinkgoT().TempDir(), time.Minute)
+		DeferCleanup(func() { registryCache = nil })
+
+		cachedApi = newAPI(apiOptions{InsecureRegistry: true})
+	})
+
+	It("should request the manifest by tag with HEAD and the image config only once", func() {
+		ctx := context.Background()
+
+		for i := 0; i < 3; i++ {
+			info, err := cachedApi.GetRepoImage(ctx, repository+":tag")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(info.Layers).Should(HaveLen(1))
+		}
+
+		Ω(counter.count(http.MethodGet, "/manifests/tag")).Should(Equal(1))
+		Ω(counter.count(http.MethodHead, "/manifests/tag")).Should(Equal(3))
+		Ω(counter.count(http.MethodGet, "/blobs/")).Should(Equal(1))
+	})
+
+	It("should cache tag listings until the repo is changed", func() {
+		ctx := context.Background()
+
+		for i := 0; i < 2; i++ {
+			tags, err := cachedApi.Tags(ctx, repository)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(tags).Should(Equal([]string{"tag"}))
+		}
+		Ω(counter.count(http.MethodGet, "/tags/list")).Should(Equal(1))
+
+		Ω(cachedApi.tagImage(ctx, repository+":tag", "new-tag")).Should(Succeed())
+
+		tags, err := cachedApi.Tags(ctx, repository)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(ConsistOf("tag", "new-tag"))
+		Ω(counter.count(http.MethodGet, "/tags/list")).Should(Equal(2))
+	})
+
+	It("should request the actual tag listings if cached tags are not allowed", func() {
+		ctx := context.Background()
+
+		dockerRegistry := newDockerRegistryWithCache(&defaultImplementation{api: cachedApi, Implementation: DefaultImplementationName})
+
+		for i := 0; i < 2; i++ {
+			_, err := dockerRegistry.Tags(ctx, repository)
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		Ω(counter.count(http.MethodGet, "/tags/list")).Should(Equal(2))
+
+		// another process pushes the tag, the in-process cache does not know it
+		img, err := random.Image(1024, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		ref, err := name.ParseReference(repository+":pushed-by-another-process", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(ref, img)).Should(Succeed())
+
+		tags, err := newDockerRegistryWithCache(&defaultImplementation{api: cachedApi, Implementation: DefaultImplementationName}).Tags(ctx, repository, WithCachedTags())
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(Equal([]string{"tag"}))
+		Ω(counter.count(http.MethodGet, "/tags/list")).Should(Equal(2))
+
+		tags, err = dockerRegistry.Tags(ctx, repository)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(ConsistOf("tag", "pushed-by-another-process"))
+		Ω(counter.count(http.MethodGet, "/tags/list")).Should(Equal(3))
+	})
+
+	It("should not serve the cached manifest for another repository", func() {
+		ctx := context.Background()
+
+		_, err := cachedApi.GetRepoImage(ctx, repository+"@"+digest)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = cachedApi.GetRepoImage(ctx, repository+"-other@"+digest)
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("should not serve the cached manifest deleted from the registry", func() {
+		ctx := context.Background()
+
+		_, err := cachedApi.GetRepoImage(ctx, repository+"@"+digest)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		ref, err := name.ParseReference(repository+"@"+digest, name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Delete(ref)).Should(Succeed())
+
+		_, err = cachedApi.GetRepoImage(ctx, repository+"@"+digest)
+		Ω(err).Should(HaveOccurred())
+	})
+})
+
+type registryCacheTestKeychain struct {
+	username string
+}
+
+func (k *registryCacheTestKeychain) Resolve(authn.Resource) (authn.Authenticator, error) {
+	return &authn.Basic{Username: k.username, Password: "password"}, nil
+}
+
+var _ = Describe("RegistryCache tag listings", func() {
+	It("should store tag listings per username instead of the request token", func() {
+		counter := &requestCounter{}
+		server := httptest.NewServer(counter.wrap(registry.New()))
+		DeferCleanup(server.Close)
+
+		img, err := random.Image(1024, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+		ref, err := name.ParseReference(strings.TrimPrefix(server.URL, "http://")+"/project:tag", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(ref, img)).Should(Succeed())
+
+		keychain := &registryCacheTestKeychain{username: "user"}
+		cache := &RegistryCache{Dir: GinkgoT().TempDir(), TagsTTL: time.Minute, MaxSize: registryCacheMaxSize, MaxAge: registryCacheMaxAge, GCPeriod: registryCacheGCPeriod, Keychain: keychain}
+		transport := cache.transport(http.DefaultTransport)
+
+		listTags := func(token string) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/v2/project/tags/list", nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := transport.RoundTrip(req)
+			Ω(err).ShouldNot(HaveOccurred())
+			resp.Body.Close()
+		}
+
+		listTags("token-1")
+		listTags("token-2")
+		Ω(counter.count(http.MethodGet, "/tags/list")).Should(Equal(1))
+
+		keychain.username = "another-user"
+		listTags("token-3")
+		Ω(counter.count(http.MethodGet, "/tags/list")).Should(Equal(2))
+
+		tagsDirEntries, err := os.ReadDir(cache.tagsDir(strings.TrimPrefix(server.URL, "http://"), "project"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tagsDirEntries).Should(HaveLen(2))
+	})
+})
+
+var _ = Describe("RegistryCache GC", func() {
+	var cache *RegistryCache
+
+	writeRecord := func(name string, size int, age time.Duration) string {
+		path := filepath.Join(cache.Dir, "blobs", name)
+		Ω(os.MkdirAll(filepath.Dir(path), 0o755)).Should(Succeed())
+		Ω(os.WriteFile(path, []byte("{}"+strings.Repeat(" ", size-2)), 0o644)).Should(Succeed())
+
+		modTime := time.Now().Add(-age)
+		Ω(os.Chtimes(path, modTime, modTime)).Should(Succeed())
+
+		return path
+	}
+
+	BeforeEach(func() {
+		cache = &RegistryCache{Dir: GinkgoT().TempDir(), MaxSize: 100, MaxAge: time.Hour, GCPeriod: time.Hour}
+	})
+
+	It("should remove records not used for MaxAge", func() {
+		oldPath := writeRecord("old", 10, 2*time.Hour)
+		recentPath := writeRecord("recent", 10, time.Minute)
+
+		Ω(cache.GC()).Should(Succeed())
+
+		Ω(oldPath).ShouldNot(BeAnExistingFile())
+		Ω(recentPath).Should(BeAnExistingFile())
+	})
+
+	It("should remove the least recently used records while the cache exceeds MaxSize", func() {
+		oldestPath := writeRecord("oldest", 40, 3*time.Minute)
+		olderPath := writeRecord("older", 40, 2*time.Minute)
+		recentPath := writeRecord("recent", 40, time.Minute)
+
+		Ω(cache.GC()).Should(Succeed())
+
+		Ω(oldestPath).ShouldNot(BeAnExistingFile())
+		Ω(olderPath).Should(BeAnExistingFile())
+		Ω(recentPath).Should(BeAnExistingFile())
+	})
+
+	It("should keep the record read recently", func() {
+		usedPath := writeRecord("used", 40, 3*time.Minute)
+		olderPath := writeRecord("older", 40, 2*time.Minute)
+		writeRecord("recent", 40, time.Minute)
+
+		Ω(cache.readRecord(usedPath)).ShouldNot(BeNil())
+		Ω(cache.GC()).Should(Succeed())
+
+		Ω(usedPath).Should(BeAnExistingFile())
+		Ω(olderPath).ShouldNot(BeAnExistingFile())
+	})
+})
@@ -39,6 +39,8 @@ type Local struct {
 type OpenLocalRepoOptions struct {
 	WithServiceHeadCommit bool
 	ServiceBranchOptions  ServiceBranchOptions
+	// HeadCommit overrides the current HEAD commit of the work tree
+	HeadCommit string
 }
 
 type ServiceBranchOptions struct {
@@ -66,6 +68,10 @@ func OpenLocalRepo(ctx context.Context, name, workTreeDir string, opts OpenLocal
 		return l, err
 	}
 
+	if opts.HeadCommit != "" {
+		l.headCommitHash = opts.HeadCommit
+	}
+
 	if opts.WithServiceHeadCommit {
 		if lock, err := CommonGitDataManager.LockGC(ctx, true); err != nil {
 			return nil, err
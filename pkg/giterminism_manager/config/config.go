@@ -84,6 +84,14 @@ func (c Config) IsConfigStapelMountFromPathAccepted(fromPath string) bool {
 	return c.Config.Stapel.Mount.IsFromPathAccepted(fromPath)
 }
 
+func (c Config) IsConfigSecretEnvNameAccepted(envName string) (bool, error) {
+	return c.Config.Secrets.IsEnvNameAccepted(envName)
+}
+
+func (c Config) IsConfigSecretSrcAccepted(src string) bool {
+	return c.Config.Secrets.IsSrcAccepted(src)
+}
+
 func (c Config) IsConfigDockerfileContextAddFileAccepted(relPath string) bool {
 	return c.Config.Dockerfile.IsContextAddFileAccepted(relPath)
 }
@@ -110,6 +118,7 @@ type config struct {
 	GoTemplateRendering       goTemplateRendering `json:"goTemplateRendering"`
 	Stapel                    stapel              `json:"stapel"`
 	Dockerfile                dockerfile          `json:"dockerfile"`
+	Secrets                   secrets             `json:"secrets"`
 }
 
 func (c config) UncommittedTemplateFilePathMatcher() path_matcher.PathMatcher {
@@ -122,30 +131,7 @@ type goTemplateRendering struct {
 }
 
 func (r goTemplateRendering) IsEnvNameAccepted(name string) (bool, error) {
-	for _, pattern := range r.AllowEnvVariables {
-		match, err := func() (bool, error) {
-			if strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
-				expr := fmt.Sprintf("^%s$", pattern[1:len(pattern)-1])
-				r, err := regexp.Compile(expr)
-				if err != nil {
-					return false, err
-				}
-
-				return r.MatchString(name), nil
-			} else {
-				return pattern == name, nil
-			}
-		}()
-		if err != nil {
-			return false, err
-		}
-
-		if match {
-			return true, nil
-		}
-	}
-
-	return false, nil
+	return isEnvNameMatched(r.AllowEnvVariables, name)
 }
 
 func (r goTemplateRendering) UncommittedFilePathMatcher() path_matcher.PathMatcher {
@@ -189,6 +175,19 @@ func (d dockerfile) IsUncommittedDockerignoreAccepted(path string) bool {
 	return isPathMatched(d.AllowUncommittedDockerignoreFiles, path)
 }
 
+type secrets struct {
+	AllowEnvVariables []string `json:"allowEnvVariables"`
+	AllowFiles        []string `json:"allowFiles"`
+}
+
+func (s secrets) IsEnvNameAccepted(name string) (bool, error) {
+	return isEnvNameMatched(s.AllowEnvVariables, name)
+}
+
+func (s secrets) IsSrcAccepted(path string) bool {
+	return isPathMatched(s.AllowFiles, path)
+}
+
 type helm struct {
 	AllowUncommittedFiles []string `json:"allowUncommittedFiles"`
 }
@@ -208,3 +207,31 @@ func pathMatcher(patterns []string) path_matcher.PathMatcher {
 		return path_matcher.NewFalsePathMatcher()
 	}
 }
+
+// isEnvNameMatched checks the env name against the exact names and the /REGEXP/ patterns
+func isEnvNameMatched(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		match, err := func() (bool, error) {
+			if strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+				expr := fmt.Sprintf("^%s$", pattern[1:len(pattern)-1])
+				r, err := regexp.Compile(expr)
+				if err != nil {
+					return false, err
+				}
+
+				return r.MatchString(name), nil
+			} else {
+				return pattern == name, nil
+			}
+		}()
+		if err != nil {
+			return false, err
+		}
+
+		if match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -53,6 +53,8 @@ definitions:
         $ref: '#/definitions/ConfigStapel'
       dockerfile:
         $ref: '#/definitions/ConfigDockerfile'
+      secrets:
+        $ref: '#/definitions/ConfigSecrets'
   ConfigGoTemplateRendering:
     type: object
     additionalProperties: {}
@@ -107,6 +109,18 @@ definitions:
         type: array
         items:
           type: string
+  ConfigSecrets:
+    type: object
+    additionalProperties: {}
+    properties:
+      allowEnvVariables:
+        type: array
+        items:
+          type: string
+      allowFiles:
+        type: array
+        items:
+          type: string
   Helm:
     type: object
     additionalProperties: {}
@@ -26,6 +26,8 @@ type giterminismConfig interface {
 	IsConfigStapelMountBuildDirAccepted() bool
 	IsConfigStapelMountFromPathAccepted(fromPath string) bool
 	IsConfigDockerfileContextAddFileAccepted(relPath string) bool
+	IsConfigSecretEnvNameAccepted(envName string) (bool, error)
+	IsConfigSecretSrcAccepted(src string) bool
 }
 
 type fileReader interface {
@@ -0,0 +1,35 @@
+package inspector
+
+import (
+	"fmt"
+)
+
+func (i Inspector) InspectConfigSecretEnv(envName string) error {
+	if i.sharedOptions.LooseGiterminism() {
+		return nil
+	}
+
+	if isAccepted, err := i.giterminismConfig.IsConfigSecretEnvNameAccepted(envName); err != nil {
+		return err
+	} else if isAccepted {
+		return nil
+	}
+
+	return NewExternalDependencyFoundError(fmt.Sprintf(`"secrets { env: %s, ... }" not allowed by giterminism
+
+The secret value from the environment variable has no effect on the final image digest, thus the image may be built differently in CI jobs and among developers depending on the environment.`, envName))
+}
+
+func (i Inspector) InspectConfigSecretSrc(src string) error {
+	if i.sharedOptions.LooseGiterminism() {
+		return nil
+	}
+
+	if i.giterminismConfig.IsConfigSecretSrcAccepted(src) {
+		return nil
+	}
+
+	return NewExternalDependencyFoundError(fmt.Sprintf(`"secrets { src: %s, ... }" not allowed by giterminism
+
+The secret value from the file has no effect on the final image digest, thus the image may be built differently in CI jobs and among developers depending on the file content.`, src))
+}
@@ -49,5 +49,7 @@ type Inspector interface {
 	InspectConfigStapelMountBuildDir() error
 	InspectConfigStapelMountFromPath(fromPath string) error
 	InspectConfigDockerfileContextAddFile(relPath string) error
+	InspectConfigSecretEnv(envName string) error
+	InspectConfigSecretSrc(src string) error
 	InspectBuildContextFiles(ctx context.Context, matcher path_matcher.PathMatcher) error
 }
@@ -20,6 +20,17 @@ const (
 	WerfCustomTagMetadataStageIDLabel = "stage-id"
 	WerfCustomTagMetadataTag          = "tag"
 
+	WerfVulnerabilityScanMetadataStageIDLabel = "stage-id"
+	WerfVulnerabilityScanMetadataResultLabel  = "vulnerability-scan-result"
+	WerfVulnerabilityScanMetadataScannerLabel = "vulnerability-scanner"
+	WerfVulnerabilityScanMetadataTimeLabel    = "vulnerability-scan-time"
+
+	WerfDigestInputsMetadataStageIDLabel = "stage-id"
+	WerfDigestInputsMetadataStagesLabel  = "stages-digest-inputs"
+
+	// BuildahVersionLabel is set by Buildah for every committed image including the intermediate layers cache images
+	BuildahVersionLabel = "io.buildah.version"
+
 	WerfMountTmpDirLabel          = "werf-mount-type-tmp-dir"
 	WerfMountBuildDirLabel        = "werf-mount-type-build-dir"
 	WerfMountCustomDirLabelPrefix = "werf-mount-type-custom-dir-"
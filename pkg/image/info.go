@@ -11,6 +11,11 @@ import (
 	"github.com/werf/werf/pkg/util"
 )
 
+type LayerInfo struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
 type Info struct {
 	Name       string `json:"name"`
 	Repository string `json:"repository"`
@@ -23,6 +28,7 @@ type Info struct {
 	ParentID          string            `json:"parentID"`
 	Labels            map[string]string `json:"labels"`
 	Size              int64             `json:"size"`
+	Layers            []LayerInfo       `json:"layers,omitempty"`
 	CreatedAtUnixNano int64             `json:"createdAtUnixNano"`
 
 	IsIndex bool
@@ -53,6 +59,7 @@ func (info *Info) GetCopy() *Info {
 		ParentID:          info.ParentID,
 		Labels:            util.CopyMap(info.Labels),
 		Size:              info.Size,
+		Layers:            append([]LayerInfo(nil), info.Layers...),
 		CreatedAtUnixNano: info.CreatedAtUnixNano,
 
 		IsIndex: info.IsIndex,
@@ -11,6 +11,8 @@ type InfoGetter struct {
 	WerfImageName string
 	Repo          string
 	Tag           string
+	// Digest pins the image to the manifest the tag pointed to when the image was verified
+	Digest string
 
 	InfoGetterOptions
 }
@@ -39,6 +41,9 @@ func (d *InfoGetter) GetWerfImageName() string {
 }
 
 func (d *InfoGetter) GetName() string {
+	if d.Digest != "" {
+		return fmt.Sprintf("%s:%s@%s", d.Repo, d.GetTag(), d.Digest)
+	}
 	return fmt.Sprintf("%s:%s", d.Repo, d.GetTag())
 }
 
@@ -11,6 +11,7 @@ var _ = Describe("InfoGetter", func() {
 	DescribeTable("TestInfoGetter",
 		func(data TestInfoGetter) {
 			getter := NewInfoGetter(data.ImageName, data.Ref, data.Opts)
+			getter.Digest = data.Digest
 
 			Expect(getter.IsNameless()).To(Equal(data.ExpectIsNameless))
 			Expect(getter.GetWerfImageName()).To(Equal(data.ExpectWerfImageName))
@@ -54,12 +55,25 @@ var _ = Describe("InfoGetter", func() {
 				ExpectName:          "myregistry.domain.com/group/project:backend-abcd",
 				ExpectTag:           "backend-abcd",
 			}),
+
+		Entry("named image with digest",
+			TestInfoGetter{
+				ImageName:           "backend",
+				Ref:                 "myregistry.domain.com/group/project:abcd",
+				Digest:              "sha256:8a3b8f2e9b1c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6",
+				Opts:                InfoGetterOptions{},
+				ExpectIsNameless:    false,
+				ExpectWerfImageName: "backend",
+				ExpectName:          "myregistry.domain.com/group/project:abcd@sha256:8a3b8f2e9b1c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6",
+				ExpectTag:           "abcd",
+			}),
 	)
 })
 
 type TestInfoGetter struct {
 	ImageName string
 	Ref       string
+	Digest    string
 	Opts      InfoGetterOptions
 
 	ExpectIsNameless    bool
@@ -3,6 +3,7 @@ package image
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,7 +29,7 @@ func (id StageID) String() string {
 }
 
 func (id StageID) UniqueIDAsTime() time.Time {
-	return time.Unix(id.UniqueID/1000, id.UniqueID%1000)
+	return time.UnixMilli(id.UniqueID)
 }
 
 func (id StageID) IsEqual(another StageID) bool {
@@ -40,6 +41,21 @@ type StageDescription struct {
 	Info    *Info    `json:"info"`
 }
 
+// ParseStageID parses the stage ID string in the DIGEST-UNIQUEID or DIGEST (multiplatform stage) format
+func ParseStageID(stageID string) (*StageID, error) {
+	parts := strings.SplitN(stageID, "-", 2)
+	if len(parts) == 1 {
+		return NewStageID(parts[0], 0), nil
+	}
+
+	uniqueID, err := ParseUniqueIDAsTimestamp(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse unique ID %q of stage ID %q: %w", parts[1], stageID, err)
+	}
+
+	return NewStageID(parts[0], uniqueID), nil
+}
+
 func ParseUniqueIDAsTimestamp(uniqueID string) (int64, error) {
 	if timestamp, err := strconv.ParseInt(uniqueID, 10, 64); err != nil {
 		return 0, err
@@ -48,6 +64,16 @@ func ParseUniqueIDAsTimestamp(uniqueID string) (int64, error) {
 	}
 }
 
+// GetBuiltAt returns the time the stage was built at. The unique ID is the build time of the stage,
+// unlike the image creation time, which is normalized to SOURCE_DATE_EPOCH in the reproducible builds
+func (desc *StageDescription) GetBuiltAt() time.Time {
+	if desc.StageID != nil && desc.StageID.UniqueID != 0 {
+		return desc.StageID.UniqueIDAsTime()
+	}
+
+	return desc.Info.GetCreatedAt()
+}
+
 func (desc *StageDescription) GetCopy() *StageDescription {
 	return &StageDescription{
 		StageID: NewStageID(desc.StageID.Digest, desc.StageID.UniqueID),
@@ -0,0 +1,91 @@
+package sbom
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+// CycloneDX 1.5 JSON document (https://cyclonedx.org/docs/1.5/json/)
+type cycloneDXDocument struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDXMetadata    `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string               `json:"timestamp"`
+	Tools     []cycloneDXComponent `json:"tools"`
+	Component cycloneDXComponent   `json:"component"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Type       string              `json:"type,omitempty"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Licenses   []cycloneDXLicense  `json:"licenses,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// encodeCycloneDX produces the same document for the same subject: the serial number is derived from the subject digest
+// and the timestamp is the creation time of the image, so the pushed artifact does not change between builds
+func encodeCycloneDX(subject string, created time.Time, inventory *Inventory) ([]byte, error) {
+	doc := cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.NewSHA1(uuid.NameSpaceURL, []byte(subject)).String(),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Tools:     []cycloneDXComponent{{Type: "application", Name: "werf", Version: werf.Version}},
+			Component: cycloneDXComponent{BOMRef: subject, Type: "container", Name: subject},
+		},
+		Components: []cycloneDXComponent{},
+	}
+
+	if inventory.OS != nil && inventory.OS.ID != "" {
+		doc.Components = append(doc.Components, cycloneDXComponent{
+			BOMRef:  "os:" + inventory.OS.ID,
+			Type:    "operating-system",
+			Name:    inventory.OS.ID,
+			Version: inventory.OS.VersionID,
+		})
+	}
+
+	for _, pkg := range inventory.Packages {
+		purl := packageURL(pkg, inventory.OS)
+
+		component := cycloneDXComponent{
+			BOMRef:     purl,
+			Type:       "library",
+			Name:       pkg.Name,
+			Version:    pkg.Version,
+			PURL:       purl,
+			Properties: []cycloneDXProperty{{Name: "werf:package:database", Value: pkg.Database}},
+		}
+		if pkg.License != "" {
+			component.Licenses = []cycloneDXLicense{{Expression: pkg.License}}
+		}
+
+		doc.Components = append(doc.Components, component)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
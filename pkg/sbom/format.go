@@ -0,0 +1,55 @@
+package sbom
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+type Format string
+
+const (
+	FormatSPDX      Format = "spdx"
+	FormatCycloneDX Format = "cyclonedx"
+)
+
+const (
+	SPDXMediaType      types.MediaType = "application/spdx+json"
+	CycloneDXMediaType types.MediaType = "application/vnd.cyclonedx+json"
+)
+
+var Formats = []Format{FormatSPDX, FormatCycloneDX}
+
+func ParseFormat(value string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(value, string(format)) {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported SBOM format %q: expected one of %v", value, Formats)
+}
+
+func (format Format) MediaType() types.MediaType {
+	switch format {
+	case FormatSPDX:
+		return SPDXMediaType
+	case FormatCycloneDX:
+		return CycloneDXMediaType
+	default:
+		panic(fmt.Sprintf("unknown SBOM format %q", format))
+	}
+}
+
+func (format Format) encode(subject string, created time.Time, inventory *Inventory) ([]byte, error) {
+	switch format {
+	case FormatSPDX:
+		return encodeSPDX(subject, created, inventory)
+	case FormatCycloneDX:
+		return encodeCycloneDX(subject, created, inventory)
+	default:
+		panic(fmt.Sprintf("unknown SBOM format %q", format))
+	}
+}
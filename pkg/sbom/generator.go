@@ -0,0 +1,207 @@
+package sbom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+type Generator struct {
+	Format Format
+}
+
+func NewGenerator(format Format) *Generator {
+	return &Generator{Format: format}
+}
+
+// Artifact is the SBOM pushed into the container registry as an OCI referrer artifact of the image manifest
+type Artifact struct {
+	// Platform is empty for the single-platform image
+	Platform  string
+	Format    Format
+	MediaType types.MediaType
+	// Subject is the image manifest described by the SBOM
+	Subject string
+	// Reference is the SBOM artifact manifest (REPO@DIGEST)
+	Reference string
+	Digest    string
+	// Packages is not counted for the existing SBOM
+	Packages int
+	// Existing is true if the SBOM of the subject has been pushed before and is not generated again
+	Existing bool
+}
+
+// ArtifactTag returns the tag of the SBOM artifact of the subject manifest (sha256-HEX.FORMAT):
+// the tag protects the artifact from the registry garbage collection of untagged manifests,
+// allows to find the existing SBOM without scanning the image and to delete it together with the subject
+func ArtifactTag(subjectDigest v1.Hash, format Format) string {
+	return fmt.Sprintf("%s-%s.%s", subjectDigest.Algorithm, subjectDigest.Hex, format)
+}
+
+// Generate generates the SBOM for the image manifest or for every image manifest of the manifest list (index)
+// the reference points to and pushes the SBOMs into the same repository as referrers of the described manifests.
+// The image is not scanned if its SBOM of the format already exists
+func (g *Generator) Generate(ctx context.Context, registry docker_registry.Interface, reference string) ([]*Artifact, error) {
+	ref, err := name.ParseReference(reference, name.WeakValidation)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse reference %q: %w", reference, err)
+	}
+	repository := ref.Context().Name()
+
+	desc, err := registry.GetManifestDescriptor(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if !desc.MediaType.IsIndex() {
+		img, err := registry.TryGetRemoteImage(ctx, repositoryDigest(repository, desc.Digest))
+		if err != nil {
+			return nil, err
+		}
+		if img == nil {
+			return nil, fmt.Errorf("image %s not found", reference)
+		}
+
+		artifact, err := g.generateForImage(ctx, registry, repository, img, *desc, "")
+		if err != nil {
+			return nil, err
+		}
+
+		return []*Artifact{artifact}, nil
+	}
+
+	idx, err := registry.GetRemoteIndex(ctx, repositoryDigest(repository, desc.Digest))
+	if err != nil {
+		return nil, err
+	}
+
+	indexManifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get index manifest of %s: %w", reference, err)
+	}
+
+	var artifacts []*Artifact
+	for _, manifestDesc := range indexManifest.Manifests {
+		if !manifestDesc.MediaType.IsImage() {
+			continue
+		}
+
+		img, err := idx.Image(manifestDesc.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get image %s: %w", repositoryDigest(repository, manifestDesc.Digest), err)
+		}
+
+		var platform string
+		if manifestDesc.Platform != nil {
+			platform = manifestDesc.Platform.String()
+		}
+
+		artifact, err := g.generateForImage(ctx, registry, repository, img, manifestDesc, platform)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	return artifacts, nil
+}
+
+func (g *Generator) generateForImage(ctx context.Context, registry docker_registry.Interface, repository string, img v1.Image, desc v1.Descriptor, platform string) (*Artifact, error) {
+	subject := repositoryDigest(repository, desc.Digest)
+	artifactTagReference := fmt.Sprintf("%s:%s", repository, ArtifactTag(desc.Digest, g.Format))
+
+	existingArtifactImage, err := registry.TryGetRemoteImage(ctx, artifactTagReference)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingArtifactImage != nil {
+		artifactDigest, err := existingArtifactImage.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get SBOM artifact digest: %w", err)
+		}
+
+		artifactReference := repositoryDigest(repository, artifactDigest)
+		logboek.Context(ctx).Default().LogFDetails("Image: %s\n", subject)
+		logboek.Context(ctx).Default().LogFDetails("SBOM: %s (already exists)\n", artifactReference)
+
+		return &Artifact{
+			Platform:  platform,
+			Format:    g.Format,
+			MediaType: g.Format.MediaType(),
+			Subject:   subject,
+			Reference: artifactReference,
+			Digest:    artifactDigest.String(),
+			Existing:  true,
+		}, nil
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image config of %s: %w", subject, err)
+	}
+
+	inventory, err := ScanImage(img)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan image %s: %w", subject, err)
+	}
+
+	for _, database := range inventory.UnsupportedDatabases {
+		logboek.Context(ctx).Warn().LogF("WARNING: Packages of the database %s found in the image %s are not listed in the SBOM: the database format is not supported\n", database, subject)
+	}
+
+	data, err := g.Format.encode(subject, configFile.Created.Time, inventory)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode %s SBOM of %s: %w", g.Format, subject, err)
+	}
+
+	artifactImage := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), g.Format.MediaType())
+	artifactImage, err = mutate.Append(artifactImage, mutate.Addendum{
+		Layer:     static.NewLayer(data, g.Format.MediaType()),
+		MediaType: g.Format.MediaType(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to add SBOM layer: %w", err)
+	}
+	artifactImage = mutate.Subject(artifactImage, v1.Descriptor{
+		MediaType: desc.MediaType,
+		Digest:    desc.Digest,
+		Size:      desc.Size,
+	}).(v1.Image)
+
+	artifactDigest, err := artifactImage.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("unable to calculate SBOM artifact digest: %w", err)
+	}
+
+	artifactReference := repositoryDigest(repository, artifactDigest)
+	if err := registry.PushRemoteImage(ctx, artifactTagReference, artifactImage); err != nil {
+		return nil, fmt.Errorf("unable to push SBOM of %s: %w", subject, err)
+	}
+
+	logboek.Context(ctx).Default().LogFDetails("Image: %s\n", subject)
+	logboek.Context(ctx).Default().LogFDetails("SBOM: %s (%d packages)\n", artifactReference, len(inventory.Packages))
+
+	return &Artifact{
+		Platform:  platform,
+		Format:    g.Format,
+		MediaType: g.Format.MediaType(),
+		Subject:   subject,
+		Reference: artifactReference,
+		Digest:    artifactDigest.String(),
+		Packages:  len(inventory.Packages),
+	}, nil
+}
+
+func repositoryDigest(repository string, digest v1.Hash) string {
+	return fmt.Sprintf("%s@%s", repository, digest)
+}
@@ -0,0 +1,38 @@
+package sbom
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// packageURL returns the package URL (https://github.com/package-url/purl-spec) of the package
+func packageURL(pkg Package, os *OS) string {
+	namespace := "unknown"
+	if os != nil && os.ID != "" {
+		namespace = os.ID
+	}
+
+	purl := fmt.Sprintf("pkg:%s/%s/%s@%s", pkg.Type, purlEscape(namespace), purlEscape(pkg.Name), purlEscape(pkg.Version))
+
+	var qualifiers []string
+	if pkg.Arch != "" {
+		qualifiers = append(qualifiers, "arch="+purlEscape(pkg.Arch))
+	}
+	if os != nil && os.ID != "" && os.VersionID != "" {
+		qualifiers = append(qualifiers, "distro="+purlEscape(os.ID+"-"+os.VersionID))
+	}
+	if pkg.Source != "" && pkg.Source != pkg.Name {
+		qualifiers = append(qualifiers, "upstream="+purlEscape(pkg.Source))
+	}
+
+	if len(qualifiers) > 0 {
+		purl += "?" + strings.Join(qualifiers, "&")
+	}
+
+	return purl
+}
+
+func purlEscape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
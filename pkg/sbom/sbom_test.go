@@ -0,0 +1,247 @@
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+const debianOSRelease = `PRETTY_NAME="Debian GNU/Linux 12 (bookworm)"
+ID=debian
+VERSION_ID="12"
+`
+
+const dpkgStatus = `Package: base-files
+Status: install ok installed
+Architecture: amd64
+Version: 12.4+deb12u1
+
+Package: removed
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0
+
+Package: libc6
+Status: install ok installed
+Architecture: amd64
+Source: glibc (2.36-9)
+Version: 2.36-9+deb12u1
+Description: GNU C Library
+ Multiline description.
+`
+
+const apkInstalled = `C:Q1
+P:musl
+V:1.2.4-r2
+A:x86_64
+L:MIT
+o:musl
+
+P:busybox
+V:1.36.1-r5
+A:x86_64
+L:GPL-2.0-only
+`
+
+func newImage(layers ...map[string][]byte) v1.Image {
+	img := empty.Image
+	for _, files := range layers {
+		layer, err := crane.Layer(files)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		img, err = mutate.AppendLayers(img, layer)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+	return img
+}
+
+var _ = Describe("ScanImage", func() {
+	It("should list installed dpkg packages from the final filesystem", func() {
+		img := newImage(
+			map[string][]byte{"etc/os-release": []byte(debianOSRelease), "var/lib/dpkg/status": []byte("Package: old\nStatus: install ok installed\nVersion: 1\n")},
+			map[string][]byte{"var/lib/dpkg/status": []byte(dpkgStatus), "var/lib/rpm/rpmdb.sqlite": []byte("")},
+		)
+
+		inventory, err := ScanImage(img)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(inventory.OS).Should(Equal(&OS{ID: "debian", VersionID: "12", PrettyName: "Debian GNU/Linux 12 (bookworm)"}))
+		Ω(inventory.Databases).Should(Equal([]string{"var/lib/dpkg/status"}))
+		Ω(inventory.UnsupportedDatabases).Should(Equal([]string{"var/lib/rpm/rpmdb.sqlite"}))
+		Ω(inventory.Packages).Should(Equal([]Package{
+			{Type: PackageTypeDeb, Name: "base-files", Version: "12.4+deb12u1", Arch: "amd64", Database: "var/lib/dpkg/status"},
+			{Type: PackageTypeDeb, Name: "libc6", Version: "2.36-9+deb12u1", Arch: "amd64", Source: "glibc", Database: "var/lib/dpkg/status"},
+		}))
+		Ω(packageURL(inventory.Packages[1], inventory.OS)).Should(Equal("pkg:deb/debian/libc6@2.36-9%2Bdeb12u1?arch=amd64&distro=debian-12&upstream=glibc"))
+	})
+
+	It("should list apk packages", func() {
+		inventory, err := ScanImage(newImage(map[string][]byte{"lib/apk/db/installed": []byte(apkInstalled)}))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(inventory.Packages).Should(Equal([]Package{
+			{Type: PackageTypeApk, Name: "busybox", Version: "1.36.1-r5", Arch: "x86_64", License: "GPL-2.0-only", Database: "lib/apk/db/installed"},
+			{Type: PackageTypeApk, Name: "musl", Version: "1.2.4-r2", Arch: "x86_64", Source: "musl", License: "MIT", Database: "lib/apk/db/installed"},
+		}))
+	})
+
+	It("should fail on the unreadable package database instead of skipping packages", func() {
+		_, err := ScanImage(newImage(map[string][]byte{"var/lib/dpkg/status": []byte("Package: " + strings.Repeat("a", 2*1024*1024) + "\n")}))
+		Ω(err).Should(MatchError(ContainSubstring("var/lib/dpkg/status")))
+	})
+})
+
+var _ = Describe("Format", func() {
+	DescribeTable("should encode the same document for the same subject",
+		func(format Format) {
+			inventory, err := ScanImage(newImage(map[string][]byte{"lib/apk/db/installed": []byte(apkInstalled)}))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			subject := "registry.example.com/project@sha256:8a3b8f2e9b1c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6"
+			created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+			data, err := format.encode(subject, created, inventory)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(data)).Should(ContainSubstring("2023-01-02T03:04:05Z"))
+
+			otherData, err := format.encode(subject, created, inventory)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(otherData).Should(Equal(data))
+		},
+		Entry("SPDX", FormatSPDX),
+		Entry("CycloneDX", FormatCycloneDX),
+	)
+})
+
+var _ = Describe("Generator", func() {
+	var ctx context.Context
+	var dockerRegistry docker_registry.Interface
+	var repository string
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		server := httptest.NewServer(registry.New())
+		DeferCleanup(server.Close)
+
+		repository = strings.TrimPrefix(server.URL, "http://") + "/project"
+
+		var err error
+		dockerRegistry, err = docker_registry.NewDockerRegistry(repository, docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{InsecureRegistry: true})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	push := func(reference string, img v1.Image) v1.Hash {
+		ref, err := name.ParseReference(reference, name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(ref, img)).Should(Succeed())
+
+		digest, err := img.Digest()
+		Ω(err).ShouldNot(HaveOccurred())
+		return digest
+	}
+
+	referrers := func(digest v1.Hash) []v1.Descriptor {
+		ref, err := name.NewDigest(repository+"@"+digest.String(), name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		index, err := remote.Referrers(ref)
+		Ω(err).ShouldNot(HaveOccurred())
+		return index.Manifests
+	}
+
+	It("should push SPDX SBOM as the referrer of the image", func() {
+		digest := push(repository+":tag", newImage(map[string][]byte{"etc/os-release": []byte(debianOSRelease), "var/lib/dpkg/status": []byte(dpkgStatus)}))
+
+		artifacts, err := NewGenerator(FormatSPDX).Generate(ctx, dockerRegistry, repository+":tag")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(artifacts).Should(HaveLen(1))
+		Ω(artifacts[0].Subject).Should(Equal(repository + "@" + digest.String()))
+		Ω(artifacts[0].Packages).Should(Equal(2))
+
+		descs := referrers(digest)
+		Ω(descs).Should(HaveLen(1))
+		Ω(descs[0].Digest.String()).Should(Equal(artifacts[0].Digest))
+		Ω(descs[0].ArtifactType).Should(Equal(string(SPDXMediaType)))
+
+		ref, err := name.ParseReference(artifacts[0].Reference, name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		artifactImage, err := remote.Image(ref)
+		Ω(err).ShouldNot(HaveOccurred())
+		layers, err := artifactImage.Layers()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(layers).Should(HaveLen(1))
+
+		rc, err := layers[0].Uncompressed()
+		Ω(err).ShouldNot(HaveOccurred())
+		defer rc.Close()
+
+		var doc spdxDocument
+		Ω(json.NewDecoder(rc).Decode(&doc)).Should(Succeed())
+		Ω(doc.SPDXVersion).Should(Equal("SPDX-2.3"))
+		Ω(doc.Packages).Should(HaveLen(3))
+	})
+
+	It("should not generate and push the existing SBOM again", func() {
+		digest := push(repository+":tag", newImage(map[string][]byte{"lib/apk/db/installed": []byte(apkInstalled)}))
+
+		generator := NewGenerator(FormatSPDX)
+		artifacts, err := generator.Generate(ctx, dockerRegistry, repository+":tag")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(artifacts).Should(HaveLen(1))
+		Ω(artifacts[0].Existing).Should(BeFalse())
+
+		existingArtifacts, err := generator.Generate(ctx, dockerRegistry, repository+":tag")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(existingArtifacts).Should(HaveLen(1))
+		Ω(existingArtifacts[0].Existing).Should(BeTrue())
+		Ω(existingArtifacts[0].Digest).Should(Equal(artifacts[0].Digest))
+
+		Ω(referrers(digest)).Should(HaveLen(1))
+
+		tags, err := dockerRegistry.Tags(ctx, repository)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(ContainElement(ArtifactTag(digest, FormatSPDX)))
+	})
+
+	It("should push CycloneDX SBOM for every image of the index", func() {
+		amd64 := newImage(map[string][]byte{"lib/apk/db/installed": []byte(apkInstalled)})
+		arm64 := newImage(map[string][]byte{"lib/apk/db/installed": []byte(apkInstalled), "arm64": []byte("")})
+		index := mutate.AppendManifests(empty.Index,
+			mutate.IndexAddendum{Add: amd64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}},
+			mutate.IndexAddendum{Add: arm64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64"}}},
+		)
+
+		ref, err := name.ParseReference(repository+":multiplatform", name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.WriteIndex(ref, index)).Should(Succeed())
+
+		artifacts, err := NewGenerator(FormatCycloneDX).Generate(ctx, dockerRegistry, repository+":multiplatform")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(artifacts).Should(HaveLen(2))
+		Ω(artifacts[0].Platform).Should(Equal("linux/amd64"))
+		Ω(artifacts[1].Platform).Should(Equal("linux/arm64"))
+
+		for i, img := range []v1.Image{amd64, arm64} {
+			digest, err := img.Digest()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			descs := referrers(digest)
+			Ω(descs).Should(HaveLen(1))
+			Ω(descs[0].Digest.String()).Should(Equal(artifacts[i].Digest))
+			Ω(descs[0].ArtifactType).Should(Equal(string(CycloneDXMediaType)))
+		}
+	})
+})
@@ -0,0 +1,306 @@
+package sbom
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+const (
+	PackageTypeDeb = "deb"
+	PackageTypeApk = "apk"
+)
+
+const (
+	dpkgStatusPath    = "var/lib/dpkg/status"
+	dpkgStatusDirPath = "var/lib/dpkg/status.d"
+	apkInstalledPath  = "lib/apk/db/installed"
+	etcOSReleasePath  = "etc/os-release"
+	usrOSReleasePath  = "usr/lib/os-release"
+)
+
+// rpmDatabasePaths are only detected and reported, the packages of these databases are not listed
+var rpmDatabasePaths = []string{
+	"var/lib/rpm/Packages",
+	"var/lib/rpm/Packages.db",
+	"var/lib/rpm/rpmdb.sqlite",
+	"usr/lib/sysimage/rpm/Packages.db",
+	"usr/lib/sysimage/rpm/rpmdb.sqlite",
+}
+
+type Inventory struct {
+	OS       *OS
+	Packages []Package
+	// Databases are the package manager databases found in the image filesystem
+	Databases []string
+	// UnsupportedDatabases are the package manager databases found in the image filesystem which packages are not listed
+	UnsupportedDatabases []string
+}
+
+type OS struct {
+	ID         string
+	VersionID  string
+	PrettyName string
+}
+
+type Package struct {
+	Type     string
+	Name     string
+	Version  string
+	Arch     string
+	Source   string
+	License  string
+	Database string
+}
+
+// ScanImage reads the final filesystem of the image (all layers with whiteouts applied) and lists the packages from the package manager databases
+func ScanImage(img v1.Image) (*Inventory, error) {
+	rc := mutate.Extract(img)
+	defer rc.Close()
+
+	return scanFilesystem(rc)
+}
+
+func scanFilesystem(r io.Reader) (*Inventory, error) {
+	inventory := &Inventory{}
+
+	var dpkgStatusDirFiles []string
+	files := map[string][]byte{}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read image filesystem: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		filePath := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+
+		switch {
+		case filePath == dpkgStatusPath, filePath == apkInstalledPath, filePath == etcOSReleasePath, filePath == usrOSReleasePath:
+		case path.Dir(filePath) == dpkgStatusDirPath:
+			dpkgStatusDirFiles = append(dpkgStatusDirFiles, filePath)
+		case isRpmDatabasePath(filePath):
+			inventory.UnsupportedDatabases = append(inventory.UnsupportedDatabases, filePath)
+			continue
+		default:
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %q: %w", filePath, err)
+		}
+		files[filePath] = data
+	}
+
+	if data, hasFile := files[etcOSReleasePath]; hasFile {
+		inventory.OS = parseOSRelease(data)
+	} else if data, hasFile := files[usrOSReleasePath]; hasFile {
+		inventory.OS = parseOSRelease(data)
+	}
+
+	if data, hasFile := files[dpkgStatusPath]; hasFile {
+		packages, err := parseDpkgStatus(data, dpkgStatusPath)
+		if err != nil {
+			return nil, err
+		}
+		inventory.Databases = append(inventory.Databases, dpkgStatusPath)
+		inventory.Packages = append(inventory.Packages, packages...)
+	}
+
+	sort.Strings(dpkgStatusDirFiles)
+	for _, filePath := range dpkgStatusDirFiles {
+		packages, err := parseDpkgStatus(files[filePath], filePath)
+		if err != nil {
+			return nil, err
+		}
+		inventory.Databases = append(inventory.Databases, filePath)
+		inventory.Packages = append(inventory.Packages, packages...)
+	}
+
+	if data, hasFile := files[apkInstalledPath]; hasFile {
+		packages, err := parseApkInstalled(data, apkInstalledPath)
+		if err != nil {
+			return nil, err
+		}
+		inventory.Databases = append(inventory.Databases, apkInstalledPath)
+		inventory.Packages = append(inventory.Packages, packages...)
+	}
+
+	sort.SliceStable(inventory.Packages, func(i, j int) bool {
+		if inventory.Packages[i].Type != inventory.Packages[j].Type {
+			return inventory.Packages[i].Type < inventory.Packages[j].Type
+		}
+		return inventory.Packages[i].Name < inventory.Packages[j].Name
+	})
+	sort.Strings(inventory.UnsupportedDatabases)
+
+	return inventory, nil
+}
+
+func isRpmDatabasePath(filePath string) bool {
+	for _, p := range rpmDatabasePaths {
+		if filePath == p {
+			return true
+		}
+	}
+	return false
+}
+
+func parseOSRelease(data []byte) *OS {
+	os := &OS{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+
+		switch key {
+		case "ID":
+			os.ID = value
+		case "VERSION_ID":
+			os.VersionID = value
+		case "PRETTY_NAME":
+			os.PrettyName = value
+		}
+	}
+
+	return os
+}
+
+// parseDpkgStatus parses the debian control file format: paragraphs of "Field: value" lines separated by empty lines
+func parseDpkgStatus(data []byte, database string) ([]Package, error) {
+	paragraphs, err := splitParagraphs(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %q: %w", database, err)
+	}
+
+	var packages []Package
+	for _, paragraph := range paragraphs {
+		fields := map[string]string{}
+		for _, line := range paragraph {
+			if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+				continue
+			}
+
+			key, value, found := strings.Cut(line, ":")
+			if !found {
+				continue
+			}
+			fields[key] = strings.TrimSpace(value)
+		}
+
+		if fields["Package"] == "" {
+			continue
+		}
+
+		// Status is absent in the distroless status.d files
+		if status, hasStatus := fields["Status"]; hasStatus && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+
+		source := fields["Source"]
+		if sourceName, _, found := strings.Cut(source, " "); found {
+			source = sourceName
+		}
+
+		packages = append(packages, Package{
+			Type:     PackageTypeDeb,
+			Name:     fields["Package"],
+			Version:  fields["Version"],
+			Arch:     fields["Architecture"],
+			Source:   source,
+			Database: database,
+		})
+	}
+
+	return packages, nil
+}
+
+// parseApkInstalled parses the apk installed database: paragraphs of "K:value" lines separated by empty lines
+func parseApkInstalled(data []byte, database string) ([]Package, error) {
+	paragraphs, err := splitParagraphs(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %q: %w", database, err)
+	}
+
+	var packages []Package
+	for _, paragraph := range paragraphs {
+		pkg := Package{Type: PackageTypeApk, Database: database}
+		for _, line := range paragraph {
+			key, value, found := strings.Cut(line, ":")
+			if !found {
+				continue
+			}
+
+			switch key {
+			case "P":
+				pkg.Name = value
+			case "V":
+				pkg.Version = value
+			case "A":
+				pkg.Arch = value
+			case "o":
+				pkg.Source = value
+			case "L":
+				pkg.License = value
+			}
+		}
+
+		if pkg.Name != "" {
+			packages = append(packages, pkg)
+		}
+	}
+
+	return packages, nil
+}
+
+// splitParagraphs returns an error if the line is too long, the packages are not silently skipped in this case
+func splitParagraphs(data []byte) ([][]string, error) {
+	var paragraphs [][]string
+	var paragraph []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			if len(paragraph) > 0 {
+				paragraphs = append(paragraphs, paragraph)
+				paragraph = nil
+			}
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(paragraph) > 0 {
+		paragraphs = append(paragraphs, paragraph)
+	}
+
+	return paragraphs, nil
+}
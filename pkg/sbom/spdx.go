@@ -0,0 +1,118 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+// SPDX 2.3 JSON document (https://spdx.github.io/spdx-spec/v2.3/)
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseDeclared  string            `json:"licenseDeclared,omitempty"`
+	SourceInfo       string            `json:"sourceInfo,omitempty"`
+	PrimaryPurpose   string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+const spdxNoAssertion = "NOASSERTION"
+
+// encodeSPDX produces the same document for the same subject: the namespace is derived from the subject digest
+// and the creation time is the creation time of the image, so the pushed artifact does not change between builds
+func encodeSPDX(subject string, created time.Time, inventory *Inventory) ([]byte, error) {
+	imageID := "SPDXRef-Image"
+
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              subject,
+		DocumentNamespace: fmt.Sprintf("https://werf.io/spdxdocs/%s", spdxIDSanitize(subject)),
+		CreationInfo: spdxCreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{fmt.Sprintf("Tool: werf-%s", werf.Version)},
+		},
+		Packages: []spdxPackage{
+			{
+				Name:             subject,
+				SPDXID:           imageID,
+				DownloadLocation: spdxNoAssertion,
+				PrimaryPurpose:   "CONTAINER",
+			},
+		},
+		Relationships: []spdxRelationship{
+			{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: imageID},
+		},
+	}
+
+	for i, pkg := range inventory.Packages {
+		pkgID := fmt.Sprintf("SPDXRef-Package-%s-%s-%d", pkg.Type, spdxIDSanitize(pkg.Name), i)
+
+		license := spdxNoAssertion
+		if pkg.License != "" {
+			license = pkg.License
+		}
+
+		doc.Packages = append(doc.Packages, spdxPackage{
+			Name:             pkg.Name,
+			SPDXID:           pkgID,
+			VersionInfo:      pkg.Version,
+			DownloadLocation: spdxNoAssertion,
+			LicenseDeclared:  license,
+			SourceInfo:       fmt.Sprintf("acquired package info from %s", pkg.Database),
+			ExternalRefs: []spdxExternalRef{
+				{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: packageURL(pkg, inventory.OS)},
+			},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: imageID, RelationshipType: "CONTAINS", RelatedSPDXElement: pkgID})
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// spdxIDSanitize leaves only letters, numbers, "." and "-" allowed in the SPDX identifiers
+func spdxIDSanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, value)
+}
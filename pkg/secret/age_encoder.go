@@ -0,0 +1,144 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// AgeEncoder encrypts data for the X25519 recipients in the age format (https://age-encryption.org/v1),
+// so each recipient decrypts data with its own identity. The encrypted age file is encoded with base64:
+//
+//	base64 -d encrypted | age -d -i key.txt
+//
+// Recipients are required only to encrypt data and identities are required only to decrypt data.
+type AgeEncoder struct {
+	Recipients []*age.X25519Recipient
+	Identities []*age.X25519Identity
+}
+
+// GenerateAgeIdentity returns the age identity file data (the same as age-keygen prints) and the recipient of the identity
+func GenerateAgeIdentity() ([]byte, string, error) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, "", err
+	}
+
+	recipient := identity.Recipient().String()
+	data := fmt.Sprintf("# public key: %s\n%s\n", recipient, identity.String())
+	return []byte(data), recipient, nil
+}
+
+// NewAgeEncoder parses the age1... recipients and the identities file data with an AGE-SECRET-KEY-1... identity per line
+func NewAgeEncoder(recipients []string, identitiesData []byte) (*AgeEncoder, error) {
+	encoder := &AgeEncoder{}
+
+	for _, recipient := range recipients {
+		key, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", recipient, err)
+		}
+		encoder.Recipients = append(encoder.Recipients, key)
+	}
+
+	for ind, line := range strings.Split(string(identitiesData), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := age.ParseX25519Identity(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age identity at line %d: %w", ind+1, err)
+		}
+		encoder.Identities = append(encoder.Identities, key)
+	}
+
+	return encoder, nil
+}
+
+func (s *AgeEncoder) Encrypt(data []byte) ([]byte, error) {
+	if len(s.Recipients) == 0 {
+		return nil, fmt.Errorf("no age recipients specified")
+	}
+
+	var recipients []age.Recipient
+	for _, recipient := range s.Recipients {
+		recipients = append(recipients, recipient)
+	}
+
+	file := &bytes.Buffer{}
+	w, err := age.Encrypt(file, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encrypt age data: %w", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("unable to encrypt age data: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("unable to encrypt age data: %w", err)
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(file.Bytes())), nil
+}
+
+func (s *AgeEncoder) Decrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	if len(s.Identities) == 0 {
+		return nil, fmt.Errorf("no age identities specified")
+	}
+
+	file, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age data: %w", err)
+	}
+
+	var identities []age.Identity
+	for _, identity := range s.Identities {
+		identities = append(identities, &ageStanzaIdentity{identity})
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(file), identities...)
+	if err != nil {
+		var noIdentityMatchErr *age.NoIdentityMatchError
+		if errors.As(err, &noIdentityMatchErr) {
+			return nil, fmt.Errorf("no age identity matched any of the recipients")
+		}
+		return nil, fmt.Errorf("invalid age data: %w", err)
+	}
+
+	result, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age data: %w", err)
+	}
+
+	return result, nil
+}
+
+// ageStanzaIdentity unwraps the stanzas one by one and skips the stanzas the identity cannot unwrap,
+// e.g. the stanza of another recipient with the low-order share, so that such stanza does not prevent decryption with the next stanza.
+// The header MAC is checked with the unwrapped file key anyway
+type ageStanzaIdentity struct {
+	*age.X25519Identity
+}
+
+func (i *ageStanzaIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	for _, stanza := range stanzas {
+		fileKey, err := i.X25519Identity.Unwrap([]*age.Stanza{stanza})
+		if err == nil {
+			return fileKey, nil
+		}
+	}
+
+	return nil, age.ErrIncorrectIdentity
+}
@@ -0,0 +1,335 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestGenerateAgeIdentity(t *testing.T) {
+	identityData, recipient, err := GenerateAgeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(recipient, "age1") {
+		t.Errorf("Got unexpected recipient %q", recipient)
+	}
+
+	if !strings.Contains(string(identityData), "\nAGE-SECRET-KEY-1") {
+		t.Errorf("Got unexpected identity data %q", identityData)
+	}
+}
+
+func TestNewAgeEncoder_knownKeys(t *testing.T) {
+	s, err := NewAgeEncoder(
+		[]string{"age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj"},
+		[]byte("# created: 2006-01-02T15:04:05Z\nAGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recipient := s.Identities[0].Recipient().String(); recipient != s.Recipients[0].String() {
+		t.Errorf("Got unexpected identity recipient %q", recipient)
+	}
+
+	encodedData, err := s.Encrypt([]byte("flant"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Decrypt(encodedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "flant" {
+		t.Errorf("\n[EXPECTED]: flant\n[GOT]: %s", result)
+	}
+}
+
+// ageTestFile is "flant" encrypted by the age reference implementation for the known identity AGE-SECRET-KEY-1GFPYYSJZ...
+const ageTestFile = "YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSA5TlhXWUwrK1Q2ZlVERmdzMnNiV01PZzk0bitoeGxBL0Yyc1R1MVhCcUJRCjhlVWRpN21GQlV1eDFFRmJqUFpFQzVlamEwTEZWelIwMU1hb1YwajVoWVUKLS0tIEg2cW91czBWd1FraDhHN3VCZWlHeFVMTWs1TnpOUGpjNkVUM0x5SWV5dUUK0QG6nRFvBm8KJq17TQnWNDQbvGTLNwSmN15IjveEXJeMtIVXJA=="
+
+func TestAgeEncoder_Decrypt_knownFile(t *testing.T) {
+	s, err := NewAgeEncoder(nil, []byte("AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Decrypt([]byte(ageTestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "flant" {
+		t.Errorf("\n[EXPECTED]: flant\n[GOT]: %s", result)
+	}
+}
+
+func TestAgeEncoder_Encrypt_ageCompatible(t *testing.T) {
+	identityData, recipient, err := GenerateAgeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewAgeEncoder([]string{recipient}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err := s.Encrypt([]byte("flant"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := base64.StdEncoding.DecodeString(string(encodedData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identities, err := age.ParseIdentities(bytes.NewReader(identityData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(file), identities...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "flant" {
+		t.Errorf("\n[EXPECTED]: flant\n[GOT]: %s", result)
+	}
+}
+
+// ageLowOrderRecipient adds the X25519 stanza with the low-order (all zeroes) share before the stanza of the recipient
+type ageLowOrderRecipient struct {
+	*age.X25519Recipient
+}
+
+func (r *ageLowOrderRecipient) Wrap(fileKey []byte) ([]*age.Stanza, error) {
+	stanzas, err := r.X25519Recipient.Wrap(fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	lowOrderStanza := &age.Stanza{
+		Type: "X25519",
+		Args: []string{base64.RawStdEncoding.EncodeToString(make([]byte, 32))},
+		Body: make([]byte, 32),
+	}
+
+	return append([]*age.Stanza{lowOrderStanza}, stanzas...), nil
+}
+
+func TestAgeEncoder_Decrypt_lowOrderStanza(t *testing.T) {
+	identityData, recipientString, err := GenerateAgeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipient, err := age.ParseX25519Recipient(recipientString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := &bytes.Buffer{}
+	w, err := age.Encrypt(file, &ageLowOrderRecipient{recipient})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("flant")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewAgeEncoder(nil, identityData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Decrypt([]byte(base64.StdEncoding.EncodeToString(file.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "flant" {
+		t.Errorf("\n[EXPECTED]: flant\n[GOT]: %s", result)
+	}
+}
+
+func TestNewAgeEncoder_negative(t *testing.T) {
+	tests := []struct {
+		name         string
+		recipients   []string
+		identities   string
+		errorMessage string
+	}{
+		{
+			name:         "invalid recipient checksum",
+			recipients:   []string{"age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwq"},
+			errorMessage: `invalid age recipient "age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwq": malformed recipient "age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwq": invalid checksum`,
+		},
+		{
+			name:         "identity as recipient",
+			recipients:   []string{"AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX"},
+			errorMessage: `invalid age recipient "AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX": malformed recipient "AGE-SECRET-KEY-1GFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPYYSJZGFPQ4EGAEX": invalid type "AGE-SECRET-KEY-"`,
+		},
+		{
+			name:         "aes key as identity",
+			identities:   "# comment\n11ac8312520b5ff037bae386ea2e8a07",
+			errorMessage: "invalid age identity at line 2: malformed secret key",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewAgeEncoder(test.recipients, []byte(test.identities))
+			if err == nil {
+				t.Errorf("Expected error: %s", test.errorMessage)
+			} else if !strings.HasPrefix(err.Error(), test.errorMessage) {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.errorMessage, err.Error())
+			}
+		})
+	}
+}
+
+// ageTestChunkSize is the payload chunk size of the age format
+const ageTestChunkSize = 64 * 1024
+
+func TestAgeEncoder(t *testing.T) {
+	firstIdentity, firstRecipient, err := GenerateAgeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondIdentity, secondRecipient, err := GenerateAgeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherIdentity, _, err := GenerateAgeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryptor, err := NewAgeEncoder([]string{firstRecipient, secondRecipient}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]byte{
+		"empty":            {},
+		"value":            []byte("value"),
+		"chunk size":       bytes.Repeat([]byte("a"), ageTestChunkSize),
+		"several chunks":   bytes.Repeat([]byte("b"), 2*ageTestChunkSize+1),
+		"chunk size twice": bytes.Repeat([]byte("c"), 2*ageTestChunkSize),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			encodedData, err := encryptor.Encrypt(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, identity := range [][]byte{firstIdentity, secondIdentity} {
+				decryptor, err := NewAgeEncoder(nil, identity)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				result, err := decryptor.Decrypt(encodedData)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.Equal(data, result) {
+					t.Errorf("\n[EXPECTED]: %d bytes\n[GOT]: %d bytes", len(data), len(result))
+				}
+			}
+
+			decryptor, err := NewAgeEncoder(nil, otherIdentity)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := decryptor.Decrypt(encodedData); err == nil || err.Error() != "no age identity matched any of the recipients" {
+				t.Errorf("Expected identity mismatch error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestAgeEncoder_Decrypt_negative(t *testing.T) {
+	identity, recipient, err := GenerateAgeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewAgeEncoder([]string{recipient}, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err := s.Encrypt([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := base64.StdEncoding.DecodeString(string(encodedData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamperedPayload := append([]byte{}, file...)
+	tamperedPayload[len(tamperedPayload)-1] ^= 1
+
+	tamperedHeader := bytes.Replace(file, []byte("age-encryption.org/v1\n-> X25519"), []byte("age-encryption.org/v1\n-> X25519 \n\n-> X25519"), 1)
+
+	tests := []struct {
+		name         string
+		encodedData  []byte
+		errorMessage string
+	}{
+		{
+			name:         "hex data",
+			encodedData:  []byte("10000f13a718d019612ab8ad30d9bec8e2c09df0f2d168c179bef954e78371bf6a5a"),
+			errorMessage: "invalid age data: failed to read header",
+		},
+		{
+			name:         "tampered payload",
+			encodedData:  []byte(base64.StdEncoding.EncodeToString(tamperedPayload)),
+			errorMessage: "invalid age data: failed to decrypt and authenticate payload chunk",
+		},
+		{
+			name:         "tampered header",
+			encodedData:  []byte(base64.StdEncoding.EncodeToString(tamperedHeader)),
+			errorMessage: "invalid age data: failed to read header: failed to parse header: malformed stanza",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := s.Decrypt(test.encodedData)
+			if err == nil {
+				t.Errorf("Expected error: %s", test.errorMessage)
+			} else if !strings.HasPrefix(err.Error(), test.errorMessage) {
+				t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.errorMessage, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// EnvelopeEncoder encrypts data with the data key the same way as AesEncoder.
+// The data key is stored wrapped (encrypted) with an external key management service
+// and unwrapped once on the first use with the local command hook: the wrapped key is passed to the command stdin
+// and the command should print the data key in the same hex format as the secret key
+type EnvelopeEncoder struct {
+	WrappedKey    []byte
+	UnwrapCommand []string
+
+	once       sync.Once
+	aesEncoder *AesEncoder
+	err        error
+}
+
+func NewEnvelopeEncoder(wrappedKey []byte, unwrapCommand []string) (*EnvelopeEncoder, error) {
+	if len(wrappedKey) == 0 {
+		return nil, fmt.Errorf("wrapped data key is empty")
+	}
+
+	if len(unwrapCommand) == 0 {
+		return nil, fmt.Errorf("unwrap command is empty")
+	}
+
+	return &EnvelopeEncoder{WrappedKey: wrappedKey, UnwrapCommand: unwrapCommand}, nil
+}
+
+func (s *EnvelopeEncoder) Encrypt(data []byte) ([]byte, error) {
+	encoder, err := s.getAesEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	return encoder.Encrypt(data)
+}
+
+func (s *EnvelopeEncoder) Decrypt(data []byte) ([]byte, error) {
+	encoder, err := s.getAesEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	return encoder.Decrypt(data)
+}
+
+func (s *EnvelopeEncoder) getAesEncoder() (*AesEncoder, error) {
+	s.once.Do(func() {
+		key, err := s.unwrapKey()
+		if err != nil {
+			s.err = err
+			return
+		}
+
+		if s.aesEncoder, err = NewAesEncoder(key); err != nil {
+			s.err = fmt.Errorf("check unwrapped data key: %w", err)
+		}
+	})
+
+	return s.aesEncoder, s.err
+}
+
+func (s *EnvelopeEncoder) unwrapKey() ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(s.UnwrapCommand[0], s.UnwrapCommand[1:]...)
+	cmd.Stdin = bytes.NewReader(s.WrappedKey)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("unwrap command %q failed: %w\n%s", strings.Join(cmd.Args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	key := bytes.TrimSpace(stdout.Bytes())
+	if len(key) == 0 {
+		return nil, fmt.Errorf("unwrap command %q printed empty data key", strings.Join(cmd.Args, " "))
+	}
+
+	return key, nil
+}
@@ -0,0 +1,93 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvelopeEncoder(t *testing.T) {
+	dir := t.TempDir()
+	counterPath := filepath.Join(dir, "counter")
+
+	// The fake unwrap command checks the wrapped key and prints the data key, every run is recorded to the counter file
+	unwrapCommand := []string{"sh", "-c", `echo >> "$0"; [ "$(cat)" = "wrapped-key" ] && echo ` + string(AesSecretKey), counterPath}
+
+	s, err := NewEnvelopeEncoder([]byte("wrapped-key"), unwrapCommand)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedData, err := s.Encrypt([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aesEncoder, err := NewAesEncoder(AesSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encoder := range []Encoder{s, aesEncoder} {
+		result, err := encoder.Decrypt(encodedData)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(result) != "value" {
+			t.Errorf("\n[EXPECTED]: value\n[GOT]: %s", result)
+		}
+	}
+
+	counterData, err := os.ReadFile(counterPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(counterData) != 1 {
+		t.Errorf("Expected the unwrap command to run once, got %d runs", len(counterData))
+	}
+}
+
+func TestEnvelopeEncoder_negative(t *testing.T) {
+	tests := []struct {
+		name          string
+		unwrapCommand []string
+		errorMessage  string
+	}{
+		{
+			name:          "failed command",
+			unwrapCommand: []string{"sh", "-c", "echo access denied >&2; exit 1"},
+			errorMessage:  "unwrap command \"sh -c echo access denied >&2; exit 1\" failed: exit status 1\naccess denied",
+		},
+		{
+			name:          "empty output",
+			unwrapCommand: []string{"true"},
+			errorMessage:  "unwrap command \"true\" printed empty data key",
+		},
+		{
+			name:          "invalid data key",
+			unwrapCommand: []string{"echo", "xx"},
+			errorMessage:  "check unwrapped data key: encoding/hex: invalid byte: U+0078 'x'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := NewEnvelopeEncoder([]byte("wrapped-key"), test.unwrapCommand)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, do := range []func([]byte) ([]byte, error){s.Encrypt, s.Decrypt} {
+				_, err := do([]byte("value"))
+				if err == nil {
+					t.Errorf("Expected error: %s", test.errorMessage)
+				} else if !strings.HasPrefix(err.Error(), test.errorMessage) {
+					t.Errorf("\n[EXPECTED]: %s\n[GOT]: %s", test.errorMessage, err.Error())
+				}
+			}
+		})
+	}
+}
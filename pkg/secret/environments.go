@@ -0,0 +1,122 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+
+	yaml_v3 "gopkg.in/yaml.v3"
+)
+
+// EnvironmentsYamlKey is the top level key of the secret values with the environment-scoped sections.
+// Each section is encrypted with the secret key of the environment:
+//
+//	werfEnvironments:
+//	  production:
+//	    password: ENCRYPTED_WITH_PRODUCTION_KEY
+//	  staging:
+//	    password: ENCRYPTED_WITH_STAGING_KEY
+const EnvironmentsYamlKey = "werfEnvironments"
+
+// EnvironmentYamlData is the yaml data of the environment-scoped section
+type EnvironmentYamlData struct {
+	Environment string
+	Data        []byte
+}
+
+// SplitEnvironmentsYamlData returns the yaml data without the environment-scoped sections and the sections in the original order
+func SplitEnvironmentsYamlData(data []byte) ([]byte, []*EnvironmentYamlData, error) {
+	var config yaml_v3.Node
+	if err := yaml_v3.Unmarshal(data, &config); err != nil {
+		return nil, nil, fmt.Errorf("unable to unmarshal yaml data: %w", err)
+	}
+
+	if len(config.Content) == 0 || config.Content[0].Kind != yaml_v3.MappingNode {
+		return data, nil, nil
+	}
+
+	root := config.Content[0]
+	for pos := 0; pos < len(root.Content); pos += 2 {
+		if root.Content[pos].Value != EnvironmentsYamlKey {
+			continue
+		}
+
+		envsNode := root.Content[pos+1]
+		if envsNode.Kind != yaml_v3.MappingNode {
+			return nil, nil, fmt.Errorf("%s should be a map of the environment names to the values", EnvironmentsYamlKey)
+		}
+
+		var environments []*EnvironmentYamlData
+		for envPos := 0; envPos < len(envsNode.Content); envPos += 2 {
+			envData, err := encodeYamlNode(envsNode.Content[envPos+1])
+			if err != nil {
+				return nil, nil, err
+			}
+
+			environments = append(environments, &EnvironmentYamlData{Environment: envsNode.Content[envPos].Value, Data: envData})
+		}
+
+		root.Content = append(root.Content[:pos], root.Content[pos+2:]...)
+
+		commonData, err := encodeYamlNode(&config)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return commonData, environments, nil
+	}
+
+	return data, nil, nil
+}
+
+// JoinEnvironmentsYamlData puts the environment-scoped sections into the yaml data, it is the reverse of SplitEnvironmentsYamlData
+func JoinEnvironmentsYamlData(data []byte, environments []*EnvironmentYamlData) ([]byte, error) {
+	if len(environments) == 0 {
+		return data, nil
+	}
+
+	var config yaml_v3.Node
+	if err := yaml_v3.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal yaml data: %w", err)
+	}
+
+	if len(config.Content) == 0 {
+		config = yaml_v3.Node{Kind: yaml_v3.DocumentNode, Content: []*yaml_v3.Node{{Kind: yaml_v3.MappingNode, Tag: "!!map"}}}
+	}
+
+	root := config.Content[0]
+	if root.Kind != yaml_v3.MappingNode {
+		return nil, fmt.Errorf("yaml data with %s should be a map", EnvironmentsYamlKey)
+	}
+	root.Style = 0
+
+	envsNode := &yaml_v3.Node{Kind: yaml_v3.MappingNode, Tag: "!!map"}
+	for _, env := range environments {
+		var envConfig yaml_v3.Node
+		if err := yaml_v3.Unmarshal(env.Data, &envConfig); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal environment %q yaml data: %w", env.Environment, err)
+		}
+
+		envValue := &yaml_v3.Node{Kind: yaml_v3.ScalarNode, Tag: "!!null"}
+		if len(envConfig.Content) > 0 {
+			envValue = envConfig.Content[0]
+		}
+
+		envsNode.Content = append(envsNode.Content, &yaml_v3.Node{Kind: yaml_v3.ScalarNode, Tag: "!!str", Value: env.Environment}, envValue)
+	}
+
+	root.Content = append(root.Content, &yaml_v3.Node{Kind: yaml_v3.ScalarNode, Tag: "!!str", Value: EnvironmentsYamlKey}, envsNode)
+
+	return encodeYamlNode(&config)
+}
+
+func encodeYamlNode(node *yaml_v3.Node) ([]byte, error) {
+	var resultData bytes.Buffer
+
+	yamlEncoder := yaml_v3.NewEncoder(&resultData)
+	yamlEncoder.SetIndent(2)
+	if err := yamlEncoder.Encode(node); err != nil {
+		return nil, fmt.Errorf("unable to marshal yaml data: %w", err)
+	}
+
+	return resultData.Bytes(), nil
+}
@@ -0,0 +1,74 @@
+package secret
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SplitEnvironmentsYamlData", func() {
+	It("should split the environment-scoped sections and join them back", func() {
+		data := []byte(`database:
+  password: common
+werfEnvironments:
+  production:
+    database:
+      password: production
+  staging:
+    database:
+      password: staging
+mailbox:
+  password: common
+`)
+
+		commonData, environments, err := SplitEnvironmentsYamlData(data)
+		Expect(err).To(Succeed())
+		Expect(string(commonData)).To(Equal(`database:
+  password: common
+mailbox:
+  password: common
+`))
+		Expect(environments).To(Equal([]*EnvironmentYamlData{
+			{Environment: "production", Data: []byte("database:\n  password: production\n")},
+			{Environment: "staging", Data: []byte("database:\n  password: staging\n")},
+		}))
+
+		joinedData, err := JoinEnvironmentsYamlData(commonData, environments)
+		Expect(err).To(Succeed())
+		Expect(string(joinedData)).To(Equal(`database:
+  password: common
+mailbox:
+  password: common
+werfEnvironments:
+  production:
+    database:
+      password: production
+  staging:
+    database:
+      password: staging
+`))
+	})
+
+	It("should return the data as is if there are no environment-scoped sections", func() {
+		data := []byte("password: common\n")
+
+		commonData, environments, err := SplitEnvironmentsYamlData(data)
+		Expect(err).To(Succeed())
+		Expect(commonData).To(Equal(data))
+		Expect(environments).To(BeEmpty())
+	})
+
+	It("should join the environment-scoped sections into the empty data", func() {
+		joinedData, err := JoinEnvironmentsYamlData([]byte("{}\n"), []*EnvironmentYamlData{{Environment: "production", Data: []byte("password: production\n")}})
+		Expect(err).To(Succeed())
+		Expect(string(joinedData)).To(Equal("werfEnvironments:\n  production:\n    password: production\n"))
+
+		joinedData, err = JoinEnvironmentsYamlData(nil, []*EnvironmentYamlData{{Environment: "production", Data: []byte("password: production\n")}})
+		Expect(err).To(Succeed())
+		Expect(string(joinedData)).To(Equal("werfEnvironments:\n  production:\n    password: production\n"))
+	})
+
+	It("should fail if the environment-scoped sections are not a map", func() {
+		_, _, err := SplitEnvironmentsYamlData([]byte("werfEnvironments: production\n"))
+		Expect(err).To(MatchError(ContainSubstring("werfEnvironments should be a map")))
+	})
+})
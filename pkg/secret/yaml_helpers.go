@@ -126,3 +126,56 @@ func getSubNodeByKey(node *yaml_v3.Node, rawKey string) *yaml_v3.Node {
 	}
 	return nil
 }
+
+// YamlValue is the scalar value of the yaml data
+type YamlValue struct {
+	// Path is the dot-separated path of the value (database.hosts[0])
+	Path     string
+	Value    string
+	IsString bool
+}
+
+// GetYamlValues returns the non-null scalar values of the yaml data in the document order
+func GetYamlValues(data []byte) ([]*YamlValue, error) {
+	var config yaml_v3.Node
+	if err := yaml_v3.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal yaml data: %w", err)
+	}
+
+	var res []*YamlValue
+	collectYamlValues(&config, "", &res)
+
+	return res, nil
+}
+
+func collectYamlValues(node *yaml_v3.Node, path string, res *[]*YamlValue) {
+	switch node.Kind {
+	case yaml_v3.DocumentNode:
+		for _, n := range node.Content {
+			collectYamlValues(n, path, res)
+		}
+
+	case yaml_v3.MappingNode:
+		for pos := 0; pos < len(node.Content); pos += 2 {
+			keyPath := node.Content[pos].Value
+			if path != "" {
+				keyPath = path + "." + keyPath
+			}
+			collectYamlValues(node.Content[pos+1], keyPath, res)
+		}
+
+	case yaml_v3.SequenceNode:
+		for pos, n := range node.Content {
+			collectYamlValues(n, fmt.Sprintf("%s[%d]", path, pos), res)
+		}
+
+	case yaml_v3.AliasNode:
+		collectYamlValues(node.Alias, path, res)
+
+	case yaml_v3.ScalarNode:
+		if node.ShortTag() == "!!null" {
+			return
+		}
+		*res = append(*res, &YamlValue{Path: path, Value: node.Value, IsString: node.ShortTag() == "!!str"})
+	}
+}
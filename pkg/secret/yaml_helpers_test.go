@@ -480,3 +480,24 @@ hosts:
 		}),
 	)
 })
+
+var _ = Describe("GetYamlValues", func() {
+	It("should return the scalar values with the paths", func() {
+		values, err := GetYamlValues([]byte(`
+database:
+  password: enc1
+  port: 5432
+  hosts:
+  - enc2
+  - enc3
+empty:
+`))
+		Expect(err).To(Succeed())
+		Expect(values).To(Equal([]*YamlValue{
+			{Path: "database.password", Value: "enc1", IsString: true},
+			{Path: "database.port", Value: "5432", IsString: false},
+			{Path: "database.hosts[0]", Value: "enc2", IsString: true},
+			{Path: "database.hosts[1]", Value: "enc3", IsString: true},
+		}))
+	})
+})
@@ -0,0 +1,135 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/payload"
+
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+// Signatures are stored the same way cosign stores them, so they can be verified by cosign and vice versa:
+// the signature image REPO:sha256-DIGEST.sig contains one layer per signature with the simple signing payload
+// and the base64 encoded signature of the payload in the layer annotation.
+const (
+	SimpleSigningMediaType types.MediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	SignatureAnnotation                    = "dev.cosignproject.cosign/signature"
+
+	SignatureTagSuffix = ".sig"
+)
+
+// signedImage is the image manifest (or manifest list) that is signed
+type signedImage struct {
+	Reference  string
+	Repository string
+	Digest     string
+}
+
+func (img *signedImage) SignatureReference() string {
+	return fmt.Sprintf("%s:%s%s", img.Repository, strings.Replace(img.Digest, ":", "-", 1), SignatureTagSuffix)
+}
+
+func (img *signedImage) String() string {
+	return fmt.Sprintf("%s@%s", img.Repository, img.Digest)
+}
+
+func getSignedImage(ctx context.Context, registry docker_registry.Interface, reference string) (*signedImage, error) {
+	ref, err := name.ParseReference(reference, name.WeakValidation)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse reference %q: %w", reference, err)
+	}
+
+	img := &signedImage{
+		Reference:  reference,
+		Repository: ref.Context().Name(),
+	}
+
+	if digestRef, ok := ref.(name.Digest); ok {
+		img.Digest = digestRef.DigestStr()
+	} else {
+		img.Digest, err = registry.GetManifestDigest(ctx, reference)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return img, nil
+}
+
+func newSignaturePayload(img *signedImage) ([]byte, error) {
+	digest, err := name.NewDigest(img.String(), name.WeakValidation)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse digest reference %q: %w", img.String(), err)
+	}
+
+	return json.Marshal(payload.Cosign{Image: digest})
+}
+
+// findValidSignature returns true if there is a layer in the signature image with the payload for the image digest signed by the verifier key
+func findValidSignature(sigImage v1.Image, img *signedImage, verifier signature.Verifier) (bool, error) {
+	manifest, err := sigImage.Manifest()
+	if err != nil {
+		return false, fmt.Errorf("unable to get signature image manifest: %w", err)
+	}
+
+	for _, desc := range manifest.Layers {
+		if desc.MediaType != SimpleSigningMediaType {
+			continue
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(desc.Annotations[SignatureAnnotation])
+		if err != nil || len(sig) == 0 {
+			continue
+		}
+
+		payloadData, err := readLayer(sigImage, desc.Digest)
+		if err != nil {
+			return false, err
+		}
+
+		if err := verifier.VerifySignature(bytes.NewReader(sig), bytes.NewReader(payloadData)); err != nil {
+			continue
+		}
+
+		var p payload.Cosign
+		if err := json.Unmarshal(payloadData, &p); err != nil {
+			continue
+		}
+
+		if p.Image.DigestStr() == img.Digest {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func readLayer(img v1.Image, digest v1.Hash) ([]byte, error) {
+	layer, err := img.LayerByDigest(digest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get signature layer %s: %w", digest, err)
+	}
+
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read signature layer %s: %w", digest, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read signature layer %s: %w", digest, err)
+	}
+
+	return data, nil
+}
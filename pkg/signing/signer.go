@@ -0,0 +1,114 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+type Signer struct {
+	KeyPath string
+
+	signer   signature.Signer
+	verifier signature.Verifier
+}
+
+// NewSigner loads a PEM encoded private key: a cosign key (generated by `cosign generate-key-pair`) encrypted with the passphrase
+// or an unencrypted PKCS#8, PKCS#1 or EC private key
+func NewSigner(keyPath string, passphrase []byte) (*Signer, error) {
+	signer, err := signature.LoadSignerFromPEMFile(keyPath, crypto.SHA256, cryptoutils.StaticPasswordFunc(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load signing key %q: %w", keyPath, err)
+	}
+
+	publicKey, err := signer.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get public key of signing key %q: %w", keyPath, err)
+	}
+
+	verifier, err := signature.LoadVerifier(publicKey, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load public key of signing key %q: %w", keyPath, err)
+	}
+
+	return &Signer{KeyPath: keyPath, signer: signer, verifier: verifier}, nil
+}
+
+// Sign signs the manifest the reference points to and pushes the signature to the registry,
+// the image is not signed again if it already has a signature made with the same key
+func (s *Signer) Sign(ctx context.Context, registry docker_registry.Interface, reference string) error {
+	img, err := getSignedImage(ctx, registry, reference)
+	if err != nil {
+		return err
+	}
+
+	sigImage, err := registry.TryGetRemoteImage(ctx, img.SignatureReference())
+	if err != nil {
+		return err
+	}
+
+	if sigImage != nil {
+		if found, err := findValidSignature(sigImage, img, s.verifier); err != nil {
+			return fmt.Errorf("unable to check existing signatures of %s: %w", img, err)
+		} else if found {
+			logboek.Context(ctx).Default().LogFDetails("Already signed: %s\n", img)
+			return nil
+		}
+	} else {
+		sigImage = mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.OCIConfigJSON)
+	}
+
+	payloadData, err := newSignaturePayload(img)
+	if err != nil {
+		return err
+	}
+
+	sig, err := s.signer.SignMessage(bytes.NewReader(payloadData))
+	if err != nil {
+		return fmt.Errorf("unable to sign %s: %w", img, err)
+	}
+
+	sigImage, err = mutate.Append(sigImage, mutate.Addendum{
+		Layer: static.NewLayer(payloadData, SimpleSigningMediaType),
+		Annotations: map[string]string{
+			SignatureAnnotation: base64.StdEncoding.EncodeToString(sig),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to add signature layer: %w", err)
+	}
+
+	if err := registry.PushRemoteImage(ctx, img.SignatureReference(), sigImage); err != nil {
+		return fmt.Errorf("unable to push signature of %s: %w", img, err)
+	}
+
+	logboek.Context(ctx).Default().LogFDetails("Signed: %s\n", img)
+	logboek.Context(ctx).Default().LogFDetails("Signature: %s\n", img.SignatureReference())
+
+	return nil
+}
+
+// SignImages signs the images and logs the process
+func (s *Signer) SignImages(ctx context.Context, registry docker_registry.Interface, references []string) error {
+	return logboek.Context(ctx).Default().LogProcess("Signing with key %s", s.KeyPath).DoError(func() error {
+		for _, reference := range references {
+			if err := s.Sign(ctx, registry, reference); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
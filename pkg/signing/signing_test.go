@@ -0,0 +1,115 @@
+package signing
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+func generateKeyPair(dir, name string, passphrase []byte) (string, string) {
+	privateKeyPEM, publicKeyPEM, err := cryptoutils.GeneratePEMEncodedECDSAKeyPair(elliptic.P256(), cryptoutils.StaticPasswordFunc(passphrase))
+	Ω(err).ShouldNot(HaveOccurred())
+
+	privateKeyPath := filepath.Join(dir, name+".key")
+	publicKeyPath := filepath.Join(dir, name+".pub")
+	Ω(os.WriteFile(privateKeyPath, privateKeyPEM, 0o600)).Should(Succeed())
+	Ω(os.WriteFile(publicKeyPath, publicKeyPEM, 0o644)).Should(Succeed())
+
+	return privateKeyPath, publicKeyPath
+}
+
+var _ = Describe("Signer and Verifier", func() {
+	var ctx context.Context
+	var dockerRegistry docker_registry.Interface
+	var imageReference string
+	var privateKeyPath, publicKeyPath string
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		server := httptest.NewServer(registry.New())
+		DeferCleanup(server.Close)
+
+		repository := strings.TrimPrefix(server.URL, "http://") + "/project"
+		imageReference = repository + ":tag"
+
+		img, err := random.Image(1024, 1)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		ref, err := name.ParseReference(imageReference, name.Insecure)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(remote.Write(ref, img)).Should(Succeed())
+
+		dockerRegistry, err = docker_registry.NewDockerRegistry(repository, docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{InsecureRegistry: true})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		privateKeyPath, publicKeyPath = generateKeyPair(GinkgoT().TempDir(), "cosign", []byte("passphrase"))
+	})
+
+	It("should verify the signed image and sign it only once", func() {
+		signer, err := NewSigner(privateKeyPath, []byte("passphrase"))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		verifier, err := NewVerifier(publicKeyPath)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = verifier.Verify(ctx, dockerRegistry, imageReference)
+		Ω(err).Should(MatchError(ContainSubstring("is not signed")))
+
+		for i := 0; i < 2; i++ {
+			Ω(signer.Sign(ctx, dockerRegistry, imageReference)).Should(Succeed())
+		}
+		digest, err := verifier.Verify(ctx, dockerRegistry, imageReference)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		img, err := getSignedImage(ctx, dockerRegistry, imageReference)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(digest).Should(Equal(img.Digest))
+		sigImage, err := dockerRegistry.TryGetRemoteImage(ctx, img.SignatureReference())
+		Ω(err).ShouldNot(HaveOccurred())
+		layers, err := sigImage.Layers()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(layers).Should(HaveLen(1))
+	})
+
+	It("should refuse the image signed with another key", func() {
+		otherPrivateKeyPath, _ := generateKeyPair(GinkgoT().TempDir(), "other", []byte("other"))
+
+		signer, err := NewSigner(otherPrivateKeyPath, []byte("other"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(signer.Sign(ctx, dockerRegistry, imageReference)).Should(Succeed())
+
+		verifier, err := NewVerifier(publicKeyPath)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = verifier.Verify(ctx, dockerRegistry, imageReference)
+		Ω(err).Should(MatchError(ContainSubstring("has no valid signature")))
+	})
+
+	It("should load unencrypted private keys", func() {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Ω(err).ShouldNot(HaveOccurred())
+		privateKeyPEM, err := cryptoutils.MarshalPrivateKeyToPEM(privateKey)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		keyPath := filepath.Join(GinkgoT().TempDir(), "plain.key")
+		Ω(os.WriteFile(keyPath, privateKeyPEM, 0o600)).Should(Succeed())
+
+		_, err = NewSigner(keyPath, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+})
@@ -0,0 +1,76 @@
+package signing
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+type Verifier struct {
+	KeyPath string
+
+	verifier signature.Verifier
+}
+
+// NewVerifier loads a PEM encoded public key (cosign.pub generated by `cosign generate-key-pair` or any ECDSA, RSA or Ed25519 public key)
+func NewVerifier(keyPath string) (*Verifier, error) {
+	verifier, err := signature.LoadVerifierFromPEMFile(keyPath, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load verification key %q: %w", keyPath, err)
+	}
+
+	return &Verifier{KeyPath: keyPath, verifier: verifier}, nil
+}
+
+// Verify returns an error if the manifest the reference points to is not signed with the key.
+// The digest is resolved once and returned, so the caller should use the verified digest instead of the mutable tag
+func (v *Verifier) Verify(ctx context.Context, registry docker_registry.Interface, reference string) (string, error) {
+	img, err := getSignedImage(ctx, registry, reference)
+	if err != nil {
+		return "", err
+	}
+
+	sigImage, err := registry.TryGetRemoteImage(ctx, img.SignatureReference())
+	if err != nil {
+		return "", err
+	}
+
+	if sigImage == nil {
+		return "", fmt.Errorf("%s is not signed: signature %s not found", reference, img.SignatureReference())
+	}
+
+	if found, err := findValidSignature(sigImage, img, v.verifier); err != nil {
+		return "", fmt.Errorf("unable to verify signatures of %s: %w", reference, err)
+	} else if !found {
+		return "", fmt.Errorf("%s has no valid signature made with the key %s", reference, v.KeyPath)
+	}
+
+	logboek.Context(ctx).Default().LogFDetails("Verified: %s\n", img)
+
+	return img.Digest, nil
+}
+
+// VerifyImages verifies all images, logs the process and returns the verified digests in the order of references
+func (v *Verifier) VerifyImages(ctx context.Context, registry docker_registry.Interface, references []string) ([]string, error) {
+	var digests []string
+	if err := logboek.Context(ctx).Default().LogProcess("Verifying signatures with key %s", v.KeyPath).DoError(func() error {
+		for _, reference := range references {
+			digest, err := v.Verify(ctx, registry, reference)
+			if err != nil {
+				return fmt.Errorf("signature verification failed: %w", err)
+			}
+			digests = append(digests, digest)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return digests, nil
+}
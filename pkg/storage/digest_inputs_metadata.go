@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/werf/werf/pkg/build/digest_inputs"
+	"github.com/werf/werf/pkg/image"
+)
+
+// DigestInputsMetadata is the digest inputs of all stages of the image keyed by the image last stage.
+// The inputs are stored in the metadata record instead of the stage image label,
+// because they contain the expanded instructions with the build args values and should not get into the final images
+type DigestInputsMetadata struct {
+	StageID string
+	Stages  []digest_inputs.Stage
+}
+
+func (m *DigestInputsMetadata) ToLabels() ([]string, error) {
+	labelsMap, err := m.ToLabelsMap()
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{
+		fmt.Sprintf("%s=%s", image.WerfDigestInputsMetadataStageIDLabel, labelsMap[image.WerfDigestInputsMetadataStageIDLabel]),
+		fmt.Sprintf("%s=%s", image.WerfDigestInputsMetadataStagesLabel, labelsMap[image.WerfDigestInputsMetadataStagesLabel]),
+	}, nil
+}
+
+func (m *DigestInputsMetadata) ToLabelsMap() (map[string]string, error) {
+	value, err := digest_inputs.EncodeStages(m.Stages)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode stages digest inputs: %w", err)
+	}
+
+	return map[string]string{
+		image.WerfDigestInputsMetadataStageIDLabel: m.StageID,
+		image.WerfDigestInputsMetadataStagesLabel:  value,
+	}, nil
+}
+
+func newDigestInputsMetadataFromLabels(labels map[string]string) (*DigestInputsMetadata, error) {
+	stages, err := digest_inputs.DecodeStages(labels[image.WerfDigestInputsMetadataStagesLabel])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode stages digest inputs: %w", err)
+	}
+
+	return &DigestInputsMetadata{
+		StageID: labels[image.WerfDigestInputsMetadataStageIDLabel],
+		Stages:  stages,
+	}, nil
+}
@@ -28,6 +28,11 @@ func (manager *GenericLockManager) LockStageCache(ctx context.Context, projectNa
 	return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
 }
 
+func (manager *GenericLockManager) LockImageBuild(ctx context.Context, projectName, imageBuildKey string, opts LockImageBuildOptions) (bool, LockHandle, error) {
+	acquired, lock, err := manager.Locker.Acquire(genericImageBuildLockName(projectName, imageBuildKey), werf.SetupLockerDefaultOptions(ctx, lockgate.AcquireOptions{NonBlocking: opts.NonBlocking}))
+	return acquired, LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
+}
+
 func (manager *GenericLockManager) Unlock(ctx context.Context, lock LockHandle) error {
 	err := manager.Locker.Release(lock.LockgateHandle)
 	if err != nil {
@@ -43,3 +48,7 @@ func genericStageLockName(projectName, digest string) string {
 func genericStageCacheLockName(projectName, digest string) string {
 	return fmt.Sprintf("%s.%s.cache", projectName, digest)
 }
+
+func genericImageBuildLockName(projectName, imageBuildKey string) string {
+	return fmt.Sprintf("%s.image-build.%s", projectName, imageBuildKey)
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/werf/lockgate/pkg/file_locker"
+)
+
+func TestGenericLockManager_LockImageBuild(t *testing.T) {
+	ctx := context.Background()
+
+	locker, err := file_locker.NewFileLocker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewGenericLockManager(locker)
+
+	acquired, lock, err := manager.LockImageBuild(ctx, "project", "key", LockImageBuildOptions{NonBlocking: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Fatal("expected image build lock to be acquired")
+	}
+
+	if acquired, _, err := manager.LockImageBuild(ctx, "project", "key", LockImageBuildOptions{NonBlocking: true}); err != nil {
+		t.Fatal(err)
+	} else if acquired {
+		t.Error("expected image build lock claimed by another process not to be acquired")
+	}
+
+	if acquired, otherLock, err := manager.LockImageBuild(ctx, "project", "other-key", LockImageBuildOptions{NonBlocking: true}); err != nil {
+		t.Fatal(err)
+	} else if !acquired {
+		t.Error("expected lock of another image build to be acquired")
+	} else if err := manager.Unlock(ctx, otherLock); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Unlock(ctx, lock); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired, lock, err = manager.LockImageBuild(ctx, "project", "key", LockImageBuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Error("expected released image build lock to be acquired")
+	}
+	if err := manager.Unlock(ctx, lock); err != nil {
+		t.Fatal(err)
+	}
+}
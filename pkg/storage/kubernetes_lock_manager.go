@@ -82,6 +82,15 @@ func (manager *KubernetesLockManager) LockStageCache(ctx context.Context, projec
 	}
 }
 
+func (manager *KubernetesLockManager) LockImageBuild(ctx context.Context, projectName, imageBuildKey string, opts LockImageBuildOptions) (bool, LockHandle, error) {
+	if locker, err := manager.getLockerForProject(ctx, projectName); err != nil {
+		return false, LockHandle{}, err
+	} else {
+		acquired, lock, err := locker.Acquire(kubernetesImageBuildLockName(projectName, imageBuildKey), werf.SetupLockerDefaultOptions(ctx, lockgate.AcquireOptions{NonBlocking: opts.NonBlocking}))
+		return acquired, LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
+	}
+}
+
 func (manager *KubernetesLockManager) Unlock(ctx context.Context, lock LockHandle) error {
 	if locker, err := manager.getLockerForProject(ctx, lock.ProjectName); err != nil {
 		return err
@@ -101,3 +110,7 @@ func kubernetesStageLockName(projectName, digest string) string {
 func kubernetesStageCacheLockName(projectName, digest string) string {
 	return fmt.Sprintf("%s/stage-cache/%s", projectName, digest)
 }
+
+func kubernetesImageBuildLockName(projectName, imageBuildKey string) string {
+	return fmt.Sprintf("%s/image-build/%s", projectName, imageBuildKey)
+}
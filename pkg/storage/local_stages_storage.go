@@ -23,6 +23,10 @@ const (
 	LocalImportMetadata_ImageNameFormat = "werf-import-metadata/%s"
 	LocalImportMetadata_TagFormat       = "%s"
 
+	LocalVulnerabilityScanMetadata_ImageNameFormat = "werf-vulnerability-scan/%s"
+
+	LocalDigestInputsMetadata_ImageNameFormat = "werf-digest-inputs/%s"
+
 	LocalClientIDRecord_ImageNameFormat = "werf-client-id/%s"
 	LocalClientIDRecord_ImageFormat     = "werf-client-id/%s:%s-%d"
 
@@ -331,6 +335,152 @@ func (storage *LocalStagesStorage) GetImportMetadataIDs(ctx context.Context, pro
 	return tags, nil
 }
 
+func (storage *LocalStagesStorage) GetVulnerabilityScanMetadata(ctx context.Context, projectName, stageID string) (*VulnerabilityScanMetadata, error) {
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.GetVulnerabilityScanMetadata %s %s\n", projectName, stageID)
+
+	fullImageName := makeLocalVulnerabilityScanMetadataName(projectName, stageID)
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.GetVulnerabilityScanMetadata full image name: %s\n", fullImageName)
+
+	info, err := storage.ContainerBackend.GetImageInfo(ctx, fullImageName, container_backend.GetImageInfoOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image %s info: %w", fullImageName, err)
+	}
+	if info == nil {
+		return nil, nil
+	}
+	return newVulnerabilityScanMetadataFromLabels(info.Labels)
+}
+
+func (storage *LocalStagesStorage) PutVulnerabilityScanMetadata(ctx context.Context, projectName string, metadata *VulnerabilityScanMetadata) error {
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.PutVulnerabilityScanMetadata %s %s\n", projectName, metadata.StageID)
+
+	fullImageName := makeLocalVulnerabilityScanMetadataName(projectName, metadata.StageID)
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.PutVulnerabilityScanMetadata full image name: %s\n", fullImageName)
+
+	labels, err := metadata.ToLabels()
+	if err != nil {
+		return err
+	}
+	labels = append(labels, fmt.Sprintf("%s=%s", image.WerfLabel, projectName))
+
+	if err := storage.ContainerBackend.PostManifest(ctx, fullImageName, container_backend.PostManifestOpts{Labels: labels}); err != nil {
+		return fmt.Errorf("unable to post manifest %q: %w", fullImageName, err)
+	}
+	return nil
+}
+
+func (storage *LocalStagesStorage) RmVulnerabilityScanMetadata(ctx context.Context, projectName, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.RmVulnerabilityScanMetadata %s %s\n", projectName, stageID)
+
+	fullImageName := makeLocalVulnerabilityScanMetadataName(projectName, stageID)
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.RmVulnerabilityScanMetadata full image name: %s\n", fullImageName)
+
+	if info, err := storage.ContainerBackend.GetImageInfo(ctx, fullImageName, container_backend.GetImageInfoOpts{}); err != nil {
+		return fmt.Errorf("unable to check existence of image %s: %w", fullImageName, err)
+	} else if info == nil {
+		return nil
+	}
+
+	if err := storage.ContainerBackend.Rmi(ctx, fullImageName, container_backend.RmiOpts{Force: true}); err != nil {
+		return fmt.Errorf("unable to remove image %s: %w", fullImageName, err)
+	}
+	return nil
+}
+
+func (storage *LocalStagesStorage) GetVulnerabilityScanMetadataIDs(ctx context.Context, projectName string, opts ...Option) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.GetVulnerabilityScanMetadataIDs %s\n", projectName)
+
+	imagesOpts := container_backend.ImagesOptions{}
+	imagesOpts.Filters = append(imagesOpts.Filters, util.NewPair("reference", fmt.Sprintf(LocalVulnerabilityScanMetadata_ImageNameFormat, projectName)))
+	images, err := storage.ContainerBackend.Images(ctx, imagesOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list images: %w", err)
+	}
+
+	var ids []string
+	for _, img := range images {
+		for _, repoTag := range img.RepoTags {
+			_, tag := image.ParseRepositoryAndTag(repoTag)
+			ids = append(ids, tag)
+		}
+	}
+
+	return ids, nil
+}
+
+func (storage *LocalStagesStorage) GetDigestInputsMetadata(ctx context.Context, projectName, stageID string) (*DigestInputsMetadata, error) {
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.GetDigestInputsMetadata %s %s\n", projectName, stageID)
+
+	fullImageName := makeLocalDigestInputsMetadataName(projectName, stageID)
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.GetDigestInputsMetadata full image name: %s\n", fullImageName)
+
+	info, err := storage.ContainerBackend.GetImageInfo(ctx, fullImageName, container_backend.GetImageInfoOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get image %s info: %w", fullImageName, err)
+	}
+	if info == nil {
+		return nil, nil
+	}
+	return newDigestInputsMetadataFromLabels(info.Labels)
+}
+
+func (storage *LocalStagesStorage) PutDigestInputsMetadata(ctx context.Context, projectName string, metadata *DigestInputsMetadata) error {
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.PutDigestInputsMetadata %s %s\n", projectName, metadata.StageID)
+
+	fullImageName := makeLocalDigestInputsMetadataName(projectName, metadata.StageID)
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.PutDigestInputsMetadata full image name: %s\n", fullImageName)
+
+	labels, err := metadata.ToLabels()
+	if err != nil {
+		return err
+	}
+	labels = append(labels, fmt.Sprintf("%s=%s", image.WerfLabel, projectName))
+
+	if err := storage.ContainerBackend.PostManifest(ctx, fullImageName, container_backend.PostManifestOpts{Labels: labels}); err != nil {
+		return fmt.Errorf("unable to post manifest %q: %w", fullImageName, err)
+	}
+	return nil
+}
+
+func (storage *LocalStagesStorage) RmDigestInputsMetadata(ctx context.Context, projectName, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.RmDigestInputsMetadata %s %s\n", projectName, stageID)
+
+	fullImageName := makeLocalDigestInputsMetadataName(projectName, stageID)
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.RmDigestInputsMetadata full image name: %s\n", fullImageName)
+
+	if info, err := storage.ContainerBackend.GetImageInfo(ctx, fullImageName, container_backend.GetImageInfoOpts{}); err != nil {
+		return fmt.Errorf("unable to check existence of image %s: %w", fullImageName, err)
+	} else if info == nil {
+		return nil
+	}
+
+	if err := storage.ContainerBackend.Rmi(ctx, fullImageName, container_backend.RmiOpts{Force: true}); err != nil {
+		return fmt.Errorf("unable to remove image %s: %w", fullImageName, err)
+	}
+	return nil
+}
+
+func (storage *LocalStagesStorage) GetDigestInputsMetadataIDs(ctx context.Context, projectName string, opts ...Option) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.GetDigestInputsMetadataIDs %s\n", projectName)
+
+	imagesOpts := container_backend.ImagesOptions{}
+	imagesOpts.Filters = append(imagesOpts.Filters, util.NewPair("reference", fmt.Sprintf(LocalDigestInputsMetadata_ImageNameFormat, projectName)))
+	images, err := storage.ContainerBackend.Images(ctx, imagesOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list images: %w", err)
+	}
+
+	var ids []string
+	for _, img := range images {
+		for _, repoTag := range img.RepoTags {
+			_, tag := image.ParseRepositoryAndTag(repoTag)
+			ids = append(ids, tag)
+		}
+	}
+
+	return ids, nil
+}
+
 func (storage *LocalStagesStorage) GetClientIDRecords(ctx context.Context, projectName string, opts ...Option) ([]*ClientIDRecord, error) {
 	logboek.Context(ctx).Debug().LogF("-- LocalStagesStorage.GetClientID for project %s\n", projectName)
 
@@ -416,6 +566,14 @@ func (storage *LocalStagesStorage) CopyFromStorage(ctx context.Context, src Stag
 	panic("not implemented")
 }
 
+func makeLocalVulnerabilityScanMetadataName(projectName, stageID string) string {
+	return fmt.Sprintf("%s:%s", fmt.Sprintf(LocalVulnerabilityScanMetadata_ImageNameFormat, projectName), stageID)
+}
+
+func makeLocalDigestInputsMetadataName(projectName, stageID string) string {
+	return fmt.Sprintf("%s:%s", fmt.Sprintf(LocalDigestInputsMetadata_ImageNameFormat, projectName), stageID)
+}
+
 func makeLocalImportMetadataName(projectName, importSourceID string) string {
 	return strings.Join(
 		[]string{
@@ -8,6 +8,9 @@ import (
 
 type LockManager interface {
 	LockStage(ctx context.Context, projectName, digest string) (LockHandle, error)
+	// LockImageBuild claims the image build by one of the werf processes working with the same repo.
+	// With the NonBlocking option false is returned without waiting if the image build is claimed by another process
+	LockImageBuild(ctx context.Context, projectName, imageBuildKey string, opts LockImageBuildOptions) (bool, LockHandle, error)
 	Unlock(ctx context.Context, lockHandle LockHandle) error
 }
 
@@ -16,6 +19,10 @@ type LockHandle struct {
 	LockgateHandle lockgate.LockHandle `json:"lockgateHandle"`
 }
 
+type LockImageBuildOptions struct {
+	NonBlocking bool
+}
+
 type LockStagesAndImagesOptions struct {
 	GetOrCreateImagesOnly bool `json:"getOrCreateImagesOnly"`
 }
@@ -22,7 +22,7 @@ func (m *StorageManager) CopyStage(ctx context.Context, src, dest storage.Stages
 	switch typedSrc := src.(type) {
 	case *storage.LocalStagesStorage:
 		return m.copyStageFromLocalStorage(ctx, typedSrc, dest, stageID, opts)
-	case *storage.RepoStagesStorage:
+	case *storage.RepoStagesStorage, *storage.OCILayoutStagesStorage:
 		return dest.CopyFromStorage(ctx, src, m.ProjectName, stageID, storage.CopyFromStorageOptions{IsMultiplatformImage: opts.IsMultiplatformImage})
 	default:
 		panic(fmt.Sprintf("not implemented for storage %s", typedSrc))
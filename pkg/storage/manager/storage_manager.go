@@ -90,6 +90,8 @@ type StorageManagerInterface interface {
 	ForEachRmManagedImage(ctx context.Context, projectName string, managedImages []string, f func(ctx context.Context, managedImage string, err error) error) error
 	ForEachGetImportMetadata(ctx context.Context, projectName string, ids []string, f func(ctx context.Context, metadataID string, metadata *storage.ImportMetadata, err error) error) error
 	ForEachRmImportMetadata(ctx context.Context, projectName string, ids []string, f func(ctx context.Context, id string, err error) error) error
+	ForEachRmVulnerabilityScanMetadata(ctx context.Context, projectName string, stageIDs []string, f func(ctx context.Context, stageID string, err error) error) error
+	ForEachRmDigestInputsMetadata(ctx context.Context, projectName string, stageIDs []string, f func(ctx context.Context, stageID string, err error) error) error
 	ForEachGetStageCustomTagMetadata(ctx context.Context, ids []string, f func(ctx context.Context, metadataID string, metadata *storage.CustomTagMetadata, err error) error) error
 	ForEachDeleteStageCustomTag(ctx context.Context, ids []string, f func(ctx context.Context, tag string, err error) error) error
 }
@@ -896,6 +898,7 @@ func ConvertStageDescriptionForStagesStorage(stageDesc *image.StageDescription,
 			ParentID:          stageDesc.Info.ParentID,
 			Labels:            stageDesc.Info.Labels,
 			Size:              stageDesc.Info.Size,
+			Layers:            stageDesc.Info.Layers,
 			CreatedAtUnixNano: stageDesc.Info.CreatedAtUnixNano,
 			OnBuild:           stageDesc.Info.OnBuild,
 			Env:               stageDesc.Info.Env,
@@ -1041,6 +1044,26 @@ func (m *StorageManager) ForEachRmImportMetadata(ctx context.Context, projectNam
 	})
 }
 
+func (m *StorageManager) ForEachRmVulnerabilityScanMetadata(ctx context.Context, projectName string, stageIDs []string, f func(ctx context.Context, stageID string, err error) error) error {
+	return parallel.DoTasks(ctx, len(stageIDs), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+	}, func(ctx context.Context, taskId int) error {
+		stageID := stageIDs[taskId]
+		err := m.StagesStorage.RmVulnerabilityScanMetadata(ctx, projectName, stageID)
+		return f(ctx, stageID, err)
+	})
+}
+
+func (m *StorageManager) ForEachRmDigestInputsMetadata(ctx context.Context, projectName string, stageIDs []string, f func(ctx context.Context, stageID string, err error) error) error {
+	return parallel.DoTasks(ctx, len(stageIDs), parallel.DoTasksOptions{
+		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
+	}, func(ctx context.Context, taskId int) error {
+		stageID := stageIDs[taskId]
+		err := m.StagesStorage.RmDigestInputsMetadata(ctx, projectName, stageID)
+		return f(ctx, stageID, err)
+	})
+}
+
 func (m *StorageManager) ForEachDeleteStageCustomTag(ctx context.Context, ids []string, f func(ctx context.Context, tag string, err error) error) error {
 	return parallel.DoTasks(ctx, len(ids), parallel.DoTasksOptions{
 		MaxNumberOfWorkers: m.MaxNumberOfWorkers(),
@@ -0,0 +1,1178 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/match"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/container_backend"
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/docker_registry/container_registry_extensions"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/slug"
+	"github.com/werf/werf/pkg/werf"
+)
+
+const (
+	OCILayoutStorageAddressPrefix = "oci:"
+
+	OCILayoutStage_ImageFormatWithUniqueID = "%s:%s-%d"
+	OCILayoutStage_ImageFormat             = "%s:%s"
+
+	ociLayoutRefNameAnnotation = "org.opencontainers.image.ref.name"
+)
+
+func IsOCILayoutStorageAddress(address string) bool {
+	return strings.HasPrefix(address, OCILayoutStorageAddressPrefix)
+}
+
+// OCILayoutStagesStorage keeps stages and all related werf records in an on-disk OCI image-layout directory.
+// Every record is an image manifest referenced from the index.json by the same tag RepoStagesStorage would use in a registry.
+type OCILayoutStagesStorage struct {
+	LayoutDir        string
+	ContainerBackend container_backend.ContainerBackend
+}
+
+func NewOCILayoutStagesStorage(layoutDir string, containerBackend container_backend.ContainerBackend) *OCILayoutStagesStorage {
+	return &OCILayoutStagesStorage{
+		LayoutDir:        layoutDir,
+		ContainerBackend: containerBackend,
+	}
+}
+
+func (storage *OCILayoutStagesStorage) ConstructStageImageName(projectName, digest string, uniqueID int64) string {
+	if uniqueID == 0 {
+		return fmt.Sprintf(OCILayoutStage_ImageFormat, projectName, digest)
+	}
+	return fmt.Sprintf(OCILayoutStage_ImageFormatWithUniqueID, projectName, digest, uniqueID)
+}
+
+func (storage *OCILayoutStagesStorage) GetStagesIDs(ctx context.Context, _ string, _ ...Option) ([]image.StageID, error) {
+	tags, err := storage.tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tags of %s: %w", storage.String(), err)
+	}
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetStagesIDs fetched tags for %q: %#v\n", storage.LayoutDir, tags)
+
+	return selectStagesIDsFromTags(ctx, tags)
+}
+
+func (storage *OCILayoutStagesStorage) GetStagesIDsByDigest(ctx context.Context, _, digest string, _ ...Option) ([]image.StageID, error) {
+	tags, err := storage.tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tags of %s: %w", storage.String(), err)
+	}
+
+	res, err := selectStagesIDsByDigestFromTags(ctx, digest, tags)
+	if err != nil {
+		return nil, err
+	}
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetStagesIDsByDigest result for %q: %#v\n", storage.LayoutDir, res)
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) GetStageDescription(ctx context.Context, projectName string, stageID image.StageID) (*image.StageDescription, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage GetStageDescription %s %s %d\n", projectName, stageID.Digest, stageID.UniqueID)
+
+	stageImageName := storage.ConstructStageImageName(projectName, stageID.Digest, stageID.UniqueID)
+	info, err := storage.getImageInfo(ctx, stageImageName, makeOCILayoutStageTag(stageID.Digest, stageID.UniqueID))
+	if err != nil {
+		return nil, fmt.Errorf("unable to inspect stage image %s: %w", stageImageName, err)
+	}
+	if info == nil {
+		return nil, nil
+	}
+
+	if rejected, err := storage.isTagExist(ctx, makeOCILayoutRejectedStageTag(stageID.Digest, stageID.UniqueID)); err != nil {
+		return nil, err
+	} else if rejected {
+		logboek.Context(ctx).Info().LogF("Stage digest %s uniqueID %d image is rejected: ignore stage image\n", stageID.Digest, stageID.UniqueID)
+		return nil, nil
+	}
+
+	return &image.StageDescription{
+		StageID: image.NewStageID(stageID.Digest, stageID.UniqueID),
+		Info:    info,
+	}, nil
+}
+
+func (storage *OCILayoutStagesStorage) ExportStage(ctx context.Context, stageDescription *image.StageDescription, destinationReference string, mutateConfigFunc func(config v1.Config) (v1.Config, error)) error {
+	img, cleanup, err := storage.getImage(ctx, stageDescription.Info.Tag)
+	if err != nil {
+		return fmt.Errorf("unable to get stage image %s: %w", stageDescription.Info.Name, err)
+	}
+	defer cleanup()
+	if img == nil {
+		return fmt.Errorf("stage image %s not found in %s", stageDescription.Info.Name, storage.String())
+	}
+
+	cfgFile, err := img.ConfigFile()
+	if err != nil {
+		return fmt.Errorf("unable to get config of stage image %s: %w", stageDescription.Info.Name, err)
+	}
+
+	cfg, err := mutateExportStageConfig(mutateConfigFunc)(*cfgFile.Config.DeepCopy())
+	if err != nil {
+		return err
+	}
+
+	img, err = mutate.Config(img, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to mutate config of stage image %s: %w", stageDescription.Info.Name, err)
+	}
+
+	opener, err := newOCILayoutImageArchiveOpener(destinationReference, img)
+	if err != nil {
+		return err
+	}
+	return docker_registry.API().PushImageArchive(ctx, opener, destinationReference)
+}
+
+func (storage *OCILayoutStagesStorage) DeleteStage(ctx context.Context, stageDescription *image.StageDescription, _ DeleteImageOptions) error {
+	stageTag := makeOCILayoutStageTag(stageDescription.StageID.Digest, stageDescription.StageID.UniqueID)
+	rejectedTag := makeOCILayoutRejectedStageTag(stageDescription.StageID.Digest, stageDescription.StageID.UniqueID)
+
+	if err := storage.removeTags(ctx, stageTag, rejectedTag); err != nil {
+		return fmt.Errorf("unable to remove stage %s: %w", stageDescription.StageID.String(), err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) AddStageCustomTag(ctx context.Context, stageDescription *image.StageDescription, tag string) error {
+	return storage.tagImage(ctx, stageDescription.Info.Tag, tag)
+}
+
+func (storage *OCILayoutStagesStorage) CheckStageCustomTag(ctx context.Context, stageDescription *image.StageDescription, tag string) error {
+	info, err := storage.getImageInfo(ctx, tag, tag)
+	if err != nil {
+		return err
+	}
+
+	if info == nil {
+		return fmt.Errorf("custom tag %q not found", tag)
+	}
+
+	if info.ID != stageDescription.Info.ID {
+		return fmt.Errorf("custom tag %q image must be the same as associated content-based tag %q image", tag, stageDescription.StageID.String())
+	}
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) DeleteStageCustomTag(ctx context.Context, tag string) error {
+	if err := storage.removeTags(ctx, tag); err != nil {
+		return fmt.Errorf("unable to delete tag %q from %s: %w", tag, storage.String(), err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) GetStageCustomTagMetadataIDs(ctx context.Context, _ ...Option) ([]string, error) {
+	tags, err := storage.tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tags of %s: %w", storage.String(), err)
+	}
+
+	var res []string
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoCustomTagMetadata_ImageTagPrefix) {
+			continue
+		}
+
+		res = append(res, strings.TrimPrefix(tag, RepoCustomTagMetadata_ImageTagPrefix))
+	}
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) GetStageCustomTagMetadata(ctx context.Context, tagOrID string) (*CustomTagMetadata, error) {
+	recordTag := makeOCILayoutCustomTagMetadataTag(tagOrID)
+	info, err := storage.getImageInfo(ctx, recordTag, recordTag)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get custom tag metadata record %s: %w", recordTag, err)
+	}
+
+	if info == nil {
+		return nil, fmt.Errorf("custom tag metadata record %s not found", recordTag)
+	}
+
+	return newCustomTagMetadataFromLabels(info.Labels), nil
+}
+
+func (storage *OCILayoutStagesStorage) RegisterStageCustomTag(ctx context.Context, projectName string, stageDescription *image.StageDescription, tag string) error {
+	labels := newCustomTagMetadata(stageDescription.StageID.String(), tag).ToLabels()
+	labels[image.WerfLabel] = projectName
+
+	if err := storage.putRecord(ctx, makeOCILayoutCustomTagMetadataTag(tag), labels); err != nil {
+		return fmt.Errorf("unable to add stage custom tag metadata: %w", err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) UnregisterStageCustomTag(ctx context.Context, tag string) error {
+	if err := storage.removeTags(ctx, makeOCILayoutCustomTagMetadataTag(tag)); err != nil {
+		return fmt.Errorf("unable to delete stage custom tag metadata: %w", err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) RejectStage(ctx context.Context, projectName, digest string, uniqueID int64) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RejectStage %s %s %d\n", projectName, digest, uniqueID)
+
+	if err := storage.putRecord(ctx, makeOCILayoutRejectedStageTag(digest, uniqueID), map[string]string{image.WerfLabel: projectName}); err != nil {
+		return fmt.Errorf("unable to put rejected stage record: %w", err)
+	}
+
+	logboek.Context(ctx).Info().LogF("Rejected stage by digest %s uniqueID %d\n", digest, uniqueID)
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) FetchImage(ctx context.Context, img container_backend.LegacyImageInterface) error {
+	_, tag := image.ParseRepositoryAndTag(img.Name())
+
+	layoutImg, cleanup, err := storage.getImage(ctx, tag)
+	if err != nil {
+		return fmt.Errorf("unable to get image %s: %w", img.Name(), err)
+	}
+	defer cleanup()
+	if layoutImg == nil {
+		return ErrBrokenImage
+	}
+
+	opener, err := newOCILayoutImageArchiveOpener(img.Name(), layoutImg)
+	if err != nil {
+		return err
+	}
+
+	archive, err := opener.Open()
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if err := storage.ContainerBackend.LoadImageFromStream(ctx, archive); err != nil {
+		return fmt.Errorf("unable to load image %s: %w", img.Name(), err)
+	}
+
+	if info, err := storage.ContainerBackend.GetImageInfo(ctx, img.Name(), container_backend.GetImageInfoOpts{TargetPlatform: img.GetTargetPlatform()}); err != nil {
+		return fmt.Errorf("unable to get inspect of image %s: %w", img.Name(), err)
+	} else {
+		img.SetInfo(info)
+	}
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) StoreImage(ctx context.Context, img container_backend.LegacyImageInterface) error {
+	if img.BuiltID() != "" {
+		if err := storage.ContainerBackend.Tag(ctx, img.BuiltID(), img.Name(), container_backend.TagOpts{TargetPlatform: img.GetTargetPlatform()}); err != nil {
+			return fmt.Errorf("unable to tag built image %q by %q: %w", img.BuiltID(), img.Name(), err)
+		}
+	}
+
+	archivePath, err := storage.saveBackendImageArchive(ctx, img.Name())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archivePath)
+
+	layoutImg, err := tarball.ImageFromPath(archivePath, nil)
+	if err != nil {
+		return fmt.Errorf("unable to open image %s archive: %w", img.Name(), err)
+	}
+
+	_, tag := image.ParseRepositoryAndTag(img.Name())
+	if err := storage.writeImage(ctx, tag, layoutImg); err != nil {
+		return fmt.Errorf("unable to store image %s: %w", img.Name(), err)
+	}
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) ShouldFetchImage(ctx context.Context, img container_backend.LegacyImageInterface) (bool, error) {
+	if info, err := storage.ContainerBackend.GetImageInfo(ctx, img.Name(), container_backend.GetImageInfoOpts{TargetPlatform: img.GetTargetPlatform()}); err != nil {
+		return false, fmt.Errorf("unable to get inspect for image %s: %w", img.Name(), err)
+	} else if info != nil {
+		img.SetInfo(info)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (storage *OCILayoutStagesStorage) CopyFromStorage(ctx context.Context, src StagesStorage, projectName string, stageID image.StageID, opts CopyFromStorageOptions) (*image.StageDescription, error) {
+	desc, err := storage.GetStageDescription(ctx, projectName, stageID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stage %s description: %w", stageID, err)
+	}
+	if desc != nil {
+		return desc, nil
+	}
+
+	if opts.IsMultiplatformImage {
+		return nil, fmt.Errorf("unable to copy multiplatform stage %s: not supported by %s", stageID.String(), storage.String())
+	}
+
+	srcRef := src.ConstructStageImageName(projectName, stageID.Digest, stageID.UniqueID)
+	dstTag := makeOCILayoutStageTag(stageID.Digest, stageID.UniqueID)
+
+	var img v1.Image
+	switch typedSrc := src.(type) {
+	case *OCILayoutStagesStorage:
+		_, srcTag := image.ParseRepositoryAndTag(srcRef)
+		var cleanup func()
+		if img, cleanup, err = typedSrc.getImage(ctx, srcTag); err != nil {
+			return nil, fmt.Errorf("unable to get image %s: %w", srcRef, err)
+		}
+		defer cleanup()
+
+		if img == nil {
+			return nil, fmt.Errorf("image %s not found in %s", srcRef, typedSrc.String())
+		}
+	case *RepoStagesStorage:
+		archivePath, err := storage.pullRegistryImageArchive(ctx, typedSrc.DockerRegistry, srcRef)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(archivePath)
+
+		if img, err = tarball.ImageFromPath(archivePath, nil); err != nil {
+			return nil, fmt.Errorf("unable to open image %s archive: %w", srcRef, err)
+		}
+	case *LocalStagesStorage:
+		archivePath, err := storage.saveBackendImageArchive(ctx, srcRef)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(archivePath)
+
+		if img, err = tarball.ImageFromPath(archivePath, nil); err != nil {
+			return nil, fmt.Errorf("unable to open image %s archive: %w", srcRef, err)
+		}
+	default:
+		return nil, fmt.Errorf("unable to copy stage %s from %s: not implemented", stageID.String(), src.String())
+	}
+
+	if err := storage.writeImage(ctx, dstTag, img); err != nil {
+		return nil, fmt.Errorf("unable to copy image %s into %s: %w", srcRef, storage.String(), err)
+	}
+
+	desc, err = storage.GetStageDescription(ctx, projectName, stageID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stage %s description: %w", stageID, err)
+	}
+	return desc, nil
+}
+
+func (storage *OCILayoutStagesStorage) CreateRepo(ctx context.Context) error {
+	_, err := storage.layoutPath(ctx)
+	return err
+}
+
+func (storage *OCILayoutStagesStorage) DeleteRepo(ctx context.Context) error {
+	return storage.withLock(ctx, func() error {
+		if err := os.RemoveAll(storage.LayoutDir); err != nil {
+			return fmt.Errorf("unable to remove %s: %w", storage.LayoutDir, err)
+		}
+		return nil
+	})
+}
+
+func (storage *OCILayoutStagesStorage) AddManagedImage(ctx context.Context, projectName, imageNameOrManagedImageName string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.AddManagedImage %s %s\n", projectName, imageNameOrManagedImageName)
+
+	if err := storage.putRecord(ctx, makeOCILayoutManagedImageTag(imageNameOrManagedImageName), map[string]string{image.WerfLabel: projectName}); err != nil {
+		return fmt.Errorf("unable to put managed image record: %w", err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) RmManagedImage(ctx context.Context, projectName, imageNameOrManagedImageName string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmManagedImage %s %s\n", projectName, imageNameOrManagedImageName)
+
+	if err := storage.removeTags(ctx, makeOCILayoutManagedImageTag(imageNameOrManagedImageName)); err != nil {
+		return fmt.Errorf("unable to remove managed image record: %w", err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) IsManagedImageExist(ctx context.Context, _, imageNameOrManagedImageName string, _ ...Option) (bool, error) {
+	return storage.isTagExist(ctx, makeOCILayoutManagedImageTag(imageNameOrManagedImageName))
+}
+
+func (storage *OCILayoutStagesStorage) GetManagedImages(ctx context.Context, projectName string, _ ...Option) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetManagedImages %s\n", projectName)
+
+	tags, err := storage.tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tags of %s: %w", storage.String(), err)
+	}
+
+	var res []string
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) {
+			continue
+		}
+
+		res = append(res, getManagedImageNameFromManagedImageID(strings.TrimPrefix(tag, RepoManagedImageRecord_ImageTagPrefix)))
+	}
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) PutImageMetadata(ctx context.Context, projectName, imageNameOrManagedImageName, commit, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PutImageMetadata %s %s %s %s\n", projectName, imageNameOrManagedImageName, commit, stageID)
+
+	if err := storage.putRecord(ctx, makeRepoImageMetadataTagName(imageNameOrManagedImageName, commit, stageID), map[string]string{image.WerfLabel: projectName}); err != nil {
+		return fmt.Errorf("unable to put image metadata record: %w", err)
+	}
+	logboek.Context(ctx).Info().LogF("Put image %s commit %s stage ID %s\n", imageNameOrManagedImageName, commit, stageID)
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) RmImageMetadata(ctx context.Context, projectName, imageNameOrManagedImageNameOrImageMetadataID, commit, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmImageMetadata %s %s %s %s\n", projectName, imageNameOrManagedImageNameOrImageMetadataID, commit, stageID)
+
+	tag := makeRepoImageMetadataTagName(imageNameOrManagedImageNameOrImageMetadataID, commit, stageID)
+	if exist, err := storage.isTagExist(ctx, tag); err != nil {
+		return err
+	} else if !exist {
+		tag = makeRepoImageMetadataTagNameByImageMetadataID(imageNameOrManagedImageNameOrImageMetadataID, commit, stageID)
+		if !slug.IsValidDockerTag(tag) { // it is not imageMetadataID
+			return nil
+		}
+	}
+
+	if err := storage.removeTags(ctx, tag); err != nil {
+		return fmt.Errorf("unable to remove image metadata record %s: %w", tag, err)
+	}
+
+	logboek.Context(ctx).Info().LogF("Removed image %s commit %s stage ID %s\n", imageNameOrManagedImageNameOrImageMetadataID, commit, stageID)
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) IsImageMetadataExist(ctx context.Context, projectName, imageNameOrManagedImageName, commit, stageID string, _ ...Option) (bool, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.IsImageMetadataExist %s %s %s %s\n", projectName, imageNameOrManagedImageName, commit, stageID)
+
+	return storage.isTagExist(ctx, makeRepoImageMetadataTagName(imageNameOrManagedImageName, commit, stageID))
+}
+
+func (storage *OCILayoutStagesStorage) GetAllAndGroupImageMetadataByImageName(ctx context.Context, projectName string, imageNameOrManagedImageList []string, _ ...Option) (map[string]map[string][]string, map[string]map[string][]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetAllAndGroupImageMetadataByImageName %s\n", projectName)
+
+	tags, err := storage.tags(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get tags of %s: %w", storage.String(), err)
+	}
+
+	return groupImageMetadataTagsByImageName(ctx, imageNameOrManagedImageList, tags, RepoImageMetadataByCommitRecord_ImageTagPrefix)
+}
+
+func (storage *OCILayoutStagesStorage) GetImportMetadata(ctx context.Context, _, id string) (*ImportMetadata, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetImportMetadata %s\n", id)
+
+	recordTag := RepoImportMetadata_ImageTagPrefix + id
+	info, err := storage.getImageInfo(ctx, recordTag, recordTag)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get import metadata record %s: %w", id, err)
+	}
+
+	if info != nil {
+		return newImportMetadataFromLabels(info.Labels), nil
+	}
+
+	return nil, nil
+}
+
+func (storage *OCILayoutStagesStorage) PutImportMetadata(ctx context.Context, projectName string, metadata *ImportMetadata) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PutImportMetadata %v\n", metadata)
+
+	labels := metadata.ToLabelsMap()
+	labels[image.WerfLabel] = projectName
+
+	if err := storage.putRecord(ctx, RepoImportMetadata_ImageTagPrefix+metadata.ImportSourceID, labels); err != nil {
+		return fmt.Errorf("unable to put import metadata record: %w", err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) RmImportMetadata(ctx context.Context, _, id string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmImportMetadata %s\n", id)
+
+	if err := storage.removeTags(ctx, RepoImportMetadata_ImageTagPrefix+id); err != nil {
+		return fmt.Errorf("unable to remove import metadata record %s: %w", id, err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) GetImportMetadataIDs(ctx context.Context, _ string, _ ...Option) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetImportMetadataIDs\n")
+
+	tags, err := storage.tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tags of %s: %w", storage.String(), err)
+	}
+
+	var ids []string
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, RepoImportMetadata_ImageTagPrefix) {
+			continue
+		}
+
+		ids = append(ids, getImportMetadataIDFromRepoTag(tag))
+	}
+
+	return ids, nil
+}
+
+func (storage *OCILayoutStagesStorage) GetVulnerabilityScanMetadata(ctx context.Context, _, stageID string) (*VulnerabilityScanMetadata, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetVulnerabilityScanMetadata %s\n", stageID)
+
+	recordTag := RepoVulnerabilityScanMetadata_ImageTagPrefix + stageID
+	info, err := storage.getImageInfo(ctx, recordTag, recordTag)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get vulnerability scan metadata record %s: %w", stageID, err)
+	}
+
+	if info != nil {
+		return newVulnerabilityScanMetadataFromLabels(info.Labels)
+	}
+
+	return nil, nil
+}
+
+func (storage *OCILayoutStagesStorage) PutVulnerabilityScanMetadata(ctx context.Context, projectName string, metadata *VulnerabilityScanMetadata) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PutVulnerabilityScanMetadata %s\n", metadata.StageID)
+
+	labels, err := metadata.ToLabelsMap()
+	if err != nil {
+		return err
+	}
+	labels[image.WerfLabel] = projectName
+
+	if err := storage.putRecord(ctx, RepoVulnerabilityScanMetadata_ImageTagPrefix+metadata.StageID, labels); err != nil {
+		return fmt.Errorf("unable to put vulnerability scan metadata record: %w", err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) RmVulnerabilityScanMetadata(ctx context.Context, _, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmVulnerabilityScanMetadata %s\n", stageID)
+
+	if err := storage.removeTags(ctx, RepoVulnerabilityScanMetadata_ImageTagPrefix+stageID); err != nil {
+		return fmt.Errorf("unable to remove vulnerability scan metadata record %s: %w", stageID, err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) GetVulnerabilityScanMetadataIDs(ctx context.Context, _ string, _ ...Option) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetVulnerabilityScanMetadataIDs\n")
+
+	tags, err := storage.tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tags of %s: %w", storage.String(), err)
+	}
+
+	return getVulnerabilityScanMetadataIDsFromRepoTags(tags), nil
+}
+
+func (storage *OCILayoutStagesStorage) GetDigestInputsMetadata(ctx context.Context, _, stageID string) (*DigestInputsMetadata, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetDigestInputsMetadata %s\n", stageID)
+
+	recordTag := RepoDigestInputsMetadata_ImageTagPrefix + stageID
+	info, err := storage.getImageInfo(ctx, recordTag, recordTag)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get digest inputs metadata record %s: %w", stageID, err)
+	}
+
+	if info != nil {
+		return newDigestInputsMetadataFromLabels(info.Labels)
+	}
+
+	return nil, nil
+}
+
+func (storage *OCILayoutStagesStorage) PutDigestInputsMetadata(ctx context.Context, projectName string, metadata *DigestInputsMetadata) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PutDigestInputsMetadata %s\n", metadata.StageID)
+
+	labels, err := metadata.ToLabelsMap()
+	if err != nil {
+		return err
+	}
+	labels[image.WerfLabel] = projectName
+
+	if err := storage.putRecord(ctx, RepoDigestInputsMetadata_ImageTagPrefix+metadata.StageID, labels); err != nil {
+		return fmt.Errorf("unable to put digest inputs metadata record: %w", err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) RmDigestInputsMetadata(ctx context.Context, _, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.RmDigestInputsMetadata %s\n", stageID)
+
+	if err := storage.removeTags(ctx, RepoDigestInputsMetadata_ImageTagPrefix+stageID); err != nil {
+		return fmt.Errorf("unable to remove digest inputs metadata record %s: %w", stageID, err)
+	}
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) GetDigestInputsMetadataIDs(ctx context.Context, _ string, _ ...Option) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetDigestInputsMetadataIDs\n")
+
+	tags, err := storage.tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tags of %s: %w", storage.String(), err)
+	}
+
+	return getDigestInputsMetadataIDsFromRepoTags(tags), nil
+}
+
+func (storage *OCILayoutStagesStorage) GetClientIDRecords(ctx context.Context, projectName string, _ ...Option) ([]*ClientIDRecord, error) {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.GetClientIDRecords for project %s\n", projectName)
+
+	tags, err := storage.tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tags of %s: %w", storage.String(), err)
+	}
+
+	return selectClientIDRecordsFromTags(tags), nil
+}
+
+func (storage *OCILayoutStagesStorage) PostClientIDRecord(ctx context.Context, projectName string, rec *ClientIDRecord) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PostClientID %s for project %s\n", rec.ClientID, projectName)
+
+	tag := fmt.Sprintf("%s%s-%d", RepoClientIDRecord_ImageTagPrefix, rec.ClientID, rec.TimestampMillisec)
+	if err := storage.putRecord(ctx, tag, map[string]string{image.WerfLabel: projectName}); err != nil {
+		return fmt.Errorf("unable to put client id record: %w", err)
+	}
+
+	logboek.Context(ctx).Info().LogF("Posted new clientID %q for project %s\n", rec.ClientID, projectName)
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) PostMultiplatformImage(ctx context.Context, projectName, tag string, allPlatformsImages []*image.Info) error {
+	logboek.Context(ctx).Debug().LogF("-- OCILayoutStagesStorage.PostMultiplatformImage by tag %s for project %s\n", tag, projectName)
+
+	var ii v1.ImageIndex
+	ii = empty.Index
+	ii = mutate.IndexMediaType(ii, types.OCIImageIndex)
+
+	adds := make([]mutate.IndexAddendum, 0, len(allPlatformsImages))
+	for _, info := range allPlatformsImages {
+		img, cleanup, err := storage.getImage(ctx, info.Tag)
+		if err != nil {
+			return fmt.Errorf("unable to get image %s: %w", info.Name, err)
+		}
+		defer cleanup()
+		if img == nil {
+			return fmt.Errorf("image %s not found in %s", info.Name, storage.String())
+		}
+
+		cf, err := img.ConfigFile()
+		if err != nil {
+			return fmt.Errorf("unable to get config file of %q: %w", info.Name, err)
+		}
+		desc, err := partial.Descriptor(img)
+		if err != nil {
+			return fmt.Errorf("unable to create image descriptor of %q: %w", info.Name, err)
+		}
+		desc.Platform = cf.Platform()
+
+		adds = append(adds, mutate.IndexAddendum{Add: img, Descriptor: *desc})
+	}
+	ii = mutate.AppendManifests(ii, adds...)
+
+	if err := storage.withLayoutLock(ctx, func(p layout.Path) error {
+		return p.ReplaceIndex(ii, match.Name(tag), layout.WithAnnotations(map[string]string{ociLayoutRefNameAnnotation: tag}))
+	}); err != nil {
+		return fmt.Errorf("unable to write image index %s: %w", tag, err)
+	}
+
+	logboek.Context(ctx).Info().LogF("Posted image index %s for project %s\n", tag, projectName)
+
+	return nil
+}
+
+func (storage *OCILayoutStagesStorage) FilterStagesAndProcessRelatedData(_ context.Context, stageDescriptions []*image.StageDescription, _ FilterStagesAndProcessRelatedDataOptions) ([]*image.StageDescription, error) {
+	return stageDescriptions, nil
+}
+
+func (storage *OCILayoutStagesStorage) String() string {
+	return storage.Address()
+}
+
+func (storage *OCILayoutStagesStorage) Address() string {
+	return OCILayoutStorageAddressPrefix + storage.LayoutDir
+}
+
+// NewStageArchiveOpener returns the stage image as a docker-archive stream referenced by destinationReference.
+// The returned cleanup function must be called when the opener is not needed anymore.
+func (storage *OCILayoutStagesStorage) NewStageArchiveOpener(ctx context.Context, stageID image.StageID, destinationReference string) (docker_registry.ArchiveOpener, func(), error) {
+	tag := makeOCILayoutStageTag(stageID.Digest, stageID.UniqueID)
+
+	img, cleanup, err := storage.getImage(ctx, tag)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get stage %s image: %w", stageID.String(), err)
+	}
+	if img == nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("stage %s not found in %s", stageID.String(), storage.String())
+	}
+
+	opener, err := newOCILayoutImageArchiveOpener(destinationReference, img)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return opener, cleanup, nil
+}
+
+// RemoveUnusedBlobs removes the blobs which are not referenced by the layout tags anymore.
+// Tags removal keeps the blobs, so that the whole batch of removals (e.g. cleanup) is followed by the single blobs scan.
+func (storage *OCILayoutStagesStorage) RemoveUnusedBlobs(ctx context.Context) error {
+	return storage.withLayoutLock(ctx, func(p layout.Path) error {
+		return gcOCILayoutBlobs(p)
+	})
+}
+
+func (storage *OCILayoutStagesStorage) putRecord(ctx context.Context, tag string, labels map[string]string) error {
+	return storage.writeImage(ctx, tag, container_registry_extensions.NewManifestOnlyImage(labels))
+}
+
+func (storage *OCILayoutStagesStorage) writeImage(ctx context.Context, tag string, img v1.Image) error {
+	return storage.withLayoutLock(ctx, func(p layout.Path) error {
+		return p.ReplaceImage(img, match.Name(tag), layout.WithAnnotations(map[string]string{ociLayoutRefNameAnnotation: tag}))
+	})
+}
+
+func (storage *OCILayoutStagesStorage) tagImage(ctx context.Context, srcTag, dstTag string) error {
+	return storage.withLayoutLock(ctx, func(p layout.Path) error {
+		desc, err := findOCILayoutDescriptor(p, srcTag)
+		if err != nil {
+			return err
+		}
+		if desc == nil {
+			return fmt.Errorf("tag %q not found", srcTag)
+		}
+
+		if err := p.RemoveDescriptors(match.Name(dstTag)); err != nil {
+			return err
+		}
+
+		newDesc := *desc
+		newDesc.Annotations = map[string]string{ociLayoutRefNameAnnotation: dstTag}
+		return p.AppendDescriptor(newDesc)
+	})
+}
+
+// removeTags removes the tags from the index, the blobs are removed by RemoveUnusedBlobs.
+func (storage *OCILayoutStagesStorage) removeTags(ctx context.Context, tags ...string) error {
+	return storage.withLayoutLock(ctx, func(p layout.Path) error {
+		for _, tag := range tags {
+			if err := p.RemoveDescriptors(match.Name(tag)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (storage *OCILayoutStagesStorage) tags(ctx context.Context) ([]string, error) {
+	var res []string
+	if err := storage.withLayoutLock(ctx, func(p layout.Path) error {
+		im, err := getOCILayoutIndexManifest(p)
+		if err != nil {
+			return err
+		}
+
+		for _, desc := range im.Manifests {
+			if tag := desc.Annotations[ociLayoutRefNameAnnotation]; tag != "" {
+				res = append(res, tag)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (storage *OCILayoutStagesStorage) isTagExist(ctx context.Context, tag string) (bool, error) {
+	var exist bool
+	if err := storage.withLayoutLock(ctx, func(p layout.Path) error {
+		desc, err := findOCILayoutDescriptor(p, tag)
+		exist = desc != nil
+		return err
+	}); err != nil {
+		return false, fmt.Errorf("unable to check tag %q existence: %w", tag, err)
+	}
+	return exist, nil
+}
+
+// getImage returns the copy of the image made holding the lock, because the layout image is read lazily
+// and its blobs might be removed by RemoveUnusedBlobs of the concurrent process.
+// The image is nil when there is no such tag in the layout.
+// The returned cleanup function removes the copy and must be called when the image is not needed anymore.
+func (storage *OCILayoutStagesStorage) getImage(ctx context.Context, tag string) (v1.Image, func(), error) {
+	var tmpDir string
+	cleanup := func() {
+		if tmpDir != "" {
+			os.RemoveAll(tmpDir)
+		}
+	}
+
+	var img v1.Image
+	if err := storage.withLayoutLock(ctx, func(p layout.Path) error {
+		desc, err := findOCILayoutDescriptor(p, tag)
+		if err != nil || desc == nil {
+			return err
+		}
+
+		if !desc.MediaType.IsImage() {
+			return fmt.Errorf("unsupported media type %q of %q", desc.MediaType, tag)
+		}
+
+		layoutImg, err := p.Image(desc.Digest)
+		if err != nil {
+			return err
+		}
+
+		tmpDir, err = ioutil.TempDir(werf.GetTmpDir(), "oci-layout-image-")
+		if err != nil {
+			return fmt.Errorf("unable to create tmp dir: %w", err)
+		}
+
+		tmpLayout, err := layout.Write(tmpDir, empty.Index)
+		if err != nil {
+			return fmt.Errorf("unable to initialize OCI layout %s: %w", tmpDir, err)
+		}
+
+		if err := tmpLayout.AppendImage(layoutImg); err != nil {
+			return fmt.Errorf("unable to copy image %q: %w", tag, err)
+		}
+
+		img, err = tmpLayout.Image(desc.Digest)
+		return err
+	}); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return img, cleanup, nil
+}
+
+// getImageInfo returns nil when there is no such tag in the layout.
+func (storage *OCILayoutStagesStorage) getImageInfo(ctx context.Context, reference, tag string) (*image.Info, error) {
+	var info *image.Info
+	if err := storage.withLayoutLock(ctx, func(p layout.Path) error {
+		desc, err := findOCILayoutDescriptor(p, tag)
+		if err != nil || desc == nil {
+			return err
+		}
+
+		ii, err := p.ImageIndex()
+		if err != nil {
+			return err
+		}
+
+		info, err = newImageInfoFromOCILayoutDescriptor(reference, tag, ii, *desc)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (storage *OCILayoutStagesStorage) saveBackendImageArchive(ctx context.Context, ref string) (string, error) {
+	stream, err := storage.ContainerBackend.SaveImageToStream(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("unable to save image %s: %w", ref, err)
+	}
+	defer stream.Close()
+
+	return writeTmpImageArchive(ref, func(w io.Writer) error {
+		_, err := io.Copy(w, stream)
+		return err
+	})
+}
+
+func (storage *OCILayoutStagesStorage) pullRegistryImageArchive(ctx context.Context, dockerRegistry docker_registry.Interface, ref string) (string, error) {
+	return writeTmpImageArchive(ref, func(w io.Writer) error {
+		return dockerRegistry.PullImageArchive(ctx, w, ref)
+	})
+}
+
+func (storage *OCILayoutStagesStorage) layoutPath(ctx context.Context) (layout.Path, error) {
+	var p layout.Path
+	err := storage.withLayoutLock(ctx, func(path layout.Path) error {
+		p = path
+		return nil
+	})
+	return p, err
+}
+
+// withLayoutLock opens (initializing if needed) the layout directory and runs f holding the host lock,
+// because index.json is rewritten in place on every change.
+func (storage *OCILayoutStagesStorage) withLayoutLock(ctx context.Context, f func(p layout.Path) error) error {
+	return storage.withLock(ctx, func() error {
+		p, err := layout.FromPath(storage.LayoutDir)
+		if err != nil {
+			if _, statErr := os.Stat(filepath.Join(storage.LayoutDir, "index.json")); !os.IsNotExist(statErr) {
+				return fmt.Errorf("unable to open OCI layout %s: %w", storage.LayoutDir, err)
+			}
+
+			if p, err = layout.Write(storage.LayoutDir, empty.Index); err != nil {
+				return fmt.Errorf("unable to initialize OCI layout %s: %w", storage.LayoutDir, err)
+			}
+		}
+
+		return f(p)
+	})
+}
+
+func (storage *OCILayoutStagesStorage) withLock(ctx context.Context, f func() error) error {
+	lockName := fmt.Sprintf("oci_layout_stages_storage.%s", slug.Slug(storage.LayoutDir))
+	return werf.WithHostLock(ctx, lockName, lockgate.AcquireOptions{Timeout: 600 * time.Second}, f)
+}
+
+func getOCILayoutIndexManifest(p layout.Path) (*v1.IndexManifest, error) {
+	ii, err := p.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	return ii.IndexManifest()
+}
+
+func findOCILayoutDescriptor(p layout.Path, tag string) (*v1.Descriptor, error) {
+	im, err := getOCILayoutIndexManifest(p)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, desc := range im.Manifests {
+		if desc.Annotations[ociLayoutRefNameAnnotation] == tag {
+			return desc.DeepCopy(), nil
+		}
+	}
+	return nil, nil
+}
+
+func newImageInfoFromOCILayoutDescriptor(reference, tag string, ii v1.ImageIndex, desc v1.Descriptor) (*image.Info, error) {
+	repository, _ := image.ParseRepositoryAndTag(reference)
+	info := &image.Info{
+		Name:       reference,
+		Repository: repository,
+		Tag:        tag,
+		RepoDigest: desc.Digest.String(),
+	}
+
+	if desc.MediaType.IsIndex() {
+		info.IsIndex = true
+
+		subIndex, err := ii.ImageIndex(desc.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("error getting image index: %w", err)
+		}
+
+		im, err := subIndex.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("error getting image index manifest: %w", err)
+		}
+
+		for _, subDesc := range im.Manifests {
+			subInfo, err := newImageInfoFromOCILayoutDescriptor(reference, tag, subIndex, subDesc)
+			if err != nil {
+				return nil, fmt.Errorf("error getting image %s descriptor: %w", subDesc.Digest, err)
+			}
+			info.Index = append(info.Index, subInfo)
+		}
+
+		return info, nil
+	}
+
+	img, err := ii.Image(desc.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("error getting image manifest: %w", err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	info.ID = manifest.Config.Digest.String()
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	info.Labels = configFile.Config.Labels
+	info.OnBuild = configFile.Config.OnBuild
+	info.Env = configFile.Config.Env
+	info.SetCreatedAtUnix(configFile.Created.Unix())
+
+	for _, l := range manifest.Layers {
+		info.Size += l.Size
+		info.Layers = append(info.Layers, image.LayerInfo{Digest: l.Digest.String(), Size: l.Size})
+	}
+
+	if baseImageID, ok := configFile.Config.Labels["werf.io/base-image-id"]; ok {
+		info.ParentID = baseImageID
+	} else {
+		info.ParentID = configFile.Config.Image
+	}
+
+	return info, nil
+}
+
+// gcOCILayoutBlobs removes blobs which are not reachable from the layout index anymore.
+func gcOCILayoutBlobs(p layout.Path) error {
+	ii, err := p.ImageIndex()
+	if err != nil {
+		return err
+	}
+
+	used := map[string]bool{}
+	if err := collectOCILayoutIndexBlobs(ii, used); err != nil {
+		return fmt.Errorf("unable to collect used blobs: %w", err)
+	}
+
+	blobsDir := filepath.Join(string(p), "blobs", "sha256")
+	entries, err := ioutil.ReadDir(blobsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to read %s: %w", blobsDir, err)
+	}
+
+	for _, entry := range entries {
+		h := v1.Hash{Algorithm: "sha256", Hex: entry.Name()}
+		if used[h.String()] {
+			continue
+		}
+
+		if err := p.RemoveBlob(h); err != nil {
+			return fmt.Errorf("unable to remove blob %s: %w", h, err)
+		}
+	}
+
+	return nil
+}
+
+func collectOCILayoutIndexBlobs(ii v1.ImageIndex, used map[string]bool) error {
+	im, err := ii.IndexManifest()
+	if err != nil {
+		return err
+	}
+
+	for _, desc := range im.Manifests {
+		used[desc.Digest.String()] = true
+
+		switch {
+		case desc.MediaType.IsIndex():
+			subIndex, err := ii.ImageIndex(desc.Digest)
+			if err != nil {
+				return err
+			}
+			if err := collectOCILayoutIndexBlobs(subIndex, used); err != nil {
+				return err
+			}
+		case desc.MediaType.IsImage():
+			img, err := ii.Image(desc.Digest)
+			if err != nil {
+				return err
+			}
+
+			manifest, err := img.Manifest()
+			if err != nil {
+				return err
+			}
+
+			used[manifest.Config.Digest.String()] = true
+			for _, l := range manifest.Layers {
+				used[l.Digest.String()] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeTmpImageArchive(ref string, write func(w io.Writer) error) (string, error) {
+	f, err := ioutil.TempFile(werf.GetTmpDir(), "image-*.tar")
+	if err != nil {
+		return "", fmt.Errorf("unable to create image archive: %w", err)
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("unable to write image %s archive: %w", ref, err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("unable to close image %s archive: %w", ref, err)
+	}
+
+	return f.Name(), nil
+}
+
+type ociLayoutImageArchiveOpener struct {
+	ref name.Tag
+	img v1.Image
+}
+
+func newOCILayoutImageArchiveOpener(reference string, img v1.Image) (*ociLayoutImageArchiveOpener, error) {
+	ref, err := name.NewTag(reference)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse reference %q: %w", reference, err)
+	}
+	return &ociLayoutImageArchiveOpener{ref: ref, img: img}, nil
+}
+
+func (opener *ociLayoutImageArchiveOpener) Open() (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(tarball.Write(opener.ref, opener.img, pw))
+	}()
+	return pr, nil
+}
+
+func makeOCILayoutStageTag(digest string, uniqueID int64) string {
+	if uniqueID == 0 {
+		return digest
+	}
+	return fmt.Sprintf("%s-%d", digest, uniqueID)
+}
+
+func makeOCILayoutRejectedStageTag(digest string, uniqueID int64) string {
+	return fmt.Sprintf("%s-%d%s", digest, uniqueID, RepoRejectedStageImageRecord_ImageTagSuffix)
+}
+
+func makeOCILayoutManagedImageTag(imageNameOrManagedImageName string) string {
+	return RepoManagedImageRecord_ImageTagPrefix + getManagedImageID(imageNameOrManagedImageName)
+}
+
+func makeOCILayoutCustomTagMetadataTag(tag string) string {
+	return RepoCustomTagMetadata_ImageTagPrefix + slug.LimitedSlug(tag, 48)
+}
@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+
+	"github.com/werf/werf/pkg/build/digest_inputs"
+	"github.com/werf/werf/pkg/vulnerability_scan"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func newTestOCILayoutStagesStorage(t *testing.T) *OCILayoutStagesStorage {
+	if err := werf.Init(t.TempDir(), t.TempDir()); err != nil {
+		t.Fatalf("unable to init werf: %s", err)
+	}
+	return NewOCILayoutStagesStorage(t.TempDir(), nil)
+}
+
+func TestOCILayoutStagesStorage_ManagedImages(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestOCILayoutStagesStorage(t)
+
+	for _, imageName := range []string{"backend", "frontend/app", ""} {
+		if err := storage.AddManagedImage(ctx, "project", imageName); err != nil {
+			t.Fatalf("unable to add managed image %q: %s", imageName, err)
+		}
+	}
+
+	if exist, err := storage.IsManagedImageExist(ctx, "project", "frontend/app"); err != nil {
+		t.Fatal(err)
+	} else if !exist {
+		t.Errorf("expected managed image %q to exist", "frontend/app")
+	}
+
+	if err := storage.RmManagedImage(ctx, "project", "backend"); err != nil {
+		t.Fatal(err)
+	}
+
+	images, err := storage.GetManagedImages(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || images[0] != "frontend/app" || images[1] != "" {
+		t.Errorf("unexpected managed images: %#v", images)
+	}
+}
+
+func TestOCILayoutStagesStorage_ImageMetadata(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestOCILayoutStagesStorage(t)
+
+	stageID := "2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7-1611836746968"
+	if err := storage.PutImageMetadata(ctx, "project", "backend", "commit1", stageID); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.PutImageMetadata(ctx, "project", "unknown", "commit2", stageID); err != nil {
+		t.Fatal(err)
+	}
+
+	if exist, err := storage.IsImageMetadataExist(ctx, "project", "backend", "commit1", stageID); err != nil {
+		t.Fatal(err)
+	} else if !exist {
+		t.Errorf("expected image metadata to exist")
+	}
+
+	managed, notManaged, err := storage.GetAllAndGroupImageMetadataByImageName(ctx, "project", []string{"backend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commits := managed["backend"][stageID]; len(commits) != 1 || commits[0] != "commit1" {
+		t.Errorf("unexpected managed image metadata: %#v", managed)
+	}
+	if len(notManaged) != 1 {
+		t.Errorf("unexpected not managed image metadata: %#v", notManaged)
+	}
+
+	if err := storage.RmImageMetadata(ctx, "project", "backend", "commit1", stageID); err != nil {
+		t.Fatal(err)
+	}
+	if exist, err := storage.IsImageMetadataExist(ctx, "project", "backend", "commit1", stageID); err != nil {
+		t.Fatal(err)
+	} else if exist {
+		t.Errorf("expected image metadata to be removed")
+	}
+}
+
+func TestOCILayoutStagesStorage_ImportMetadata(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestOCILayoutStagesStorage(t)
+
+	metadata := &ImportMetadata{ImportSourceID: "source-id", SourceImageID: "sha256:image", Checksum: "checksum"}
+	if err := storage.PutImportMetadata(ctx, "project", metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := storage.GetImportMetadata(ctx, "project", "source-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || *got != *metadata {
+		t.Errorf("unexpected import metadata: %#v", got)
+	}
+
+	ids, err := storage.GetImportMetadataIDs(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "source-id" {
+		t.Errorf("unexpected import metadata ids: %#v", ids)
+	}
+
+	if err := storage.RmImportMetadata(ctx, "project", "source-id"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := storage.GetImportMetadata(ctx, "project", "source-id"); err != nil {
+		t.Fatal(err)
+	} else if got != nil {
+		t.Errorf("expected import metadata to be removed, got %#v", got)
+	}
+}
+
+func TestOCILayoutStagesStorage_VulnerabilityScanMetadata(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestOCILayoutStagesStorage(t)
+
+	metadata := &VulnerabilityScanMetadata{
+		StageID: "digest-1611836746968",
+		Result: &vulnerability_scan.Result{Findings: []*vulnerability_scan.Finding{
+			{ID: "CVE-2023-0001", Package: "libcrypto3", InstalledVersion: "3.0.7-r0", FixedVersion: "3.0.8-r0", Severity: vulnerability_scan.SeverityCritical},
+		}},
+		Scanner:   "trivy image --format json --quiet",
+		ScannedAt: time.Unix(1683000000, 0),
+	}
+	if err := storage.PutVulnerabilityScanMetadata(ctx, "project", metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := storage.GetVulnerabilityScanMetadata(ctx, "project", "digest-1611836746968")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || !reflect.DeepEqual(got, metadata) {
+		t.Errorf("unexpected vulnerability scan metadata: %#v", got)
+	}
+
+	ids, err := storage.GetVulnerabilityScanMetadataIDs(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "digest-1611836746968" {
+		t.Errorf("unexpected vulnerability scan metadata ids: %#v", ids)
+	}
+
+	if err := storage.RmVulnerabilityScanMetadata(ctx, "project", "digest-1611836746968"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := storage.GetVulnerabilityScanMetadata(ctx, "project", "digest-1611836746968"); err != nil {
+		t.Fatal(err)
+	} else if got != nil {
+		t.Errorf("expected vulnerability scan metadata to be removed, got %#v", got)
+	}
+}
+
+func TestOCILayoutStagesStorage_DigestInputsMetadata(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestOCILayoutStagesStorage(t)
+
+	metadata := &DigestInputsMetadata{
+		StageID: "digest-1611836746968",
+		Stages: []digest_inputs.Stage{
+			{Name: "from", Digest: "from-digest", Inputs: []digest_inputs.Input{{Name: "base image", Value: "alpine:3.18"}}},
+			{Name: "install", Digest: "digest", Inputs: []digest_inputs.Input{{Name: "RUN", Value: "apk add curl"}}},
+		},
+	}
+	if err := storage.PutDigestInputsMetadata(ctx, "project", metadata); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.PutVulnerabilityScanMetadata(ctx, "project", &VulnerabilityScanMetadata{StageID: "other-1611836746968", Result: &vulnerability_scan.Result{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := storage.GetDigestInputsMetadata(ctx, "project", "digest-1611836746968")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || !reflect.DeepEqual(got, metadata) {
+		t.Errorf("unexpected digest inputs metadata: %#v", got)
+	}
+
+	ids, err := storage.GetDigestInputsMetadataIDs(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "digest-1611836746968" {
+		t.Errorf("unexpected digest inputs metadata ids: %#v", ids)
+	}
+
+	if err := storage.RmDigestInputsMetadata(ctx, "project", "digest-1611836746968"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := storage.GetDigestInputsMetadata(ctx, "project", "digest-1611836746968"); err != nil {
+		t.Fatal(err)
+	} else if got != nil {
+		t.Errorf("expected digest inputs metadata to be removed, got %#v", got)
+	}
+}
+
+func TestOCILayoutStagesStorage_ClientIDRecords(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestOCILayoutStagesStorage(t)
+
+	if err := storage.PostClientIDRecord(ctx, "project", &ClientIDRecord{ClientID: "client-1", TimestampMillisec: 1611836746968}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := storage.GetClientIDRecords(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ClientID != "client-1" || records[0].TimestampMillisec != 1611836746968 {
+		t.Errorf("unexpected client id records: %v", records)
+	}
+}
+
+func TestOCILayoutStagesStorage_RemoveUnusedBlobs(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestOCILayoutStagesStorage(t)
+
+	img, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := makeOCILayoutStageTag("2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7", 1611836746968)
+	if err := storage.writeImage(ctx, tag, img); err != nil {
+		t.Fatal(err)
+	}
+
+	copiedImg, cleanup, err := storage.getImage(ctx, tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	blobsBefore := countOCILayoutBlobs(t, storage)
+
+	if err := storage.removeTags(ctx, tag); err != nil {
+		t.Fatal(err)
+	}
+	if blobs := countOCILayoutBlobs(t, storage); blobs != blobsBefore {
+		t.Errorf("expected %d blobs to be kept until RemoveUnusedBlobs, got %d", blobsBefore, blobs)
+	}
+
+	if err := storage.RemoveUnusedBlobs(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if blobs := countOCILayoutBlobs(t, storage); blobs != 0 {
+		t.Errorf("expected all blobs to be removed, got %d", blobs)
+	}
+
+	// the copy of the image is still readable after the layout blobs removal
+	layers, err := copiedImg.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range layers {
+		rc, err := l.Compressed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, rc); err != nil {
+			t.Fatal(err)
+		}
+		rc.Close()
+	}
+
+	if expected, err := img.Digest(); err != nil {
+		t.Fatal(err)
+	} else if got, err := copiedImg.Digest(); err != nil {
+		t.Fatal(err)
+	} else if got != expected {
+		t.Errorf("expected image copy digest %s, got %s", expected, got)
+	}
+
+	cleanup()
+	if _, err := copiedImg.ConfigFile(); err == nil {
+		t.Errorf("expected image copy to be removed by cleanup")
+	}
+}
+
+func countOCILayoutBlobs(t *testing.T, storage *OCILayoutStagesStorage) int {
+	entries, err := os.ReadDir(filepath.Join(storage.LayoutDir, "blobs", "sha256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -12,6 +13,8 @@ import (
 	"github.com/werf/werf/pkg/container_backend"
 	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/sbom"
+	"github.com/werf/werf/pkg/signing"
 	"github.com/werf/werf/pkg/slug"
 	"github.com/werf/werf/pkg/util"
 )
@@ -35,6 +38,14 @@ const (
 	RepoImportMetadata_ImageTagPrefix  = "import-metadata-"
 	RepoImportMetadata_ImageNameFormat = "%s:import-metadata-%s"
 
+	RepoVulnerabilityScanMetadata_ImageTagPrefix  = "vulnerability-scan-"
+	RepoVulnerabilityScanMetadata_ImageNameFormat = "%s:vulnerability-scan-%s"
+
+	RepoDigestInputsMetadata_ImageTagPrefix  = "digest-inputs-"
+	RepoDigestInputsMetadata_ImageNameFormat = "%s:digest-inputs-%s"
+
+	RepoDockerfileLayersCacheRecord_ImageTag = "dockerfile-layers-cache"
+
 	RepoClientIDRecord_ImageTagPrefix  = "client-id-"
 	RepoClientIDRecord_ImageNameFormat = "%s:client-id-%s-%d"
 
@@ -86,40 +97,43 @@ func (storage *RepoStagesStorage) ConstructStageImageName(_, digest string, uniq
 }
 
 func (storage *RepoStagesStorage) GetStagesIDs(ctx context.Context, _ string, opts ...Option) ([]image.StageID, error) {
-	var res []image.StageID
-
 	o := makeOptions(opts...)
 	if tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress, o.dockerRegistryOptions...); err != nil {
 		return nil, fmt.Errorf("unable to fetch tags for repo %q: %w", storage.RepoAddress, err)
 	} else {
 		logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetStagesIDs fetched tags for %q: %#v\n", storage.RepoAddress, tags)
+		return selectStagesIDsFromTags(ctx, tags)
+	}
+}
 
-		for _, tag := range tags {
-			isRegularStage := (len(tag) == 70 && len(strings.Split(tag, "-")) == 2) // 2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7-1611836746968
-			isMultiplatformStage := (len(tag) == 56)                                // 2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7
-			if !isRegularStage && !isMultiplatformStage {
-				continue
-			}
+func selectStagesIDsFromTags(ctx context.Context, tags []string) ([]image.StageID, error) {
+	var res []image.StageID
 
-			if strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoImageMetadataByCommitRecord_ImageTagPrefix) || strings.HasSuffix(tag, RepoRejectedStageImageRecord_ImageTagSuffix) {
-				continue
-			}
+	for _, tag := range tags {
+		isRegularStage := (len(tag) == 70 && len(strings.Split(tag, "-")) == 2) // 2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7-1611836746968
+		isMultiplatformStage := (len(tag) == 56)                                // 2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7
+		if !isRegularStage && !isMultiplatformStage {
+			continue
+		}
 
-			if digest, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(tag); err != nil {
-				if isUnexpectedTagFormatError(err) {
-					logboek.Context(ctx).Debug().LogLn(err.Error())
-					continue
-				}
-				return nil, err
-			} else {
-				res = append(res, *image.NewStageID(digest, uniqueID))
+		if strings.HasPrefix(tag, RepoManagedImageRecord_ImageTagPrefix) || strings.HasPrefix(tag, RepoImageMetadataByCommitRecord_ImageTagPrefix) || strings.HasSuffix(tag, RepoRejectedStageImageRecord_ImageTagSuffix) {
+			continue
+		}
 
-				logboek.Context(ctx).Debug().LogF("Selected stage by digest %q uniqueID %d\n", digest, uniqueID)
+		if digest, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(tag); err != nil {
+			if isUnexpectedTagFormatError(err) {
+				logboek.Context(ctx).Debug().LogLn(err.Error())
+				continue
 			}
-		}
+			return nil, err
+		} else {
+			res = append(res, *image.NewStageID(digest, uniqueID))
 
-		return res, nil
+			logboek.Context(ctx).Debug().LogF("Selected stage by digest %q uniqueID %d\n", digest, uniqueID)
+		}
 	}
+
+	return res, nil
 }
 
 func (storage *RepoStagesStorage) ExportStage(ctx context.Context, stageDescription *image.StageDescription, destinationReference string, mutateConfigFunc func(config v1.Config) (v1.Config, error)) error {
@@ -193,68 +207,77 @@ func (storage *RepoStagesStorage) DeleteRepo(ctx context.Context) error {
 }
 
 func (storage *RepoStagesStorage) GetStagesIDsByDigest(ctx context.Context, _, digest string, opts ...Option) ([]image.StageID, error) {
-	var res []image.StageID
-
 	o := makeOptions(opts...)
-	if tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress, o.dockerRegistryOptions...); err != nil {
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress, o.dockerRegistryOptions...)
+	if err != nil {
 		return nil, fmt.Errorf("unable to fetch tags for repo %q: %w", storage.RepoAddress, err)
-	} else {
-		var rejectedStages []image.StageID
+	}
 
-		for _, tag := range tags {
-			if !strings.HasSuffix(tag, RepoRejectedStageImageRecord_ImageTagSuffix) {
-				continue
-			}
+	res, err := selectStagesIDsByDigestFromTags(ctx, digest, tags)
+	if err != nil {
+		return nil, err
+	}
 
-			realTag := strings.TrimSuffix(tag, RepoRejectedStageImageRecord_ImageTagSuffix)
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetRepoImagesByDigest result for %q: %#v\n", storage.RepoAddress, res)
 
-			if _, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(realTag); err != nil {
-				if isUnexpectedTagFormatError(err) {
-					logboek.Context(ctx).Info().LogF("Unexpected tag %q format: %s\n", realTag, err)
-					continue
-				}
-				return nil, fmt.Errorf("unable to get digest and uniqueID from rejected stage tag %q: %w", tag, err)
-			} else {
-				logboek.Context(ctx).Info().LogF("Found rejected stage %q\n", tag)
-				rejectedStages = append(rejectedStages, *image.NewStageID(digest, uniqueID))
-			}
+	return res, nil
+}
+
+func selectStagesIDsByDigestFromTags(ctx context.Context, digest string, tags []string) ([]image.StageID, error) {
+	var res []image.StageID
+	var rejectedStages []image.StageID
+
+	for _, tag := range tags {
+		if !strings.HasSuffix(tag, RepoRejectedStageImageRecord_ImageTagSuffix) {
+			continue
 		}
 
-	FindSuitableStages:
-		for _, tag := range tags {
-			if !strings.HasPrefix(tag, digest) {
+		realTag := strings.TrimSuffix(tag, RepoRejectedStageImageRecord_ImageTagSuffix)
+
+		if _, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(realTag); err != nil {
+			if isUnexpectedTagFormatError(err) {
+				logboek.Context(ctx).Info().LogF("Unexpected tag %q format: %s\n", realTag, err)
 				continue
 			}
+			return nil, fmt.Errorf("unable to get digest and uniqueID from rejected stage tag %q: %w", tag, err)
+		} else {
+			logboek.Context(ctx).Info().LogF("Found rejected stage %q\n", tag)
+			rejectedStages = append(rejectedStages, *image.NewStageID(digest, uniqueID))
+		}
+	}
+
+FindSuitableStages:
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, digest) {
+			continue
+		}
+
+		if strings.HasSuffix(tag, RepoRejectedStageImageRecord_ImageTagSuffix) {
+			continue
+		}
 
-			if strings.HasSuffix(tag, RepoRejectedStageImageRecord_ImageTagSuffix) {
+		if _, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(tag); err != nil {
+			if isUnexpectedTagFormatError(err) {
+				logboek.Context(ctx).Debug().LogLn(err.Error())
+				logboek.Context(ctx).Info().LogF("Unexpected tag %q format: %s\n", tag, err)
 				continue
 			}
+			return nil, fmt.Errorf("unable to get digest and uniqueID from tag %q: %w", tag, err)
+		} else {
+			stageID := image.NewStageID(digest, uniqueID)
 
-			if _, uniqueID, err := getDigestAndUniqueIDFromRepoStageImageTag(tag); err != nil {
-				if isUnexpectedTagFormatError(err) {
-					logboek.Context(ctx).Debug().LogLn(err.Error())
-					logboek.Context(ctx).Info().LogF("Unexpected tag %q format: %s\n", tag, err)
-					continue
-				}
-				return nil, fmt.Errorf("unable to get digest and uniqueID from tag %q: %w", tag, err)
-			} else {
-				stageID := image.NewStageID(digest, uniqueID)
-
-				for _, rejectedStage := range rejectedStages {
-					if rejectedStage.Digest == stageID.Digest && rejectedStage.UniqueID == stageID.UniqueID {
-						logboek.Context(ctx).Info().LogF("Discarding rejected stage %q\n", tag)
-						continue FindSuitableStages
-					}
+			for _, rejectedStage := range rejectedStages {
+				if rejectedStage.Digest == stageID.Digest && rejectedStage.UniqueID == stageID.UniqueID {
+					logboek.Context(ctx).Info().LogF("Discarding rejected stage %q\n", tag)
+					continue FindSuitableStages
 				}
-
-				logboek.Context(ctx).Debug().LogF("Stage %q is suitable for digest %q\n", tag, digest)
-				res = append(res, *stageID)
 			}
+
+			logboek.Context(ctx).Debug().LogF("Stage %q is suitable for digest %q\n", tag, digest)
+			res = append(res, *stageID)
 		}
 	}
 
-	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetRepoImagesByDigest result for %q: %#v\n", storage.RepoAddress, res)
-
 	return res, nil
 }
 
@@ -335,6 +358,169 @@ func (storage *RepoStagesStorage) DeleteStageCustomTag(ctx context.Context, tag
 	return nil
 }
 
+// GetDockerfileLayersCacheTags returns the tags of the intermediate layers cache pushed by Buildah into the repo (sha256 of the cache key).
+// The tags are returned only if werf has recorded that the layers cache is pushed into the repo,
+// the layers cache image should still be checked with GetDockerfileLayersCacheTagInfo before the deletion
+func (storage *RepoStagesStorage) GetDockerfileLayersCacheTags(ctx context.Context, opts ...Option) ([]string, error) {
+	o := makeOptions(opts...)
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress, o.dockerRegistryOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch tags for repo %q: %w", storage.RepoAddress, err)
+	}
+
+	if !isDockerfileLayersCacheRecordExist(tags) {
+		return nil, nil
+	}
+
+	var res []string
+	for _, tag := range tags {
+		if isDockerfileLayersCacheTag(tag) {
+			res = append(res, tag)
+		}
+	}
+
+	return res, nil
+}
+
+// GetDockerfileLayersCacheTagInfo returns the info of the layers cache image or nil if the tag does not exist or the image is not committed by Buildah
+func (storage *RepoStagesStorage) GetDockerfileLayersCacheTagInfo(ctx context.Context, tag string) (*image.Info, error) {
+	fullImageName := strings.Join([]string{storage.RepoAddress, tag}, ":")
+	info, err := storage.DockerRegistry.TryGetRepoImage(ctx, fullImageName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get repo image %q info: %w", fullImageName, err)
+	}
+
+	if info == nil {
+		return nil, nil
+	}
+
+	if _, hasLabel := info.Labels[image.BuildahVersionLabel]; !hasLabel {
+		return nil, nil
+	}
+
+	return info, nil
+}
+
+func (storage *RepoStagesStorage) DeleteDockerfileLayersCacheTag(ctx context.Context, tag string) error {
+	return storage.deleteTag(ctx, tag)
+}
+
+// PutDockerfileLayersCacheRecord records that the Dockerfile layers cache is pushed into the repo, so the cleanup processes the layers cache tags
+func (storage *RepoStagesStorage) PutDockerfileLayersCacheRecord(ctx context.Context, projectName string, opts ...Option) error {
+	o := makeOptions(opts...)
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress, o.dockerRegistryOptions...)
+	if err != nil {
+		return fmt.Errorf("unable to fetch tags for repo %q: %w", storage.RepoAddress, err)
+	}
+
+	if isDockerfileLayersCacheRecordExist(tags) {
+		return nil
+	}
+
+	fullImageName := strings.Join([]string{storage.RepoAddress, RepoDockerfileLayersCacheRecord_ImageTag}, ":")
+	if err := storage.DockerRegistry.PushImage(ctx, fullImageName, &docker_registry.PushImageOptions{Labels: map[string]string{image.WerfLabel: projectName}}); err != nil {
+		return fmt.Errorf("unable to push image %s: %w", fullImageName, err)
+	}
+
+	return nil
+}
+
+func (storage *RepoStagesStorage) DeleteDockerfileLayersCacheRecord(ctx context.Context) error {
+	return storage.deleteTag(ctx, RepoDockerfileLayersCacheRecord_ImageTag)
+}
+
+func isDockerfileLayersCacheRecordExist(tags []string) bool {
+	for _, tag := range tags {
+		if tag == RepoDockerfileLayersCacheRecord_ImageTag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (storage *RepoStagesStorage) deleteTag(ctx context.Context, tag string) error {
+	fullImageName := strings.Join([]string{storage.RepoAddress, tag}, ":")
+	imgInfo, err := storage.DockerRegistry.TryGetRepoImage(ctx, fullImageName)
+	if err != nil {
+		return fmt.Errorf("unable to get repo image %q info: %w", fullImageName, err)
+	}
+
+	if imgInfo == nil {
+		return nil
+	}
+
+	if err := storage.DockerRegistry.DeleteRepoImage(ctx, imgInfo); err != nil {
+		return fmt.Errorf("unable to delete image %q from repo: %w", fullImageName, err)
+	}
+
+	return nil
+}
+
+// imageArtifactTagRegexp matches the tags of the artifacts that describe the images: the SBOM artifacts (sha256-HEX.FORMAT, see sbom.ArtifactTag),
+// the signatures (sha256-HEX.sig, see signing.SignatureTagSuffix) and the OCI referrers fallback indexes (sha256-HEX) which are pushed by the registry client for the registries without the referrers API
+var imageArtifactTagRegexp = regexp.MustCompile(fmt.Sprintf(`^sha256-([0-9a-f]{64})(\.(%s))?$`, strings.Join(append(util.MapFuncToSlice(sbom.Formats, func(format sbom.Format) string {
+	return string(format)
+}), strings.TrimPrefix(signing.SignatureTagSuffix, ".")), "|")))
+
+// GetImageArtifactTags returns the tags of the SBOM artifacts, the signatures and the referrers fallback indexes of the images in the repo
+func (storage *RepoStagesStorage) GetImageArtifactTags(ctx context.Context, opts ...Option) ([]string, error) {
+	o := makeOptions(opts...)
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress, o.dockerRegistryOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch tags for repo %q: %w", storage.RepoAddress, err)
+	}
+
+	var res []string
+	for _, tag := range tags {
+		if imageArtifactTagRegexp.MatchString(tag) {
+			res = append(res, tag)
+		}
+	}
+
+	return res, nil
+}
+
+// GetImageArtifactTagSubjectDigest returns the digest of the manifest described by the SBOM, signed by the signature or referred by the referrers fallback index
+func GetImageArtifactTagSubjectDigest(tag string) string {
+	match := imageArtifactTagRegexp.FindStringSubmatch(tag)
+	if match == nil {
+		return ""
+	}
+
+	return "sha256:" + match[1]
+}
+
+func (storage *RepoStagesStorage) DeleteImageArtifactTag(ctx context.Context, tag string) error {
+	return storage.deleteTag(ctx, tag)
+}
+
+// IsManifestExist returns false if there is no manifest or manifest list by digest in the repo
+func (storage *RepoStagesStorage) IsManifestExist(ctx context.Context, digest string) (bool, error) {
+	if _, err := storage.DockerRegistry.GetManifestDescriptor(ctx, fmt.Sprintf("%s@%s", storage.RepoAddress, digest)); err != nil {
+		if docker_registry.IsStatusNotFoundErr(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func isDockerfileLayersCacheTag(tag string) bool {
+	if len(tag) != 64 {
+		return false
+	}
+
+	for _, r := range tag {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (storage *RepoStagesStorage) addStageCustomTagMetadata(ctx context.Context, projectName string, stageDescription *image.StageDescription, tag string) error {
 	fullImageName := makeRepoCustomTagMetadataRecord(storage.RepoAddress, tag)
 	metadata := newCustomTagMetadata(stageDescription.StageID.String(), tag)
@@ -687,6 +873,174 @@ func (storage *RepoStagesStorage) GetImportMetadataIDs(ctx context.Context, _ st
 	return ids, nil
 }
 
+func (storage *RepoStagesStorage) GetVulnerabilityScanMetadata(ctx context.Context, _, stageID string) (*VulnerabilityScanMetadata, error) {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetVulnerabilityScanMetadata %s\n", stageID)
+
+	fullImageName := makeRepoVulnerabilityScanMetadataName(storage.RepoAddress, stageID)
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetVulnerabilityScanMetadata full image name: %s\n", fullImageName)
+
+	img, err := storage.DockerRegistry.TryGetRepoImage(ctx, fullImageName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get repo image %s: %w", fullImageName, err)
+	}
+
+	if img != nil {
+		return newVulnerabilityScanMetadataFromLabels(img.Labels)
+	}
+
+	return nil, nil
+}
+
+func (storage *RepoStagesStorage) PutVulnerabilityScanMetadata(ctx context.Context, projectName string, metadata *VulnerabilityScanMetadata) error {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.PutVulnerabilityScanMetadata %s\n", metadata.StageID)
+
+	fullImageName := makeRepoVulnerabilityScanMetadataName(storage.RepoAddress, metadata.StageID)
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.PutVulnerabilityScanMetadata full image name: %s\n", fullImageName)
+
+	labels, err := metadata.ToLabelsMap()
+	if err != nil {
+		return err
+	}
+	labels[image.WerfLabel] = projectName
+
+	if err := storage.DockerRegistry.PushImage(ctx, fullImageName, &docker_registry.PushImageOptions{Labels: labels}); err != nil {
+		return fmt.Errorf("unable to push image %s: %w", fullImageName, err)
+	}
+
+	return nil
+}
+
+func (storage *RepoStagesStorage) RmVulnerabilityScanMetadata(ctx context.Context, _, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.RmVulnerabilityScanMetadata %s\n", stageID)
+
+	fullImageName := makeRepoVulnerabilityScanMetadataName(storage.RepoAddress, stageID)
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.RmVulnerabilityScanMetadata full image name: %s\n", fullImageName)
+
+	img, err := storage.DockerRegistry.TryGetRepoImage(ctx, fullImageName)
+	if err != nil {
+		return fmt.Errorf("unable to get repo image %s: %w", fullImageName, err)
+	} else if img == nil {
+		return nil
+	}
+
+	if err := storage.DockerRegistry.DeleteRepoImage(ctx, img); err != nil {
+		return fmt.Errorf("unable to remove repo image %s: %w", img.Tag, err)
+	}
+
+	return nil
+}
+
+func (storage *RepoStagesStorage) GetVulnerabilityScanMetadataIDs(ctx context.Context, _ string, opts ...Option) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetVulnerabilityScanMetadataIDs\n")
+
+	o := makeOptions(opts...)
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress, o.dockerRegistryOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get repo %s tags: %w", storage.RepoAddress, err)
+	}
+
+	return getVulnerabilityScanMetadataIDsFromRepoTags(tags), nil
+}
+
+func getVulnerabilityScanMetadataIDsFromRepoTags(tags []string) []string {
+	var ids []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, RepoVulnerabilityScanMetadata_ImageTagPrefix) {
+			ids = append(ids, strings.TrimPrefix(tag, RepoVulnerabilityScanMetadata_ImageTagPrefix))
+		}
+	}
+
+	return ids
+}
+
+func makeRepoVulnerabilityScanMetadataName(repoAddress, stageID string) string {
+	return fmt.Sprintf(RepoVulnerabilityScanMetadata_ImageNameFormat, repoAddress, stageID)
+}
+
+func (storage *RepoStagesStorage) GetDigestInputsMetadata(ctx context.Context, _, stageID string) (*DigestInputsMetadata, error) {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetDigestInputsMetadata %s\n", stageID)
+
+	fullImageName := makeRepoDigestInputsMetadataName(storage.RepoAddress, stageID)
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetDigestInputsMetadata full image name: %s\n", fullImageName)
+
+	img, err := storage.DockerRegistry.TryGetRepoImage(ctx, fullImageName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get repo image %s: %w", fullImageName, err)
+	}
+
+	if img != nil {
+		return newDigestInputsMetadataFromLabels(img.Labels)
+	}
+
+	return nil, nil
+}
+
+func (storage *RepoStagesStorage) PutDigestInputsMetadata(ctx context.Context, projectName string, metadata *DigestInputsMetadata) error {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.PutDigestInputsMetadata %s\n", metadata.StageID)
+
+	fullImageName := makeRepoDigestInputsMetadataName(storage.RepoAddress, metadata.StageID)
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.PutDigestInputsMetadata full image name: %s\n", fullImageName)
+
+	labels, err := metadata.ToLabelsMap()
+	if err != nil {
+		return err
+	}
+	labels[image.WerfLabel] = projectName
+
+	if err := storage.DockerRegistry.PushImage(ctx, fullImageName, &docker_registry.PushImageOptions{Labels: labels}); err != nil {
+		return fmt.Errorf("unable to push image %s: %w", fullImageName, err)
+	}
+
+	return nil
+}
+
+func (storage *RepoStagesStorage) RmDigestInputsMetadata(ctx context.Context, _, stageID string) error {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.RmDigestInputsMetadata %s\n", stageID)
+
+	fullImageName := makeRepoDigestInputsMetadataName(storage.RepoAddress, stageID)
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.RmDigestInputsMetadata full image name: %s\n", fullImageName)
+
+	img, err := storage.DockerRegistry.TryGetRepoImage(ctx, fullImageName)
+	if err != nil {
+		return fmt.Errorf("unable to get repo image %s: %w", fullImageName, err)
+	} else if img == nil {
+		return nil
+	}
+
+	if err := storage.DockerRegistry.DeleteRepoImage(ctx, img); err != nil {
+		return fmt.Errorf("unable to remove repo image %s: %w", img.Tag, err)
+	}
+
+	return nil
+}
+
+func (storage *RepoStagesStorage) GetDigestInputsMetadataIDs(ctx context.Context, _ string, opts ...Option) ([]string, error) {
+	logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetDigestInputsMetadataIDs\n")
+
+	o := makeOptions(opts...)
+	tags, err := storage.DockerRegistry.Tags(ctx, storage.RepoAddress, o.dockerRegistryOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get repo %s tags: %w", storage.RepoAddress, err)
+	}
+
+	return getDigestInputsMetadataIDsFromRepoTags(tags), nil
+}
+
+func getDigestInputsMetadataIDsFromRepoTags(tags []string) []string {
+	var ids []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, RepoDigestInputsMetadata_ImageTagPrefix) {
+			ids = append(ids, strings.TrimPrefix(tag, RepoDigestInputsMetadata_ImageTagPrefix))
+		}
+	}
+
+	return ids
+}
+
+func makeRepoDigestInputsMetadataName(repoAddress, stageID string) string {
+	return fmt.Sprintf(RepoDigestInputsMetadata_ImageNameFormat, repoAddress, stageID)
+}
+
 func getImportMetadataIDFromRepoTag(tag string) string {
 	return strings.TrimPrefix(tag, RepoImportMetadata_ImageTagPrefix)
 }
@@ -831,6 +1185,15 @@ func (storage *RepoStagesStorage) GetClientIDRecords(ctx context.Context, projec
 		return nil, fmt.Errorf("unable to get repo %s tags: %w", storage.RepoAddress, err)
 	}
 
+	res := selectClientIDRecordsFromTags(tags)
+	for _, rec := range res {
+		logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetClientIDRecords got clientID record: %s\n", rec)
+	}
+
+	return res, nil
+}
+
+func selectClientIDRecordsFromTags(tags []string) []*ClientIDRecord {
 	var res []*ClientIDRecord
 	for _, tag := range tags {
 		if !strings.HasPrefix(tag, RepoClientIDRecord_ImageTagPrefix) {
@@ -852,11 +1215,9 @@ func (storage *RepoStagesStorage) GetClientIDRecords(ctx context.Context, projec
 
 		rec := &ClientIDRecord{ClientID: clientID, TimestampMillisec: timestampMillisec}
 		res = append(res, rec)
-
-		logboek.Context(ctx).Debug().LogF("-- RepoStagesStorage.GetClientIDRecords got clientID record: %s\n", rec)
 	}
 
-	return res, nil
+	return res
 }
 
 func (storage *RepoStagesStorage) PostClientIDRecord(ctx context.Context, projectName string, rec *ClientIDRecord) error {
@@ -903,10 +1264,25 @@ func (storage *RepoStagesStorage) CopyFromStorage(ctx context.Context, src Stage
 		return desc, nil
 	}
 
-	srcRef := src.ConstructStageImageName(projectName, stageID.Digest, stageID.UniqueID)
 	dstRef := storage.ConstructStageImageName(projectName, stageID.Digest, stageID.UniqueID)
-	if err := storage.DockerRegistry.CopyImage(ctx, srcRef, dstRef, docker_registry.CopyImageOptions{}); err != nil {
-		return nil, fmt.Errorf("unable to copy image into registry: %w", err)
+	if ociLayoutSrc, isOCILayout := src.(*OCILayoutStagesStorage); isOCILayout {
+		if opts.IsMultiplatformImage {
+			return nil, fmt.Errorf("unable to copy multiplatform stage %s: not supported by %s", stageID.String(), ociLayoutSrc.String())
+		}
+
+		archiveOpener, cleanup, err := ociLayoutSrc.NewStageArchiveOpener(ctx, stageID, dstRef)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		if err := storage.DockerRegistry.PushImageArchive(ctx, archiveOpener, dstRef); err != nil {
+			return nil, fmt.Errorf("unable to push image archive into registry: %w", err)
+		}
+	} else {
+		srcRef := src.ConstructStageImageName(projectName, stageID.Digest, stageID.UniqueID)
+		if err := storage.DockerRegistry.CopyImage(ctx, srcRef, dstRef, docker_registry.CopyImageOptions{}); err != nil {
+			return nil, fmt.Errorf("unable to copy image into registry: %w", err)
+		}
 	}
 
 	desc, err = storage.GetStageDescription(ctx, projectName, stageID)
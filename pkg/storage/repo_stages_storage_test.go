@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIsDockerfileLayersCacheTag(t *testing.T) {
+	for tag, expected := range map[string]bool{
+		"4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a":            true,
+		"4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1":             false,
+		"4B0C9FF9F6BD2BA0AF45EB8FA73B3A5BD7A5A29C8BD9A3B2A3457E9C54C4EF1A":            false,
+		"2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7-1611836746968":      false,
+		"2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7":                    false,
+		"managed-image-0123456789abcdef0123456789abcdef0123456789abcdef01234567":      false,
+		"meta-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef_x_y_z": false,
+	} {
+		if res := isDockerfileLayersCacheTag(tag); res != expected {
+			t.Errorf("isDockerfileLayersCacheTag(%q) = %v, expected %v", tag, res, expected)
+		}
+	}
+}
+
+func TestSelectStagesIDsFromTagsSkipsDockerfileLayersCacheTags(t *testing.T) {
+	stagesIDs, err := selectStagesIDsFromTags(context.Background(), []string{
+		"4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a",
+		"2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7-1611836746968",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stagesIDs) != 1 || stagesIDs[0].Digest != "2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7" {
+		t.Errorf("unexpected stages ids: %#v", stagesIDs)
+	}
+}
+
+func TestGetImageArtifactTagSubjectDigest(t *testing.T) {
+	for tag, expected := range map[string]string{
+		"sha256-4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a.spdx":      "sha256:4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a",
+		"sha256-4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a.cyclonedx": "sha256:4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a",
+		"sha256-4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a":           "sha256:4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a",
+		"sha256-4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a.sig":       "sha256:4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a",
+		"sha256-4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a.att":       "",
+		"4b0c9ff9f6bd2ba0af45eb8fa73b3a5bd7a5a29c8bd9a3b2a3457e9c54c4ef1a":                  "",
+		"2604b86b2c7a1c6d19c62601aadb19e7d5c6bb8f17bc2bf26a390ea7-1611836746968":            "",
+	} {
+		if res := GetImageArtifactTagSubjectDigest(tag); res != expected {
+			t.Errorf("GetImageArtifactTagSubjectDigest(%q) = %q, expected %q", tag, res, expected)
+		}
+	}
+}
@@ -77,6 +77,16 @@ type StagesStorage interface {
 	RmImportMetadata(ctx context.Context, projectName, id string) error
 	GetImportMetadataIDs(ctx context.Context, projectName string, opts ...Option) ([]string, error)
 
+	GetVulnerabilityScanMetadata(ctx context.Context, projectName, stageID string) (*VulnerabilityScanMetadata, error)
+	PutVulnerabilityScanMetadata(ctx context.Context, projectName string, metadata *VulnerabilityScanMetadata) error
+	RmVulnerabilityScanMetadata(ctx context.Context, projectName, stageID string) error
+	GetVulnerabilityScanMetadataIDs(ctx context.Context, projectName string, opts ...Option) ([]string, error)
+
+	GetDigestInputsMetadata(ctx context.Context, projectName, stageID string) (*DigestInputsMetadata, error)
+	PutDigestInputsMetadata(ctx context.Context, projectName string, metadata *DigestInputsMetadata) error
+	RmDigestInputsMetadata(ctx context.Context, projectName, stageID string) error
+	GetDigestInputsMetadataIDs(ctx context.Context, projectName string, opts ...Option) ([]string, error)
+
 	GetClientIDRecords(ctx context.Context, projectName string, opts ...Option) ([]*ClientIDRecord, error)
 	PostClientIDRecord(ctx context.Context, projectName string, rec *ClientIDRecord) error
 	PostMultiplatformImage(ctx context.Context, projectName, tag string, allPlatformsImages []*image.Info) error
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/vulnerability_scan"
+)
+
+// VulnerabilityScanMetadata is the cached result of the stage image vulnerability scan.
+// The result and the scanner command are stored base64-encoded, because the labels of the local metadata images are passed in the LABEL instruction
+type VulnerabilityScanMetadata struct {
+	StageID string
+	Result  *vulnerability_scan.Result
+	// Scanner is the command the stage image was scanned with
+	Scanner string
+	// ScannedAt is the time of the scan, the result becomes outdated as the vulnerabilities databases are updated
+	ScannedAt time.Time
+}
+
+func (m *VulnerabilityScanMetadata) ToLabels() ([]string, error) {
+	labelsMap, err := m.ToLabelsMap()
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{
+		fmt.Sprintf("%s=%s", image.WerfVulnerabilityScanMetadataStageIDLabel, labelsMap[image.WerfVulnerabilityScanMetadataStageIDLabel]),
+		fmt.Sprintf("%s=%s", image.WerfVulnerabilityScanMetadataResultLabel, labelsMap[image.WerfVulnerabilityScanMetadataResultLabel]),
+		fmt.Sprintf("%s=%s", image.WerfVulnerabilityScanMetadataScannerLabel, labelsMap[image.WerfVulnerabilityScanMetadataScannerLabel]),
+		fmt.Sprintf("%s=%s", image.WerfVulnerabilityScanMetadataTimeLabel, labelsMap[image.WerfVulnerabilityScanMetadataTimeLabel]),
+	}, nil
+}
+
+func (m *VulnerabilityScanMetadata) ToLabelsMap() (map[string]string, error) {
+	data, err := json.Marshal(m.Result)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal vulnerability scan result: %w", err)
+	}
+
+	return map[string]string{
+		image.WerfVulnerabilityScanMetadataStageIDLabel: m.StageID,
+		image.WerfVulnerabilityScanMetadataResultLabel:  base64.StdEncoding.EncodeToString(data),
+		image.WerfVulnerabilityScanMetadataScannerLabel: base64.StdEncoding.EncodeToString([]byte(m.Scanner)),
+		image.WerfVulnerabilityScanMetadataTimeLabel:    strconv.FormatInt(m.ScannedAt.Unix(), 10),
+	}, nil
+}
+
+func newVulnerabilityScanMetadataFromLabels(labels map[string]string) (*VulnerabilityScanMetadata, error) {
+	data, err := base64.StdEncoding.DecodeString(labels[image.WerfVulnerabilityScanMetadataResultLabel])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode vulnerability scan result: %w", err)
+	}
+
+	result := &vulnerability_scan.Result{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal vulnerability scan result: %w", err)
+	}
+
+	// the results cached before the scanner and the time were recorded are left empty to be rescanned
+	scanner, err := base64.StdEncoding.DecodeString(labels[image.WerfVulnerabilityScanMetadataScannerLabel])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode vulnerability scanner: %w", err)
+	}
+
+	var scannedAt time.Time
+	if value := labels[image.WerfVulnerabilityScanMetadataTimeLabel]; value != "" {
+		timestamp, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse vulnerability scan time %q: %w", value, err)
+		}
+		scannedAt = time.Unix(timestamp, 0)
+	}
+
+	return &VulnerabilityScanMetadata{
+		StageID:   labels[image.WerfVulnerabilityScanMetadataStageIDLabel],
+		Result:    result,
+		Scanner:   string(scanner),
+		ScannedAt: scannedAt,
+	}, nil
+}
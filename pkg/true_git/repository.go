@@ -70,6 +70,15 @@ func GetLastBranchCommitSHA(ctx context.Context, repoPath, branch string) (strin
 	return strings.TrimSpace(revParseCmd.OutBuf.String()), nil
 }
 
+func ResolveCommit(ctx context.Context, repoPath, revision string) (string, error) {
+	revParseCmd := NewGitCmd(ctx, &GitCmdOptions{RepoDir: repoPath}, "rev-parse", "--verify", fmt.Sprintf("%s^{commit}", revision))
+	if err := revParseCmd.Run(ctx); err != nil {
+		return "", fmt.Errorf("git rev parse revision %q command failed: %w", revision, err)
+	}
+
+	return strings.TrimSpace(revParseCmd.OutBuf.String()), nil
+}
+
 func IsShallowClone(ctx context.Context, path string) (bool, error) {
 	if gitVersion.LessThan(semver.MustParse("2.15.0")) {
 		exist, err := util.FileExists(filepath.Join(path, ".git", "shallow"))
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -61,3 +62,26 @@ func ExtractSecretValuesFromMap(data map[string]interface{}) []string {
 
 	return maskedValues
 }
+
+// MaskSecretValues replaces the secret values in the text with ***, the multiline secret values are masked line by line
+func MaskSecretValues(text string, secretValues []string) string {
+	var valuesToMask []string
+	for _, value := range secretValues {
+		for _, line := range strings.Split(value, "\n") {
+			if trimmedLine := strings.TrimSpace(line); len(trimmedLine) >= 4 {
+				valuesToMask = append(valuesToMask, trimmedLine)
+			}
+		}
+	}
+
+	// the longest values are masked first so that the values containing other values are not masked partially
+	sort.SliceStable(valuesToMask, func(i, j int) bool {
+		return len(valuesToMask[i]) > len(valuesToMask[j])
+	})
+
+	for _, value := range valuesToMask {
+		text = strings.ReplaceAll(text, value, "***")
+	}
+
+	return text
+}
@@ -0,0 +1,116 @@
+package vulnerability_scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Finding is the vulnerability of the package found in the image
+type Finding struct {
+	ID               string
+	Package          string
+	InstalledVersion string
+	FixedVersion     string `json:",omitempty"`
+	Severity         Severity
+	Title            string `json:",omitempty"`
+}
+
+// Result is the normalized result of the image scan.
+// Findings are sorted from the highest severity to the lowest
+type Result struct {
+	Findings []*Finding
+}
+
+func (r *Result) Summary() map[Severity]int {
+	summary := map[Severity]int{}
+	for _, finding := range r.Findings {
+		summary[finding.Severity]++
+	}
+	return summary
+}
+
+// SummaryString returns the number of findings of each severity from the highest to the lowest (CRITICAL: 1, HIGH: 2)
+func (r *Result) SummaryString() string {
+	summary := r.Summary()
+
+	var parts []string
+	for ind := len(Severities) - 1; ind >= 0; ind-- {
+		if count := summary[Severities[ind]]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", Severities[ind], count))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "no vulnerabilities"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (r *Result) FindingsAtLeast(threshold Severity) []*Finding {
+	var res []*Finding
+	for _, finding := range r.Findings {
+		if finding.Severity.IsAtLeast(threshold) {
+			res = append(res, finding)
+		}
+	}
+	return res
+}
+
+type trivyReport struct {
+	Results []struct {
+		Target          string
+		Vulnerabilities []struct {
+			VulnerabilityID  string
+			PkgName          string
+			InstalledVersion string
+			FixedVersion     string
+			Severity         string
+			Title            string
+		}
+	}
+}
+
+// ParseTrivyReport parses the scanner output in the Trivy JSON format.
+// The same vulnerability of the same package found in several targets is reported once
+func ParseTrivyReport(data []byte) (*Result, error) {
+	var report trivyReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("unable to parse report: %w", err)
+	}
+
+	result := &Result{}
+	found := map[string]bool{}
+	for _, target := range report.Results {
+		for _, vuln := range target.Vulnerabilities {
+			key := strings.Join([]string{vuln.VulnerabilityID, vuln.PkgName, vuln.InstalledVersion}, "/")
+			if found[key] {
+				continue
+			}
+			found[key] = true
+
+			result.Findings = append(result.Findings, &Finding{
+				ID:               vuln.VulnerabilityID,
+				Package:          vuln.PkgName,
+				InstalledVersion: vuln.InstalledVersion,
+				FixedVersion:     vuln.FixedVersion,
+				Severity:         normalizeSeverity(vuln.Severity),
+				Title:            vuln.Title,
+			})
+		}
+	}
+
+	sort.SliceStable(result.Findings, func(i, j int) bool {
+		a, b := result.Findings[i], result.Findings[j]
+		if a.Severity != b.Severity {
+			return a.Severity.rank() > b.Severity.rank()
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return a.Package < b.Package
+	})
+
+	return result, nil
+}
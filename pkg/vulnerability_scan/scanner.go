@@ -0,0 +1,65 @@
+package vulnerability_scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+)
+
+// Scanner runs the local scanner binary for the image and blocks the images with vulnerabilities of the threshold severity or higher.
+// The image reference is passed to the scanner command as the last argument, the scanner should print the report in the Trivy JSON format
+// (for example, trivy image --format json --quiet)
+type Scanner struct {
+	Command   []string
+	Threshold Severity
+	// CacheTTL is the time the cached scan result is used for, the result is not cached if zero
+	CacheTTL time.Duration
+}
+
+func NewScanner(command string, threshold Severity) (*Scanner, error) {
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse scanner command %q: %w", command, err)
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("scanner command is empty")
+	}
+
+	return &Scanner{Command: args, Threshold: threshold}, nil
+}
+
+func (s *Scanner) Scan(ctx context.Context, reference string) (*Result, error) {
+	args := append(append([]string{}, s.Command[1:]...), reference)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.Command[0], args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("scanner command %q failed: %w\n%s", strings.Join(cmd.Args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	result, err := ParseTrivyReport(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse scanner command %q output: %w", strings.Join(cmd.Args, " "), err)
+	}
+
+	return result, nil
+}
+
+// CommandString returns the scanner command the cached scan results are bound to
+func (s *Scanner) CommandString() string {
+	return strings.Join(s.Command, " ")
+}
+
+// BlockingFindings returns the findings of the threshold severity or higher
+func (s *Scanner) BlockingFindings(result *Result) []*Finding {
+	return result.FindingsAtLeast(s.Threshold)
+}
@@ -0,0 +1,58 @@
+package vulnerability_scan
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityUnknown  Severity = "UNKNOWN"
+	SeverityLow      Severity = "LOW"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityHigh     Severity = "HIGH"
+	SeverityCritical Severity = "CRITICAL"
+)
+
+// Severities are ordered from the lowest to the highest
+var Severities = []Severity{SeverityUnknown, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+func ParseSeverity(value string) (Severity, error) {
+	for _, severity := range Severities {
+		if strings.EqualFold(value, string(severity)) {
+			return severity, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported severity %q: one of %s expected", value, strings.Join(severitiesStrings(), ", "))
+}
+
+// normalizeSeverity converts the severity reported by the scanner, the unsupported values are considered as unknown
+func normalizeSeverity(value string) Severity {
+	if severity, err := ParseSeverity(value); err == nil {
+		return severity
+	}
+	return SeverityUnknown
+}
+
+func (s Severity) rank() int {
+	for ind, severity := range Severities {
+		if s == severity {
+			return ind
+		}
+	}
+	return 0
+}
+
+func (s Severity) IsAtLeast(threshold Severity) bool {
+	return s.rank() >= threshold.rank()
+}
+
+func severitiesStrings() []string {
+	var res []string
+	for _, severity := range Severities {
+		res = append(res, string(severity))
+	}
+	return res
+}
@@ -0,0 +1,128 @@
+package vulnerability_scan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const testTrivyReport = `{
+  "SchemaVersion": 2,
+  "ArtifactName": "alpine:3.17",
+  "Results": [
+    {
+      "Target": "alpine:3.17 (alpine 3.17.0)",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2023-0002", "PkgName": "busybox", "InstalledVersion": "1.35.0-r29", "Severity": "MEDIUM"},
+        {"VulnerabilityID": "CVE-2023-0001", "PkgName": "libcrypto3", "InstalledVersion": "3.0.7-r0", "FixedVersion": "3.0.8-r0", "Severity": "CRITICAL", "Title": "openssl: double free"},
+        {"VulnerabilityID": "CVE-2023-0003", "PkgName": "zlib", "InstalledVersion": "1.2.13-r0", "Severity": "negligible"}
+      ]
+    },
+    {
+      "Target": "usr/lib/app.jar"
+    },
+    {
+      "Target": "alpine:3.17 (duplicate)",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2023-0002", "PkgName": "busybox", "InstalledVersion": "1.35.0-r29", "Severity": "MEDIUM"},
+        {"VulnerabilityID": "CVE-2023-0004", "PkgName": "libssl3", "InstalledVersion": "3.0.7-r0", "Severity": "HIGH"}
+      ]
+    }
+  ]
+}`
+
+var _ = Describe("Severity", func() {
+	It("should be parsed case-insensitively", func() {
+		severity, err := ParseSeverity("high")
+		Expect(err).To(Succeed())
+		Expect(severity).To(Equal(SeverityHigh))
+
+		_, err = ParseSeverity("severe")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should be compared with the threshold", func() {
+		Expect(SeverityCritical.IsAtLeast(SeverityHigh)).To(BeTrue())
+		Expect(SeverityHigh.IsAtLeast(SeverityHigh)).To(BeTrue())
+		Expect(SeverityMedium.IsAtLeast(SeverityHigh)).To(BeFalse())
+		Expect(SeverityUnknown.IsAtLeast(SeverityLow)).To(BeFalse())
+	})
+})
+
+var _ = Describe("ParseTrivyReport", func() {
+	It("should normalize, deduplicate and sort findings", func() {
+		result, err := ParseTrivyReport([]byte(testTrivyReport))
+		Expect(err).To(Succeed())
+
+		var ids []string
+		for _, finding := range result.Findings {
+			ids = append(ids, finding.ID)
+		}
+		Expect(ids).To(Equal([]string{"CVE-2023-0001", "CVE-2023-0004", "CVE-2023-0002", "CVE-2023-0003"}))
+
+		Expect(*result.Findings[0]).To(Equal(Finding{
+			ID:               "CVE-2023-0001",
+			Package:          "libcrypto3",
+			InstalledVersion: "3.0.7-r0",
+			FixedVersion:     "3.0.8-r0",
+			Severity:         SeverityCritical,
+			Title:            "openssl: double free",
+		}))
+		Expect(result.Findings[3].Severity).To(Equal(SeverityUnknown))
+
+		Expect(result.Summary()).To(Equal(map[Severity]int{SeverityCritical: 1, SeverityHigh: 1, SeverityMedium: 1, SeverityUnknown: 1}))
+		Expect(result.SummaryString()).To(Equal("CRITICAL: 1, HIGH: 1, MEDIUM: 1, UNKNOWN: 1"))
+		Expect(result.FindingsAtLeast(SeverityHigh)).To(HaveLen(2))
+	})
+
+	It("should fail on the invalid report", func() {
+		_, err := ParseTrivyReport([]byte("not a json"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Scanner", func() {
+	var scriptPath string
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		scriptPath = filepath.Join(dir, "scanner.sh")
+		Expect(os.WriteFile(filepath.Join(dir, "report.json"), []byte(testTrivyReport), 0o644)).To(Succeed())
+		Expect(os.WriteFile(scriptPath, []byte(`#!/bin/sh
+if [ "$2" != "registry.example.org/app:tag" ]; then
+  echo "unexpected arguments: $*" >&2
+  exit 1
+fi
+cat "$(dirname "$0")/report.json"
+`), 0o755)).To(Succeed())
+	})
+
+	It("should pass the image reference as the last argument and parse the report", func() {
+		scanner, err := NewScanner(scriptPath+` "--format json"`, SeverityCritical)
+		Expect(err).To(Succeed())
+
+		result, err := scanner.Scan(context.Background(), "registry.example.org/app:tag")
+		Expect(err).To(Succeed())
+		Expect(result.Findings).To(HaveLen(4))
+
+		blocking := scanner.BlockingFindings(result)
+		Expect(blocking).To(HaveLen(1))
+		Expect(blocking[0].ID).To(Equal("CVE-2023-0001"))
+	})
+
+	It("should return the scanner error output", func() {
+		scanner, err := NewScanner(scriptPath, SeverityHigh)
+		Expect(err).To(Succeed())
+
+		_, err = scanner.Scan(context.Background(), "registry.example.org/other:tag")
+		Expect(err).To(MatchError(ContainSubstring("unexpected arguments")))
+	})
+
+	It("should fail on the empty command", func() {
+		_, err := NewScanner("  ", SeverityHigh)
+		Expect(err).To(HaveOccurred())
+	})
+})
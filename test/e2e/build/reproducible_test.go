@@ -0,0 +1,79 @@
+package e2e_build_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/build"
+	"github.com/werf/werf/test/pkg/contback"
+	"github.com/werf/werf/test/pkg/werf"
+)
+
+type reproducibleTestOptions struct {
+	setupEnvOptions
+}
+
+var _ = Describe("Reproducible build", Label("e2e", "build", "reproducible"), func() {
+	DescribeTable("should produce the same images digests when rebuilding the same commit",
+		func(testOpts reproducibleTestOptions) {
+			By("initializing")
+			setupEnv(testOpts.setupEnvOptions)
+			Expect(SuiteData.WerfRepo).NotTo(BeEmpty())
+
+			contRuntime, err := contback.NewContainerBackend(testOpts.ContainerBackendMode)
+			if err == contback.ErrRuntimeUnavailable {
+				Skip(err.Error())
+			} else if err != nil {
+				Fail(err.Error())
+			}
+
+			repoDirname := "repo0"
+			fixtureRelPath := "reproducible/state0"
+
+			By("preparing test repo")
+			SuiteData.InitTestRepo(repoDirname, fixtureRelPath)
+			werfProject := werf.NewProject(SuiteData.WerfBinPath, SuiteData.GetTestRepoPath(repoDirname))
+
+			By("building images")
+			buildOut, buildReport := werfProject.BuildWithReport(SuiteData.GetBuildReportPath("report0.json"), nil)
+			Expect(buildOut).To(ContainSubstring("Building stage"))
+
+			By("rebuilding images from scratch into another repo")
+			SuiteData.Stubs.SetEnv("WERF_REPO", SuiteData.WerfRepo+"-rebuild")
+			rebuildOut, rebuildReport := werfProject.BuildWithReport(SuiteData.GetBuildReportPath("report1.json"), nil)
+			Expect(rebuildOut).To(ContainSubstring("Building stage"))
+			Expect(rebuildOut).NotTo(ContainSubstring("Use previously built image"))
+
+			By("comparing images digests")
+			for _, imageName := range []string{"dockerfile", "stapel-shell"} {
+				Expect(buildReport.Images).To(HaveKey(imageName))
+				Expect(rebuildReport.Images).To(HaveKey(imageName))
+				Expect(reportImageDigest(rebuildReport.Images[imageName])).To(Equal(reportImageDigest(buildReport.Images[imageName])), imageName)
+			}
+
+			By(`checking "stapel-shell" image files ownership`)
+			contRuntime.ExpectCmdsToSucceed(
+				rebuildReport.Images["stapel-shell"].DockerImageName,
+				"stat -c %u:%g /file | diff <(echo 0:0) -",
+				"stat -c %u:%g /built-file | diff <(echo 0:0) -",
+			)
+		},
+		Entry("with local repo using Native Buildah with rootless isolation", reproducibleTestOptions{setupEnvOptions{
+			ContainerBackendMode: "native-rootless",
+			WithLocalRepo:        true,
+		}}),
+		Entry("with local repo using Native Buildah with chroot isolation", reproducibleTestOptions{setupEnvOptions{
+			ContainerBackendMode: "native-chroot",
+			WithLocalRepo:        true,
+		}}),
+	)
+})
+
+// reportImageDigest returns the manifest digest of the image without the repo the image is stored in
+func reportImageDigest(record build.ReportImageRecord) string {
+	_, digest, _ := strings.Cut(record.DockerImageDigest, "@")
+	Expect(digest).NotTo(BeEmpty())
+	return digest
+}